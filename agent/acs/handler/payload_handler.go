@@ -26,6 +26,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/wsclient"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/cihub/seelog"
@@ -290,7 +291,11 @@ func (payloadHandler *payloadRequestHandler) addTasks(payload *ecsacs.PayloadMes
 		if skipAddTask(task.GetDesiredStatus()) {
 			continue
 		}
+		span := tracing.TracerGlobal.StartSpan(task.Arn, "acs.payload",
+			tracing.String("aws.ecs.acs.message_id", aws.StringValue(payload.MessageId)),
+			tracing.String(tracing.StatusAttribute, task.GetDesiredStatus().String()))
 		payloadHandler.taskEngine.AddTask(task)
+		span.End(nil)
 
 		ackCredentials := func(id string, description string) {
 			ack, err := payloadHandler.ackCredentials(payload.MessageId, id)
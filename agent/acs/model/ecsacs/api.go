@@ -257,6 +257,30 @@ func (s BadRequestException) GoString() string {
 	return s.String()
 }
 
+type BlockIODeviceLimit struct {
+	_ struct{} `type:"structure"`
+
+	Device *string `locationName:"device" type:"string"`
+
+	ReadBps *int64 `locationName:"readBps" type:"long"`
+
+	ReadIOPS *int64 `locationName:"readIOPS" type:"long"`
+
+	WriteBps *int64 `locationName:"writeBps" type:"long"`
+
+	WriteIOPS *int64 `locationName:"writeIOPS" type:"long"`
+}
+
+// String returns the string representation
+func (s BlockIODeviceLimit) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s BlockIODeviceLimit) GoString() string {
+	return s.String()
+}
+
 type CloseMessage struct {
 	_ struct{} `type:"structure"`
 
@@ -1284,6 +1308,10 @@ type Task struct {
 
 	Associations []*Association `locationName:"associations" type:"list"`
 
+	BlockIODeviceLimits []*BlockIODeviceLimit `locationName:"blockIODeviceLimits" type:"list"`
+
+	BlockIOWeight *int64 `locationName:"blockIOWeight" type:"integer"`
+
 	Containers []*Container `locationName:"containers" type:"list"`
 
 	Cpu *float64 `locationName:"cpu" type:"double"`
@@ -1304,6 +1332,8 @@ type Task struct {
 
 	PidMode *string `locationName:"pidMode" type:"string"`
 
+	PidsLimit *int64 `locationName:"pidsLimit" type:"long"`
+
 	ProxyConfiguration *ProxyConfiguration `locationName:"proxyConfiguration" type:"structure"`
 
 	RoleCredentials *IAMRoleCredentials `locationName:"roleCredentials" type:"structure"`
@@ -66,15 +66,30 @@ const (
 	// MetadataURIFormat defines the URI format for v4 metadata endpoint
 	MetadataURIFormatV4 = "http://169.254.170.2/v4/%s"
 
+	// MetadataURIEnvVarNameV5 defines the name of the environment
+	// variable in containers' config, which can be used by the containers to access the
+	// v5 metadata endpoint
+	MetadataURIEnvVarNameV5 = "ECS_CONTAINER_METADATA_URI_V5"
+
+	// MetadataURIFormatV5 defines the URI format for v5 metadata endpoint
+	MetadataURIFormatV5 = "http://169.254.170.2/v5/%s"
+
 	// SecretProviderSSM is to show secret provider being SSM
 	SecretProviderSSM = "ssm"
 
 	// SecretProviderASM is to show secret provider being ASM
 	SecretProviderASM = "asm"
 
+	// SecretProviderVault is to show secret provider being HashiCorp Vault
+	SecretProviderVault = "vault"
+
 	// SecretTypeEnv is to show secret type being ENVIRONMENT_VARIABLE
 	SecretTypeEnv = "ENVIRONMENT_VARIABLE"
 
+	// SecretTypeMountPoint is to show secret type being MOUNT_POINT, the secret value being
+	// vended as a file on a tmpfs mount, bind mounted to the container at its ContainerPath
+	SecretTypeMountPoint = "MOUNT_POINT"
+
 	// TargetLogDriver is to show secret target being "LOG_DRIVER", the default will be "CONTAINER"
 	SecretTargetLogDriver = "LOG_DRIVER"
 
@@ -244,6 +259,12 @@ type Container struct {
 	// and `SetKnownExitCode`.
 	KnownExitCodeUnsafe *int `json:"KnownExitCode"`
 
+	// OOMKilledUnsafe is true if docker reports that the container was killed
+	// for running out of memory.
+	// NOTE: Do not access OOMKilledUnsafe directly. Instead, use `GetOOMKilled`
+	// and `SetOOMKilled`.
+	OOMKilledUnsafe bool `json:"OOMKilled,omitempty"`
+
 	// KnownPortBindingsUnsafe is an array of port bindings for the container.
 	KnownPortBindingsUnsafe []PortBinding `json:"KnownPortBindings"`
 
@@ -422,6 +443,24 @@ func (c *Container) GetKnownExitCode() *int {
 	return c.KnownExitCodeUnsafe
 }
 
+// SetOOMKilled records whether the container was killed for running out of
+// memory
+func (c *Container) SetOOMKilled(oomKilled bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.OOMKilledUnsafe = oomKilled
+}
+
+// GetOOMKilled returns true if the container was killed for running out of
+// memory
+func (c *Container) GetOOMKilled() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.OOMKilledUnsafe
+}
+
 // SetRegistryAuthCredentials sets the credentials for pulling image from ECR
 func (c *Container) SetRegistryAuthCredentials(credential credentials.IAMRoleCredentials) {
 	c.lock.Lock()
@@ -885,6 +924,20 @@ func (c *Container) InjectV4MetadataEndpoint() {
 		fmt.Sprintf(MetadataURIFormatV4, c.V3EndpointID)
 }
 
+// InjectV5MetadataEndpoint injects the v5 metadata endpoint as an environment variable for a container
+func (c *Container) InjectV5MetadataEndpoint() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// don't assume that the environment variable map has been initialized by others
+	if c.Environment == nil {
+		c.Environment = make(map[string]string)
+	}
+
+	c.Environment[MetadataURIEnvVarNameV5] =
+		fmt.Sprintf(MetadataURIFormatV5, c.V3EndpointID)
+}
+
 // ShouldCreateWithSSMSecret returns true if this container needs to get secret
 // value from SSM Parameter Store
 func (c *Container) ShouldCreateWithSSMSecret() bool {
@@ -923,6 +976,25 @@ func (c *Container) ShouldCreateWithASMSecret() bool {
 	return false
 }
 
+// ShouldCreateWithVaultSecret returns true if this container needs to get secret
+// value from HashiCorp Vault
+func (c *Container) ShouldCreateWithVaultSecret() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	// Secrets field will be nil if there is no secrets for container
+	if c.Secrets == nil {
+		return false
+	}
+
+	for _, secret := range c.Secrets {
+		if secret.Provider == SecretProviderVault {
+			return true
+		}
+	}
+	return false
+}
+
 // ShouldCreateWithEnvFiles returns true if this container needs to
 // retrieve environment variable files
 func (c *Container) ShouldCreateWithEnvFiles() bool {
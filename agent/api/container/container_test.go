@@ -280,6 +280,17 @@ func TestInjectV4MetadataEndpoint(t *testing.T) {
 		fmt.Sprintf(MetadataURIFormatV4, "EndpointID"))
 }
 
+func TestInjectV5MetadataEndpoint(t *testing.T) {
+	container := Container{
+		V3EndpointID: "EndpointID",
+	}
+	container.InjectV5MetadataEndpoint()
+
+	assert.NotNil(t, container.Environment)
+	assert.Equal(t, container.Environment[MetadataURIEnvVarNameV5],
+		fmt.Sprintf(MetadataURIFormatV5, "EndpointID"))
+}
+
 func TestShouldCreateWithSSMSecret(t *testing.T) {
 	cases := []struct {
 		in  Container
@@ -426,6 +437,44 @@ func TestShouldCreateWithASMSecret(t *testing.T) {
 	}
 }
 
+func TestShouldCreateWithVaultSecret(t *testing.T) {
+	cases := []struct {
+		in  Container
+		out bool
+	}{
+		{Container{
+			Name:  "myName",
+			Image: "image:tag",
+			Secrets: []Secret{
+				Secret{
+					Provider:  "vault",
+					Name:      "secret",
+					ValueFrom: "secret/data/test#secretName",
+				}},
+		}, true},
+		{Container{
+			Name:    "myName",
+			Image:   "image:tag",
+			Secrets: nil,
+		}, false},
+		{Container{
+			Name:  "myName",
+			Image: "image:tag",
+			Secrets: []Secret{
+				Secret{
+					Provider:  "asm",
+					Name:      "secret",
+					ValueFrom: "secret/data/test#secretName",
+				}},
+		}, false},
+	}
+
+	for _, test := range cases {
+		container := test.in
+		assert.Equal(t, test.out, container.ShouldCreateWithVaultSecret())
+	}
+}
+
 func TestHasSecret(t *testing.T) {
 	isEnvOrLogDriverSecret := func(s Secret) bool {
 		return s.Type == SecretTypeEnv || s.Target == SecretTargetLogDriver
@@ -18,11 +18,14 @@ type DockerEventType int
 
 const (
 	// ContainerStatusEvent represents the container status change events from docker
-	// currently create, start, stop, die, restart and oom event will have this type
+	// currently create, start, stop, die and restart event will have this type
 	ContainerStatusEvent DockerEventType = iota
 	// ContainerHealthEvent represents the container health status event from docker
 	// "health_status: unhealthy" and "health_status: healthy" will have this type
 	ContainerHealthEvent
+	// ContainerOOMEvent represents a process of the container being killed
+	// for running out of memory, the "oom" event from docker
+	ContainerOOMEvent
 )
 
 func (eventType DockerEventType) String() string {
@@ -31,6 +34,8 @@ func (eventType DockerEventType) String() string {
 		return "ContainerStatusChangeEvent"
 	case ContainerHealthEvent:
 		return "ContainerHealthChangeEvent"
+	case ContainerOOMEvent:
+		return "ContainerOOMEvent"
 	default:
 		return "UNKNOWN"
 	}
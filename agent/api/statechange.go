@@ -16,6 +16,7 @@ package api
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
@@ -29,6 +30,10 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 )
 
+// OutOfMemoryReason prefixes the reason of the state change of containers
+// stopped after one of their processes was killed for running out of memory
+const OutOfMemoryReason = "OutOfMemory"
+
 // ContainerStateChange represents a state change that needs to be sent to the
 // SubmitContainerStateChange API
 type ContainerStateChange struct {
@@ -89,6 +94,48 @@ type AttachmentStateChange struct {
 	Attachment *apieni.ENIAttachment
 }
 
+// ContainerDrift represents the changes made to a running container outside
+// of the agent. It isn't sent to the ECS backend.
+type ContainerDrift struct {
+	// TaskArn is the unique identifier for the task
+	TaskArn string
+	// RuntimeID is the dockerID of the container
+	RuntimeID string
+	// ContainerName is the name of the container
+	ContainerName string
+	// Fields are the fields of the container that differ from the
+	// configuration the agent created it with
+	Fields []DriftedField
+}
+
+// ContainerHealthChange represents a change of the health status of a
+// container. It isn't sent to the ECS backend, which gets the health status
+// along with the container state changes.
+type ContainerHealthChange struct {
+	// TaskArn is the unique identifier for the task
+	TaskArn string
+	// RuntimeID is the dockerID of the container
+	RuntimeID string
+	// ContainerName is the name of the container
+	ContainerName string
+	// Health is the new health status of the container
+	Health apicontainer.HealthStatus
+
+	// Container is a pointer to the container whose health status changed
+	Container *apicontainer.Container
+}
+
+// DriftedField is a field of a container whose value differs from the one the
+// agent created the container with
+type DriftedField struct {
+	// Name is the name of the field, such as "HostConfig.Memory"
+	Name string
+	// Expected is the value the agent created the container with
+	Expected string
+	// Actual is the value reported by docker
+	Actual string
+}
+
 // NewTaskStateChangeEvent creates a new task state change event
 // returns error if the state change doesn't need to be sent to the ECS backend.
 func NewTaskStateChangeEvent(task *apitask.Task, reason string) (TaskStateChange, error) {
@@ -141,6 +188,10 @@ func NewContainerStateChangeEvent(task *apitask.Task, cont *apicontainer.Contain
 	if reason == "" && cont.ApplyingError != nil {
 		reason = cont.ApplyingError.Error()
 	}
+	if contKnownStatus == apicontainerstatus.ContainerStopped && cont.GetOOMKilled() &&
+		!strings.HasPrefix(reason, OutOfMemoryReason) {
+		reason = outOfMemoryReason(reason)
+	}
 	event = ContainerStateChange{
 		TaskArn:       task.Arn,
 		ContainerName: cont.Name,
@@ -155,6 +206,15 @@ func NewContainerStateChangeEvent(task *apitask.Task, cont *apicontainer.Contain
 	return event, nil
 }
 
+// outOfMemoryReason returns the reason of a container stopped after one of its
+// processes was killed for running out of memory
+func outOfMemoryReason(reason string) string {
+	if reason == "" {
+		return OutOfMemoryReason + ": a process of the container was killed for running out of memory"
+	}
+	return OutOfMemoryReason + ": " + reason
+}
+
 // NewAttachmentStateChangeEvent creates a new attachment state change event
 func NewAttachmentStateChangeEvent(eniAttachment *apieni.ENIAttachment) AttachmentStateChange {
 	return AttachmentStateChange{
@@ -247,6 +307,22 @@ func (change *AttachmentStateChange) String() string {
 	return ""
 }
 
+// String returns a human readable string representation of this object
+func (drift *ContainerDrift) String() string {
+	fields := make([]string, 0, len(drift.Fields))
+	for _, field := range drift.Fields {
+		fields = append(fields, fmt.Sprintf("%s: %q -> %q", field.Name, field.Expected, field.Actual))
+	}
+	return fmt.Sprintf("%s %s (Runtime ID: %s) drifted, %s", drift.TaskArn, drift.ContainerName, drift.RuntimeID,
+		strings.Join(fields, ", "))
+}
+
+// String returns a human readable string representation of this object
+func (change *ContainerHealthChange) String() string {
+	return fmt.Sprintf("%s %s (Runtime ID: %s) -> %s", change.TaskArn, change.ContainerName, change.RuntimeID,
+		change.Health.Status.String())
+}
+
 // GetEventType returns an enum identifying the event type
 func (ContainerStateChange) GetEventType() statechange.EventType {
 	return statechange.ContainerEvent
@@ -261,3 +337,13 @@ func (TaskStateChange) GetEventType() statechange.EventType {
 func (AttachmentStateChange) GetEventType() statechange.EventType {
 	return statechange.AttachmentEvent
 }
+
+// GetEventType returns an enum identifying the event type
+func (ContainerDrift) GetEventType() statechange.EventType {
+	return statechange.ContainerDriftEvent
+}
+
+// GetEventType returns an enum identifying the event type
+func (ContainerHealthChange) GetEventType() statechange.EventType {
+	return statechange.ContainerHealthEvent
+}
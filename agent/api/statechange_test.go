@@ -125,3 +125,43 @@ func TestSetImageDigest(t *testing.T) {
 	assert.NoError(t, ok, "error create newContainerStateChangeEvent")
 	assert.Equal(t, "sha256:d1c14fcf2e9476ed58ebc4251b211f403f271e96b6c3d9ada0f1c5454ca4d230", resp.ImageDigest)
 }
+
+func TestContainerStateChangeReasonOutOfMemory(t *testing.T) {
+	testCases := []struct {
+		name           string
+		reason         string
+		expectedReason string
+	}{
+		{
+			name:           "no reason",
+			expectedReason: "OutOfMemory: a process of the container was killed for running out of memory",
+		},
+		{
+			name:           "reason",
+			reason:         "container exited",
+			expectedReason: "OutOfMemory: container exited",
+		},
+		{
+			name:           "out of memory reason",
+			reason:         "OutOfMemory: container exited",
+			expectedReason: "OutOfMemory: container exited",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &apitask.Task{}
+			container := &apicontainer.Container{
+				KnownStatusUnsafe: apicontainerstatus.ContainerStopped,
+				SentStatusUnsafe:  apicontainerstatus.ContainerRunning,
+				Type:              apicontainer.ContainerNormal,
+			}
+			container.SetOOMKilled(true)
+			task.Containers = []*apicontainer.Container{container}
+
+			change, err := NewContainerStateChangeEvent(task, container, tc.reason)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedReason, change.Reason)
+		})
+	}
+}
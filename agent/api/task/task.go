@@ -47,9 +47,11 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmsecret"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/envFiles"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/firelens"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfile"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	resourcetype "github.com/aws/amazon-ecs-agent/agent/taskresource/types"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/vaultsecret"
 	taskresourcevolume "github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
@@ -115,6 +117,11 @@ const (
 	// firelensSocketBindFormat specifies the format for firelens container's socket directory bind mount.
 	// First placeholder is host data dir, second placeholder is taskID.
 	firelensSocketBindFormat = "%s/data/firelens/%s/socket/:/var/run/"
+	// secretFileBindFormat specifies the format of the read-only bind mount of a directory of secret files, from
+	// the tmpfs of the secret resource to the directory of the container paths of the secrets. The placeholders are
+	// the data directory on the host, the name of the secret resource, the task id, the name of the directory and
+	// the container directory.
+	secretFileBindFormat = "%s/data/%s/%s/%s:%s:ro"
 	// firelensDriverName is the log driver name for containers that want to use the firelens container to send logs.
 	firelensDriverName = "awsfirelens"
 
@@ -168,6 +175,15 @@ type Task struct {
 	CPU float64 `json:"Cpu,omitempty"`
 	// Memory is a task-level limit for memory resources in bytes
 	Memory int64 `json:"Memory,omitempty"`
+	// PidsLimit is a task-level limit for the number of processes. The
+	// Agent's default limit is used if 0.
+	PidsLimit int64 `json:"PidsLimit,omitempty"`
+	// BlockIOWeight is the task-level block IO weight, from 10 to 1000. The
+	// Agent's default weight is used if 0.
+	BlockIOWeight uint16 `json:"BlockIOWeight,omitempty"`
+	// BlockIODeviceLimits are task-level block IO throttles. The Agent's
+	// default throttles are used if empty.
+	BlockIODeviceLimits []config.BlockIODeviceLimit `json:"BlockIODeviceLimits,omitempty"`
 	// DesiredStatusUnsafe represents the state where the task should go. Generally,
 	// the desired status is informed by the ECS backend as a result of either
 	// API calls made to ECS or decisions made by the ECS service scheduler.
@@ -335,11 +351,15 @@ func (task *Task) PostUnmarshalTask(cfg *config.Config,
 	}
 
 	if task.requiresSSMSecret() {
-		task.initializeSSMSecretResource(credentialsManager, resourceFields)
+		task.initializeSSMSecretResource(cfg, credentialsManager, resourceFields)
 	}
 
 	if task.requiresASMSecret() {
-		task.initializeASMSecretResource(credentialsManager, resourceFields)
+		task.initializeASMSecretResource(cfg, credentialsManager, resourceFields)
+	}
+
+	if task.requiresVaultSecret() {
+		task.initializeVaultSecretResource(cfg, resourceFields)
 	}
 
 	task.initializeCredentialsEndpoint(credentialsManager)
@@ -728,8 +748,8 @@ func (task *Task) initializeContainersV3MetadataEndpoint(uuidProvider utils.UUID
 }
 
 // initializeContainersV4MetadataEndpoint generates an v4 endpoint id which we reuse the v3 container id
-// (they are the same) for each container, constructs the v4 metadata endpoint,
-// and injects it as an environment variable
+// (they are the same) for each container, constructs the v4 and v5 metadata endpoints,
+// and injects them as environment variables
 func (task *Task) initializeContainersV4MetadataEndpoint(uuidProvider utils.UUIDProvider) {
 	for _, container := range task.Containers {
 		v3EndpointID := container.GetV3EndpointID()
@@ -738,6 +758,7 @@ func (task *Task) initializeContainersV4MetadataEndpoint(uuidProvider utils.UUID
 		}
 
 		container.InjectV4MetadataEndpoint()
+		container.InjectV5MetadataEndpoint()
 	}
 }
 
@@ -789,10 +810,11 @@ func (task *Task) requiresSSMSecret() bool {
 }
 
 // initializeSSMSecretResource builds the resource dependency map for the SSM ssmsecret resource
-func (task *Task) initializeSSMSecretResource(credentialsManager credentials.Manager,
+func (task *Task) initializeSSMSecretResource(cfg *config.Config, credentialsManager credentials.Manager,
 	resourceFields *taskresource.ResourceFields) {
 	ssmSecretResource := ssmsecret.NewSSMSecretResource(task.Arn, task.getAllSSMSecretRequirements(),
-		task.ExecutionCredentialsID, credentialsManager, resourceFields.SSMClientCreator)
+		task.ExecutionCredentialsID, credentialsManager, resourceFields.SSMClientCreator,
+		task.getSecretFileDir(cfg, apicontainer.SecretProviderSSM, ssmsecret.ResourceName), getSecretFileOptions(cfg))
 	task.AddResource(ssmsecret.ResourceName, ssmSecretResource)
 
 	// for every container that needs ssm secret vending as env, it needs to wait all secrets got retrieved
@@ -857,10 +879,11 @@ func (task *Task) requiresASMSecret() bool {
 }
 
 // initializeASMSecretResource builds the resource dependency map for the asmsecret resource
-func (task *Task) initializeASMSecretResource(credentialsManager credentials.Manager,
+func (task *Task) initializeASMSecretResource(cfg *config.Config, credentialsManager credentials.Manager,
 	resourceFields *taskresource.ResourceFields) {
 	asmSecretResource := asmsecret.NewASMSecretResource(task.Arn, task.getAllASMSecretRequirements(),
-		task.ExecutionCredentialsID, credentialsManager, resourceFields.ASMClientCreator)
+		task.ExecutionCredentialsID, credentialsManager, resourceFields.ASMClientCreator,
+		task.getSecretFileDir(cfg, apicontainer.SecretProviderASM, asmsecret.ResourceName), getSecretFileOptions(cfg))
 	task.AddResource(asmsecret.ResourceName, asmSecretResource)
 
 	// for every container that needs asm secret vending as envvar, it needs to wait all secrets got retrieved
@@ -880,7 +903,8 @@ func (task *Task) initializeASMSecretResource(credentialsManager credentials.Man
 	}
 }
 
-// getAllASMSecretRequirements stores secrets in a task in a map
+// getAllASMSecretRequirements stores secrets in a task in a map. Secrets vended as files are
+// stored once per container path, so that the resource writes a file for each of them.
 func (task *Task) getAllASMSecretRequirements() map[string]apicontainer.Secret {
 	reqs := make(map[string]apicontainer.Secret)
 
@@ -888,6 +912,65 @@ func (task *Task) getAllASMSecretRequirements() map[string]apicontainer.Secret {
 		for _, secret := range container.Secrets {
 			if secret.Provider == apicontainer.SecretProviderASM {
 				secretKey := secret.GetSecretResourceCacheKey()
+				if secret.Type == apicontainer.SecretTypeMountPoint {
+					secretKey = secretFileRequirementKey(secret)
+				}
+				if _, ok := reqs[secretKey]; !ok {
+					reqs[secretKey] = secret
+				}
+			}
+		}
+	}
+	return reqs
+}
+
+// requiresVaultSecret returns true if at least one container in the task
+// needs to retrieve secret from HashiCorp Vault
+func (task *Task) requiresVaultSecret() bool {
+	for _, container := range task.Containers {
+		if container.ShouldCreateWithVaultSecret() {
+			return true
+		}
+	}
+	return false
+}
+
+// initializeVaultSecretResource builds the resource dependency map for the vaultsecret resource
+func (task *Task) initializeVaultSecretResource(cfg *config.Config, resourceFields *taskresource.ResourceFields) {
+	vaultSecretResource := vaultsecret.NewVaultSecretResource(task.Arn, task.getAllVaultSecretRequirements(),
+		resourceFields.VaultClient, task.getSecretFileDir(cfg, apicontainer.SecretProviderVault, vaultsecret.ResourceName),
+		getSecretFileOptions(cfg))
+	task.AddResource(vaultsecret.ResourceName, vaultSecretResource)
+
+	// for every container that needs vault secret vending as envvar, it needs to wait all secrets got retrieved
+	for _, container := range task.Containers {
+		if container.ShouldCreateWithVaultSecret() {
+			container.BuildResourceDependency(vaultSecretResource.GetName(),
+				resourcestatus.ResourceStatus(vaultsecret.VaultSecretCreated),
+				apicontainerstatus.ContainerCreated)
+		}
+
+		// Firelens container needs to depends on secret if other containers use secret log options.
+		if container.GetFirelensConfig() != nil && task.firelensDependsOnSecretResource(apicontainer.SecretProviderVault) {
+			container.BuildResourceDependency(vaultSecretResource.GetName(),
+				resourcestatus.ResourceStatus(vaultsecret.VaultSecretCreated),
+				apicontainerstatus.ContainerCreated)
+		}
+	}
+}
+
+// getAllVaultSecretRequirements stores secrets in a task in a map. Secrets vended as files are
+// stored once per container path, so that the resource writes a file for each of them.
+func (task *Task) getAllVaultSecretRequirements() map[string]apicontainer.Secret {
+	reqs := make(map[string]apicontainer.Secret)
+
+	for _, container := range task.Containers {
+		for _, secret := range container.Secrets {
+			if secret.Provider == apicontainer.SecretProviderVault {
+				secretKey := secret.GetSecretResourceCacheKey()
+				if secret.Type == apicontainer.SecretTypeMountPoint {
+					secretKey = secretFileRequirementKey(secret)
+				}
 				if _, ok := reqs[secretKey]; !ok {
 					reqs[secretKey] = secret
 				}
@@ -897,6 +980,36 @@ func (task *Task) getAllASMSecretRequirements() map[string]apicontainer.Secret {
 	return reqs
 }
 
+// secretFileRequirementKey returns the key of a secret vended as a file in the secrets required
+// by a secret resource, which differs from the one of the same secret vended otherwise or at another
+// container path
+func secretFileRequirementKey(secret apicontainer.Secret) string {
+	return secret.GetSecretResourceCacheKey() + "_" + secret.ContainerPath
+}
+
+// getSecretFileDir returns the directory a secret resource writes the secrets of the provider
+// vended as files to, or an empty string if no container gets such secrets as files
+func (task *Task) getSecretFileDir(cfg *config.Config, provider string, resourceName string) string {
+	for _, container := range task.Containers {
+		for _, secret := range container.Secrets {
+			if secret.Provider == provider && secret.Type == apicontainer.SecretTypeMountPoint {
+				return secretfile.Dir(cfg.DataDir, resourceName, task.Arn)
+			}
+		}
+	}
+	return ""
+}
+
+// getSecretFileOptions returns the mode and owner of the files the secret resources write
+// the values of the secrets vended as files to
+func getSecretFileOptions(cfg *config.Config) secretfile.Options {
+	return secretfile.Options{
+		Mode: cfg.SecretFileMode,
+		UID:  cfg.SecretFileUID,
+		GID:  cfg.SecretFileGID,
+	}
+}
+
 // GetFirelensContainer returns the firelens container in the task, if there is one.
 func (task *Task) GetFirelensContainer() *apicontainer.Container {
 	for _, container := range task.Containers {
@@ -911,19 +1024,53 @@ func (task *Task) GetFirelensContainer() *apicontainer.Container {
 // firelens container.
 func (task *Task) initializeFirelensResource(config *config.Config, resourceFields *taskresource.ResourceFields,
 	firelensContainer *apicontainer.Container, credentialsManager credentials.Manager) error {
+	firelensResource, container, err := task.newFirelensResource(config, resourceFields, firelensContainer,
+		credentialsManager)
+	if err != nil {
+		return err
+	}
+	task.AddResource(firelens.ResourceName, firelensResource)
+	container.BuildResourceDependency(firelensResource.GetName(), resourcestatus.ResourceCreated,
+		apicontainerstatus.ContainerCreated)
+	return nil
+}
+
+// RenderFirelensConfig returns the config that the firelens resource of the task generates for the firelens
+// container, without adding the resource to the task. The EC2 instance ID isn't known, so it isn't part of the
+// config.
+func (task *Task) RenderFirelensConfig(config *config.Config) ([]byte, error) {
+	firelensContainer := task.GetFirelensContainer()
+	if firelensContainer == nil {
+		return nil, errors.New("the task has no firelens container")
+	}
+
+	resourceFields := &taskresource.ResourceFields{
+		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{},
+	}
+	firelensResource, _, err := task.newFirelensResource(config, resourceFields, firelensContainer, nil)
+	if err != nil {
+		return nil, err
+	}
+	return firelensResource.RenderConfig()
+}
+
+// newFirelensResource creates the firelens task resource, and returns it along with the firelens container.
+func (task *Task) newFirelensResource(config *config.Config, resourceFields *taskresource.ResourceFields,
+	firelensContainer *apicontainer.Container,
+	credentialsManager credentials.Manager) (*firelens.FirelensResource, *apicontainer.Container, error) {
 	if firelensContainer.GetFirelensConfig() == nil {
-		return errors.New("firelens container config doesn't exist")
+		return nil, nil, errors.New("firelens container config doesn't exist")
 	}
 
 	containerToLogOptions := make(map[string]map[string]string)
 	// Collect plain text log options.
 	if err := task.collectFirelensLogOptions(containerToLogOptions); err != nil {
-		return errors.Wrap(err, "unable to initialize firelens resource")
+		return nil, nil, errors.Wrap(err, "unable to initialize firelens resource")
 	}
 
 	// Collect secret log options.
 	if err := task.collectFirelensLogEnvOptions(containerToLogOptions, firelensContainer.FirelensConfig.Type); err != nil {
-		return errors.Wrap(err, "unable to initialize firelens resource")
+		return nil, nil, errors.Wrap(err, "unable to initialize firelens resource")
 	}
 
 	for _, container := range task.Containers {
@@ -946,16 +1093,13 @@ func (task *Task) initializeFirelensResource(config *config.Config, resourceFiel
 				ec2InstanceID, config.DataDir, firelensConfig.Type, config.AWSRegion, networkMode, firelensConfig.Options, containerToLogOptions,
 				credentialsManager, task.ExecutionCredentialsID)
 			if err != nil {
-				return errors.Wrap(err, "unable to initialize firelens resource")
+				return nil, nil, errors.Wrap(err, "unable to initialize firelens resource")
 			}
-			task.AddResource(firelens.ResourceName, firelensResource)
-			container.BuildResourceDependency(firelensResource.GetName(), resourcestatus.ResourceCreated,
-				apicontainerstatus.ContainerCreated)
-			return nil
+			return firelensResource, container, nil
 		}
 	}
 
-	return errors.New("unable to initialize firelens resource because there's no firelens container")
+	return nil, nil, errors.New("unable to initialize firelens resource because there's no firelens container")
 }
 
 // addFirelensContainerDependency adds a START dependency between each container using awsfirelens log driver
@@ -1104,6 +1248,66 @@ func (task *Task) AddFirelensContainerBindMounts(firelensConfig *apicontainer.Fi
 	return nil
 }
 
+// AddSecretFileBindMounts adds the read-only bind mounts of the directories holding the files of the
+// container's secrets vended as files to the container's host config. The files are written to the
+// tmpfs of the secret resources, in a directory for each directory of their container paths, which
+// is mounted at that directory. Mounting the directories rather than the files lets the files be
+// replaced atomically by renaming new ones over them. A container directory can therefore only hold
+// the secret files of a single provider, and is hidden by the mount.
+func (task *Task) AddSecretFileBindMounts(container *apicontainer.Container, hostConfig *dockercontainer.HostConfig,
+	config *config.Config) *apierrors.HostConfigError {
+	taskID, err := task.GetID()
+	if err != nil {
+		return &apierrors.HostConfigError{Msg: err.Error()}
+	}
+
+	// resourceNames are the names of the secret resources whose directories are mounted, by container directory
+	resourceNames := make(map[string]string)
+	var containerDirs []string
+	for _, secret := range container.Secrets {
+		if secret.Type != apicontainer.SecretTypeMountPoint {
+			continue
+		}
+
+		var resourceName string
+		switch secret.Provider {
+		case apicontainer.SecretProviderSSM:
+			resourceName = ssmsecret.ResourceName
+		case apicontainer.SecretProviderASM:
+			resourceName = asmsecret.ResourceName
+		case apicontainer.SecretProviderVault:
+			resourceName = vaultsecret.ResourceName
+		default:
+			return &apierrors.HostConfigError{Msg: fmt.Sprintf("secret %s has invalid provider %s",
+				secret.Name, secret.Provider)}
+		}
+		if !filepath.IsAbs(secret.ContainerPath) {
+			return &apierrors.HostConfigError{Msg: fmt.Sprintf("secret %s has invalid container path %s: not an absolute path",
+				secret.Name, secret.ContainerPath)}
+		}
+		containerDir := filepath.Dir(filepath.Clean(secret.ContainerPath))
+		if containerDir == "/" {
+			return &apierrors.HostConfigError{Msg: fmt.Sprintf("secret %s has invalid container path %s: not in a directory",
+				secret.Name, secret.ContainerPath)}
+		}
+
+		mountedResourceName, ok := resourceNames[containerDir]
+		if !ok {
+			resourceNames[containerDir] = resourceName
+			containerDirs = append(containerDirs, containerDir)
+		} else if mountedResourceName != resourceName {
+			return &apierrors.HostConfigError{Msg: fmt.Sprintf("secret %s has invalid container path %s: %s holds secrets of another provider",
+				secret.Name, secret.ContainerPath, containerDir)}
+		}
+	}
+
+	for _, containerDir := range containerDirs {
+		hostConfig.Binds = append(hostConfig.Binds, fmt.Sprintf(secretFileBindFormat, config.DataDirOnHost,
+			resourceNames[containerDir], taskID, secretfile.MountDir(containerDir), containerDir))
+	}
+	return nil
+}
+
 // BuildCNIConfig builds a list of CNI network configurations for the task.
 // If includeIPAMConfig is set to true, the list also includes the bridge IPAM configuration.
 func (task *Task) BuildCNIConfig(includeIPAMConfig bool, cniConfig *ecscni.Config) (*ecscni.Config, error) {
@@ -2350,6 +2554,7 @@ func (task *Task) getSSMSecretsResource() ([]taskresource.TaskResource, bool) {
 func (task *Task) PopulateSecrets(hostConfig *dockercontainer.HostConfig, container *apicontainer.Container) *apierrors.DockerClientConfigError {
 	var ssmRes *ssmsecret.SSMSecretResource
 	var asmRes *asmsecret.ASMSecretResource
+	var vaultRes *vaultsecret.VaultSecretResource
 
 	if container.ShouldCreateWithSSMSecret() {
 		resource, ok := task.getSSMSecretsResource()
@@ -2367,12 +2572,20 @@ func (task *Task) PopulateSecrets(hostConfig *dockercontainer.HostConfig, contai
 		asmRes = resource[0].(*asmsecret.ASMSecretResource)
 	}
 
-	populateContainerSecrets(hostConfig, container, ssmRes, asmRes)
+	if container.ShouldCreateWithVaultSecret() {
+		resource, ok := task.getVaultSecretsResource()
+		if !ok {
+			return &apierrors.DockerClientConfigError{Msg: "task secret data: unable to fetch Vault Secrets resource"}
+		}
+		vaultRes = resource[0].(*vaultsecret.VaultSecretResource)
+	}
+
+	populateContainerSecrets(hostConfig, container, ssmRes, asmRes, vaultRes)
 	return nil
 }
 
 func populateContainerSecrets(hostConfig *dockercontainer.HostConfig, container *apicontainer.Container,
-	ssmRes *ssmsecret.SSMSecretResource, asmRes *asmsecret.ASMSecretResource) {
+	ssmRes *ssmsecret.SSMSecretResource, asmRes *asmsecret.ASMSecretResource, vaultRes *vaultsecret.VaultSecretResource) {
 	envVars := make(map[string]string)
 
 	logDriverTokenName := ""
@@ -2395,11 +2608,23 @@ func populateContainerSecrets(hostConfig *dockercontainer.HostConfig, container
 			}
 		}
 
+		if secret.Provider == apicontainer.SecretProviderVault {
+			k := secret.GetSecretResourceCacheKey()
+			if secretValue, ok := vaultRes.GetCachedSecretValue(k); ok {
+				secretVal = secretValue
+			}
+		}
+
 		if secret.Type == apicontainer.SecretTypeEnv {
 			envVars[secret.Name] = secretVal
 			continue
 		}
 
+		// Secrets vended as files are bind mounted from the tmpfs of the secret resource instead
+		if secret.Type == apicontainer.SecretTypeMountPoint {
+			continue
+		}
+
 		if secret.Target == apicontainer.SecretTargetLogDriver {
 			// Log driver secrets for container using awsfirelens log driver won't be saved in log config and passed to
 			// Docker here. They will only be used to configure the firelens container.
@@ -2432,6 +2657,7 @@ func (task *Task) PopulateSecretLogOptionsToFirelensContainer(firelensContainer
 
 	var ssmRes *ssmsecret.SSMSecretResource
 	var asmRes *asmsecret.ASMSecretResource
+	var vaultRes *vaultsecret.VaultSecretResource
 
 	resource, ok := task.getSSMSecretsResource()
 	if ok {
@@ -2443,12 +2669,17 @@ func (task *Task) PopulateSecretLogOptionsToFirelensContainer(firelensContainer
 		asmRes = resource[0].(*asmsecret.ASMSecretResource)
 	}
 
+	resource, ok = task.getVaultSecretsResource()
+	if ok {
+		vaultRes = resource[0].(*vaultsecret.VaultSecretResource)
+	}
+
 	for _, container := range task.Containers {
 		if container.GetLogDriver() != firelensDriverName {
 			continue
 		}
 
-		logDriverSecretData, err := collectLogDriverSecretData(container.Secrets, ssmRes, asmRes)
+		logDriverSecretData, err := collectLogDriverSecretData(container.Secrets, ssmRes, asmRes, vaultRes)
 		if err != nil {
 			return &apierrors.DockerClientConfigError{
 				Msg: fmt.Sprintf("unable to generate config to create firelens container: %v", err),
@@ -2473,7 +2704,7 @@ func (task *Task) PopulateSecretLogOptionsToFirelensContainer(firelensContainer
 
 // collectLogDriverSecretData collects all the secret values for log driver secrets.
 func collectLogDriverSecretData(secrets []apicontainer.Secret, ssmRes *ssmsecret.SSMSecretResource,
-	asmRes *asmsecret.ASMSecretResource) (map[string]string, error) {
+	asmRes *asmsecret.ASMSecretResource, vaultRes *vaultsecret.VaultSecretResource) (map[string]string, error) {
 	secretData := make(map[string]string)
 	for _, secret := range secrets {
 		if secret.Target != apicontainer.SecretTargetLogDriver {
@@ -2498,6 +2729,14 @@ func collectLogDriverSecretData(secrets []apicontainer.Secret, ssmRes *ssmsecret
 			if secretValue, ok := asmRes.GetCachedSecretValue(cacheKey); ok {
 				secretVal = secretValue
 			}
+		} else if secret.Provider == apicontainer.SecretProviderVault {
+			if vaultRes == nil {
+				return nil, errors.Errorf("missing secret value for secret %s", secret.Name)
+			}
+
+			if secretValue, ok := vaultRes.GetCachedSecretValue(cacheKey); ok {
+				secretVal = secretValue
+			}
 		}
 
 		secretData[secret.Name] = secretVal
@@ -2515,6 +2754,51 @@ func (task *Task) getASMSecretsResource() ([]taskresource.TaskResource, bool) {
 	return res, ok
 }
 
+// getVaultSecretsResource retrieves vaultsecret resource from resource map
+func (task *Task) getVaultSecretsResource() ([]taskresource.TaskResource, bool) {
+	task.lock.RLock()
+	defer task.lock.RUnlock()
+
+	res, ok := task.ResourcesMapUnsafe[vaultsecret.ResourceName]
+	return res, ok
+}
+
+// RefreshSecretFiles retrieves the values of the secrets vended as files again, and rewrites the
+// files of those whose value changed. The secrets whose value changed are returned.
+func (task *Task) RefreshSecretFiles() ([]apicontainer.Secret, error) {
+	var changed []apicontainer.Secret
+	var errs []error
+
+	if resource, ok := task.getSSMSecretsResource(); ok {
+		ssmChanged, err := resource[0].(*ssmsecret.SSMSecretResource).RefreshSecretFiles()
+		changed = append(changed, ssmChanged...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if resource, ok := task.getASMSecretsResource(); ok {
+		asmChanged, err := resource[0].(*asmsecret.ASMSecretResource).RefreshSecretFiles()
+		changed = append(changed, asmChanged...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if resource, ok := task.getVaultSecretsResource(); ok {
+		vaultChanged, err := resource[0].(*vaultsecret.VaultSecretResource).RefreshSecretFiles()
+		changed = append(changed, vaultChanged...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return changed, apierrors.NewMultiError(errs...)
+	}
+	return changed, nil
+}
+
 // InitializeResources initializes the required field in the task on agent restart
 // Some of the fields in task isn't saved in the agent state file, agent needs
 // to initialize these fields before processing the task, eg: docker client in resource
@@ -2602,7 +2886,8 @@ func (task *Task) initializeEnvfilesResource(config *config.Config, credentialsM
 	for _, container := range task.Containers {
 		if container.ShouldCreateWithEnvFiles() {
 			envfileResource, err := envFiles.NewEnvironmentFileResource(config.Cluster, task.Arn, config.AWSRegion, config.DataDir,
-				container.Name, container.EnvironmentFiles, credentialsManager, task.ExecutionCredentialsID)
+				container.Name, container.EnvironmentFiles, config.EnvironmentFileAllowedDirs, credentialsManager,
+				task.ExecutionCredentialsID)
 			if err != nil {
 				return errors.Wrapf(err, "unable to initialize envfiles resource for container %s", container.Name)
 			}
@@ -2634,6 +2919,16 @@ func (task *Task) getEnvfilesResource(containerName string) (taskresource.TaskRe
 	return nil, false
 }
 
+// GetResolvedEnvironmentFiles returns the environment files of the container
+// along with the names of the variables read from them
+func (task *Task) GetResolvedEnvironmentFiles(containerName string) []envFiles.ResolvedEnvironmentFile {
+	resource, ok := task.getEnvfilesResource(containerName)
+	if !ok {
+		return nil
+	}
+	return resource.(*envFiles.EnvironmentFileResource).GetResolvedEnvironmentFiles()
+}
+
 // MergeEnvVarsFromEnvfiles should be called when creating a container -
 // this method reads the environment variables specified in the environment files
 // that was downloaded to disk and merges it with existing environment variables
@@ -24,6 +24,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	resourcetype "github.com/aws/amazon-ecs-agent/agent/taskresource/types"
 	"github.com/cihub/seelog"
@@ -49,6 +50,15 @@ func (task *Task) adjustForPlatform(cfg *config.Config) {
 	task.lock.Lock()
 	defer task.lock.Unlock()
 	task.MemoryCPULimitsEnabled = cfg.TaskCPUMemLimit.Enabled()
+	if task.PidsLimit == 0 {
+		task.PidsLimit = cfg.TaskPidsLimit
+	}
+	if task.BlockIOWeight == 0 {
+		task.BlockIOWeight = cfg.TaskBlockIOWeight
+	}
+	if len(task.BlockIODeviceLimits) == 0 {
+		task.BlockIODeviceLimits = cfg.TaskBlockIODeviceLimits
+	}
 }
 
 func (task *Task) initializeCgroupResourceSpec(cgroupPath string, cGroupCPUPeriod time.Duration, resourceFields *taskresource.ResourceFields) error {
@@ -111,6 +121,20 @@ func (task *Task) BuildLinuxResourceSpec(cGroupCPUPeriod time.Duration) (specs.L
 		linuxResourceSpec.Memory = &linuxMemorySpec
 	}
 
+	// NOTE: task PID and block IO limits are optional
+	if task.PidsLimit > 0 {
+		linuxResourceSpec.Pids = &specs.LinuxPids{
+			Limit: task.PidsLimit,
+		}
+	}
+	if task.BlockIOWeight != 0 || len(task.BlockIODeviceLimits) > 0 {
+		linuxBlockIOSpec, err := task.buildLinuxBlockIOSpec()
+		if err != nil {
+			return specs.LinuxResources{}, err
+		}
+		linuxResourceSpec.BlockIO = &linuxBlockIOSpec
+	}
+
 	return linuxResourceSpec, nil
 }
 
@@ -183,6 +207,62 @@ func (task *Task) buildLinuxMemorySpec() (specs.LinuxMemory, error) {
 	}, nil
 }
 
+// buildLinuxBlockIOSpec validates and builds the task block IO spec
+func (task *Task) buildLinuxBlockIOSpec() (specs.LinuxBlockIO, error) {
+	linuxBlockIOSpec := specs.LinuxBlockIO{}
+	if task.BlockIOWeight != 0 {
+		if task.BlockIOWeight < config.MinimumBlockIOWeight || task.BlockIOWeight > config.MaximumBlockIOWeight {
+			return specs.LinuxBlockIO{},
+				errors.Errorf("task block IO spec builder: unsupported block IO weight, requested=%d, supported=%d-%d",
+					task.BlockIOWeight, config.MinimumBlockIOWeight, config.MaximumBlockIOWeight)
+		}
+		weight := task.BlockIOWeight
+		linuxBlockIOSpec.Weight = &weight
+	}
+
+	for _, deviceLimit := range task.BlockIODeviceLimits {
+		major, minor, err := deviceLimit.MajorMinor()
+		if err != nil {
+			return specs.LinuxBlockIO{}, errors.Wrap(err, "task block IO spec builder")
+		}
+		throttle := func(rate uint64) []specs.LinuxThrottleDevice {
+			if rate == 0 {
+				return nil
+			}
+			// The device numbers are fields of an unexported embedded struct,
+			// which can't be set in the literal
+			device := specs.LinuxThrottleDevice{Rate: rate}
+			device.Major = major
+			device.Minor = minor
+			return []specs.LinuxThrottleDevice{device}
+		}
+		linuxBlockIOSpec.ThrottleReadBpsDevice = append(linuxBlockIOSpec.ThrottleReadBpsDevice,
+			throttle(deviceLimit.ReadBps)...)
+		linuxBlockIOSpec.ThrottleWriteBpsDevice = append(linuxBlockIOSpec.ThrottleWriteBpsDevice,
+			throttle(deviceLimit.WriteBps)...)
+		linuxBlockIOSpec.ThrottleReadIOPSDevice = append(linuxBlockIOSpec.ThrottleReadIOPSDevice,
+			throttle(deviceLimit.ReadIOPS)...)
+		linuxBlockIOSpec.ThrottleWriteIOPSDevice = append(linuxBlockIOSpec.ThrottleWriteIOPSDevice,
+			throttle(deviceLimit.WriteIOPS)...)
+	}
+	return linuxBlockIOSpec, nil
+}
+
+// GetCgroupUsage returns the current resource usage of the task cgroup
+func (task *Task) GetCgroupUsage() (*control.Usage, error) {
+	task.lock.RLock()
+	resources, ok := task.ResourcesMapUnsafe[resourcetype.CgroupKey]
+	task.lock.RUnlock()
+	if !ok || len(resources) == 0 {
+		return nil, errors.Errorf("task %s has no cgroup", task.Arn)
+	}
+	cgroupResource, ok := resources[0].(*cgroup.CgroupResource)
+	if !ok {
+		return nil, errors.Errorf("task %s has no cgroup", task.Arn)
+	}
+	return cgroupResource.GetUsage()
+}
+
 // platformHostConfigOverride to override platform specific feature sets
 func (task *Task) platformHostConfigOverride(hostConfig *dockercontainer.HostConfig) error {
 	// Override cgroup parent
@@ -362,6 +362,72 @@ func TestBuildLinuxResourceSpecInvalidMem(t *testing.T) {
 	assert.EqualValues(t, expectedLinuxResourceSpec, linuxResourceSpec)
 }
 
+// TestBuildLinuxResourceSpecPidsBlockIO validates the PID and block IO limits
+// of the linux resource spec
+func TestBuildLinuxResourceSpecPidsBlockIO(t *testing.T) {
+	task := &Task{
+		Arn:           validTaskArn,
+		PidsLimit:     512,
+		BlockIOWeight: 200,
+		BlockIODeviceLimits: []config.BlockIODeviceLimit{
+			{Device: "259:0", ReadBps: 1048576, WriteIOPS: 100},
+		},
+		Containers: []*apicontainer.Container{
+			{
+				Name: "C1",
+			},
+		},
+	}
+
+	linuxResourceSpec, err := task.BuildLinuxResourceSpec(defaultCPUPeriod)
+	require.NoError(t, err)
+
+	assert.Equal(t, &specs.LinuxPids{Limit: 512}, linuxResourceSpec.Pids)
+	require.NotNil(t, linuxResourceSpec.BlockIO)
+	assert.Equal(t, uint16(200), *linuxResourceSpec.BlockIO.Weight)
+	require.Len(t, linuxResourceSpec.BlockIO.ThrottleReadBpsDevice, 1)
+	assert.Equal(t, int64(259), linuxResourceSpec.BlockIO.ThrottleReadBpsDevice[0].Major)
+	assert.Equal(t, int64(0), linuxResourceSpec.BlockIO.ThrottleReadBpsDevice[0].Minor)
+	assert.Equal(t, uint64(1048576), linuxResourceSpec.BlockIO.ThrottleReadBpsDevice[0].Rate)
+	require.Len(t, linuxResourceSpec.BlockIO.ThrottleWriteIOPSDevice, 1)
+	assert.Equal(t, uint64(100), linuxResourceSpec.BlockIO.ThrottleWriteIOPSDevice[0].Rate)
+	assert.Empty(t, linuxResourceSpec.BlockIO.ThrottleWriteBpsDevice)
+	assert.Empty(t, linuxResourceSpec.BlockIO.ThrottleReadIOPSDevice)
+}
+
+// TestBuildLinuxResourceSpecInvalidBlockIO validates the block IO limits of
+// the linux resource spec
+func TestBuildLinuxResourceSpecInvalidBlockIO(t *testing.T) {
+	for _, task := range []*Task{
+		{Arn: validTaskArn, BlockIOWeight: 2000},
+		{Arn: validTaskArn, BlockIODeviceLimits: []config.BlockIODeviceLimit{{Device: "sda", ReadBps: 1}}},
+	} {
+		_, err := task.BuildLinuxResourceSpec(defaultCPUPeriod)
+		assert.Error(t, err)
+	}
+}
+
+// TestAdjustForPlatformTaskLimits validates that the limits of the task
+// default to the ones of the config
+func TestAdjustForPlatformTaskLimits(t *testing.T) {
+	cfg := &config.Config{
+		TaskPidsLimit:           1024,
+		TaskBlockIOWeight:       500,
+		TaskBlockIODeviceLimits: []config.BlockIODeviceLimit{{Device: "259:0", WriteBps: 1048576}},
+	}
+
+	task := &Task{Arn: validTaskArn}
+	task.adjustForPlatform(cfg)
+	assert.Equal(t, int64(1024), task.PidsLimit)
+	assert.Equal(t, uint16(500), task.BlockIOWeight)
+	assert.Equal(t, cfg.TaskBlockIODeviceLimits, task.BlockIODeviceLimits)
+
+	task = &Task{Arn: validTaskArn, PidsLimit: 64, BlockIOWeight: 100}
+	task.adjustForPlatform(cfg)
+	assert.Equal(t, int64(64), task.PidsLimit)
+	assert.Equal(t, uint16(100), task.BlockIOWeight)
+}
+
 // TestOverrideCgroupParent validates the cgroup parent override
 func TestOverrideCgroupParentHappyPath(t *testing.T) {
 	task := &Task{
@@ -898,6 +964,50 @@ func TestInitializeFirelensResourceWithExternalConfig(t *testing.T) {
 	}
 }
 
+func TestRenderFirelensConfig(t *testing.T) {
+	task := getFirelensTask(t)
+	rawHostConfig, err := json.Marshal(&dockercontainer.HostConfig{
+		LogConfig: dockercontainer.LogConfig{
+			Type: firelensDriverName,
+			Config: map[string]string{
+				"@type": "stdout",
+			},
+		},
+	})
+	require.NoError(t, err)
+	task.Containers[0].DockerConfig.HostConfig = strptr(string(rawHostConfig))
+	cfg := &config.Config{
+		DataDir:   testDataDir,
+		Cluster:   testCluster,
+		AWSRegion: testRegion,
+	}
+
+	rendered, err := task.RenderFirelensConfig(cfg)
+	require.NoError(t, err)
+	assert.Contains(t, string(rendered), "<match logsender-firelens**>\n    @type stdout\n")
+	assert.Contains(t, string(rendered), "ecs_cluster "+testCluster)
+	assert.NotContains(t, string(rendered), "ec2_instance_id")
+	assert.Empty(t, task.GetResources())
+}
+
+func TestRenderFirelensConfigErrors(t *testing.T) {
+	cfg := &config.Config{
+		DataDir:   testDataDir,
+		Cluster:   testCluster,
+		AWSRegion: testRegion,
+	}
+
+	task := getFirelensTask(t)
+	task.Containers[1].FirelensConfig = nil
+	_, err := task.RenderFirelensConfig(cfg)
+	assert.Error(t, err)
+
+	// The log options of the container have no @type
+	task = getFirelensTask(t)
+	_, err = task.RenderFirelensConfig(cfg)
+	assert.Error(t, err)
+}
+
 func TestCollectFirelensLogOptions(t *testing.T) {
 	task := getFirelensTask(t)
 
@@ -1132,7 +1242,7 @@ func TestCollectLogDriverSecretData(t *testing.T) {
 		},
 	}
 
-	secretData, err := collectLogDriverSecretData(secrets, ssmRes, asmRes)
+	secretData, err := collectLogDriverSecretData(secrets, ssmRes, asmRes, nil)
 	assert.NoError(t, err)
 	assert.Len(t, secretData, 2)
 	assert.Equal(t, "secret-val", secretData["secret-name"])
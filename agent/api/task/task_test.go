@@ -49,7 +49,10 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmsecret"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/envFiles"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfile"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/vaultsecret"
+	mock_vault "github.com/aws/amazon-ecs-agent/agent/vault/mocks"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/docker/docker/api/types"
 	dockercontainer "github.com/docker/docker/api/types/container"
@@ -823,6 +826,8 @@ func TestInitializeContainersV4MetadataEndpoint(t *testing.T) {
 	assert.Equal(t, container.GetV3EndpointID(), "new-uuid")
 	assert.Equal(t, container.Environment[apicontainer.MetadataURIEnvVarNameV4],
 		fmt.Sprintf(apicontainer.MetadataURIFormatV4, "new-uuid"))
+	assert.Equal(t, container.Environment[apicontainer.MetadataURIEnvVarNameV5],
+		fmt.Sprintf(apicontainer.MetadataURIFormatV5, "new-uuid"))
 }
 
 func TestPostUnmarshalTaskWithLocalVolumes(t *testing.T) {
@@ -2454,7 +2459,7 @@ func TestInitializeAndGetSSMSecretResource(t *testing.T) {
 		},
 	}
 
-	task.initializeSSMSecretResource(credentialsManager, resFields)
+	task.initializeSSMSecretResource(&config.Config{}, credentialsManager, resFields)
 
 	resourceDep := apicontainer.ResourceDependency{
 		Name:           ssmsecret.ResourceName,
@@ -2670,7 +2675,7 @@ func TestInitializeAndGetASMSecretResource(t *testing.T) {
 		},
 	}
 
-	task.initializeASMSecretResource(credentialsManager, resFields)
+	task.initializeASMSecretResource(&config.Config{}, credentialsManager, resFields)
 
 	resourceDep := apicontainer.ResourceDependency{
 		Name:           asmsecret.ResourceName,
@@ -2749,6 +2754,243 @@ func TestPopulateSecrets(t *testing.T) {
 	assert.Equal(t, "option", hostConfig.LogConfig.Config["splunk-option"])
 }
 
+func TestPopulateSecretsAsFiles(t *testing.T) {
+	secret := apicontainer.Secret{
+		Provider:      "ssm",
+		Name:          "secret1",
+		Region:        "us-west-2",
+		Type:          apicontainer.SecretTypeMountPoint,
+		ContainerPath: "/run/secrets/secret1",
+		ValueFrom:     "/test/secretName",
+	}
+	container := &apicontainer.Container{
+		Name:    "myName",
+		Image:   "image:tag",
+		Secrets: []apicontainer.Secret{secret},
+	}
+	task := &Task{
+		Arn:                "arn:aws:ecs:us-west-2:123456789012:task/cluster/task-id",
+		ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+		Containers:         []*apicontainer.Container{container},
+	}
+	ssmRes := &ssmsecret.SSMSecretResource{}
+	ssmRes.SetCachedSecretValue(secretKeyWest1, "secretValue1")
+	task.AddResource(ssmsecret.ResourceName, ssmRes)
+
+	hostConfig := &dockercontainer.HostConfig{}
+	task.PopulateSecrets(hostConfig, container)
+	assert.NotContains(t, container.Environment, "secret1")
+
+	cfg := &config.Config{DataDirOnHost: "/var/lib/ecs"}
+	require.Nil(t, task.AddSecretFileBindMounts(container, hostConfig, cfg))
+	assert.Equal(t, []string{"/var/lib/ecs/data/ssmsecret/task-id/" + secretfile.MountDir("/run/secrets") + ":/run/secrets:ro"},
+		hostConfig.Binds)
+}
+
+func TestAddSecretFileBindMountsRelativeContainerPath(t *testing.T) {
+	container := &apicontainer.Container{
+		Name: "myName",
+		Secrets: []apicontainer.Secret{{
+			Provider:      "asm",
+			Name:          "secret1",
+			Type:          apicontainer.SecretTypeMountPoint,
+			ContainerPath: "secrets/secret1",
+		}},
+	}
+	task := &Task{
+		Arn:        "arn:aws:ecs:us-west-2:123456789012:task/cluster/task-id",
+		Containers: []*apicontainer.Container{container},
+	}
+
+	hostConfig := &dockercontainer.HostConfig{}
+	assert.NotNil(t, task.AddSecretFileBindMounts(container, hostConfig, &config.Config{}))
+	assert.Empty(t, hostConfig.Binds)
+}
+
+func TestAddSecretFileBindMountsMountsDirectoriesOnce(t *testing.T) {
+	container := &apicontainer.Container{
+		Name: "myName",
+		Secrets: []apicontainer.Secret{
+			{Provider: "ssm", Name: "secret1", Type: apicontainer.SecretTypeMountPoint, ContainerPath: "/run/secrets/secret1"},
+			{Provider: "ssm", Name: "secret2", Type: apicontainer.SecretTypeMountPoint, ContainerPath: "/run/secrets/secret2"},
+			{Provider: "asm", Name: "secret3", Type: apicontainer.SecretTypeMountPoint, ContainerPath: "/etc/app/secret3"},
+		},
+	}
+	task := &Task{
+		Arn:        "arn:aws:ecs:us-west-2:123456789012:task/cluster/task-id",
+		Containers: []*apicontainer.Container{container},
+	}
+
+	hostConfig := &dockercontainer.HostConfig{}
+	require.Nil(t, task.AddSecretFileBindMounts(container, hostConfig, &config.Config{DataDirOnHost: "/var/lib/ecs"}))
+	assert.Equal(t, []string{
+		"/var/lib/ecs/data/ssmsecret/task-id/" + secretfile.MountDir("/run/secrets") + ":/run/secrets:ro",
+		"/var/lib/ecs/data/asmsecret/task-id/" + secretfile.MountDir("/etc/app") + ":/etc/app:ro",
+	}, hostConfig.Binds)
+}
+
+func TestAddSecretFileBindMountsInvalidDirectory(t *testing.T) {
+	for name, secrets := range map[string][]apicontainer.Secret{
+		"root directory": {
+			{Provider: "ssm", Name: "secret1", Type: apicontainer.SecretTypeMountPoint, ContainerPath: "/secret1"},
+		},
+		"providers sharing a directory": {
+			{Provider: "ssm", Name: "secret1", Type: apicontainer.SecretTypeMountPoint, ContainerPath: "/run/secrets/secret1"},
+			{Provider: "asm", Name: "secret2", Type: apicontainer.SecretTypeMountPoint, ContainerPath: "/run/secrets/secret2"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			container := &apicontainer.Container{Name: "myName", Secrets: secrets}
+			task := &Task{
+				Arn:        "arn:aws:ecs:us-west-2:123456789012:task/cluster/task-id",
+				Containers: []*apicontainer.Container{container},
+			}
+			assert.NotNil(t, task.AddSecretFileBindMounts(container, &dockercontainer.HostConfig{}, &config.Config{}))
+		})
+	}
+}
+
+func TestGetAllASMSecretRequirementsSecretFiles(t *testing.T) {
+	envSecret := apicontainer.Secret{
+		Provider:  "asm",
+		Name:      "secret1",
+		Region:    "us-west-2",
+		Type:      apicontainer.SecretTypeEnv,
+		ValueFrom: "arn:aws:secretsmanager:us-west-2:11111:secret:/test/secretName",
+	}
+	fileSecret := envSecret
+	fileSecret.Type = apicontainer.SecretTypeMountPoint
+	fileSecret.ContainerPath = "/run/secrets/secret1"
+	otherFileSecret := fileSecret
+	otherFileSecret.ContainerPath = "/etc/app/secret1"
+	task := &Task{
+		Containers: []*apicontainer.Container{
+			{Name: "env", Secrets: []apicontainer.Secret{envSecret}},
+			{Name: "file", Secrets: []apicontainer.Secret{fileSecret}},
+			{Name: "other", Secrets: []apicontainer.Secret{otherFileSecret, fileSecret}},
+		},
+	}
+
+	// Each container path of the secret gets its own file
+	reqs := task.getAllASMSecretRequirements()
+	assert.Equal(t, map[string]apicontainer.Secret{
+		envSecret.GetSecretResourceCacheKey():     envSecret,
+		secretFileRequirementKey(fileSecret):      fileSecret,
+		secretFileRequirementKey(otherFileSecret): otherFileSecret,
+	}, reqs)
+}
+
+func TestInitializeAndGetVaultSecretResource(t *testing.T) {
+	secret := apicontainer.Secret{
+		Provider:  "vault",
+		Name:      "secret",
+		Type:      apicontainer.SecretTypeEnv,
+		ValueFrom: "secret/data/db#password",
+	}
+	logDriverSecret := apicontainer.Secret{
+		Provider:  "vault",
+		Name:      "Splunk-Token",
+		Target:    apicontainer.SecretTargetLogDriver,
+		ValueFrom: "secret/data/splunk#token",
+	}
+	container := &apicontainer.Container{
+		Name:                      "myName",
+		Image:                     "image:tag",
+		Secrets:                   []apicontainer.Secret{secret, logDriverSecret},
+		DockerConfig:              apicontainer.DockerConfig{HostConfig: strptr(`{"LogConfig":{"Type":"awsfirelens"}}`)},
+		TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+	}
+	firelensContainer := &apicontainer.Container{
+		Name:                      "firelens",
+		Image:                     "image:tag",
+		FirelensConfig:            &apicontainer.FirelensConfig{Type: "fluentbit"},
+		TransitionDependenciesMap: make(map[apicontainerstatus.ContainerStatus]apicontainer.TransitionDependencySet),
+	}
+	task := &Task{
+		Arn:                "test",
+		ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+		Containers:         []*apicontainer.Container{container, firelensContainer},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vaultClient := mock_vault.NewMockClient(ctrl)
+	resFields := &taskresource.ResourceFields{
+		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
+			VaultClient: vaultClient,
+		},
+	}
+
+	require.True(t, task.requiresVaultSecret())
+	task.initializeVaultSecretResource(&config.Config{}, resFields)
+
+	resourceDep := apicontainer.ResourceDependency{
+		Name:           vaultsecret.ResourceName,
+		RequiredStatus: resourcestatus.ResourceStatus(vaultsecret.VaultSecretCreated),
+	}
+	assert.Equal(t, resourceDep, task.Containers[0].TransitionDependenciesMap[apicontainerstatus.ContainerCreated].ResourceDependencies[0])
+	assert.Equal(t, resourceDep, task.Containers[1].TransitionDependenciesMap[apicontainerstatus.ContainerCreated].ResourceDependencies[0],
+		"the firelens container must wait for the log driver secrets")
+
+	_, ok := task.getVaultSecretsResource()
+	assert.True(t, ok)
+	assert.Equal(t, map[string]apicontainer.Secret{
+		secret.GetSecretResourceCacheKey():          secret,
+		logDriverSecret.GetSecretResourceCacheKey(): logDriverSecret,
+	}, task.getAllVaultSecretRequirements())
+}
+
+func TestPopulateVaultSecrets(t *testing.T) {
+	envSecret := apicontainer.Secret{
+		Provider:  "vault",
+		Name:      "secret1",
+		Type:      apicontainer.SecretTypeEnv,
+		ValueFrom: "secret/data/db#password",
+	}
+	logDriverSecret := apicontainer.Secret{
+		Provider:  "vault",
+		Name:      "splunk-token",
+		Target:    apicontainer.SecretTargetLogDriver,
+		ValueFrom: "secret/data/splunk#token",
+	}
+	fileSecret := apicontainer.Secret{
+		Provider:      "vault",
+		Name:          "secret2",
+		Type:          apicontainer.SecretTypeMountPoint,
+		ContainerPath: "/run/secrets/secret2",
+		ValueFrom:     "secret/data/api#key",
+	}
+	container := &apicontainer.Container{
+		Name:    "myName",
+		Image:   "image:tag",
+		Secrets: []apicontainer.Secret{envSecret, logDriverSecret, fileSecret},
+	}
+	task := &Task{
+		Arn:                "arn:aws:ecs:us-west-2:123456789012:task/cluster/task-id",
+		ResourcesMapUnsafe: make(map[string][]taskresource.TaskResource),
+		Containers:         []*apicontainer.Container{container},
+	}
+
+	vaultRes := &vaultsecret.VaultSecretResource{}
+	vaultRes.SetCachedSecretValue(envSecret.GetSecretResourceCacheKey(), "secretValue1")
+	vaultRes.SetCachedSecretValue(logDriverSecret.GetSecretResourceCacheKey(), "secretValue2")
+	vaultRes.SetCachedSecretValue(fileSecret.GetSecretResourceCacheKey(), "secretValue3")
+	task.AddResource(vaultsecret.ResourceName, vaultRes)
+
+	hostConfig := &dockercontainer.HostConfig{}
+	hostConfig.LogConfig.Type = "splunk"
+	require.Nil(t, task.PopulateSecrets(hostConfig, container))
+	assert.Equal(t, "secretValue1", container.Environment["secret1"])
+	assert.Equal(t, "secretValue2", hostConfig.LogConfig.Config["splunk-token"])
+	assert.NotContains(t, container.Environment, "secret2")
+
+	cfg := &config.Config{DataDirOnHost: "/var/lib/ecs"}
+	require.Nil(t, task.AddSecretFileBindMounts(container, hostConfig, cfg))
+	assert.Equal(t, []string{"/var/lib/ecs/data/vaultsecret/task-id/" + secretfile.MountDir("/run/secrets") + ":/run/secrets:ro"},
+		hostConfig.Binds)
+}
+
 func TestPopulateSecretsNoConfigInHostConfig(t *testing.T) {
 	secret1 := apicontainer.Secret{
 		Provider:  "ssm",
@@ -3253,6 +3495,29 @@ func TestTaskFromACSPerContainerTimeouts(t *testing.T) {
 	assert.Equal(t, task.Containers[0].StopTimeout, expectedTimeout)
 }
 
+func TestTaskFromACSPidsAndBlockIOLimits(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		PidsLimit:     aws.Int64(512),
+		BlockIOWeight: aws.Int64(500),
+		BlockIODeviceLimits: []*ecsacs.BlockIODeviceLimit{
+			{
+				Device:    aws.String("259:0"),
+				ReadBps:   aws.Int64(1048576),
+				WriteIOPS: aws.Int64(100),
+			},
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	require.NoError(t, err, "Should be able to handle acs task")
+
+	assert.Equal(t, int64(512), task.PidsLimit)
+	assert.Equal(t, uint16(500), task.BlockIOWeight)
+	assert.Equal(t, []config.BlockIODeviceLimit{
+		{Device: "259:0", ReadBps: 1048576, WriteIOPS: 100},
+	}, task.BlockIODeviceLimits)
+}
+
 func TestGetContainerIndex(t *testing.T) {
 	task := &Task{
 		Containers: []*apicontainer.Container{
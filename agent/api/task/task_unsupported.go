@@ -21,6 +21,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control"
 	"github.com/cihub/seelog"
 	dockercontainer "github.com/docker/docker/api/types/container"
 	"github.com/pkg/errors"
@@ -45,6 +46,11 @@ func (task *Task) initializeCgroupResourceSpec(cgroupPath string, cGroupCPUPerio
 	return nil
 }
 
+// GetCgroupUsage returns the current resource usage of the task cgroup
+func (task *Task) GetCgroupUsage() (*control.Usage, error) {
+	return nil, errors.New("unsupported platform")
+}
+
 func (task *Task) platformHostConfigOverride(hostConfig *dockercontainer.HostConfig) error {
 	return nil
 }
@@ -29,6 +29,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/credentialspec"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	taskresourcevolume "github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
@@ -157,6 +158,11 @@ func (task *Task) initializeCgroupResourceSpec(cgroupPath string, cGroupCPUPerio
 	return errors.New("unsupported platform")
 }
 
+// GetCgroupUsage returns the current resource usage of the task cgroup
+func (task *Task) GetCgroupUsage() (*control.Usage, error) {
+	return nil, errors.New("unsupported platform")
+}
+
 // requiresCredentialSpecResource returns true if at least one container in the task
 // needs a valid credentialspec resource
 func (task *Task) requiresCredentialSpecResource() bool {
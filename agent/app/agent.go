@@ -40,16 +40,21 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/eni/pause"
 	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
+	"github.com/aws/amazon-ecs-agent/agent/eventsink"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	"github.com/aws/amazon-ecs-agent/agent/handlers"
+	v4 "github.com/aws/amazon-ecs-agent/agent/handlers/v4"
+	"github.com/aws/amazon-ecs-agent/agent/logger/audit"
 	"github.com/aws/amazon-ecs-agent/agent/sighandlers"
 	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	tcshandler "github.com/aws/amazon-ecs-agent/agent/tcs/handler"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/amazon-ecs-agent/agent/utils/mobypkgwrapper"
+	"github.com/aws/amazon-ecs-agent/agent/vault"
 	"github.com/aws/amazon-ecs-agent/agent/version"
 	"github.com/aws/aws-sdk-go/aws"
 	aws_credentials "github.com/aws/aws-sdk-go/aws/credentials"
@@ -84,6 +89,9 @@ type agent interface {
 	startWindowsService() int
 	// start starts the Agent execution
 	start() int
+	// startLocal starts the Agent execution without connecting to ECS, to
+	// run the tasks in the task file or posted to the listen address
+	startLocal(taskFile string, listenAddress string, agentCredentials bool) int
 	// setTerminationHandler sets the termination handler
 	setTerminationHandler(sighandlers.TerminationHandler)
 }
@@ -250,6 +258,9 @@ func (agent *ecsAgent) doStart(containerChangeEventStream *eventstream.EventStre
 
 	agent.initMetricsEngine()
 
+	// Start exporting the traces of the task lifecycles, if enabled
+	tracing.MustInit(agent.ctx, agent.cfg)
+
 	// Initialize the state manager
 	stateManager, err := agent.newStateManager(taskEngine, &agent.cfg.Cluster, &agent.containerInstanceARN,
 		&currentEC2InstanceID, &agent.availabilityZone, agent.latestSeqNumberTaskManifest)
@@ -315,6 +326,11 @@ func (agent *ecsAgent) doStart(containerChangeEventStream *eventstream.EventStre
 		agent.metadataManager.SetHostPublicIPv4Address(agent.getHostPublicIPv4AddressFromEC2Metadata())
 	}
 
+	// The audit log is shared by the task engine, which records the decisions
+	// of the task admission policy, and the credentials endpoint
+	auditLogger := agent.newAuditLogger()
+	taskEngine.SetAuditLogger(auditLogger)
+
 	// Begin listening to the docker daemon and saving changes
 	taskEngine.SetSaver(stateManager)
 	imageManager.SetSaver(stateManager)
@@ -327,13 +343,24 @@ func (agent *ecsAgent) doStart(containerChangeEventStream *eventstream.EventStre
 	taskHandler := eventhandler.NewTaskHandler(agent.ctx, stateManager, state, client)
 	attachmentEventHandler := eventhandler.NewAttachmentEventHandler(agent.ctx, stateManager, client)
 	agent.startAsyncRoutines(containerChangeEventStream, credentialsManager, imageManager,
-		taskEngine, stateManager, deregisterInstanceEventStream, client, taskHandler, attachmentEventHandler, state)
+		taskEngine, stateManager, deregisterInstanceEventStream, client, taskHandler, attachmentEventHandler, state, auditLogger)
 
 	// Start the acs session, which should block doStart
 	return agent.startACSSession(credentialsManager, taskEngine, stateManager,
 		deregisterInstanceEventStream, client, state, taskHandler)
 }
 
+// newAuditLogger creates the audit log of the container instance
+func (agent *ecsAgent) newAuditLogger() audit.AuditLogger {
+	logger, err := seelog.LoggerFromConfigAsString(audit.AuditLoggerConfig(agent.cfg))
+	if err != nil {
+		seelog.Errorf("Error initializing the audit log: %v", err)
+		// If the logger cannot be initialized, use the provided dummy seelog.LoggerInterface, seelog.Disabled.
+		logger = seelog.Disabled
+	}
+	return audit.NewAuditLog(agent.containerInstanceARN, agent.cfg, logger)
+}
+
 // newTaskEngine creates a new docker task engine object. It tries to load the
 // local state if needed, else initializes a new one
 func (agent *ecsAgent) newTaskEngine(containerChangeEventStream *eventstream.EventStream,
@@ -364,6 +391,9 @@ func (agent *ecsAgent) newTaskEngine(containerChangeEventStream *eventstream.Eve
 		seelog.Criticalf("Error creating state manager: %v", err)
 		return nil, "", err
 	}
+	// The state store is opened again by the state manager of the agent, which
+	// can't happen while the boltdb store is held by this one
+	defer closeStateManager(previousStateManager)
 
 	err = previousStateManager.Load()
 	if err != nil {
@@ -450,6 +480,27 @@ func (agent *ecsAgent) getEC2InstanceID() string {
 	return instanceID
 }
 
+// newVaultClient creates the client reading the secrets of the vault provider,
+// or returns nil if no Vault server is configured
+func (agent *ecsAgent) newVaultClient() vault.Client {
+	if agent.cfg.VaultAddress == "" {
+		return nil
+	}
+	client, err := vault.NewClient(vault.Config{
+		Address:      agent.cfg.VaultAddress,
+		CACertFile:   agent.cfg.VaultCACertFile,
+		TokenFile:    agent.cfg.VaultTokenFile,
+		RoleIDFile:   agent.cfg.VaultAppRoleRoleIDFile,
+		SecretIDFile: agent.cfg.VaultAppRoleSecretIDFile,
+		AllowedPaths: agent.cfg.VaultAllowedPaths,
+	})
+	if err != nil {
+		seelog.Errorf("Unable to create the Vault client, tasks using Vault secrets will fail to start: %v", err)
+		return nil
+	}
+	return client
+}
+
 // getoutpostARN gets the Outpost ARN from the metadata service
 func (agent *ecsAgent) getoutpostARN() string {
 	outpostARN, err := agent.ec2MetadataClient.OutpostARN()
@@ -488,6 +539,13 @@ func (agent *ecsAgent) newStateManager(
 	)
 }
 
+// closeStateManager releases the state store of the state manager
+func closeStateManager(stateManager statemanager.StateManager) {
+	if err := stateManager.Close(); err != nil {
+		seelog.Warnf("Error closing the state store: %v", err)
+	}
+}
+
 // constructVPCSubnetAttributes returns vpc and subnet IDs of the instance as
 // an attribute list
 func (agent *ecsAgent) constructVPCSubnetAttributes() []*ecs.Attribute {
@@ -605,7 +663,8 @@ func (agent *ecsAgent) startAsyncRoutines(
 	client api.ECSClient,
 	taskHandler *eventhandler.TaskHandler,
 	attachmentEventHandler *eventhandler.AttachmentEventHandler,
-	state dockerstate.TaskEngineState) {
+	state dockerstate.TaskEngineState,
+	auditLogger audit.AuditLogger) {
 
 	// Start of the periodic image cleanup process
 	if !agent.cfg.ImageCleanupDisabled {
@@ -623,17 +682,24 @@ func (agent *ecsAgent) startAsyncRoutines(
 	go handlers.ServeIntrospectionHTTPEndpoint(agent.ctx, &agent.containerInstanceARN, taskEngine, agent.cfg)
 
 	statsEngine := stats.NewDockerStatsEngine(agent.cfg, agent.dockerClient, containerChangeEventStream)
+	// Publish the resource stats of the tasks along with the agent metrics
+	metrics.MetricsEngineGlobal.RegisterCollector(stats.NewPrometheusCollector(statsEngine))
+	taskEventStreams := v4.NewTaskEventStreams(agent.ctx)
+	taskEngine.SetContainerHealthListener(taskEventStreams)
 
 	// Start serving the endpoint to fetch IAM Role credentials and other task metadata
 	if agent.cfg.TaskMetadataAZDisabled {
 		// send empty availability zone
-		go handlers.ServeTaskHTTPEndpoint(agent.ctx, credentialsManager, state, client, agent.containerInstanceARN, agent.cfg, statsEngine, "")
+		go handlers.ServeTaskHTTPEndpoint(agent.ctx, credentialsManager, auditLogger, state, client, agent.dockerClient, agent.containerInstanceARN, agent.cfg, statsEngine, "", taskEventStreams)
 	} else {
-		go handlers.ServeTaskHTTPEndpoint(agent.ctx, credentialsManager, state, client, agent.containerInstanceARN, agent.cfg, statsEngine, agent.availabilityZone)
+		go handlers.ServeTaskHTTPEndpoint(agent.ctx, credentialsManager, auditLogger, state, client, agent.dockerClient, agent.containerInstanceARN, agent.cfg, statsEngine, agent.availabilityZone, taskEventStreams)
 	}
 
-	// Start sending events to the backend
-	go eventhandler.HandleEngineEvents(agent.ctx, taskEngine, client, taskHandler, attachmentEventHandler)
+	// Start sending events to the backend, to the local event sinks, and to
+	// the task event streams of the metadata endpoint
+	eventSinks := eventsink.NewDispatcher(agent.ctx, agent.cfg.EventSinks, agent.cfg.DataDir)
+	go eventhandler.HandleEngineEvents(agent.ctx, taskEngine, client, taskHandler, attachmentEventHandler,
+		eventSinks, taskEventStreams)
 
 	telemetrySessionParams := tcshandler.TelemetrySessionParams{
 		Ctx:                           agent.ctx,
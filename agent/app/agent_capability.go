@@ -60,6 +60,9 @@ const (
 	capabilityEFS                               = "efs"
 	capabilityEFSAuth                           = "efsAuth"
 	capabilityEnvFilesS3                        = "env-files.s3"
+	capabilityEnvFilesFile                      = "env-files.file"
+	capabilityEnvFilesHTTPS                     = "env-files.https"
+	capabilityEnvFilesSSM                       = "env-files.ssm"
 )
 
 var nameOnlyAttributes = []string{
@@ -82,6 +85,9 @@ var nameOnlyAttributes = []string{
 	capabilityFullTaskSync,
 	// ecs agent version 1.39.0 supports bulk loading env vars through environmentFiles in S3
 	capabilityEnvFilesS3,
+	// support bulk loading env vars through environmentFiles over https and in ssm
+	capabilityEnvFilesHTTPS,
+	capabilityEnvFilesSSM,
 }
 
 // capabilities returns the supported capabilities of this agent / docker-client pair.
@@ -130,6 +136,9 @@ var nameOnlyAttributes = []string{
 //    ecs.capability.gmsa
 //    ecs.capability.efsAuth
 //    ecs.capability.env-files.s3
+//    ecs.capability.env-files.file
+//    ecs.capability.env-files.https
+//    ecs.capability.env-files.ssm
 func (agent *ecsAgent) capabilities() ([]*ecs.Attribute, error) {
 	var capabilities []*ecs.Attribute
 
@@ -214,6 +223,11 @@ func (agent *ecsAgent) capabilities() ([]*ecs.Attribute, error) {
 		capabilities = agent.appendEFSVolumePluginCapabilities(capabilities, cap)
 	}
 
+	// support environment files read from the allowed host directories
+	if len(agent.cfg.EnvironmentFileAllowedDirs) > 0 {
+		capabilities = appendNameOnlyAttribute(capabilities, attributePrefix+capabilityEnvFilesFile)
+	}
+
 	return capabilities, nil
 }
 
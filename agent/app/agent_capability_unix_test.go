@@ -467,6 +467,8 @@ func TestPIDAndIPCNamespaceSharingCapabilitiesUnix(t *testing.T) {
 		attributePrefix + capabilityContainerOrdering,
 		attributePrefix + capabilityFullTaskSync,
 		attributePrefix + capabilityEnvFilesS3,
+		attributePrefix + capabilityEnvFilesHTTPS,
+		attributePrefix + capabilityEnvFilesSSM,
 		attributePrefix + capabiltyPIDAndIPCNamespaceSharing,
 	}
 
@@ -534,6 +536,8 @@ func TestPIDAndIPCNamespaceSharingCapabilitiesNoPauseContainer(t *testing.T) {
 		attributePrefix + capabilityContainerOrdering,
 		attributePrefix + capabilityFullTaskSync,
 		attributePrefix + capabilityEnvFilesS3,
+		attributePrefix + capabilityEnvFilesHTTPS,
+		attributePrefix + capabilityEnvFilesSSM,
 	}
 
 	var expectedCapabilities []*ecs.Attribute
@@ -600,6 +604,8 @@ func TestAppMeshCapabilitiesUnix(t *testing.T) {
 		attributePrefix + capabilityContainerOrdering,
 		attributePrefix + capabilityFullTaskSync,
 		attributePrefix + capabilityEnvFilesS3,
+		attributePrefix + capabilityEnvFilesHTTPS,
+		attributePrefix + capabilityEnvFilesSSM,
 		attributePrefix + capabiltyPIDAndIPCNamespaceSharing,
 		attributePrefix + appMeshAttributeSuffix,
 	}
@@ -774,6 +780,8 @@ func TestCapabilitiesUnix(t *testing.T) {
 		attributePrefix + capabilityEFSAuth,
 		capabilityPrefix + capabilityFirelensLoggingDriver,
 		attributePrefix + capabilityEnvFilesS3,
+		attributePrefix + capabilityEnvFilesHTTPS,
+		attributePrefix + capabilityEnvFilesSSM,
 	}
 
 	var expectedCapabilities []*ecs.Attribute
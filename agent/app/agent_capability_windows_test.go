@@ -179,6 +179,8 @@ func TestSupportedCapabilitiesWindows(t *testing.T) {
 		attributePrefix + capabilityContainerOrdering,
 		attributePrefix + capabilityFullTaskSync,
 		attributePrefix + capabilityEnvFilesS3,
+		attributePrefix + capabilityEnvFilesHTTPS,
+		attributePrefix + capabilityEnvFilesSSM,
 		attributePrefix + taskENIBlockInstanceMetadataAttributeSuffix}
 
 	var expectedCapabilities []*ecs.Attribute
@@ -51,6 +51,7 @@ func TestCompatibilityEnabledSuccess(t *testing.T) {
 			gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(stateManager, nil),
 		stateManager.EXPECT().Load().AnyTimes(),
 		state.EXPECT().AllTasks().Return([]*apitask.Task{}),
+		stateManager.EXPECT().Close().AnyTimes(),
 	)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -84,6 +85,7 @@ func TestCompatibilityDefaultEnabledFail(t *testing.T) {
 			gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(stateManager, nil),
 		stateManager.EXPECT().Load().AnyTimes(),
 		state.EXPECT().AllTasks().Return(getTaskListWithOneBadTask()),
+		stateManager.EXPECT().Close().AnyTimes(),
 	)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -116,6 +118,7 @@ func TestCompatibilityExplicitlyEnabledFail(t *testing.T) {
 			gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(stateManager, nil),
 		stateManager.EXPECT().Load().AnyTimes(),
 		state.EXPECT().AllTasks().Return(getTaskListWithOneBadTask()),
+		stateManager.EXPECT().Close().AnyTimes(),
 	)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
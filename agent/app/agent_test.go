@@ -19,6 +19,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sort"
 	"sync"
 	"testing"
@@ -27,6 +29,7 @@ import (
 
 	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
 	mock_api "github.com/aws/amazon-ecs-agent/agent/api/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/app/factory"
 	mock_factory "github.com/aws/amazon-ecs-agent/agent/app/factory/mocks"
 	app_mocks "github.com/aws/amazon-ecs-agent/agent/app/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/config"
@@ -38,6 +41,7 @@ import (
 	mock_ec2 "github.com/aws/amazon-ecs-agent/agent/ec2/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	mock_dockerstate "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate/mocks"
 	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
@@ -50,6 +54,7 @@ import (
 	aws_credentials "github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -645,6 +650,7 @@ func TestNewTaskEngineRestoreFromCheckpointStateLoadError(t *testing.T) {
 			gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
 		).Return(stateManager, nil),
 		stateManager.EXPECT().Load().Return(errors.New("error")),
+		stateManager.EXPECT().Close(),
 	)
 
 	ctx, cancel := context.WithCancel(context.TODO())
@@ -710,6 +716,47 @@ func TestNewTaskEngineRestoreFromCheckpoint(t *testing.T) {
 	assert.Equal(t, expectedInstanceID, instanceID)
 }
 
+func TestNewTaskEngineRestoreFromCheckpointBoltDB(t *testing.T) {
+	ctrl, credentialsManager, _, imageManager, _, dockerClient, _, _ := setup(t)
+	defer ctrl.Finish()
+
+	dataDir, err := ioutil.TempDir("", "agent-boltdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	ec2MetadataClient.EXPECT().InstanceID().Return("inst-1", nil)
+	cfg := getTestConfig()
+	cfg.Checkpoint = true
+	cfg.DataDir = dataDir
+	cfg.StateStore = config.StateStoreBoltDB
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	// Cancel the context to cancel async routines
+	defer cancel()
+	var seqNumber int64
+	agent := &ecsAgent{
+		ctx:                         ctx,
+		cfg:                         &cfg,
+		dockerClient:                dockerClient,
+		stateManagerFactory:         factory.NewStateManager(),
+		ec2MetadataClient:           ec2MetadataClient,
+		saveableOptionFactory:       factory.NewSaveableOption(),
+		latestSeqNumberTaskManifest: &seqNumber,
+	}
+
+	taskEngine, instanceID, err := agent.newTaskEngine(eventstream.NewEventStream("events", ctx),
+		credentialsManager, dockerstate.NewTaskEngineState(), imageManager)
+	require.NoError(t, err)
+
+	// The state store is opened again to start the agent, as doStart does
+	stateManager, err := agent.newStateManager(taskEngine, &cfg.Cluster, &agent.containerInstanceARN,
+		&instanceID, &agent.availabilityZone, agent.latestSeqNumberTaskManifest)
+	require.NoError(t, err)
+	defer closeStateManager(stateManager)
+	assert.NoError(t, stateManager.Save())
+}
+
 func TestSetClusterInConfigMismatch(t *testing.T) {
 	clusterNamesInConfig := []string{"", "foo"}
 	for _, clusterNameInConfig := range clusterNamesInConfig {
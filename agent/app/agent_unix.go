@@ -198,13 +198,15 @@ func contains(capabilities []string, capability string) bool {
 // object
 func (agent *ecsAgent) initializeResourceFields(credentialsManager credentials.Manager) {
 	agent.resourceFields = &taskresource.ResourceFields{
-		Control: cgroup.New(),
+		Control: cgroup.New(agent.cfg.CgroupPath),
 		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
-			IOUtil:             ioutilwrapper.NewIOUtil(),
-			ASMClientCreator:   asmfactory.NewClientCreator(),
-			SSMClientCreator:   ssmfactory.NewSSMClientCreator(),
-			CredentialsManager: credentialsManager,
-			EC2InstanceID:      agent.getEC2InstanceID(),
+			IOUtil:                     ioutilwrapper.NewIOUtil(),
+			ASMClientCreator:           asmfactory.NewClientCreator(),
+			SSMClientCreator:           ssmfactory.NewSSMClientCreator(),
+			CredentialsManager:         credentialsManager,
+			VaultClient:                agent.newVaultClient(),
+			EnvironmentFileAllowedDirs: agent.cfg.EnvironmentFileAllowedDirs,
+			EC2InstanceID:              agent.getEC2InstanceID(),
 		},
 		Ctx:              agent.ctx,
 		DockerClient:     agent.dockerClient,
@@ -256,9 +256,11 @@ func (t *termHandlerIndicator) wait() uint32 {
 func (agent *ecsAgent) initializeResourceFields(credentialsManager credentials.Manager) {
 	agent.resourceFields = &taskresource.ResourceFields{
 		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
-			ASMClientCreator:   asmfactory.NewClientCreator(),
-			SSMClientCreator:   ssmfactory.NewSSMClientCreator(),
-			CredentialsManager: credentialsManager,
+			ASMClientCreator:           asmfactory.NewClientCreator(),
+			SSMClientCreator:           ssmfactory.NewSSMClientCreator(),
+			CredentialsManager:         credentialsManager,
+			VaultClient:                agent.newVaultClient(),
+			EnvironmentFileAllowedDirs: agent.cfg.EnvironmentFileAllowedDirs,
 		},
 		Ctx:             agent.ctx,
 		DockerClient:    agent.dockerClient,
@@ -46,6 +46,9 @@ func (m *mockAgent) setTerminationHandler(handler sighandlers.TerminationHandler
 }
 func (m *mockAgent) printECSAttributes() int  { return 0 }
 func (m *mockAgent) startWindowsService() int { return 0 }
+func (m *mockAgent) startLocal(taskFile string, listenAddress string, agentCredentials bool) int {
+	return 0
+}
 
 func TestHandler_RunAgent_StartExitImmediately(t *testing.T) {
 	// register some mocks, but nothing should get called on any of them
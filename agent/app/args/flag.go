@@ -24,6 +24,15 @@ const (
 	blacholeEC2MetadataUsage = "Blackhole the EC2 Metadata requests. Setting this option can cause the ECS Agent to fail to work properly.  We do not recommend setting this option"
 	windowsServiceUsage      = "Run the ECS agent as a Windows Service"
 	healthcheckServiceUsage  = "Run the agent healthcheck"
+	stateInspectUsage        = "Print the tasks, containers, image states and ENI attachments saved in ECS_DATADIR and exit. The agent must not be running. Logging is limited to critical messages unless --loglevel is set"
+	stateValidateUsage       = "Validate the state saved in ECS_DATADIR against the data version supported by this agent and exit"
+	stateJSONUsage           = "Print the state as json instead of tables, used with --state-inspect"
+	stateDropTaskUsage       = "Drop the tasks with the given comma separated ARNs, along with their containers and ENI attachments, from the state saved in ECS_DATADIR and exit"
+	stateDowngradeUsage      = "Rewrite the state saved in ECS_DATADIR to ecs_agent_data.json as the given older data version, so that an older agent can load it, and exit. Fails for data versions older than 28, and if tasks hold resources or fields added after that version, which must be dropped first"
+	localTaskUsage           = "Run the task in the given JSON file, in the shape of the tasks sent by ECS, without connecting to ECS. State changes are printed instead of being submitted, and the agent exits once the task has stopped unless --local-task-listen is set"
+	localTaskListenUsage     = "Run tasks posted as JSON, in the shape of the tasks sent by ECS, to the /v1/tasks path of the given loopback address or unix:<path> socket, without connecting to ECS. State changes are printed instead of being submitted"
+	localTaskAgentCredsUsage = "Give the tasks run with --local-task or --local-task-listen that have no credentials the credentials of the agent"
+	firelensRenderUsage      = "Print the firelens config generated for the task in the given JSON file, in the shape of the tasks sent by ECS, after validating it, and exit. Logging is limited to critical messages unless --loglevel is set"
 
 	versionFlagName              = "version"
 	logLevelFlagName             = "loglevel"
@@ -33,6 +42,15 @@ const (
 	blackholeEC2MetadataFlagName = "blackhole-ec2-metadata"
 	windowsServiceFlagName       = "windows-service"
 	healthCheckFlagName          = "healthcheck"
+	stateInspectFlagName         = "state-inspect"
+	stateValidateFlagName        = "state-validate"
+	stateJSONFlagName            = "state-json"
+	stateDropTaskFlagName        = "state-drop-task"
+	stateDowngradeFlagName       = "state-downgrade"
+	localTaskFlagName            = "local-task"
+	localTaskListenFlagName      = "local-task-listen"
+	localTaskAgentCredsFlagName  = "local-task-agent-credentials"
+	firelensRenderFlagName       = "firelens-render"
 )
 
 // Args wraps various ECS Agent arguments
@@ -55,6 +73,29 @@ type Args struct {
 	WindowsService *bool
 	// Healthcheck indicates that agent should run healthcheck
 	Healthcheck *bool
+	// StateInspect indicates that the agent should print the saved state
+	StateInspect *bool
+	// StateValidate indicates that the agent should validate the saved state
+	StateValidate *bool
+	// StateJSON indicates that the saved state should be printed as json
+	StateJSON *bool
+	// StateDropTask is the comma separated list of task ARNs that should be
+	// dropped from the saved state
+	StateDropTask *string
+	// StateDowngrade is the older data version that the saved state should be
+	// rewritten to
+	StateDowngrade *int
+	// LocalTask is the file of the task that should be run without ECS
+	LocalTask *string
+	// LocalTaskListen is the address on which tasks that should be run
+	// without ECS are accepted
+	LocalTaskListen *string
+	// LocalTaskAgentCredentials indicates that the tasks run without ECS
+	// that have no credentials should be given the credentials of the agent
+	LocalTaskAgentCredentials *bool
+	// FirelensRender is the file of the task whose firelens config should
+	// be printed
+	FirelensRender *string
 }
 
 // New creates a new Args object from the argument list
@@ -62,14 +103,23 @@ func New(arguments []string) (*Args, error) {
 	flagset := flag.NewFlagSet("Amazon ECS Agent", flag.ContinueOnError)
 
 	args := &Args{
-		Version:              flagset.Bool(versionFlagName, false, versionUsage),
-		LogLevel:             flagset.String(logLevelFlagName, "", logLevelUsage),
-		AcceptInsecureCert:   flagset.Bool(acceptInsecureCertFlagName, false, acceptInsecureCertUsage),
-		License:              flagset.Bool(licenseFlagName, false, licenseUsage),
-		BlackholeEC2Metadata: flagset.Bool(blackholeEC2MetadataFlagName, false, blacholeEC2MetadataUsage),
-		ECSAttributes:        flagset.Bool(ecsAttributesFlagName, false, ecsAttributesUsage),
-		WindowsService:       flagset.Bool(windowsServiceFlagName, false, windowsServiceUsage),
-		Healthcheck:          flagset.Bool(healthCheckFlagName, false, healthcheckServiceUsage),
+		Version:                   flagset.Bool(versionFlagName, false, versionUsage),
+		LogLevel:                  flagset.String(logLevelFlagName, "", logLevelUsage),
+		AcceptInsecureCert:        flagset.Bool(acceptInsecureCertFlagName, false, acceptInsecureCertUsage),
+		License:                   flagset.Bool(licenseFlagName, false, licenseUsage),
+		BlackholeEC2Metadata:      flagset.Bool(blackholeEC2MetadataFlagName, false, blacholeEC2MetadataUsage),
+		ECSAttributes:             flagset.Bool(ecsAttributesFlagName, false, ecsAttributesUsage),
+		WindowsService:            flagset.Bool(windowsServiceFlagName, false, windowsServiceUsage),
+		Healthcheck:               flagset.Bool(healthCheckFlagName, false, healthcheckServiceUsage),
+		StateInspect:              flagset.Bool(stateInspectFlagName, false, stateInspectUsage),
+		StateValidate:             flagset.Bool(stateValidateFlagName, false, stateValidateUsage),
+		StateJSON:                 flagset.Bool(stateJSONFlagName, false, stateJSONUsage),
+		StateDropTask:             flagset.String(stateDropTaskFlagName, "", stateDropTaskUsage),
+		StateDowngrade:            flagset.Int(stateDowngradeFlagName, 0, stateDowngradeUsage),
+		LocalTask:                 flagset.String(localTaskFlagName, "", localTaskUsage),
+		LocalTaskListen:           flagset.String(localTaskListenFlagName, "", localTaskListenUsage),
+		LocalTaskAgentCredentials: flagset.Bool(localTaskAgentCredsFlagName, false, localTaskAgentCredsUsage),
+		FirelensRender:            flagset.String(firelensRenderFlagName, "", firelensRenderUsage),
 	}
 
 	err := flagset.Parse(arguments)
@@ -79,3 +129,15 @@ func New(arguments []string) (*Args, error) {
 
 	return args, nil
 }
+
+// IsStateCommand returns true if the arguments request an offline operation
+// on the saved state rather than starting the agent
+func (args *Args) IsStateCommand() bool {
+	return *args.StateInspect || *args.StateValidate || *args.StateDropTask != "" || *args.StateDowngrade != 0
+}
+
+// IsLocalMode returns true if the arguments request running tasks locally,
+// without connecting to ECS
+func (args *Args) IsLocalMode() bool {
+	return *args.LocalTask != "" || *args.LocalTaskListen != ""
+}
@@ -0,0 +1,62 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"io"
+	"os"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+	"github.com/cihub/seelog"
+)
+
+// runFirelensRender prints the firelens config that is generated for the
+// firelens container of the task in the task file, without running the task
+func runFirelensRender(taskFile string) int {
+	cfg, err := config.NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	if err != nil {
+		// Only the cluster, region and data directory are part of the config,
+		// none of which depend on the settings that failed to load
+		seelog.Debugf("Error loading config, continuing with cluster %s: %v", cfg.Cluster, err)
+	}
+	return renderFirelensConfig(cfg, taskFile, os.Stdout)
+}
+
+func renderFirelensConfig(cfg *config.Config, taskFile string, w io.Writer) int {
+	acsTask, err := loadLocalTask(taskFile)
+	if err != nil {
+		seelog.Criticalf("Unable to load task: %v", err)
+		return exitcodes.ExitTerminal
+	}
+	task, err := apitask.TaskFromACS(acsTask, &ecsacs.PayloadMessage{})
+	if err != nil {
+		seelog.Criticalf("Unable to read task %s: %v", taskFile, err)
+		return exitcodes.ExitTerminal
+	}
+
+	rendered, err := task.RenderFirelensConfig(cfg)
+	if err != nil {
+		seelog.Criticalf("Unable to render the firelens config of task %s: %v", taskFile, err)
+		return exitcodes.ExitTerminal
+	}
+	if _, err := w.Write(rendered); err != nil {
+		seelog.Criticalf("Unable to print the firelens config: %v", err)
+		return exitcodes.ExitError
+	}
+	return exitcodes.ExitSuccess
+}
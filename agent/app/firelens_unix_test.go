@@ -0,0 +1,82 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const firelensTaskJSON = `{
+	"arn": "arn:aws:ecs:us-west-2:000000000000:task/local/task-id",
+	"family": "firelens",
+	"version": "1",
+	"containers": [{
+		"name": "app",
+		"image": "busybox",
+		"essential": true,
+		"dockerConfig": {
+			"hostConfig": "{\"LogConfig\":{\"Type\":\"awsfirelens\",\"Config\":{\"Name\":\"cloudwatch_logs\",\"region\":\"us-west-2\"}}}"
+		}
+	}, {
+		"name": "log_router",
+		"image": "amazon/aws-for-fluent-bit",
+		"essential": true,
+		"firelensConfiguration": {"type": "fluentbit", "options": {"enable-ecs-log-metadata": "false"}}
+	}]
+}`
+
+func writeFirelensTaskFile(t *testing.T, dir string, taskJSON string) string {
+	taskFile := filepath.Join(dir, "task.json")
+	require.NoError(t, ioutil.WriteFile(taskFile, []byte(taskJSON), 0644))
+	return taskFile
+}
+
+func TestRenderFirelensConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "firelens-render")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	taskFile := writeFirelensTaskFile(t, dir, firelensTaskJSON)
+	var buf bytes.Buffer
+	exitCode := renderFirelensConfig(&config.Config{Cluster: "local", AWSRegion: "us-west-2"}, taskFile, &buf)
+	assert.Equal(t, exitcodes.ExitSuccess, exitCode)
+	assert.Contains(t, buf.String(), "[OUTPUT]\n    Name cloudwatch_logs\n    Match app-firelens*\n    region us-west-2\n")
+}
+
+func TestRenderFirelensConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "firelens-render")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cfg := &config.Config{Cluster: "local", AWSRegion: "us-west-2"}
+
+	var buf bytes.Buffer
+	exitCode := renderFirelensConfig(cfg, filepath.Join(dir, "missing.json"), &buf)
+	assert.Equal(t, exitcodes.ExitTerminal, exitCode)
+
+	taskFile := writeFirelensTaskFile(t, dir, localTaskJSON)
+	exitCode = renderFirelensConfig(cfg, taskFile, &buf)
+	assert.Equal(t, exitcodes.ExitTerminal, exitCode)
+	assert.Empty(t, buf.String())
+}
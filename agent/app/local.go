@@ -0,0 +1,406 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
+	"github.com/aws/amazon-ecs-agent/agent/eventsink"
+	"github.com/aws/amazon-ecs-agent/agent/eventstream"
+	"github.com/aws/amazon-ecs-agent/agent/handlers"
+	v4 "github.com/aws/amazon-ecs-agent/agent/handlers/v4"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
+	"github.com/aws/aws-sdk-go/aws"
+	aws_credentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/cihub/seelog"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// localTaskPath is the path to which tasks run locally are posted
+	localTaskPath = "/v1/tasks"
+	// maxLocalTaskSize is the maximum size of the tasks posted
+	maxLocalTaskSize = 1024 * 1024
+	// localTaskPollInterval is the interval at which the tasks of the task
+	// file are checked for having stopped
+	localTaskPollInterval = time.Second
+	// localTaskAccountID is the account of the ARNs generated for tasks run
+	// locally without one
+	localTaskAccountID = "000000000000"
+	// localTaskUnixSocketPrefix is the prefix of the listen addresses that
+	// are unix sockets
+	localTaskUnixSocketPrefix = "unix:"
+	// localTaskUnixSocketPerm is the permission of the unix sockets that
+	// tasks are posted to
+	localTaskUnixSocketPerm = 0600
+)
+
+// startLocal starts the ECS Agent without connecting to ECS, to run the task
+// of the task file and the tasks posted to the listen address. The tasks
+// go through the same task engine as tasks sent by ECS, and are served the
+// task metadata and credentials endpoints. State changes are printed to
+// stdout instead of being submitted to ECS, the logs going to stderr, and
+// nothing is saved to the data directory. Without a listen address, the agent
+// exits once the task of the task file has stopped. Tasks without credentials are only given the
+// credentials of the agent if agentCredentials is set.
+func (agent *ecsAgent) startLocal(taskFile string, listenAddress string, agentCredentials bool) int {
+	var listener net.Listener
+	if listenAddress != "" {
+		var err error
+		listener, err = localTaskListener(listenAddress)
+		if err != nil {
+			seelog.Criticalf("Unable to accept local tasks on %s: %v", listenAddress, err)
+			return exitcodes.ExitTerminal
+		}
+	}
+
+	containerChangeEventStream := eventstream.NewEventStream(containerChangeEventStreamName, agent.ctx)
+	credentialsManager := credentials.NewManager()
+	state := dockerstate.NewTaskEngineState()
+	imageManager := engine.NewImageManager(agent.cfg, agent.dockerClient, state)
+	client := newLocalECSClient(os.Stdout)
+
+	agent.initializeResourceFields(credentialsManager)
+
+	if exitcode, ok := agent.verifyRequiredDockerVersion(); !ok {
+		return exitcode
+	}
+	if agent.cfg.TaskCPUMemLimit.Enabled() {
+		if err := agent.cgroupInit(); err != nil {
+			seelog.Criticalf("Unable to initialize cgroup root for ECS: %v", err)
+			return exitcodes.ExitTerminal
+		}
+	}
+
+	containerChangeEventStream.StartListening()
+	taskEngine := engine.NewTaskEngine(agent.cfg, agent.dockerClient, credentialsManager,
+		containerChangeEventStream, imageManager, state, agent.metadataManager, agent.resourceFields)
+	stateManager := statemanager.NewNoopStateManager()
+
+	auditLogger := agent.newAuditLogger()
+	taskEngine.SetAuditLogger(auditLogger)
+	tracing.MustInit(agent.ctx, agent.cfg)
+	taskEngine.MustInit(agent.ctx)
+
+	taskHandler := eventhandler.NewTaskHandler(agent.ctx, stateManager, state, client)
+	attachmentEventHandler := eventhandler.NewAttachmentEventHandler(agent.ctx, stateManager, client)
+	// Local tasks aren't saved, neither are the events waiting for the sinks
+	eventSinks := eventsink.NewDispatcher(agent.ctx, agent.cfg.EventSinks, "")
+	taskEventStreams := v4.NewTaskEventStreams(agent.ctx)
+	taskEngine.SetContainerHealthListener(taskEventStreams)
+	go eventhandler.HandleEngineEvents(agent.ctx, taskEngine, client, taskHandler, attachmentEventHandler,
+		eventSinks, taskEventStreams)
+
+	go agent.terminationHandler(stateManager, taskEngine, agent.cancel)
+
+	statsEngine := stats.NewDockerStatsEngine(agent.cfg, agent.dockerClient, containerChangeEventStream)
+	if err := statsEngine.MustInit(agent.ctx, taskEngine, agent.cfg.Cluster, agent.containerInstanceARN); err != nil {
+		seelog.Warnf("Unable to initialize the stats engine, task stats won't be available: %v", err)
+	}
+	go handlers.ServeTaskHTTPEndpoint(agent.ctx, credentialsManager, auditLogger, state, client, agent.dockerClient,
+		agent.containerInstanceARN, agent.cfg, statsEngine, "", taskEventStreams)
+
+	runner := &localTaskRunner{
+		cfg:                agent.cfg,
+		taskEngine:         taskEngine,
+		credentialsManager: credentialsManager,
+		credentialProvider: agent.credentialProvider,
+		agentCredentials:   agentCredentials,
+	}
+
+	var tasks []*apitask.Task
+	if taskFile != "" {
+		acsTask, err := loadLocalTask(taskFile)
+		if err != nil {
+			seelog.Criticalf("Unable to load local task: %v", err)
+			return exitcodes.ExitTerminal
+		}
+		task, err := runner.addTask(acsTask)
+		if err != nil {
+			seelog.Criticalf("Unable to run local task %s: %v", taskFile, err)
+			return exitcodes.ExitTerminal
+		}
+		tasks = append(tasks, task)
+	}
+
+	if listener == nil {
+		waitForStoppedTasks(agent.ctx, tasks)
+		return exitcodes.ExitSuccess
+	}
+	return runner.serve(agent.ctx, listener)
+}
+
+// localTaskRunner adds the tasks run locally to the task engine
+type localTaskRunner struct {
+	cfg                *config.Config
+	taskEngine         engine.TaskEngine
+	credentialsManager credentials.Manager
+	credentialProvider *aws_credentials.Credentials
+	// agentCredentials is set if tasks without credentials are given the
+	// credentials of the agent
+	agentCredentials bool
+}
+
+// loadLocalTask reads the task of the task file, in the shape of the tasks
+// sent by ECS
+func loadLocalTask(taskFile string) (*ecsacs.Task, error) {
+	file, err := os.Open(taskFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open task file %s", taskFile)
+	}
+	defer file.Close()
+
+	acsTask, err := decodeLocalTask(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse task file %s", taskFile)
+	}
+	return acsTask, nil
+}
+
+func decodeLocalTask(reader io.Reader) (*ecsacs.Task, error) {
+	acsTask := &ecsacs.Task{}
+	if err := jsonutil.UnmarshalJSON(acsTask, reader); err != nil {
+		return nil, err
+	}
+	return acsTask, nil
+}
+
+// addTask adds the task to the task engine, and returns it. An ARN is
+// generated for tasks without one, and tasks without a desired status are
+// run. Tasks without credentials are given the credentials of the agent, if
+// enabled.
+func (runner *localTaskRunner) addTask(acsTask *ecsacs.Task) (*apitask.Task, error) {
+	if len(acsTask.ElasticNetworkInterfaces) > 0 {
+		return nil, errors.New("tasks with elastic network interfaces can't be run locally")
+	}
+	if aws.StringValue(acsTask.Arn) == "" {
+		acsTask.Arn = aws.String(fmt.Sprintf("arn:aws:ecs:%s:%s:task/%s/%s",
+			runner.cfg.AWSRegion, localTaskAccountID, runner.cfg.Cluster, uuid.New()))
+	}
+	if aws.StringValue(acsTask.DesiredStatus) == "" {
+		acsTask.DesiredStatus = aws.String(apitaskstatus.TaskRunning.String())
+	}
+	taskARN := aws.StringValue(acsTask.Arn)
+
+	task, err := apitask.TaskFromACS(acsTask, &ecsacs.PayloadMessage{})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read task")
+	}
+
+	roleCredentials, err := runner.roleCredentials(taskARN, acsTask.RoleCredentials, credentials.ApplicationRoleType)
+	if err != nil {
+		return nil, err
+	}
+	if roleCredentials != "" {
+		task.SetCredentialsID(roleCredentials)
+	}
+	executionRoleCredentials, err := runner.roleCredentials(taskARN, acsTask.ExecutionRoleCredentials,
+		credentials.ExecutionRoleType)
+	if err != nil {
+		return nil, err
+	}
+	if executionRoleCredentials != "" {
+		task.SetExecutionRoleCredentialsID(executionRoleCredentials)
+	}
+
+	seelog.Infof("Running local task %s", taskARN)
+	runner.taskEngine.AddTask(task)
+	return task, nil
+}
+
+// roleCredentials adds the credentials of a role of the task to the
+// credentials manager, and returns their id. The credentials of the agent
+// are used if the task has none and they're enabled. No id is returned if
+// there are no credentials to use.
+func (runner *localTaskRunner) roleCredentials(taskARN string, acsCredentials *ecsacs.IAMRoleCredentials,
+	roleType string) (string, error) {
+	var roleCredentials credentials.IAMRoleCredentials
+	if acsCredentials != nil {
+		roleCredentials = credentials.IAMRoleCredentialsFromACS(acsCredentials, roleType)
+	} else if !runner.agentCredentials {
+		return "", nil
+	} else {
+		value, err := runner.credentialProvider.Get()
+		if err != nil {
+			seelog.Warnf("Local task %s won't have %s credentials, unable to get the credentials of the agent: %v",
+				taskARN, roleType, err)
+			return "", nil
+		}
+		roleCredentials = credentials.IAMRoleCredentials{
+			AccessKeyID:     value.AccessKeyID,
+			SecretAccessKey: value.SecretAccessKey,
+			SessionToken:    value.SessionToken,
+			RoleType:        roleType,
+		}
+		if expiration, err := runner.credentialProvider.ExpiresAt(); err == nil {
+			roleCredentials.Expiration = expiration.UTC().Format(time.RFC3339)
+		}
+	}
+	if roleCredentials.CredentialsID == "" {
+		roleCredentials.CredentialsID = uuid.New()
+	}
+
+	err := runner.credentialsManager.SetTaskCredentials(&credentials.TaskIAMRoleCredentials{
+		ARN:                taskARN,
+		IAMRoleCredentials: roleCredentials,
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to set %s credentials", roleType)
+	}
+	return roleCredentials.CredentialsID, nil
+}
+
+// waitForStoppedTasks waits until the stopped state change of the tasks has
+// been printed
+func waitForStoppedTasks(ctx context.Context, tasks []*apitask.Task) {
+	ticker := time.NewTicker(localTaskPollInterval)
+	defer ticker.Stop()
+	for !tasksStopped(tasks) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func tasksStopped(tasks []*apitask.Task) bool {
+	for _, task := range tasks {
+		if task.GetSentStatus() != apitaskstatus.TaskStopped {
+			return false
+		}
+	}
+	return true
+}
+
+// localTaskListener listens on the address that tasks are posted to. As
+// anyone who can post tasks can run containers on the host, only loopback
+// addresses and unix sockets, in the unix:<path> format, are accepted. Unix
+// sockets are only accessible to the user of the agent.
+func localTaskListener(listenAddress string) (net.Listener, error) {
+	if strings.HasPrefix(listenAddress, localTaskUnixSocketPrefix) {
+		socketPath := strings.TrimPrefix(listenAddress, localTaskUnixSocketPrefix)
+		if socketPath == "" {
+			return nil, errors.New("the unix socket has no path")
+		}
+		listener, err := net.Listen("unix", socketPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(socketPath, localTaskUnixSocketPerm); err != nil {
+			listener.Close()
+			return nil, errors.Wrapf(err, "unable to restrict the permissions of %s", socketPath)
+		}
+		return listener, nil
+	}
+
+	host, _, err := net.SplitHostPort(listenAddress)
+	if err != nil {
+		return nil, err
+	}
+	if host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return nil, errors.Errorf("%s is not a loopback address or a unix socket", listenAddress)
+		}
+	}
+	return net.Listen("tcp", listenAddress)
+}
+
+// serve accepts the tasks posted to the listener until the context is
+// cancelled
+func (runner *localTaskRunner) serve(ctx context.Context, listener net.Listener) int {
+	muxRouter := http.NewServeMux()
+	muxRouter.Handle(localTaskPath, runner)
+	server := http.Server{
+		Handler: muxRouter,
+	}
+	go func() {
+		<-ctx.Done()
+		if err := server.Shutdown(context.Background()); err != nil {
+			seelog.Infof("Local task server Shutdown: %v", err)
+		}
+	}()
+
+	seelog.Infof("Accepting local tasks on %s%s", listener.Addr().String(), localTaskPath)
+	if err := server.Serve(listener); err != http.ErrServerClosed {
+		seelog.Criticalf("Error serving local tasks: %v", err)
+		return exitcodes.ExitTerminal
+	}
+	return exitcodes.ExitSuccess
+}
+
+// localTaskResponse is the response to a posted task
+type localTaskResponse struct {
+	TaskARN string `json:"TaskARN,omitempty"`
+	Error   string `json:"Error,omitempty"`
+}
+
+// ServeHTTP adds the posted task to the task engine. Posting a task again
+// with a STOPPED desired status stops it.
+func (runner *localTaskRunner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeLocalTaskResponse(w, http.StatusMethodNotAllowed, localTaskResponse{
+			Error: "tasks must be posted",
+		})
+		return
+	}
+	acsTask, err := decodeLocalTask(http.MaxBytesReader(w, r.Body, maxLocalTaskSize))
+	if err != nil {
+		writeLocalTaskResponse(w, http.StatusBadRequest, localTaskResponse{
+			Error: fmt.Sprintf("unable to parse task: %v", err),
+		})
+		return
+	}
+	task, err := runner.addTask(acsTask)
+	if err != nil {
+		writeLocalTaskResponse(w, http.StatusBadRequest, localTaskResponse{
+			Error: fmt.Sprintf("unable to run task: %v", err),
+		})
+		return
+	}
+	writeLocalTaskResponse(w, http.StatusAccepted, localTaskResponse{TaskARN: task.Arn})
+}
+
+func writeLocalTaskResponse(w http.ResponseWriter, status int, response localTaskResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		seelog.Errorf("Unable to marshal local task response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,139 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/pkg/errors"
+)
+
+const (
+	localTaskStateChangeType       = "TaskStateChange"
+	localContainerStateChangeType  = "ContainerStateChange"
+	localAttachmentStateChangeType = "AttachmentStateChange"
+)
+
+// errLocalMode is returned by the calls to ECS that can't be made when tasks
+// are run locally
+var errLocalMode = errors.New("not supported when running tasks locally")
+
+// localStateChange is a state change printed when tasks are run locally
+type localStateChange struct {
+	Type          string             `json:"type"`
+	TaskARN       string             `json:"taskArn,omitempty"`
+	ContainerName string             `json:"containerName,omitempty"`
+	RuntimeID     string             `json:"runtimeId,omitempty"`
+	AttachmentARN string             `json:"attachmentArn,omitempty"`
+	Status        string             `json:"status"`
+	Reason        string             `json:"reason,omitempty"`
+	ExitCode      *int               `json:"exitCode,omitempty"`
+	Containers    []localStateChange `json:"containers,omitempty"`
+}
+
+// localECSClient stands in for the ECS client when tasks are run locally.
+// State changes are printed as lines of json instead of being submitted, and
+// the other calls to ECS fail.
+type localECSClient struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+// newLocalECSClient creates the ECS client of tasks run locally, which prints
+// state changes to the writer
+func newLocalECSClient(w io.Writer) api.ECSClient {
+	return &localECSClient{w: w}
+}
+
+func (client *localECSClient) RegisterContainerInstance(string, []*ecs.Attribute, []*ecs.Tag, string,
+	[]*ecs.PlatformDevice, string) (string, string, error) {
+	return "", "", errLocalMode
+}
+
+// SubmitTaskStateChange prints the task state change, along with the state
+// changes of its containers
+func (client *localECSClient) SubmitTaskStateChange(change api.TaskStateChange) error {
+	printed := localStateChange{
+		Type:    localTaskStateChangeType,
+		TaskARN: change.TaskARN,
+		Status:  change.Status.BackendStatus(),
+		Reason:  change.Reason,
+	}
+	for _, containerChange := range change.Containers {
+		printed.Containers = append(printed.Containers, newLocalContainerStateChange(containerChange))
+	}
+	return client.print(printed)
+}
+
+// SubmitContainerStateChange prints the container state change
+func (client *localECSClient) SubmitContainerStateChange(change api.ContainerStateChange) error {
+	return client.print(newLocalContainerStateChange(change))
+}
+
+// SubmitAttachmentStateChange prints the attachment state change
+func (client *localECSClient) SubmitAttachmentStateChange(change api.AttachmentStateChange) error {
+	if change.Attachment == nil {
+		return errors.New("attachment state change has no attachment")
+	}
+	return client.print(localStateChange{
+		Type:          localAttachmentStateChangeType,
+		TaskARN:       change.Attachment.TaskARN,
+		AttachmentARN: change.Attachment.AttachmentARN,
+		Status:        change.Attachment.Status.String(),
+	})
+}
+
+func (client *localECSClient) DiscoverPollEndpoint(string) (string, error) {
+	return "", errLocalMode
+}
+
+func (client *localECSClient) DiscoverTelemetryEndpoint(string) (string, error) {
+	return "", errLocalMode
+}
+
+// GetResourceTags returns no tags, as tasks run locally aren't tagged
+func (client *localECSClient) GetResourceTags(string) ([]*ecs.Tag, error) {
+	return nil, nil
+}
+
+func (client *localECSClient) UpdateContainerInstancesState(string, string) error {
+	return errLocalMode
+}
+
+func (client *localECSClient) print(change localStateChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal state change")
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	_, err = client.w.Write(append(data, '\n'))
+	return err
+}
+
+func newLocalContainerStateChange(change api.ContainerStateChange) localStateChange {
+	return localStateChange{
+		Type:          localContainerStateChangeType,
+		TaskARN:       change.TaskArn,
+		ContainerName: change.ContainerName,
+		RuntimeID:     change.RuntimeID,
+		Status:        change.Status.String(),
+		Reason:        change.Reason,
+		ExitCode:      change.ExitCode,
+	}
+}
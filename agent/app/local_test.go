@@ -0,0 +1,282 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	app_mocks "github.com/aws/amazon-ecs-agent/agent/app/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	aws_credentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const localTaskJSON = `{
+	"family": "local",
+	"version": "1",
+	"containers": [{"name": "app", "image": "busybox", "essential": true}]
+}`
+
+func newTestLocalTaskRunner(t *testing.T, ctrl *gomock.Controller) (*localTaskRunner,
+	*mock_engine.MockTaskEngine, credentials.Manager) {
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	provider := app_mocks.NewMockProvider(ctrl)
+	provider.EXPECT().Retrieve().Return(aws_credentials.Value{
+		AccessKeyID:     "akid",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+	}, nil).AnyTimes()
+	provider.EXPECT().IsExpired().Return(false).AnyTimes()
+	credentialsManager := credentials.NewManager()
+	return &localTaskRunner{
+		cfg:                &config.Config{AWSRegion: "us-west-2", Cluster: "local"},
+		taskEngine:         taskEngine,
+		credentialsManager: credentialsManager,
+		credentialProvider: aws_credentials.NewCredentials(provider),
+	}, taskEngine, credentialsManager
+}
+
+func TestLocalTaskRunnerAddTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	runner, taskEngine, credentialsManager := newTestLocalTaskRunner(t, ctrl)
+	runner.agentCredentials = true
+
+	acsTask, err := decodeLocalTask(strings.NewReader(localTaskJSON))
+	require.NoError(t, err)
+
+	var addedTask *apitask.Task
+	taskEngine.EXPECT().AddTask(gomock.Any()).Do(func(task *apitask.Task) {
+		addedTask = task
+	})
+	task, err := runner.addTask(acsTask)
+	require.NoError(t, err)
+	require.Equal(t, task, addedTask)
+
+	assert.True(t, strings.HasPrefix(task.Arn, "arn:aws:ecs:us-west-2:000000000000:task/local/"))
+	assert.Equal(t, apitaskstatus.TaskRunning, task.GetDesiredStatus())
+	require.Len(t, task.Containers, 1)
+	assert.Equal(t, "busybox", task.Containers[0].Image)
+
+	roleCredentials, ok := credentialsManager.GetTaskCredentials(task.GetCredentialsID())
+	require.True(t, ok)
+	assert.Equal(t, "akid", roleCredentials.IAMRoleCredentials.AccessKeyID)
+	assert.Equal(t, credentials.ApplicationRoleType, roleCredentials.IAMRoleCredentials.RoleType)
+	executionRoleCredentials, ok := credentialsManager.GetTaskCredentials(task.GetExecutionCredentialsID())
+	require.True(t, ok)
+	assert.Equal(t, "token", executionRoleCredentials.IAMRoleCredentials.SessionToken)
+	assert.Equal(t, credentials.ExecutionRoleType, executionRoleCredentials.IAMRoleCredentials.RoleType)
+}
+
+func TestLocalTaskRunnerAddTaskWithoutAgentCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	runner, taskEngine, credentialsManager := newTestLocalTaskRunner(t, ctrl)
+
+	acsTask, err := decodeLocalTask(strings.NewReader(localTaskJSON))
+	require.NoError(t, err)
+
+	taskEngine.EXPECT().AddTask(gomock.Any())
+	task, err := runner.addTask(acsTask)
+	require.NoError(t, err)
+	assert.Empty(t, task.GetCredentialsID())
+	assert.Empty(t, task.GetExecutionCredentialsID())
+	_, ok := credentialsManager.GetTaskCredentials(task.GetCredentialsID())
+	assert.False(t, ok)
+}
+
+func TestLocalTaskRunnerAddTaskWithCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	runner, taskEngine, credentialsManager := newTestLocalTaskRunner(t, ctrl)
+
+	acsTask, err := decodeLocalTask(strings.NewReader(localTaskJSON))
+	require.NoError(t, err)
+	acsTask.Arn = aws.String("arn:aws:ecs:us-west-2:123456789012:task/local/t1")
+	acsTask.RoleCredentials = &ecsacs.IAMRoleCredentials{
+		CredentialsId:   aws.String("credsid"),
+		AccessKeyId:     aws.String("taskakid"),
+		SecretAccessKey: aws.String("tasksecret"),
+	}
+
+	taskEngine.EXPECT().AddTask(gomock.Any())
+	task, err := runner.addTask(acsTask)
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:ecs:us-west-2:123456789012:task/local/t1", task.Arn)
+	assert.Equal(t, "credsid", task.GetCredentialsID())
+
+	roleCredentials, ok := credentialsManager.GetTaskCredentials("credsid")
+	require.True(t, ok)
+	assert.Equal(t, "taskakid", roleCredentials.IAMRoleCredentials.AccessKeyID)
+}
+
+func TestLocalTaskRunnerAddTaskWithENI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	runner, _, _ := newTestLocalTaskRunner(t, ctrl)
+
+	acsTask, err := decodeLocalTask(strings.NewReader(localTaskJSON))
+	require.NoError(t, err)
+	acsTask.ElasticNetworkInterfaces = []*ecsacs.ElasticNetworkInterface{{}}
+
+	_, err = runner.addTask(acsTask)
+	assert.Error(t, err)
+}
+
+func TestLocalTaskRunnerServeHTTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	runner, taskEngine, _ := newTestLocalTaskRunner(t, ctrl)
+
+	taskEngine.EXPECT().AddTask(gomock.Any())
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, localTaskPath, strings.NewReader(localTaskJSON))
+	runner.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	var response localTaskResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.TaskARN)
+	assert.Empty(t, response.Error)
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, localTaskPath, nil)
+	runner.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, localTaskPath, strings.NewReader("{"))
+	runner.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.Error)
+}
+
+func TestLocalTaskListener(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:0", "localhost:0", "[::1]:0"} {
+		t.Run(address, func(t *testing.T) {
+			listener, err := localTaskListener(address)
+			if address == "[::1]:0" && err != nil {
+				t.Skip("IPv6 is not available")
+			}
+			require.NoError(t, err)
+			listener.Close()
+		})
+	}
+	for _, address := range []string{":8080", "0.0.0.0:8080", "10.0.0.1:8080", "example.com:8080", "unix:", "127.0.0.1"} {
+		t.Run(address, func(t *testing.T) {
+			_, err := localTaskListener(address)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLocalTaskListenerUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local-task")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "tasks.sock")
+	listener, err := localTaskListener("unix:" + socketPath)
+	require.NoError(t, err)
+	defer listener.Close()
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(localTaskUnixSocketPerm), info.Mode().Perm())
+}
+
+func TestLoadLocalTask(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local-task")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	taskFile := filepath.Join(dir, "task.json")
+	require.NoError(t, ioutil.WriteFile(taskFile, []byte(localTaskJSON), 0644))
+	acsTask, err := loadLocalTask(taskFile)
+	require.NoError(t, err)
+	assert.Equal(t, "local", aws.StringValue(acsTask.Family))
+
+	_, err = loadLocalTask(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestTasksStopped(t *testing.T) {
+	task := &apitask.Task{}
+	assert.False(t, tasksStopped([]*apitask.Task{task}))
+	task.SetSentStatus(apitaskstatus.TaskStopped)
+	assert.True(t, tasksStopped([]*apitask.Task{task}))
+}
+
+func TestLocalECSClientPrintsStateChanges(t *testing.T) {
+	var out bytes.Buffer
+	client := newLocalECSClient(&out)
+
+	exitCode := 1
+	containerChange := api.ContainerStateChange{
+		TaskArn:       "t1",
+		ContainerName: "app",
+		RuntimeID:     "cid",
+		Status:        apicontainerstatus.ContainerStopped,
+		Reason:        "exited",
+		ExitCode:      &exitCode,
+	}
+	require.NoError(t, client.SubmitContainerStateChange(containerChange))
+	require.NoError(t, client.SubmitTaskStateChange(api.TaskStateChange{
+		TaskARN:    "t1",
+		Status:     apitaskstatus.TaskStopped,
+		Containers: []api.ContainerStateChange{containerChange},
+	}))
+	_, err := client.DiscoverPollEndpoint("")
+	assert.Error(t, err)
+
+	var printed []localStateChange
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var change localStateChange
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &change))
+		printed = append(printed, change)
+	}
+	require.Len(t, printed, 2)
+
+	assert.Equal(t, localContainerStateChangeType, printed[0].Type)
+	assert.Equal(t, "app", printed[0].ContainerName)
+	assert.Equal(t, "STOPPED", printed[0].Status)
+	assert.Equal(t, 1, aws.IntValue(printed[0].ExitCode))
+
+	assert.Equal(t, localTaskStateChangeType, printed[1].Type)
+	assert.Equal(t, "t1", printed[1].TaskARN)
+	assert.Equal(t, "STOPPED", printed[1].Status)
+	require.Len(t, printed[1].Containers, 1)
+	assert.Equal(t, "cid", printed[1].Containers[0].RuntimeID)
+}
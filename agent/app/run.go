@@ -44,12 +44,31 @@ func Run(arguments []string) int {
 		// issue within agent logs.
 		// see https://docs.docker.com/engine/reference/builder/#healthcheck
 		return runHealthcheck("http://localhost:51678/v1/metadata", time.Second*25)
+	} else if parsedArgs.IsStateCommand() || *parsedArgs.FirelensRender != "" {
+		// Only critical messages are logged by default, so that they don't get
+		// mixed up with the printed state or config
+		if *parsedArgs.LogLevel == "" {
+			logger.SetLevel("crit")
+		} else {
+			logger.SetLevel(*parsedArgs.LogLevel)
+		}
+		if *parsedArgs.FirelensRender != "" {
+			return runFirelensRender(*parsedArgs.FirelensRender)
+		}
+		return runStateCommand(parsedArgs)
 	}
 
 	logger.SetLevel(*parsedArgs.LogLevel)
+	if parsedArgs.IsLocalMode() {
+		// State changes are printed to stdout when tasks are run locally
+		logger.SetConsoleStderr()
+	}
 
 	// Create an Agent object
-	agent, err := newAgent(aws.BoolValue(parsedArgs.BlackholeEC2Metadata), parsedArgs.AcceptInsecureCert)
+	// The EC2 metadata isn't needed to run tasks locally, as the container
+	// instance isn't registered
+	blackholeEC2Metadata := aws.BoolValue(parsedArgs.BlackholeEC2Metadata) || parsedArgs.IsLocalMode()
+	agent, err := newAgent(blackholeEC2Metadata, parsedArgs.AcceptInsecureCert)
 	if err != nil {
 		// Failure to initialize either the docker client or the EC2 metadata
 		// service client are non terminal errors as they could be transient
@@ -60,6 +79,10 @@ func Run(arguments []string) int {
 	case *parsedArgs.ECSAttributes:
 		// Print agent's ecs attributes based on its environment and exit
 		return agent.printECSAttributes()
+	case parsedArgs.IsLocalMode():
+		// Run tasks without connecting to ECS
+		return agent.startLocal(*parsedArgs.LocalTask, *parsedArgs.LocalTaskListen,
+			*parsedArgs.LocalTaskAgentCredentials)
 	case *parsedArgs.WindowsService:
 		// Enable Windows Service
 		return agent.startWindowsService()
@@ -0,0 +1,539 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/app/args"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmauth"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmsecret"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/credentialspec"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/envFiles"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/firelens"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/vaultsecret"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// resourceDataVersions maps the task resources that were added to the
+// 'resources' field of tasks after it was introduced in data version 13 to the
+// data version that added them. Agents supporting an older data version fail
+// to load a task holding such a resource.
+var resourceDataVersions = map[string]int{
+	asmauth.ResourceName:        14,
+	ssmsecret.ResourceName:      17,
+	asmsecret.ResourceName:      18,
+	firelens.ResourceName:       23,
+	credentialspec.ResourceName: 26,
+	envFiles.ResourceName:       28,
+	vaultsecret.ResourceName:    32,
+}
+
+// oldestDowngradeDataVersion is the oldest data version that the state can be
+// saved as. taskFieldDataVersions only lists the fields added after it, so
+// tasks can't be checked against older data versions.
+const oldestDowngradeDataVersion = 28
+
+// taskFieldDataVersions lists the data versions that added fields to the
+// tasks, their containers or their resources, along with the function
+// reporting whether a task holds data in these fields. Agents supporting an
+// older data version ignore these fields, and would manage such a task
+// differently, so it can't be saved as an older data version.
+var taskFieldDataVersions = []struct {
+	version     int
+	description string
+	used        func(task *apitask.Task) bool
+}{
+	{29, "pids and block IO limits", hasPidsOrBlockIOLimits},
+	{30, "containers killed for running out of memory", hasOOMKilledContainers},
+	{31, "secrets vended as files", hasSecretFiles},
+	{33, "envfile variable names", hasEnvironmentFileVariableNames},
+}
+
+// hasPidsOrBlockIOLimits returns whether the cgroup of the task has pids or
+// block IO limits
+func hasPidsOrBlockIOLimits(task *apitask.Task) bool {
+	return task.PidsLimit != 0 || task.BlockIOWeight != 0 || len(task.BlockIODeviceLimits) != 0
+}
+
+// hasOOMKilledContainers returns whether a container of the task was killed
+// for running out of memory
+func hasOOMKilledContainers(task *apitask.Task) bool {
+	for _, container := range task.Containers {
+		if container.GetOOMKilled() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSecretFiles returns whether a container of the task gets secrets vended
+// as files
+func hasSecretFiles(task *apitask.Task) bool {
+	for _, container := range task.Containers {
+		for _, secret := range container.Secrets {
+			if secret.Type == apicontainer.SecretTypeMountPoint {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasEnvironmentFileVariableNames returns whether the names of the variables
+// read from the environment files of the task were saved
+func hasEnvironmentFileVariableNames(task *apitask.Task) bool {
+	for _, resource := range task.ResourcesMapUnsafe[envFiles.ResourceName] {
+		envfileResource, ok := resource.(*envFiles.EnvironmentFileResource)
+		if !ok {
+			continue
+		}
+		for _, resolved := range envfileResource.GetResolvedEnvironmentFiles() {
+			if len(resolved.VariableNames) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// savedAgentState holds the state saved by the agent, loaded with the same
+// saveables as the agent uses
+type savedAgentState struct {
+	stateManager statemanager.StateManager
+
+	DataVersion                 int
+	TaskEngine                  dockerstate.TaskEngineState
+	Cluster                     string
+	ContainerInstanceARN        string
+	EC2InstanceID               string
+	AvailabilityZone            string
+	LatestSeqNumberTaskManifest int64
+}
+
+// stateSummary is the summary of the saved state printed by --state-inspect
+type stateSummary struct {
+	DataDir                     string
+	StateStore                  string
+	DataVersion                 int
+	SupportedDataVersion        int
+	Cluster                     string
+	ContainerInstanceARN        string
+	EC2InstanceID               string
+	AvailabilityZone            string
+	LatestSeqNumberTaskManifest int64
+	Tasks                       []taskSummary
+	Containers                  []containerSummary
+	ImageStates                 []imageStateSummary
+	ENIAttachments              []eniAttachmentSummary
+}
+
+type taskSummary struct {
+	Arn           string
+	Family        string
+	Version       string
+	KnownStatus   string
+	DesiredStatus string
+	Resources     []string
+}
+
+type containerSummary struct {
+	TaskArn       string
+	Name          string
+	DockerID      string
+	DockerName    string
+	Image         string
+	KnownStatus   string
+	DesiredStatus string
+}
+
+type imageStateSummary struct {
+	ImageID    string
+	Names      []string
+	PulledAt   time.Time
+	LastUsedAt time.Time
+}
+
+type eniAttachmentSummary struct {
+	MACAddress    string
+	TaskArn       string
+	AttachmentArn string
+	Status        string
+}
+
+// runStateCommand runs the offline operations on the saved state requested by
+// the arguments. Tasks are dropped first, then the state is downgraded and
+// finally printed, so that these can be combined in a single invocation.
+func runStateCommand(parsedArgs *args.Args) int {
+	cfg, err := config.NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	if err != nil {
+		// Only the data directory and the state store are needed, neither of
+		// which depend on the settings that failed to load, such as the region
+		seelog.Debugf("Error loading config, continuing with data directory %s: %v", cfg.DataDir, err)
+	}
+	return runStateCommandWithConfig(cfg, parsedArgs, os.Stdout)
+}
+
+func runStateCommandWithConfig(cfg *config.Config, parsedArgs *args.Args, w io.Writer) int {
+	if *parsedArgs.StateValidate {
+		return validateState(cfg, w)
+	}
+	// The data version is checked before the state is loaded, so that tasks
+	// aren't dropped if it can't be downgraded anyway
+	if *parsedArgs.StateDowngrade != 0 && *parsedArgs.StateDowngrade < oldestDowngradeDataVersion {
+		seelog.Criticalf("Unable to downgrade the saved state: data version %d is older than %d, the oldest data version the state can be saved as",
+			*parsedArgs.StateDowngrade, oldestDowngradeDataVersion)
+		return exitcodes.ExitError
+	}
+
+	saved, err := loadSavedAgentState(cfg)
+	if err != nil {
+		seelog.Criticalf("Unable to load the state saved in %s: %v", cfg.DataDir, err)
+		return exitcodes.ExitError
+	}
+	defer saved.close()
+
+	if *parsedArgs.StateDropTask != "" {
+		if err := saved.dropTasks(strings.Split(*parsedArgs.StateDropTask, ",")); err != nil {
+			seelog.Criticalf("Unable to drop tasks from the saved state: %v", err)
+			return exitcodes.ExitError
+		}
+		fmt.Fprintf(w, "Dropped tasks %s from the state saved in %s\n", *parsedArgs.StateDropTask, cfg.DataDir)
+	}
+	if *parsedArgs.StateDowngrade != 0 {
+		if err := saved.downgrade(*parsedArgs.StateDowngrade); err != nil {
+			seelog.Criticalf("Unable to downgrade the saved state: %v", err)
+			return exitcodes.ExitError
+		}
+		fmt.Fprintf(w, "Downgraded the state saved in %s to data version %d\n", cfg.DataDir, *parsedArgs.StateDowngrade)
+	}
+	if *parsedArgs.StateInspect {
+		summary := saved.summary(cfg)
+		if *parsedArgs.StateJSON {
+			err = printStateJSON(summary, w)
+		} else {
+			err = printStateTables(summary, w)
+		}
+		if err != nil {
+			seelog.Criticalf("Unable to print the saved state: %v", err)
+			return exitcodes.ExitError
+		}
+	}
+	return exitcodes.ExitSuccess
+}
+
+// loadSavedAgentState loads the state saved in the data directory of the
+// given config
+func loadSavedAgentState(cfg *config.Config) (*savedAgentState, error) {
+	version, err := statemanager.DataVersion(cfg)
+	if err != nil {
+		return nil, err
+	}
+	saved := &savedAgentState{
+		DataVersion: version,
+		TaskEngine:  dockerstate.NewTaskEngineState(),
+	}
+	saved.stateManager, err = statemanager.NewStateManager(cfg,
+		statemanager.AddSaveable("TaskEngine", saved.TaskEngine),
+		statemanager.AddSaveable("ContainerInstanceArn", &saved.ContainerInstanceARN),
+		statemanager.AddSaveable("Cluster", &saved.Cluster),
+		statemanager.AddSaveable("EC2InstanceID", &saved.EC2InstanceID),
+		statemanager.AddSaveable("availabilityZone", &saved.AvailabilityZone),
+		statemanager.AddSaveable("latestSeqNumberTaskManifest", &saved.LatestSeqNumberTaskManifest),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := saved.stateManager.Load(); err != nil {
+		saved.close()
+		return nil, err
+	}
+	return saved, nil
+}
+
+// close releases the state store
+func (saved *savedAgentState) close() {
+	closeStateManager(saved.stateManager)
+}
+
+// dropTasks removes the given tasks, along with their containers, ip address
+// and eni attachments, from the state and saves it
+func (saved *savedAgentState) dropTasks(taskARNs []string) error {
+	for _, taskARN := range taskARNs {
+		task, ok := saved.TaskEngine.TaskByArn(strings.TrimSpace(taskARN))
+		if !ok {
+			return errors.Errorf("task %s is not in the saved state", taskARN)
+		}
+		saved.TaskEngine.RemoveTask(task)
+		for _, eniAttachment := range saved.TaskEngine.AllENIAttachments() {
+			if eniAttachment.TaskARN == task.Arn {
+				saved.TaskEngine.RemoveENIAttachment(eniAttachment.MACAddress)
+			}
+		}
+	}
+	return saved.stateManager.ForceSave()
+}
+
+// downgrade saves the state as the given older data version, after making
+// sure that no task holds a resource that an agent supporting only that data
+// version would fail to load, or data in fields that it would ignore. The
+// state can't be saved again afterwards.
+func (saved *savedAgentState) downgrade(version int) error {
+	var incompatible []string
+	for _, task := range saved.TaskEngine.AllTasks() {
+		for resourceType := range task.ResourcesMapUnsafe {
+			if resourceVersion, ok := resourceDataVersions[resourceType]; ok && resourceVersion > version {
+				incompatible = append(incompatible, fmt.Sprintf("%s (%s resource)", task.Arn, resourceType))
+			}
+		}
+		for _, fields := range taskFieldDataVersions {
+			if fields.version > version && fields.used(task) {
+				incompatible = append(incompatible, fmt.Sprintf("%s (%s)", task.Arn, fields.description))
+			}
+		}
+	}
+	if len(incompatible) != 0 {
+		sort.Strings(incompatible)
+		return errors.Errorf("tasks can't be saved as data version %d, drop them with --state-drop-task first: %s",
+			version, strings.Join(incompatible, ", "))
+	}
+	return statemanager.ForceSaveAsVersion(saved.stateManager, version)
+}
+
+// summary returns the summary of the state, sorted for stable output
+func (saved *savedAgentState) summary(cfg *config.Config) *stateSummary {
+	summary := &stateSummary{
+		DataDir:                     cfg.DataDir,
+		StateStore:                  cfg.StateStore,
+		DataVersion:                 saved.DataVersion,
+		SupportedDataVersion:        statemanager.ECSDataVersion,
+		Cluster:                     saved.Cluster,
+		ContainerInstanceARN:        saved.ContainerInstanceARN,
+		EC2InstanceID:               saved.EC2InstanceID,
+		AvailabilityZone:            saved.AvailabilityZone,
+		LatestSeqNumberTaskManifest: saved.LatestSeqNumberTaskManifest,
+		Tasks:                       []taskSummary{},
+		Containers:                  []containerSummary{},
+		ImageStates:                 []imageStateSummary{},
+		ENIAttachments:              []eniAttachmentSummary{},
+	}
+
+	for _, task := range saved.TaskEngine.AllTasks() {
+		resources := []string{}
+		for resourceType := range task.ResourcesMapUnsafe {
+			resources = append(resources, resourceType)
+		}
+		sort.Strings(resources)
+		summary.Tasks = append(summary.Tasks, taskSummary{
+			Arn:           task.Arn,
+			Family:        task.Family,
+			Version:       task.Version,
+			KnownStatus:   task.GetKnownStatus().String(),
+			DesiredStatus: task.GetDesiredStatus().String(),
+			Resources:     resources,
+		})
+
+		containers, _ := saved.TaskEngine.ContainerMapByArn(task.Arn)
+		for _, dockerContainer := range containers {
+			summary.Containers = append(summary.Containers, containerSummary{
+				TaskArn:       task.Arn,
+				Name:          dockerContainer.Container.Name,
+				DockerID:      dockerContainer.DockerID,
+				DockerName:    dockerContainer.DockerName,
+				Image:         dockerContainer.Container.Image,
+				KnownStatus:   dockerContainer.Container.GetKnownStatus().String(),
+				DesiredStatus: dockerContainer.Container.GetDesiredStatus().String(),
+			})
+		}
+	}
+	sort.Slice(summary.Tasks, func(i, j int) bool {
+		return summary.Tasks[i].Arn < summary.Tasks[j].Arn
+	})
+	sort.Slice(summary.Containers, func(i, j int) bool {
+		if summary.Containers[i].TaskArn != summary.Containers[j].TaskArn {
+			return summary.Containers[i].TaskArn < summary.Containers[j].TaskArn
+		}
+		return summary.Containers[i].Name < summary.Containers[j].Name
+	})
+
+	for _, imageState := range saved.TaskEngine.AllImageStates() {
+		summary.ImageStates = append(summary.ImageStates, imageStateSummary{
+			ImageID:    imageState.Image.ImageID,
+			Names:      imageState.Image.Names,
+			PulledAt:   imageState.PulledAt,
+			LastUsedAt: imageState.LastUsedAt,
+		})
+	}
+	sort.Slice(summary.ImageStates, func(i, j int) bool {
+		return summary.ImageStates[i].ImageID < summary.ImageStates[j].ImageID
+	})
+
+	for _, eniAttachment := range saved.TaskEngine.AllENIAttachments() {
+		summary.ENIAttachments = append(summary.ENIAttachments, eniAttachmentSummary{
+			MACAddress:    eniAttachment.MACAddress,
+			TaskArn:       eniAttachment.TaskARN,
+			AttachmentArn: eniAttachment.AttachmentARN,
+			Status:        eniAttachment.Status.String(),
+		})
+	}
+	sort.Slice(summary.ENIAttachments, func(i, j int) bool {
+		return summary.ENIAttachments[i].MACAddress < summary.ENIAttachments[j].MACAddress
+	})
+	return summary
+}
+
+func printStateJSON(summary *stateSummary, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summary)
+}
+
+func printStateTables(summary *stateSummary, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Data directory:\t%s\n", summary.DataDir)
+	fmt.Fprintf(tw, "State store:\t%s\n", summary.StateStore)
+	fmt.Fprintf(tw, "Data version:\t%d (supported: %d)\n", summary.DataVersion, summary.SupportedDataVersion)
+	fmt.Fprintf(tw, "Cluster:\t%s\n", summary.Cluster)
+	fmt.Fprintf(tw, "Container instance:\t%s\n", summary.ContainerInstanceARN)
+	fmt.Fprintf(tw, "EC2 instance:\t%s\n", summary.EC2InstanceID)
+	fmt.Fprintf(tw, "Availability zone:\t%s\n", summary.AvailabilityZone)
+	fmt.Fprintf(tw, "Task manifest sequence number:\t%d\n", summary.LatestSeqNumberTaskManifest)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nTASKS\n")
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ARN\tFAMILY\tKNOWN\tDESIRED\tRESOURCES")
+	for _, task := range summary.Tasks {
+		fmt.Fprintf(tw, "%s\t%s:%s\t%s\t%s\t%s\n", task.Arn, task.Family, task.Version,
+			task.KnownStatus, task.DesiredStatus, strings.Join(task.Resources, ","))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nCONTAINERS\n")
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK ARN\tNAME\tDOCKER ID\tIMAGE\tKNOWN\tDESIRED")
+	for _, container := range summary.Containers {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", container.TaskArn, container.Name, container.DockerID,
+			container.Image, container.KnownStatus, container.DesiredStatus)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nIMAGE STATES\n")
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "IMAGE ID\tNAMES\tPULLED AT\tLAST USED AT")
+	for _, imageState := range summary.ImageStates {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", imageState.ImageID, strings.Join(imageState.Names, ","),
+			imageState.PulledAt.Format(time.RFC3339), imageState.LastUsedAt.Format(time.RFC3339))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nENI ATTACHMENTS\n")
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "MAC ADDRESS\tTASK ARN\tATTACHMENT ARN\tSTATUS")
+	for _, eniAttachment := range summary.ENIAttachments {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", eniAttachment.MACAddress, eniAttachment.TaskArn,
+			eniAttachment.AttachmentArn, eniAttachment.Status)
+	}
+	return tw.Flush()
+}
+
+// validateState checks that the saved state can be loaded by this agent and
+// is consistent, printing the problems found
+func validateState(cfg *config.Config, w io.Writer) int {
+	version, err := statemanager.DataVersion(cfg)
+	if err != nil {
+		fmt.Fprintf(w, "Unable to read the version of the state saved in %s: %v\n", cfg.DataDir, err)
+		return exitcodes.ExitError
+	}
+	if version > statemanager.ECSDataVersion {
+		fmt.Fprintf(w, "The state saved in %s is data version %d, which is newer than the supported data version %d\n",
+			cfg.DataDir, version, statemanager.ECSDataVersion)
+		return exitcodes.ExitError
+	}
+
+	saved, err := loadSavedAgentState(cfg)
+	if err != nil {
+		fmt.Fprintf(w, "The state saved in %s can't be loaded: %v\n", cfg.DataDir, err)
+		return exitcodes.ExitError
+	}
+	defer saved.close()
+
+	problems, err := saved.validate()
+	if err != nil {
+		fmt.Fprintf(w, "Unable to validate the state saved in %s: %v\n", cfg.DataDir, err)
+		return exitcodes.ExitError
+	}
+	if len(problems) != 0 {
+		fmt.Fprintf(w, "The state saved in %s is data version %d and is inconsistent:\n", cfg.DataDir, version)
+		for _, problem := range problems {
+			fmt.Fprintf(w, "  %s\n", problem)
+		}
+		return exitcodes.ExitError
+	}
+	fmt.Fprintf(w, "The state saved in %s is data version %d and is valid\n", cfg.DataDir, version)
+	return exitcodes.ExitSuccess
+}
+
+// validate returns the references to tasks that are not in the state. These
+// are not checked when the state is loaded, but the agent doesn't expect them.
+func (saved *savedAgentState) validate() ([]string, error) {
+	var problems []string
+	for _, eniAttachment := range saved.TaskEngine.AllENIAttachments() {
+		if _, ok := saved.TaskEngine.TaskByArn(eniAttachment.TaskARN); !ok {
+			problems = append(problems, fmt.Sprintf("eni attachment %s belongs to unknown task %s",
+				eniAttachment.MACAddress, eniAttachment.TaskARN))
+		}
+	}
+
+	records, err := saved.TaskEngine.Records()
+	if err != nil {
+		return nil, err
+	}
+	for ipAddr, data := range records[dockerstate.IPToTaskRecordType] {
+		var taskARN string
+		if err := json.Unmarshal(data, &taskARN); err != nil {
+			return nil, err
+		}
+		if _, ok := saved.TaskEngine.TaskByArn(taskARN); !ok {
+			problems = append(problems, fmt.Sprintf("ip address %s belongs to unknown task %s", ipAddr, taskARN))
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
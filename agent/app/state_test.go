@@ -0,0 +1,234 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/app/args"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const savedTaskARN = "arn:aws:ecs:us-west-2:123456789011:task/70947c96-f64e-483a-a612-3fd4303546e7"
+
+// setupSavedState copies a state file saved with data version 27 into a new
+// data directory
+func setupSavedState(t *testing.T) (string, func()) {
+	return setupSavedStateFrom(t, filepath.Join("v28", "environmentFiles"))
+}
+
+// setupSavedStateFrom copies the state file of the given state manager test
+// data directory into a new data directory
+func setupSavedStateFrom(t *testing.T, testDataDir string) (string, func()) {
+	dataDir, err := ioutil.TempDir("", "state_test")
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join("..", "statemanager", "testdata", testDataDir, "ecs_agent_data.json"))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dataDir, "ecs_agent_data.json"), data, 0600))
+	return dataDir, func() { os.RemoveAll(dataDir) }
+}
+
+func runStateCommandForTest(t *testing.T, cfg *config.Config, arguments ...string) (int, string) {
+	parsedArgs, err := args.New(arguments)
+	require.NoError(t, err)
+	require.True(t, parsedArgs.IsStateCommand())
+	var out bytes.Buffer
+	exitCode := runStateCommandWithConfig(cfg, parsedArgs, &out)
+	return exitCode, out.String()
+}
+
+func readSavedDataVersion(t *testing.T, dataDir string) int {
+	data, err := ioutil.ReadFile(filepath.Join(dataDir, "ecs_agent_data.json"))
+	require.NoError(t, err)
+	var saved struct{ Version int }
+	require.NoError(t, json.Unmarshal(data, &saved))
+	return saved.Version
+}
+
+func TestStateInspectJSON(t *testing.T) {
+	dataDir, cleanup := setupSavedState(t)
+	defer cleanup()
+	cfg := &config.Config{DataDir: dataDir, StateStore: config.StateStoreJSON}
+
+	exitCode, out := runStateCommandForTest(t, cfg, "--state-inspect", "--state-json")
+	require.Equal(t, exitcodes.ExitSuccess, exitCode)
+
+	var summary stateSummary
+	require.NoError(t, json.Unmarshal([]byte(out), &summary))
+	assert.Equal(t, 27, summary.DataVersion)
+	assert.Equal(t, "state-file", summary.Cluster)
+	require.Len(t, summary.Tasks, 1)
+	assert.Equal(t, savedTaskARN, summary.Tasks[0].Arn)
+	assert.Equal(t, "sleep360", summary.Tasks[0].Family)
+	assert.Equal(t, []string{"envfile"}, summary.Tasks[0].Resources)
+	require.Len(t, summary.Containers, 1)
+	assert.Equal(t, savedTaskARN, summary.Containers[0].TaskArn)
+	require.Len(t, summary.ImageStates, 1)
+	assert.Equal(t, []string{"busybox"}, summary.ImageStates[0].Names)
+	assert.Empty(t, summary.ENIAttachments)
+}
+
+func TestStateInspectTables(t *testing.T) {
+	dataDir, cleanup := setupSavedState(t)
+	defer cleanup()
+	cfg := &config.Config{DataDir: dataDir, StateStore: config.StateStoreJSON}
+
+	exitCode, out := runStateCommandForTest(t, cfg, "--state-inspect")
+	require.Equal(t, exitcodes.ExitSuccess, exitCode)
+	assert.Contains(t, out, "state-file")
+	assert.Contains(t, out, "TASKS")
+	assert.Contains(t, out, savedTaskARN)
+	assert.Contains(t, out, "busybox")
+}
+
+func TestStateValidate(t *testing.T) {
+	dataDir, cleanup := setupSavedState(t)
+	defer cleanup()
+	cfg := &config.Config{DataDir: dataDir, StateStore: config.StateStoreJSON}
+
+	exitCode, out := runStateCommandForTest(t, cfg, "--state-validate")
+	assert.Equal(t, exitcodes.ExitSuccess, exitCode)
+	assert.Contains(t, out, "is valid")
+
+	newerState := []byte(`{"Data":{"Cluster":"newer"},"Version":1000}`)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dataDir, "ecs_agent_data.json"), newerState, 0600))
+	exitCode, out = runStateCommandForTest(t, cfg, "--state-validate")
+	assert.Equal(t, exitcodes.ExitError, exitCode)
+	assert.Contains(t, out, "newer than the supported data version")
+}
+
+func TestStateDropTask(t *testing.T) {
+	dataDir, cleanup := setupSavedState(t)
+	defer cleanup()
+	cfg := &config.Config{DataDir: dataDir, StateStore: config.StateStoreJSON}
+
+	exitCode, _ := runStateCommandForTest(t, cfg, "--state-drop-task", "arn:aws:ecs:us-west-2:123456789011:task/unknown")
+	assert.Equal(t, exitcodes.ExitError, exitCode)
+
+	exitCode, _ = runStateCommandForTest(t, cfg, "--state-drop-task", savedTaskARN)
+	require.Equal(t, exitcodes.ExitSuccess, exitCode)
+
+	saved, err := loadSavedAgentState(cfg)
+	require.NoError(t, err)
+	defer saved.close()
+	assert.Empty(t, saved.TaskEngine.AllTasks())
+	assert.Empty(t, saved.TaskEngine.GetAllContainerIDs())
+	assert.Len(t, saved.TaskEngine.AllImageStates(), 1)
+	assert.Equal(t, "state-file", saved.Cluster)
+}
+
+func TestStateDowngrade(t *testing.T) {
+	dataDir, cleanup := setupSavedStateFrom(t, filepath.Join("v32", "vaultSecret"))
+	defer cleanup()
+	cfg := &config.Config{DataDir: dataDir, StateStore: config.StateStoreJSON}
+
+	// The saved task uses a vaultsecret resource, which can't be read by
+	// agents supporting data versions older than 32
+	exitCode, _ := runStateCommandForTest(t, cfg, "--state-downgrade", "31")
+	assert.Equal(t, exitcodes.ExitError, exitCode)
+	assert.Equal(t, 32, readSavedDataVersion(t, dataDir))
+
+	exitCode, _ = runStateCommandForTest(t, cfg, "--state-drop-task", savedTaskARN, "--state-downgrade", "31")
+	require.Equal(t, exitcodes.ExitSuccess, exitCode)
+	assert.Equal(t, 31, readSavedDataVersion(t, dataDir))
+}
+
+func TestStateDowngradeOldestDataVersion(t *testing.T) {
+	for _, fields := range taskFieldDataVersions {
+		assert.True(t, fields.version > oldestDowngradeDataVersion,
+			"fields of data version %d should be newer than the oldest downgrade data version", fields.version)
+	}
+
+	dataDir, cleanup := setupSavedState(t)
+	defer cleanup()
+	cfg := &config.Config{DataDir: dataDir, StateStore: config.StateStoreJSON}
+
+	// The fields added by data versions older than 28 aren't known, so the
+	// state can't be saved as these versions, even without tasks. The tasks
+	// are not dropped either.
+	exitCode, _ := runStateCommandForTest(t, cfg, "--state-drop-task", savedTaskARN, "--state-downgrade", "27")
+	assert.Equal(t, exitcodes.ExitError, exitCode)
+	assert.Equal(t, 27, readSavedDataVersion(t, dataDir))
+	saved, err := loadSavedAgentState(cfg)
+	require.NoError(t, err)
+	assert.Len(t, saved.TaskEngine.AllTasks(), 1)
+	saved.close()
+
+	// The saved task uses an envfile resource, which was added by data
+	// version 28
+	exitCode, _ = runStateCommandForTest(t, cfg, "--state-downgrade", "28")
+	require.Equal(t, exitcodes.ExitSuccess, exitCode)
+	assert.Equal(t, 28, readSavedDataVersion(t, dataDir))
+}
+
+func TestStateDowngradeTaskFields(t *testing.T) {
+	for _, tc := range []struct {
+		testDataDir  string
+		savedVersion int
+	}{
+		{filepath.Join("v29", "taskLimits"), 29},
+		{filepath.Join("v30", "oomKilled"), 30},
+		{filepath.Join("v31", "secretFiles"), 31},
+		{filepath.Join("v33", "environmentFiles"), 33},
+	} {
+		t.Run(tc.testDataDir, func(t *testing.T) {
+			dataDir, cleanup := setupSavedStateFrom(t, tc.testDataDir)
+			defer cleanup()
+			cfg := &config.Config{DataDir: dataDir, StateStore: config.StateStoreJSON}
+
+			// The saved task holds data in fields that agents supporting older
+			// data versions would ignore
+			exitCode, _ := runStateCommandForTest(t, cfg, "--state-downgrade", strconv.Itoa(tc.savedVersion-1))
+			assert.Equal(t, exitcodes.ExitError, exitCode)
+			assert.Equal(t, tc.savedVersion, readSavedDataVersion(t, dataDir))
+
+			exitCode, _ = runStateCommandForTest(t, cfg, "--state-drop-task", savedTaskARN,
+				"--state-downgrade", strconv.Itoa(tc.savedVersion-1))
+			require.Equal(t, exitcodes.ExitSuccess, exitCode)
+			assert.Equal(t, tc.savedVersion-1, readSavedDataVersion(t, dataDir))
+		})
+	}
+}
+
+func TestStateDowngradeBoltDB(t *testing.T) {
+	dataDir, cleanup := setupSavedState(t)
+	defer cleanup()
+	cfg := &config.Config{DataDir: dataDir, StateStore: config.StateStoreBoltDB}
+
+	// Migrate the state file into the database
+	saved, err := loadSavedAgentState(cfg)
+	require.NoError(t, err)
+	saved.close()
+	_, err = os.Stat(filepath.Join(dataDir, "ecs_agent_data.json"))
+	require.True(t, os.IsNotExist(err))
+
+	exitCode, _ := runStateCommandForTest(t, cfg, "--state-drop-task", savedTaskARN, "--state-downgrade", "28")
+	require.Equal(t, exitcodes.ExitSuccess, exitCode)
+	assert.Equal(t, 28, readSavedDataVersion(t, dataDir))
+	_, err = os.Stat(filepath.Join(dataDir, "ecs_agent_data.db"))
+	assert.True(t, os.IsNotExist(err), "state database should be moved out of the way")
+	_, err = os.Stat(filepath.Join(dataDir, "ecs_agent_data.db.downgraded"))
+	assert.NoError(t, err)
+}
@@ -53,6 +53,12 @@ const (
 	// defaultConfigFileName is the default (json-formatted) config file
 	defaultConfigFileName = "/etc/ecs_container_agent/config.json"
 
+	// StateStoreJSON is the state store backed by the ecs_agent_data.json file
+	StateStoreJSON = "json"
+
+	// StateStoreBoltDB is the state store backed by an embedded BoltDB database
+	StateStoreBoltDB = "boltdb"
+
 	// DefaultClusterName is the name of the default cluster.
 	DefaultClusterName = "default"
 
@@ -79,6 +85,14 @@ const (
 	// nonecs containers cleanup.
 	DefaultNumNonECSContainersToDeletePerCycle = 5
 
+	// DefaultTaskAdmissionDiskWaitTimeout specifies the default value for how long a task waits for free
+	// space or inodes to become available before it is stopped
+	DefaultTaskAdmissionDiskWaitTimeout = 5 * time.Minute
+
+	// DefaultImagePullMirrorTimeout specifies the default value for how long an image is pulled from
+	// a mirror before it's pulled from its registry instead
+	DefaultImagePullMirrorTimeout = 5 * time.Minute
+
 	// DefaultImageDeletionAge specifies the default value for minimum amount of elapsed time after an image
 	// has been pulled before it can be deleted.
 	DefaultImageDeletionAge = 1 * time.Hour
@@ -110,6 +124,14 @@ const (
 	// image cleanup.
 	minimumImageCleanupInterval = 10 * time.Minute
 
+	// minimumContainerDriftCheckInterval specifies the minimum time for agent to wait between two
+	// checks of the running containers for drift.
+	minimumContainerDriftCheckInterval = 10 * time.Second
+
+	// minimumSecretRotationInterval specifies the minimum time for agent to wait between two
+	// retrievals of the secrets vended as files.
+	minimumSecretRotationInterval = 1 * time.Minute
+
 	// minimumNumImagesToDeletePerCycle specifies the minimum number of images that to be deleted when
 	// performing image cleanup.
 	minimumNumImagesToDeletePerCycle = 1
@@ -143,6 +165,12 @@ const (
 	maximumCgroupCPUPeriod = 100 * time.Millisecond
 	minimumCgroupCPUPeriod = 8 * time.Millisecond
 
+	// MinimumBlockIOWeight and MaximumBlockIOWeight are the bounds of the
+	// block IO weight of the task cgroups
+	// Reference: https://www.kernel.org/doc/Documentation/cgroup-v1/blkio-controller.txt
+	MinimumBlockIOWeight = 10
+	MaximumBlockIOWeight = 1000
+
 	// DefaultContainerMetricsPublishInterval is the default interval that we publish
 	// metrics to the ECS telemetry backend (TACS)
 	DefaultContainerMetricsPublishInterval = 20 * time.Second
@@ -332,6 +360,54 @@ func (cfg *Config) validateAndOverrideBounds() error {
 		cfg.NumImagesToDeletePerCycle = DefaultNumImagesToDeletePerCycle
 	}
 
+	if cfg.TaskAdmissionDiskWaitTimeout < 0 {
+		seelog.Warnf("Invalid value for ECS_TASK_ADMISSION_DISK_WAIT_TIMEOUT, will be overridden with the default value: %s. Parsed value: %v.", DefaultTaskAdmissionDiskWaitTimeout.String(), cfg.TaskAdmissionDiskWaitTimeout)
+		cfg.TaskAdmissionDiskWaitTimeout = DefaultTaskAdmissionDiskWaitTimeout
+	}
+
+	if cfg.ImagePullMirrorTimeout <= 0 {
+		seelog.Warnf("Invalid value for ECS_IMAGE_PULL_MIRROR_TIMEOUT, will be overridden with the default value: %s. Parsed value: %v.", DefaultImagePullMirrorTimeout.String(), cfg.ImagePullMirrorTimeout)
+		cfg.ImagePullMirrorTimeout = DefaultImagePullMirrorTimeout
+	}
+
+	if cfg.ContainerDriftCheckInterval != 0 && cfg.ContainerDriftCheckInterval < minimumContainerDriftCheckInterval {
+		seelog.Warnf("Invalid value for ECS_CONTAINER_DRIFT_CHECK_INTERVAL, will be overridden with the minimum value: %s. Parsed value: %v.", minimumContainerDriftCheckInterval.String(), cfg.ContainerDriftCheckInterval)
+		cfg.ContainerDriftCheckInterval = minimumContainerDriftCheckInterval
+	}
+
+	if cfg.SecretRotationInterval != 0 && cfg.SecretRotationInterval < minimumSecretRotationInterval {
+		seelog.Warnf("Invalid value for ECS_SECRET_ROTATION_INTERVAL, will be overridden with the minimum value: %s. Parsed value: %v.", minimumSecretRotationInterval.String(), cfg.SecretRotationInterval)
+		cfg.SecretRotationInterval = minimumSecretRotationInterval
+	}
+
+	if cfg.TaskPidsLimit < 0 {
+		seelog.Warnf("Invalid value for ECS_TASK_PIDS_LIMIT, the number of processes of tasks will not be limited. Parsed value: %d.", cfg.TaskPidsLimit)
+		cfg.TaskPidsLimit = 0
+	}
+
+	if cfg.TaskBlockIOWeight != 0 && (cfg.TaskBlockIOWeight < MinimumBlockIOWeight || cfg.TaskBlockIOWeight > MaximumBlockIOWeight) {
+		seelog.Warnf("Invalid value for ECS_TASK_BLKIO_WEIGHT, the block IO weight of tasks will not be set. Parsed value: %d, minimum value: %d, maximum value: %d.", cfg.TaskBlockIOWeight, MinimumBlockIOWeight, MaximumBlockIOWeight)
+		cfg.TaskBlockIOWeight = 0
+	}
+
+	if cfg.ImageCleanupHighWatermark != 0 && (cfg.ImageCleanupHighWatermark > 100 ||
+		cfg.ImageCleanupLowWatermark == 0 || cfg.ImageCleanupLowWatermark >= cfg.ImageCleanupHighWatermark) {
+		seelog.Warnf("Invalid values for ECS_IMAGE_CLEANUP_HIGH_WATERMARK and ECS_IMAGE_CLEANUP_LOW_WATERMARK, disk pressure image cleanup will be disabled. Parsed values: %d, %d. The low watermark must be greater than 0 and lower than the high watermark, which must not exceed 100.", cfg.ImageCleanupHighWatermark, cfg.ImageCleanupLowWatermark)
+		cfg.ImageCleanupHighWatermark = 0
+		cfg.ImageCleanupLowWatermark = 0
+	}
+
+	if (cfg.ImagePolicyPublicKeysDir == "") != (cfg.ImagePolicySignaturesDir == "") {
+		seelog.Warnf("Invalid values for ECS_IMAGE_POLICY_PUBLIC_KEYS_DIR and ECS_IMAGE_POLICY_SIGNATURES_DIR, image signatures will not be verified. Parsed values: %q, %q. Both must be set to verify image signatures.", cfg.ImagePolicyPublicKeysDir, cfg.ImagePolicySignaturesDir)
+		cfg.ImagePolicyPublicKeysDir = ""
+		cfg.ImagePolicySignaturesDir = ""
+	}
+
+	if cfg.StateStore != StateStoreJSON && cfg.StateStore != StateStoreBoltDB {
+		seelog.Warnf("Invalid value for ECS_STATE_STORE, will be overridden with the default value: %s. Parsed value: %s, supported values: %s, %s.", StateStoreJSON, cfg.StateStore, StateStoreJSON, StateStoreBoltDB)
+		cfg.StateStore = StateStoreJSON
+	}
+
 	if cfg.TaskMetadataSteadyStateRate <= 0 || cfg.TaskMetadataBurstRate <= 0 {
 		seelog.Warnf("Invalid values for rate limits, will be overridden with default values: %d,%d.", DefaultTaskMetadataSteadyStateRate, DefaultTaskMetadataBurstRate)
 		cfg.TaskMetadataSteadyStateRate = DefaultTaskMetadataSteadyStateRate
@@ -493,6 +569,28 @@ func environmentConfig() (Config, error) {
 
 	additionalLocalRoutes, errs := parseAdditionalLocalRoutes(errs)
 
+	imagePullMirrors, errs := parseImagePullMirrors(errs)
+
+	taskBlockIODeviceLimits, errs := parseTaskBlockIODeviceLimits(errs)
+
+	eventSinks, errs := parseEventSinks(errs)
+
+	otlpTracesEndpoint, errs := parseOTLPTracesEndpoint(errs)
+
+	secretFileMode, errs := parseSecretFileMode(errs)
+
+	secretFileUID, secretFileGID, errs := parseSecretFileOwner(errs)
+
+	secretRotationSignal, errs := parseSecretRotationSignal(errs)
+
+	vaultAddress, errs := parseVaultAddress(errs)
+
+	vaultAppRoleRoleIDFile, vaultAppRoleSecretIDFile, errs := parseVaultAppRole(errs)
+
+	vaultAllowedPaths := parseVaultAllowedPaths()
+
+	environmentFileAllowedDirs, errs := parseEnvironmentFileAllowedDirs(errs)
+
 	var err error
 	if len(errs) > 0 {
 		err = apierrors.NewMultiError(errs...)
@@ -506,6 +604,7 @@ func environmentConfig() (Config, error) {
 		ReservedPortsUDP:                    parseReservedPorts("ECS_RESERVED_PORTS_UDP"),
 		DataDir:                             dataDir,
 		Checkpoint:                          parseCheckpoint(dataDir),
+		StateStore:                          os.Getenv("ECS_STATE_STORE"),
 		EngineAuthType:                      os.Getenv("ECS_ENGINE_AUTH_TYPE"),
 		EngineAuthData:                      NewSensitiveRawMessage([]byte(os.Getenv("ECS_ENGINE_AUTH_DATA"))),
 		UpdatesEnabled:                      utils.ParseBool(os.Getenv("ECS_UPDATES_ENABLED"), false),
@@ -521,6 +620,9 @@ func environmentConfig() (Config, error) {
 		TaskIAMRoleEnabled:                  utils.ParseBool(os.Getenv("ECS_ENABLE_TASK_IAM_ROLE"), false),
 		DeleteNonECSImagesEnabled:           utils.ParseBool(os.Getenv("ECS_ENABLE_UNTRACKED_IMAGE_CLEANUP"), false),
 		TaskCPUMemLimit:                     parseTaskCPUMemLimitEnabled(),
+		TaskPidsLimit:                       parseEnvVariableInt64("ECS_TASK_PIDS_LIMIT"),
+		TaskBlockIOWeight:                   parseEnvVariableUint16("ECS_TASK_BLKIO_WEIGHT"),
+		TaskBlockIODeviceLimits:             taskBlockIODeviceLimits,
 		DockerStopTimeout:                   parseDockerStopTimeout(),
 		ContainerStartTimeout:               parseContainerStartTimeout(),
 		ImagePullInactivityTimeout:          parseImagePullInactivityTimeout(),
@@ -533,8 +635,39 @@ func environmentConfig() (Config, error) {
 		ImageCleanupInterval:                parseEnvVariableDuration("ECS_IMAGE_CLEANUP_INTERVAL"),
 		NumImagesToDeletePerCycle:           parseNumImagesToDeletePerCycle(),
 		NumNonECSContainersToDeletePerCycle: parseNumNonECSContainersToDeletePerCycle(),
+		DockerDataRoot:                      os.Getenv("ECS_DOCKER_DATA_ROOT"),
+		TaskAdmissionMinFreeDiskMB:          parseEnvVariableUint64("ECS_TASK_ADMISSION_MIN_FREE_DISK_MB"),
+		TaskAdmissionMinFreeInodes:          parseEnvVariableUint64("ECS_TASK_ADMISSION_MIN_FREE_INODES"),
+		TaskAdmissionDiskWaitTimeout:        parseEnvVariableDuration("ECS_TASK_ADMISSION_DISK_WAIT_TIMEOUT"),
+		TaskAdmissionPolicyFile:             os.Getenv("ECS_TASK_ADMISSION_POLICY_FILE"),
+		ContainerDriftCheckInterval:         parseEnvVariableDuration("ECS_CONTAINER_DRIFT_CHECK_INTERVAL"),
+		ContainerDriftStopTask:              utils.ParseBool(os.Getenv("ECS_CONTAINER_DRIFT_STOP_TASK"), false),
+		EventSinks:                          eventSinks,
+		OTLPTracesEndpoint:                  otlpTracesEndpoint,
+		SecretFileMode:                      secretFileMode,
+		SecretFileUID:                       secretFileUID,
+		SecretFileGID:                       secretFileGID,
+		SecretRotationInterval:              parseEnvVariableDuration("ECS_SECRET_ROTATION_INTERVAL"),
+		SecretRotationSignal:                secretRotationSignal,
+		VaultAddress:                        vaultAddress,
+		VaultCACertFile:                     os.Getenv("ECS_VAULT_CACERT"),
+		VaultTokenFile:                      os.Getenv("ECS_VAULT_TOKEN_FILE"),
+		VaultAppRoleRoleIDFile:              vaultAppRoleRoleIDFile,
+		VaultAppRoleSecretIDFile:            vaultAppRoleSecretIDFile,
+		VaultAllowedPaths:                   vaultAllowedPaths,
+		EnvironmentFileAllowedDirs:          environmentFileAllowedDirs,
+		ImageCleanupHighWatermark:           parseEnvVariableUint16("ECS_IMAGE_CLEANUP_HIGH_WATERMARK"),
+		ImageCleanupLowWatermark:            parseEnvVariableUint16("ECS_IMAGE_CLEANUP_LOW_WATERMARK"),
 		ImagePullBehavior:                   parseImagePullBehavior(),
+		ImagePullMaxConcurrencyPerRegistry:  parseEnvVariableUint16("ECS_IMAGE_PULL_MAX_CONCURRENCY_PER_REGISTRY"),
+		ImagePullMirrors:                    imagePullMirrors,
+		ImagePullMirrorTimeout:              parseEnvVariableDuration("ECS_IMAGE_PULL_MIRROR_TIMEOUT"),
+		ImagePolicyRequireDigest:            utils.ParseBool(os.Getenv("ECS_IMAGE_POLICY_REQUIRE_DIGEST"), false),
+		ImagePolicyAllowedRepositories:      parseImagePolicyAllowedRepositories(),
+		ImagePolicyPublicKeysDir:            os.Getenv("ECS_IMAGE_POLICY_PUBLIC_KEYS_DIR"),
+		ImagePolicySignaturesDir:            os.Getenv("ECS_IMAGE_POLICY_SIGNATURES_DIR"),
 		ImageCleanupExclusionList:           parseImageCleanupExclusionList("ECS_EXCLUDE_UNTRACKED_IMAGE"),
+		PinnedImages:                        parsePinnedImages(),
 		InstanceAttributes:                  instanceAttributes,
 		CNIPluginsPath:                      os.Getenv("ECS_CNI_PLUGINS_PATH"),
 		AWSVPCBlockInstanceMetdata:          utils.ParseBool(os.Getenv("ECS_AWSVPC_BLOCK_IMDS"), false),
@@ -423,6 +423,323 @@ func TestValidForImagesCleanupExclusion(t *testing.T) {
 	assert.Equal(t, expectedImages, imagesNotDelete, "unexpected imageCleanupExclusionList")
 }
 
+func TestImagePullMaxConcurrencyPerRegistry(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_MAX_CONCURRENCY_PER_REGISTRY", "4")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(4), cfg.ImagePullMaxConcurrencyPerRegistry, "Wrong value for ImagePullMaxConcurrencyPerRegistry")
+}
+
+func TestImagePullMirrors(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_MIRRORS", `{"docker.io":{"Endpoint":"localhost:5000"},"123456789012.dkr.ecr.us-west-2.amazonaws.com":{"Endpoint":"10.0.0.10:5000/ecr","ForwardCredentials":true}}`)()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]ImagePullMirror{
+		"docker.io": {Endpoint: "localhost:5000"},
+		"123456789012.dkr.ecr.us-west-2.amazonaws.com": {Endpoint: "10.0.0.10:5000/ecr", ForwardCredentials: true},
+	}, cfg.ImagePullMirrors, "Wrong value for ImagePullMirrors")
+}
+
+func TestImagePullMirrorTimeout(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_MIRROR_TIMEOUT", "30s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.ImagePullMirrorTimeout, "Wrong value for ImagePullMirrorTimeout")
+}
+
+func TestInvalidImagePullMirrorTimeout(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_PULL_MIRROR_TIMEOUT", "-1s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultImagePullMirrorTimeout, cfg.ImagePullMirrorTimeout, "Wrong value for ImagePullMirrorTimeout")
+}
+
+func TestInvalidImagePullMirrors(t *testing.T) {
+	for _, mirrors := range []string{`["localhost:5000"]`, `{"docker.io":{"ForwardCredentials":true}}`} {
+		t.Run(mirrors, func(t *testing.T) {
+			defer setTestEnv("ECS_IMAGE_PULL_MIRRORS", mirrors)()
+			_, err := environmentConfig()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTaskResourceLimits(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_TASK_PIDS_LIMIT", "512")()
+	defer setTestEnv("ECS_TASK_BLKIO_WEIGHT", "200")()
+	defer setTestEnv("ECS_TASK_BLKIO_DEVICE_LIMITS", `[{"Device":"259:0","ReadBps":10485760,"WriteIOPS":100}]`)()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(512), cfg.TaskPidsLimit, "Wrong value for TaskPidsLimit")
+	assert.Equal(t, uint16(200), cfg.TaskBlockIOWeight, "Wrong value for TaskBlockIOWeight")
+	assert.Equal(t, []BlockIODeviceLimit{{Device: "259:0", ReadBps: 10485760, WriteIOPS: 100}},
+		cfg.TaskBlockIODeviceLimits, "Wrong value for TaskBlockIODeviceLimits")
+}
+
+func TestInvalidTaskResourceLimitsAreIgnored(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_TASK_PIDS_LIMIT", "-1")()
+	defer setTestEnv("ECS_TASK_BLKIO_WEIGHT", "5")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.TaskPidsLimit, "Wrong value for TaskPidsLimit")
+	assert.Zero(t, cfg.TaskBlockIOWeight, "Wrong value for TaskBlockIOWeight")
+}
+
+func TestInvalidTaskBlockIODeviceLimits(t *testing.T) {
+	for _, deviceLimits := range []string{`{"259:0":{}}`, `[{"Device":"/dev/nvme0n1"}]`, `[{"Device":"259:x"}]`} {
+		t.Run(deviceLimits, func(t *testing.T) {
+			defer setTestEnv("ECS_TASK_BLKIO_DEVICE_LIMITS", deviceLimits)()
+			_, err := environmentConfig()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEventSinks(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_EVENT_SINKS", `[{"Type":"webhook","URL":"http://127.0.0.1:8080/events"},{"Type":"file","Path":"/var/log/ecs/events.jsonl"}]`)()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, []EventSink{
+		{Type: EventSinkTypeWebhook, URL: "http://127.0.0.1:8080/events"},
+		{Type: EventSinkTypeFile, Path: "/var/log/ecs/events.jsonl"},
+	}, cfg.EventSinks, "Wrong value for EventSinks")
+}
+
+func TestInvalidEventSinks(t *testing.T) {
+	for _, eventSinks := range []string{
+		`{"Type":"file","Path":"/events"}`,
+		`[{"Type":"kafka"}]`,
+		`[{"Type":"webhook","URL":"127.0.0.1:8080"}]`,
+		`[{"Type":"unix","Path":"events.sock"}]`,
+	} {
+		t.Run(eventSinks, func(t *testing.T) {
+			defer setTestEnv("ECS_EVENT_SINKS", eventSinks)()
+			_, err := environmentConfig()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestOTLPTracesEndpoint(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_OTLP_TRACES_ENDPOINT", "http://127.0.0.1:4318/v1/traces")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:4318/v1/traces", cfg.OTLPTracesEndpoint, "Wrong value for OTLPTracesEndpoint")
+}
+
+func TestInvalidOTLPTracesEndpoint(t *testing.T) {
+	for _, endpoint := range []string{
+		"127.0.0.1:4318",
+		"grpc://127.0.0.1:4317",
+		"http://",
+	} {
+		t.Run(endpoint, func(t *testing.T) {
+			defer setTestEnv("ECS_OTLP_TRACES_ENDPOINT", endpoint)()
+			_, err := environmentConfig()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSecretFileModeAndOwner(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_SECRET_FILE_MODE", "0440")()
+	defer setTestEnv("ECS_SECRET_FILE_OWNER", "1000:2000")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0440), cfg.SecretFileMode, "Wrong value for SecretFileMode")
+	assert.Equal(t, 1000, cfg.SecretFileUID, "Wrong value for SecretFileUID")
+	assert.Equal(t, 2000, cfg.SecretFileGID, "Wrong value for SecretFileGID")
+}
+
+func TestSecretFileOwnerWithoutGroup(t *testing.T) {
+	defer setTestEnv("ECS_SECRET_FILE_OWNER", "1000")()
+	cfg, err := environmentConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, cfg.SecretFileUID, "Wrong value for SecretFileUID")
+	assert.Equal(t, 1000, cfg.SecretFileGID, "Wrong value for SecretFileGID")
+}
+
+func TestInvalidSecretFileModeAndOwner(t *testing.T) {
+	for _, env := range []struct {
+		name  string
+		value string
+	}{
+		{"ECS_SECRET_FILE_MODE", "0"},
+		{"ECS_SECRET_FILE_MODE", "0999"},
+		{"ECS_SECRET_FILE_MODE", "01777"},
+		{"ECS_SECRET_FILE_OWNER", "root"},
+		{"ECS_SECRET_FILE_OWNER", "1000:"},
+		{"ECS_SECRET_FILE_OWNER", "-1:1000"},
+	} {
+		t.Run(env.name+"="+env.value, func(t *testing.T) {
+			defer setTestEnv(env.name, env.value)()
+			_, err := environmentConfig()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSecretRotation(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_SECRET_ROTATION_INTERVAL", "15m")()
+	defer setTestEnv("ECS_SECRET_ROTATION_SIGNAL", "SIGHUP")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, cfg.SecretRotationInterval, "Wrong value for SecretRotationInterval")
+	assert.Equal(t, "SIGHUP", cfg.SecretRotationSignal, "Wrong value for SecretRotationSignal")
+}
+
+func TestSecretRotationIntervalBelowMinimum(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_SECRET_ROTATION_INTERVAL", "10s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, minimumSecretRotationInterval, cfg.SecretRotationInterval, "Wrong value for SecretRotationInterval")
+	assert.Empty(t, cfg.SecretRotationSignal, "Wrong value for SecretRotationSignal")
+}
+
+func TestInvalidSecretRotationSignal(t *testing.T) {
+	for _, signal := range []string{"sighup", "SIG HUP", "-1"} {
+		t.Run(signal, func(t *testing.T) {
+			defer setTestEnv("ECS_SECRET_ROTATION_SIGNAL", signal)()
+			_, err := environmentConfig()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestVault(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_VAULT_ADDR", "https://vault.example.com:8200")()
+	defer setTestEnv("ECS_VAULT_CACERT", "/etc/ecs/vault-ca.pem")()
+	defer setTestEnv("ECS_VAULT_APPROLE_ROLE_ID_FILE", "/etc/ecs/vault-role-id")()
+	defer setTestEnv("ECS_VAULT_APPROLE_SECRET_ID_FILE", "/etc/ecs/vault-secret-id")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "https://vault.example.com:8200", cfg.VaultAddress, "Wrong value for VaultAddress")
+	assert.Equal(t, "/etc/ecs/vault-ca.pem", cfg.VaultCACertFile, "Wrong value for VaultCACertFile")
+	assert.Equal(t, "/etc/ecs/vault-role-id", cfg.VaultAppRoleRoleIDFile, "Wrong value for VaultAppRoleRoleIDFile")
+	assert.Equal(t, "/etc/ecs/vault-secret-id", cfg.VaultAppRoleSecretIDFile, "Wrong value for VaultAppRoleSecretIDFile")
+	assert.Empty(t, cfg.VaultTokenFile, "Wrong value for VaultTokenFile")
+}
+
+func TestInvalidVaultAddress(t *testing.T) {
+	for _, address := range []string{"vault.example.com:8200", "ftp://vault.example.com", "http://"} {
+		t.Run(address, func(t *testing.T) {
+			defer setTestEnv("ECS_VAULT_ADDR", address)()
+			_, err := environmentConfig()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestInvalidVaultAuth(t *testing.T) {
+	t.Run("partial AppRole", func(t *testing.T) {
+		defer setTestEnv("ECS_VAULT_APPROLE_ROLE_ID_FILE", "/etc/ecs/vault-role-id")()
+		_, err := environmentConfig()
+		assert.Error(t, err)
+	})
+	t.Run("token and AppRole", func(t *testing.T) {
+		defer setTestEnv("ECS_VAULT_TOKEN_FILE", "/etc/ecs/vault-token")()
+		defer setTestEnv("ECS_VAULT_APPROLE_ROLE_ID_FILE", "/etc/ecs/vault-role-id")()
+		defer setTestEnv("ECS_VAULT_APPROLE_SECRET_ID_FILE", "/etc/ecs/vault-secret-id")()
+		_, err := environmentConfig()
+		assert.Error(t, err)
+	})
+}
+
+func TestVaultAllowedPaths(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_VAULT_ALLOWED_PATHS", "secret/data/app/, /kv/app,,secret/data/app")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"secret/data/app", "kv/app"}, cfg.VaultAllowedPaths, "Wrong value for VaultAllowedPaths")
+}
+
+func TestEnvironmentFileAllowedDirs(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_ENVIRONMENT_FILE_ALLOWED_DIRS", "/etc/ecs/envfiles, /opt/app/,,/etc/ecs/envfiles")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/etc/ecs/envfiles", "/opt/app"}, cfg.EnvironmentFileAllowedDirs,
+		"Wrong value for EnvironmentFileAllowedDirs")
+}
+
+func TestInvalidEnvironmentFileAllowedDirs(t *testing.T) {
+	defer setTestEnv("ECS_ENVIRONMENT_FILE_ALLOWED_DIRS", "/etc/ecs/envfiles,envfiles")()
+	_, err := environmentConfig()
+	assert.Error(t, err)
+}
+
+func TestPinnedImages(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_PINNED_IMAGES", "busybox:1.31, amazonlinux@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef,,busybox:1.31")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	expectedImages := []string{"busybox:1.31", "amazonlinux@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}
+	assert.Equal(t, expectedImages, cfg.PinnedImages, "Wrong value for PinnedImages")
+}
+
+func TestTaskAdmissionPolicyFile(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_TASK_ADMISSION_POLICY_FILE", "/etc/ecs/admission-policy.json")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/ecs/admission-policy.json", cfg.TaskAdmissionPolicyFile, "Wrong value for TaskAdmissionPolicyFile")
+}
+
+func TestContainerDrift(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_CONTAINER_DRIFT_CHECK_INTERVAL", "5m")()
+	defer setTestEnv("ECS_CONTAINER_DRIFT_STOP_TASK", "true")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.ContainerDriftCheckInterval, "Wrong value for ContainerDriftCheckInterval")
+	assert.True(t, cfg.ContainerDriftStopTask, "Wrong value for ContainerDriftStopTask")
+}
+
+func TestContainerDriftCheckIntervalBelowMinimum(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_CONTAINER_DRIFT_CHECK_INTERVAL", "1s")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, minimumContainerDriftCheckInterval, cfg.ContainerDriftCheckInterval, "Wrong value for ContainerDriftCheckInterval")
+	assert.False(t, cfg.ContainerDriftStopTask, "Wrong value for ContainerDriftStopTask")
+}
+
+func TestImagePolicy(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_POLICY_REQUIRE_DIGEST", "true")()
+	defer setTestEnv("ECS_IMAGE_POLICY_ALLOWED_REPOSITORIES", "123456789012.dkr.ecr.us-west-2.amazonaws.com, docker.io/library/*,,docker.io/library/*")()
+	defer setTestEnv("ECS_IMAGE_POLICY_PUBLIC_KEYS_DIR", "/etc/ecs/keys")()
+	defer setTestEnv("ECS_IMAGE_POLICY_SIGNATURES_DIR", "/etc/ecs/signatures")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.True(t, cfg.ImagePolicyRequireDigest, "Wrong value for ImagePolicyRequireDigest")
+	assert.Equal(t, []string{"123456789012.dkr.ecr.us-west-2.amazonaws.com", "docker.io/library/*"},
+		cfg.ImagePolicyAllowedRepositories, "Wrong value for ImagePolicyAllowedRepositories")
+	assert.Equal(t, "/etc/ecs/keys", cfg.ImagePolicyPublicKeysDir, "Wrong value for ImagePolicyPublicKeysDir")
+	assert.Equal(t, "/etc/ecs/signatures", cfg.ImagePolicySignaturesDir, "Wrong value for ImagePolicySignaturesDir")
+}
+
+func TestImagePolicyPublicKeysWithoutSignatures(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_POLICY_PUBLIC_KEYS_DIR", "/etc/ecs/keys")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.ImagePolicyPublicKeysDir, "Wrong value for ImagePolicyPublicKeysDir")
+	assert.Empty(t, cfg.ImagePolicySignaturesDir, "Wrong value for ImagePolicySignaturesDir")
+}
+
 func TestValidFormatParseEnvVariableDuration(t *testing.T) {
 	defer setTestRegion()()
 	setTestEnv("FOO", "1s")
@@ -532,6 +849,70 @@ func TestInvalidImagePullBehavior(t *testing.T) {
 	assert.Equal(t, ImagePullDefaultBehavior, cfg.ImagePullBehavior, "Wrong value for ImagePullBehavior")
 }
 
+func TestStateStore(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_STATE_STORE", "boltdb")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, StateStoreBoltDB, cfg.StateStore, "Wrong value for StateStore")
+}
+
+func TestInvalidStateStore(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_STATE_STORE", "invalid")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, StateStoreJSON, cfg.StateStore, "Wrong value for StateStore")
+}
+
+func TestTaskAdmissionDiskThresholds(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_DOCKER_DATA_ROOT", "/docker")()
+	defer setTestEnv("ECS_TASK_ADMISSION_MIN_FREE_DISK_MB", "2048")()
+	defer setTestEnv("ECS_TASK_ADMISSION_MIN_FREE_INODES", "10000")()
+	defer setTestEnv("ECS_TASK_ADMISSION_DISK_WAIT_TIMEOUT", "1m")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, "/docker", cfg.DockerDataRoot, "Wrong value for DockerDataRoot")
+	assert.Equal(t, uint64(2048), cfg.TaskAdmissionMinFreeDiskMB, "Wrong value for TaskAdmissionMinFreeDiskMB")
+	assert.Equal(t, uint64(10000), cfg.TaskAdmissionMinFreeInodes, "Wrong value for TaskAdmissionMinFreeInodes")
+	assert.Equal(t, time.Minute, cfg.TaskAdmissionDiskWaitTimeout, "Wrong value for TaskAdmissionDiskWaitTimeout")
+}
+
+func TestInvalidTaskAdmissionDiskThresholds(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_TASK_ADMISSION_MIN_FREE_DISK_MB", "-1")()
+	defer setTestEnv("ECS_TASK_ADMISSION_DISK_WAIT_TIMEOUT", "-1m")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.TaskAdmissionMinFreeDiskMB, "Wrong value for TaskAdmissionMinFreeDiskMB")
+	assert.Equal(t, DefaultTaskAdmissionDiskWaitTimeout, cfg.TaskAdmissionDiskWaitTimeout, "Wrong value for TaskAdmissionDiskWaitTimeout")
+}
+
+func TestImageCleanupWatermarks(t *testing.T) {
+	defer setTestRegion()()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_HIGH_WATERMARK", "85")()
+	defer setTestEnv("ECS_IMAGE_CLEANUP_LOW_WATERMARK", "70")()
+	cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(85), cfg.ImageCleanupHighWatermark, "Wrong value for ImageCleanupHighWatermark")
+	assert.Equal(t, uint16(70), cfg.ImageCleanupLowWatermark, "Wrong value for ImageCleanupLowWatermark")
+}
+
+func TestInvalidImageCleanupWatermarks(t *testing.T) {
+	for _, watermarks := range [][2]string{{"85", ""}, {"70", "85"}, {"85", "85"}, {"101", "70"}} {
+		t.Run(watermarks[0]+"-"+watermarks[1], func(t *testing.T) {
+			defer setTestRegion()()
+			defer setTestEnv("ECS_IMAGE_CLEANUP_HIGH_WATERMARK", watermarks[0])()
+			defer setTestEnv("ECS_IMAGE_CLEANUP_LOW_WATERMARK", watermarks[1])()
+			cfg, err := NewConfig(ec2.NewBlackholeEC2MetadataClient())
+			assert.NoError(t, err)
+			assert.Zero(t, cfg.ImageCleanupHighWatermark, "Wrong value for ImageCleanupHighWatermark")
+			assert.Zero(t, cfg.ImageCleanupLowWatermark, "Wrong value for ImageCleanupLowWatermark")
+		})
+	}
+}
+
 func TestSharedVolumeMatchFullConfigEnabled(t *testing.T) {
 	defer setTestRegion()()
 	defer setTestEnv("ECS_SHARED_VOLUME_MATCH_FULL_CONFIG", "true")()
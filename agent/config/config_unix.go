@@ -34,6 +34,11 @@ const (
 	// Default cgroup memory system root path, this is the default used if the
 	// path has not been configured through ECS_CGROUP_PATH
 	defaultCgroupPath = "/sys/fs/cgroup"
+	// defaultDockerDataRoot is the default docker data root directory
+	defaultDockerDataRoot = "/var/lib/docker"
+	// defaultSecretFileMode is the default mode of the files the values of the secrets vended
+	// as files are written to, which can only be read by their owner
+	defaultSecretFileMode = 0400
 	// defaultContainerStartTimeout specifies the value for container start timeout duration
 	defaultContainerStartTimeout = 3 * time.Minute
 	// minimumContainerStartTimeout specifies the minimum value for starting a container
@@ -50,6 +55,7 @@ func DefaultConfig() Config {
 		ReservedPortsUDP:                    []uint16{},
 		DataDir:                             "/data/",
 		DataDirOnHost:                       "/var/lib/ecs",
+		StateStore:                          StateStoreJSON,
 		DisableMetrics:                      false,
 		ReservedMemory:                      0,
 		AvailableLoggingDrivers:             []dockerclient.LoggingDriver{dockerclient.JSONFileDriver, dockerclient.NoneDriver},
@@ -65,6 +71,9 @@ func DefaultConfig() Config {
 		ImagePullInactivityTimeout:          defaultImagePullInactivityTimeout,
 		NumImagesToDeletePerCycle:           DefaultNumImagesToDeletePerCycle,
 		NumNonECSContainersToDeletePerCycle: DefaultNumNonECSContainersToDeletePerCycle,
+		DockerDataRoot:                      defaultDockerDataRoot,
+		TaskAdmissionDiskWaitTimeout:        DefaultTaskAdmissionDiskWaitTimeout,
+		ImagePullMirrorTimeout:              DefaultImagePullMirrorTimeout,
 		CNIPluginsPath:                      defaultCNIPluginsPath,
 		PauseContainerTarballPath:           pauseContainerTarballPath,
 		PauseContainerImageName:             DefaultPauseContainerImageName,
@@ -83,6 +92,7 @@ func DefaultConfig() Config {
 		NvidiaRuntime:                       DefaultNvidiaRuntime,
 		CgroupCPUPeriod:                     defaultCgroupCPUPeriod,
 		GMSACapable:                         false,
+		SecretFileMode:                      defaultSecretFileMode,
 	}
 }
 
@@ -58,6 +58,10 @@ func TestConfigDefault(t *testing.T) {
 	assert.Equal(t, DefaultNonECSImageDeletionAge, cfg.NonECSMinimumImageDeletionAge, "NonECSMinimumImageDeletionAge default is set incorrectly")
 	assert.Equal(t, DefaultImageCleanupTimeInterval, cfg.ImageCleanupInterval, "ImageCleanupInterval default is set incorrectly")
 	assert.Equal(t, DefaultNumImagesToDeletePerCycle, cfg.NumImagesToDeletePerCycle, "NumImagesToDeletePerCycle default is set incorrectly")
+	assert.Equal(t, "/var/lib/docker", cfg.DockerDataRoot, "DockerDataRoot default is set incorrectly")
+	assert.Zero(t, cfg.TaskAdmissionMinFreeDiskMB, "TaskAdmissionMinFreeDiskMB default is set incorrectly")
+	assert.Equal(t, DefaultTaskAdmissionDiskWaitTimeout, cfg.TaskAdmissionDiskWaitTimeout, "TaskAdmissionDiskWaitTimeout default is set incorrectly")
+	assert.Equal(t, DefaultImagePullMirrorTimeout, cfg.ImagePullMirrorTimeout, "ImagePullMirrorTimeout default is set incorrectly")
 	assert.Equal(t, defaultCNIPluginsPath, cfg.CNIPluginsPath, "CNIPluginsPath default is set incorrectly")
 	assert.False(t, cfg.AWSVPCBlockInstanceMetdata, "AWSVPCBlockInstanceMetdata default is incorrectly set")
 	assert.Equal(t, "/var/lib/ecs", cfg.DataDirOnHost, "Default DataDirOnHost set incorrectly")
@@ -67,6 +71,7 @@ func TestConfigDefault(t *testing.T) {
 		"Default TaskMetadataBurstRate is set incorrectly")
 	assert.False(t, cfg.SharedVolumeMatchFullConfig, "Default SharedVolumeMatchFullConfig set incorrectly")
 	assert.Equal(t, defaultCgroupCPUPeriod, cfg.CgroupCPUPeriod, "CFS cpu period set incorrectly")
+	assert.Equal(t, os.FileMode(defaultSecretFileMode), cfg.SecretFileMode, "Default SecretFileMode set incorrectly")
 }
 
 // TestConfigFromFile tests the configuration can be read from file
@@ -81,6 +81,7 @@ func DefaultConfig() Config {
 		// DataDirOnHost is identical to DataDir for Windows because we do not
 		// run as a container
 		DataDirOnHost:                       dataDir,
+		StateStore:                          StateStoreJSON,
 		ReservedMemory:                      0,
 		AvailableLoggingDrivers:             []dockerclient.LoggingDriver{dockerclient.JSONFileDriver, dockerclient.NoneDriver, dockerclient.AWSLogsDriver},
 		TaskCleanupWaitDuration:             DefaultTaskCleanupWaitDuration,
@@ -95,6 +96,9 @@ func DefaultConfig() Config {
 		ImageCleanupInterval:                DefaultImageCleanupTimeInterval,
 		NumImagesToDeletePerCycle:           DefaultNumImagesToDeletePerCycle,
 		NumNonECSContainersToDeletePerCycle: DefaultNumNonECSContainersToDeletePerCycle,
+		DockerDataRoot:                      filepath.Join(programData, "docker"),
+		TaskAdmissionDiskWaitTimeout:        DefaultTaskAdmissionDiskWaitTimeout,
+		ImagePullMirrorTimeout:              DefaultImagePullMirrorTimeout,
 		ContainerMetadataEnabled:            false,
 		TaskCPUMemLimit:                     ExplicitlyDisabled,
 		PlatformVariables:                   platformVariables,
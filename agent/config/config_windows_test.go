@@ -53,6 +53,10 @@ func TestConfigDefault(t *testing.T) {
 	assert.Equal(t, DefaultNonECSImageDeletionAge, cfg.NonECSMinimumImageDeletionAge, "NonECSMinimumImageDeletionAge default is set incorrectly")
 	assert.Equal(t, DefaultImageCleanupTimeInterval, cfg.ImageCleanupInterval, "ImageCleanupInterval default is set incorrectly")
 	assert.Equal(t, DefaultNumImagesToDeletePerCycle, cfg.NumImagesToDeletePerCycle, "NumImagesToDeletePerCycle default is set incorrectly")
+	assert.Equal(t, `C:\ProgramData\docker`, cfg.DockerDataRoot, "DockerDataRoot default is set incorrectly")
+	assert.Zero(t, cfg.TaskAdmissionMinFreeDiskMB, "TaskAdmissionMinFreeDiskMB default is set incorrectly")
+	assert.Equal(t, DefaultTaskAdmissionDiskWaitTimeout, cfg.TaskAdmissionDiskWaitTimeout, "TaskAdmissionDiskWaitTimeout default is set incorrectly")
+	assert.Equal(t, DefaultImagePullMirrorTimeout, cfg.ImagePullMirrorTimeout, "ImagePullMirrorTimeout default is set incorrectly")
 	assert.Equal(t, `C:\ProgramData\Amazon\ECS\data`, cfg.DataDirOnHost, "Default DataDirOnHost set incorrectly")
 	assert.False(t, cfg.PlatformVariables.CPUUnbounded, "CPUUnbounded should be false by default")
 	assert.Equal(t, DefaultTaskMetadataSteadyStateRate, cfg.TaskMetadataSteadyStateRate,
@@ -17,7 +17,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -201,6 +204,201 @@ func parseAdditionalLocalRoutes(errs []error) ([]cnitypes.IPNet, []error) {
 	return additionalLocalRoutes, errs
 }
 
+func parseImagePullMirrors(errs []error) (map[string]ImagePullMirror, []error) {
+	var imagePullMirrors map[string]ImagePullMirror
+	imagePullMirrorsEnv := os.Getenv("ECS_IMAGE_PULL_MIRRORS")
+	if imagePullMirrorsEnv == "" {
+		return nil, errs
+	}
+	err := json.Unmarshal([]byte(imagePullMirrorsEnv), &imagePullMirrors)
+	if err != nil {
+		wrappedErr := fmt.Errorf("Invalid format for ECS_IMAGE_PULL_MIRRORS. Expected a json hash of registries to mirrors: %v", err)
+		seelog.Error(wrappedErr)
+		return nil, append(errs, wrappedErr)
+	}
+	for registry, mirror := range imagePullMirrors {
+		if mirror.Endpoint == "" {
+			wrappedErr := fmt.Errorf("Invalid format for ECS_IMAGE_PULL_MIRRORS. Missing endpoint of the mirror of %s", registry)
+			seelog.Error(wrappedErr)
+			return nil, append(errs, wrappedErr)
+		}
+		seelog.Infof("Pulling images of registry %s from mirror %s", registry, mirror.Endpoint)
+	}
+	return imagePullMirrors, errs
+}
+
+func parseTaskBlockIODeviceLimits(errs []error) ([]BlockIODeviceLimit, []error) {
+	var deviceLimits []BlockIODeviceLimit
+	deviceLimitsEnv := os.Getenv("ECS_TASK_BLKIO_DEVICE_LIMITS")
+	if deviceLimitsEnv == "" {
+		return nil, errs
+	}
+	err := json.Unmarshal([]byte(deviceLimitsEnv), &deviceLimits)
+	if err != nil {
+		wrappedErr := fmt.Errorf("Invalid format for ECS_TASK_BLKIO_DEVICE_LIMITS. Expected a json array of device limits: %v", err)
+		seelog.Error(wrappedErr)
+		return nil, append(errs, wrappedErr)
+	}
+	for _, deviceLimit := range deviceLimits {
+		if _, _, err := deviceLimit.MajorMinor(); err != nil {
+			wrappedErr := fmt.Errorf("Invalid format for ECS_TASK_BLKIO_DEVICE_LIMITS: %v", err)
+			seelog.Error(wrappedErr)
+			return nil, append(errs, wrappedErr)
+		}
+	}
+	return deviceLimits, errs
+}
+
+func parseEventSinks(errs []error) ([]EventSink, []error) {
+	var eventSinks []EventSink
+	eventSinksEnv := os.Getenv("ECS_EVENT_SINKS")
+	if eventSinksEnv == "" {
+		return nil, errs
+	}
+	err := json.Unmarshal([]byte(eventSinksEnv), &eventSinks)
+	if err != nil {
+		wrappedErr := fmt.Errorf("Invalid format for ECS_EVENT_SINKS. Expected a json array of event sinks: %v", err)
+		seelog.Error(wrappedErr)
+		return nil, append(errs, wrappedErr)
+	}
+	for _, eventSink := range eventSinks {
+		if err := eventSink.Validate(); err != nil {
+			wrappedErr := fmt.Errorf("Invalid format for ECS_EVENT_SINKS: %v", err)
+			seelog.Error(wrappedErr)
+			return nil, append(errs, wrappedErr)
+		}
+	}
+	return eventSinks, errs
+}
+
+func parseOTLPTracesEndpoint(errs []error) (string, []error) {
+	endpoint := os.Getenv("ECS_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		return "", errs
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") || endpointURL.Host == "" {
+		wrappedErr := fmt.Errorf("Invalid format for ECS_OTLP_TRACES_ENDPOINT. Expected an http or https URL: %s", endpoint)
+		seelog.Error(wrappedErr)
+		return "", append(errs, wrappedErr)
+	}
+	return endpoint, errs
+}
+
+func parseSecretFileMode(errs []error) (os.FileMode, []error) {
+	modeString := os.Getenv("ECS_SECRET_FILE_MODE")
+	if modeString == "" {
+		return 0, errs
+	}
+	mode, err := strconv.ParseUint(modeString, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		wrappedErr := fmt.Errorf("Invalid format for ECS_SECRET_FILE_MODE. Expected an octal file mode, such as 0440: %s", modeString)
+		seelog.Error(wrappedErr)
+		return 0, append(errs, wrappedErr)
+	}
+	return os.FileMode(mode), errs
+}
+
+func parseSecretFileOwner(errs []error) (int, int, []error) {
+	owner := os.Getenv("ECS_SECRET_FILE_OWNER")
+	if owner == "" {
+		return 0, 0, errs
+	}
+	ids := strings.SplitN(owner, ":", 2)
+	if len(ids) == 1 {
+		ids = append(ids, ids[0])
+	}
+	uid, uidErr := strconv.ParseUint(ids[0], 10, 31)
+	gid, gidErr := strconv.ParseUint(ids[1], 10, 31)
+	if uidErr != nil || gidErr != nil {
+		wrappedErr := fmt.Errorf("Invalid format for ECS_SECRET_FILE_OWNER. Expected a numeric uid:gid, such as 1000:1000: %s", owner)
+		seelog.Error(wrappedErr)
+		return 0, 0, append(errs, wrappedErr)
+	}
+	return int(uid), int(gid), errs
+}
+
+// secretRotationSignalRegex matches the signals accepted by Docker, either by
+// name, with or without the "SIG" prefix, or by number
+var secretRotationSignalRegex = regexp.MustCompile(`^((SIG)?[A-Z][A-Z0-9+-]*|[0-9]+)$`)
+
+func parseSecretRotationSignal(errs []error) (string, []error) {
+	signal := os.Getenv("ECS_SECRET_ROTATION_SIGNAL")
+	if signal == "" {
+		return "", errs
+	}
+	if !secretRotationSignalRegex.MatchString(signal) {
+		wrappedErr := fmt.Errorf("Invalid format for ECS_SECRET_ROTATION_SIGNAL. Expected a signal name or number, such as SIGHUP: %s", signal)
+		seelog.Error(wrappedErr)
+		return "", append(errs, wrappedErr)
+	}
+	return signal, errs
+}
+
+func parseVaultAddress(errs []error) (string, []error) {
+	address := os.Getenv("ECS_VAULT_ADDR")
+	if address == "" {
+		return "", errs
+	}
+	addressURL, err := url.Parse(address)
+	if err != nil || (addressURL.Scheme != "http" && addressURL.Scheme != "https") || addressURL.Host == "" {
+		wrappedErr := fmt.Errorf("Invalid format for ECS_VAULT_ADDR. Expected an http or https URL: %s", address)
+		seelog.Error(wrappedErr)
+		return "", append(errs, wrappedErr)
+	}
+	return address, errs
+}
+
+func parseVaultAppRole(errs []error) (string, string, []error) {
+	roleIDFile := os.Getenv("ECS_VAULT_APPROLE_ROLE_ID_FILE")
+	secretIDFile := os.Getenv("ECS_VAULT_APPROLE_SECRET_ID_FILE")
+	if roleIDFile == "" && secretIDFile == "" {
+		return "", "", errs
+	}
+	if roleIDFile == "" || secretIDFile == "" {
+		wrappedErr := fmt.Errorf("Invalid value for ECS_VAULT_APPROLE_ROLE_ID_FILE and ECS_VAULT_APPROLE_SECRET_ID_FILE. Expected both to be set")
+		seelog.Error(wrappedErr)
+		return "", "", append(errs, wrappedErr)
+	}
+	if os.Getenv("ECS_VAULT_TOKEN_FILE") != "" {
+		wrappedErr := fmt.Errorf("Invalid value for ECS_VAULT_TOKEN_FILE. Expected either a token file or AppRole files, not both")
+		seelog.Error(wrappedErr)
+		return "", "", append(errs, wrappedErr)
+	}
+	return roleIDFile, secretIDFile, errs
+}
+
+func parseVaultAllowedPaths() []string {
+	var allowedPaths []string
+	for _, path := range strings.Split(os.Getenv("ECS_VAULT_ALLOWED_PATHS"), ",") {
+		path = strings.Trim(strings.TrimSpace(path), "/")
+		if path != "" && !utils.StrSliceContains(allowedPaths, path) {
+			allowedPaths = append(allowedPaths, path)
+		}
+	}
+	return allowedPaths
+}
+
+func parseEnvironmentFileAllowedDirs(errs []error) ([]string, []error) {
+	var allowedDirs []string
+	for _, dir := range strings.Split(os.Getenv("ECS_ENVIRONMENT_FILE_ALLOWED_DIRS"), ",") {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			wrappedErr := fmt.Errorf("Invalid format for ECS_ENVIRONMENT_FILE_ALLOWED_DIRS. Expected a comma separated list of absolute paths: %s", dir)
+			seelog.Error(wrappedErr)
+			return nil, append(errs, wrappedErr)
+		}
+		dir = filepath.Clean(dir)
+		if !utils.StrSliceContains(allowedDirs, dir) {
+			allowedDirs = append(allowedDirs, dir)
+		}
+	}
+	return allowedDirs, errs
+}
+
 func parseTaskCPUMemLimitEnabled() Conditional {
 	var taskCPUMemLimitEnabled Conditional
 	taskCPUMemLimitConfigString := os.Getenv("ECS_ENABLE_TASK_CPU_MEM_LIMIT")
@@ -289,6 +487,32 @@ func parseEnvVariableUint16(envVar string) uint16 {
 	return var16
 }
 
+func parseEnvVariableUint64(envVar string) uint64 {
+	envVal := os.Getenv(envVar)
+	var var64 uint64
+	if envVal != "" {
+		var err error
+		var64, err = strconv.ParseUint(envVal, 10, 64)
+		if err != nil {
+			seelog.Warnf("Invalid format for \""+envVar+"\" environment variable; expected unsigned integer. err %v", err)
+		}
+	}
+	return var64
+}
+
+func parseEnvVariableInt64(envVar string) int64 {
+	envVal := os.Getenv(envVar)
+	var var64 int64
+	if envVal != "" {
+		var err error
+		var64, err = strconv.ParseInt(envVal, 10, 64)
+		if err != nil {
+			seelog.Warnf("Invalid format for \""+envVar+"\" environment variable; expected integer. err %v", err)
+		}
+	}
+	return var64
+}
+
 func parseEnvVariableDuration(envVar string) time.Duration {
 	var duration time.Duration
 	envVal := os.Getenv(envVar)
@@ -322,6 +546,34 @@ func parseImageCleanupExclusionList(envVar string) []string {
 	return imageCleanupExclusionList
 }
 
+func parsePinnedImages() []string {
+	var pinnedImages []string
+	for _, image := range strings.Split(os.Getenv("ECS_PINNED_IMAGES"), ",") {
+		image = strings.TrimSpace(image)
+		if image == "" || utils.StrSliceContains(pinnedImages, image) {
+			continue
+		}
+		seelog.Infof("Image pinned: %s", image)
+		pinnedImages = append(pinnedImages, image)
+	}
+	return pinnedImages
+}
+
+func parseImagePolicyAllowedRepositories() []string {
+	var allowedRepositories []string
+	for _, repository := range strings.Split(os.Getenv("ECS_IMAGE_POLICY_ALLOWED_REPOSITORIES"), ",") {
+		repository = strings.TrimSpace(repository)
+		if repository == "" || utils.StrSliceContains(allowedRepositories, repository) {
+			continue
+		}
+		allowedRepositories = append(allowedRepositories, repository)
+	}
+	if len(allowedRepositories) > 0 {
+		seelog.Infof("Images allowed from repositories: %s", strings.Join(allowedRepositories, ", "))
+	}
+	return allowedRepositories
+}
+
 func parseCgroupCPUPeriod() time.Duration {
 	duration := parseEnvVariableDuration("ECS_CGROUP_CPU_PERIOD")
 
@@ -14,6 +14,11 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
@@ -28,6 +33,90 @@ type ImagePullBehaviorType int8
 // ways to propagate tags, it includes none (default) and ec2_instance.
 type ContainerInstancePropagateTagsFromType int8
 
+// ImagePullMirror is a registry mirroring the images of another registry
+type ImagePullMirror struct {
+	// Endpoint is the host of the mirror, optionally with a port and a path
+	// prefix, e.g. "localhost:5000" or "mirror.example.com/ecr"
+	Endpoint string
+	// ForwardCredentials specifies whether the credentials for the source
+	// registry are sent to the mirror. If not, the mirror is accessed with
+	// the engine auth data for it, if any.
+	ForwardCredentials bool
+}
+
+// BlockIODeviceLimit throttles the block IO of the tasks on a device. Limits
+// that are 0 aren't set.
+type BlockIODeviceLimit struct {
+	// Device is the major and minor numbers of the device, e.g. "259:0"
+	Device string
+	// ReadBps is the maximum number of bytes read from the device per second
+	ReadBps uint64
+	// WriteBps is the maximum number of bytes written to the device per second
+	WriteBps uint64
+	// ReadIOPS is the maximum number of read operations on the device per second
+	ReadIOPS uint64
+	// WriteIOPS is the maximum number of write operations on the device per second
+	WriteIOPS uint64
+}
+
+// MajorMinor returns the major and minor numbers of the device
+func (limit BlockIODeviceLimit) MajorMinor() (int64, int64, error) {
+	numbers := strings.Split(limit.Device, ":")
+	if len(numbers) != 2 {
+		return 0, 0, fmt.Errorf("invalid device %q, expected <major>:<minor>", limit.Device)
+	}
+	major, err := strconv.ParseInt(numbers[0], 10, 64)
+	if err != nil || major < 0 {
+		return 0, 0, fmt.Errorf("invalid major number of device %q", limit.Device)
+	}
+	minor, err := strconv.ParseInt(numbers[1], 10, 64)
+	if err != nil || minor < 0 {
+		return 0, 0, fmt.Errorf("invalid minor number of device %q", limit.Device)
+	}
+	return major, minor, nil
+}
+
+const (
+	// EventSinkTypeWebhook is the type of the event sinks POSTing each event
+	// to a URL
+	EventSinkTypeWebhook = "webhook"
+	// EventSinkTypeUnix is the type of the event sinks writing the events as
+	// JSON lines to a Unix socket
+	EventSinkTypeUnix = "unix"
+	// EventSinkTypeFile is the type of the event sinks appending the events
+	// as JSON lines to a file
+	EventSinkTypeFile = "file"
+)
+
+// EventSink is a local destination that the state changes of tasks and
+// containers are delivered to
+type EventSink struct {
+	// Type is the type of the sink: "webhook", "unix" or "file"
+	Type string
+	// URL is the http or https URL that webhook sinks POST the events to
+	URL string
+	// Path is the path of the Unix socket, or of the file, that the events
+	// are written to
+	Path string
+}
+
+// Validate checks that the sink has the fields required by its type
+func (sink EventSink) Validate() error {
+	switch sink.Type {
+	case EventSinkTypeWebhook:
+		if !strings.HasPrefix(sink.URL, "http://") && !strings.HasPrefix(sink.URL, "https://") {
+			return fmt.Errorf("webhook event sink requires an http or https URL, got %q", sink.URL)
+		}
+	case EventSinkTypeUnix, EventSinkTypeFile:
+		if !filepath.IsAbs(sink.Path) {
+			return fmt.Errorf("%s event sink requires an absolute path, got %q", sink.Type, sink.Path)
+		}
+	default:
+		return fmt.Errorf("unknown event sink type %q", sink.Type)
+	}
+	return nil
+}
+
 type Config struct {
 	// DEPRECATED
 	// ClusterArn is the Name or full ARN of a Cluster to register into. It has
@@ -69,6 +158,11 @@ type Config struct {
 	// file, in DataDir, such that on instance or agent restarts it will resume
 	// as the same ContainerInstance. It defaults to false.
 	Checkpoint bool
+	// StateStore selects the backend used to checkpoint state in DataDir.
+	// "json" (the default) keeps the single ecs_agent_data.json file, while
+	// "boltdb" stores each task, container, image state and ENI attachment as
+	// its own record in an embedded key-value database.
+	StateStore string `trim:"true"`
 
 	// EngineAuthType configures what type of data is in EngineAuthData.
 	// Supported types, right now, can be found in the dockerauth package: https://godoc.org/github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerauth
@@ -145,6 +239,18 @@ type Config struct {
 	// TaskCPUMemLimit specifies if Agent can launch a task with a hierarchical cgroup
 	TaskCPUMemLimit Conditional
 
+	// TaskPidsLimit is the maximum number of processes in the cgroup of the
+	// tasks that don't set their own limit. There's no limit if 0.
+	TaskPidsLimit int64
+
+	// TaskBlockIOWeight is the block IO weight, from 10 to 1000, of the cgroup
+	// of the tasks that don't set their own weight. The weight isn't set if 0.
+	TaskBlockIOWeight uint16
+
+	// TaskBlockIODeviceLimits throttles the block IO of the cgroup of the
+	// tasks that don't set their own device limits
+	TaskBlockIODeviceLimits []BlockIODeviceLimit
+
 	// CredentialsAuditLogFile specifies the path/filename of the audit log.
 	CredentialsAuditLogFile string
 
@@ -186,10 +292,157 @@ type Config struct {
 	// when Agent performs cleanup
 	NumNonECSContainersToDeletePerCycle int
 
+	// DockerDataRoot is the path of the docker data root directory, as seen by
+	// the Agent. The free space and inodes of its filesystem are checked
+	// before tasks pull their images.
+	DockerDataRoot string
+
+	// TaskAdmissionMinFreeDiskMB specifies the free space, in MiB, that the
+	// filesystems of DockerDataRoot and DataDir must have for tasks to pull
+	// their images. 0 disables the check.
+	TaskAdmissionMinFreeDiskMB uint64
+
+	// TaskAdmissionMinFreeInodes specifies the number of free inodes that the
+	// filesystems of DockerDataRoot and DataDir must have for tasks to pull
+	// their images. 0 disables the check.
+	TaskAdmissionMinFreeInodes uint64
+
+	// TaskAdmissionDiskWaitTimeout specifies how long a task waits for free
+	// space or inodes to become available before it is stopped
+	TaskAdmissionDiskWaitTimeout time.Duration
+
+	// TaskAdmissionPolicyFile is the path of the file holding the policy that
+	// denies or strips the host-level privileges requested by containers,
+	// such as privileged mode or host bind mounts
+	TaskAdmissionPolicyFile string
+
+	// ContainerDriftCheckInterval specifies how often the running containers
+	// are inspected for changes made to them outside of the Agent, such as
+	// with `docker update` or `docker network connect`. Drift isn't checked
+	// if 0.
+	ContainerDriftCheckInterval time.Duration
+
+	// ContainerDriftStopTask specifies whether tasks are stopped when one of
+	// their containers has drifted from the configuration the Agent created
+	// it with
+	ContainerDriftStopTask bool
+
+	// EventSinks are the local destinations, such as webhooks, that the
+	// state changes of tasks and containers are delivered to, in addition
+	// to ECS
+	EventSinks []EventSink
+
+	// OTLPTracesEndpoint is the URL of the local OpenTelemetry collector
+	// that the traces of the task lifecycles are exported to over OTLP/HTTP,
+	// such as "http://127.0.0.1:4318/v1/traces". No traces are collected if
+	// it's empty.
+	OTLPTracesEndpoint string
+
+	// SecretFileMode is the mode of the files the values of the secrets
+	// vended as files are written to
+	SecretFileMode os.FileMode
+
+	// SecretFileUID and SecretFileGID are the user and group owning the
+	// files the values of the secrets vended as files are written to
+	SecretFileUID int
+	SecretFileGID int
+
+	// SecretRotationInterval is the time between two retrievals of the
+	// secrets vended as files of the running tasks, whose files are
+	// rewritten when their value changed. Secrets aren't rotated if 0.
+	SecretRotationInterval time.Duration
+
+	// SecretRotationSignal is the signal, such as "SIGHUP", sent to the
+	// running containers whose secret files were rewritten. No signal is
+	// sent if it's empty.
+	SecretRotationSignal string
+
+	// VaultAddress is the URL of the HashiCorp Vault server, or of the
+	// local Vault agent, that the secrets of the "vault" provider are read
+	// from. Tasks using such secrets fail to start if it's empty.
+	VaultAddress string
+
+	// VaultCACertFile is the PEM file of the CA certificates that the
+	// certificate of the Vault server is verified against, instead of the
+	// ones of the host
+	VaultCACertFile string
+
+	// VaultTokenFile is the file holding the token that the requests to
+	// Vault are authenticated with. It's read before every request.
+	VaultTokenFile string
+
+	// VaultAppRoleRoleIDFile and VaultAppRoleSecretIDFile are the files
+	// holding the role ID and secret ID that the Agent logs in to Vault
+	// with, using AppRole. Requests to Vault aren't authenticated if
+	// neither these nor VaultTokenFile are set, such as when a local Vault
+	// agent adds its own token.
+	VaultAppRoleRoleIDFile   string
+	VaultAppRoleSecretIDFile string
+
+	// VaultAllowedPaths are the paths of Vault that the secrets of the
+	// "vault" provider can be read from, along with the paths under them.
+	// Vault secrets can't be read if it's empty.
+	VaultAllowedPaths []string
+
+	// EnvironmentFileAllowedDirs are the host directories that the
+	// environment files of type "file" can be read from, along with the
+	// directories under them. Environment files can't be read from the host
+	// if it's empty.
+	EnvironmentFileAllowedDirs []string
+
+	// ImageCleanupHighWatermark specifies the percentage of the space of the
+	// DockerDataRoot filesystem that, once used, makes the Agent remove unused
+	// images until the usage drops below ImageCleanupLowWatermark. 0 disables
+	// the disk pressure image cleanup.
+	ImageCleanupHighWatermark uint16
+
+	// ImageCleanupLowWatermark specifies the percentage of the space of the
+	// DockerDataRoot filesystem that the disk pressure image cleanup brings
+	// the usage down to
+	ImageCleanupLowWatermark uint16
+
 	// ImagePullBehavior specifies the agent's behavior for pulling image and loading
 	// local Docker image cache
 	ImagePullBehavior ImagePullBehaviorType
 
+	// ImagePullMaxConcurrencyPerRegistry specifies how many images can be
+	// pulled at the same time from a registry host. Pulls over the limit are
+	// queued, the ones of essential containers first. 0 means no limit.
+	ImagePullMaxConcurrencyPerRegistry uint16
+
+	// ImagePullMirrors maps registry hosts to mirrors that images hosted in
+	// these registries are pulled from first. Images are pulled from their
+	// registry if the pull from the mirror fails, and keep their original name
+	// once pulled.
+	ImagePullMirrors map[string]ImagePullMirror
+
+	// ImagePullMirrorTimeout specifies how long an image is pulled from a
+	// mirror before it's pulled from its registry instead. It's shorter than
+	// the timeout of image pulls so that an unresponsive mirror doesn't use up
+	// the time of the pull.
+	ImagePullMirrorTimeout time.Duration
+
+	// ImagePolicyRequireDigest specifies whether the images of containers
+	// must be referenced by digest for the containers to be created
+	ImagePolicyRequireDigest bool
+
+	// ImagePolicyAllowedRepositories is the list of repositories that the
+	// images of containers must come from for the containers to be created.
+	// Entries are registry hosts, repository names or patterns matched
+	// against repository names, such as "docker.io/library/*". Any
+	// repository is allowed if empty.
+	ImagePolicyAllowedRepositories []string
+
+	// ImagePolicyPublicKeysDir is the directory holding the PEM encoded
+	// public keys that the signatures of the images of containers are
+	// verified with. Signatures aren't verified if empty.
+	ImagePolicyPublicKeysDir string
+
+	// ImagePolicySignaturesDir is the directory holding the signatures of
+	// images, which are looked up as
+	// <repository>@<algorithm>=<digest>/signature-<n>
+	ImagePolicySignaturesDir string
+
 	// InstanceAttributes contains key/value pairs representing
 	// attributes to be associated with this instance within the
 	// ECS service and used to influence behavior such as launch
@@ -215,7 +468,8 @@ type Config struct {
 	// the image from the tarball; the referenced image must already be loaded.
 	PauseContainerTag string
 
-	// PrometheusMetricsEnabled configures whether Agent metrics should be
+	// PrometheusMetricsEnabled configures whether Agent metrics, along with
+	// the resource stats and health status of tasks and containers, should be
 	// collected and published to the specified endpoint. This is disabled by
 	// default.
 	PrometheusMetricsEnabled bool
@@ -288,6 +542,12 @@ type Config struct {
 	// ImageCleanupExclusionList is the list of image names customers want to keep for their own use and delete automatically
 	ImageCleanupExclusionList []string
 
+	// PinnedImages is the list of images, optionally with digests, that the
+	// Agent pulls when it starts so that they are cached before the first tasks
+	// using them get started. Pinned images are never removed by the image
+	// cleanup.
+	PinnedImages []string
+
 	// NvidiaRuntime is the runtime to be used for passing Nvidia GPU devices to containers
 	NvidiaRuntime string `trim:"true"`
 
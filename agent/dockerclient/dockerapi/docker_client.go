@@ -44,6 +44,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 
 	"github.com/cihub/seelog"
+	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
 	dockercontainer "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
@@ -133,6 +134,10 @@ type DockerClient interface {
 	// provided for the request.
 	InspectContainer(context.Context, string, time.Duration) (*types.ContainerJSON, error)
 
+	// KillContainer sends a signal, such as "SIGHUP", to the container identified by the name provided. A timeout
+	// value and a context should be provided for the request.
+	KillContainer(context.Context, string, string, time.Duration) error
+
 	// ListContainers returns the set of containers known to the Docker daemon. A timeout value and a context
 	// should be provided for the request.
 	ListContainers(context.Context, bool, time.Duration) ListContainersResponse
@@ -353,6 +358,100 @@ func (dg *dockerGoClient) pullImage(ctx context.Context, image string,
 	if err != nil {
 		return wrapPullErrorAsNamedError(err)
 	}
+
+	if mirrorRepository, mirror, ok := dg.imagePullMirror(image); ok {
+		err := dg.pullImageFromMirror(ctx, client, image, mirrorRepository, mirror, sdkAuthConfig)
+		if err == nil {
+			return nil
+		}
+		seelog.Warnf("DockerGoClient: unable to pull image %s from mirror %s, pulling it from its registry: %v",
+			image, mirror.Endpoint, err)
+	}
+	return dg.pullRepository(ctx, client, image, getRepository(image), sdkAuthConfig)
+}
+
+// pullImageFromMirror pulls an image from a mirror of its registry, and tags it
+// with its original name. The pull from the mirror is given the mirror timeout,
+// so that the pull from the registry still has time if the mirror is
+// unresponsive.
+func (dg *dockerGoClient) pullImageFromMirror(ctx context.Context, client sdkclient.Client, image string,
+	mirrorRepository string, mirror config.ImagePullMirror, sdkAuthConfig types.AuthConfig) apierrors.NamedError {
+	mirrorAuthConfig := sdkAuthConfig
+	mirrorAuthConfig.ServerAddress = mirror.Endpoint
+	if !mirror.ForwardCredentials {
+		var err error
+		mirrorAuthConfig, err = dg.auth.GetAuthconfig(mirrorRepository, nil)
+		if err != nil {
+			return wrapPullErrorAsNamedError(err)
+		}
+	}
+
+	// The name of the mirror is only removed once the image is pulled if the
+	// pull creates it, so that names of the mirror used by others are kept
+	_, _, err := client.ImageInspectWithRaw(ctx, mirrorRepository)
+	mirrorNameCreated := err != nil
+
+	seelog.Infof("DockerGoClient: pulling image %s from mirror as %s", image, mirrorRepository)
+	mirrorCtx, cancel := context.WithTimeout(ctx, dg.config.ImagePullMirrorTimeout)
+	defer cancel()
+	if err := dg.pullRepository(mirrorCtx, client, image, mirrorRepository, mirrorAuthConfig); err != nil {
+		return err
+	}
+	if mirrorNameCreated {
+		// Only the name of the mirror is removed, as the image is tagged with
+		// its original name as well. Leaving it would keep the image from being
+		// removed by the image cleanup.
+		defer dg.removeMirrorName(ctx, client, image, mirrorRepository)
+	}
+
+	repository := getRepository(image)
+	if err := client.ImageTag(ctx, mirrorRepository, repository); err != nil {
+		return CannotPullContainerError{fmt.Errorf("unable to tag %s as %s: %v", mirrorRepository, repository, err)}
+	}
+	return nil
+}
+
+// removeMirrorName removes the name of the mirror an image was pulled from
+func (dg *dockerGoClient) removeMirrorName(ctx context.Context, client sdkclient.Client, image string,
+	mirrorRepository string) {
+	if _, err := client.ImageRemove(ctx, mirrorRepository, types.ImageRemoveOptions{}); err != nil {
+		seelog.Warnf("DockerGoClient: unable to remove name %s of image %s pulled from mirror: %v", mirrorRepository, image, err)
+	}
+}
+
+// imagePullMirror returns the repository to pull an image from, if the image
+// has a mirror configured for its registry
+func (dg *dockerGoClient) imagePullMirror(image string) (string, config.ImagePullMirror, bool) {
+	return ImagePullMirror(dg.config.ImagePullMirrors, image)
+}
+
+// ImagePullMirror returns the repository to pull an image from, if the image
+// has one of the given mirrors configured for its registry. Images referenced
+// by digest are never pulled from mirrors, as they couldn't be tagged with
+// their original name.
+func ImagePullMirror(mirrors map[string]config.ImagePullMirror, image string) (string, config.ImagePullMirror, bool) {
+	if len(mirrors) == 0 {
+		return "", config.ImagePullMirror{}, false
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", config.ImagePullMirror{}, false
+	}
+	mirror, ok := mirrors[reference.Domain(named)]
+	if !ok {
+		return "", config.ImagePullMirror{}, false
+	}
+	tagged, ok := reference.TagNameOnly(named).(reference.Tagged)
+	if _, digested := named.(reference.Digested); digested || !ok {
+		seelog.Debugf("DockerGoClient: image %s is referenced by digest, not pulling it from mirror %s", image, mirror.Endpoint)
+		return "", config.ImagePullMirror{}, false
+	}
+	return strings.TrimSuffix(mirror.Endpoint, "/") + "/" + reference.Path(named) + ":" + tagged.Tag(), mirror, true
+}
+
+// pullRepository pulls an image from the given repository
+func (dg *dockerGoClient) pullRepository(ctx context.Context, client sdkclient.Client, image string,
+	repository string, sdkAuthConfig types.AuthConfig) apierrors.NamedError {
 	// encode auth data
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(sdkAuthConfig); err != nil {
@@ -364,8 +463,6 @@ func (dg *dockerGoClient) pullImage(ctx context.Context, image string,
 		RegistryAuth: base64.URLEncoding.EncodeToString(buf.Bytes()),
 	}
 
-	repository := getRepository(image)
-
 	timeout := dg.time().After(dockerclient.DockerPullBeginTimeout)
 	// pullBegan is a channel indicating that we have seen at least one line of data on the 'OutputStream' above.
 	// It is here to guard against a bug wherein Docker never writes anything to that channel and hangs in pulling forever.
@@ -435,7 +532,7 @@ func (dg *dockerGoClient) pullImage(ctx context.Context, image string,
 	}
 	seelog.Debugf("DockerGoClient: pull began for image: %s", image)
 
-	err = <-pullFinished
+	err := <-pullFinished
 	if err != nil {
 		return CannotPullContainerError{err}
 	}
@@ -721,6 +818,40 @@ func (dg *dockerGoClient) RemoveContainer(ctx context.Context, dockerID string,
 	}
 }
 
+func (dg *dockerGoClient) KillContainer(ctx context.Context, dockerID string, signal string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	defer metrics.MetricsEngineGlobal.RecordDockerMetric("KILL_CONTAINER")()
+	// Buffered channel so in the case of timeout it takes one write, never gets
+	// read, and can still be GC'd
+	response := make(chan error, 1)
+	go func() { response <- dg.killContainer(ctx, dockerID, signal) }()
+	// Wait until we get a response or for the 'done' context channel
+	select {
+	case resp := <-response:
+		return resp
+	case <-ctx.Done():
+		err := ctx.Err()
+		// Context has either expired or canceled. If it has timed out,
+		// send back the DockerTimeoutError
+		if err == context.DeadlineExceeded {
+			return &DockerTimeoutError{timeout, "killing"}
+		}
+		return &CannotKillContainerError{err}
+	}
+}
+
+func (dg *dockerGoClient) killContainer(ctx context.Context, dockerID string, signal string) error {
+	client, err := dg.sdkDockerClient()
+	if err != nil {
+		return err
+	}
+	if err := client.ContainerKill(ctx, dockerID, signal); err != nil {
+		return &CannotKillContainerError{err}
+	}
+	return nil
+}
+
 func (dg *dockerGoClient) removeContainer(ctx context.Context, dockerID string) error {
 	client, err := dg.sdkDockerClient()
 	if err != nil {
@@ -798,6 +929,7 @@ func MetadataFromContainer(dockerContainer *types.ContainerJSON) DockerContainer
 		metadata.Error = NewDockerStateError(dockerContainer.State.Error)
 	}
 	if dockerContainer.State.OOMKilled {
+		metadata.OOMKilled = true
 		metadata.Error = OutOfMemoryError{}
 	}
 	// Health field in Docker SDK is a pointer, need to check before not nil before dereference.
@@ -933,7 +1065,15 @@ func (dg *dockerGoClient) handleContainerEvents(ctx context.Context,
 			seelog.Infof("DockerGoClient: process within container %s died due to OOM", containerInfo)
 			// "oom" can either means any process got OOM'd, but doesn't always
 			// mean the container dies (non-init processes). If the container also
-			// dies, you see a "die" status as well; we'll update suitably there
+			// dies, you see a "die" status as well; we'll update suitably there.
+			// The OOM kill itself is passed up so that it's recorded.
+			changedContainers <- DockerContainerChangeEvent{
+				Type: apicontainer.ContainerOOMEvent,
+				DockerContainerMetadata: DockerContainerMetadata{
+					DockerID:  containerID,
+					OOMKilled: true,
+				},
+			}
 			continue
 		case "health_status: healthy":
 			fallthrough
@@ -18,6 +18,7 @@ package dockerapi
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -248,6 +249,230 @@ func TestImagePullDigest(t *testing.T) {
 	assert.NoError(t, metadata.Error, "Expected pull to succeed")
 }
 
+func TestImagePullFromMirror(t *testing.T) {
+	mockDockerSDK, client, testTime, _, _, done := dockerClientSetup(t)
+	defer done()
+	client.config.ImagePullMirrors = map[string]config.ImagePullMirror{
+		"docker.io": {Endpoint: "mirror.local:5000/"},
+	}
+
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
+	gomock.InOrder(
+		mockDockerSDK.EXPECT().ImageInspectWithRaw(gomock.Any(), "mirror.local:5000/library/image:latest").Return(
+			types.ImageInspect{}, nil, errors.New("no such image")),
+		mockDockerSDK.EXPECT().ImagePull(gomock.Any(), "mirror.local:5000/library/image:latest", gomock.Any()).Return(
+			mockReadCloser{
+				reader: strings.NewReader(`{"status":"pull complete"}`),
+			}, nil),
+		mockDockerSDK.EXPECT().ImageTag(gomock.Any(), "mirror.local:5000/library/image:latest", "image:latest").Return(nil),
+		mockDockerSDK.EXPECT().ImageRemove(gomock.Any(), "mirror.local:5000/library/image:latest", gomock.Any()).Return(nil, nil),
+	)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	metadata := client.PullImage(ctx, "image", nil, dockerclient.PullImageTimeout)
+	assert.NoError(t, metadata.Error, "Expected pull to succeed")
+}
+
+func TestImagePullFromMirrorKeepsExistingMirrorName(t *testing.T) {
+	mockDockerSDK, client, testTime, _, _, done := dockerClientSetup(t)
+	defer done()
+	client.config.ImagePullMirrors = map[string]config.ImagePullMirror{
+		"docker.io": {Endpoint: "mirror.local:5000"},
+	}
+
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
+	gomock.InOrder(
+		mockDockerSDK.EXPECT().ImageInspectWithRaw(gomock.Any(), "mirror.local:5000/library/image:latest").Return(
+			types.ImageInspect{ID: "sha256:image"}, nil, nil),
+		mockDockerSDK.EXPECT().ImagePull(gomock.Any(), "mirror.local:5000/library/image:latest", gomock.Any()).Return(
+			mockReadCloser{
+				reader: strings.NewReader(`{"status":"pull complete"}`),
+			}, nil),
+		mockDockerSDK.EXPECT().ImageTag(gomock.Any(), "mirror.local:5000/library/image:latest", "image:latest").Return(nil),
+	)
+	mockDockerSDK.EXPECT().ImageRemove(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	metadata := client.PullImage(ctx, "image", nil, dockerclient.PullImageTimeout)
+	assert.NoError(t, metadata.Error, "Expected pull to succeed")
+}
+
+func TestImagePullFromMirrorRemovesMirrorNameWhenTagFails(t *testing.T) {
+	mockDockerSDK, client, testTime, _, _, done := dockerClientSetup(t)
+	defer done()
+	client.config.ImagePullMirrors = map[string]config.ImagePullMirror{
+		"docker.io": {Endpoint: "mirror.local:5000"},
+	}
+
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
+	gomock.InOrder(
+		mockDockerSDK.EXPECT().ImageInspectWithRaw(gomock.Any(), "mirror.local:5000/library/image:latest").Return(
+			types.ImageInspect{}, nil, errors.New("no such image")),
+		mockDockerSDK.EXPECT().ImagePull(gomock.Any(), "mirror.local:5000/library/image:latest", gomock.Any()).Return(
+			mockReadCloser{
+				reader: strings.NewReader(`{"status":"pull complete"}`),
+			}, nil),
+		mockDockerSDK.EXPECT().ImageTag(gomock.Any(), "mirror.local:5000/library/image:latest", "image:latest").Return(
+			errors.New("tag failed")),
+		mockDockerSDK.EXPECT().ImageRemove(gomock.Any(), "mirror.local:5000/library/image:latest", gomock.Any()).Return(nil, nil),
+		mockDockerSDK.EXPECT().ImagePull(gomock.Any(), "image:latest", gomock.Any()).Return(
+			mockReadCloser{
+				reader: strings.NewReader(`{"status":"pull complete"}`),
+			}, nil),
+	)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	metadata := client.PullImage(ctx, "image", nil, dockerclient.PullImageTimeout)
+	assert.NoError(t, metadata.Error, "Expected pull to succeed")
+}
+
+func TestImagePullFromMirrorTimeout(t *testing.T) {
+	mockDockerSDK, client, testTime, _, _, done := dockerClientSetup(t)
+	defer done()
+	client.config.ImagePullMirrors = map[string]config.ImagePullMirror{
+		"docker.io": {Endpoint: "mirror.local:5000"},
+	}
+	client.config.ImagePullMirrorTimeout = 10 * time.Millisecond
+
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
+	gomock.InOrder(
+		mockDockerSDK.EXPECT().ImageInspectWithRaw(gomock.Any(), "mirror.local:5000/library/image:latest").Return(
+			types.ImageInspect{}, nil, errors.New("no such image")),
+		mockDockerSDK.EXPECT().ImagePull(gomock.Any(), "mirror.local:5000/library/image:latest", gomock.Any()).DoAndReturn(
+			func(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}),
+		mockDockerSDK.EXPECT().ImagePull(gomock.Any(), "image:latest", gomock.Any()).Return(
+			mockReadCloser{
+				reader: strings.NewReader(`{"status":"pull complete"}`),
+			}, nil),
+	)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	metadata := client.PullImage(ctx, "image", nil, dockerclient.PullImageTimeout)
+	assert.NoError(t, metadata.Error, "Expected pull to succeed")
+}
+
+func TestImagePullFromMirrorFallsBackToRegistry(t *testing.T) {
+	mockDockerSDK, client, testTime, _, _, done := dockerClientSetup(t)
+	defer done()
+	client.config.ImagePullMirrors = map[string]config.ImagePullMirror{
+		"registry.example.com": {Endpoint: "mirror.local:5000"},
+	}
+
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
+	gomock.InOrder(
+		mockDockerSDK.EXPECT().ImageInspectWithRaw(gomock.Any(), "mirror.local:5000/team/image:mytag").Return(
+			types.ImageInspect{}, nil, errors.New("no such image")),
+		mockDockerSDK.EXPECT().ImagePull(gomock.Any(), "mirror.local:5000/team/image:mytag", gomock.Any()).Return(
+			nil, errors.New("connection refused")),
+		mockDockerSDK.EXPECT().ImagePull(gomock.Any(), "registry.example.com/team/image:mytag", gomock.Any()).Return(
+			mockReadCloser{
+				reader: strings.NewReader(`{"status":"pull complete"}`),
+			}, nil),
+	)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	metadata := client.PullImage(ctx, "registry.example.com/team/image:mytag", nil, dockerclient.PullImageTimeout)
+	assert.NoError(t, metadata.Error, "Expected pull to succeed")
+}
+
+func TestImagePullFromMirrorForwardCredentials(t *testing.T) {
+	testCases := []struct {
+		name               string
+		forwardCredentials bool
+		expectedAuthConfig types.AuthConfig
+	}{
+		{
+			name:               "credentials forwarded",
+			forwardCredentials: true,
+			expectedAuthConfig: types.AuthConfig{
+				Username:      "user",
+				Password:      "pass",
+				ServerAddress: "mirror.local:5000",
+			},
+		},
+		{
+			name:               "credentials not forwarded",
+			forwardCredentials: false,
+			expectedAuthConfig: types.AuthConfig{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDockerSDK, client, testTime, _, _, done := dockerClientSetup(t)
+			defer done()
+			client.config.ImagePullMirrors = map[string]config.ImagePullMirror{
+				"registry.example.com": {Endpoint: "mirror.local:5000", ForwardCredentials: tc.forwardCredentials},
+			}
+			authData := &apicontainer.RegistryAuthenticationData{
+				Type:        apicontainer.AuthTypeASM,
+				ASMAuthData: &apicontainer.ASMAuthData{},
+			}
+			authData.ASMAuthData.SetDockerAuthConfig(types.AuthConfig{Username: "user", Password: "pass"})
+
+			testTime.EXPECT().After(gomock.Any()).AnyTimes()
+			mockDockerSDK.EXPECT().ImageInspectWithRaw(gomock.Any(), "mirror.local:5000/image:latest").Return(
+				types.ImageInspect{}, nil, errors.New("no such image"))
+			mockDockerSDK.EXPECT().ImagePull(gomock.Any(), "mirror.local:5000/image:latest", gomock.Any()).DoAndReturn(
+				func(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
+					authJSON, err := base64.URLEncoding.DecodeString(options.RegistryAuth)
+					require.NoError(t, err)
+					var authConfig types.AuthConfig
+					require.NoError(t, json.Unmarshal(authJSON, &authConfig))
+					assert.Equal(t, tc.expectedAuthConfig, authConfig)
+					return mockReadCloser{
+						reader: strings.NewReader(`{"status":"pull complete"}`),
+					}, nil
+				})
+			mockDockerSDK.EXPECT().ImageTag(gomock.Any(), "mirror.local:5000/image:latest", "registry.example.com/image:latest").Return(nil)
+			mockDockerSDK.EXPECT().ImageRemove(gomock.Any(), "mirror.local:5000/image:latest", gomock.Any()).Return(nil, nil)
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			metadata := client.PullImage(ctx, "registry.example.com/image", authData, dockerclient.PullImageTimeout)
+			assert.NoError(t, metadata.Error, "Expected pull to succeed")
+		})
+	}
+}
+
+func TestImagePullMirror(t *testing.T) {
+	client := &dockerGoClient{
+		config: &config.Config{
+			ImagePullMirrors: map[string]config.ImagePullMirror{
+				"docker.io":            {Endpoint: "mirror.local:5000"},
+				"registry.example.com": {Endpoint: "mirror.local/example/"},
+			},
+		},
+	}
+	testCases := []struct {
+		image              string
+		expectedRepository string
+		expectedOK         bool
+	}{
+		{"busybox", "mirror.local:5000/library/busybox:latest", true},
+		{"amazon/amazon-ecs-agent:v1", "mirror.local:5000/amazon/amazon-ecs-agent:v1", true},
+		{"registry.example.com/team/app:v2", "mirror.local/example/team/app:v2", true},
+		{"busybox@sha256:bc8813ea7b3603864987522f02a76101c17ad122e1c46d790efc0fca78ca7bfb", "", false},
+		{"other.example.com/app", "", false},
+		{"Invalid:Image:Name", "", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.image, func(t *testing.T) {
+			repository, _, ok := client.imagePullMirror(tc.image)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedRepository, repository)
+		})
+	}
+}
+
 func TestPullImageECRSuccess(t *testing.T) {
 	mockDockerSDK, client, mockTime, ctrl, ecrClientFactory, done := dockerClientSetup(t)
 	defer done()
@@ -559,6 +784,31 @@ func TestRemoveContainer(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestKillContainer(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	mockDockerSDK.EXPECT().ContainerKill(gomock.Any(), "id", "SIGHUP").Return(nil)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	err := client.KillContainer(ctx, "id", "SIGHUP", dockerclient.KillContainerTimeout)
+	assert.NoError(t, err)
+}
+
+func TestKillContainerError(t *testing.T) {
+	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
+	defer done()
+
+	mockDockerSDK.EXPECT().ContainerKill(gomock.Any(), "id", "SIGHUP").Return(errors.New("container not running"))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	err := client.KillContainer(ctx, "id", "SIGHUP", dockerclient.KillContainerTimeout)
+	assert.Error(t, err)
+	assert.Equal(t, "CannotKillContainerError", err.(apierrors.NamedError).ErrorName(), "Wrong error type")
+}
+
 func TestInspectContainerTimeout(t *testing.T) {
 	mockDockerSDK, client, _, _, _, done := dockerClientSetup(t)
 	defer done()
@@ -711,6 +961,14 @@ func TestContainerEvents(t *testing.T) {
 	assert.Equal(t, anEvent.Health.Status, apicontainerstatus.ContainerHealthy)
 	assert.Equal(t, anEvent.Health.Output, "health output")
 
+	go func() {
+		eventsChan <- events.Message{Type: "container", ID: "container_oom", Status: "oom"}
+	}()
+	anEvent = <-dockerEvents
+	assert.Equal(t, apicontainer.ContainerOOMEvent, anEvent.Type, "unexpected docker events type received")
+	assert.Equal(t, "container_oom", anEvent.DockerID)
+	assert.True(t, anEvent.OOMKilled)
+
 	// Verify the following events do not translate into our event stream
 
 	//
@@ -732,7 +990,6 @@ func TestContainerEvents(t *testing.T) {
 		"untag",
 		"import",
 		"delete",
-		"oom",
 		"kill",
 	}
 	for _, eventStatus := range ignore {
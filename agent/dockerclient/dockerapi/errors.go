@@ -234,6 +234,20 @@ func (err CannotRemoveContainerError) ErrorName() string {
 	return "CannotRemoveContainerError"
 }
 
+// CannotKillContainerError indicates any error when trying to send a signal to a container
+type CannotKillContainerError struct {
+	FromError error
+}
+
+func (err CannotKillContainerError) Error() string {
+	return err.FromError.Error()
+}
+
+// ErrorName returns name of the CannotKillContainerError
+func (err CannotKillContainerError) ErrorName() string {
+	return "CannotKillContainerError"
+}
+
 // CannotDescribeContainerError indicates any error when trying to describe a container
 type CannotDescribeContainerError struct {
 	FromError error
@@ -189,6 +189,20 @@ func (mr *MockDockerClientMockRecorder) InspectVolume(arg0, arg1, arg2 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectVolume", reflect.TypeOf((*MockDockerClient)(nil).InspectVolume), arg0, arg1, arg2)
 }
 
+// KillContainer mocks base method
+func (m *MockDockerClient) KillContainer(arg0 context.Context, arg1, arg2 string, arg3 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KillContainer", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// KillContainer indicates an expected call of KillContainer
+func (mr *MockDockerClientMockRecorder) KillContainer(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KillContainer", reflect.TypeOf((*MockDockerClient)(nil).KillContainer), arg0, arg1, arg2, arg3)
+}
+
 // KnownVersions mocks base method
 func (m *MockDockerClient) KnownVersions() []dockerclient.DockerVersion {
 	m.ctrl.T.Helper()
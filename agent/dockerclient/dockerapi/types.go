@@ -71,6 +71,9 @@ type DockerContainerMetadata struct {
 	FinishedAt time.Time
 	// Health contains the result of a container health check
 	Health apicontainer.HealthStatus
+	// OOMKilled is true if a process of the container was killed for
+	// running out of memory
+	OOMKilled bool
 	// NetworkMode denotes the network mode in which the container is started
 	NetworkMode string
 	// NetworksUnsafe denotes the Docker Network Settings in the container
@@ -133,6 +136,10 @@ func (event *DockerContainerChangeEvent) String() string {
 		res += ", Error: " + event.Error.Error()
 	}
 
+	if event.OOMKilled {
+		res += ", OOMKilled"
+	}
+
 	if len(event.Volumes) != 0 {
 		res += fmt.Sprintf(", Volumes: %v", event.Volumes)
 	}
@@ -44,6 +44,8 @@ const (
 	StopContainerTimeout = 30 * time.Second
 	// RemoveContainerTimeout is the timeout for the RemoveContainer API.
 	RemoveContainerTimeout = 5 * time.Minute
+	// KillContainerTimeout is the timeout for the KillContainer API.
+	KillContainerTimeout = 30 * time.Second
 
 	// CreateVolumeTimeout is the timeout for CreateVolume API.
 	CreateVolumeTimeout = 5 * time.Minute
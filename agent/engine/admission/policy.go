@@ -0,0 +1,391 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package admission implements the task admission policy, which denies or
+// strips the host-level privileges requested by the containers of tasks
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/pkg/errors"
+)
+
+// Action is the action that the policy takes on the containers requesting a
+// privilege
+type Action string
+
+const (
+	// ActionAllow lets containers use the privilege. It's the action of
+	// rules without an action.
+	ActionAllow Action = "allow"
+	// ActionDeny keeps containers requesting the privilege from being
+	// created, which stops their task
+	ActionDeny Action = "deny"
+	// ActionStrip removes the privilege from the containers requesting it,
+	// which are created without it
+	ActionStrip Action = "strip"
+)
+
+const (
+	// RulePrivileged is the name of the rule about privileged mode
+	RulePrivileged = "Privileged"
+	// RuleHostNetwork is the name of the rule about the host network mode
+	RuleHostNetwork = "HostNetwork"
+	// RuleHostPID is the name of the rule about the host PID mode
+	RuleHostPID = "HostPID"
+	// RuleHostIPC is the name of the rule about the host IPC mode
+	RuleHostIPC = "HostIPC"
+	// RuleCapabilities is the name of the rule about Linux capabilities
+	RuleCapabilities = "Capabilities"
+	// RuleBindMounts is the name of the rule about host bind mounts
+	RuleBindMounts = "BindMounts"
+	// RuleDevices is the name of the rule about host devices
+	RuleDevices = "Devices"
+)
+
+// Rule is the action taken on the containers requesting a privilege
+type Rule struct {
+	Action Action
+}
+
+// CapabilitiesRule is the action taken on the containers adding Linux
+// capabilities
+type CapabilitiesRule struct {
+	Action Action
+	// Capabilities are the capabilities the action is taken on, such as
+	// "SYS_ADMIN". The action is taken on any added capability if empty.
+	Capabilities []string
+}
+
+// HostPathRule is the action taken on the containers using host paths,
+// other than the allowed ones
+type HostPathRule struct {
+	Action Action
+	// AllowedPrefixes are the host paths that containers can use, along
+	// with the paths under them
+	AllowedPrefixes []string
+}
+
+// Policy is the task admission policy. Containers are checked against it
+// before being created.
+type Policy struct {
+	Privileged   Rule
+	HostNetwork  Rule
+	HostPID      Rule
+	HostIPC      Rule
+	Capabilities CapabilitiesRule
+	BindMounts   HostPathRule
+	Devices      HostPathRule
+}
+
+// Decision is the action taken by the policy on a privilege requested by a
+// container
+type Decision struct {
+	// Rule is the name of the rule of the policy that took the action
+	Rule string
+	// Action is the action taken, either ActionDeny or ActionStrip
+	Action Action
+	// Privilege describes the privilege, such as "capability SYS_ADMIN"
+	Privilege string
+}
+
+// Denied returns true if the container is denied because of the decision
+func (decision Decision) Denied() bool {
+	return decision.Action == ActionDeny
+}
+
+func (decision Decision) String() string {
+	if decision.Denied() {
+		return fmt.Sprintf("%s is denied by the task admission policy", decision.Privilege)
+	}
+	return fmt.Sprintf("%s is stripped by the task admission policy", decision.Privilege)
+}
+
+// LoadPolicy loads the policy from a JSON file. Fields that aren't part of the
+// policy are rejected, so that misspelled rules don't go unnoticed.
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read task admission policy %s", file)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	policy := &Policy{}
+	if err := decoder.Decode(policy); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse task admission policy %s", file)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid task admission policy %s", file)
+	}
+	return policy, nil
+}
+
+func (policy *Policy) validate() error {
+	actions := map[string]Action{
+		RulePrivileged:   policy.Privileged.Action,
+		RuleHostNetwork:  policy.HostNetwork.Action,
+		RuleHostPID:      policy.HostPID.Action,
+		RuleHostIPC:      policy.HostIPC.Action,
+		RuleCapabilities: policy.Capabilities.Action,
+		RuleBindMounts:   policy.BindMounts.Action,
+		RuleDevices:      policy.Devices.Action,
+	}
+	for rule, action := range actions {
+		switch action {
+		case "", ActionAllow, ActionDeny, ActionStrip:
+		default:
+			return fmt.Errorf("unknown action %q of rule %s", action, rule)
+		}
+	}
+	if policy.HostNetwork.Action == ActionStrip {
+		return fmt.Errorf("action %q of rule %s is not supported", ActionStrip, RuleHostNetwork)
+	}
+	for _, rule := range []HostPathRule{policy.BindMounts, policy.Devices} {
+		for _, prefix := range rule.AllowedPrefixes {
+			if !filepath.IsAbs(prefix) {
+				return fmt.Errorf("allowed prefix %s is not an absolute path", prefix)
+			}
+		}
+	}
+	return nil
+}
+
+// Evaluate checks the host config of a container against the policy. The
+// privileges stripped by the policy are removed from the host config. The
+// decisions taken are returned, and the container must not be created if any
+// of them is denied.
+//
+// The task containers are the docker names of the other containers of the
+// task, whose volumes the container can use, as their bind mounts were checked
+// when they were created. The volumes of any other container are taken as
+// bind mounts of host paths that the policy can't check.
+func (policy *Policy) Evaluate(hostConfig *dockercontainer.HostConfig, taskContainers []string) []Decision {
+	if policy == nil || hostConfig == nil {
+		return nil
+	}
+	var decisions []Decision
+
+	if hostConfig.Privileged && taken(policy.Privileged.Action) {
+		decisions = append(decisions, Decision{RulePrivileged, policy.Privileged.Action, "privileged mode"})
+		if policy.Privileged.Action == ActionStrip {
+			hostConfig.Privileged = false
+		}
+	}
+
+	// The host network mode is never stripped, as the containers would be
+	// moved to the bridge network without being configured for it
+	if hostConfig.NetworkMode.IsHost() && taken(policy.HostNetwork.Action) {
+		decisions = append(decisions, Decision{RuleHostNetwork, ActionDeny, "host network mode"})
+	}
+
+	if hostConfig.PidMode.IsHost() && taken(policy.HostPID.Action) {
+		decisions = append(decisions, Decision{RuleHostPID, policy.HostPID.Action, "host PID mode"})
+		if policy.HostPID.Action == ActionStrip {
+			hostConfig.PidMode = ""
+		}
+	}
+
+	if hostConfig.IpcMode.IsHost() && taken(policy.HostIPC.Action) {
+		decisions = append(decisions, Decision{RuleHostIPC, policy.HostIPC.Action, "host IPC mode"})
+		if policy.HostIPC.Action == ActionStrip {
+			hostConfig.IpcMode = ""
+		}
+	}
+
+	if taken(policy.Capabilities.Action) {
+		var capAdd []string
+		for _, capability := range hostConfig.CapAdd {
+			if !policy.Capabilities.matches(capability) {
+				capAdd = append(capAdd, capability)
+				continue
+			}
+			decisions = append(decisions, Decision{RuleCapabilities, policy.Capabilities.Action,
+				"capability " + normalizeCapability(capability)})
+			if policy.Capabilities.Action == ActionDeny {
+				capAdd = append(capAdd, capability)
+			}
+		}
+		hostConfig.CapAdd = capAdd
+	}
+
+	if taken(policy.BindMounts.Action) {
+		var binds []string
+		for _, bind := range hostConfig.Binds {
+			hostPath, ok := bindHostPath(bind)
+			if !ok || policy.BindMounts.allows(hostPath) {
+				binds = append(binds, bind)
+				continue
+			}
+			decisions = append(decisions, Decision{RuleBindMounts, policy.BindMounts.Action,
+				"bind mount of host path " + hostPath})
+			if policy.BindMounts.Action == ActionDeny {
+				binds = append(binds, bind)
+			}
+		}
+		hostConfig.Binds = binds
+
+		var mounts []mount.Mount
+		for _, hostMount := range hostConfig.Mounts {
+			if hostMount.Type != mount.TypeBind || policy.BindMounts.allows(hostMount.Source) {
+				mounts = append(mounts, hostMount)
+				continue
+			}
+			decisions = append(decisions, Decision{RuleBindMounts, policy.BindMounts.Action,
+				"bind mount of host path " + hostMount.Source})
+			if policy.BindMounts.Action == ActionDeny {
+				mounts = append(mounts, hostMount)
+			}
+		}
+		hostConfig.Mounts = mounts
+
+		var volumesFrom []string
+		for _, volumeFrom := range hostConfig.VolumesFrom {
+			sourceContainer := strings.SplitN(volumeFrom, ":", 2)[0]
+			if isTaskContainer(sourceContainer, taskContainers) {
+				volumesFrom = append(volumesFrom, volumeFrom)
+				continue
+			}
+			decisions = append(decisions, Decision{RuleBindMounts, policy.BindMounts.Action,
+				"volumes of container " + sourceContainer})
+			if policy.BindMounts.Action == ActionDeny {
+				volumesFrom = append(volumesFrom, volumeFrom)
+			}
+		}
+		hostConfig.VolumesFrom = volumesFrom
+	}
+
+	if taken(policy.Devices.Action) {
+		var devices []dockercontainer.DeviceMapping
+		for _, device := range hostConfig.Devices {
+			if policy.Devices.allows(device.PathOnHost) {
+				devices = append(devices, device)
+				continue
+			}
+			decisions = append(decisions, Decision{RuleDevices, policy.Devices.Action,
+				"device " + device.PathOnHost})
+			if policy.Devices.Action == ActionDeny {
+				devices = append(devices, device)
+			}
+		}
+		hostConfig.Devices = devices
+	}
+
+	return decisions
+}
+
+// taken returns true if the action restricts the privilege
+func taken(action Action) bool {
+	return action == ActionDeny || action == ActionStrip
+}
+
+// matches returns true if the action of the rule is taken on the capability
+func (rule CapabilitiesRule) matches(capability string) bool {
+	if len(rule.Capabilities) == 0 {
+		return true
+	}
+	capability = normalizeCapability(capability)
+	for _, ruleCapability := range rule.Capabilities {
+		ruleCapability = normalizeCapability(ruleCapability)
+		if ruleCapability == capability || ruleCapability == "ALL" || capability == "ALL" {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCapability returns the name of the capability without the "CAP_"
+// prefix, which Docker accepts but doesn't require
+func normalizeCapability(capability string) string {
+	return strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+}
+
+// allows returns true if the host path is one of the allowed prefixes or a
+// path under them
+func (rule HostPathRule) allows(hostPath string) bool {
+	hostPath = filepath.Clean(hostPath)
+	for _, prefix := range rule.AllowedPrefixes {
+		prefix = filepath.Clean(prefix)
+		if hostPath == prefix || strings.HasPrefix(hostPath, strings.TrimSuffix(prefix, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindHostPath returns the host path of a bind, in the
+// <source>:<destination>[:<options>] format. Binds of named volumes have no
+// host path.
+func bindHostPath(bind string) (string, bool) {
+	source := bindSource(bind, runtime.GOOS == "windows")
+	if !filepath.IsAbs(source) {
+		return "", false
+	}
+	return source, true
+}
+
+// bindSource returns the source of a bind, which precedes its destination and
+// options. On Windows, paths start with a drive letter whose colon doesn't
+// separate the fields of the bind, as in C:\host:C:\container:ro.
+func bindSource(bind string, windows bool) string {
+	var fields []string
+	for _, field := range strings.Split(bind, ":") {
+		if last := len(fields) - 1; windows && last >= 0 && isDriveLetter(fields[last]) &&
+			(strings.HasPrefix(field, `\`) || strings.HasPrefix(field, "/")) {
+			fields[last] += ":" + field
+			continue
+		}
+		fields = append(fields, field)
+	}
+	// The destination is the last field, or the one before it if the last
+	// one holds the options
+	if len(fields) > 2 && !isContainerPath(fields[len(fields)-1], windows) {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) < 2 {
+		return ""
+	}
+	return strings.Join(fields[:len(fields)-1], ":")
+}
+
+// isDriveLetter returns true if the field of a bind is a Windows drive letter
+func isDriveLetter(field string) bool {
+	return len(field) == 1 && (field[0] >= 'a' && field[0] <= 'z' || field[0] >= 'A' && field[0] <= 'Z')
+}
+
+// isContainerPath returns true if the field of a bind is a path rather than
+// options, such as ro or z
+func isContainerPath(field string, windows bool) bool {
+	if windows {
+		return strings.Contains(field, `\`) || strings.Contains(field, "/")
+	}
+	return strings.HasPrefix(field, "/")
+}
+
+// isTaskContainer returns true if the container is one of the task containers
+func isTaskContainer(container string, taskContainers []string) bool {
+	for _, taskContainer := range taskContainers {
+		if container == taskContainer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package admission
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePolicy(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "admission")
+	require.NoError(t, err)
+	file := filepath.Join(dir, "policy.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(content), 0600))
+	return file
+}
+
+func TestLoadPolicy(t *testing.T) {
+	file := writePolicy(t, `{
+		"Privileged": {"Action": "deny"},
+		"HostNetwork": {"Action": "deny"},
+		"Capabilities": {"Action": "deny", "Capabilities": ["SYS_ADMIN"]},
+		"BindMounts": {"Action": "deny", "AllowedPrefixes": ["/var/log"]}
+	}`)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	policy, err := LoadPolicy(file)
+	require.NoError(t, err)
+	assert.Equal(t, ActionDeny, policy.Privileged.Action)
+	assert.Equal(t, ActionDeny, policy.HostNetwork.Action)
+	assert.Equal(t, Action(""), policy.HostPID.Action)
+	assert.Equal(t, []string{"SYS_ADMIN"}, policy.Capabilities.Capabilities)
+	assert.Equal(t, []string{"/var/log"}, policy.BindMounts.AllowedPrefixes)
+}
+
+func TestLoadPolicyErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{"invalid json", `{"Privileged": `},
+		{"unknown rule", `{"Privilegd": {"Action": "deny"}}`},
+		{"unknown action", `{"HostPID": {"Action": "block"}}`},
+		{"host network strip", `{"HostNetwork": {"Action": "strip"}}`},
+		{"relative prefix", `{"Devices": {"Action": "deny", "AllowedPrefixes": ["dev/fuse"]}}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := writePolicy(t, tc.content)
+			defer os.RemoveAll(filepath.Dir(file))
+
+			_, err := LoadPolicy(file)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := LoadPolicy("/nonexistent/policy.json")
+	assert.Error(t, err)
+}
+
+func TestEvaluateNilPolicy(t *testing.T) {
+	var policy *Policy
+	hostConfig := &dockercontainer.HostConfig{Privileged: true}
+	assert.Empty(t, policy.Evaluate(hostConfig, nil))
+	assert.True(t, hostConfig.Privileged)
+}
+
+func TestEvaluateAllow(t *testing.T) {
+	policy := &Policy{Privileged: Rule{Action: ActionAllow}}
+	hostConfig := &dockercontainer.HostConfig{
+		Privileged:  true,
+		NetworkMode: "host",
+		CapAdd:      []string{"SYS_ADMIN"},
+		Binds:       []string{"/etc:/etc"},
+	}
+	assert.Empty(t, policy.Evaluate(hostConfig, nil))
+}
+
+func TestEvaluateDeny(t *testing.T) {
+	policy := &Policy{
+		Privileged:  Rule{Action: ActionDeny},
+		HostNetwork: Rule{Action: ActionDeny},
+		HostPID:     Rule{Action: ActionDeny},
+		HostIPC:     Rule{Action: ActionDeny},
+	}
+	hostConfig := &dockercontainer.HostConfig{
+		Privileged:  true,
+		NetworkMode: "host",
+		PidMode:     "host",
+		IpcMode:     "host",
+	}
+	decisions := policy.Evaluate(hostConfig, nil)
+	require.Len(t, decisions, 4)
+	for _, decision := range decisions {
+		assert.True(t, decision.Denied())
+	}
+	assert.Equal(t, RulePrivileged, decisions[0].Rule)
+	assert.Equal(t, "privileged mode is denied by the task admission policy", decisions[0].String())
+	// Denied privileges are left in the host config
+	assert.True(t, hostConfig.Privileged)
+	assert.True(t, hostConfig.NetworkMode.IsHost())
+}
+
+func TestEvaluateStrip(t *testing.T) {
+	policy := &Policy{
+		Privileged:  Rule{Action: ActionStrip},
+		HostNetwork: Rule{Action: ActionStrip},
+		HostPID:     Rule{Action: ActionStrip},
+		HostIPC:     Rule{Action: ActionStrip},
+	}
+	hostConfig := &dockercontainer.HostConfig{
+		Privileged:  true,
+		NetworkMode: "host",
+		PidMode:     "host",
+		IpcMode:     "host",
+	}
+	decisions := policy.Evaluate(hostConfig, nil)
+	require.Len(t, decisions, 4)
+	assert.False(t, decisions[0].Denied())
+	// The host network mode is denied rather than stripped
+	assert.Equal(t, RuleHostNetwork, decisions[1].Rule)
+	assert.True(t, decisions[1].Denied())
+	assert.False(t, decisions[2].Denied())
+	assert.False(t, decisions[3].Denied())
+	assert.False(t, hostConfig.Privileged)
+	assert.True(t, hostConfig.NetworkMode.IsHost())
+	assert.False(t, hostConfig.PidMode.IsHost())
+	assert.False(t, hostConfig.IpcMode.IsHost())
+}
+
+func TestEvaluateCapabilities(t *testing.T) {
+	policy := &Policy{
+		Capabilities: CapabilitiesRule{Action: ActionStrip, Capabilities: []string{"SYS_ADMIN", "CAP_NET_ADMIN"}},
+	}
+	hostConfig := &dockercontainer.HostConfig{CapAdd: []string{"CAP_SYS_ADMIN", "NET_ADMIN", "CHOWN"}}
+	decisions := policy.Evaluate(hostConfig, nil)
+	require.Len(t, decisions, 2)
+	assert.Equal(t, "capability SYS_ADMIN", decisions[0].Privilege)
+	assert.Equal(t, "capability NET_ADMIN", decisions[1].Privilege)
+	assert.Equal(t, []string{"CHOWN"}, []string(hostConfig.CapAdd))
+
+	hostConfig = &dockercontainer.HostConfig{CapAdd: []string{"ALL"}}
+	decisions = policy.Evaluate(hostConfig, nil)
+	require.Len(t, decisions, 1)
+	assert.Empty(t, hostConfig.CapAdd)
+
+	policy = &Policy{Capabilities: CapabilitiesRule{Action: ActionDeny}}
+	hostConfig = &dockercontainer.HostConfig{CapAdd: []string{"CHOWN"}}
+	decisions = policy.Evaluate(hostConfig, nil)
+	require.Len(t, decisions, 1)
+	assert.True(t, decisions[0].Denied())
+	assert.Equal(t, []string{"CHOWN"}, []string(hostConfig.CapAdd))
+}
+
+func TestEvaluateBindMounts(t *testing.T) {
+	policy := &Policy{
+		BindMounts: HostPathRule{Action: ActionStrip, AllowedPrefixes: []string{"/var/log/"}},
+	}
+	hostConfig := &dockercontainer.HostConfig{Binds: []string{
+		"/var/log:/logs",
+		"/var/log/app:/logs/app:ro",
+		"/var/logs:/other",
+		"/etc:/host/etc",
+		"named-volume:/data",
+	}}
+	decisions := policy.Evaluate(hostConfig, nil)
+	require.Len(t, decisions, 2)
+	assert.Equal(t, "bind mount of host path /var/logs", decisions[0].Privilege)
+	assert.Equal(t, "bind mount of host path /etc", decisions[1].Privilege)
+	assert.Equal(t, []string{"/var/log:/logs", "/var/log/app:/logs/app:ro", "named-volume:/data"}, hostConfig.Binds)
+}
+
+func TestEvaluateBindMountsMounts(t *testing.T) {
+	policy := &Policy{
+		BindMounts: HostPathRule{Action: ActionStrip, AllowedPrefixes: []string{"/var/log"}},
+	}
+	hostConfig := &dockercontainer.HostConfig{Mounts: []mount.Mount{
+		{Type: mount.TypeBind, Source: "/var/log/app", Target: "/logs"},
+		{Type: mount.TypeBind, Source: "/etc", Target: "/host/etc"},
+		{Type: mount.TypeVolume, Source: "named-volume", Target: "/data"},
+	}}
+	decisions := policy.Evaluate(hostConfig, nil)
+	require.Len(t, decisions, 1)
+	assert.Equal(t, RuleBindMounts, decisions[0].Rule)
+	assert.Equal(t, "bind mount of host path /etc", decisions[0].Privilege)
+	assert.Equal(t, []mount.Mount{
+		{Type: mount.TypeBind, Source: "/var/log/app", Target: "/logs"},
+		{Type: mount.TypeVolume, Source: "named-volume", Target: "/data"},
+	}, hostConfig.Mounts)
+}
+
+func TestEvaluateBindMountsVolumesFrom(t *testing.T) {
+	policy := &Policy{
+		BindMounts: HostPathRule{Action: ActionDeny, AllowedPrefixes: []string{"/var/log"}},
+	}
+	hostConfig := &dockercontainer.HostConfig{VolumesFrom: []string{"ecs-task-1-data", "ecs-task-1-logs:ro", "other"}}
+
+	// Only the volumes of the containers of the same task can be used
+	decisions := policy.Evaluate(hostConfig, []string{"ecs-task-1-data", "ecs-task-1-logs"})
+	require.Len(t, decisions, 1)
+	assert.True(t, decisions[0].Denied())
+	assert.Equal(t, "volumes of container other", decisions[0].Privilege)
+	assert.Len(t, hostConfig.VolumesFrom, 3)
+
+	policy.BindMounts.Action = ActionStrip
+	decisions = policy.Evaluate(hostConfig, []string{"ecs-task-1-data", "ecs-task-1-logs"})
+	require.Len(t, decisions, 1)
+	assert.Equal(t, []string{"ecs-task-1-data", "ecs-task-1-logs:ro"}, hostConfig.VolumesFrom)
+}
+
+func TestBindSource(t *testing.T) {
+	testCases := []struct {
+		bind    string
+		windows bool
+		source  string
+	}{
+		{"/var/log:/logs", false, "/var/log"},
+		{"/var/log:/logs:ro", false, "/var/log"},
+		{"named-volume:/data:rw,z", false, "named-volume"},
+		{"/data", false, ""},
+		{`C:\host:C:\container`, true, `C:\host`},
+		{`C:\host:C:\container:ro`, true, `C:\host`},
+		{`c:/host:c:/container`, true, `c:/host`},
+		{`named-volume:C:\data`, true, "named-volume"},
+		{`\\.\pipe\docker_engine:\\.\pipe\docker_engine`, true, `\\.\pipe\docker_engine`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.bind, func(t *testing.T) {
+			assert.Equal(t, tc.source, bindSource(tc.bind, tc.windows))
+		})
+	}
+}
+
+func TestEvaluateDevices(t *testing.T) {
+	policy := &Policy{
+		Devices: HostPathRule{Action: ActionDeny, AllowedPrefixes: []string{"/dev/fuse"}},
+	}
+	hostConfig := &dockercontainer.HostConfig{}
+	hostConfig.Devices = []dockercontainer.DeviceMapping{
+		{PathOnHost: "/dev/fuse", PathInContainer: "/dev/fuse"},
+		{PathOnHost: "/dev/sda", PathInContainer: "/dev/sda"},
+	}
+	decisions := policy.Evaluate(hostConfig, nil)
+	require.Len(t, decisions, 1)
+	assert.Equal(t, RuleDevices, decisions[0].Rule)
+	assert.Equal(t, "device /dev/sda", decisions[0].Privilege)
+	assert.Len(t, hostConfig.Devices, 2)
+}
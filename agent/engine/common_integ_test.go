@@ -92,35 +92,40 @@ func loggerConfigIntegrationTest(logfile string) string {
 	return config
 }
 
+// nextContainerStateChange reads the next state change of the engine, which
+// must be a container state change
+func nextContainerStateChange(t *testing.T, taskEngine TaskEngine) api.ContainerStateChange {
+	event := <-taskEngine.StateChangeEvents()
+	change, ok := event.(api.ContainerStateChange)
+	require.True(t, ok, "Expected a container state change, got %v", event)
+	return change
+}
+
 func verifyContainerRunningStateChange(t *testing.T, taskEngine TaskEngine) {
-	stateChangeEvents := taskEngine.StateChangeEvents()
-	event := <-stateChangeEvents
-	assert.Equal(t, event.(api.ContainerStateChange).Status, apicontainerstatus.ContainerRunning,
+	change := nextContainerStateChange(t, taskEngine)
+	assert.Equal(t, change.Status, apicontainerstatus.ContainerRunning,
 		"Expected container to be RUNNING")
 }
 
 func verifyContainerRunningStateChangeWithRuntimeID(t *testing.T, taskEngine TaskEngine) {
-	stateChangeEvents := taskEngine.StateChangeEvents()
-	event := <-stateChangeEvents
-	assert.Equal(t, event.(api.ContainerStateChange).Status, apicontainerstatus.ContainerRunning,
+	change := nextContainerStateChange(t, taskEngine)
+	assert.Equal(t, change.Status, apicontainerstatus.ContainerRunning,
 		"Expected container to be RUNNING")
-	assert.NotEqual(t, "", event.(api.ContainerStateChange).RuntimeID,
+	assert.NotEqual(t, "", change.RuntimeID,
 		"Expected container runtimeID should not empty")
 }
 
 func verifyContainerStoppedStateChange(t *testing.T, taskEngine TaskEngine) {
-	stateChangeEvents := taskEngine.StateChangeEvents()
-	event := <-stateChangeEvents
-	assert.Equal(t, event.(api.ContainerStateChange).Status, apicontainerstatus.ContainerStopped,
+	change := nextContainerStateChange(t, taskEngine)
+	assert.Equal(t, change.Status, apicontainerstatus.ContainerStopped,
 		"Expected container to be STOPPED")
 }
 
 func verifyContainerStoppedStateChangeWithRuntimeID(t *testing.T, taskEngine TaskEngine) {
-	stateChangeEvents := taskEngine.StateChangeEvents()
-	event := <-stateChangeEvents
-	assert.Equal(t, event.(api.ContainerStateChange).Status, apicontainerstatus.ContainerStopped,
+	change := nextContainerStateChange(t, taskEngine)
+	assert.Equal(t, change.Status, apicontainerstatus.ContainerStopped,
 		"Expected container to be STOPPED")
-	assert.NotEqual(t, "", event.(api.ContainerStateChange).RuntimeID,
+	assert.NotEqual(t, "", change.RuntimeID,
 		"Expected container runtimeID should not empty")
 }
 
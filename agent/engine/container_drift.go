@@ -0,0 +1,332 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+
+	"github.com/cihub/seelog"
+	"github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+)
+
+const (
+	// containerDriftReason is the reason of the tasks stopped because one
+	// of their containers has drifted
+	containerDriftReason = "ContainerDrift"
+	// networksDriftField is the name of the field holding the networks a
+	// container is connected to
+	networksDriftField = "NetworkSettings.Networks"
+)
+
+// containerSpec holds the fields of a container that are checked for drift,
+// with the values the engine created the container with
+type containerSpec struct {
+	fields map[string]string
+	// reported is the drift last reported for the container, so that the
+	// same drift isn't reported on every check
+	reported string
+}
+
+// driftDetector keeps the specs of the containers created by the engine, by
+// docker ID. Specs are kept in memory only, so containers created before the
+// agent was restarted aren't checked for drift. A nil driftDetector doesn't
+// check any container.
+type driftDetector struct {
+	lock  sync.Mutex
+	specs map[string]*containerSpec
+}
+
+// newDriftDetector returns a driftDetector if drift detection is enabled
+func newDriftDetector(interval time.Duration) *driftDetector {
+	if interval <= 0 {
+		return nil
+	}
+	return &driftDetector{specs: make(map[string]*containerSpec)}
+}
+
+// containerCreated records the configuration a container was created with
+func (detector *driftDetector) containerCreated(dockerID string, config *dockercontainer.Config,
+	hostConfig *dockercontainer.HostConfig) {
+	if detector == nil {
+		return
+	}
+	detector.lock.Lock()
+	defer detector.lock.Unlock()
+	detector.specs[dockerID] = &containerSpec{fields: driftFields(config, hostConfig)}
+}
+
+// containerStarted records the networks a container was connected to when
+// it started
+func (detector *driftDetector) containerStarted(dockerID string, networkSettings *types.NetworkSettings) {
+	if detector == nil || networkSettings == nil {
+		return
+	}
+	detector.lock.Lock()
+	defer detector.lock.Unlock()
+	spec, ok := detector.specs[dockerID]
+	if !ok {
+		return
+	}
+	spec.fields[networksDriftField] = networkNames(networkSettings)
+}
+
+// check returns the fields of the inspected container that differ from the
+// configuration it was created with. Nothing is returned if the drift has
+// already been reported.
+func (detector *driftDetector) check(dockerID string, inspected *types.ContainerJSON) []api.DriftedField {
+	if detector == nil || inspected == nil || inspected.ContainerJSONBase == nil {
+		return nil
+	}
+	detector.lock.Lock()
+	defer detector.lock.Unlock()
+	spec, ok := detector.specs[dockerID]
+	if !ok {
+		return nil
+	}
+
+	actual := driftFields(inspected.Config, inspected.HostConfig)
+	if inspected.NetworkSettings != nil {
+		actual[networksDriftField] = networkNames(inspected.NetworkSettings)
+	}
+	names := make([]string, 0, len(spec.fields))
+	for name := range spec.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var drifted []api.DriftedField
+	var signature []string
+	for _, name := range names {
+		if actual[name] == spec.fields[name] {
+			continue
+		}
+		drifted = append(drifted, api.DriftedField{Name: name, Expected: spec.fields[name], Actual: actual[name]})
+		signature = append(signature, name+"="+actual[name])
+	}
+	reported := strings.Join(signature, "\n")
+	if reported == spec.reported {
+		return nil
+	}
+	spec.reported = reported
+	return drifted
+}
+
+// prune forgets the containers that the engine no longer tracks
+func (detector *driftDetector) prune(tracked map[string]struct{}) {
+	if detector == nil {
+		return
+	}
+	detector.lock.Lock()
+	defer detector.lock.Unlock()
+	for dockerID := range detector.specs {
+		if _, ok := tracked[dockerID]; !ok {
+			delete(detector.specs, dockerID)
+		}
+	}
+}
+
+// driftFields returns the fields of a container configuration that can be
+// changed while the container is running, or that the agent sets explicitly.
+// Values are normalized so that the configuration the agent created the
+// container with and the one reported by docker inspect can be compared.
+func driftFields(config *dockercontainer.Config, hostConfig *dockercontainer.HostConfig) map[string]string {
+	fields := make(map[string]string)
+	if config != nil {
+		fields["Config.Image"] = config.Image
+		if config.User != "" {
+			fields["Config.User"] = config.User
+		}
+	}
+	if hostConfig == nil {
+		return fields
+	}
+
+	fields["HostConfig.Memory"] = strconv.FormatInt(hostConfig.Memory, 10)
+	fields["HostConfig.MemoryReservation"] = strconv.FormatInt(hostConfig.MemoryReservation, 10)
+	fields["HostConfig.CpuShares"] = strconv.FormatInt(hostConfig.CPUShares, 10)
+	fields["HostConfig.CpuQuota"] = strconv.FormatInt(hostConfig.CPUQuota, 10)
+	fields["HostConfig.CpuPeriod"] = strconv.FormatInt(hostConfig.CPUPeriod, 10)
+	fields["HostConfig.CpusetCpus"] = hostConfig.CpusetCpus
+	fields["HostConfig.CpusetMems"] = hostConfig.CpusetMems
+	fields["HostConfig.BlkioWeight"] = strconv.FormatUint(uint64(hostConfig.BlkioWeight), 10)
+	pidsLimit := hostConfig.PidsLimit
+	if pidsLimit < 0 {
+		// Both 0 and -1 mean unlimited
+		pidsLimit = 0
+	}
+	fields["HostConfig.PidsLimit"] = strconv.FormatInt(pidsLimit, 10)
+	fields["HostConfig.Privileged"] = strconv.FormatBool(hostConfig.Privileged)
+	fields["HostConfig.ReadonlyRootfs"] = strconv.FormatBool(hostConfig.ReadonlyRootfs)
+
+	fields["HostConfig.CapAdd"] = capabilities(hostConfig.CapAdd)
+	fields["HostConfig.CapDrop"] = capabilities(hostConfig.CapDrop)
+	binds := append([]string{}, hostConfig.Binds...)
+	sort.Strings(binds)
+	fields["HostConfig.Binds"] = strings.Join(binds, ",")
+	devices := make([]string, 0, len(hostConfig.Devices))
+	for _, device := range hostConfig.Devices {
+		permissions := device.CgroupPermissions
+		if permissions == "" {
+			permissions = "rwm"
+		}
+		devices = append(devices, device.PathOnHost+":"+device.PathInContainer+":"+permissions)
+	}
+	sort.Strings(devices)
+	fields["HostConfig.Devices"] = strings.Join(devices, ",")
+
+	restartPolicy := hostConfig.RestartPolicy.Name
+	if restartPolicy == "" {
+		restartPolicy = "no"
+	}
+	if hostConfig.RestartPolicy.MaximumRetryCount > 0 {
+		restartPolicy += ":" + strconv.Itoa(hostConfig.RestartPolicy.MaximumRetryCount)
+	}
+	fields["HostConfig.RestartPolicy"] = restartPolicy
+
+	// Docker picks the modes that aren't set, so they are only compared if
+	// the agent set them
+	networkMode := string(hostConfig.NetworkMode)
+	if networkMode == "" {
+		networkMode = "default"
+	}
+	fields["HostConfig.NetworkMode"] = networkMode
+	if hostConfig.PidMode != "" {
+		fields["HostConfig.PidMode"] = string(hostConfig.PidMode)
+	}
+	if hostConfig.IpcMode != "" {
+		fields["HostConfig.IpcMode"] = string(hostConfig.IpcMode)
+	}
+	return fields
+}
+
+// capabilities returns the sorted list of capabilities without the "CAP_"
+// prefix, which newer versions of docker add
+func capabilities(capabilities []string) string {
+	normalized := make([]string, 0, len(capabilities))
+	for _, capability := range capabilities {
+		normalized = append(normalized, strings.TrimPrefix(strings.ToUpper(capability), "CAP_"))
+	}
+	sort.Strings(normalized)
+	return strings.Join(normalized, ",")
+}
+
+// networkNames returns the sorted list of the docker networks a container is
+// connected to
+func networkNames(networkSettings *types.NetworkSettings) string {
+	names := make([]string, 0, len(networkSettings.Networks))
+	for name := range networkSettings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// checkContainerDrift periodically checks the running containers for drift
+// until the context is cancelled
+func (engine *DockerTaskEngine) checkContainerDrift(ctx context.Context) {
+	ticker := time.NewTicker(engine.cfg.ContainerDriftCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			engine.checkRunningContainersDrift(ctx)
+		}
+	}
+}
+
+// checkRunningContainersDrift inspects the running containers of the tasks
+// that aren't stopping, and reports those that no longer match the
+// configuration the engine created them with
+func (engine *DockerTaskEngine) checkRunningContainersDrift(ctx context.Context) {
+	tracked := make(map[string]struct{})
+	for _, task := range engine.state.AllTasks() {
+		containerMap, ok := engine.state.ContainerMapByArn(task.Arn)
+		if !ok {
+			continue
+		}
+		for _, dockerContainer := range containerMap {
+			tracked[dockerContainer.DockerID] = struct{}{}
+			container := dockerContainer.Container
+			if task.GetDesiredStatus().Terminal() || container.IsInternal() ||
+				container.GetKnownStatus() != apicontainerstatus.ContainerRunning {
+				continue
+			}
+			inspected, err := engine.client.InspectContainer(ctx, dockerContainer.DockerID,
+				dockerclient.InspectContainerTimeout)
+			if err != nil {
+				seelog.Warnf("Task engine [%s]: unable to inspect container %s for drift: %v",
+					task.Arn, container.Name, err)
+				continue
+			}
+			drifted := engine.driftDetector.check(dockerContainer.DockerID, inspected)
+			if len(drifted) > 0 {
+				engine.reportContainerDrift(ctx, task, container, dockerContainer.DockerID, drifted)
+			}
+		}
+	}
+	engine.driftDetector.prune(tracked)
+}
+
+// reportContainerDrift emits the drift of a container, and stops its task if
+// the engine is configured to do so
+func (engine *DockerTaskEngine) reportContainerDrift(ctx context.Context, task *apitask.Task,
+	container *apicontainer.Container, dockerID string, drifted []api.DriftedField) {
+	drift := api.ContainerDrift{
+		TaskArn:       task.Arn,
+		RuntimeID:     dockerID,
+		ContainerName: container.Name,
+		Fields:        drifted,
+	}
+	seelog.Warnf("Task engine [%s]: container %s has drifted from the configuration it was created with: %s",
+		task.Arn, container.Name, drift.String())
+	select {
+	case <-ctx.Done():
+		return
+	case engine.stateChangeEvents <- drift:
+	}
+
+	if !engine.cfg.ContainerDriftStopTask {
+		return
+	}
+	engine.tasksLock.RLock()
+	managedTask, ok := engine.managedTasks[task.Arn]
+	engine.tasksLock.RUnlock()
+	if !ok {
+		return
+	}
+	names := make([]string, 0, len(drifted))
+	for _, field := range drifted {
+		names = append(names, field.Name)
+	}
+	seelog.Infof("Task engine [%s]: stopping task because container %s has drifted", task.Arn, container.Name)
+	managedTask.SetTerminalReason(fmt.Sprintf("%s: container %s changed outside of the agent: %s",
+		containerDriftReason, container.Name, strings.Join(names, ", ")))
+	managedTask.emitACSTransition(acsTransition{desiredStatus: apitaskstatus.TaskStopped})
+}
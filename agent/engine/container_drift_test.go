@@ -0,0 +1,176 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+
+	"github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const driftTestDockerID = "dockerID"
+
+func driftTestCreatedConfig() (*dockercontainer.Config, *dockercontainer.HostConfig) {
+	config := &dockercontainer.Config{Image: "busybox"}
+	hostConfig := &dockercontainer.HostConfig{
+		Binds:  []string{"/var/log:/logs"},
+		CapAdd: []string{"NET_ADMIN"},
+	}
+	hostConfig.Memory = 512 * 1024 * 1024
+	hostConfig.CPUShares = 256
+	return config, hostConfig
+}
+
+// driftTestInspected returns the inspect output of a container created with
+// driftTestCreatedConfig, including the values docker fills in
+func driftTestInspected() *types.ContainerJSON {
+	config, hostConfig := driftTestCreatedConfig()
+	hostConfig.CapAdd = []string{"CAP_NET_ADMIN"}
+	hostConfig.NetworkMode = "default"
+	hostConfig.IpcMode = "private"
+	hostConfig.RestartPolicy = dockercontainer.RestartPolicy{Name: "no"}
+	hostConfig.MemorySwap = 2 * hostConfig.Memory
+	return &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: driftTestDockerID, HostConfig: hostConfig},
+		Config:            config,
+		NetworkSettings: &types.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{"bridge": {}},
+		},
+	}
+}
+
+func TestDriftDetectorDisabled(t *testing.T) {
+	detector := newDriftDetector(0)
+	require.Nil(t, detector)
+
+	config, hostConfig := driftTestCreatedConfig()
+	detector.containerCreated(driftTestDockerID, config, hostConfig)
+	inspected := driftTestInspected()
+	inspected.HostConfig.Memory = 0
+	assert.Empty(t, detector.check(driftTestDockerID, inspected))
+}
+
+func TestDriftDetectorNoDrift(t *testing.T) {
+	detector := newDriftDetector(time.Minute)
+	config, hostConfig := driftTestCreatedConfig()
+	detector.containerCreated(driftTestDockerID, config, hostConfig)
+	detector.containerStarted(driftTestDockerID, driftTestInspected().NetworkSettings)
+
+	assert.Empty(t, detector.check(driftTestDockerID, driftTestInspected()))
+	// Containers that weren't created by the engine aren't checked
+	assert.Empty(t, detector.check("other", driftTestInspected()))
+}
+
+func TestDriftDetectorDrift(t *testing.T) {
+	detector := newDriftDetector(time.Minute)
+	config, hostConfig := driftTestCreatedConfig()
+	detector.containerCreated(driftTestDockerID, config, hostConfig)
+	detector.containerStarted(driftTestDockerID, driftTestInspected().NetworkSettings)
+
+	// docker update --memory 1g && docker network connect other
+	inspected := driftTestInspected()
+	inspected.HostConfig.Memory = 1024 * 1024 * 1024
+	inspected.NetworkSettings.Networks["other"] = &network.EndpointSettings{}
+	assert.Equal(t, []api.DriftedField{
+		{Name: "HostConfig.Memory", Expected: "536870912", Actual: "1073741824"},
+		{Name: networksDriftField, Expected: "bridge", Actual: "bridge,other"},
+	}, detector.check(driftTestDockerID, inspected))
+
+	// The same drift is reported once
+	assert.Empty(t, detector.check(driftTestDockerID, inspected))
+
+	// A new drift is reported again
+	inspected.HostConfig.RestartPolicy = dockercontainer.RestartPolicy{Name: "always"}
+	drifted := detector.check(driftTestDockerID, inspected)
+	require.Len(t, drifted, 3)
+	assert.Equal(t, "HostConfig.RestartPolicy", drifted[1].Name)
+
+	// Reverting the changes and making them again reports the drift again
+	assert.Empty(t, detector.check(driftTestDockerID, driftTestInspected()))
+	assert.Len(t, detector.check(driftTestDockerID, inspected), 3)
+}
+
+func TestDriftDetectorPrune(t *testing.T) {
+	detector := newDriftDetector(time.Minute)
+	config, hostConfig := driftTestCreatedConfig()
+	detector.containerCreated(driftTestDockerID, config, hostConfig)
+	detector.containerCreated("other", config, hostConfig)
+
+	detector.prune(map[string]struct{}{"other": {}})
+	assert.Len(t, detector.specs, 1)
+	assert.Contains(t, detector.specs, "other")
+}
+
+func TestCheckRunningContainersDrift(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := &config.Config{ContainerDriftCheckInterval: time.Minute, ContainerDriftStopTask: true}
+	ctrl, client, _, privateTaskEngine, _, _, _ := mocks(t, ctx, cfg)
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+
+	container := &apicontainer.Container{Name: "c"}
+	container.SetKnownStatus(apicontainerstatus.ContainerRunning)
+	task := &apitask.Task{
+		Arn:                 "arn",
+		Containers:          []*apicontainer.Container{container},
+		DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+	}
+	taskEngine.state.AddTask(task)
+	taskEngine.state.AddContainer(&apicontainer.DockerContainer{
+		DockerID:  driftTestDockerID,
+		Container: container,
+	}, task)
+	mtask := &managedTask{
+		Task:        task,
+		ctx:         ctx,
+		acsMessages: make(chan acsTransition, 1),
+	}
+	taskEngine.managedTasks[task.Arn] = mtask
+
+	config, hostConfig := driftTestCreatedConfig()
+	taskEngine.driftDetector.containerCreated(driftTestDockerID, config, hostConfig)
+	inspected := driftTestInspected()
+	inspected.HostConfig.CPUShares = 1024
+	client.EXPECT().InspectContainer(gomock.Any(), driftTestDockerID, gomock.Any()).Return(inspected, nil)
+
+	go taskEngine.checkRunningContainersDrift(ctx)
+
+	event := <-taskEngine.StateChangeEvents()
+	drift, ok := event.(api.ContainerDrift)
+	require.True(t, ok)
+	assert.Equal(t, "arn", drift.TaskArn)
+	assert.Equal(t, "c", drift.ContainerName)
+	assert.Equal(t, []api.DriftedField{{Name: "HostConfig.CpuShares", Expected: "256", Actual: "1024"}}, drift.Fields)
+
+	transition := <-mtask.acsMessages
+	assert.Equal(t, apitaskstatus.TaskStopped, transition.desiredStatus)
+	assert.Contains(t, task.GetTerminalReason(), containerDriftReason)
+}
@@ -0,0 +1,125 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// diskSpaceRecheckInterval is how often a task that is held for lack of
+	// free space or inodes checks again
+	diskSpaceRecheckInterval = 15 * time.Second
+
+	// diskSpaceCleanupFactor is the multiple of the free space and inodes
+	// thresholds below which an image cleanup is triggered, so that space is
+	// reclaimed before tasks have to be held
+	diskSpaceCleanupFactor = 2
+
+	// insufficientDiskSpaceReason is the prefix of the stopped reason of tasks
+	// that are stopped for lack of free space or inodes
+	insufficientDiskSpaceReason = "DiskSpaceError"
+
+	bytesPerMiB = 1024 * 1024
+)
+
+var _diskSpaceRecheckInterval = diskSpaceRecheckInterval
+
+// diskAdmissionEnabled returns true if tasks must check for free space or
+// inodes before pulling their images
+func (engine *DockerTaskEngine) diskAdmissionEnabled() bool {
+	return engine.cfg.TaskAdmissionMinFreeDiskMB != 0 || engine.cfg.TaskAdmissionMinFreeInodes != 0
+}
+
+// checkHostDiskSpace returns an error if the filesystem of the docker data
+// root or of the data directory has less free space or inodes than configured
+// to admit tasks. An image cleanup is triggered when either gets close to the
+// thresholds. Paths whose usage can't be read are skipped.
+func (engine *DockerTaskEngine) checkHostDiskSpace() error {
+	minFreeBytes := engine.cfg.TaskAdmissionMinFreeDiskMB * bytesPerMiB
+	minFreeInodes := engine.cfg.TaskAdmissionMinFreeInodes
+	for _, path := range []string{engine.cfg.DockerDataRoot, engine.cfg.DataDir} {
+		if path == "" {
+			continue
+		}
+		usage, err := engine.diskUsage.Usage(path)
+		if err != nil {
+			seelog.Warnf("Task engine: unable to check free disk space, skipping %s: %v", path, err)
+			continue
+		}
+		// Filesystems that don't have a fixed number of inodes report 0
+		checkInodes := usage.TotalInodes != 0
+		if usage.FreeBytes < minFreeBytes*diskSpaceCleanupFactor ||
+			(checkInodes && usage.FreeInodes < minFreeInodes*diskSpaceCleanupFactor) {
+			engine.imageManager.TriggerImageCleanup()
+		}
+		if usage.FreeBytes < minFreeBytes {
+			return errors.Errorf("%s has %d MiB free, below the minimum of %d MiB",
+				path, usage.FreeBytes/bytesPerMiB, engine.cfg.TaskAdmissionMinFreeDiskMB)
+		}
+		if checkInodes && usage.FreeInodes < minFreeInodes {
+			return errors.Errorf("%s has %d free inodes, below the minimum of %d",
+				path, usage.FreeInodes, minFreeInodes)
+		}
+	}
+	return nil
+}
+
+// waitForDiskSpace holds a task that hasn't started yet until the docker data
+// root and the data directory have enough free space and inodes for it to pull
+// its images. The task is stopped if that doesn't happen within
+// TaskAdmissionDiskWaitTimeout.
+func (mtask *managedTask) waitForDiskSpace() {
+	if !mtask.engine.diskAdmissionEnabled() {
+		return
+	}
+	if mtask.GetDesiredStatus().Terminal() || mtask.GetKnownStatus() != apitaskstatus.TaskStatusNone {
+		// The task is either not going to pull its images or has already done
+		// so, such as when its state is restored
+		return
+	}
+
+	deadline := mtask.time().Now().Add(mtask.cfg.TaskAdmissionDiskWaitTimeout)
+	for {
+		err := mtask.engine.checkHostDiskSpace()
+		if err == nil {
+			return
+		}
+		if !mtask.time().Now().Before(deadline) {
+			seelog.Errorf("Managed task [%s]: not enough disk space to start the task, stopping it: %v", mtask.Arn, err)
+			mtask.SetTerminalReason(fmt.Sprintf("%s: %v", insufficientDiskSpaceReason, err))
+			mtask.handleDesiredStatusChange(apitaskstatus.TaskStopped, 0)
+			return
+		}
+		seelog.Warnf("Managed task [%s]: waiting for disk space to start the task: %v", mtask.Arn, err)
+
+		recheckCtx, cancel := context.WithTimeout(mtask.ctx, _diskSpaceRecheckInterval)
+		for !mtask.waitEvent(recheckCtx.Done()) {
+			if mtask.GetDesiredStatus().Terminal() {
+				break
+			}
+		}
+		cancel()
+		if mtask.shouldExit() || mtask.GetDesiredStatus().Terminal() {
+			return
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/utils/diskusage"
+	mock_diskusage "github.com/aws/amazon-ecs-agent/agent/utils/diskusage/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testDockerDataRoot = "/var/lib/docker"
+	testDataDir        = "/data"
+)
+
+func newDiskAdmissionTestEngine(t *testing.T, cfg *config.Config) (*DockerTaskEngine, *mock_diskusage.MockGetter, *mock_engine.MockImageManager, func()) {
+	ctrl := gomock.NewController(t)
+	diskUsage := mock_diskusage.NewMockGetter(ctrl)
+	imageManager := mock_engine.NewMockImageManager(ctrl)
+	cfg.DockerDataRoot = testDockerDataRoot
+	cfg.DataDir = testDataDir
+	engine := &DockerTaskEngine{
+		cfg:          cfg,
+		diskUsage:    diskUsage,
+		imageManager: imageManager,
+	}
+	return engine, diskUsage, imageManager, ctrl.Finish
+}
+
+func TestCheckHostDiskSpace(t *testing.T) {
+	testCases := []struct {
+		name          string
+		usage         *diskusage.Usage
+		expectError   bool
+		expectTrigger bool
+	}{
+		{
+			name:  "plenty of space",
+			usage: &diskusage.Usage{FreeBytes: 10240 * bytesPerMiB, TotalInodes: 1000, FreeInodes: 500},
+		},
+		{
+			name:          "close to the space threshold",
+			usage:         &diskusage.Usage{FreeBytes: 1500 * bytesPerMiB, TotalInodes: 1000, FreeInodes: 500},
+			expectTrigger: true,
+		},
+		{
+			name:          "below the space threshold",
+			usage:         &diskusage.Usage{FreeBytes: 500 * bytesPerMiB, TotalInodes: 1000, FreeInodes: 500},
+			expectError:   true,
+			expectTrigger: true,
+		},
+		{
+			name:          "below the inodes threshold",
+			usage:         &diskusage.Usage{FreeBytes: 10240 * bytesPerMiB, TotalInodes: 1000, FreeInodes: 50},
+			expectError:   true,
+			expectTrigger: true,
+		},
+		{
+			name:  "inodes not reported",
+			usage: &diskusage.Usage{FreeBytes: 10240 * bytesPerMiB},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine, diskUsage, imageManager, done := newDiskAdmissionTestEngine(t, &config.Config{
+				TaskAdmissionMinFreeDiskMB: 1024,
+				TaskAdmissionMinFreeInodes: 100,
+			})
+			defer done()
+
+			diskUsage.EXPECT().Usage(testDockerDataRoot).Return(tc.usage, nil)
+			if !tc.expectError {
+				diskUsage.EXPECT().Usage(testDataDir).Return(tc.usage, nil)
+			}
+			if tc.expectTrigger {
+				imageManager.EXPECT().TriggerImageCleanup().MinTimes(1)
+			}
+			err := engine.checkHostDiskSpace()
+			assert.Equal(t, tc.expectError, err != nil)
+		})
+	}
+}
+
+func TestCheckHostDiskSpaceSkipsUnreadablePaths(t *testing.T) {
+	engine, diskUsage, _, done := newDiskAdmissionTestEngine(t, &config.Config{TaskAdmissionMinFreeDiskMB: 1024})
+	defer done()
+
+	diskUsage.EXPECT().Usage(testDockerDataRoot).Return(nil, errors.New("no such file or directory"))
+	diskUsage.EXPECT().Usage(testDataDir).Return(&diskusage.Usage{FreeBytes: 10240 * bytesPerMiB}, nil)
+	assert.NoError(t, engine.checkHostDiskSpace())
+}
+
+func TestWaitForDiskSpaceStopsTask(t *testing.T) {
+	engine, diskUsage, imageManager, done := newDiskAdmissionTestEngine(t, &config.Config{TaskAdmissionMinFreeDiskMB: 1024})
+	defer done()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mtask := &managedTask{
+		ctx:    ctx,
+		engine: engine,
+		cfg:    engine.cfg,
+		Task: &apitask.Task{
+			Arn:                 "task",
+			DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+		},
+	}
+
+	diskUsage.EXPECT().Usage(testDockerDataRoot).Return(&diskusage.Usage{FreeBytes: 100 * bytesPerMiB}, nil)
+	imageManager.EXPECT().TriggerImageCleanup()
+	mtask.waitForDiskSpace()
+
+	assert.Equal(t, apitaskstatus.TaskStopped, mtask.GetDesiredStatus())
+	assert.Contains(t, mtask.GetTerminalReason(), insufficientDiskSpaceReason)
+}
+
+func TestWaitForDiskSpaceHoldsTask(t *testing.T) {
+	defer func() {
+		_diskSpaceRecheckInterval = diskSpaceRecheckInterval
+	}()
+	_diskSpaceRecheckInterval = time.Millisecond
+
+	engine, diskUsage, imageManager, done := newDiskAdmissionTestEngine(t, &config.Config{
+		TaskAdmissionMinFreeDiskMB:   1024,
+		TaskAdmissionDiskWaitTimeout: time.Minute,
+	})
+	defer done()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mtask := &managedTask{
+		ctx:    ctx,
+		engine: engine,
+		cfg:    engine.cfg,
+		Task: &apitask.Task{
+			Arn:                 "task",
+			DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+		},
+	}
+
+	gomock.InOrder(
+		diskUsage.EXPECT().Usage(testDockerDataRoot).Return(&diskusage.Usage{FreeBytes: 100 * bytesPerMiB}, nil),
+		diskUsage.EXPECT().Usage(testDockerDataRoot).Return(&diskusage.Usage{FreeBytes: 10240 * bytesPerMiB}, nil),
+		diskUsage.EXPECT().Usage(testDataDir).Return(&diskusage.Usage{FreeBytes: 10240 * bytesPerMiB}, nil),
+	)
+	imageManager.EXPECT().TriggerImageCleanup()
+	mtask.waitForDiskSpace()
+
+	assert.Equal(t, apitaskstatus.TaskRunning, mtask.GetDesiredStatus())
+	assert.Empty(t, mtask.GetTerminalReason())
+}
+
+func TestWaitForDiskSpaceDisabled(t *testing.T) {
+	engine, _, _, done := newDiskAdmissionTestEngine(t, &config.Config{})
+	defer done()
+	mtask := &managedTask{
+		ctx:    context.Background(),
+		engine: engine,
+		cfg:    engine.cfg,
+		Task: &apitask.Task{
+			Arn:                 "task",
+			DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+		},
+	}
+
+	mtask.waitForDiskSpace()
+	assert.Equal(t, apitaskstatus.TaskRunning, mtask.GetDesiredStatus())
+}
@@ -21,6 +21,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
 
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
@@ -30,6 +31,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/engine/image"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/utils/diskusage"
 	"github.com/cihub/seelog"
 )
 
@@ -45,6 +47,9 @@ type ImageManager interface {
 	AddAllImageStates(imageStates []*image.ImageState)
 	GetImageStateFromImageName(containerImageName string) (*image.ImageState, bool)
 	StartImageCleanupProcess(ctx context.Context)
+	TriggerImageCleanup()
+	GetPinnedImages() []image.PinnedImage
+	UpdatePinnedImage(pinnedImage image.PinnedImage)
 	SetSaver(stateManager statemanager.Saver)
 }
 
@@ -55,6 +60,7 @@ type dockerImageManager struct {
 	client                             dockerapi.DockerClient
 	updateLock                         sync.RWMutex
 	imageCleanupTicker                 *time.Ticker
+	imageCleanupRequests               chan struct{}
 	state                              dockerstate.TaskEngineState
 	saver                              statemanager.Saver
 	imageStatesConsideredForDeletion   map[string]*image.ImageState
@@ -67,6 +73,13 @@ type dockerImageManager struct {
 	nonECSContainerCleanupWaitDuration time.Duration
 	numNonECSContainersToDelete        int
 	nonECSMinimumAgeBeforeDeletion     time.Duration
+	diskUsage                          diskusage.Getter
+	dockerDataRoot                     string
+	imageCleanupHighWatermark          uint16
+	imageCleanupLowWatermark           uint16
+	diskPressureCheckInterval          time.Duration
+	pinnedImages                       []image.PinnedImage
+	pinnedImagesLock                   sync.RWMutex
 }
 
 // ImageStatesForDeletion is used for implementing the sort interface
@@ -74,9 +87,14 @@ type ImageStatesForDeletion []*image.ImageState
 
 // NewImageManager returns a new ImageManager
 func NewImageManager(cfg *config.Config, client dockerapi.DockerClient, state dockerstate.TaskEngineState) ImageManager {
+	var pinnedImages []image.PinnedImage
+	for _, name := range cfg.PinnedImages {
+		pinnedImages = append(pinnedImages, image.PinnedImage{Name: name, Status: image.PinnedImagePending})
+	}
 	return &dockerImageManager{
 		client:                             client,
 		state:                              state,
+		imageCleanupRequests:               make(chan struct{}, 1),
 		minimumAgeBeforeDeletion:           cfg.MinimumImageDeletionAge,
 		numImagesToDelete:                  cfg.NumImagesToDeletePerCycle,
 		imageCleanupTimeInterval:           cfg.ImageCleanupInterval,
@@ -86,6 +104,12 @@ func NewImageManager(cfg *config.Config, client dockerapi.DockerClient, state do
 		nonECSContainerCleanupWaitDuration: cfg.TaskCleanupWaitDuration,
 		numNonECSContainersToDelete:        cfg.NumNonECSContainersToDeletePerCycle,
 		nonECSMinimumAgeBeforeDeletion:     cfg.NonECSMinimumImageDeletionAge,
+		diskUsage:                          diskusage.NewGetter(),
+		dockerDataRoot:                     cfg.DockerDataRoot,
+		imageCleanupHighWatermark:          cfg.ImageCleanupHighWatermark,
+		imageCleanupLowWatermark:           cfg.ImageCleanupLowWatermark,
+		diskPressureCheckInterval:          diskPressureCheckInterval,
+		pinnedImages:                       pinnedImages,
 	}
 }
 
@@ -327,10 +351,26 @@ func (imageManager *dockerImageManager) StartImageCleanupProcess(ctx context.Con
 
 func (imageManager *dockerImageManager) performPeriodicImageCleanup(ctx context.Context, imageCleanupInterval time.Duration) {
 	imageManager.imageCleanupTicker = time.NewTicker(imageCleanupInterval)
+	// diskPressureChecks is left nil when the disk pressure cleanup is
+	// disabled, so that it never gets selected
+	var diskPressureChecks <-chan time.Time
+	if imageManager.diskPressureCleanupEnabled() {
+		diskPressureTicker := time.NewTicker(imageManager.diskPressureCheckInterval)
+		defer diskPressureTicker.Stop()
+		diskPressureChecks = diskPressureTicker.C
+	}
 	for {
 		select {
 		case <-imageManager.imageCleanupTicker.C:
 			go imageManager.removeUnusedImages(ctx)
+		case <-diskPressureChecks:
+			go imageManager.removeImagesUnderDiskPressure(ctx)
+		case <-imageManager.imageCleanupRequests:
+			seelog.Info("Performing image cleanup ahead of the cleanup interval")
+			go imageManager.removeUnusedImages(ctx)
+			if imageManager.diskPressureCleanupEnabled() {
+				go imageManager.removeImagesUnderDiskPressure(ctx)
+			}
 		case <-ctx.Done():
 			imageManager.imageCleanupTicker.Stop()
 			return
@@ -338,6 +378,16 @@ func (imageManager *dockerImageManager) performPeriodicImageCleanup(ctx context.
 	}
 }
 
+// TriggerImageCleanup requests an image cleanup cycle to be performed without
+// waiting for the cleanup interval. Requests made while one is already pending
+// are merged into it.
+func (imageManager *dockerImageManager) TriggerImageCleanup() {
+	select {
+	case imageManager.imageCleanupRequests <- struct{}{}:
+	default:
+	}
+}
+
 func (imageManager *dockerImageManager) removeUnusedImages(ctx context.Context) {
 	seelog.Debug("Attempting to obtain ImagePullDeleteLock for removing images")
 	ImagePullDeleteLock.Lock()
@@ -426,6 +476,7 @@ func (imageManager *dockerImageManager) getNonECSContainerIDs(ctx context.Contex
 
 type ImageWithSizeID struct {
 	RepoTags    []string
+	RepoDigests []string
 	ImageID     string
 	Size        int64
 	createdTime time.Time
@@ -452,28 +503,36 @@ func (imageManager *dockerImageManager) removeNonECSImages(ctx context.Context,
 		if !imageManager.nonECSImageOldEnough(image) {
 			continue
 		}
-		if len(image.RepoTags) > 1 {
-			seelog.Debugf("Non-ECS image has more than one tag Image: %s (Tags: %s)", image.ImageID, image.RepoTags)
-			for _, tag := range image.RepoTags {
-				err := imageManager.client.RemoveImage(ctx, tag, dockerclient.RemoveImageTimeout)
-				if err != nil {
-					seelog.Errorf("Error removing RepoTag (ImageID: %s, Tag: %s) %v", image.ImageID, tag, err)
-				} else {
-					seelog.Infof("Image Tag Removed: %s (ImageID: %s)", tag, image.ImageID)
-					numImagesAlreadyDeleted++
-				}
-			}
-		} else {
-			seelog.Debugf("Removing non-ECS Image: %s (Tags: %s)", image.ImageID, image.RepoTags)
-			err := imageManager.client.RemoveImage(ctx, image.ImageID, dockerclient.RemoveImageTimeout)
+		numImagesAlreadyDeleted += imageManager.removeNonECSImage(ctx, image)
+	}
+}
+
+// removeNonECSImage removes the given non-ECS image, one tag at a time if it
+// has more than one, and returns the number of successful removals
+func (imageManager *dockerImageManager) removeNonECSImage(ctx context.Context, image ImageWithSizeID) int {
+	numRemoved := 0
+	if len(image.RepoTags) > 1 {
+		seelog.Debugf("Non-ECS image has more than one tag Image: %s (Tags: %s)", image.ImageID, image.RepoTags)
+		for _, tag := range image.RepoTags {
+			err := imageManager.client.RemoveImage(ctx, tag, dockerclient.RemoveImageTimeout)
 			if err != nil {
-				seelog.Errorf("Error removing Image %s (Tags: %s) - %v", image.ImageID, image.RepoTags, err)
+				seelog.Errorf("Error removing RepoTag (ImageID: %s, Tag: %s) %v", image.ImageID, tag, err)
 			} else {
-				seelog.Infof("Image removed: %s (Tags: %s)", image.ImageID, image.RepoTags)
-				numImagesAlreadyDeleted++
+				seelog.Infof("Image Tag Removed: %s (ImageID: %s)", tag, image.ImageID)
+				numRemoved++
 			}
 		}
+	} else {
+		seelog.Debugf("Removing non-ECS Image: %s (Tags: %s)", image.ImageID, image.RepoTags)
+		err := imageManager.client.RemoveImage(ctx, image.ImageID, dockerclient.RemoveImageTimeout)
+		if err != nil {
+			seelog.Errorf("Error removing Image %s (Tags: %s) - %v", image.ImageID, image.RepoTags, err)
+		} else {
+			seelog.Infof("Image removed: %s (Tags: %s)", image.ImageID, image.RepoTags)
+			numRemoved++
+		}
 	}
+	return numRemoved
 }
 
 // getNonECSImages returns type ImageWithSizeID with all fields populated.
@@ -497,6 +556,7 @@ func (imageManager *dockerImageManager) getNonECSImages(ctx context.Context) []I
 				ImageID:     imageID,
 				Size:        resp.Size,
 				RepoTags:    resp.RepoTags,
+				RepoDigests: resp.RepoDigests,
 				createdTime: createTime,
 			})
 	}
@@ -514,6 +574,11 @@ func (imageManager *dockerImageManager) getNonECSImages(ctx context.Context) []I
 		if isInExclusionList(image.ImageID, ecsImageIDs) {
 			continue
 		}
+		// check image is not pinned
+		imageNames := append(append([]string{}, image.RepoTags...), image.RepoDigests...)
+		if imageManager.isPinnedImage(image.ImageID, imageNames) {
+			continue
+		}
 		// check image TAG(s) is not excluded
 		if !anyIsInExclusionList(image.RepoTags, imageManager.imageCleanupExclusionList) {
 			nonECSImages = append(nonECSImages, image)
@@ -581,9 +646,65 @@ func (imageManager *dockerImageManager) isExcludedFromCleanup(imageState *image.
 			}
 		}
 	}
+	return imageManager.isPinnedImage(imageState.Image.ImageID, imageState.Image.Names)
+}
+
+// GetPinnedImages returns the images pinned on the instance and the status of
+// their pull
+func (imageManager *dockerImageManager) GetPinnedImages() []image.PinnedImage {
+	imageManager.pinnedImagesLock.RLock()
+	defer imageManager.pinnedImagesLock.RUnlock()
+
+	pinnedImages := make([]image.PinnedImage, len(imageManager.pinnedImages))
+	copy(pinnedImages, imageManager.pinnedImages)
+	return pinnedImages
+}
+
+// UpdatePinnedImage records the status of the pull of a pinned image
+func (imageManager *dockerImageManager) UpdatePinnedImage(pinnedImage image.PinnedImage) {
+	imageManager.pinnedImagesLock.Lock()
+	defer imageManager.pinnedImagesLock.Unlock()
+
+	for i := range imageManager.pinnedImages {
+		if imageManager.pinnedImages[i].Name == pinnedImage.Name {
+			imageManager.pinnedImages[i] = pinnedImage
+			return
+		}
+	}
+	seelog.Warnf("Image Manager: image %s is not pinned, ignoring its status", pinnedImage.Name)
+}
+
+// isPinnedImage returns true if the image with the given ID and names is
+// pinned. Images are matched by name as well, so that they are kept before
+// the Agent is done pulling them.
+func (imageManager *dockerImageManager) isPinnedImage(imageID string, imageNames []string) bool {
+	imageManager.pinnedImagesLock.RLock()
+	defer imageManager.pinnedImagesLock.RUnlock()
+
+	for _, pinnedImage := range imageManager.pinnedImages {
+		if pinnedImage.ImageID != "" && pinnedImage.ImageID == imageID {
+			return true
+		}
+		for _, imageName := range imageNames {
+			if normalizeImageName(pinnedImage.Name) == normalizeImageName(imageName) {
+				return true
+			}
+		}
+	}
 	return false
 }
 
+// normalizeImageName returns the name of an image the way docker reports it
+// in the tags and digests of images, e.g. "busybox:latest" for "busybox" and
+// "docker.io/library/busybox"
+func normalizeImageName(imageName string) string {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return imageName
+	}
+	return reference.FamiliarString(reference.TagNameOnly(named))
+}
+
 func (imageManager *dockerImageManager) removeLeastRecentlyUsedImage(ctx context.Context) error {
 	leastRecentlyUsedImage := imageManager.getUnusedImageForDeletion()
 	if leastRecentlyUsedImage == nil {
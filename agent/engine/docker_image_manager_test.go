@@ -1950,3 +1950,14 @@ func TestImageCleanupProcessNotStart(t *testing.T) {
 	imageManager.StartImageCleanupProcess(ctx)
 	// Nothing should happen.
 }
+
+func TestTriggerImageCleanupMergesPendingRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	imageManager := NewImageManager(defaultTestConfig(), client, dockerstate.NewTaskEngineState()).(*dockerImageManager)
+	imageManager.TriggerImageCleanup()
+	imageManager.TriggerImageCleanup()
+	assert.Len(t, imageManager.imageCleanupRequests, 1, "pending cleanup requests should be merged")
+}
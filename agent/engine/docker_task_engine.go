@@ -36,16 +36,22 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
 	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	"github.com/aws/amazon-ecs-agent/agent/ecscni"
+	"github.com/aws/amazon-ecs-agent/agent/engine/admission"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dependencygraph"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	"github.com/aws/amazon-ecs-agent/agent/engine/imagepolicy"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
+	"github.com/aws/amazon-ecs-agent/agent/logger/audit"
 	"github.com/aws/amazon-ecs-agent/agent/metrics"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/credentialspec"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/firelens"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/aws/amazon-ecs-agent/agent/utils/diskusage"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	utilsync "github.com/aws/amazon-ecs-agent/agent/utils/sync"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
@@ -157,6 +163,29 @@ type DockerTaskEngine struct {
 
 	resourceFields *taskresource.ResourceFields
 
+	// diskUsage reports the free space and inodes of the docker data root and
+	// the data directory, which are checked before tasks pull their images
+	diskUsage diskusage.Getter
+	// pullScheduler schedules the image pulls of task containers
+	pullScheduler *pullScheduler
+	// imagePolicy is the policy that the images of containers are checked
+	// against before the containers are created
+	imagePolicy *imagepolicy.Policy
+	// admissionPolicy is the task admission policy that the host configs of
+	// containers are checked against before the containers are created.
+	// admissionPolicyErr is set if the configured policy couldn't be loaded.
+	admissionPolicy    *admission.Policy
+	admissionPolicyErr error
+	// auditLogger records the decisions of the task admission policy
+	auditLogger audit.AuditLogger
+	// healthListener is notified of the changes of the health status of
+	// containers
+	healthListener     statechange.Listener
+	healthListenerLock sync.RWMutex
+	// driftDetector keeps the configurations that containers were created
+	// with, which running containers are checked against for drift
+	driftDetector *driftDetector
+
 	// handleDelay is a function used to delay cleanup. Implementation is
 	// swappable for testing
 	handleDelay func(duration time.Duration)
@@ -174,6 +203,7 @@ func NewDockerTaskEngine(cfg *config.Config,
 	state dockerstate.TaskEngineState,
 	metadataManager containermetadata.Manager,
 	resourceFields *taskresource.ResourceFields) *DockerTaskEngine {
+	admissionPolicy, admissionPolicyErr := loadAdmissionPolicy(cfg)
 	dockerTaskEngine := &DockerTaskEngine{
 		cfg:    cfg,
 		client: client,
@@ -195,6 +225,12 @@ func NewDockerTaskEngine(cfg *config.Config,
 		taskSteadyStatePollInterval:       defaultTaskSteadyStatePollInterval,
 		taskSteadyStatePollIntervalJitter: defaultTaskSteadyStatePollIntervalJitter,
 		resourceFields:                    resourceFields,
+		diskUsage:                         diskusage.NewGetter(),
+		pullScheduler:                     newPullScheduler(int(cfg.ImagePullMaxConcurrencyPerRegistry), cfg.ImagePullMirrors),
+		imagePolicy:                       imagepolicy.NewPolicy(cfg),
+		admissionPolicy:                   admissionPolicy,
+		admissionPolicyErr:                admissionPolicyErr,
+		driftDetector:                     newDriftDetector(cfg.ContainerDriftCheckInterval),
 		handleDelay:                       time.Sleep,
 	}
 
@@ -229,6 +265,23 @@ func (engine *DockerTaskEngine) MarshalJSON() ([]byte, error) {
 	return engine.state.MarshalJSON()
 }
 
+// Records returns the task-engine state as individual records, so that it can
+// be saved by a record oriented state manager
+func (engine *DockerTaskEngine) Records() (map[string]map[string][]byte, error) {
+	return engine.state.Records()
+}
+
+// ChangedRecords returns the task-engine records that changed since they were
+// last returned, so that only those are marshaled when the state is saved
+func (engine *DockerTaskEngine) ChangedRecords() (map[string]map[string][]byte, map[string]map[string][]byte, error) {
+	return engine.state.ChangedRecords()
+}
+
+// LoadRecords restores a task-engine state previously returned by Records
+func (engine *DockerTaskEngine) LoadRecords(records map[string]map[string][]byte) error {
+	return engine.state.LoadRecords(records)
+}
+
 // Init initializes a DockerTaskEngine such that it may communicate with docker
 // and operate normally.
 // This function must be called before any other function, except serializing and deserializing, can succeed without error.
@@ -247,6 +300,17 @@ func (engine *DockerTaskEngine) Init(ctx context.Context) error {
 	engine.synchronizeState()
 	// Now catch up and start processing new events per normal
 	go engine.handleDockerEvents(derivedCtx)
+	// Pinned images are pulled in the background, so that tasks can be
+	// started in the meantime
+	if len(engine.cfg.PinnedImages) > 0 {
+		go engine.pullPinnedImages(derivedCtx)
+	}
+	if engine.driftDetector != nil {
+		go engine.checkContainerDrift(derivedCtx)
+	}
+	if engine.cfg.SecretRotationInterval > 0 {
+		go engine.rotateSecrets(derivedCtx)
+	}
 	engine.initialized = true
 	return nil
 }
@@ -281,6 +345,20 @@ func (engine *DockerTaskEngine) SetSaver(saver statemanager.Saver) {
 	engine.saver = saver
 }
 
+// SetAuditLogger sets the audit logger that the decisions of the task
+// admission policy are recorded with
+func (engine *DockerTaskEngine) SetAuditLogger(auditLogger audit.AuditLogger) {
+	engine.auditLogger = auditLogger
+}
+
+// SetContainerHealthListener sets the listener that is notified of the
+// changes of the health status of containers
+func (engine *DockerTaskEngine) SetContainerHealthListener(listener statechange.Listener) {
+	engine.healthListenerLock.Lock()
+	defer engine.healthListenerLock.Unlock()
+	engine.healthListener = listener
+}
+
 // Shutdown makes a best-effort attempt to cleanup after the task engine.
 // This should not be relied on for anything more complicated than testing.
 func (engine *DockerTaskEngine) Shutdown() {
@@ -338,6 +416,7 @@ func (engine *DockerTaskEngine) synchronizeState() {
 	tasksToStart := engine.filterTasksToStartUnsafe(tasks)
 	for _, task := range tasks {
 		task.InitializeResources(engine.resourceFields)
+		engine.state.MarkTaskChanged(task.Arn)
 	}
 
 	for _, task := range tasksToStart {
@@ -400,6 +479,10 @@ func updateContainerMetadata(metadata *dockerapi.DockerContainerMetadata, contai
 		container.SetKnownExitCode(metadata.ExitCode)
 	}
 
+	// The OOM status is taken from the inspect of the container, which docker
+	// resets when the container starts again
+	container.SetOOMKilled(metadata.OOMKilled)
+
 	// Set port mappings
 	if len(metadata.PortBindings) != 0 && len(container.GetKnownPortBindings()) == 0 {
 		container.SetKnownPortBindings(metadata.PortBindings)
@@ -526,6 +609,7 @@ func (engine *DockerTaskEngine) sweepTask(task *apitask.Task) {
 			seelog.Warnf("Task engine [%s]: clean task metadata failed: %v", task.Arn, err)
 		}
 	}
+	engine.state.MarkTaskChanged(task.Arn)
 	engine.saver.Save()
 }
 
@@ -650,7 +734,27 @@ func (engine *DockerTaskEngine) handleDockerEvent(event dockerapi.DockerContaine
 		if cont.Container.HealthStatusShouldBeReported() {
 			seelog.Debugf("Task engine: updating container [%s(%s)] health status: %v",
 				cont.Container.Name, cont.DockerID, event.DockerContainerMetadata.Health)
+			previousStatus := cont.Container.GetHealthStatus().Status
 			cont.Container.SetHealthStatus(event.DockerContainerMetadata.Health)
+			if health := cont.Container.GetHealthStatus(); health.Status != previousStatus {
+				engine.emitContainerHealthChange(task, cont, health)
+			}
+		}
+		return
+	}
+
+	// OOM kills don't affect the container status either. They are passed on
+	// to the stats engine. The process killed isn't necessarily the main one
+	// of the container, whether the container was killed is taken from its
+	// inspect once it dies.
+	if event.Type == apicontainer.ContainerOOMEvent {
+		seelog.Warnf("Task engine [%s]: a process of container [%s(%s)] was killed for running out of memory",
+			task.Arn, cont.Container.Name, cont.DockerID)
+		if engine.containerChangeEventStream != nil {
+			if err := engine.containerChangeEventStream.WriteToEventStream(event); err != nil {
+				seelog.Warnf("Task engine [%s]: failed to write OOM kill event of container [%s] to the event stream: %v",
+					task.Arn, cont.Container.Name, err)
+			}
 		}
 		return
 	}
@@ -670,6 +774,28 @@ func (engine *DockerTaskEngine) handleDockerEvent(event dockerapi.DockerContaine
 		task.Arn, event.String())
 }
 
+// emitContainerHealthChange emits the change of the health status of a
+// container to the health listener. Health changes don't go through the state
+// change events, so that docker events are never held up by their consumers.
+func (engine *DockerTaskEngine) emitContainerHealthChange(task *apitask.Task, cont *apicontainer.DockerContainer,
+	health apicontainer.HealthStatus) {
+	engine.healthListenerLock.RLock()
+	listener := engine.healthListener
+	engine.healthListenerLock.RUnlock()
+	if listener == nil {
+		return
+	}
+	change := api.ContainerHealthChange{
+		TaskArn:       task.Arn,
+		RuntimeID:     cont.DockerID,
+		ContainerName: cont.Container.Name,
+		Health:        health,
+		Container:     cont.Container,
+	}
+	seelog.Infof("Task engine [%s]: container health changed: %s", task.Arn, change.String())
+	listener.Dispatch(change)
+}
+
 // StateChangeEvents returns channels to read task and container state changes. These
 // changes should be read as soon as possible as them not being read will block
 // processing the task referenced by the event.
@@ -855,7 +981,13 @@ func (engine *DockerTaskEngine) pullAndUpdateContainerReference(task *apitask.Ta
 		defer container.SetASMDockerAuthConfig(types.AuthConfig{})
 	}
 
-	metadata := engine.client.PullImage(engine.ctx, container.Image, container.RegistryAuthentication, dockerclient.PullImageTimeout)
+	metadata := engine.pullScheduler.pull(engine.ctx, pullRequest{
+		image:     container.Image,
+		authKey:   registryAuthKey(container.RegistryAuthentication),
+		essential: container.Essential,
+	}, func() dockerapi.DockerContainerMetadata {
+		return engine.client.PullImage(engine.ctx, container.Image, container.RegistryAuthentication, dockerclient.PullImageTimeout)
+	})
 
 	// Don't add internal images(created by ecs-agent) into imagemanger state
 	if container.IsInternal() {
@@ -882,6 +1014,11 @@ func (engine *DockerTaskEngine) updateContainerReference(pullSucceeded bool, con
 
 func (engine *DockerTaskEngine) createContainer(task *apitask.Task, container *apicontainer.Container) dockerapi.DockerContainerMetadata {
 	seelog.Infof("Task engine [%s]: creating container: %s", task.Arn, container.Name)
+	verifiedImageID, policyErr := engine.checkImagePolicy(task, container)
+	if policyErr != nil {
+		return dockerapi.DockerContainerMetadata{Error: policyErr}
+	}
+
 	client := engine.client
 	if container.DockerConfig.Version != nil {
 		client = client.WithVersion(dockerclient.DockerVersion(*container.DockerConfig.Version))
@@ -914,6 +1051,10 @@ func (engine *DockerTaskEngine) createContainer(task *apitask.Task, container *a
 		return dockerapi.DockerContainerMetadata{Error: apierrors.NamedError(hcerr)}
 	}
 
+	if err := engine.admitContainer(task, container, hostConfig, containerMap); err != nil {
+		return dockerapi.DockerContainerMetadata{Error: err}
+	}
+
 	if container.AWSLogAuthExecutionRole() {
 		err := task.ApplyExecutionRoleLogsAuth(hostConfig, engine.credentialsManager)
 		if err != nil {
@@ -979,6 +1120,17 @@ func (engine *DockerTaskEngine) createContainer(task *apitask.Task, container *a
 		}
 	}
 
+	// Bind mount the files of the secrets vended as files
+	hasSecretAsFile := func(s apicontainer.Secret) bool {
+		return s.Type == apicontainer.SecretTypeMountPoint
+	}
+	if container.HasSecret(hasSecretAsFile) {
+		err := task.AddSecretFileBindMounts(container, hostConfig, engine.cfg)
+		if err != nil {
+			return dockerapi.DockerContainerMetadata{Error: apierrors.NamedError(err)}
+		}
+	}
+
 	// Populate credentialspec resource
 	if container.RequiresCredentialSpec() {
 		seelog.Debugf("Obtained container %s with credentialspec resource requirement for task %s.", container.Name, task.Arn)
@@ -1029,6 +1181,9 @@ func (engine *DockerTaskEngine) createContainer(task *apitask.Task, container *a
 	if err != nil {
 		return dockerapi.DockerContainerMetadata{Error: apierrors.NamedError(err)}
 	}
+	if verifiedImageID != "" {
+		config.Image = verifiedImageID
+	}
 
 	// Augment labels with some metadata from the agent. Explicitly do this last
 	// such that it will always override duplicates in the provided raw config
@@ -1083,6 +1238,9 @@ func (engine *DockerTaskEngine) createContainer(task *apitask.Task, container *a
 		engine.state.AddContainer(&apicontainer.DockerContainer{DockerID: metadata.DockerID,
 			DockerName: dockerContainerName,
 			Container:  container}, task)
+		if !container.IsInternal() {
+			engine.driftDetector.containerCreated(metadata.DockerID, config, hostConfig)
+		}
 	}
 	container.SetLabels(config.Labels)
 	seelog.Infof("Task engine [%s]: created docker container for task: %s -> %s, took %s",
@@ -1188,6 +1346,9 @@ func (engine *DockerTaskEngine) startContainer(task *apitask.Task, container *ap
 
 		}
 	}
+	if dockerContainerMD.Error == nil {
+		engine.driftDetector.containerStarted(dockerContainer.DockerID, dockerContainerMD.NetworkSettings)
+	}
 	return dockerContainerMD
 }
 
@@ -1389,7 +1550,11 @@ func (engine *DockerTaskEngine) updateTaskUnsafe(task *apitask.Task, update *api
 func (engine *DockerTaskEngine) transitionContainer(task *apitask.Task, container *apicontainer.Container, to apicontainerstatus.ContainerStatus) {
 	// Let docker events operate async so that we can continue to handle ACS / other requests
 	// This is safe because 'applyContainerState' will not mutate the task
+	span := tracing.TracerGlobal.StartSpan(task.Arn, containerTransitionSpanName(to),
+		tracing.String(tracing.ContainerNameAttribute, container.Name),
+		tracing.String(tracing.StatusAttribute, to.String()))
 	metadata := engine.applyContainerState(task, container, to)
+	span.End(metadata.Error)
 
 	engine.tasksLock.RLock()
 	managedTask, ok := engine.managedTasks[task.Arn]
@@ -1405,6 +1570,25 @@ func (engine *DockerTaskEngine) transitionContainer(task *apitask.Task, containe
 	}
 }
 
+// containerTransitionSpanName returns the name of the span of the transition
+// of a container to the given state
+func containerTransitionSpanName(to apicontainerstatus.ContainerStatus) string {
+	switch to {
+	case apicontainerstatus.ContainerPulled:
+		return "container.pull"
+	case apicontainerstatus.ContainerCreated:
+		return "container.create"
+	case apicontainerstatus.ContainerRunning:
+		return "container.start"
+	case apicontainerstatus.ContainerResourcesProvisioned:
+		return "container.provision_resources"
+	case apicontainerstatus.ContainerStopped:
+		return "container.stop"
+	default:
+		return "container.transition"
+	}
+}
+
 // applyContainerState moves the container to the given state by calling the
 // function defined in the transitionFunctionMap for the state
 func (engine *DockerTaskEngine) applyContainerState(task *apitask.Task, container *apicontainer.Container, nextState apicontainerstatus.ContainerStatus) dockerapi.DockerContainerMetadata {
@@ -1421,6 +1605,7 @@ func (engine *DockerTaskEngine) applyContainerState(task *apitask.Task, containe
 	} else {
 		seelog.Debugf("Task engine [%s]: transitioned container [%s (Runtime ID: %s)] to [%s]",
 			task.Arn, container.Name, container.GetRuntimeID(), nextState.String())
+		engine.state.MarkTaskChanged(task.Arn)
 		engine.saver.Save()
 	}
 	return metadata
@@ -1442,6 +1627,12 @@ func (engine *DockerTaskEngine) State() dockerstate.TaskEngineState {
 	return engine.state
 }
 
+// PinnedImages returns the images pinned on the instance and the status of
+// their pull
+func (engine *DockerTaskEngine) PinnedImages() []image.PinnedImage {
+	return engine.imageManager.GetPinnedImages()
+}
+
 // Version returns the underlying docker version.
 func (engine *DockerTaskEngine) Version() (string, error) {
 	return engine.client.Version(engine.ctx, dockerclient.VersionTimeout)
@@ -54,11 +54,13 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	mock_ssm_factory "github.com/aws/amazon-ecs-agent/agent/ssm/factory/mocks"
 	mock_ssmiface "github.com/aws/amazon-ecs-agent/agent/ssm/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	mock_statemanager "github.com/aws/amazon-ecs-agent/agent/statemanager/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmauth"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmsecret"
 	mock_taskresource "github.com/aws/amazon-ecs-agent/agent/taskresource/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfile"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
 	taskresourcevolume "github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
 	mock_ttime "github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
@@ -730,6 +732,23 @@ func TestCreateContainerForceSave(t *testing.T) {
 	}
 }
 
+func TestCreateContainerImagePolicyViolation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, _, privateTaskEngine, _, _, _ := mocks(t, ctx, &config.Config{ImagePolicyRequireDigest: true})
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+
+	sleepTask := testdata.LoadTask("sleep5")
+	sleepContainer, _ := sleepTask.ContainerByName("sleep5")
+
+	// The container must not be created
+	metadata := taskEngine.createContainer(sleepTask, sleepContainer)
+	require.Error(t, metadata.Error)
+	assert.Equal(t, "ImagePolicyViolationError", metadata.Error.ErrorName())
+	assert.Contains(t, metadata.Error.Error(), "is not referenced by digest")
+}
+
 func TestCreateContainerMetadata(t *testing.T) {
 	testcases := []struct {
 		name  string
@@ -2165,7 +2184,7 @@ func TestHandleDockerHealthEvent(t *testing.T) {
 		Container:  testContainer,
 	}, testTask)
 
-	taskEngine.(*DockerTaskEngine).handleDockerEvent(dockerapi.DockerContainerChangeEvent{
+	healthEvent := dockerapi.DockerContainerChangeEvent{
 		Status: apicontainerstatus.ContainerRunning,
 		Type:   apicontainer.ContainerHealthEvent,
 		DockerContainerMetadata: dockerapi.DockerContainerMetadata{
@@ -2174,8 +2193,80 @@ func TestHandleDockerHealthEvent(t *testing.T) {
 				Status: apicontainerstatus.ContainerHealthy,
 			},
 		},
-	})
+	}
+	listener := &testHealthListener{}
+	taskEngine.SetContainerHealthListener(listener)
+	taskEngine.(*DockerTaskEngine).handleDockerEvent(healthEvent)
+
+	// The health change is emitted to the listener, once, and not to the
+	// state change events
+	require.Len(t, listener.events, 1)
+	change, ok := listener.events[0].(api.ContainerHealthChange)
+	require.True(t, ok, "expected a container health change, got %v", listener.events[0])
+	assert.Equal(t, testTask.Arn, change.TaskArn)
+	assert.Equal(t, "id", change.RuntimeID)
+	assert.Equal(t, apicontainerstatus.ContainerHealthy, change.Health.Status)
 	assert.Equal(t, testContainer.Health.Status, apicontainerstatus.ContainerHealthy)
+
+	taskEngine.(*DockerTaskEngine).handleDockerEvent(healthEvent)
+	assert.Len(t, listener.events, 1, "unexpected health change for an unchanged health status")
+	select {
+	case event := <-taskEngine.StateChangeEvents():
+		t.Errorf("unexpected state change for a health change: %v", event)
+	default:
+	}
+}
+
+type testHealthListener struct {
+	events []statechange.Event
+}
+
+func (listener *testHealthListener) Dispatch(event statechange.Event) {
+	listener.events = append(listener.events, event)
+}
+
+// TestHandleDockerOOMEvent tests the docker oom event neither marks the
+// container as OOM killed nor changes its status, as the process killed might
+// not be the main one of the container
+func TestHandleDockerOOMEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, _, _, taskEngine, _, _, _ := mocks(t, ctx, &defaultConfig)
+	defer ctrl.Finish()
+
+	state := taskEngine.(*DockerTaskEngine).State()
+	testTask := testdata.LoadTask("sleep5")
+	testContainer := testTask.Containers[0]
+	testContainer.SetKnownStatus(apicontainerstatus.ContainerRunning)
+
+	state.AddTask(testTask)
+	state.AddContainer(&apicontainer.DockerContainer{DockerID: "id",
+		DockerName: "container_name",
+		Container:  testContainer,
+	}, testTask)
+
+	taskEngine.(*DockerTaskEngine).handleDockerEvent(dockerapi.DockerContainerChangeEvent{
+		Type: apicontainer.ContainerOOMEvent,
+		DockerContainerMetadata: dockerapi.DockerContainerMetadata{
+			DockerID:  "id",
+			OOMKilled: true,
+		},
+	})
+	assert.False(t, testContainer.GetOOMKilled())
+	assert.Equal(t, apicontainerstatus.ContainerRunning, testContainer.GetKnownStatus())
+}
+
+// TestUpdateContainerMetadataOOMKilled tests the OOM status of the container
+// follows the one of its inspect, which is reset when it starts again
+func TestUpdateContainerMetadataOOMKilled(t *testing.T) {
+	testTask := testdata.LoadTask("sleep5")
+	testContainer := testTask.Containers[0]
+
+	updateContainerMetadata(&dockerapi.DockerContainerMetadata{OOMKilled: true}, testContainer, testTask)
+	assert.True(t, testContainer.GetOOMKilled())
+
+	updateContainerMetadata(&dockerapi.DockerContainerMetadata{}, testContainer, testTask)
+	assert.False(t, testContainer.GetOOMKilled())
 }
 
 func TestContainerMetadataUpdatedOnRestart(t *testing.T) {
@@ -2673,7 +2764,9 @@ func TestTaskSecretsEnvironmentVariables(t *testing.T) {
 				ssmRequirements,
 				credentialsID,
 				credentialsManager,
-				ssmClientCreator)
+				ssmClientCreator,
+				"",
+				secretfile.Options{})
 
 			// required for validating asm workflows
 			asmClientCreator := mock_asm_factory.NewMockClientCreator(ctrl)
@@ -2688,7 +2781,9 @@ func TestTaskSecretsEnvironmentVariables(t *testing.T) {
 				asmRequirements,
 				credentialsID,
 				credentialsManager,
-				asmClientCreator)
+				asmClientCreator,
+				"",
+				secretfile.Options{})
 
 			testTask.ResourcesMapUnsafe = map[string][]taskresource.TaskResource{
 				ssmsecret.ResourceName: {ssmSecretRes},
@@ -72,6 +72,16 @@ type TaskEngineState interface {
 	DockerIDByV3EndpointID(v3EndpointID string) (string, bool)
 	// TaskARNByV3EndpointID returns a taskARN for a given v3 endpoint ID
 	TaskARNByV3EndpointID(v3EndpointID string) (string, bool)
+	// Records returns the state as individual records grouped by record type
+	Records() (map[string]map[string][]byte, error)
+	// LoadRecords restores the state from records returned by Records
+	LoadRecords(records map[string]map[string][]byte) error
+	// ChangedRecords returns the task and container records that changed
+	// since the records were last returned, and the other records
+	ChangedRecords() (map[string]map[string][]byte, map[string]map[string][]byte, error)
+	// MarkTaskChanged records that the task or its containers changed, so
+	// that their records are returned by ChangedRecords
+	MarkTaskChanged(arn string)
 
 	json.Marshaler
 	json.Unmarshaler
@@ -102,6 +112,13 @@ type DockerTaskEngineState struct {
 	ipToTask               map[string]string // ip address -> task arn
 	v3EndpointIDToTask     map[string]string // container's v3 endpoint id -> taskarn
 	v3EndpointIDToDockerID map[string]string // container's v3 endpoint id -> DockerId
+
+	// changedTasks and changedContainers are the keys of the task and
+	// container records that changed since the records were last returned.
+	// They're nil until the records are first returned, since only the state
+	// stores that save records need the changes.
+	changedTasks      map[string]struct{}
+	changedContainers map[string]struct{}
 }
 
 // NewTaskEngineState returns a new TaskEngineState
@@ -128,6 +145,8 @@ func (state *DockerTaskEngineState) initializeDockerTaskEngineState() {
 	state.ipToTask = make(map[string]string)
 	state.v3EndpointIDToTask = make(map[string]string)
 	state.v3EndpointIDToDockerID = make(map[string]string)
+	state.changedTasks = nil
+	state.changedContainers = nil
 }
 
 // Reset resets all the states
@@ -314,6 +333,7 @@ func (state *DockerTaskEngineState) AddTask(task *apitask.Task) {
 	defer state.lock.Unlock()
 
 	state.tasks[task.Arn] = task
+	state.markTaskChangedUnsafe(task.Arn)
 }
 
 // AddContainer adds a container to the state.
@@ -349,6 +369,7 @@ func (state *DockerTaskEngineState) AddContainer(container *apicontainer.DockerC
 		state.taskToID[task.Arn] = existingMap
 	}
 	existingMap[container.Container.Name] = container
+	state.markTaskChangedUnsafe(task.Arn)
 }
 
 // AddImageState adds an image.ImageState to be stored
@@ -378,6 +399,7 @@ func (state *DockerTaskEngineState) RemoveTask(task *apitask.Task) {
 		seelog.Warnf("Failed to locate task %s for removal from state", task.Arn)
 		return
 	}
+	state.markTaskChangedUnsafe(task.Arn)
 	delete(state.tasks, task.Arn)
 	if ip, ok := state.taskToIPUnsafe(task.Arn); ok {
 		delete(state.ipToTask, ip)
@@ -55,7 +55,13 @@ func (state *DockerTaskEngineState) UnmarshalJSON(data []byte) error {
 	if err != nil {
 		return err
 	}
+	return state.restore(&saved)
+}
 
+// restore validates the saved state and, if it is consistent, resets the
+// state and populates it with the saved tasks, containers, image states, eni
+// attachments and ip addresses
+func (state *DockerTaskEngineState) restore(saved *savedState) error {
 	// run precheck to shake out all the errors before resetting state.
 	precheckState := newDockerTaskEngineState()
 	for _, task := range saved.Tasks {
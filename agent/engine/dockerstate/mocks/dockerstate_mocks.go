@@ -153,6 +153,22 @@ func (mr *MockTaskEngineStateMockRecorder) AllTasks() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllTasks", reflect.TypeOf((*MockTaskEngineState)(nil).AllTasks))
 }
 
+// ChangedRecords mocks base method
+func (m *MockTaskEngineState) ChangedRecords() (map[string]map[string][]byte, map[string]map[string][]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangedRecords")
+	ret0, _ := ret[0].(map[string]map[string][]byte)
+	ret1, _ := ret[1].(map[string]map[string][]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ChangedRecords indicates an expected call of ChangedRecords
+func (mr *MockTaskEngineStateMockRecorder) ChangedRecords() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangedRecords", reflect.TypeOf((*MockTaskEngineState)(nil).ChangedRecords))
+}
+
 // ContainerByID mocks base method
 func (m *MockTaskEngineState) ContainerByID(arg0 string) (*container.DockerContainer, bool) {
 	m.ctrl.T.Helper()
@@ -242,6 +258,32 @@ func (mr *MockTaskEngineStateMockRecorder) GetTaskByIPAddress(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTaskByIPAddress", reflect.TypeOf((*MockTaskEngineState)(nil).GetTaskByIPAddress), arg0)
 }
 
+// LoadRecords mocks base method
+func (m *MockTaskEngineState) LoadRecords(arg0 map[string]map[string][]byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadRecords", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LoadRecords indicates an expected call of LoadRecords
+func (mr *MockTaskEngineStateMockRecorder) LoadRecords(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadRecords", reflect.TypeOf((*MockTaskEngineState)(nil).LoadRecords), arg0)
+}
+
+// MarkTaskChanged mocks base method
+func (m *MockTaskEngineState) MarkTaskChanged(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MarkTaskChanged", arg0)
+}
+
+// MarkTaskChanged indicates an expected call of MarkTaskChanged
+func (mr *MockTaskEngineStateMockRecorder) MarkTaskChanged(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkTaskChanged", reflect.TypeOf((*MockTaskEngineState)(nil).MarkTaskChanged), arg0)
+}
+
 // MarshalJSON mocks base method
 func (m *MockTaskEngineState) MarshalJSON() ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -257,6 +299,21 @@ func (mr *MockTaskEngineStateMockRecorder) MarshalJSON() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarshalJSON", reflect.TypeOf((*MockTaskEngineState)(nil).MarshalJSON))
 }
 
+// Records mocks base method
+func (m *MockTaskEngineState) Records() (map[string]map[string][]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Records")
+	ret0, _ := ret[0].(map[string]map[string][]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Records indicates an expected call of Records
+func (mr *MockTaskEngineStateMockRecorder) Records() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Records", reflect.TypeOf((*MockTaskEngineState)(nil).Records))
+}
+
 // RemoveENIAttachment mocks base method
 func (m *MockTaskEngineState) RemoveENIAttachment(arg0 string) {
 	m.ctrl.T.Helper()
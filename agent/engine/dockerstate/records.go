@@ -0,0 +1,255 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerstate
+
+import (
+	"encoding/json"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TasksRecordType is the record type of tasks, keyed by task arn
+	TasksRecordType = "tasks"
+	// ContainersRecordType is the record type of docker containers, keyed by
+	// docker id
+	ContainersRecordType = "containers"
+	// ImageStatesRecordType is the record type of image states, keyed by
+	// image id
+	ImageStatesRecordType = "imagestates"
+	// ENIAttachmentsRecordType is the record type of eni attachments, keyed by
+	// mac address
+	ENIAttachmentsRecordType = "eniattachments"
+	// IPToTaskRecordType is the record type of task ip addresses, keyed by ip
+	// address
+	IPToTaskRecordType = "iptotask"
+)
+
+// savedContainer is the record saved for each docker container. It carries
+// the arn of the task that the container belongs to, so that the container
+// can be resolved into its task when the records are loaded.
+type savedContainer struct {
+	TaskARN   string
+	Container *apicontainer.DockerContainer
+}
+
+// Records returns the state as individual json records, grouped by record
+// type and keyed by a stable identifier within each record type. The task and
+// container records that change from then on are returned by ChangedRecords.
+func (state *DockerTaskEngineState) Records() (map[string]map[string][]byte, error) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	return state.recordsUnsafe()
+}
+
+// ChangedRecords returns the task and container records that changed since the
+// records were last returned by Records or ChangedRecords, with a nil value for
+// the removed ones, followed by the records of the other record types, which
+// are small and changed in place by their owners and are therefore always
+// returned in full. Every record is returned in full if the changes aren't
+// known, such as after the state is reset.
+func (state *DockerTaskEngineState) ChangedRecords() (map[string]map[string][]byte, map[string]map[string][]byte, error) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	if state.changedTasks == nil {
+		records, err := state.recordsUnsafe()
+		return nil, records, err
+	}
+
+	changed := map[string]map[string][]byte{
+		TasksRecordType:      make(map[string][]byte),
+		ContainersRecordType: make(map[string][]byte),
+	}
+	for arn := range state.changedTasks {
+		changed[TasksRecordType][arn] = nil
+		if task, ok := state.tasks[arn]; ok {
+			if err := addRecord(changed, TasksRecordType, arn, task); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for id := range state.changedContainers {
+		changed[ContainersRecordType][id] = nil
+		if container, ok := state.idToContainer[id]; ok {
+			if err := addRecord(changed, ContainersRecordType, id, state.savedContainerUnsafe(id, container)); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	records, err := state.untrackedRecordsUnsafe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state.changedTasks = make(map[string]struct{})
+	state.changedContainers = make(map[string]struct{})
+	return changed, records, nil
+}
+
+// MarkTaskChanged records that the task or its containers changed, so that
+// their records are returned by the next call to ChangedRecords
+func (state *DockerTaskEngineState) MarkTaskChanged(arn string) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	state.markTaskChangedUnsafe(arn)
+}
+
+// markTaskChangedUnsafe records that the task and its containers changed, if
+// the changes are being tracked
+func (state *DockerTaskEngineState) markTaskChangedUnsafe(arn string) {
+	if state.changedTasks == nil {
+		return
+	}
+	state.changedTasks[arn] = struct{}{}
+	for _, container := range state.taskToID[arn] {
+		// Containers are keyed by name until they get a docker id, so both
+		// are marked to remove the record keyed by name once that happens
+		if container.DockerID != "" {
+			state.changedContainers[container.DockerID] = struct{}{}
+		}
+		if container.DockerName != "" {
+			state.changedContainers[container.DockerName] = struct{}{}
+		}
+	}
+}
+
+// recordsUnsafe returns every record of the state, and starts tracking the
+// task and container records that change
+func (state *DockerTaskEngineState) recordsUnsafe() (map[string]map[string][]byte, error) {
+	records, err := state.untrackedRecordsUnsafe()
+	if err != nil {
+		return nil, err
+	}
+	records[TasksRecordType] = make(map[string][]byte)
+	records[ContainersRecordType] = make(map[string][]byte)
+	for arn, task := range state.tasks {
+		if err := addRecord(records, TasksRecordType, arn, task); err != nil {
+			return nil, err
+		}
+	}
+	for id, container := range state.idToContainer {
+		if err := addRecord(records, ContainersRecordType, id, state.savedContainerUnsafe(id, container)); err != nil {
+			return nil, err
+		}
+	}
+
+	state.changedTasks = make(map[string]struct{})
+	state.changedContainers = make(map[string]struct{})
+	return records, nil
+}
+
+// untrackedRecordsUnsafe returns the records of the record types whose changes
+// aren't tracked
+func (state *DockerTaskEngineState) untrackedRecordsUnsafe() (map[string]map[string][]byte, error) {
+	records := map[string]map[string][]byte{
+		ImageStatesRecordType:    make(map[string][]byte),
+		ENIAttachmentsRecordType: make(map[string][]byte),
+		IPToTaskRecordType:       make(map[string][]byte),
+	}
+	for id, imageState := range state.imageStates {
+		if err := addRecord(records, ImageStatesRecordType, id, imageState); err != nil {
+			return nil, err
+		}
+	}
+	for mac, eniAttachment := range state.eniAttachments {
+		if err := addRecord(records, ENIAttachmentsRecordType, mac, eniAttachment); err != nil {
+			return nil, err
+		}
+	}
+	for ipAddr, taskARN := range state.ipToTask {
+		if err := addRecord(records, IPToTaskRecordType, ipAddr, taskARN); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// savedContainerUnsafe returns the record saved for the container with the
+// given key
+func (state *DockerTaskEngineState) savedContainerUnsafe(id string, container *apicontainer.DockerContainer) savedContainer {
+	return savedContainer{
+		TaskARN:   state.idToTask[id],
+		Container: container,
+	}
+}
+
+// addRecord adds the json representation of the value to the records
+func addRecord(records map[string]map[string][]byte, recordType, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "unable to marshal %s record %s", recordType, key)
+	}
+	records[recordType][key] = data
+	return nil
+}
+
+// LoadRecords resets the state and populates it with records previously
+// returned by Records. The state is left untouched if the records are
+// inconsistent.
+func (state *DockerTaskEngineState) LoadRecords(records map[string]map[string][]byte) error {
+	saved := savedState{
+		IdToContainer: make(map[string]*apicontainer.DockerContainer),
+		IdToTask:      make(map[string]string),
+		IPToTask:      make(map[string]string),
+	}
+
+	for arn, data := range records[TasksRecordType] {
+		var task apitask.Task
+		if err := json.Unmarshal(data, &task); err != nil {
+			return errors.Wrapf(err, "unable to unmarshal task record %s", arn)
+		}
+		saved.Tasks = append(saved.Tasks, &task)
+	}
+	for id, data := range records[ContainersRecordType] {
+		var container savedContainer
+		if err := json.Unmarshal(data, &container); err != nil {
+			return errors.Wrapf(err, "unable to unmarshal container record %s", id)
+		}
+		if container.Container == nil || container.Container.Container == nil {
+			return errors.Errorf("incomplete container record %s", id)
+		}
+		saved.IdToContainer[id] = container.Container
+		saved.IdToTask[id] = container.TaskARN
+	}
+	for id, data := range records[ImageStatesRecordType] {
+		var imageState image.ImageState
+		if err := json.Unmarshal(data, &imageState); err != nil {
+			return errors.Wrapf(err, "unable to unmarshal image state record %s", id)
+		}
+		saved.ImageStates = append(saved.ImageStates, &imageState)
+	}
+	for mac, data := range records[ENIAttachmentsRecordType] {
+		var eniAttachment apieni.ENIAttachment
+		if err := json.Unmarshal(data, &eniAttachment); err != nil {
+			return errors.Wrapf(err, "unable to unmarshal eni attachment record %s", mac)
+		}
+		saved.ENIAttachments = append(saved.ENIAttachments, &eniAttachment)
+	}
+	for ipAddr, data := range records[IPToTaskRecordType] {
+		var taskARN string
+		if err := json.Unmarshal(data, &taskARN); err != nil {
+			return errors.Wrapf(err, "unable to unmarshal task ip address record %s", ipAddr)
+		}
+		saved.IPToTask[ipAddr] = taskARN
+	}
+
+	return state.restore(&saved)
+}
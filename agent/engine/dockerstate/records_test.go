@@ -0,0 +1,104 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerstate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordsLoadRecords(t *testing.T) {
+	state := newDockerTaskEngineState()
+	require.NoError(t, state.UnmarshalJSON([]byte(stateFileContents)))
+
+	records, err := state.Records()
+	require.NoError(t, err)
+	assert.Len(t, records[TasksRecordType], 1)
+	assert.Len(t, records[ContainersRecordType], 2)
+	assert.Len(t, records[ImageStatesRecordType], 1)
+
+	loaded := newDockerTaskEngineState()
+	require.NoError(t, loaded.LoadRecords(records))
+	expected, err := state.MarshalJSON()
+	require.NoError(t, err)
+	validateUnmarshaledState(t, loaded, expected)
+}
+
+func TestLoadRecordsContainerWithoutTask(t *testing.T) {
+	state := newDockerTaskEngineState()
+	require.NoError(t, state.UnmarshalJSON([]byte(stateFileContents)))
+	records, err := state.Records()
+	require.NoError(t, err)
+	delete(records, TasksRecordType)
+
+	loaded := newDockerTaskEngineState()
+	loaded.AddTaskIPAddress("10.0.0.1", "task1")
+	assert.Error(t, loaded.LoadRecords(records))
+	_, ok := loaded.GetTaskByIPAddress("10.0.0.1")
+	assert.True(t, ok, "state should be left untouched")
+}
+
+func TestChangedRecords(t *testing.T) {
+	state := newDockerTaskEngineState()
+	require.NoError(t, state.UnmarshalJSON([]byte(stateFileContents)))
+
+	// The changes aren't known until the records are first returned
+	changed, records, err := state.ChangedRecords()
+	require.NoError(t, err)
+	assert.Nil(t, changed)
+	assert.Len(t, records[TasksRecordType], 1)
+	assert.Len(t, records[ContainersRecordType], 2)
+
+	changed, records, err = state.ChangedRecords()
+	require.NoError(t, err)
+	assert.Empty(t, changed[TasksRecordType])
+	assert.Empty(t, changed[ContainersRecordType])
+	assert.NotContains(t, records, TasksRecordType)
+	assert.Len(t, records[ImageStatesRecordType], 1)
+	assert.Len(t, records[IPToTaskRecordType], 1)
+
+	state.MarkTaskChanged("task1")
+	changed, _, err = state.ChangedRecords()
+	require.NoError(t, err)
+	assert.NotNil(t, changed[TasksRecordType]["task1"])
+	// The containers are also marked by name, to remove the records saved
+	// before they got their docker ids
+	saved := 0
+	for _, value := range changed[ContainersRecordType] {
+		if value != nil {
+			saved++
+		}
+	}
+	assert.Equal(t, 2, saved)
+
+	task, ok := state.TaskByArn("task1")
+	require.True(t, ok)
+	state.RemoveTask(task)
+	changed, _, err = state.ChangedRecords()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"task1": nil}, changed[TasksRecordType])
+	for id, value := range changed[ContainersRecordType] {
+		assert.Nil(t, value, "container record %s should be removed", id)
+	}
+
+	// Resetting the state forgets the changes
+	state.Reset()
+	changed, _, err = state.ChangedRecords()
+	require.NoError(t, err)
+	assert.Nil(t, changed)
+}
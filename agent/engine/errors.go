@@ -97,3 +97,40 @@ func (err CannotGetDockerClientVersionError) ErrorName() string {
 func (err CannotGetDockerClientVersionError) Error() string {
 	return err.fromError.Error()
 }
+
+// imagePolicyViolationErrorName is the name of ImagePolicyViolationError
+const imagePolicyViolationErrorName = "ImagePolicyViolationError"
+
+// ImagePolicyViolationError indicates that the image of a container doesn't
+// comply with the image policy, which keeps the container from being created
+type ImagePolicyViolationError struct {
+	fromError error
+}
+
+func (err ImagePolicyViolationError) Error() string {
+	return err.fromError.Error()
+}
+
+// ErrorName returns the name of the error
+func (err ImagePolicyViolationError) ErrorName() string {
+	return imagePolicyViolationErrorName
+}
+
+// taskAdmissionDeniedErrorName is the name of TaskAdmissionDeniedError
+const taskAdmissionDeniedErrorName = "TaskAdmissionDeniedError"
+
+// TaskAdmissionDeniedError indicates that a container requests host-level
+// privileges denied by the task admission policy, which keeps the container
+// from being created
+type TaskAdmissionDeniedError struct {
+	fromError error
+}
+
+func (err TaskAdmissionDeniedError) Error() string {
+	return err.fromError.Error()
+}
+
+// ErrorName returns the name of the error
+func (err TaskAdmissionDeniedError) ErrorName() string {
+	return taskAdmissionDeniedErrorName
+}
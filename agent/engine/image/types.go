@@ -34,6 +34,33 @@ func (image *Image) String() string {
 	return fmt.Sprintf("ImageID: %s; Names: %s", image.ImageID, strings.Join(image.Names, ", "))
 }
 
+// PinnedImageStatus is the status of the pull of a pinned image
+type PinnedImageStatus string
+
+const (
+	// PinnedImagePending is the status of a pinned image not pulled yet
+	PinnedImagePending PinnedImageStatus = "PENDING"
+	// PinnedImagePulled is the status of a pinned image pulled successfully
+	PinnedImagePulled PinnedImageStatus = "PULLED"
+	// PinnedImageFailed is the status of a pinned image that couldn't be pulled
+	PinnedImageFailed PinnedImageStatus = "FAILED"
+)
+
+// PinnedImage is an image pulled by the Agent when it starts, which is never
+// removed by the image cleanup
+type PinnedImage struct {
+	// Name is the name of the image as configured, optionally with a digest
+	Name string
+	// ImageID is the ID of the pulled image
+	ImageID string
+	// Status is the status of the pull of the image
+	Status PinnedImageStatus
+	// PulledAt is the time when the image was pulled
+	PulledAt time.Time
+	// Error is the reason why the image couldn't be pulled
+	Error string
+}
+
 // ImageState represents a docker image
 // and its state information such as containers associated with it
 type ImageState struct {
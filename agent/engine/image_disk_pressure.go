@@ -0,0 +1,118 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// diskPressureCheckInterval is how often the usage of the docker storage
+	// is compared with the image cleanup high watermark
+	diskPressureCheckInterval = time.Minute
+
+	// diskPressureCleanup labels the bytes reclaimed by the disk pressure image
+	// cleanup in the published metrics
+	diskPressureCleanup = "DiskPressure"
+)
+
+// diskPressureCleanupEnabled returns true if images are to be removed when the
+// usage of the docker storage crosses the high watermark
+func (imageManager *dockerImageManager) diskPressureCleanupEnabled() bool {
+	return imageManager.imageCleanupHighWatermark != 0
+}
+
+// removeImagesUnderDiskPressure removes unused images once the usage of the
+// docker storage is above the high watermark, until it drops below the low
+// watermark. Images pulled by the Agent are removed first, least recently used
+// first, followed by non-ECS images, oldest first, if their cleanup is enabled.
+// The exclusion list and minimum deletion ages are respected the same way as
+// in the periodic cleanup.
+func (imageManager *dockerImageManager) removeImagesUnderDiskPressure(ctx context.Context) {
+	usage, err := imageManager.diskUsage.Usage(imageManager.dockerDataRoot)
+	if err != nil {
+		seelog.Warnf("Image Manager: unable to get usage of docker storage %s: %v", imageManager.dockerDataRoot, err)
+		return
+	}
+	if usage.UsedBytesPercent() < float64(imageManager.imageCleanupHighWatermark) {
+		return
+	}
+
+	seelog.Debug("Attempting to obtain ImagePullDeleteLock for removing images under disk pressure")
+	ImagePullDeleteLock.Lock()
+	seelog.Debug("Obtained ImagePullDeleteLock for removing images under disk pressure")
+	defer seelog.Debug("Released ImagePullDeleteLock after removing images under disk pressure")
+	defer ImagePullDeleteLock.Unlock()
+
+	imageManager.updateLock.Lock()
+	defer imageManager.updateLock.Unlock()
+
+	seelog.Infof("Image Manager: docker storage usage of %.1f%% is above the high watermark of %d%%, removing unused images",
+		usage.UsedBytesPercent(), imageManager.imageCleanupHighWatermark)
+	freeBytesBefore := usage.FreeBytes
+	// belowLowWatermark refreshes the usage after an image has been removed.
+	// Failing to get it ends the cleanup as well, as there is no telling
+	// whether more images need to be removed.
+	belowLowWatermark := func() bool {
+		current, err := imageManager.diskUsage.Usage(imageManager.dockerDataRoot)
+		if err != nil {
+			seelog.Warnf("Image Manager: unable to get usage of docker storage %s: %v", imageManager.dockerDataRoot, err)
+			return true
+		}
+		usage = current
+		return usage.UsedBytesPercent() < float64(imageManager.imageCleanupLowWatermark)
+	}
+
+	done := false
+	imageManager.imageStatesConsideredForDeletion = imageManager.imagesConsiderForDeletion(imageManager.getAllImageStates())
+	for !done {
+		if err := imageManager.removeLeastRecentlyUsedImage(ctx); err != nil {
+			seelog.Infof("End of eligible images for deletion: %v; Still have %d image states being managed", err, len(imageManager.getAllImageStates()))
+			break
+		}
+		done = belowLowWatermark()
+	}
+	if !done && imageManager.deleteNonECSImagesEnabled {
+		imageManager.removeOldestNonECSImages(ctx, belowLowWatermark)
+	}
+
+	reclaimedBytes := uint64(0)
+	if usage.FreeBytes > freeBytesBefore {
+		reclaimedBytes = usage.FreeBytes - freeBytesBefore
+	}
+	seelog.Infof("Image Manager: reclaimed %d bytes of docker storage, usage is now %.1f%%", reclaimedBytes, usage.UsedBytesPercent())
+	metrics.MetricsEngineGlobal.RecordImageCleanupReclaimedBytes(diskPressureCleanup, reclaimedBytes)
+}
+
+// removeOldestNonECSImages removes the non-ECS images that are old enough to
+// be deleted, oldest first, until done returns true
+func (imageManager *dockerImageManager) removeOldestNonECSImages(ctx context.Context, done func() bool) {
+	nonECSImages := imageManager.getNonECSImages(ctx)
+	sort.Slice(nonECSImages, func(i, j int) bool {
+		return nonECSImages[i].createdTime.Before(nonECSImages[j].createdTime)
+	})
+	for _, image := range nonECSImages {
+		if !imageManager.nonECSImageOldEnough(image) {
+			continue
+		}
+		if imageManager.removeNonECSImage(ctx, image) > 0 && done() {
+			return
+		}
+	}
+}
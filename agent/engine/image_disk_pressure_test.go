@@ -0,0 +1,175 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/utils/diskusage"
+	mock_diskusage "github.com/aws/amazon-ecs-agent/agent/utils/diskusage/mocks"
+
+	"github.com/docker/docker/api/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// usedPercent returns the usage of a 100 bytes filesystem with the given
+// number of bytes used
+func usedPercent(used uint64) *diskusage.Usage {
+	return &diskusage.Usage{TotalBytes: 100, FreeBytes: 100 - used}
+}
+
+func newDiskPressureImageManager(client dockerapi.DockerClient, diskUsage diskusage.Getter) *dockerImageManager {
+	imageManager := &dockerImageManager{
+		client:                    client,
+		state:                     dockerstate.NewTaskEngineState(),
+		minimumAgeBeforeDeletion:  time.Hour,
+		numImagesToDelete:         config.DefaultNumImagesToDeletePerCycle,
+		imageCleanupTimeInterval:  config.DefaultImageCleanupTimeInterval,
+		diskUsage:                 diskUsage,
+		dockerDataRoot:            testDockerDataRoot,
+		imageCleanupHighWatermark: 85,
+		imageCleanupLowWatermark:  70,
+	}
+	imageManager.SetSaver(statemanager.NewNoopStateManager())
+	return imageManager
+}
+
+func addUnusedImageState(imageManager *dockerImageManager, name string, pulledAt time.Time, lastUsedAt time.Time) {
+	imageManager.addImageState(&image.ImageState{
+		Image:      &image.Image{ImageID: "sha256:" + name, Names: []string{name}},
+		PulledAt:   pulledAt,
+		LastUsedAt: lastUsedAt,
+	})
+}
+
+func TestRemoveImagesUnderDiskPressureBelowHighWatermark(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	diskUsage := mock_diskusage.NewMockGetter(ctrl)
+	imageManager := newDiskPressureImageManager(client, diskUsage)
+	addUnusedImageState(imageManager, "image1", time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour))
+
+	diskUsage.EXPECT().Usage(testDockerDataRoot).Return(usedPercent(84), nil)
+
+	imageManager.removeImagesUnderDiskPressure(context.TODO())
+	assert.Equal(t, 1, imageManager.GetImageStatesCount())
+}
+
+func TestRemoveImagesUnderDiskPressureLeastRecentlyUsedFirst(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	diskUsage := mock_diskusage.NewMockGetter(ctrl)
+	imageManager := newDiskPressureImageManager(client, diskUsage)
+	imageManager.imageCleanupExclusionList = []string{"excluded"}
+
+	old := time.Now().Add(-2 * time.Hour)
+	addUnusedImageState(imageManager, "recent", old, time.Now().Add(-10*time.Minute))
+	addUnusedImageState(imageManager, "leastRecent", old, time.Now().Add(-time.Hour))
+	addUnusedImageState(imageManager, "lessRecent", old, time.Now().Add(-30*time.Minute))
+	addUnusedImageState(imageManager, "excluded", old, old)
+	addUnusedImageState(imageManager, "justPulled", time.Now(), old)
+
+	gomock.InOrder(
+		diskUsage.EXPECT().Usage(testDockerDataRoot).Return(usedPercent(90), nil),
+		client.EXPECT().RemoveImage(gomock.Any(), "leastRecent", dockerclient.RemoveImageTimeout).Return(nil),
+		diskUsage.EXPECT().Usage(testDockerDataRoot).Return(usedPercent(80), nil),
+		client.EXPECT().RemoveImage(gomock.Any(), "lessRecent", dockerclient.RemoveImageTimeout).Return(nil),
+		diskUsage.EXPECT().Usage(testDockerDataRoot).Return(usedPercent(65), nil),
+	)
+
+	imageManager.removeImagesUnderDiskPressure(context.TODO())
+	assert.Equal(t, 3, imageManager.GetImageStatesCount())
+	_, ok := imageManager.GetImageStateFromImageName("recent")
+	assert.True(t, ok, "image used more recently should be kept once below the low watermark")
+}
+
+func TestRemoveImagesUnderDiskPressureRemovesNonECSImagesOldestFirst(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	diskUsage := mock_diskusage.NewMockGetter(ctrl)
+	imageManager := newDiskPressureImageManager(client, diskUsage)
+	imageManager.deleteNonECSImagesEnabled = true
+	imageManager.nonECSMinimumAgeBeforeDeletion = time.Hour
+	addUnusedImageState(imageManager, "ecs", time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour))
+
+	client.EXPECT().ListImages(gomock.Any(), dockerclient.ListImagesTimeout).Return(dockerapi.ListImagesResponse{
+		ImageIDs: []string{"sha256:newer", "sha256:oldest", "sha256:tooYoung"},
+	})
+	client.EXPECT().InspectImage("sha256:newer").Return(&types.ImageInspect{
+		RepoTags: []string{"newer"},
+		Created:  time.Now().Add(-2 * time.Hour).Format(time.RFC3339),
+	}, nil)
+	client.EXPECT().InspectImage("sha256:oldest").Return(&types.ImageInspect{
+		RepoTags: []string{"oldest"},
+		Created:  time.Now().Add(-3 * time.Hour).Format(time.RFC3339),
+	}, nil)
+	client.EXPECT().InspectImage("sha256:tooYoung").Return(&types.ImageInspect{
+		RepoTags: []string{"tooYoung"},
+		Created:  time.Now().Format(time.RFC3339),
+	}, nil)
+
+	gomock.InOrder(
+		diskUsage.EXPECT().Usage(testDockerDataRoot).Return(usedPercent(95), nil),
+		client.EXPECT().RemoveImage(gomock.Any(), "ecs", dockerclient.RemoveImageTimeout).Return(nil),
+		diskUsage.EXPECT().Usage(testDockerDataRoot).Return(usedPercent(90), nil),
+		client.EXPECT().RemoveImage(gomock.Any(), "sha256:oldest", dockerclient.RemoveImageTimeout).Return(nil),
+		diskUsage.EXPECT().Usage(testDockerDataRoot).Return(usedPercent(80), nil),
+		client.EXPECT().RemoveImage(gomock.Any(), "sha256:newer", dockerclient.RemoveImageTimeout).Return(nil),
+		diskUsage.EXPECT().Usage(testDockerDataRoot).Return(usedPercent(75), nil),
+	)
+
+	imageManager.removeImagesUnderDiskPressure(context.TODO())
+	assert.Equal(t, 0, imageManager.GetImageStatesCount())
+}
+
+func TestPeriodicImageCleanupChecksDiskPressure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	diskUsage := mock_diskusage.NewMockGetter(ctrl)
+	imageManager := newDiskPressureImageManager(client, diskUsage)
+	imageManager.diskPressureCheckInterval = time.Millisecond
+
+	checked := make(chan struct{})
+	diskUsage.EXPECT().Usage(testDockerDataRoot).Do(func(string) {
+		select {
+		case checked <- struct{}{}:
+		default:
+		}
+	}).Return(usedPercent(10), nil).MinTimes(1)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go imageManager.performPeriodicImageCleanup(ctx, time.Hour)
+	select {
+	case <-checked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("docker storage usage was not checked")
+	}
+}
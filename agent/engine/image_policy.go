@@ -0,0 +1,53 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// checkImagePolicy returns an error if the image of the container doesn't
+// comply with the image policy. The image has been pulled at this point, so
+// that its repository digests can be used to verify its signature. When
+// signatures are verified, the ID of the verified image is returned, and the
+// container must be created from it rather than from the image name, which
+// could be tagged on another image once verified.
+func (engine *DockerTaskEngine) checkImagePolicy(task *apitask.Task, container *apicontainer.Container) (string, apierrors.NamedError) {
+	if !engine.imagePolicy.Enabled() || container.IsInternal() {
+		return "", nil
+	}
+
+	var imageID string
+	var repoDigests []string
+	if engine.imagePolicy.VerifiesSignatures() {
+		imageInspected, err := engine.client.InspectImage(container.Image)
+		if err != nil {
+			return "", ImagePolicyViolationError{errors.Wrapf(err, "unable to inspect image %s to verify its signature", container.Image)}
+		}
+		imageID = imageInspected.ID
+		repoDigests = imageInspected.RepoDigests
+	}
+
+	if err := engine.imagePolicy.Check(container.Image, repoDigests); err != nil {
+		seelog.Errorf("Task engine [%s]: image %s of container %s violates the image policy: %v",
+			task.Arn, container.Image, container.Name, err)
+		return "", ImagePolicyViolationError{err}
+	}
+	return imageID, nil
+}
@@ -0,0 +1,104 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
+
+	"github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testImageID     = "sha256:4b9c9e8b6d6bbf1b2a1bc7e8f5d0c0e0a4b1b8e5d0c5f0a7d8b2c9e1f3a4b5c6"
+	testImageDigest = "sha256:bc8813ea7b3603864987522f02a76101c17ad122e1c46d790efc0fca78ca7bfb"
+)
+
+// signImage writes the public key of the signer to the public keys directory,
+// and the signature of the repository digest to the signatures directory
+func signImage(t *testing.T, keysDir, signaturesDir, repository, digest string) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(keysDir, "key.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+
+	hash := sha256.Sum256([]byte(repository + "@" + digest))
+	signature, err := signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+	require.NoError(t, err)
+	dir := filepath.Join(signaturesDir, filepath.FromSlash(repository)+"@sha256="+digest[len("sha256:"):])
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "signature-1"),
+		[]byte(base64.StdEncoding.EncodeToString(signature)), 0644))
+}
+
+func TestCreateContainerFromVerifiedImage(t *testing.T) {
+	keysDir, err := ioutil.TempDir("", "image-policy-keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(keysDir)
+	signaturesDir, err := ioutil.TempDir("", "image-policy-signatures")
+	require.NoError(t, err)
+	defer os.RemoveAll(signaturesDir)
+	signImage(t, keysDir, signaturesDir, "docker.io/library/busybox", testImageDigest)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, _, privateTaskEngine, _, _, _ := mocks(t, ctx, &config.Config{
+		ImagePolicyPublicKeysDir: keysDir,
+		ImagePolicySignaturesDir: signaturesDir,
+	})
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+
+	sleepTask := testdata.LoadTask("sleep5")
+	sleepContainer, _ := sleepTask.ContainerByName("sleep5")
+	sleepContainer.Image = "busybox:1.31"
+
+	// The container is created from the image whose signature was verified,
+	// rather than from the tag, which could have been moved since
+	client.EXPECT().InspectImage("busybox:1.31").Return(&types.ImageInspect{
+		ID:          testImageID,
+		RepoDigests: []string{"busybox@" + testImageDigest},
+	}, nil)
+	client.EXPECT().APIVersion().Return(defaultDockerClientAPIVersion, nil).AnyTimes()
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(ctx interface{}, config *dockercontainer.Config, hostConfig interface{}, name string, timeout interface{}) {
+			assert.Equal(t, testImageID, config.Image)
+		})
+
+	metadata := taskEngine.createContainer(sleepTask, sleepContainer)
+	assert.NoError(t, metadata.Error)
+	assert.Equal(t, "busybox:1.31", sleepContainer.Image)
+}
@@ -0,0 +1,102 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package imagepolicy checks the images of containers against the image
+// policy configured for the Agent before the containers are created
+package imagepolicy
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// Policy is the policy that the images of containers must comply with for the
+// containers to be created. Images can be required to be referenced by
+// digest, to come from allowed repositories and to carry a valid signature.
+type Policy struct {
+	requireDigest       bool
+	allowedRepositories []string
+	publicKeysDir       string
+	signaturesDir       string
+}
+
+// NewPolicy returns the image policy configured by cfg
+func NewPolicy(cfg *config.Config) *Policy {
+	return &Policy{
+		requireDigest:       cfg.ImagePolicyRequireDigest,
+		allowedRepositories: cfg.ImagePolicyAllowedRepositories,
+		publicKeysDir:       cfg.ImagePolicyPublicKeysDir,
+		signaturesDir:       cfg.ImagePolicySignaturesDir,
+	}
+}
+
+// Enabled returns true if the policy restricts the images of containers
+func (policy *Policy) Enabled() bool {
+	return policy != nil && (policy.requireDigest || len(policy.allowedRepositories) > 0 || policy.VerifiesSignatures())
+}
+
+// VerifiesSignatures returns true if images must carry a valid signature
+func (policy *Policy) VerifiesSignatures() bool {
+	return policy != nil && policy.publicKeysDir != ""
+}
+
+// Check returns an error describing why the image doesn't comply with the
+// policy. repoDigests are the repository digests of the pulled image, as
+// returned by the inspection of the image, which are needed to verify the
+// signature of images referenced by tag.
+func (policy *Policy) Check(image string, repoDigests []string) error {
+	if !policy.Enabled() {
+		return nil
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return errors.Wrapf(err, "unable to parse image name %s", image)
+	}
+
+	if _, ok := named.(reference.Digested); policy.requireDigest && !ok {
+		return fmt.Errorf("image %s is not referenced by digest", image)
+	}
+
+	if len(policy.allowedRepositories) > 0 && !policy.repositoryAllowed(named) {
+		return fmt.Errorf("repository %s of image %s is not allowed", named.Name(), image)
+	}
+
+	if policy.VerifiesSignatures() {
+		return policy.verifySignature(named, repoDigests)
+	}
+	return nil
+}
+
+// repositoryAllowed returns true if the repository of the image matches one of
+// the allowed registry hosts, repository names or repository name patterns
+func (policy *Policy) repositoryAllowed(named reference.Named) bool {
+	for _, allowed := range policy.allowedRepositories {
+		if allowed == reference.Domain(named) || allowed == named.Name() {
+			return true
+		}
+		if matched, _ := path.Match(allowed, named.Name()); matched {
+			return true
+		}
+		// Entries can also use the familiar name of Docker Hub repositories,
+		// such as "busybox"
+		if allowedNamed, err := reference.ParseNormalizedNamed(allowed); err == nil && allowedNamed.String() == named.Name() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package imagepolicy
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDigest     = "sha256:bc8813ea7b3603864987522f02a76101c17ad122e1c46d790efc0fca78ca7bfb"
+	testDigestPath = "sha256=bc8813ea7b3603864987522f02a76101c17ad122e1c46d790efc0fca78ca7bfb"
+)
+
+func TestPolicyDisabled(t *testing.T) {
+	policy := NewPolicy(&config.Config{})
+	assert.False(t, policy.Enabled())
+	assert.NoError(t, policy.Check("busybox", nil))
+
+	var nilPolicy *Policy
+	assert.False(t, nilPolicy.Enabled())
+	assert.NoError(t, nilPolicy.Check("busybox", nil))
+}
+
+func TestPolicyRequireDigest(t *testing.T) {
+	policy := NewPolicy(&config.Config{ImagePolicyRequireDigest: true})
+	assert.True(t, policy.Enabled())
+	assert.NoError(t, policy.Check("busybox@"+testDigest, nil))
+	assert.Error(t, policy.Check("busybox", nil))
+	assert.Error(t, policy.Check("busybox:1.31", nil))
+}
+
+func TestPolicyAllowedRepositories(t *testing.T) {
+	policy := NewPolicy(&config.Config{
+		ImagePolicyAllowedRepositories: []string{
+			"123456789012.dkr.ecr.us-west-2.amazonaws.com",
+			"docker.io/amazon/*",
+			"busybox",
+		},
+	})
+	testCases := []struct {
+		image   string
+		allowed bool
+	}{
+		{"123456789012.dkr.ecr.us-west-2.amazonaws.com/team/app:v1", true},
+		{"amazon/amazon-ecs-agent:latest", true},
+		{"busybox:1.31", true},
+		{"docker.io/library/busybox@" + testDigest, true},
+		{"amazonlinux", false},
+		{"210987654321.dkr.ecr.us-west-2.amazonaws.com/team/app:v1", false},
+		{"registry.example.com/amazon/app", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.image, func(t *testing.T) {
+			err := policy.Check(tc.image, nil)
+			if tc.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicyInvalidImageName(t *testing.T) {
+	policy := NewPolicy(&config.Config{ImagePolicyRequireDigest: true})
+	assert.Error(t, policy.Check("Invalid:Image:Name", nil))
+}
+
+// setupSignatureDirs creates the public keys and signatures directories, and
+// writes the public keys of the given signers to the former
+func setupSignatureDirs(t *testing.T, signers ...crypto.Signer) (string, string, func()) {
+	keysDir, err := ioutil.TempDir("", "image-policy-keys")
+	require.NoError(t, err)
+	signaturesDir, err := ioutil.TempDir("", "image-policy-signatures")
+	require.NoError(t, err)
+	for i, signer := range signers {
+		der, err := x509.MarshalPKIXPublicKey(signer.Public())
+		require.NoError(t, err)
+		data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+		require.NoError(t, ioutil.WriteFile(filepath.Join(keysDir, fmt.Sprintf("key%d.pem", i)), data, 0644))
+	}
+	return keysDir, signaturesDir, func() {
+		os.RemoveAll(keysDir)
+		os.RemoveAll(signaturesDir)
+	}
+}
+
+// writeSignature signs the payload of the given repository and digest, and
+// writes the signature as the nth signature of the digest
+func writeSignature(t *testing.T, signaturesDir string, signer crypto.Signer, repository string, n int) {
+	hash := sha256.Sum256([]byte(repository + "@" + testDigest))
+	signature, err := signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+	require.NoError(t, err)
+	dir := filepath.Join(signaturesDir, filepath.FromSlash(repository)+"@"+testDigestPath)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("signature-%d", n)),
+		[]byte(base64.StdEncoding.EncodeToString(signature)+"\n"), 0644))
+}
+
+func TestPolicyVerifySignature(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	for name, signer := range map[string]crypto.Signer{"rsa": rsaKey, "ecdsa": ecdsaKey} {
+		t.Run(name, func(t *testing.T) {
+			keysDir, signaturesDir, cleanup := setupSignatureDirs(t, rsaKey, ecdsaKey)
+			defer cleanup()
+			writeSignature(t, signaturesDir, signer, "docker.io/library/busybox", 1)
+			policy := NewPolicy(&config.Config{
+				ImagePolicyPublicKeysDir: keysDir,
+				ImagePolicySignaturesDir: signaturesDir,
+			})
+			require.True(t, policy.VerifiesSignatures())
+
+			assert.NoError(t, policy.Check("busybox@"+testDigest, nil))
+			// Images referenced by tag are verified with the digests they were
+			// pulled with
+			assert.NoError(t, policy.Check("busybox:1.31", []string{"busybox@" + testDigest}))
+			assert.Error(t, policy.Check("busybox:1.31", nil))
+			// Signatures are bound to the repository
+			assert.Error(t, policy.Check("amazon/busybox@"+testDigest, nil))
+		})
+	}
+}
+
+func TestPolicyVerifySignatureUnknownKey(t *testing.T) {
+	trustedKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	untrustedKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keysDir, signaturesDir, cleanup := setupSignatureDirs(t, trustedKey)
+	defer cleanup()
+	policy := NewPolicy(&config.Config{
+		ImagePolicyPublicKeysDir: keysDir,
+		ImagePolicySignaturesDir: signaturesDir,
+	})
+
+	writeSignature(t, signaturesDir, untrustedKey, "docker.io/library/busybox", 1)
+	assert.Error(t, policy.Check("busybox@"+testDigest, nil))
+
+	// Any of the signatures can be valid
+	writeSignature(t, signaturesDir, trustedKey, "docker.io/library/busybox", 2)
+	assert.NoError(t, policy.Check("busybox@"+testDigest, nil))
+}
+
+func TestPolicyVerifySignatureNoPublicKeys(t *testing.T) {
+	keysDir, signaturesDir, cleanup := setupSignatureDirs(t)
+	defer cleanup()
+	policy := NewPolicy(&config.Config{
+		ImagePolicyPublicKeysDir: keysDir,
+		ImagePolicySignaturesDir: signaturesDir,
+	})
+	assert.Error(t, policy.Check("busybox@"+testDigest, nil))
+}
@@ -0,0 +1,203 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package imagepolicy
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cihub/seelog"
+	"github.com/docker/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+const (
+	// signatureFilePrefix is the prefix of the name of signature files,
+	// which are numbered from 1
+	signatureFilePrefix = "signature-"
+
+	// maxSignatures is the maximum number of signatures looked up for an
+	// image digest
+	maxSignatures = 16
+)
+
+// ecdsaSignature is the ASN.1 structure of ECDSA signatures
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// verifySignature returns an error if none of the digests of the image has a
+// signature that can be verified with the public keys. The signed payload is
+// the repository name followed by the digest, such as
+// "docker.io/library/busybox@sha256:...", so that a signature can't be used
+// for another repository.
+func (policy *Policy) verifySignature(named reference.Named, repoDigests []string) error {
+	keys, err := loadPublicKeys(policy.publicKeysDir)
+	if err != nil {
+		return err
+	}
+	digests := imageDigests(named, repoDigests)
+	if len(digests) == 0 {
+		return fmt.Errorf("unable to verify signature of image %s: no digest found", named.String())
+	}
+	for _, imageDigest := range digests {
+		signatures, err := policy.readSignatures(named.Name(), imageDigest)
+		if err != nil {
+			return err
+		}
+		payload := []byte(named.Name() + "@" + imageDigest.String())
+		for _, signature := range signatures {
+			for _, key := range keys {
+				if verify(key, payload, signature) {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("image %s has no valid signature", named.String())
+}
+
+// imageDigests returns the digest of the image if it's referenced by digest,
+// or the digests it was pulled with otherwise
+func imageDigests(named reference.Named, repoDigests []string) []digest.Digest {
+	if digested, ok := named.(reference.Digested); ok {
+		return []digest.Digest{digested.Digest()}
+	}
+	var digests []digest.Digest
+	seen := make(map[digest.Digest]struct{})
+	for _, repoDigest := range repoDigests {
+		repoNamed, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil {
+			continue
+		}
+		digested, ok := repoNamed.(reference.Digested)
+		if !ok {
+			continue
+		}
+		if _, ok := seen[digested.Digest()]; ok {
+			continue
+		}
+		seen[digested.Digest()] = struct{}{}
+		digests = append(digests, digested.Digest())
+	}
+	return digests
+}
+
+// readSignatures reads the signatures of a repository digest, stored in the
+// <repository>@<algorithm>=<digest> directory of the signatures directory
+func (policy *Policy) readSignatures(name string, imageDigest digest.Digest) ([][]byte, error) {
+	dir := filepath.Join(policy.signaturesDir,
+		filepath.FromSlash(name)+"@"+imageDigest.Algorithm().String()+"="+imageDigest.Hex())
+	var signatures [][]byte
+	for i := 1; i <= maxSignatures; i++ {
+		file := filepath.Join(dir, fmt.Sprintf("%s%d", signatureFilePrefix, i))
+		data, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read signature %s", file)
+		}
+		signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			seelog.Warnf("Image policy: ignoring signature %s that isn't base64 encoded: %v", file, err)
+			continue
+		}
+		signatures = append(signatures, signature)
+	}
+	return signatures, nil
+}
+
+// loadPublicKeys loads the PEM encoded RSA and ECDSA public keys of the files
+// of the directory. Keys are loaded for every check, so that they can be
+// rotated without restarting the Agent.
+func loadPublicKeys(dir string) ([]crypto.PublicKey, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read public keys directory %s", dir)
+	}
+	var keys []crypto.PublicKey
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read public key %s", file.Name())
+		}
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			key, err := parsePublicKey(block)
+			if err != nil {
+				seelog.Warnf("Image policy: ignoring key of %s: %v", file.Name(), err)
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no public key found in %s", dir)
+	}
+	return keys, nil
+}
+
+func parsePublicKey(block *pem.Block) (crypto.PublicKey, error) {
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			return key, nil
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %s", block.Type)
+	}
+}
+
+// verify returns true if the signature is a valid RSA PKCS #1 v1.5 or ASN.1
+// encoded ECDSA signature of the SHA-256 hash of the payload
+func verify(key crypto.PublicKey, payload []byte, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) == nil
+	case *ecdsa.PublicKey:
+		var sig ecdsaSignature
+		if rest, err := asn1.Unmarshal(signature, &sig); err != nil || len(rest) != 0 {
+			return false
+		}
+		return sig.R != nil && sig.S != nil && ecdsa.Verify(key, hash[:], sig.R, sig.S)
+	default:
+		return false
+	}
+}
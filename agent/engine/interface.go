@@ -19,6 +19,7 @@ import (
 	"context"
 
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/logger/audit"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 )
@@ -37,6 +38,12 @@ type TaskEngine interface {
 	// running or stopped, as well as providing portbinding and other metadata
 	StateChangeEvents() chan statechange.Event
 	SetSaver(statemanager.Saver)
+	// SetAuditLogger sets the audit logger that the decisions of the task
+	// admission policy are recorded with
+	SetAuditLogger(audit.AuditLogger)
+	// SetContainerHealthListener sets the listener that is notified of the
+	// changes of the health status of containers
+	SetContainerHealthListener(statechange.Listener)
 
 	// AddTask adds a new task to the task engine and manages its container's
 	// lifecycle. If it returns an error, the task was not added.
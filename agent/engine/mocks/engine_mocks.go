@@ -25,6 +25,7 @@ import (
 	container "github.com/aws/amazon-ecs-agent/agent/api/container"
 	task "github.com/aws/amazon-ecs-agent/agent/api/task"
 	image "github.com/aws/amazon-ecs-agent/agent/engine/image"
+	audit "github.com/aws/amazon-ecs-agent/agent/logger/audit"
 	statechange "github.com/aws/amazon-ecs-agent/agent/statechange"
 	statemanager "github.com/aws/amazon-ecs-agent/agent/statemanager"
 	gomock "github.com/golang/mock/gomock"
@@ -148,6 +149,30 @@ func (mr *MockTaskEngineMockRecorder) MustInit(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MustInit", reflect.TypeOf((*MockTaskEngine)(nil).MustInit), arg0)
 }
 
+// SetAuditLogger mocks base method
+func (m *MockTaskEngine) SetAuditLogger(arg0 audit.AuditLogger) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetAuditLogger", arg0)
+}
+
+// SetAuditLogger indicates an expected call of SetAuditLogger
+func (mr *MockTaskEngineMockRecorder) SetAuditLogger(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAuditLogger", reflect.TypeOf((*MockTaskEngine)(nil).SetAuditLogger), arg0)
+}
+
+// SetContainerHealthListener mocks base method
+func (m *MockTaskEngine) SetContainerHealthListener(arg0 statechange.Listener) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetContainerHealthListener", arg0)
+}
+
+// SetContainerHealthListener indicates an expected call of SetContainerHealthListener
+func (mr *MockTaskEngineMockRecorder) SetContainerHealthListener(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetContainerHealthListener", reflect.TypeOf((*MockTaskEngine)(nil).SetContainerHealthListener), arg0)
+}
+
 // SetSaver mocks base method
 func (m *MockTaskEngine) SetSaver(arg0 statemanager.Saver) {
 	m.ctrl.T.Helper()
@@ -253,6 +278,20 @@ func (mr *MockImageManagerMockRecorder) GetImageStateFromImageName(arg0 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageStateFromImageName", reflect.TypeOf((*MockImageManager)(nil).GetImageStateFromImageName), arg0)
 }
 
+// GetPinnedImages mocks base method
+func (m *MockImageManager) GetPinnedImages() []image.PinnedImage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPinnedImages")
+	ret0, _ := ret[0].([]image.PinnedImage)
+	return ret0
+}
+
+// GetPinnedImages indicates an expected call of GetPinnedImages
+func (mr *MockImageManagerMockRecorder) GetPinnedImages() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPinnedImages", reflect.TypeOf((*MockImageManager)(nil).GetPinnedImages))
+}
+
 // RecordContainerReference mocks base method
 func (m *MockImageManager) RecordContainerReference(arg0 *container.Container) error {
 	m.ctrl.T.Helper()
@@ -304,3 +343,27 @@ func (mr *MockImageManagerMockRecorder) StartImageCleanupProcess(arg0 interface{
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartImageCleanupProcess", reflect.TypeOf((*MockImageManager)(nil).StartImageCleanupProcess), arg0)
 }
+
+// TriggerImageCleanup mocks base method
+func (m *MockImageManager) TriggerImageCleanup() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "TriggerImageCleanup")
+}
+
+// TriggerImageCleanup indicates an expected call of TriggerImageCleanup
+func (mr *MockImageManagerMockRecorder) TriggerImageCleanup() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TriggerImageCleanup", reflect.TypeOf((*MockImageManager)(nil).TriggerImageCleanup))
+}
+
+// UpdatePinnedImage mocks base method
+func (m *MockImageManager) UpdatePinnedImage(arg0 image.PinnedImage) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatePinnedImage", arg0)
+}
+
+// UpdatePinnedImage indicates an expected call of UpdatePinnedImage
+func (mr *MockImageManagerMockRecorder) UpdatePinnedImage(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePinnedImage", reflect.TypeOf((*MockImageManager)(nil).UpdatePinnedImage), arg0)
+}
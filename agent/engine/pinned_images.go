@@ -0,0 +1,114 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
+	"github.com/cihub/seelog"
+)
+
+const (
+	pinnedImagePullAttempts        = 3
+	pinnedImagePullRetryMinDelay   = 5 * time.Second
+	pinnedImagePullRetryMaxDelay   = 30 * time.Second
+	pinnedImagePullRetryJitter     = 0.2
+	pinnedImagePullRetryMultiplier = 2
+)
+
+var (
+	_pinnedImagePullAttempts      = pinnedImagePullAttempts
+	_pinnedImagePullRetryMinDelay = pinnedImagePullRetryMinDelay
+)
+
+// pullPinnedImages pulls the images pinned on the instance one after the other
+// and records the outcome in the image manager
+func (engine *DockerTaskEngine) pullPinnedImages(ctx context.Context) {
+	for _, pinnedImage := range engine.imageManager.GetPinnedImages() {
+		if ctx.Err() != nil {
+			return
+		}
+		engine.imageManager.UpdatePinnedImage(engine.pullPinnedImage(ctx, pinnedImage.Name))
+	}
+}
+
+// pullPinnedImage pulls a pinned image through the same path as the images of
+// task containers, unless the image is cached and the pull behavior prefers
+// cached images
+func (engine *DockerTaskEngine) pullPinnedImage(ctx context.Context, name string) image.PinnedImage {
+	pinnedImage := image.PinnedImage{Name: name}
+	if engine.cfg.ImagePullBehavior != config.ImagePullPreferCachedBehavior || !engine.isImageCached(name) {
+		seelog.Infof("Task engine: pulling pinned image %s", name)
+		var metadata dockerapi.DockerContainerMetadata
+		backoff := retry.NewExponentialBackoff(_pinnedImagePullRetryMinDelay, pinnedImagePullRetryMaxDelay,
+			pinnedImagePullRetryJitter, pinnedImagePullRetryMultiplier)
+		retry.RetryNWithBackoffCtx(ctx, backoff, _pinnedImagePullAttempts, func() error {
+			metadata = engine.pullPinnedImageAttempt(ctx, name)
+			if metadata.Error != nil {
+				seelog.Warnf("Task engine: unable to pull pinned image %s: %v", name, metadata.Error)
+				return metadata.Error
+			}
+			return nil
+		})
+		if metadata.Error != nil {
+			pinnedImage.Status = image.PinnedImageFailed
+			pinnedImage.Error = metadata.Error.Error()
+			return pinnedImage
+		}
+	}
+
+	inspected, err := engine.client.InspectImage(name)
+	if err != nil {
+		seelog.Errorf("Task engine: unable to inspect pinned image %s: %v", name, err)
+		pinnedImage.Status = image.PinnedImageFailed
+		pinnedImage.Error = err.Error()
+		return pinnedImage
+	}
+	seelog.Infof("Task engine: pinned image %s is available as %s", name, inspected.ID)
+	pinnedImage.Status = image.PinnedImagePulled
+	pinnedImage.ImageID = inspected.ID
+	pinnedImage.PulledAt = engine.time().Now()
+	return pinnedImage
+}
+
+// pullPinnedImageAttempt makes one attempt at pulling a pinned image. The
+// ImagePullDeleteLock is only held during the attempt, so that images can be
+// deleted while the pull backs off before the next attempt.
+func (engine *DockerTaskEngine) pullPinnedImageAttempt(ctx context.Context, name string) dockerapi.DockerContainerMetadata {
+	seelog.Debugf("Task engine: attempting to obtain ImagePullDeleteLock to pull pinned image %s", name)
+	ImagePullDeleteLock.RLock()
+	seelog.Debugf("Task engine: acquired ImagePullDeleteLock, start pulling pinned image %s", name)
+	defer seelog.Debugf("Task engine: released ImagePullDeleteLock after pulling pinned image %s", name)
+	defer ImagePullDeleteLock.RUnlock()
+
+	// Pinned images don't belong to a task, so they are pulled without
+	// registry authentication data, which makes the docker client get the
+	// credentials of the image from the dockerauth provider
+	return engine.pullScheduler.pull(ctx, pullRequest{image: name},
+		func() dockerapi.DockerContainerMetadata {
+			return engine.client.PullImage(ctx, name, nil, dockerclient.PullImageTimeout)
+		})
+}
+
+// isImageCached returns true if the image is present on the instance
+func (engine *DockerTaskEngine) isImageCached(name string) bool {
+	_, err := engine.client.InspectImage(name)
+	return err == nil
+}
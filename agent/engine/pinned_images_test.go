@@ -0,0 +1,181 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	mock_ttime "github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
+
+	"github.com/docker/docker/api/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPinnedImage    = "busybox:1.31"
+	testPinnedECRImage = "123456789012.dkr.ecr.us-west-2.amazonaws.com/pinned@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+)
+
+func TestPullPinnedImages(t *testing.T) {
+	defer func(attempts int) {
+		_pinnedImagePullAttempts = attempts
+	}(_pinnedImagePullAttempts)
+	_pinnedImagePullAttempts = 1
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	mockTime := mock_ttime.NewMockTime(ctrl)
+	cfg := &config.Config{PinnedImages: []string{testPinnedImage, testPinnedECRImage}}
+	imageManager := NewImageManager(cfg, client, dockerstate.NewTaskEngineState())
+	engine := &DockerTaskEngine{
+		pullScheduler: newPullScheduler(0, nil),
+		cfg:           cfg,
+		client:        client,
+		imageManager:  imageManager,
+		_time:         mockTime,
+	}
+	pulledAt := time.Now()
+
+	client.EXPECT().PullImage(gomock.Any(), testPinnedImage, nil, dockerclient.PullImageTimeout).Return(dockerapi.DockerContainerMetadata{})
+	client.EXPECT().InspectImage(testPinnedImage).Return(&types.ImageInspect{ID: "sha256:busybox"}, nil)
+	mockTime.EXPECT().Now().Return(pulledAt)
+	client.EXPECT().PullImage(gomock.Any(), testPinnedECRImage, nil, dockerclient.PullImageTimeout).Return(
+		dockerapi.DockerContainerMetadata{Error: dockerapi.CannotPullContainerError{FromError: errors.New("denied")}})
+
+	engine.pullPinnedImages(context.TODO())
+
+	pinnedImages := engine.PinnedImages()
+	require.Len(t, pinnedImages, 2)
+	assert.Equal(t, image.PinnedImage{
+		Name:     testPinnedImage,
+		ImageID:  "sha256:busybox",
+		Status:   image.PinnedImagePulled,
+		PulledAt: pulledAt,
+	}, pinnedImages[0])
+	assert.Equal(t, image.PinnedImageFailed, pinnedImages[1].Status)
+	assert.Contains(t, pinnedImages[1].Error, "denied")
+}
+
+func TestPullPinnedImageReleasesLockBetweenAttempts(t *testing.T) {
+	defer func(attempts int, delay time.Duration) {
+		_pinnedImagePullAttempts = attempts
+		_pinnedImagePullRetryMinDelay = delay
+	}(_pinnedImagePullAttempts, _pinnedImagePullRetryMinDelay)
+	_pinnedImagePullAttempts = 2
+	_pinnedImagePullRetryMinDelay = time.Millisecond
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	mockTime := mock_ttime.NewMockTime(ctrl)
+	engine := &DockerTaskEngine{
+		pullScheduler: newPullScheduler(0, nil),
+		cfg:           &config.Config{},
+		client:        client,
+		_time:         mockTime,
+	}
+
+	// An image deletion waiting for the lock during the first attempt goes
+	// ahead of the second attempt
+	deleted := make(chan struct{})
+	gomock.InOrder(
+		client.EXPECT().PullImage(gomock.Any(), testPinnedImage, nil, dockerclient.PullImageTimeout).Do(
+			func(interface{}, string, interface{}, interface{}) {
+				go func() {
+					ImagePullDeleteLock.Lock()
+					close(deleted)
+					ImagePullDeleteLock.Unlock()
+				}()
+			}).Return(dockerapi.DockerContainerMetadata{
+			Error: dockerapi.CannotPullContainerError{FromError: errors.New("timeout")}}),
+		client.EXPECT().PullImage(gomock.Any(), testPinnedImage, nil, dockerclient.PullImageTimeout).Do(
+			func(interface{}, string, interface{}, interface{}) {
+				select {
+				case <-deleted:
+				case <-time.After(time.Second):
+					t.Error("expected the lock to be released between the attempts")
+				}
+			}).Return(dockerapi.DockerContainerMetadata{}),
+	)
+	client.EXPECT().InspectImage(testPinnedImage).Return(&types.ImageInspect{ID: "sha256:busybox"}, nil)
+	mockTime.EXPECT().Now().Return(time.Now())
+
+	pinnedImage := engine.pullPinnedImage(context.TODO(), testPinnedImage)
+	assert.Equal(t, image.PinnedImagePulled, pinnedImage.Status)
+}
+
+func TestPullPinnedImagePreferCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	mockTime := mock_ttime.NewMockTime(ctrl)
+	engine := &DockerTaskEngine{
+		pullScheduler: newPullScheduler(0, nil),
+		cfg:           &config.Config{ImagePullBehavior: config.ImagePullPreferCachedBehavior},
+		client:        client,
+		_time:         mockTime,
+	}
+
+	client.EXPECT().InspectImage(testPinnedImage).Return(&types.ImageInspect{ID: "sha256:busybox"}, nil).Times(2)
+	mockTime.EXPECT().Now().Return(time.Now())
+
+	pinnedImage := engine.pullPinnedImage(context.TODO(), testPinnedImage)
+	assert.Equal(t, image.PinnedImagePulled, pinnedImage.Status)
+	assert.Equal(t, "sha256:busybox", pinnedImage.ImageID)
+}
+
+func TestPinnedImagesExcludedFromCleanup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_dockerapi.NewMockDockerClient(ctrl)
+	imageManager := NewImageManager(&config.Config{PinnedImages: []string{"busybox", testPinnedECRImage, "pulled"}},
+		client, dockerstate.NewTaskEngineState()).(*dockerImageManager)
+	imageManager.UpdatePinnedImage(image.PinnedImage{Name: "pulled", ImageID: "sha256:pulled", Status: image.PinnedImagePulled})
+
+	imageStates := []*image.ImageState{
+		{Image: &image.Image{ImageID: "sha256:busybox", Names: []string{"docker.io/library/busybox:latest"}}},
+		{Image: &image.Image{ImageID: "sha256:pulled", Names: []string{"retagged"}}},
+		{Image: &image.Image{ImageID: "sha256:other", Names: []string{"busybox:1.31"}}},
+	}
+	consideredForDeletion := imageManager.imagesConsiderForDeletion(imageStates)
+	assert.Len(t, consideredForDeletion, 1)
+	assert.Contains(t, consideredForDeletion, "sha256:other")
+
+	client.EXPECT().ListImages(gomock.Any(), dockerclient.ListImagesTimeout).Return(dockerapi.ListImagesResponse{
+		ImageIDs: []string{"sha256:ecr", "sha256:untracked"},
+	})
+	client.EXPECT().InspectImage("sha256:ecr").Return(&types.ImageInspect{
+		RepoDigests: []string{testPinnedECRImage},
+	}, nil)
+	client.EXPECT().InspectImage("sha256:untracked").Return(&types.ImageInspect{
+		RepoTags: []string{"untracked:latest"},
+	}, nil)
+	nonECSImages := imageManager.getNonECSImages(context.TODO())
+	require.Len(t, nonECSImages, 1)
+	assert.Equal(t, "sha256:untracked", nonECSImages[0].ImageID)
+}
@@ -0,0 +1,242 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
+	"github.com/cihub/seelog"
+	"github.com/docker/distribution/reference"
+)
+
+// pullRequest describes an image pull to be scheduled
+type pullRequest struct {
+	// image is the name of the image to pull
+	image string
+	// authKey identifies the credentials used for the pull. Pulls of the same
+	// image are only merged when they use the same credentials, so that a task
+	// never gets an image pulled on its behalf with credentials it doesn't have.
+	authKey string
+	// essential is true if the image is needed by an essential container
+	essential bool
+}
+
+func (request pullRequest) key() string {
+	return request.image + "|" + request.authKey
+}
+
+// inflightPull is a pull in progress, which pulls of the same image wait for
+type inflightPull struct {
+	done     chan struct{}
+	metadata dockerapi.DockerContainerMetadata
+}
+
+// registryPulls tracks the pulls running and waiting for a registry host
+type registryPulls struct {
+	running int
+	waiting []*pullWaiter
+}
+
+type pullWaiter struct {
+	essential bool
+	ready     chan struct{}
+}
+
+// pullScheduler merges pulls of the same image requested by several tasks at
+// the same time, and caps the number of pulls running at the same time for a
+// registry host. Pulls over the cap are queued, the ones of essential
+// containers ahead of the others. Images pulled from a mirror of their
+// registry count towards the cap of the mirror host.
+type pullScheduler struct {
+	maxPerRegistry int
+	mirrors        map[string]config.ImagePullMirror
+	lock           sync.Mutex
+	inflight       map[string]*inflightPull
+	registries     map[string]*registryPulls
+}
+
+// newPullScheduler creates a pullScheduler running at most maxPerRegistry
+// pulls at the same time for a registry host, or any number of them if
+// maxPerRegistry is 0. The images of the registries that have mirrors are
+// pulled from the mirrors.
+func newPullScheduler(maxPerRegistry int, mirrors map[string]config.ImagePullMirror) *pullScheduler {
+	return &pullScheduler{
+		maxPerRegistry: maxPerRegistry,
+		mirrors:        mirrors,
+		inflight:       make(map[string]*inflightPull),
+		registries:     make(map[string]*registryPulls),
+	}
+}
+
+// pull runs pullImage once a pull for the registry of the image can be
+// started, or waits for the result of the same pull requested before
+func (scheduler *pullScheduler) pull(ctx context.Context, request pullRequest,
+	pullImage func() dockerapi.DockerContainerMetadata) dockerapi.DockerContainerMetadata {
+	scheduler.lock.Lock()
+	if inflight, ok := scheduler.inflight[request.key()]; ok {
+		scheduler.lock.Unlock()
+		seelog.Infof("Pull scheduler: waiting for the pull of image %s already in progress", request.image)
+		select {
+		case <-inflight.done:
+			return inflight.metadata
+		case <-ctx.Done():
+			return dockerapi.DockerContainerMetadata{Error: dockerapi.CannotPullContainerError{FromError: ctx.Err()}}
+		}
+	}
+	inflight := &inflightPull{done: make(chan struct{})}
+	scheduler.inflight[request.key()] = inflight
+	scheduler.lock.Unlock()
+
+	defer func() {
+		scheduler.lock.Lock()
+		delete(scheduler.inflight, request.key())
+		scheduler.lock.Unlock()
+		close(inflight.done)
+	}()
+
+	registry := scheduler.pullHost(request.image)
+	if err := scheduler.acquire(ctx, registry, request.essential); err != nil {
+		inflight.metadata = dockerapi.DockerContainerMetadata{Error: dockerapi.CannotPullContainerError{FromError: err}}
+		return inflight.metadata
+	}
+	defer scheduler.release(registry)
+	inflight.metadata = pullImage()
+	return inflight.metadata
+}
+
+// acquire waits until a pull can be started for the registry
+func (scheduler *pullScheduler) acquire(ctx context.Context, registry string, essential bool) error {
+	start := time.Now()
+	scheduler.lock.Lock()
+	pulls := scheduler.registryPulls(registry)
+	if scheduler.maxPerRegistry == 0 || (pulls.running < scheduler.maxPerRegistry && len(pulls.waiting) == 0) {
+		pulls.running++
+		scheduler.lock.Unlock()
+		metrics.MetricsEngineGlobal.RecordImagePullWait(registry, 0)
+		return nil
+	}
+
+	waiter := &pullWaiter{essential: essential, ready: make(chan struct{})}
+	position := len(pulls.waiting)
+	if essential {
+		// Essential pulls go after the essential ones already waiting, but
+		// ahead of all the others
+		position = 0
+		for position < len(pulls.waiting) && pulls.waiting[position].essential {
+			position++
+		}
+	}
+	pulls.waiting = append(pulls.waiting, nil)
+	copy(pulls.waiting[position+1:], pulls.waiting[position:])
+	pulls.waiting[position] = waiter
+	metrics.MetricsEngineGlobal.RecordImagePullQueueDepth(registry, len(pulls.waiting))
+	seelog.Infof("Pull scheduler: %d pulls running for registry %s, queueing pull at position %d",
+		pulls.running, registry, position+1)
+	scheduler.lock.Unlock()
+
+	select {
+	case <-waiter.ready:
+		metrics.MetricsEngineGlobal.RecordImagePullWait(registry, time.Since(start))
+		return nil
+	case <-ctx.Done():
+		scheduler.lock.Lock()
+		defer scheduler.lock.Unlock()
+		for i, queued := range pulls.waiting {
+			if queued == waiter {
+				pulls.waiting = append(pulls.waiting[:i], pulls.waiting[i+1:]...)
+				metrics.MetricsEngineGlobal.RecordImagePullQueueDepth(registry, len(pulls.waiting))
+				return ctx.Err()
+			}
+		}
+		// The pull was started at the same time as the context was done, hand
+		// it over to the next one
+		scheduler.releaseLocked(registry)
+		return ctx.Err()
+	}
+}
+
+// release hands the pull slot over to the next pull waiting for the registry
+func (scheduler *pullScheduler) release(registry string) {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	scheduler.releaseLocked(registry)
+}
+
+func (scheduler *pullScheduler) releaseLocked(registry string) {
+	pulls := scheduler.registryPulls(registry)
+	if len(pulls.waiting) == 0 {
+		pulls.running--
+		if pulls.running == 0 {
+			delete(scheduler.registries, registry)
+		}
+		return
+	}
+	next := pulls.waiting[0]
+	pulls.waiting = pulls.waiting[1:]
+	metrics.MetricsEngineGlobal.RecordImagePullQueueDepth(registry, len(pulls.waiting))
+	close(next.ready)
+}
+
+func (scheduler *pullScheduler) registryPulls(registry string) *registryPulls {
+	pulls, ok := scheduler.registries[registry]
+	if !ok {
+		pulls = &registryPulls{}
+		scheduler.registries[registry] = pulls
+	}
+	return pulls
+}
+
+// pullHost returns the host that an image is pulled from, which is the host of
+// the mirror of its registry if it has one
+func (scheduler *pullScheduler) pullHost(image string) string {
+	if mirrorRepository, _, ok := dockerapi.ImagePullMirror(scheduler.mirrors, image); ok {
+		return registryHost(mirrorRepository)
+	}
+	return registryHost(image)
+}
+
+// registryHost returns the host of the registry an image is pulled from
+func registryHost(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return reference.Domain(named)
+}
+
+// registryAuthKey identifies the credentials used to pull an image with the
+// given registry authentication data
+func registryAuthKey(authData *apicontainer.RegistryAuthenticationData) string {
+	if authData == nil {
+		return ""
+	}
+	switch {
+	case authData.Type == apicontainer.AuthTypeECR && authData.ECRAuthData != nil:
+		ecrAuthData := authData.ECRAuthData
+		return strings.Join([]string{authData.Type, ecrAuthData.Region, ecrAuthData.EndpointOverride,
+			ecrAuthData.RegistryID, ecrAuthData.GetPullCredentials().RoleArn}, "|")
+	case authData.Type == apicontainer.AuthTypeASM && authData.ASMAuthData != nil:
+		return strings.Join([]string{authData.Type, authData.ASMAuthData.Region,
+			authData.ASMAuthData.CredentialsParameter}, "|")
+	default:
+		return authData.Type
+	}
+}
@@ -0,0 +1,232 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForQueuedPulls waits until the given number of pulls are waiting for
+// the registry
+func waitForQueuedPulls(t *testing.T, scheduler *pullScheduler, registry string, queued int) {
+	for i := 0; i < 500; i++ {
+		scheduler.lock.Lock()
+		pulls, ok := scheduler.registries[registry]
+		done := ok && len(pulls.waiting) == queued
+		scheduler.lock.Unlock()
+		if done {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%d pulls were never queued for registry %s", queued, registry)
+}
+
+func TestPullSchedulerMergesPullsOfTheSameImage(t *testing.T) {
+	scheduler := newPullScheduler(0, nil)
+	request := pullRequest{image: "busybox", authKey: "ecr|role"}
+	pullStarted := make(chan struct{})
+	finishPull := make(chan struct{})
+	pullError := dockerapi.CannotPullContainerError{FromError: errors.New("pull failed")}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		metadata := scheduler.pull(context.TODO(), request, func() dockerapi.DockerContainerMetadata {
+			close(pullStarted)
+			<-finishPull
+			return dockerapi.DockerContainerMetadata{Error: pullError}
+		})
+		assert.Equal(t, pullError, metadata.Error)
+	}()
+	<-pullStarted
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		metadata := scheduler.pull(context.TODO(), request, func() dockerapi.DockerContainerMetadata {
+			t.Error("pull of the same image should have been merged")
+			return dockerapi.DockerContainerMetadata{}
+		})
+		assert.Equal(t, pullError, metadata.Error)
+	}()
+	// Give the second pull time to find the one in progress
+	time.Sleep(100 * time.Millisecond)
+	close(finishPull)
+	wg.Wait()
+	assert.Empty(t, scheduler.inflight)
+}
+
+func TestPullSchedulerDoesNotMergePullsWithDifferentCredentials(t *testing.T) {
+	scheduler := newPullScheduler(0, nil)
+	pullStarted := make(chan struct{})
+	finishPull := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.pull(context.TODO(), pullRequest{image: "busybox", authKey: "ecr|role1"}, func() dockerapi.DockerContainerMetadata {
+			close(pullStarted)
+			<-finishPull
+			return dockerapi.DockerContainerMetadata{}
+		})
+	}()
+	<-pullStarted
+
+	pulled := false
+	scheduler.pull(context.TODO(), pullRequest{image: "busybox", authKey: "ecr|role2"}, func() dockerapi.DockerContainerMetadata {
+		pulled = true
+		return dockerapi.DockerContainerMetadata{}
+	})
+	assert.True(t, pulled)
+	close(finishPull)
+	wg.Wait()
+}
+
+func TestPullSchedulerLimitsPullsPerRegistryAndPrioritizesEssential(t *testing.T) {
+	scheduler := newPullScheduler(1, nil)
+	pullStarted := make(chan struct{})
+	finishPull := make(chan struct{})
+
+	var wg sync.WaitGroup
+	var orderLock sync.Mutex
+	var order []string
+	pull := func(request pullRequest) {
+		defer wg.Done()
+		scheduler.pull(context.TODO(), request, func() dockerapi.DockerContainerMetadata {
+			orderLock.Lock()
+			order = append(order, request.image)
+			orderLock.Unlock()
+			return dockerapi.DockerContainerMetadata{}
+		})
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.pull(context.TODO(), pullRequest{image: "registry.example.com/first"}, func() dockerapi.DockerContainerMetadata {
+			close(pullStarted)
+			<-finishPull
+			return dockerapi.DockerContainerMetadata{}
+		})
+	}()
+	<-pullStarted
+
+	// Images from other registries are not held back
+	pulled := false
+	scheduler.pull(context.TODO(), pullRequest{image: "busybox"}, func() dockerapi.DockerContainerMetadata {
+		pulled = true
+		return dockerapi.DockerContainerMetadata{}
+	})
+	assert.True(t, pulled)
+
+	wg.Add(3)
+	go pull(pullRequest{image: "registry.example.com/nonessential"})
+	waitForQueuedPulls(t, scheduler, "registry.example.com", 1)
+	go pull(pullRequest{image: "registry.example.com/essential1", essential: true})
+	waitForQueuedPulls(t, scheduler, "registry.example.com", 2)
+	go pull(pullRequest{image: "registry.example.com/essential2", essential: true})
+	waitForQueuedPulls(t, scheduler, "registry.example.com", 3)
+
+	close(finishPull)
+	wg.Wait()
+	assert.Equal(t, []string{
+		"registry.example.com/essential1",
+		"registry.example.com/essential2",
+		"registry.example.com/nonessential",
+	}, order)
+	assert.Empty(t, scheduler.registries)
+}
+
+func TestPullSchedulerQueuedPullCanceled(t *testing.T) {
+	scheduler := newPullScheduler(1, nil)
+	pullStarted := make(chan struct{})
+	finishPull := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.pull(context.TODO(), pullRequest{image: "busybox:1"}, func() dockerapi.DockerContainerMetadata {
+			close(pullStarted)
+			<-finishPull
+			return dockerapi.DockerContainerMetadata{}
+		})
+	}()
+	<-pullStarted
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		metadata := scheduler.pull(ctx, pullRequest{image: "busybox:2"}, func() dockerapi.DockerContainerMetadata {
+			t.Error("canceled pull should not be started")
+			return dockerapi.DockerContainerMetadata{}
+		})
+		assert.Error(t, metadata.Error)
+	}()
+	waitForQueuedPulls(t, scheduler, "docker.io", 1)
+	cancel()
+	waitForQueuedPulls(t, scheduler, "docker.io", 0)
+
+	close(finishPull)
+	wg.Wait()
+	assert.Empty(t, scheduler.registries)
+}
+
+func TestRegistryHost(t *testing.T) {
+	assert.Equal(t, "docker.io", registryHost("busybox"))
+	assert.Equal(t, "docker.io", registryHost("amazon/amazon-ecs-agent:latest"))
+	assert.Equal(t, "localhost:5000", registryHost("localhost:5000/image"))
+	assert.Equal(t, "123456789012.dkr.ecr.us-west-2.amazonaws.com",
+		registryHost("123456789012.dkr.ecr.us-west-2.amazonaws.com/image@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"))
+}
+
+func TestPullSchedulerPullHost(t *testing.T) {
+	scheduler := newPullScheduler(1, map[string]config.ImagePullMirror{
+		"docker.io": {Endpoint: "mirror.example.com:5000/docker-hub"},
+	})
+	assert.Equal(t, "mirror.example.com:5000", scheduler.pullHost("busybox"))
+	assert.Equal(t, "mirror.example.com:5000", scheduler.pullHost("amazon/amazon-ecs-agent:latest"))
+	// Images referenced by digest aren't pulled from the mirror
+	assert.Equal(t, "docker.io", scheduler.pullHost("busybox@"+testImageDigest))
+	assert.Equal(t, "localhost:5000", scheduler.pullHost("localhost:5000/image"))
+}
+
+func TestRegistryAuthKey(t *testing.T) {
+	assert.Empty(t, registryAuthKey(nil))
+
+	ecrAuthData := &apicontainer.ECRAuthData{Region: "us-west-2", RegistryID: "123456789012"}
+	authData := &apicontainer.RegistryAuthenticationData{Type: apicontainer.AuthTypeECR, ECRAuthData: ecrAuthData}
+	require.Equal(t, "ecr|us-west-2||123456789012|", registryAuthKey(authData))
+
+	asmAuthData := &apicontainer.ASMAuthData{Region: "us-west-2", CredentialsParameter: "secret"}
+	authData = &apicontainer.RegistryAuthenticationData{Type: apicontainer.AuthTypeASM, ASMAuthData: asmAuthData}
+	assert.Equal(t, "asm|us-west-2|secret", registryAuthKey(authData))
+}
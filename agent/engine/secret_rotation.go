@@ -0,0 +1,100 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+
+	"github.com/cihub/seelog"
+)
+
+// rotateSecrets periodically refreshes the secrets vended as files of the
+// running tasks until the context is cancelled
+func (engine *DockerTaskEngine) rotateSecrets(ctx context.Context) {
+	ticker := time.NewTicker(engine.cfg.SecretRotationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			engine.rotateRunningTasksSecrets(ctx)
+		}
+	}
+}
+
+// rotateRunningTasksSecrets retrieves the values of the secrets vended as
+// files of the running tasks that aren't stopping, rewrites the files of those
+// that changed, and signals the containers using them if the engine is
+// configured to do so
+func (engine *DockerTaskEngine) rotateRunningTasksSecrets(ctx context.Context) {
+	for _, task := range engine.state.AllTasks() {
+		if task.GetDesiredStatus().Terminal() || task.GetKnownStatus() != apitaskstatus.TaskRunning {
+			continue
+		}
+		changed, err := task.RefreshSecretFiles()
+		if err != nil {
+			seelog.Warnf("Task engine [%s]: unable to rotate secrets: %v", task.Arn, err)
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		seelog.Infof("Task engine [%s]: rotated %d secrets", task.Arn, len(changed))
+		if engine.cfg.SecretRotationSignal != "" {
+			engine.signalSecretRotation(ctx, task, changed)
+		}
+	}
+}
+
+// signalSecretRotation sends the configured signal to the running containers
+// of the task using one of the rotated secrets
+func (engine *DockerTaskEngine) signalSecretRotation(ctx context.Context, task *apitask.Task,
+	rotated []apicontainer.Secret) {
+	containerMap, ok := engine.state.ContainerMapByArn(task.Arn)
+	if !ok {
+		return
+	}
+	usesRotatedSecret := func(s apicontainer.Secret) bool {
+		if s.Type != apicontainer.SecretTypeMountPoint {
+			return false
+		}
+		for _, rotatedSecret := range rotated {
+			if s.Provider == rotatedSecret.Provider &&
+				s.GetSecretResourceCacheKey() == rotatedSecret.GetSecretResourceCacheKey() {
+				return true
+			}
+		}
+		return false
+	}
+	for _, dockerContainer := range containerMap {
+		container := dockerContainer.Container
+		if container.GetKnownStatus() != apicontainerstatus.ContainerRunning || !container.HasSecret(usesRotatedSecret) {
+			continue
+		}
+		seelog.Infof("Task engine [%s]: sending %s to container %s after rotating its secrets",
+			task.Arn, engine.cfg.SecretRotationSignal, container.Name)
+		if err := engine.client.KillContainer(ctx, dockerContainer.DockerID, engine.cfg.SecretRotationSignal,
+			dockerclient.KillContainerTimeout); err != nil {
+			seelog.Warnf("Task engine [%s]: unable to send %s to container %s: %v",
+				task.Arn, engine.cfg.SecretRotationSignal, container.Name, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestSignalSecretRotation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	cfg := &config.Config{SecretRotationSignal: "SIGHUP"}
+	ctrl, client, _, privateTaskEngine, _, _, _ := mocks(t, ctx, cfg)
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+
+	rotated := apicontainer.Secret{
+		Name:      "db-password",
+		ValueFrom: "/db/password",
+		Region:    "us-west-2",
+		Provider:  apicontainer.SecretProviderSSM,
+		Type:      apicontainer.SecretTypeMountPoint,
+	}
+	unchanged := rotated
+	unchanged.Name = "api-key"
+	unchanged.ValueFrom = "/api/key"
+	asEnv := rotated
+	asEnv.Type = apicontainer.SecretTypeEnv
+
+	newContainer := func(name string, status apicontainerstatus.ContainerStatus, secret apicontainer.Secret) *apicontainer.Container {
+		container := &apicontainer.Container{Name: name, Secrets: []apicontainer.Secret{secret}}
+		container.SetKnownStatus(status)
+		return container
+	}
+	containers := []*apicontainer.Container{
+		newContainer("rotated", apicontainerstatus.ContainerRunning, rotated),
+		newContainer("stopped", apicontainerstatus.ContainerStopped, rotated),
+		newContainer("unchanged", apicontainerstatus.ContainerRunning, unchanged),
+		newContainer("env", apicontainerstatus.ContainerRunning, asEnv),
+	}
+	task := &apitask.Task{
+		Arn:                 "arn",
+		Containers:          containers,
+		DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+	}
+	taskEngine.state.AddTask(task)
+	for _, container := range containers {
+		taskEngine.state.AddContainer(&apicontainer.DockerContainer{
+			DockerID:  container.Name + "-id",
+			Container: container,
+		}, task)
+	}
+
+	client.EXPECT().KillContainer(gomock.Any(), "rotated-id", "SIGHUP", dockerclient.KillContainerTimeout).Return(nil)
+
+	taskEngine.signalSecretRotation(ctx, task, []apicontainer.Secret{rotated})
+}
@@ -0,0 +1,104 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apierrors "github.com/aws/amazon-ecs-agent/agent/api/errors"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine/admission"
+
+	"github.com/cihub/seelog"
+	dockercontainer "github.com/docker/docker/api/types/container"
+)
+
+const (
+	// admissionDecisionDenied is the decision recorded in the audit log for
+	// denied privileges
+	admissionDecisionDenied = "denied"
+	// admissionDecisionStripped is the decision recorded in the audit log
+	// for stripped privileges
+	admissionDecisionStripped = "stripped"
+	// admissionPolicyFileRule is the rule recorded in the audit log for
+	// containers denied because the policy couldn't be loaded
+	admissionPolicyFileRule = "PolicyFile"
+)
+
+// loadAdmissionPolicy loads the task admission policy, if one is configured
+func loadAdmissionPolicy(cfg *config.Config) (*admission.Policy, error) {
+	if cfg.TaskAdmissionPolicyFile == "" {
+		return nil, nil
+	}
+	policy, err := admission.LoadPolicy(cfg.TaskAdmissionPolicyFile)
+	if err != nil {
+		seelog.Criticalf("Task engine: unable to load the task admission policy, all containers will be denied: %v", err)
+		return nil, err
+	}
+	seelog.Infof("Task engine: loaded task admission policy %s", cfg.TaskAdmissionPolicyFile)
+	return policy, nil
+}
+
+// admitContainer checks the host config of the container against the task
+// admission policy before the container is created. Privileges stripped by
+// the policy are removed from the host config, and an error is returned if
+// any privilege is denied. Every decision is recorded in the audit log. All
+// containers are denied if the policy couldn't be loaded. The container map
+// holds the docker containers of the task, whose volumes the container can use.
+func (engine *DockerTaskEngine) admitContainer(task *apitask.Task, container *apicontainer.Container,
+	hostConfig *dockercontainer.HostConfig, containerMap map[string]*apicontainer.DockerContainer) apierrors.NamedError {
+	if container.IsInternal() {
+		return nil
+	}
+	if engine.admissionPolicyErr != nil {
+		engine.auditTaskAdmission(task, container, admissionPolicyFileRule, admissionDecisionDenied)
+		return TaskAdmissionDeniedError{fmt.Errorf("container %s: unable to load the task admission policy: %v",
+			container.Name, engine.admissionPolicyErr)}
+	}
+
+	var taskContainers []string
+	for name, dockerContainer := range containerMap {
+		if name != container.Name && dockerContainer.DockerName != "" {
+			taskContainers = append(taskContainers, dockerContainer.DockerName)
+		}
+	}
+	var denied []string
+	for _, decision := range engine.admissionPolicy.Evaluate(hostConfig, taskContainers) {
+		if decision.Denied() {
+			engine.auditTaskAdmission(task, container, decision.Rule, admissionDecisionDenied)
+			denied = append(denied, decision.Privilege)
+			continue
+		}
+		engine.auditTaskAdmission(task, container, decision.Rule, admissionDecisionStripped)
+		seelog.Warnf("Task engine [%s]: container %s: %s", task.Arn, container.Name, decision.String())
+	}
+	if len(denied) > 0 {
+		err := TaskAdmissionDeniedError{fmt.Errorf("container %s: %s denied by the task admission policy",
+			container.Name, strings.Join(denied, ", "))}
+		seelog.Errorf("Task engine [%s]: %v", task.Arn, err)
+		return err
+	}
+	return nil
+}
+
+func (engine *DockerTaskEngine) auditTaskAdmission(task *apitask.Task, container *apicontainer.Container,
+	rule string, decision string) {
+	if engine.auditLogger == nil {
+		return
+	}
+	engine.auditLogger.LogTaskAdmission(task.Arn, container.Name, rule, decision)
+}
@@ -0,0 +1,151 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine/admission"
+	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
+	mock_audit "github.com/aws/amazon-ecs-agent/agent/logger/audit/mocks"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmitContainerWithoutPolicy(t *testing.T) {
+	engine := &DockerTaskEngine{}
+	task := &apitask.Task{Arn: "arn"}
+	container := &apicontainer.Container{Name: "c"}
+	hostConfig := &dockercontainer.HostConfig{Privileged: true}
+
+	assert.Nil(t, engine.admitContainer(task, container, hostConfig, nil))
+	assert.True(t, hostConfig.Privileged)
+}
+
+func TestAdmitContainerDenied(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	auditLogger := mock_audit.NewMockAuditLogger(ctrl)
+
+	engine := &DockerTaskEngine{
+		admissionPolicy: &admission.Policy{
+			Privileged:   admission.Rule{Action: admission.ActionDeny},
+			Capabilities: admission.CapabilitiesRule{Action: admission.ActionStrip},
+		},
+		auditLogger: auditLogger,
+	}
+	task := &apitask.Task{Arn: "arn"}
+	container := &apicontainer.Container{Name: "c"}
+	hostConfig := &dockercontainer.HostConfig{Privileged: true, CapAdd: []string{"SYS_ADMIN"}}
+
+	gomock.InOrder(
+		auditLogger.EXPECT().LogTaskAdmission("arn", "c", admission.RulePrivileged, admissionDecisionDenied),
+		auditLogger.EXPECT().LogTaskAdmission("arn", "c", admission.RuleCapabilities, admissionDecisionStripped),
+	)
+	err := engine.admitContainer(task, container, hostConfig, nil)
+	require.Error(t, err)
+	assert.Equal(t, taskAdmissionDeniedErrorName, err.ErrorName())
+	assert.Contains(t, err.Error(), "privileged mode denied by the task admission policy")
+	assert.Empty(t, hostConfig.CapAdd)
+}
+
+func TestAdmitContainerStripped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	auditLogger := mock_audit.NewMockAuditLogger(ctrl)
+
+	engine := &DockerTaskEngine{
+		admissionPolicy: &admission.Policy{
+			HostPID: admission.Rule{Action: admission.ActionStrip},
+		},
+		auditLogger: auditLogger,
+	}
+	task := &apitask.Task{Arn: "arn"}
+	container := &apicontainer.Container{Name: "c"}
+	hostConfig := &dockercontainer.HostConfig{PidMode: "host"}
+
+	auditLogger.EXPECT().LogTaskAdmission("arn", "c", admission.RuleHostPID, admissionDecisionStripped)
+	assert.Nil(t, engine.admitContainer(task, container, hostConfig, nil))
+	assert.False(t, hostConfig.PidMode.IsHost())
+}
+
+func TestAdmitContainerVolumesFromTaskContainers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	auditLogger := mock_audit.NewMockAuditLogger(ctrl)
+
+	engine := &DockerTaskEngine{
+		admissionPolicy: &admission.Policy{
+			BindMounts: admission.HostPathRule{Action: admission.ActionStrip},
+		},
+		auditLogger: auditLogger,
+	}
+	task := &apitask.Task{Arn: "arn"}
+	container := &apicontainer.Container{Name: "c"}
+	containerMap := map[string]*apicontainer.DockerContainer{
+		"c":    {DockerName: "ecs-task-c"},
+		"data": {DockerName: "ecs-task-data"},
+	}
+	hostConfig := &dockercontainer.HostConfig{VolumesFrom: []string{"ecs-task-data", "ecs-task-c", "other"}}
+
+	auditLogger.EXPECT().LogTaskAdmission("arn", "c", admission.RuleBindMounts, admissionDecisionStripped).Times(2)
+	assert.Nil(t, engine.admitContainer(task, container, hostConfig, containerMap))
+	assert.Equal(t, []string{"ecs-task-data"}, hostConfig.VolumesFrom)
+}
+
+func TestAdmitContainerInternal(t *testing.T) {
+	engine := &DockerTaskEngine{
+		admissionPolicy: &admission.Policy{
+			Privileged: admission.Rule{Action: admission.ActionDeny},
+		},
+	}
+	task := &apitask.Task{Arn: "arn"}
+	container := &apicontainer.Container{Name: "c", Type: apicontainer.ContainerCNIPause}
+	hostConfig := &dockercontainer.HostConfig{Privileged: true}
+
+	assert.Nil(t, engine.admitContainer(task, container, hostConfig, nil))
+}
+
+func TestCreateContainerAdmissionPolicyError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ctrl, client, _, privateTaskEngine, _, _, _ := mocks(t, ctx, &config.Config{})
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+	auditLogger := mock_audit.NewMockAuditLogger(ctrl)
+	taskEngine.SetAuditLogger(auditLogger)
+	taskEngine.admissionPolicyErr = errors.New("unable to read task admission policy")
+
+	sleepTask := testdata.LoadTask("sleep5")
+	sleepContainer, _ := sleepTask.ContainerByName("sleep5")
+
+	client.EXPECT().APIVersion().Return(defaultDockerClientAPIVersion, nil)
+	auditLogger.EXPECT().LogTaskAdmission(sleepTask.Arn, sleepContainer.Name,
+		admissionPolicyFileRule, admissionDecisionDenied)
+
+	// The container must not be created if the policy can't be loaded
+	metadata := taskEngine.createContainer(sleepTask, sleepContainer)
+	require.Error(t, metadata.Error)
+	assert.Equal(t, taskAdmissionDeniedErrorName, metadata.Error.ErrorName())
+}
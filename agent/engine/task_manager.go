@@ -36,6 +36,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	utilsync "github.com/aws/amazon-ecs-agent/agent/utils/sync"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
@@ -158,6 +159,10 @@ type managedTask struct {
 	// verification logic gets executed to set it to a low interval
 	steadyStatePollInterval       time.Duration
 	steadyStatePollIntervalJitter time.Duration
+
+	// overseenAt is the time the task started being overseen, which the
+	// traced time for its containers to reach their steady state starts at
+	overseenAt time.Time
 }
 
 // newManagedTask is a method on DockerTaskEngine to create a new managedTask.
@@ -191,6 +196,13 @@ func (engine *DockerTaskEngine) newManagedTask(task *apitask.Task) *managedTask
 // loop of receiving messages and attempting to take action based on those
 // messages.
 func (mtask *managedTask) overseeTask() {
+	mtask.overseenAt = time.Now()
+	// The span of the task lifecycle ends once the task is stopped, it isn't
+	// exported if the agent exits first
+	taskSpan := tracing.TracerGlobal.StartTaskSpan(mtask.Arn,
+		tracing.String("aws.ecs.task.family", mtask.Family),
+		tracing.String("aws.ecs.task.revision", mtask.Version))
+
 	// Do a single updatestatus at the beginning to create the container
 	// `desiredstatus`es which are a construct of the engine used only here,
 	// not present on the backend
@@ -200,6 +212,8 @@ func (mtask *managedTask) overseeTask() {
 
 	// Wait for host resources required by this task to become available
 	mtask.waitForHostResources()
+	// Wait for enough disk space to pull the task's images
+	mtask.waitForDiskSpace()
 
 	// Main infinite loop. This is where we receive messages and dispatch work.
 	for {
@@ -229,6 +243,7 @@ func (mtask *managedTask) overseeTask() {
 		// Conversely, for it to spin in steady state it will have to have been
 		// loaded in steady state or progressed through here, so saving here should
 		// be sufficient to capture state changes.
+		mtask.engine.state.MarkTaskChanged(mtask.Arn)
 		err := mtask.saver.Save()
 		if err != nil {
 			seelog.Warnf("Managed task [%s]: unable to checkpoint task's states to disk: %v",
@@ -242,6 +257,8 @@ func (mtask *managedTask) overseeTask() {
 	// We only break out of the above if this task is known to be stopped. Do
 	// onetime cleanup here, including removing the task after a timeout
 	seelog.Infof("Managed task [%s]: task has reached stopped. Waiting for container cleanup", mtask.Arn)
+	taskSpan.SetAttributes(tracing.String(tracing.StatusAttribute, mtask.GetKnownStatus().String()))
+	taskSpan.End(nil)
 	mtask.cleanupCredentials()
 	if mtask.StopSequenceNumber != 0 {
 		seelog.Debugf("Managed task [%s]: marking done for this sequence: %d",
@@ -443,6 +460,11 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 	}
 
 	mtask.RecordExecutionStoppedAt(container)
+	if event.Status == container.GetSteadyStateStatus() {
+		tracing.TracerGlobal.StartSpanAt(mtask.Arn, "container.steady_state", mtask.overseenAt,
+			tracing.String(tracing.ContainerNameAttribute, container.Name),
+			tracing.String(tracing.StatusAttribute, event.Status.String())).End(nil)
+	}
 	seelog.Debugf("Managed task [%s]: Container [name=%s runtimeID=%s]: sending container change event to tcs, status: %s",
 		mtask.Arn, container.Name, runtimeID, event.Status.String())
 	err := mtask.containerChangeEventStream.WriteToEventStream(event)
@@ -486,6 +508,7 @@ func (mtask *managedTask) handleResourceStateChange(resChange resourceStateChang
 	}
 
 	defer mtask.engine.saver.Save()
+	defer mtask.engine.state.MarkTaskChanged(mtask.Arn)
 	// Set known status regardless of error so the applied status can be cleared. If there is error,
 	// the known status might be set again below (but that won't affect the applied status anymore).
 	// This follows how container state change is handled.
@@ -679,6 +702,15 @@ func (mtask *managedTask) handleEventError(containerChange dockerContainerChange
 	case apicontainerstatus.ContainerStatusNone:
 		fallthrough
 	case apicontainerstatus.ContainerCreated:
+		if errorName := event.Error.ErrorName(); errorName == imagePolicyViolationErrorName ||
+			errorName == taskAdmissionDeniedErrorName {
+			// The task should be stopped regardless of whether this container is
+			// essential or non-essential, as the task isn't allowed to run
+			seelog.Errorf("Managed task [%s]: container %s is not allowed to run, moving task to STOPPED: %v",
+				mtask.Arn, container.Name, event.Error)
+			mtask.SetTerminalReason(apierrors.NewNamedError(event.Error).Error())
+			mtask.SetDesiredStatus(apitaskstatus.TaskStopped)
+		}
 		// No need to explicitly stop containers if this is a * -> NONE/CREATED transition
 		seelog.Warnf("Managed task [%s]: error creating container [%s]; marking its desired status as STOPPED: %v",
 			mtask.Arn, container.Name, event.Error)
@@ -956,7 +988,11 @@ func (mtask *managedTask) startResourceTransitions(transitionFunc resourceTransi
 // task of the change. transitionResource is called by progressTask
 func (mtask *managedTask) transitionResource(resource taskresource.TaskResource,
 	to resourcestatus.ResourceStatus) {
+	span := tracing.TracerGlobal.StartSpan(mtask.Arn, "resource.transition",
+		tracing.String("aws.ecs.resource.name", resource.GetName()),
+		tracing.String(tracing.StatusAttribute, resource.StatusString(to)))
 	err := mtask.applyResourceState(resource, to)
+	span.End(err)
 
 	if mtask.engine.isTaskManaged(mtask.Arn) {
 		mtask.emitResourceChange(resourceStateChange{
@@ -40,6 +40,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dependencygraph"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	mock_dockerstate "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate/mocks"
 	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
@@ -173,6 +174,32 @@ func TestHandleEventError(t *testing.T) {
 			ExpectedContainerDesiredStatusStopped: true,
 			ExpectedOK:                            false,
 		},
+		{
+			Name:        "Image policy violated and task fails",
+			EventStatus: apicontainerstatus.ContainerCreated,
+			Error: ImagePolicyViolationError{
+				fromError: errors.New("image busybox is not referenced by digest"),
+			},
+			CurrentContainerKnownStatus:           apicontainerstatus.ContainerPulled,
+			ExpectedContainerKnownStatusSet:       true,
+			ExpectedContainerKnownStatus:          apicontainerstatus.ContainerPulled,
+			ExpectedContainerDesiredStatusStopped: true,
+			ExpectedTaskDesiredStatusStopped:      true,
+			ExpectedOK:                            false,
+		},
+		{
+			Name:        "Task admission denied and task fails",
+			EventStatus: apicontainerstatus.ContainerCreated,
+			Error: TaskAdmissionDeniedError{
+				fromError: errors.New("container c: privileged mode denied by the task admission policy"),
+			},
+			CurrentContainerKnownStatus:           apicontainerstatus.ContainerPulled,
+			ExpectedContainerKnownStatusSet:       true,
+			ExpectedContainerKnownStatus:          apicontainerstatus.ContainerPulled,
+			ExpectedContainerDesiredStatusStopped: true,
+			ExpectedTaskDesiredStatusStopped:      true,
+			ExpectedOK:                            false,
+		},
 		{
 			Name:        "Pull image fails and task fails",
 			EventStatus: apicontainerstatus.ContainerPulled,
@@ -219,6 +246,9 @@ func TestHandleEventError(t *testing.T) {
 				assert.Equal(t, apicontainerstatus.ContainerStopped, containerDesiredStatus,
 					"desired status %s != %s", apicontainerstatus.ContainerStopped.String(), containerDesiredStatus.String())
 			}
+			if tc.ExpectedTaskDesiredStatusStopped {
+				assert.Equal(t, apitaskstatus.TaskStopped, mtask.GetDesiredStatus())
+			}
 			assert.Equal(t, tc.Error.ErrorName(), containerChange.container.ApplyingError.ErrorName())
 		})
 	}
@@ -927,6 +957,7 @@ func TestHandleStoppedToSteadyStateTransition(t *testing.T) {
 
 	taskEngine := &DockerTaskEngine{
 		saver: mockStateManager,
+		state: dockerstate.NewTaskEngineState(),
 	}
 	firstContainerName := "container1"
 	firstContainer := &apicontainer.Container{
@@ -1074,6 +1105,7 @@ func TestCleanupTask(t *testing.T) {
 	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
+	mockState.EXPECT().MarkTaskChanged(mTask.Arn)
 	mockState.EXPECT().RemoveTask(mTask.Task)
 	mockResource.EXPECT().Cleanup()
 	mockResource.EXPECT().GetName()
@@ -1145,6 +1177,7 @@ func TestCleanupTaskWaitsForStoppedSent(t *testing.T) {
 		map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
+	mockState.EXPECT().MarkTaskChanged(mTask.Arn)
 	mockState.EXPECT().RemoveTask(mTask.Task)
 	mTask.cleanupTask(taskStoppedDuration)
 	assert.Equal(t, apitaskstatus.TaskStopped, mTask.GetSentStatus())
@@ -1273,6 +1306,7 @@ func TestCleanupTaskENIs(t *testing.T) {
 	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
+	mockState.EXPECT().MarkTaskChanged(mTask.Arn)
 	mockState.EXPECT().RemoveTask(mTask.Task)
 	mockState.EXPECT().RemoveENIAttachment(mac)
 	mTask.cleanupTask(taskStoppedDuration)
@@ -1388,6 +1422,7 @@ func TestCleanupTaskWithInvalidInterval(t *testing.T) {
 	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
+	mockState.EXPECT().MarkTaskChanged(mTask.Arn)
 	mockState.EXPECT().RemoveTask(mTask.Task)
 	mTask.cleanupTask(taskStoppedDuration)
 }
@@ -1448,6 +1483,7 @@ func TestCleanupTaskWithResourceHappyPath(t *testing.T) {
 	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
+	mockState.EXPECT().MarkTaskChanged(mTask.Arn)
 	mockState.EXPECT().RemoveTask(mTask.Task)
 	mockResource.EXPECT().GetName()
 	mockResource.EXPECT().Cleanup().Return(nil)
@@ -1510,6 +1546,7 @@ func TestCleanupTaskWithResourceErrorPath(t *testing.T) {
 	mockState.EXPECT().ContainerMapByArn(mTask.Arn).Return(map[string]*apicontainer.DockerContainer{container.Name: dockerContainer}, true)
 	mockClient.EXPECT().RemoveContainer(gomock.Any(), dockerContainer.DockerName, gomock.Any()).Return(nil)
 	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(container).Return(nil)
+	mockState.EXPECT().MarkTaskChanged(mTask.Arn)
 	mockState.EXPECT().RemoveTask(mTask.Task)
 	mockResource.EXPECT().GetName()
 	mockResource.EXPECT().Cleanup().Return(errors.New("cleanup error"))
@@ -1762,7 +1799,7 @@ func TestHandleVolumeResourceStateChangeAndSave(t *testing.T) {
 					ResourcesMapUnsafe:  make(map[string][]taskresource.TaskResource),
 					DesiredStatusUnsafe: apitaskstatus.TaskRunning,
 				},
-				engine: &DockerTaskEngine{},
+				engine: &DockerTaskEngine{state: dockerstate.NewTaskEngineState()},
 			}
 			mtask.AddResource(volumeName, res)
 			mtask.engine.SetSaver(mockSaver)
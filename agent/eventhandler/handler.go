@@ -15,6 +15,7 @@ package eventhandler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
@@ -24,13 +25,15 @@ import (
 )
 
 // HandleEngineEvents handles state change events from the state change event channel by sending it to
-// responsible event handler
+// responsible event handler. Every event is also dispatched to the listeners, in order, such as
+// the event sinks and the task event streams of the metadata endpoint.
 func HandleEngineEvents(
 	ctx context.Context,
 	taskEngine engine.TaskEngine,
 	client api.ECSClient,
 	taskHandler *TaskHandler,
-	attachmentEventHandler *AttachmentEventHandler) {
+	attachmentEventHandler *AttachmentEventHandler,
+	listeners ...statechange.Listener) {
 	for {
 		stateChangeEvents := taskEngine.StateChangeEvents()
 
@@ -45,6 +48,9 @@ func HandleEngineEvents(
 					seelog.Error("Unable to handle state change event. The events channel is closed")
 					break
 				}
+				for _, listener := range listeners {
+					listener.Dispatch(event)
+				}
 				err := handleEngineEvent(event, client, taskHandler, attachmentEventHandler)
 				if err != nil {
 					seelog.Errorf("Handler unable to add state change event %v: %v", event, err)
@@ -61,6 +67,14 @@ func handleEngineEvent(event statechange.Event, client api.ECSClient, taskHandle
 		return taskHandler.AddStateChangeEvent(event, client)
 	case statechange.AttachmentEvent:
 		return attachmentEventHandler.AddStateChangeEvent(event)
+	case statechange.ContainerDriftEvent:
+		// Drift isn't reported to the ECS backend
+		drift, ok := event.(api.ContainerDrift)
+		if !ok {
+			return errors.New("eventhandler: unable to get container drift from state change event")
+		}
+		seelog.Warnf("Container drift: %s", drift.String())
+		return nil
 	default:
 		return fmt.Errorf("unrecognized event type: %d", event.GetEventType())
 	}
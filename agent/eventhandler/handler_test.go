@@ -22,6 +22,9 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	mock_api "github.com/aws/amazon-ecs-agent/agent/api/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	mock_engine "github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -35,7 +38,7 @@ func TestHandleEngineEvent(t *testing.T) {
 	stateManager := statemanager.NewNoopStateManager()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	taskHandler := NewTaskHandler(ctx, stateManager, nil, client)
+	taskHandler := NewTaskHandler(ctx, stateManager, dockerstate.NewTaskEngineState(), client)
 	attachmentHandler := NewAttachmentEventHandler(ctx, statemanager.NewNoopStateManager(), client)
 	defer cancel()
 
@@ -72,3 +75,57 @@ func TestHandleEngineEvent(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestHandleEngineEventContainerDrift(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Container drift isn't submitted to the ECS backend
+	client := mock_api.NewMockECSClient(ctrl)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	taskHandler := NewTaskHandler(ctx, statemanager.NewNoopStateManager(), dockerstate.NewTaskEngineState(), client)
+	attachmentHandler := NewAttachmentEventHandler(ctx, statemanager.NewNoopStateManager(), client)
+
+	drift := api.ContainerDrift{
+		TaskArn:       taskARN,
+		ContainerName: "c",
+		RuntimeID:     "id",
+		Fields:        []api.DriftedField{{Name: "HostConfig.Memory", Expected: "536870912", Actual: "1073741824"}},
+	}
+	assert.NoError(t, handleEngineEvent(drift, client, taskHandler, attachmentHandler))
+}
+
+type testListener struct {
+	events chan statechange.Event
+}
+
+func (listener *testListener) Dispatch(event statechange.Event) {
+	listener.events <- event
+}
+
+func TestHandleEngineEventsDispatchesToListeners(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := mock_api.NewMockECSClient(ctrl)
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	stateChangeEvents := make(chan statechange.Event)
+	taskEngine.EXPECT().StateChangeEvents().Return(stateChangeEvents).AnyTimes()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	taskHandler := NewTaskHandler(ctx, statemanager.NewNoopStateManager(), dockerstate.NewTaskEngineState(), client)
+	attachmentHandler := NewAttachmentEventHandler(ctx, statemanager.NewNoopStateManager(), client)
+	listener := &testListener{events: make(chan statechange.Event, 1)}
+	go HandleEngineEvents(ctx, taskEngine, client, taskHandler, attachmentHandler, listener)
+
+	// Drift isn't submitted to the ECS backend, only dispatched
+	drift := api.ContainerDrift{
+		TaskArn:       taskARN,
+		ContainerName: "c",
+		RuntimeID:     "id",
+	}
+	stateChangeEvents <- drift
+	assert.Equal(t, drift, <-listener.events)
+}
@@ -355,18 +355,18 @@ func (taskEvents *taskSendableEvents) submitFirstEvent(handler *TaskHandler, bac
 
 	if event.containerShouldBeSent() {
 		if err := event.send(sendContainerStatusToECS, setContainerChangeSent, "container",
-			handler.client, eventToSubmit, handler.stateSaver, backoff, taskEvents); err != nil {
+			handler.client, eventToSubmit, handler.state, handler.stateSaver, backoff, taskEvents); err != nil {
 			return false, err
 		}
 	} else if event.taskShouldBeSent() {
 		if err := event.send(sendTaskStatusToECS, setTaskChangeSent, "task",
-			handler.client, eventToSubmit, handler.stateSaver, backoff, taskEvents); err != nil {
+			handler.client, eventToSubmit, handler.state, handler.stateSaver, backoff, taskEvents); err != nil {
 			handleInvalidParamException(err, taskEvents.events, eventToSubmit)
 			return false, err
 		}
 	} else if event.taskAttachmentShouldBeSent() {
 		if err := event.send(sendTaskStatusToECS, setTaskAttachmentSent, "task attachment",
-			handler.client, eventToSubmit, handler.stateSaver, backoff, taskEvents); err != nil {
+			handler.client, eventToSubmit, handler.state, handler.stateSaver, backoff, taskEvents); err != nil {
 			handleInvalidParamException(err, taskEvents.events, eventToSubmit)
 			return false, err
 		}
@@ -32,6 +32,7 @@ import (
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	mock_dockerstate "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
@@ -53,7 +54,7 @@ func TestSendsEventsOneContainer(t *testing.T) {
 	stateManager := statemanager.NewNoopStateManager()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := NewTaskHandler(ctx, stateManager, nil, client)
+	handler := NewTaskHandler(ctx, stateManager, dockerstate.NewTaskEngineState(), client)
 	defer cancel()
 
 	var wg sync.WaitGroup
@@ -85,7 +86,7 @@ func TestSendsEventsOneEventRetries(t *testing.T) {
 	stateManager := statemanager.NewNoopStateManager()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := NewTaskHandler(ctx, stateManager, nil, client)
+	handler := NewTaskHandler(ctx, stateManager, dockerstate.NewTaskEngineState(), client)
 	defer cancel()
 
 	var wg sync.WaitGroup
@@ -111,7 +112,7 @@ func TestSendsEventsInvalidParametersEventsRemoved(t *testing.T) {
 	stateManager := statemanager.NewNoopStateManager()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := NewTaskHandler(ctx, stateManager, nil, client)
+	handler := NewTaskHandler(ctx, stateManager, dockerstate.NewTaskEngineState(), client)
 	defer cancel()
 
 	var wg sync.WaitGroup
@@ -140,7 +141,7 @@ func TestSendsEventsConcurrentLimit(t *testing.T) {
 	stateManager := statemanager.NewNoopStateManager()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := NewTaskHandler(ctx, stateManager, nil, client)
+	handler := NewTaskHandler(ctx, stateManager, dockerstate.NewTaskEngineState(), client)
 	defer cancel()
 
 	completeStateChange := make(chan bool, concurrentEventCalls+1)
@@ -179,7 +180,7 @@ func TestSendsEventsContainerDifferences(t *testing.T) {
 	stateManager := statemanager.NewNoopStateManager()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := NewTaskHandler(ctx, stateManager, nil, client)
+	handler := NewTaskHandler(ctx, stateManager, dockerstate.NewTaskEngineState(), client)
 	defer cancel()
 
 	var wg sync.WaitGroup
@@ -213,7 +214,7 @@ func TestSendsEventsTaskDifferences(t *testing.T) {
 	stateManager := statemanager.NewNoopStateManager()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := NewTaskHandler(ctx, stateManager, nil, client)
+	handler := NewTaskHandler(ctx, stateManager, dockerstate.NewTaskEngineState(), client)
 	defer cancel()
 
 	taskARNA := "taskarnA"
@@ -263,7 +264,7 @@ func TestSendsEventsDedupe(t *testing.T) {
 	stateManager := statemanager.NewNoopStateManager()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := NewTaskHandler(ctx, stateManager, nil, client)
+	handler := NewTaskHandler(ctx, stateManager, dockerstate.NewTaskEngineState(), client)
 	defer cancel()
 
 	taskARNA := "taskarnA"
@@ -310,7 +311,7 @@ func TestCleanupTaskEventAfterSubmit(t *testing.T) {
 	client := mock_api.NewMockECSClient(ctrl)
 
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := NewTaskHandler(ctx, stateManager, nil, client)
+	handler := NewTaskHandler(ctx, stateManager, dockerstate.NewTaskEngineState(), client)
 	defer cancel()
 
 	taskARN2 := "taskarn2"
@@ -398,7 +399,7 @@ func TestENISentStatusChange(t *testing.T) {
 	events := list.New()
 	events.PushBack(sendableTaskEvent)
 	ctx, cancel := context.WithCancel(context.Background())
-	handler := NewTaskHandler(ctx, statemanager.NewNoopStateManager(), nil, client)
+	handler := NewTaskHandler(ctx, statemanager.NewNoopStateManager(), dockerstate.NewTaskEngineState(), client)
 	defer cancel()
 	handler.submitTaskEvents(&taskSendableEvents{
 		events: events,
@@ -495,6 +496,7 @@ func TestSubmitTaskEventsWhenSubmittingTaskRunningAfterStopped(t *testing.T) {
 		client.EXPECT().SubmitTaskStateChange(gomock.Any()).Do(func(change api.TaskStateChange) {
 			assert.Equal(t, apitaskstatus.TaskStopped, change.Status)
 		}),
+		state.EXPECT().MarkTaskChanged(taskARN),
 		backoff.EXPECT().Reset().Do(func() {
 			wg.Done()
 		}),
@@ -559,6 +561,7 @@ func TestSubmitTaskEventsWhenSubmittingTaskStoppedAfterRunning(t *testing.T) {
 		client.EXPECT().SubmitTaskStateChange(gomock.Any()).Do(func(change api.TaskStateChange) {
 			assert.Equal(t, apitaskstatus.TaskRunning, change.Status)
 		}),
+		state.EXPECT().MarkTaskChanged(taskARN),
 		backoff.EXPECT().Reset().Do(func() {
 			wg.Done()
 		}),
@@ -574,6 +577,7 @@ func TestSubmitTaskEventsWhenSubmittingTaskStoppedAfterRunning(t *testing.T) {
 		client.EXPECT().SubmitTaskStateChange(gomock.Any()).Do(func(change api.TaskStateChange) {
 			assert.Equal(t, apitaskstatus.TaskStopped, change.Status)
 		}),
+		state.EXPECT().MarkTaskChanged(taskARN),
 		backoff.EXPECT().Reset().Do(func() {
 			wg.Done()
 		}),
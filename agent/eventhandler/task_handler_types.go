@@ -20,7 +20,9 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	"github.com/cihub/seelog"
 )
@@ -136,22 +138,34 @@ func (event *sendableEvent) send(
 	eventType string,
 	client api.ECSClient,
 	eventToSubmit *list.Element,
+	state dockerstate.TaskEngineState,
 	stateSaver statemanager.Saver,
 	backoff retry.Backoff,
 	taskEvents *taskSendableEvents) error {
 
 	seelog.Infof("TaskHandler: Sending %s change: %s", eventType, event.toString())
+	span := tracing.TracerGlobal.StartSpan(event.taskArn(), "state_change.submit",
+		tracing.String("aws.ecs.state_change.type", eventType))
+	if event.isContainerEvent {
+		span.SetAttributes(tracing.String(tracing.ContainerNameAttribute, event.containerChange.ContainerName),
+			tracing.String(tracing.StatusAttribute, event.containerChange.Status.String()))
+	} else {
+		span.SetAttributes(tracing.String(tracing.StatusAttribute, event.taskChange.Status.String()))
+	}
 	// Try submitting the change to ECS
 	if err := sendStatusToECS(client, event); err != nil {
 		seelog.Errorf("TaskHandler: Unretriable error submitting %s state change [%s]: %v",
 			eventType, event.toString(), err)
+		span.End(err)
 		return err
 	}
+	span.End(nil)
 	// submitted; ensure we don't retry it
 	event.setSent()
 	// Mark event as sent
 	setChangeSent(event)
 	// Update the state file
+	state.MarkTaskChanged(event.taskArn())
 	stateSaver.Save()
 	seelog.Debugf("TaskHandler: Submitted task state change: %s", event.toString())
 	taskEvents.events.Remove(eventToSubmit)
@@ -0,0 +1,276 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eventsink
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/statechange"
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// maxPendingEvents is the maximum number of events waiting to be
+	// delivered to a sink. New events are dropped once it's reached, and an
+	// event counting them is delivered in their place once there's room.
+	maxPendingEvents = 10000
+	// droppedEventsLogInterval is the number of dropped events between the
+	// errors logged about them
+	droppedEventsLogInterval = 100
+	// sendTimeout is the maximum time to deliver an event to a sink
+	sendTimeout = 10 * time.Second
+
+	sendBackoffMin            = time.Second
+	sendBackoffMax            = time.Minute
+	sendBackoffJitterMultiple = 0.2
+	sendBackoffMultiple       = 2
+)
+
+// Dispatcher delivers the state changes of tasks and containers to the event
+// sinks. Each sink receives the events in order, and an event is sent again
+// until the sink accepts it, so that sinks get every event at least once. The
+// events waiting to be delivered are saved under the data directory, and are
+// delivered after the agent restarts. A nil dispatcher has no sinks.
+type Dispatcher struct {
+	queues []*queue
+}
+
+// queue holds the events waiting to be delivered to a sink
+type queue struct {
+	sink    Sink
+	backoff retry.Backoff
+	// events is the list of the json encoded events. New events are added to
+	// the back of the list, and are removed from the front once delivered.
+	events *list.List
+	// spool persists the events of the list, it's nil if they're only kept
+	// in memory
+	spool *spool
+	// dropped is the number of events dropped since the list was full
+	dropped int
+	// pending is signaled when events are added to the list
+	pending chan struct{}
+	lock    sync.Mutex
+}
+
+// NewDispatcher creates the sinks of the configuration, and starts delivering
+// the events to them until the context is done. The events waiting to be
+// delivered are saved under the data directory, or only kept in memory if
+// it's empty. It returns nil if there are no sinks.
+func NewDispatcher(ctx context.Context, sinks []config.EventSink, dataDir string) *Dispatcher {
+	dispatcher := &Dispatcher{}
+	for _, cfg := range sinks {
+		sink, err := newSink(cfg)
+		if err != nil {
+			seelog.Errorf("Event sinks: unable to create event sink: %v", err)
+			continue
+		}
+		seelog.Infof("Event sinks: delivering task and container events to %s", sink.String())
+		queue := newQueue(sink)
+		if dataDir != "" {
+			if err := queue.openSpool(filepath.Join(dataDir, spoolDirName)); err != nil {
+				seelog.Errorf("Event sinks: unable to save the events waiting to be delivered to %s, "+
+					"they won't be delivered if the agent restarts: %v", sink.String(), err)
+			}
+		}
+		dispatcher.queues = append(dispatcher.queues, queue)
+	}
+	if len(dispatcher.queues) == 0 {
+		return nil
+	}
+	for _, queue := range dispatcher.queues {
+		go queue.run(ctx)
+	}
+	return dispatcher
+}
+
+func newQueue(sink Sink) *queue {
+	return &queue{
+		sink: sink,
+		backoff: retry.NewExponentialBackoff(sendBackoffMin, sendBackoffMax,
+			sendBackoffJitterMultiple, sendBackoffMultiple),
+		events:  list.New(),
+		pending: make(chan struct{}, 1),
+	}
+}
+
+// openSpool saves the events of the queue to a file in the directory, and
+// queues the events saved to it that haven't been delivered yet
+func (queue *queue) openSpool(dir string) error {
+	spool, events, err := openSpool(dir, queue.sink)
+	if err != nil {
+		return err
+	}
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	queue.spool = spool
+	for _, event := range events {
+		queue.events.PushBack(event)
+	}
+	if len(events) > 0 {
+		seelog.Infof("Event sinks: %d events saved before the agent restarted are waiting to be delivered to %s",
+			len(events), queue.sink.String())
+		queue.signal()
+	}
+	return nil
+}
+
+// Dispatch queues the state change to be delivered to the sinks. Only task
+// and container state changes are delivered.
+func (dispatcher *Dispatcher) Dispatch(change statechange.Event) {
+	if dispatcher == nil {
+		return
+	}
+	event, ok := newEvent(change)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		seelog.Errorf("Event sinks: unable to encode event for task %s: %v", event.TaskARN, err)
+		return
+	}
+	// The event is shared by the queues, cap it so that appending to it
+	// copies it
+	data = data[:len(data):len(data)]
+	for _, queue := range dispatcher.queues {
+		queue.push(data)
+	}
+}
+
+// push queues the event. The event is dropped if the queue is full, in which
+// case an event counting the dropped events is queued before the next one.
+func (queue *queue) push(event []byte) {
+	queue.lock.Lock()
+	if queue.events.Len() >= maxPendingEvents {
+		queue.dropped++
+		if queue.dropped%droppedEventsLogInterval == 1 {
+			seelog.Errorf("Event sinks: %d events are waiting to be delivered to %s, dropped %d new events",
+				queue.events.Len(), queue.sink.String(), queue.dropped)
+		}
+		queue.lock.Unlock()
+		return
+	}
+	if queue.dropped > 0 {
+		if dropped, err := json.Marshal(newDroppedEvent(queue.dropped)); err == nil {
+			queue.pushUnsafe(dropped)
+		}
+		queue.dropped = 0
+	}
+	queue.pushUnsafe(event)
+	queue.lock.Unlock()
+
+	queue.signal()
+}
+
+func (queue *queue) pushUnsafe(event []byte) {
+	queue.events.PushBack(event)
+	if queue.spool == nil {
+		return
+	}
+	if err := queue.spool.append(event); err != nil {
+		seelog.Errorf("Event sinks: unable to save event waiting to be delivered to %s: %v",
+			queue.sink.String(), err)
+	}
+}
+
+// signal wakes up the delivery of the events, if it's waiting
+func (queue *queue) signal() {
+	select {
+	case queue.pending <- struct{}{}:
+	default:
+	}
+}
+
+func (queue *queue) front() *list.Element {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	return queue.events.Front()
+}
+
+// remove removes the delivered element at the front of the queue
+func (queue *queue) remove(element *list.Element) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	queue.events.Remove(element)
+	if queue.spool == nil {
+		return
+	}
+	if err := queue.spool.markDelivered(queue.events.Len(), queue.eventsUnsafe); err != nil {
+		seelog.Errorf("Event sinks: unable to remove delivered event from %s: %v", queue.spool.path, err)
+	}
+}
+
+// eventsUnsafe returns the events of the queue, in order
+func (queue *queue) eventsUnsafe() [][]byte {
+	events := make([][]byte, 0, queue.events.Len())
+	for element := queue.events.Front(); element != nil; element = element.Next() {
+		events = append(events, element.Value.([]byte))
+	}
+	return events
+}
+
+// run delivers the events of the queue to the sink, in order, until the
+// context is done
+func (queue *queue) run(ctx context.Context) {
+	defer queue.close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-queue.pending:
+		}
+		for element := queue.front(); element != nil; element = queue.front() {
+			queue.send(ctx, element.Value.([]byte))
+			if ctx.Err() != nil {
+				return
+			}
+			queue.remove(element)
+		}
+	}
+}
+
+// close releases the sink and the spool of the queue
+func (queue *queue) close() {
+	queue.sink.Close()
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	if queue.spool == nil {
+		return
+	}
+	if err := queue.spool.close(queue.eventsUnsafe()); err != nil {
+		seelog.Errorf("Event sinks: unable to remove delivered events from %s: %v", queue.spool.path, err)
+	}
+}
+
+// send delivers the event to the sink, retrying until it succeeds or the
+// context is done
+func (queue *queue) send(ctx context.Context, event []byte) {
+	queue.backoff.Reset()
+	retry.RetryWithBackoffCtx(ctx, queue.backoff, func() error {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		defer cancel()
+		err := queue.sink.Send(sendCtx, event)
+		if err != nil {
+			seelog.Warnf("Event sinks: unable to deliver event to %s, retrying: %v", queue.sink.String(), err)
+		}
+		return err
+	})
+}
@@ -0,0 +1,395 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eventsink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 10 * time.Second
+
+func dispatchTestEvents(dispatcher *Dispatcher) {
+	dispatcher.Dispatch(api.ContainerStateChange{
+		TaskArn:       "t1",
+		ContainerName: "c1",
+		Status:        apicontainerstatus.ContainerRunning,
+	})
+	dispatcher.Dispatch(api.TaskStateChange{
+		TaskARN: "t1",
+		Status:  apitaskstatus.TaskRunning,
+	})
+}
+
+func assertTestEvents(t *testing.T, events []Event) {
+	require.Len(t, events, 2)
+	assert.Equal(t, EventTypeContainer, events[0].Type)
+	assert.Equal(t, "c1", events[0].ContainerName)
+	assert.Equal(t, EventTypeTask, events[1].Type)
+	assert.Equal(t, "RUNNING", events[1].Status)
+}
+
+func TestNewDispatcherWithoutSinks(t *testing.T) {
+	dispatcher := NewDispatcher(context.Background(), nil, "")
+	assert.Nil(t, dispatcher)
+	// Dispatching to a nil dispatcher does nothing
+	dispatchTestEvents(dispatcher)
+}
+
+func TestDispatcherWebhookRetries(t *testing.T) {
+	var lock sync.Mutex
+	var events []Event
+	requests := 0
+	received := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		if requests == 1 {
+			// The first delivery fails, and is retried
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event Event
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dispatcher := NewDispatcher(ctx, []config.EventSink{{Type: config.EventSinkTypeWebhook, URL: server.URL}}, "")
+	require.NotNil(t, dispatcher)
+	dispatcher.queues[0].backoff = retry.NewExponentialBackoff(time.Millisecond, time.Millisecond, 0, 1)
+	dispatchTestEvents(dispatcher)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(testTimeout):
+			t.Fatal("timed out waiting for the events")
+		}
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 3, requests)
+	assertTestEvents(t, events)
+}
+
+func readEventLines(t *testing.T, lines []string) []Event {
+	var events []Event
+	for _, line := range lines {
+		var event Event
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestDispatcherFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventsink")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "events.jsonl")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dispatcher := NewDispatcher(ctx, []config.EventSink{{Type: config.EventSinkTypeFile, Path: file}}, "")
+	dispatchTestEvents(dispatcher)
+
+	var lines []string
+	for deadline := time.Now().Add(testTimeout); len(lines) < 2; time.Sleep(10 * time.Millisecond) {
+		require.True(t, time.Now().Before(deadline), "timed out waiting for the events")
+		data, err := ioutil.ReadFile(file)
+		if err == nil && len(data) > 0 {
+			lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+		}
+	}
+	assertTestEvents(t, readEventLines(t, lines))
+}
+
+func TestDispatcherUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventsink")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "events.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dispatcher := NewDispatcher(ctx, []config.EventSink{{Type: config.EventSinkTypeUnix, Path: socket}}, "")
+	dispatchTestEvents(dispatcher)
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+	scanner := bufio.NewScanner(conn)
+	var lines []string
+	for len(lines) < 2 && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	assertTestEvents(t, readEventLines(t, lines))
+}
+
+func TestQueueDropsNewEvents(t *testing.T) {
+	queue := newQueue(&fileSink{path: "/events.jsonl"})
+	for i := 0; i < maxPendingEvents+2; i++ {
+		queue.push([]byte{byte(i)})
+	}
+	assert.Equal(t, maxPendingEvents, queue.events.Len())
+	assert.Equal(t, []byte{0}, queue.front().Value)
+	assert.Equal(t, 2, queue.dropped)
+
+	// Once there's room, an event counting the dropped events is queued
+	// before the next one
+	queue.remove(queue.front())
+	queue.push([]byte("{}"))
+	assert.Equal(t, maxPendingEvents+1, queue.events.Len())
+	assert.Equal(t, []byte("{}"), queue.events.Back().Value)
+	var dropped Event
+	require.NoError(t, json.Unmarshal(queue.events.Back().Prev().Value.([]byte), &dropped))
+	assert.Equal(t, EventTypeDropped, dropped.Type)
+	assert.Equal(t, 2, dropped.DroppedEvents)
+	assert.NotEmpty(t, dropped.ID)
+	assert.Equal(t, 0, queue.dropped)
+}
+
+func TestQueueSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventsink")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sink := &fileSink{path: "/events.jsonl"}
+
+	queue := newQueue(sink)
+	require.NoError(t, queue.openSpool(dir))
+	queue.push([]byte(`{"id":"1"}`))
+	queue.push([]byte(`{"id":"2"}`))
+	queue.push([]byte(`{"id":"3"}`))
+	queue.remove(queue.front())
+	queue.close()
+
+	// The events that weren't delivered are queued again, and the delivery
+	// is signaled
+	queue = newQueue(sink)
+	require.NoError(t, queue.openSpool(dir))
+	assert.Equal(t, [][]byte{[]byte(`{"id":"2"}`), []byte(`{"id":"3"}`)}, queue.eventsUnsafe())
+	select {
+	case <-queue.pending:
+	default:
+		t.Error("expected the delivery to be signaled")
+	}
+
+	// The spool file is emptied once every event is delivered
+	queue.remove(queue.front())
+	queue.remove(queue.front())
+	info, err := os.Stat(queue.spool.path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+	assert.Equal(t, os.FileMode(spoolFilePermissions), info.Mode().Perm())
+	queue.close()
+}
+
+func TestQueueSpoolCompacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventsink")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	queue := newQueue(&fileSink{path: "/events.jsonl"})
+	require.NoError(t, queue.openSpool(dir))
+	defer queue.close()
+	for i := 0; i <= spoolCompactThreshold; i++ {
+		queue.push([]byte(`{}`))
+	}
+	for i := 0; i < spoolCompactThreshold; i++ {
+		queue.remove(queue.front())
+	}
+	data, err := ioutil.ReadFile(queue.spool.path)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", string(data))
+
+	// Events are still appended after the file is rewritten
+	queue.push([]byte(`{"id":"1"}`))
+	data, err = ioutil.ReadFile(queue.spool.path)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n{\"id\":\"1\"}\n", string(data))
+}
+
+func TestOpenSpoolSkipsInvalidEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventsink")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sink := &fileSink{path: "/events.jsonl"}
+
+	spool, _, err := openSpool(dir, sink)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(spool.path, []byte("{\"id\":\"1\"}\n\n{\"id\":\"2\"}\n{\"id\""), 0600))
+	spool.close(nil)
+
+	spool, events, err := openSpool(dir, sink)
+	require.NoError(t, err)
+	defer spool.close(nil)
+	assert.Equal(t, [][]byte{[]byte(`{"id":"1"}`), []byte(`{"id":"2"}`)}, events)
+}
+
+func TestOpenSpoolRemovesTornEvent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventsink")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sink := &fileSink{path: "/events.jsonl"}
+
+	spool, _, err := openSpool(dir, sink)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(spool.path, []byte("{\"id\":\"1\"}\n{\"id\""), 0600))
+	spool.close(nil)
+
+	// The event appended after the torn one is read back on its own line
+	spool, events, err := openSpool(dir, sink)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(`{"id":"1"}`)}, events)
+	require.NoError(t, spool.append([]byte(`{"id":"2"}`)))
+	spool.close(nil)
+
+	spool, events, err = openSpool(dir, sink)
+	require.NoError(t, err)
+	defer spool.close(nil)
+	assert.Equal(t, [][]byte{[]byte(`{"id":"1"}`), []byte(`{"id":"2"}`)}, events)
+	data, err := ioutil.ReadFile(spool.path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"id\":\"1\"}\n{\"id\":\"2\"}\n", string(data))
+	info, err := os.Stat(spool.path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(spoolFilePermissions), info.Mode().Perm())
+}
+
+func TestNewDispatcherDeliversSpooledEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventsink")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "events.jsonl")
+	sinks := []config.EventSink{{Type: config.EventSinkTypeFile, Path: file}}
+
+	// Save the events as if the agent stopped before delivering them
+	spooled := newQueue(&fileSink{path: file})
+	require.NoError(t, spooled.openSpool(filepath.Join(dir, spoolDirName)))
+	dispatchTestEvents(&Dispatcher{queues: []*queue{spooled}})
+	spooled.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	NewDispatcher(ctx, sinks, dir)
+
+	var lines []string
+	for deadline := time.Now().Add(testTimeout); len(lines) < 2; time.Sleep(10 * time.Millisecond) {
+		require.True(t, time.Now().Before(deadline), "timed out waiting for the events")
+		data, err := ioutil.ReadFile(file)
+		if err == nil && len(data) > 0 {
+			lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+		}
+	}
+	assertTestEvents(t, readEventLines(t, lines))
+}
+
+// timeoutError is the error of a write that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// partialConn is a connection whose writes write at most limit bytes, and
+// fail with err if they can't write everything
+type partialConn struct {
+	net.Conn
+	limit   int
+	err     error
+	written []byte
+	closed  bool
+}
+
+func (conn *partialConn) Write(data []byte) (int, error) {
+	if len(data) <= conn.limit {
+		conn.written = append(conn.written, data...)
+		return len(data), nil
+	}
+	conn.written = append(conn.written, data[:conn.limit]...)
+	return conn.limit, conn.err
+}
+
+func (conn *partialConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+func (conn *partialConn) Close() error {
+	conn.closed = true
+	return nil
+}
+
+func TestUnixSinkCompletesPartialLines(t *testing.T) {
+	conn := &partialConn{limit: 3, err: timeoutError{}}
+	sink := &unixSink{path: "/events.sock", conn: conn}
+
+	assert.Error(t, sink.Send(context.Background(), []byte(`{"id":"1"}`)))
+	assert.False(t, conn.closed)
+	assert.Equal(t, `{"i`, string(conn.written))
+
+	// Sending the same event again completes its line
+	conn.limit = 100
+	assert.NoError(t, sink.Send(context.Background(), []byte(`{"id":"1"}`)))
+	assert.Equal(t, "{\"id\":\"1\"}\n", string(conn.written))
+
+	// The line of an event is completed before the next one
+	conn.limit = 3
+	assert.Error(t, sink.Send(context.Background(), []byte(`{"id":"2"}`)))
+	conn.limit = 100
+	assert.NoError(t, sink.Send(context.Background(), []byte(`{"id":"3"}`)))
+	assert.Equal(t, "{\"id\":\"1\"}\n{\"id\":\"2\"}\n{\"id\":\"3\"}\n", string(conn.written))
+}
+
+func TestUnixSinkClosesFailedConnection(t *testing.T) {
+	conn := &partialConn{limit: 3, err: errors.New("broken pipe")}
+	sink := &unixSink{path: "/events.sock", conn: conn}
+
+	assert.Error(t, sink.Send(context.Background(), []byte(`{"id":"1"}`)))
+	assert.True(t, conn.closed)
+	assert.Nil(t, sink.conn)
+	assert.Empty(t, sink.unwritten)
+}
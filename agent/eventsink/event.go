@@ -0,0 +1,103 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package eventsink delivers the state changes of tasks and containers to
+// local destinations, such as webhooks, Unix sockets and files, so that
+// on-host tooling can react to them
+package eventsink
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/statechange"
+	"github.com/pborman/uuid"
+)
+
+const (
+	// EventTypeTask is the type of the events about tasks
+	EventTypeTask = "task"
+	// EventTypeContainer is the type of the events about containers
+	EventTypeContainer = "container"
+	// EventTypeDropped is the type of the events counting the events that
+	// were dropped in their place, as too many were waiting to be delivered
+	EventTypeDropped = "dropped"
+)
+
+// Event is the json record of a state change delivered to the sinks
+type Event struct {
+	// ID is the unique identifier of the event. Events can be delivered more
+	// than once, and consumers can discard the ones they have already seen.
+	ID string `json:"id"`
+	// Type is the type of the event: "task", "container" or "dropped"
+	Type string `json:"type"`
+	// Time is the time the agent handled the state change at
+	Time time.Time `json:"time"`
+	// TaskARN is the ARN of the task of the state change
+	TaskARN string `json:"taskArn"`
+	// ContainerName is the name of the container, for container events
+	ContainerName string `json:"containerName,omitempty"`
+	// RuntimeID is the docker ID of the container, for container events
+	RuntimeID string `json:"runtimeId,omitempty"`
+	// Status is the status of the task or container, such as "RUNNING"
+	Status string `json:"status"`
+	// Reason is the reason of the state change, if any
+	Reason string `json:"reason,omitempty"`
+	// ExitCode is the exit code of the container, if it exited
+	ExitCode *int `json:"exitCode,omitempty"`
+	// ImageDigest is the digest of the image of the container, if known
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// DroppedEvents is the number of events dropped, for dropped events
+	DroppedEvents int `json:"droppedEvents,omitempty"`
+}
+
+// newDroppedEvent creates the event record counting dropped events
+func newDroppedEvent(count int) *Event {
+	return &Event{
+		ID:            uuid.New(),
+		Type:          EventTypeDropped,
+		Time:          time.Now().UTC(),
+		DroppedEvents: count,
+	}
+}
+
+// newEvent creates the event record of a task or container state change. It
+// returns false for the other state changes, which aren't delivered.
+func newEvent(change statechange.Event) (*Event, bool) {
+	event := &Event{
+		ID:   uuid.New(),
+		Time: time.Now().UTC(),
+	}
+	switch change := change.(type) {
+	case api.TaskStateChange:
+		if change.Attachment != nil {
+			return nil, false
+		}
+		event.Type = EventTypeTask
+		event.TaskARN = change.TaskARN
+		event.Status = change.Status.String()
+		event.Reason = change.Reason
+	case api.ContainerStateChange:
+		event.Type = EventTypeContainer
+		event.TaskARN = change.TaskArn
+		event.ContainerName = change.ContainerName
+		event.RuntimeID = change.RuntimeID
+		event.Status = change.Status.String()
+		event.Reason = change.Reason
+		event.ExitCode = change.ExitCode
+		event.ImageDigest = change.ImageDigest
+	default:
+		return nil, false
+	}
+	return event, true
+}
@@ -0,0 +1,65 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eventsink
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEventTask(t *testing.T) {
+	event, ok := newEvent(api.TaskStateChange{
+		TaskARN: "t1",
+		Status:  apitaskstatus.TaskStopped,
+		Reason:  "Essential container in task exited",
+	})
+	require.True(t, ok)
+	assert.NotEmpty(t, event.ID)
+	assert.Equal(t, EventTypeTask, event.Type)
+	assert.Equal(t, "t1", event.TaskARN)
+	assert.Equal(t, "STOPPED", event.Status)
+	assert.Equal(t, "Essential container in task exited", event.Reason)
+}
+
+func TestNewEventContainer(t *testing.T) {
+	exitCode := 137
+	event, ok := newEvent(api.ContainerStateChange{
+		TaskArn:       "t1",
+		ContainerName: "c1",
+		RuntimeID:     "id",
+		Status:        apicontainerstatus.ContainerStopped,
+		ExitCode:      &exitCode,
+	})
+	require.True(t, ok)
+	assert.Equal(t, EventTypeContainer, event.Type)
+	assert.Equal(t, "c1", event.ContainerName)
+	assert.Equal(t, "id", event.RuntimeID)
+	assert.Equal(t, "STOPPED", event.Status)
+	assert.Equal(t, &exitCode, event.ExitCode)
+}
+
+func TestNewEventIgnoresOtherStateChanges(t *testing.T) {
+	_, ok := newEvent(api.TaskStateChange{TaskARN: "t1", Attachment: &apieni.ENIAttachment{}})
+	assert.False(t, ok)
+	_, ok = newEvent(api.ContainerDrift{TaskArn: "t1"})
+	assert.False(t, ok)
+}
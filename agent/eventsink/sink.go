@@ -0,0 +1,203 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eventsink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	// dialTimeout is the maximum time to connect to the Unix socket of a sink
+	dialTimeout = 5 * time.Second
+	// eventFilePermissions are the permissions of the files created by the
+	// file sinks
+	eventFilePermissions = 0644
+)
+
+// Sink is a destination of the events
+type Sink interface {
+	// Send delivers an event, encoded in json. An error means that the event
+	// might not have been delivered, and it's sent again.
+	Send(ctx context.Context, event []byte) error
+	// Close releases the resources of the sink
+	Close() error
+	// String describes the sink in logs
+	String() string
+}
+
+// newSink creates the sink of the configuration
+func newSink(cfg config.EventSink) (Sink, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Type {
+	case config.EventSinkTypeWebhook:
+		return &webhookSink{url: cfg.URL, client: &http.Client{}}, nil
+	case config.EventSinkTypeUnix:
+		return &unixSink{path: cfg.Path}, nil
+	default:
+		return &fileSink{path: cfg.Path}, nil
+	}
+}
+
+// webhookSink POSTs each event to a URL. The event is delivered once the
+// webhook responds with a 2xx status code.
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func (sink *webhookSink) Send(ctx context.Context, event []byte) error {
+	request, err := http.NewRequest(http.MethodPost, sink.url, bytes.NewReader(event))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := sink.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.Errorf("unexpected response status %s", response.Status)
+	}
+	return nil
+}
+
+func (sink *webhookSink) Close() error {
+	return nil
+}
+
+func (sink *webhookSink) String() string {
+	return fmt.Sprintf("webhook %s", sink.url)
+}
+
+// unixSink writes each event as a json line to a Unix socket. The connection
+// is kept open between events, and opened again after an error. A line is
+// never left incomplete on an open connection: if writing it times out, the
+// rest of it is written before the next event. A line can only be incomplete
+// at the end of a connection that failed, which readers should discard.
+type unixSink struct {
+	path string
+	conn net.Conn
+	// unwritten is the part of the last line that wasn't written, as writing
+	// it timed out
+	unwritten []byte
+	// unwrittenEvent is the event of the last line
+	unwrittenEvent []byte
+}
+
+func (sink *unixSink) Send(ctx context.Context, event []byte) error {
+	if sink.conn == nil {
+		dialer := net.Dialer{Timeout: dialTimeout}
+		conn, err := dialer.DialContext(ctx, "unix", sink.path)
+		if err != nil {
+			return err
+		}
+		sink.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		sink.conn.SetWriteDeadline(deadline)
+	}
+	if len(sink.unwritten) > 0 {
+		if err := sink.write(sink.unwritten); err != nil {
+			return err
+		}
+		// The event is delivered if it's the one whose line was completed
+		if bytes.Equal(sink.unwrittenEvent, event) {
+			return nil
+		}
+	}
+	sink.unwrittenEvent = event
+	return sink.write(append(event[:len(event):len(event)], '\n'))
+}
+
+// write writes the data to the connection. If the write times out, the data
+// that wasn't written is kept to be written first by the next Send. The
+// connection is closed after other errors.
+func (sink *unixSink) write(data []byte) error {
+	written, err := sink.conn.Write(data)
+	if err == nil {
+		sink.unwritten = nil
+		return nil
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		sink.unwritten = data[written:]
+		return err
+	}
+	sink.Close()
+	return err
+}
+
+func (sink *unixSink) Close() error {
+	sink.unwritten = nil
+	sink.unwrittenEvent = nil
+	if sink.conn == nil {
+		return nil
+	}
+	err := sink.conn.Close()
+	sink.conn = nil
+	return err
+}
+
+func (sink *unixSink) String() string {
+	return fmt.Sprintf("unix socket %s", sink.path)
+}
+
+// fileSink appends each event as a json line to a file. The file is kept open
+// between events, and opened again after an error.
+type fileSink struct {
+	path string
+	file *os.File
+}
+
+func (sink *fileSink) Send(ctx context.Context, event []byte) error {
+	if sink.file == nil {
+		file, err := os.OpenFile(sink.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, eventFilePermissions)
+		if err != nil {
+			return err
+		}
+		sink.file = file
+	}
+	if _, err := sink.file.Write(append(event, '\n')); err != nil {
+		sink.Close()
+		return err
+	}
+	return nil
+}
+
+func (sink *fileSink) Close() error {
+	if sink.file == nil {
+		return nil
+	}
+	err := sink.file.Close()
+	sink.file = nil
+	return err
+}
+
+func (sink *fileSink) String() string {
+	return fmt.Sprintf("file %s", sink.path)
+}
@@ -0,0 +1,201 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eventsink
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// spoolDirName is the directory, under the data directory, holding the
+	// events waiting to be delivered to the sinks
+	spoolDirName = "eventsinks"
+	// spoolFilePermissions are the permissions of the files holding the
+	// events waiting to be delivered to a sink
+	spoolFilePermissions = 0600
+	// spoolCompactThreshold is the number of delivered events at the start of
+	// a spool file beyond which the file is rewritten without them
+	spoolCompactThreshold = 1000
+	// maxSpoolLineSize is the maximum size of an event read from a spool file
+	maxSpoolLineSize = 1024 * 1024
+)
+
+// spool persists the events waiting to be delivered to a sink, as json lines
+// appended to a file, so that they're still delivered after the agent
+// restarts. Delivered events are removed from the file once it only holds
+// delivered events, or enough of them.
+type spool struct {
+	path string
+	file *os.File
+	// delivered is the number of events at the start of the file that have
+	// been delivered
+	delivered int
+}
+
+// openSpool opens the spool file of the sink in the directory, and returns it
+// along with the events it holds, which haven't been delivered yet
+func openSpool(dir string, sink Sink) (*spool, [][]byte, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to create directory %s", dir)
+	}
+	sum := sha256.Sum256([]byte(sink.String()))
+	path := filepath.Join(dir, hex.EncodeToString(sum[:])+".jsonl")
+
+	events, malformed, err := readSpool(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, spoolFilePermissions)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to open %s", path)
+	}
+	spool := &spool{path: path, file: file}
+	if malformed {
+		// The file is rewritten with the valid events only, so that the events
+		// appended next don't end up on the same line as a torn one
+		if err := spool.rewrite(events); err != nil {
+			file.Close()
+			return nil, nil, errors.Wrapf(err, "unable to rewrite %s", path)
+		}
+	}
+	return spool, events, nil
+}
+
+// readSpool returns the events of the spool file, and whether it's malformed.
+// Lines that aren't valid json, such as the last one if the agent stopped
+// while writing it, are skipped, and the file is malformed if it holds any, or
+// if its last line isn't terminated.
+func readSpool(path string) ([][]byte, bool, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "unable to open %s", path)
+	}
+	defer file.Close()
+
+	var events [][]byte
+	malformed := false
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxSpoolLineSize)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if advance > 0 && data[advance-1] != '\n' {
+			malformed = true
+		}
+		return advance, token, err
+	})
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			seelog.Warnf("Event sinks: skipping invalid event in %s", path)
+			malformed = true
+			continue
+		}
+		event := make([]byte, len(line))
+		copy(event, line)
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, errors.Wrapf(err, "unable to read %s", path)
+	}
+	return events, malformed, nil
+}
+
+// append persists an event at the end of the spool file
+func (spool *spool) append(event []byte) error {
+	_, err := spool.file.Write(append(event[:len(event):len(event)], '\n'))
+	return err
+}
+
+// markDelivered records that the first event of the spool file was
+// delivered, with the given number of events left. The file is emptied if no
+// event is left, and rewritten with the remaining events if enough events
+// were delivered.
+func (spool *spool) markDelivered(left int, remaining func() [][]byte) error {
+	spool.delivered++
+	if left == 0 {
+		spool.delivered = 0
+		return spool.file.Truncate(0)
+	}
+	if spool.delivered < spoolCompactThreshold {
+		return nil
+	}
+	spool.delivered = 0
+	return spool.rewrite(remaining())
+}
+
+// rewrite replaces the spool file with one holding the events
+func (spool *spool) rewrite(events [][]byte) error {
+	temp, err := ioutil.TempFile(filepath.Dir(spool.path), filepath.Base(spool.path)+".")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(temp)
+	for _, event := range events {
+		writer.Write(event)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Chmod(spoolFilePermissions); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := os.Rename(temp.Name(), spool.path); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+
+	file, err := os.OpenFile(spool.path, os.O_WRONLY|os.O_APPEND, spoolFilePermissions)
+	if err != nil {
+		return err
+	}
+	spool.file.Close()
+	spool.file = file
+	return nil
+}
+
+// close closes the spool file, after removing the delivered events from it.
+// The events it holds are delivered the next time it's opened.
+func (spool *spool) close(remaining [][]byte) error {
+	if spool.delivered > 0 {
+		if err := spool.rewrite(remaining); err != nil {
+			spool.file.Close()
+			return err
+		}
+	}
+	return spool.file.Close()
+}
@@ -33,8 +33,11 @@ type rootResponse struct {
 	AvailableCommands []string
 }
 
-func introspectionServerSetup(containerInstanceArn *string, taskEngine handlersutils.DockerStateResolver, cfg *config.Config) *http.Server {
-	paths := []string{v1.AgentMetadataPath, v1.TaskContainerMetadataPath, v1.LicensePath}
+func introspectionServerSetup(containerInstanceArn *string,
+	taskEngine handlersutils.DockerStateResolver,
+	pinnedImages handlersutils.PinnedImagesResolver,
+	cfg *config.Config) *http.Server {
+	paths := []string{v1.AgentMetadataPath, v1.TaskContainerMetadataPath, v1.PinnedImagesPath, v1.LicensePath}
 	availableCommands := &rootResponse{paths}
 	// Autogenerated list of the above serverFunctions paths
 	availableCommandResponse, err := json.Marshal(&availableCommands)
@@ -49,7 +52,7 @@ func introspectionServerSetup(containerInstanceArn *string, taskEngine handlersu
 	serverMux := http.NewServeMux()
 	serverMux.HandleFunc("/", defaultHandler)
 
-	v1HandlersSetup(serverMux, containerInstanceArn, taskEngine, cfg)
+	v1HandlersSetup(serverMux, containerInstanceArn, taskEngine, pinnedImages, cfg)
 
 	// Log all requests and then pass through to serverMux
 	loggingServeMux := http.NewServeMux()
@@ -69,9 +72,11 @@ func introspectionServerSetup(containerInstanceArn *string, taskEngine handlersu
 func v1HandlersSetup(serverMux *http.ServeMux,
 	containerInstanceArn *string,
 	taskEngine handlersutils.DockerStateResolver,
+	pinnedImages handlersutils.PinnedImagesResolver,
 	cfg *config.Config) {
 	serverMux.HandleFunc(v1.AgentMetadataPath, v1.AgentMetadataHandler(containerInstanceArn, cfg))
 	serverMux.HandleFunc(v1.TaskContainerMetadataPath, v1.TaskContainerMetadataHandler(taskEngine))
+	serverMux.HandleFunc(v1.PinnedImagesPath, v1.PinnedImagesHandler(pinnedImages))
 	serverMux.HandleFunc(v1.LicensePath, v1.LicenseHandler)
 }
 
@@ -83,7 +88,7 @@ func ServeIntrospectionHTTPEndpoint(ctx context.Context, containerInstanceArn *s
 	// Revisit if we ever add another type..
 	dockerTaskEngine := taskEngine.(*engine.DockerTaskEngine)
 
-	server := introspectionServerSetup(containerInstanceArn, dockerTaskEngine, cfg)
+	server := introspectionServerSetup(containerInstanceArn, dockerTaskEngine, dockerTaskEngine, cfg)
 
 	go func() {
 		<-ctx.Done()
@@ -21,6 +21,7 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
@@ -28,6 +29,7 @@ import (
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
 	mock_utils "github.com/aws/amazon-ecs-agent/agent/handlers/mocks"
 	v1 "github.com/aws/amazon-ecs-agent/agent/handlers/v1"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
@@ -195,6 +197,38 @@ func TestGetTaskByTaskArnAndDockerIDBadRequest(t *testing.T) {
 	}
 }
 
+func TestPinnedImagesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pulledAt := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+	mockPinnedImagesResolver := mock_utils.NewMockPinnedImagesResolver(ctrl)
+	mockPinnedImagesResolver.EXPECT().PinnedImages().Return([]image.PinnedImage{
+		{Name: "busybox:1.31", ImageID: "sha256:busybox", Status: image.PinnedImagePulled, PulledAt: pulledAt},
+		{Name: "private/image", Status: image.PinnedImageFailed, Error: "access denied"},
+		{Name: "amazonlinux", Status: image.PinnedImagePending},
+	})
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn),
+		mock_utils.NewMockDockerStateResolver(ctrl), mockPinnedImagesResolver, &config.Config{Cluster: testClusterArn})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", v1.PinnedImagesPath, nil)
+	requestHandler.Handler.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp v1.PinnedImagesResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Len(t, resp.PinnedImages, 3)
+	assert.Equal(t, "sha256:busybox", resp.PinnedImages[0].ImageID)
+	assert.Equal(t, "PULLED", resp.PinnedImages[0].Status)
+	require.NotNil(t, resp.PinnedImages[0].PulledAt)
+	assert.True(t, pulledAt.Equal(*resp.PinnedImages[0].PulledAt))
+	assert.Equal(t, "FAILED", resp.PinnedImages[1].Status)
+	assert.Equal(t, "access denied", resp.PinnedImages[1].Error)
+	assert.Equal(t, "PENDING", resp.PinnedImages[2].Status)
+	assert.Nil(t, resp.PinnedImages[2].PulledAt)
+}
+
 func TestBackendMismatchMapping(t *testing.T) {
 	// Test that a KnownStatus past a DesiredStatus suppresses the DesiredStatus output
 	ctrl := gomock.NewController(t)
@@ -410,7 +444,8 @@ func performMockRequest(t *testing.T, path string) *httptest.ResponseRecorder {
 	stateSetupHelper(state, testTasks)
 
 	mockStateResolver.EXPECT().State().Return(state)
-	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), mockStateResolver, &config.Config{Cluster: testClusterArn})
+	mockPinnedImagesResolver := mock_utils.NewMockPinnedImagesResolver(ctrl)
+	requestHandler := introspectionServerSetup(utils.Strptr(testContainerInstanceArn), mockStateResolver, mockPinnedImagesResolver, &config.Config{Cluster: testClusterArn})
 
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", path, nil)
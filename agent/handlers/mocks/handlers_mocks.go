@@ -13,7 +13,7 @@
 //
 
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/aws/amazon-ecs-agent/agent/handlers/utils (interfaces: DockerStateResolver)
+// Source: github.com/aws/amazon-ecs-agent/agent/handlers/utils (interfaces: DockerStateResolver,PinnedImagesResolver)
 
 // Package mock_utils is a generated GoMock package.
 package mock_utils
@@ -22,6 +22,7 @@ import (
 	reflect "reflect"
 
 	dockerstate "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	image "github.com/aws/amazon-ecs-agent/agent/engine/image"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -61,3 +62,40 @@ func (mr *MockDockerStateResolverMockRecorder) State() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*MockDockerStateResolver)(nil).State))
 }
+
+// MockPinnedImagesResolver is a mock of PinnedImagesResolver interface
+type MockPinnedImagesResolver struct {
+	ctrl     *gomock.Controller
+	recorder *MockPinnedImagesResolverMockRecorder
+}
+
+// MockPinnedImagesResolverMockRecorder is the mock recorder for MockPinnedImagesResolver
+type MockPinnedImagesResolverMockRecorder struct {
+	mock *MockPinnedImagesResolver
+}
+
+// NewMockPinnedImagesResolver creates a new mock instance
+func NewMockPinnedImagesResolver(ctrl *gomock.Controller) *MockPinnedImagesResolver {
+	mock := &MockPinnedImagesResolver{ctrl: ctrl}
+	mock.recorder = &MockPinnedImagesResolverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockPinnedImagesResolver) EXPECT() *MockPinnedImagesResolverMockRecorder {
+	return m.recorder
+}
+
+// PinnedImages mocks base method
+func (m *MockPinnedImagesResolver) PinnedImages() []image.PinnedImage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PinnedImages")
+	ret0, _ := ret[0].([]image.PinnedImage)
+	return ret0
+}
+
+// PinnedImages indicates an expected call of PinnedImages
+func (mr *MockPinnedImagesResolverMockRecorder) PinnedImages() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PinnedImages", reflect.TypeOf((*MockPinnedImagesResolver)(nil).PinnedImages))
+}
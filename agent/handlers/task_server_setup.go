@@ -22,12 +22,14 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	handlersutils "github.com/aws/amazon-ecs-agent/agent/handlers/utils"
 	v1 "github.com/aws/amazon-ecs-agent/agent/handlers/v1"
 	v2 "github.com/aws/amazon-ecs-agent/agent/handlers/v2"
 	v3 "github.com/aws/amazon-ecs-agent/agent/handlers/v3"
 	v4 "github.com/aws/amazon-ecs-agent/agent/handlers/v4"
+	v5 "github.com/aws/amazon-ecs-agent/agent/handlers/v5"
 	"github.com/aws/amazon-ecs-agent/agent/logger/audit"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
 	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
@@ -50,12 +52,14 @@ func taskServerSetup(credentialsManager credentials.Manager,
 	auditLogger audit.AuditLogger,
 	state dockerstate.TaskEngineState,
 	ecsClient api.ECSClient,
+	dockerClient dockerapi.DockerClient,
 	cluster string,
 	statsEngine stats.Engine,
 	steadyStateRate int,
 	burstRate int,
 	availabilityZone string,
-	containerInstanceArn string) *http.Server {
+	containerInstanceArn string,
+	taskEventStreams *v4.TaskEventStreams) *http.Server {
 	muxRouter := mux.NewRouter()
 
 	// Set this to false so that for request like "//v3//metadata/task"
@@ -69,7 +73,9 @@ func taskServerSetup(credentialsManager credentials.Manager,
 
 	v3HandlersSetup(muxRouter, state, ecsClient, statsEngine, cluster, availabilityZone, containerInstanceArn)
 
-	v4HandlersSetup(muxRouter, state, ecsClient, statsEngine, cluster, availabilityZone, containerInstanceArn)
+	v4HandlersSetup(muxRouter, state, ecsClient, statsEngine, cluster, availabilityZone, containerInstanceArn, taskEventStreams)
+
+	v5HandlersSetup(muxRouter, state, ecsClient, dockerClient, statsEngine, cluster, availabilityZone, containerInstanceArn)
 
 	limiter := tollbooth.NewLimiter(int64(steadyStateRate), nil)
 	limiter.SetOnLimitReached(handlersutils.LimitReachedHandler(auditLogger))
@@ -134,14 +140,16 @@ func v3HandlersSetup(muxRouter *mux.Router,
 	muxRouter.HandleFunc(v3.ContainerAssociationPath, v3.ContainerAssociationHandler(state))
 }
 
-// v4HandlerSetup adda all handlers in v4 package to the mux router
+// v4HandlerSetup adda all handlers in v4 package to the mux router. The task
+// events are streamed only if the task event streams are given.
 func v4HandlersSetup(muxRouter *mux.Router,
 	state dockerstate.TaskEngineState,
 	ecsClient api.ECSClient,
 	statsEngine stats.Engine,
 	cluster string,
 	availabilityZone string,
-	containerInstanceArn string) {
+	containerInstanceArn string,
+	taskEventStreams *v4.TaskEventStreams) {
 	muxRouter.HandleFunc(v4.ContainerMetadataPath, v4.ContainerMetadataHandler(state))
 	muxRouter.HandleFunc(v4.TaskMetadataPath, v4.TaskMetadataHandler(state, ecsClient, cluster, availabilityZone, containerInstanceArn, false))
 	muxRouter.HandleFunc(v4.TaskWithTagsMetadataPath, v4.TaskMetadataHandler(state, ecsClient, cluster, availabilityZone, containerInstanceArn, true))
@@ -150,31 +158,40 @@ func v4HandlersSetup(muxRouter *mux.Router,
 	muxRouter.HandleFunc(v4.ContainerAssociationsPath, v4.ContainerAssociationsHandler(state))
 	muxRouter.HandleFunc(v4.ContainerAssociationPathWithSlash, v4.ContainerAssociationHandler(state))
 	muxRouter.HandleFunc(v4.ContainerAssociationPath, v4.ContainerAssociationHandler(state))
+	if taskEventStreams != nil {
+		muxRouter.HandleFunc(v4.TaskEventsPath, v4.TaskEventsHandler(state, taskEventStreams))
+	}
+}
+
+// v5HandlersSetup adds all handlers in v5 package to the mux router.
+func v5HandlersSetup(muxRouter *mux.Router,
+	state dockerstate.TaskEngineState,
+	ecsClient api.ECSClient,
+	dockerClient dockerapi.DockerClient,
+	statsEngine stats.Engine,
+	cluster string,
+	availabilityZone string,
+	containerInstanceArn string) {
+	muxRouter.HandleFunc(v5.TaskMetadataPath, v5.TaskMetadataHandler(state, ecsClient, dockerClient, statsEngine, cluster, availabilityZone, containerInstanceArn))
 }
 
-// ServeTaskHTTPEndpoint serves task/container metadata, task/container stats, and IAM Role Credentials
-// for tasks being managed by the agent.
+// ServeTaskHTTPEndpoint serves task/container metadata, task/container stats, task event streams and
+// IAM Role Credentials for tasks being managed by the agent.
 func ServeTaskHTTPEndpoint(
 	ctx context.Context,
 	credentialsManager credentials.Manager,
+	auditLogger audit.AuditLogger,
 	state dockerstate.TaskEngineState,
 	ecsClient api.ECSClient,
+	dockerClient dockerapi.DockerClient,
 	containerInstanceArn string,
 	cfg *config.Config,
 	statsEngine stats.Engine,
-	availabilityZone string) {
-	// Create and initialize the audit log
-	logger, err := seelog.LoggerFromConfigAsString(audit.AuditLoggerConfig(cfg))
-	if err != nil {
-		seelog.Errorf("Error initializing the audit log: %v", err)
-		// If the logger cannot be initialized, use the provided dummy seelog.LoggerInterface, seelog.Disabled.
-		logger = seelog.Disabled
-	}
-
-	auditLogger := audit.NewAuditLog(containerInstanceArn, cfg, logger)
-
-	server := taskServerSetup(credentialsManager, auditLogger, state, ecsClient, cfg.Cluster, statsEngine,
-		cfg.TaskMetadataSteadyStateRate, cfg.TaskMetadataBurstRate, availabilityZone, containerInstanceArn)
+	availabilityZone string,
+	taskEventStreams *v4.TaskEventStreams) {
+	server := taskServerSetup(credentialsManager, auditLogger, state, ecsClient, dockerClient, cfg.Cluster, statsEngine,
+		cfg.TaskMetadataSteadyStateRate, cfg.TaskMetadataBurstRate, availabilityZone, containerInstanceArn,
+		taskEventStreams)
 
 	go func() {
 		<-ctx.Done()
@@ -16,16 +16,21 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ecs-agent/agent/api"
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
 	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
@@ -36,6 +41,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/containermetadata"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
+	mock_dockerapi "github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
 	mock_dockerstate "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
@@ -43,12 +49,14 @@ import (
 	v2 "github.com/aws/amazon-ecs-agent/agent/handlers/v2"
 	v3 "github.com/aws/amazon-ecs-agent/agent/handlers/v3"
 	v4 "github.com/aws/amazon-ecs-agent/agent/handlers/v4"
+	v5 "github.com/aws/amazon-ecs-agent/agent/handlers/v5"
 	mock_audit "github.com/aws/amazon-ecs-agent/agent/logger/audit/mocks"
 	mock_stats "github.com/aws/amazon-ecs-agent/agent/stats/mock"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/docker/docker/api/types"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -78,6 +86,7 @@ const (
 	v2BaseMetadataWithTagsPath = "/v2/metadataWithTags"
 	v3BasePath                 = "/v3/"
 	v4BasePath                 = "/v4/"
+	v5BasePath                 = "/v5/"
 	v3EndpointID               = "v3eid"
 	availabilityzone           = "us-west-2b"
 	containerInstanceArn       = "containerInstanceArn-test"
@@ -311,9 +320,21 @@ var (
 			}},
 		},
 	}
+	// The task limits of the v4 response replace the ones of the v2 response
+	expectedV4BaseTaskResponse = func() v2.TaskResponse {
+		resp := expectedTaskResponse
+		resp.Limits = nil
+		return resp
+	}()
 	expectedV4TaskResponse = v4.TaskResponse{
-		TaskResponse: &expectedTaskResponse,
+		TaskResponse: &expectedV4BaseTaskResponse,
 		Containers:   []v4.ContainerResponse{expectedV4ContainerResponse},
+		Limits: &v4.LimitsResponse{
+			LimitsResponse: &v2.LimitsResponse{
+				CPU:    aws.Float64(cpu),
+				Memory: aws.Int64(memory),
+			},
+		},
 	}
 	expectedV4BridgeContainerResponse = v4.ContainerResponse{
 		ContainerResponse: &expectedBridgeContainerResponse,
@@ -331,9 +352,20 @@ var (
 			}},
 		},
 	}
+	expectedV4BaseBridgeTaskResponse = func() v2.TaskResponse {
+		resp := expectedBridgeTaskResponse
+		resp.Limits = nil
+		return resp
+	}()
 	expectedV4BridgeTaskResponse = v4.TaskResponse{
-		TaskResponse: &expectedBridgeTaskResponse,
+		TaskResponse: &expectedV4BaseBridgeTaskResponse,
 		Containers:   []v4.ContainerResponse{expectedV4BridgeContainerResponse},
+		Limits: &v4.LimitsResponse{
+			LimitsResponse: &v2.LimitsResponse{
+				CPU:    aws.Float64(cpu),
+				Memory: aws.Int64(memory),
+			},
+		},
 	}
 )
 
@@ -480,8 +512,8 @@ func testErrorResponsesFromServer(t *testing.T, path string, expectedErrorMessag
 	credentialsManager := mock_credentials.NewMockManager(ctrl)
 	auditLog := mock_audit.NewMockAuditLogger(ctrl)
 	ecsClient := mock_api.NewMockECSClient(ctrl)
-	server := taskServerSetup(credentialsManager, auditLog, nil, ecsClient, "", nil, config.DefaultTaskMetadataSteadyStateRate,
-		config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentialsManager, auditLog, nil, ecsClient, nil, "", nil, config.DefaultTaskMetadataSteadyStateRate,
+		config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", path, nil)
@@ -515,8 +547,8 @@ func getResponseForCredentialsRequest(t *testing.T, expectedStatus int,
 	credentialsManager := mock_credentials.NewMockManager(ctrl)
 	auditLog := mock_audit.NewMockAuditLogger(ctrl)
 	ecsClient := mock_api.NewMockECSClient(ctrl)
-	server := taskServerSetup(credentialsManager, auditLog, nil, ecsClient, "", nil, config.DefaultTaskMetadataSteadyStateRate,
-		config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentialsManager, auditLog, nil, ecsClient, nil, "", nil, config.DefaultTaskMetadataSteadyStateRate,
+		config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 
 	creds, ok := getCredentials()
@@ -583,8 +615,8 @@ func TestV2TaskMetadata(t *testing.T) {
 				state.EXPECT().TaskByArn(taskARN).Return(task, true),
 				state.EXPECT().ContainerMapByArn(taskARN).Return(containerNameToDockerContainer, true),
 			)
-			server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-				config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn)
+			server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+				config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn, nil)
 			recorder := httptest.NewRecorder()
 			req, _ := http.NewRequest("GET", tc.path, nil)
 			req.RemoteAddr = remoteIP + ":" + remotePort
@@ -668,8 +700,8 @@ func TestV2TaskWithTagsMetadata(t *testing.T) {
 					},
 				}, nil),
 			)
-			server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-				config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn)
+			server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+				config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn, nil)
 			recorder := httptest.NewRecorder()
 			req, _ := http.NewRequest("GET", v2BaseMetadataWithTagsPath, nil)
 			req.RemoteAddr = remoteIP + ":" + remotePort
@@ -699,8 +731,8 @@ func TestV2ContainerMetadata(t *testing.T) {
 		state.EXPECT().ContainerByID(containerID).Return(dockerContainer, true),
 		state.EXPECT().TaskByID(containerID).Return(task, true),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v2BaseMetadataPath+"/"+containerID, nil)
 	req.RemoteAddr = remoteIP + ":" + remotePort
@@ -729,8 +761,8 @@ func TestV2ContainerStats(t *testing.T) {
 		state.EXPECT().GetTaskByIPAddress(remoteIP).Return(taskARN, true),
 		statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(dockerStats, nil),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v2BaseStatsPath+"/"+containerID, nil)
 	req.RemoteAddr = remoteIP + ":" + remotePort
@@ -778,8 +810,8 @@ func TestV2TaskStats(t *testing.T) {
 				state.EXPECT().ContainerMapByArn(taskARN).Return(containerMap, true),
 				statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(dockerStats, nil),
 			)
-			server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-				config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+			server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+				config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 			recorder := httptest.NewRecorder()
 			req, _ := http.NewRequest("GET", tc.path, nil)
 			req.RemoteAddr = remoteIP + ":" + remotePort
@@ -812,8 +844,8 @@ func TestV3TaskMetadata(t *testing.T) {
 		state.EXPECT().ContainerMapByArn(taskARN).Return(containerNameToDockerContainer, true),
 		state.EXPECT().TaskByArn(taskARN).Return(task, true),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v3BasePath+v3EndpointID+"/task", nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -842,8 +874,8 @@ func TestV3BridgeTaskMetadata(t *testing.T) {
 		state.EXPECT().TaskByArn(taskARN).Return(bridgeTask, true),
 		state.EXPECT().ContainerByID(containerID).Return(bridgeContainer, true),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v3BasePath+v3EndpointID+"/task", nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -871,8 +903,8 @@ func TestV3BridgeContainerMetadata(t *testing.T) {
 		state.EXPECT().TaskByID(containerID).Return(bridgeTask, true),
 		state.EXPECT().ContainerByID(containerID).Return(bridgeContainer, true),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v3BasePath+v3EndpointID, nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -942,8 +974,8 @@ func TestV3TaskMetadataWithTags(t *testing.T) {
 		}, nil),
 		state.EXPECT().TaskByArn(taskARN).Return(task, true),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v3BasePath+v3EndpointID+"/taskWithTags", nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -970,8 +1002,8 @@ func TestV3ContainerMetadata(t *testing.T) {
 		state.EXPECT().ContainerByID(containerID).Return(dockerContainer, true),
 		state.EXPECT().TaskByID(containerID).Return(task, true),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v3BasePath+v3EndpointID, nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1007,8 +1039,8 @@ func TestV3TaskStats(t *testing.T) {
 		state.EXPECT().ContainerMapByArn(taskARN).Return(containerMap, true),
 		statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(dockerStats, nil),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v3BasePath+v3EndpointID+"/task/stats", nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1040,8 +1072,8 @@ func TestV3ContainerStats(t *testing.T) {
 		state.EXPECT().DockerIDByV3EndpointID(v3EndpointID).Return(containerID, true),
 		statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(dockerStats, nil),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v3BasePath+v3EndpointID+"/stats", nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1069,8 +1101,8 @@ func TestV3ContainerAssociations(t *testing.T) {
 		state.EXPECT().ContainerByID(containerID).Return(dockerContainer, true),
 		state.EXPECT().TaskByArn(taskARN).Return(task, true),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v3BasePath+v3EndpointID+"/associations/"+associationType, nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1097,8 +1129,8 @@ func TestV3ContainerAssociation(t *testing.T) {
 		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
 		state.EXPECT().TaskByArn(taskARN).Return(task, true),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v3BasePath+v3EndpointID+"/associations/"+associationType+"/"+associationName, nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1124,8 +1156,8 @@ func TestV4TaskMetadata(t *testing.T) {
 		state.EXPECT().ContainerMapByArn(taskARN).Return(containerNameToDockerContainer, true),
 		state.EXPECT().TaskByArn(taskARN).Return(task, true).AnyTimes(),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/task", nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1155,8 +1187,8 @@ func TestV4ContainerMetadata(t *testing.T) {
 		state.EXPECT().ContainerByID(containerID).Return(dockerContainer, true),
 		state.EXPECT().TaskByID(containerID).Return(task, true).Times(2),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "us-west-2b", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "us-west-2b", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID, nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1233,8 +1265,8 @@ func TestV4TaskMetadataWithTags(t *testing.T) {
 		}, nil),
 		state.EXPECT().TaskByArn(taskARN).Return(task, true).AnyTimes(),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/taskWithTags", nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1263,12 +1295,12 @@ func TestV4BridgeTaskMetadata(t *testing.T) {
 		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
 		state.EXPECT().TaskByArn(taskARN).Return(bridgeTask, true),
 		state.EXPECT().ContainerMapByArn(taskARN).Return(containerNameToBridgeContainer, true),
-		state.EXPECT().TaskByArn(taskARN).Return(bridgeTask, true),
+		state.EXPECT().TaskByArn(taskARN).Return(bridgeTask, true).Times(2),
 		state.EXPECT().ContainerByID(containerID).Return(bridgeContainer, true),
 	)
 
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/task", nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1300,8 +1332,8 @@ func TestV4BridgeContainerMetadata(t *testing.T) {
 		state.EXPECT().ContainerByID(containerID).Return(bridgeContainer, true),
 	)
 
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID, nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1338,21 +1370,23 @@ func TestV4TaskStats(t *testing.T) {
 		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
 		state.EXPECT().ContainerMapByArn(taskARN).Return(containerMap, true),
 		statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(dockerStats, nil),
+		statsEngine.EXPECT().ContainerOOMKills(taskARN, containerID).Return(uint64(1), nil),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/task/stats", nil)
 	server.Handler.ServeHTTP(recorder, req)
 	res, err := ioutil.ReadAll(recorder.Body)
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, recorder.Code)
-	var statsFromResult map[string]*types.StatsJSON
+	var statsFromResult map[string]*v4.StatsResponse
 	err = json.Unmarshal(res, &statsFromResult)
 	assert.NoError(t, err)
 	containerStats, ok := statsFromResult[containerID]
 	assert.True(t, ok)
 	assert.Equal(t, dockerStats.NumProcs, containerStats.NumProcs)
+	assert.Equal(t, uint64(1), containerStats.OOMKills)
 }
 
 func TestV4ContainerStats(t *testing.T) {
@@ -1371,19 +1405,21 @@ func TestV4ContainerStats(t *testing.T) {
 		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
 		state.EXPECT().DockerIDByV3EndpointID(v3EndpointID).Return(containerID, true),
 		statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(dockerStats, nil),
+		statsEngine.EXPECT().ContainerOOMKills(taskARN, containerID).Return(uint64(2), nil),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/stats", nil)
 	server.Handler.ServeHTTP(recorder, req)
 	res, err := ioutil.ReadAll(recorder.Body)
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, recorder.Code)
-	var statsFromResult *types.StatsJSON
+	var statsFromResult *v4.StatsResponse
 	err = json.Unmarshal(res, &statsFromResult)
 	assert.NoError(t, err)
 	assert.Equal(t, dockerStats.NumProcs, statsFromResult.NumProcs)
+	assert.Equal(t, uint64(2), statsFromResult.OOMKills)
 }
 
 func TestV4ContainerAssociations(t *testing.T) {
@@ -1401,8 +1437,8 @@ func TestV4ContainerAssociations(t *testing.T) {
 		state.EXPECT().ContainerByID(containerID).Return(dockerContainer, true),
 		state.EXPECT().TaskByArn(taskARN).Return(task, true),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/associations/"+associationType, nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1429,7 +1465,7 @@ func TestV4ContainerAssociation(t *testing.T) {
 		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
 		state.EXPECT().TaskByArn(taskARN).Return(task, true),
 	)
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine, config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine, config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/associations/"+associationType+"/"+associationName, nil)
 	server.Handler.ServeHTTP(recorder, req)
@@ -1453,8 +1489,8 @@ func TestTaskHTTPEndpoint301Redirect(t *testing.T) {
 	statsEngine := mock_stats.NewMockEngine(ctrl)
 	ecsClient := mock_api.NewMockECSClient(ctrl)
 
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 
 	for testPath, expectedPath := range testPathsMap {
 		t.Run(fmt.Sprintf("Test path: %s", testPath), func(t *testing.T) {
@@ -1494,8 +1530,8 @@ func TestTaskHTTPEndpointErrorCode404(t *testing.T) {
 	statsEngine := mock_stats.NewMockEngine(ctrl)
 	ecsClient := mock_api.NewMockECSClient(ctrl)
 
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 
 	for _, testPath := range testPaths {
 		t.Run(fmt.Sprintf("Test path: %s", testPath), func(t *testing.T) {
@@ -1545,8 +1581,8 @@ func TestTaskHTTPEndpointErrorCode400(t *testing.T) {
 	statsEngine := mock_stats.NewMockEngine(ctrl)
 	ecsClient := mock_api.NewMockECSClient(ctrl)
 
-	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, clusterName, statsEngine,
-		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
 
 	for _, testPath := range testPaths {
 		t.Run(fmt.Sprintf("Test path: %s", testPath), func(t *testing.T) {
@@ -1564,3 +1600,186 @@ func TestTaskHTTPEndpointErrorCode400(t *testing.T) {
 		})
 	}
 }
+
+// readTaskEvent reads the next server-sent event of a task event stream
+func readTaskEvent(t *testing.T, reader *bufio.Reader) (string, map[string]interface{}) {
+	var eventType string
+	var data map[string]interface{}
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if eventType != "" {
+				return eventType, data
+			}
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data))
+		}
+	}
+}
+
+func TestV4TaskEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	statsEngine := mock_stats.NewMockEngine(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+
+	eventsTask := &apitask.Task{
+		Arn:               taskARN,
+		KnownStatusUnsafe: apitaskstatus.TaskRunning,
+		Containers:        []*apicontainer.Container{container},
+	}
+	gomock.InOrder(
+		state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true),
+		state.EXPECT().TaskByArn(taskARN).Return(eventsTask, true),
+		state.EXPECT().ContainerMapByArn(taskARN).Return(map[string]*apicontainer.DockerContainer{
+			containerName: dockerContainer,
+		}, true),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	taskEventStreams := v4.NewTaskEventStreams(ctx)
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn,
+		taskEventStreams)
+	testServer := httptest.NewServer(server.Handler)
+	defer testServer.Close()
+
+	response, err := http.Get(testServer.URL + v4BasePath + v3EndpointID + "/task/events")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+	reader := bufio.NewReader(response.Body)
+
+	// The current state of the task and its containers is sent first
+	eventType, data := readTaskEvent(t, reader)
+	assert.Equal(t, v4.TaskEventType, eventType)
+	assert.Equal(t, statusRunning, data["KnownStatus"])
+	eventType, data = readTaskEvent(t, reader)
+	assert.Equal(t, v4.ContainerEventType, eventType)
+	assert.Equal(t, containerName, data["Name"])
+	assert.Equal(t, containerID, data["DockerId"])
+	assert.Equal(t, statusRunning, data["KnownStatus"])
+
+	// Changes of other tasks aren't sent
+	exitCode := 1
+	taskEventStreams.Dispatch(api.TaskStateChange{TaskARN: "t2", Status: apitaskstatus.TaskStopped})
+	taskEventStreams.Dispatch(api.ContainerStateChange{
+		TaskArn:       taskARN,
+		ContainerName: containerName,
+		RuntimeID:     containerID,
+		Status:        apicontainerstatus.ContainerStopped,
+		ExitCode:      &exitCode,
+	})
+	eventType, data = readTaskEvent(t, reader)
+	assert.Equal(t, v4.ContainerEventType, eventType)
+	assert.Equal(t, "STOPPED", data["KnownStatus"])
+	assert.Equal(t, float64(exitCode), data["ExitCode"])
+}
+
+func TestV4TaskEventsNotServedWithoutStreams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	statsEngine := mock_stats.NewMockEngine(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, nil, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, "", containerInstanceArn, nil)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", v4BasePath+v3EndpointID+"/task/events", nil)
+	server.Handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestV5TaskMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	statsEngine := mock_stats.NewMockEngine(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+	dockerClient := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	dockerStats := &types.StatsJSON{}
+	dockerStats.CPUStats.CPUUsage.TotalUsage = 100
+	dockerStats.MemoryStats.Usage = 200
+	dockerStats.Networks = map[string]types.NetworkStats{"eth0": {RxBytes: 10, TxBytes: 20}}
+	dockerContainerJSON := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{RestartCount: 2},
+	}
+	state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true)
+	state.EXPECT().TaskByArn(taskARN).Return(task, true).AnyTimes()
+	state.EXPECT().ContainerMapByArn(taskARN).Return(containerNameToDockerContainer, true)
+	state.EXPECT().ENIByMac(macAddress).Return(&apieni.ENIAttachment{AttachmentARN: "attachment"}, true)
+	dockerClient.EXPECT().InspectContainer(gomock.Any(), containerID, gomock.Any()).Return(dockerContainerJSON, nil)
+	statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(dockerStats, nil)
+	statsEngine.EXPECT().ContainerOOMKills(taskARN, containerID).Return(uint64(1), nil)
+
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, dockerClient, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn, nil)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", v5BasePath+v3EndpointID+"/task", nil)
+	server.Handler.ServeHTTP(recorder, req)
+	res, err := ioutil.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var taskResponse v5.TaskResponse
+	require.NoError(t, json.Unmarshal(res, &taskResponse))
+
+	assert.Equal(t, taskARN, taskResponse.TaskARN)
+	require.Len(t, taskResponse.Containers, 1)
+	assert.Equal(t, containerName, taskResponse.Containers[0].Name)
+	require.NotNil(t, taskResponse.Containers[0].RestartCount)
+	assert.Equal(t, 2, *taskResponse.Containers[0].RestartCount)
+	require.NotNil(t, taskResponse.Containers[0].ResourceUsage)
+	assert.Equal(t, uint64(1), taskResponse.Containers[0].ResourceUsage.OOMKills)
+	require.NotNil(t, taskResponse.ResourceUsage)
+	assert.Equal(t, uint64(100), taskResponse.ResourceUsage.Total.CPUTotalUsage)
+	assert.Equal(t, uint64(200), taskResponse.ResourceUsage.Total.MemoryUsage)
+	assert.Equal(t, uint64(10), taskResponse.ResourceUsage.Total.NetworkRxBytes)
+	assert.Equal(t, uint64(20), taskResponse.ResourceUsage.Total.NetworkTxBytes)
+	require.Len(t, taskResponse.Attachments, 1)
+	assert.Equal(t, "attachment", taskResponse.Attachments[0].AttachmentARN)
+	assert.Equal(t, macAddress, taskResponse.Attachments[0].MACAddress)
+	assert.Equal(t, []string{eniIPv4Address}, taskResponse.Attachments[0].IPv4Addresses)
+}
+
+func TestV5TaskMetadataFields(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	statsEngine := mock_stats.NewMockEngine(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+	dockerClient := mock_dockerapi.NewMockDockerClient(ctrl)
+
+	state.EXPECT().TaskARNByV3EndpointID(v3EndpointID).Return(taskARN, true)
+	state.EXPECT().TaskByArn(taskARN).Return(task, true).AnyTimes()
+	state.EXPECT().ContainerMapByArn(taskARN).Return(containerNameToDockerContainer, true)
+	state.EXPECT().ENIByMac(macAddress).Return(nil, false)
+	dockerClient.EXPECT().InspectContainer(gomock.Any(), containerID, gomock.Any()).Return(nil, errors.New("error"))
+	statsEngine.EXPECT().ContainerDockerStats(taskARN, containerID).Return(nil, errors.New("error"))
+
+	server := taskServerSetup(credentials.NewManager(), auditLog, state, ecsClient, dockerClient, clusterName, statsEngine,
+		config.DefaultTaskMetadataSteadyStateRate, config.DefaultTaskMetadataBurstRate, availabilityzone, containerInstanceArn, nil)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", v5BasePath+v3EndpointID+"/task?fields=KnownStatus,Containers.Name", nil)
+	server.Handler.ServeHTTP(recorder, req)
+	res, err := ioutil.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"KnownStatus":"RUNNING","Containers":[{"Name":"sleepy"}]}`, string(res))
+}
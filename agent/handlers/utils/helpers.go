@@ -45,12 +45,18 @@ const (
 	// RequestTypeAgentMetadata specifies the Agent metadata request type of AgentMetadataHandler.
 	RequestTypeAgentMetadata = "agent metadata"
 
+	// RequestTypePinnedImages specifies the pinned images request type of PinnedImagesHandler.
+	RequestTypePinnedImages = "pinned images"
+
 	// RequestTypeContainerAssociations specifies the container associations request type of ContainerAssociationsHandler.
 	RequestTypeContainerAssociations = "container associations"
 
 	// RequestTypeContainerAssociation specifies the container association request type of ContainerAssociationHandler.
 	RequestTypeContainerAssociation = "container association"
 
+	// RequestTypeTaskEvents specifies the task events request type of TaskEventsHandler.
+	RequestTypeTaskEvents = "task events"
+
 	// AnythingButSlashRegEx is a regex pattern that matches any string without slash.
 	AnythingButSlashRegEx = "[^/]*"
 
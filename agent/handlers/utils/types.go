@@ -13,10 +13,19 @@
 
 package utils
 
-import "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+import (
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+)
 
 // DockerStateResolver is a sub-interface for the engine.TaskEngine interface
 // to make it easy to test code in this package
 type DockerStateResolver interface {
 	State() dockerstate.TaskEngineState
 }
+
+// PinnedImagesResolver is a sub-interface for the engine.DockerTaskEngine
+// returning the status of the images pinned on the instance
+type PinnedImagesResolver interface {
+	PinnedImages() []image.PinnedImage
+}
@@ -14,11 +14,14 @@
 package v1
 
 import (
+	"time"
+
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	"github.com/aws/amazon-ecs-agent/agent/containermetadata"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
 	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
 )
 
@@ -29,6 +32,20 @@ type MetadataResponse struct {
 	Version              string  `json:"Version"`
 }
 
+// PinnedImagesResponse is the schema for the pinned images response JSON object
+type PinnedImagesResponse struct {
+	PinnedImages []PinnedImageResponse `json:"PinnedImages"`
+}
+
+// PinnedImageResponse is the schema for the pinned image response JSON object
+type PinnedImageResponse struct {
+	Name     string     `json:"Name"`
+	ImageID  string     `json:"ImageId,omitempty"`
+	Status   string     `json:"Status"`
+	PulledAt *time.Time `json:"PulledAt,omitempty"`
+	Error    string     `json:"Error,omitempty"`
+}
+
 // TaskResponse is the schema for the task response JSON object
 type TaskResponse struct {
 	Arn           string              `json:"Arn"`
@@ -183,3 +200,22 @@ func NewTasksResponse(state dockerstate.TaskEngineState) *TasksResponse {
 
 	return &TasksResponse{Tasks: taskResponses}
 }
+
+// NewPinnedImagesResponse creates PinnedImagesResponse for the images pinned on the instance.
+func NewPinnedImagesResponse(pinnedImages []image.PinnedImage) *PinnedImagesResponse {
+	pinnedImageResponses := make([]PinnedImageResponse, len(pinnedImages))
+	for ndx, pinnedImage := range pinnedImages {
+		pinnedImageResponses[ndx] = PinnedImageResponse{
+			Name:    pinnedImage.Name,
+			ImageID: pinnedImage.ImageID,
+			Status:  string(pinnedImage.Status),
+			Error:   pinnedImage.Error,
+		}
+		if !pinnedImage.PulledAt.IsZero() {
+			pulledAt := pinnedImage.PulledAt
+			pinnedImageResponses[ndx].PulledAt = &pulledAt
+		}
+	}
+
+	return &PinnedImagesResponse{PinnedImages: pinnedImageResponses}
+}
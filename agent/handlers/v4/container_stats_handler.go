@@ -20,7 +20,6 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
-	v2 "github.com/aws/amazon-ecs-agent/agent/handlers/v2"
 	v3 "github.com/aws/amazon-ecs-agent/agent/handlers/v3"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
 	"github.com/cihub/seelog"
@@ -53,7 +52,29 @@ func ContainerStatsHandler(state dockerstate.TaskEngineState, statsEngine stats.
 		}
 
 		seelog.Infof("V4 container stats handler: writing response for container '%s'", containerID)
-		// v4 handler shares the same container states response format with v2 handler.
-		v2.WriteContainerStatsResponse(w, taskArn, containerID, statsEngine)
+		WriteContainerStatsResponse(w, taskArn, containerID, statsEngine)
 	}
 }
+
+// WriteContainerStatsResponse writes the container stats, along with the OOM
+// kill counter of the container, to response writer.
+func WriteContainerStatsResponse(w http.ResponseWriter,
+	taskARN string,
+	containerID string,
+	statsEngine stats.Engine) {
+	statsResponse, err := NewContainerStatsResponse(taskARN, containerID, statsEngine)
+	if err != nil {
+		errResponseJSON, err := json.Marshal("Unable to get container stats for: " + containerID)
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusBadRequest, errResponseJSON, utils.RequestTypeContainerStats)
+		return
+	}
+
+	responseJSON, err := json.Marshal(statsResponse)
+	if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+		return
+	}
+	utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, utils.RequestTypeContainerStats)
+}
@@ -18,10 +18,13 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/containermetadata"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
 	v2 "github.com/aws/amazon-ecs-agent/agent/handlers/v2"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control"
+	"github.com/cihub/seelog"
 	"github.com/pkg/errors"
 )
 
@@ -29,7 +32,30 @@ import (
 // with the v2 task response object.
 type TaskResponse struct {
 	*v2.TaskResponse
-	Containers []ContainerResponse `json:"Containers,omitempty"`
+	Containers    []ContainerResponse    `json:"Containers,omitempty"`
+	Limits        *LimitsResponse        `json:"Limits,omitempty"`
+	ResourceUsage *ResourceUsageResponse `json:"ResourceUsage,omitempty"`
+}
+
+// LimitsResponse is the v4 task limits response. It augments the v2 task
+// limits response with the limits of the processes and block IO of the task.
+type LimitsResponse struct {
+	*v2.LimitsResponse
+	Pids    *int64                 `json:"Pids,omitempty"`
+	BlockIO *BlockIOLimitsResponse `json:"BlockIO,omitempty"`
+}
+
+// BlockIOLimitsResponse defines the schema for the task block IO limits
+type BlockIOLimitsResponse struct {
+	Weight  *uint16                     `json:"Weight,omitempty"`
+	Devices []config.BlockIODeviceLimit `json:"Devices,omitempty"`
+}
+
+// ResourceUsageResponse defines the schema for the current resource usage of
+// the task cgroup
+type ResourceUsageResponse struct {
+	Pids    uint64                  `json:"Pids"`
+	BlockIO []control.DeviceIOUsage `json:"BlockIO,omitempty"`
 }
 
 // ContainerResponse is the v4 Container response. It augments the v4 Network response
@@ -89,11 +115,15 @@ func NewTaskResponse(
 	if err != nil {
 		return nil, err
 	}
+	task, ok := state.TaskByArn(taskARN)
+	if !ok {
+		return nil, errors.Errorf("v4 task response: unable to find task '%s'", taskARN)
+	}
 	var containers []ContainerResponse
 	// Convert each container response into v4 container response.
 	for i, container := range v2Resp.Containers {
 		networks, err := toV4NetworkResponse(container.Networks, func() (*apitask.Task, bool) {
-			return state.TaskByArn(taskARN)
+			return task, true
 		})
 		if err != nil {
 			return nil, err
@@ -104,10 +134,42 @@ func NewTaskResponse(
 		})
 	}
 
-	return &TaskResponse{
+	resp := &TaskResponse{
 		TaskResponse: v2Resp,
 		Containers:   containers,
-	}, nil
+	}
+	resp.Limits = newLimitsResponse(task, v2Resp.Limits)
+	if task.MemoryCPULimitsEnabled {
+		if usage, err := task.GetCgroupUsage(); err == nil {
+			resp.ResourceUsage = &ResourceUsageResponse{
+				Pids:    usage.Pids,
+				BlockIO: usage.BlockIO,
+			}
+		} else {
+			seelog.Debugf("v4 task response: unable to get the resource usage of task '%s': %v", taskARN, err)
+		}
+	}
+	return resp, nil
+}
+
+// newLimitsResponse creates the v4 task limits response from the v2 one
+func newLimitsResponse(task *apitask.Task, v2Limits *v2.LimitsResponse) *LimitsResponse {
+	limits := &LimitsResponse{LimitsResponse: v2Limits}
+	if task.PidsLimit > 0 {
+		pidsLimit := task.PidsLimit
+		limits.Pids = &pidsLimit
+	}
+	if task.BlockIOWeight != 0 || len(task.BlockIODeviceLimits) > 0 {
+		limits.BlockIO = &BlockIOLimitsResponse{Devices: task.BlockIODeviceLimits}
+		if task.BlockIOWeight != 0 {
+			weight := task.BlockIOWeight
+			limits.BlockIO.Weight = &weight
+		}
+	}
+	if limits.LimitsResponse == nil && limits.Pids == nil && limits.BlockIO == nil {
+		return nil
+	}
+	return limits
 }
 
 // NewContainerResponse creates a new v4 container response based on container id.  It augments
@@ -26,6 +26,7 @@ import (
 	mock_api "github.com/aws/amazon-ecs-agent/agent/api/mocks"
 	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
 	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
 	mock_dockerstate "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate/mocks"
 	"github.com/docker/docker/api/types"
 	"github.com/golang/mock/gomock"
@@ -145,3 +146,55 @@ func TestNewTaskContainerResponses(t *testing.T) {
 	assert.Equal(t, "192.168.0.0/24", containerResponse.Networks[0].IPV4SubnetCIDRBlock)
 	assert.Equal(t, subnetGatewayIPV4Address, containerResponse.Networks[0].SubnetGatewayIPV4Address)
 }
+
+func TestNewTaskResponseLimits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	state := mock_dockerstate.NewMockTaskEngineState(ctrl)
+	ecsClient := mock_api.NewMockECSClient(ctrl)
+	task := &apitask.Task{
+		Arn:                 taskARN,
+		Family:              family,
+		Version:             version,
+		DesiredStatusUnsafe: apitaskstatus.TaskRunning,
+		KnownStatusUnsafe:   apitaskstatus.TaskRunning,
+		Memory:              memory,
+		PidsLimit:           512,
+		BlockIOWeight:       200,
+		BlockIODeviceLimits: []config.BlockIODeviceLimit{
+			{Device: "259:0", WriteBps: 1048576},
+		},
+	}
+	gomock.InOrder(
+		state.EXPECT().TaskByArn(taskARN).Return(task, true),
+		state.EXPECT().ContainerMapByArn(taskARN).Return(map[string]*apicontainer.DockerContainer{}, true),
+		state.EXPECT().TaskByArn(taskARN).Return(task, true),
+	)
+
+	taskResponse, err := NewTaskResponse(taskARN, state, ecsClient, cluster, availabilityZone, containerInstanceArn, false)
+	require.NoError(t, err)
+	require.NotNil(t, taskResponse.Limits)
+	assert.Nil(t, taskResponse.ResourceUsage)
+
+	taskResponseJSON, err := json.Marshal(taskResponse)
+	require.NoError(t, err)
+	var limits struct {
+		Limits map[string]interface{}
+	}
+	require.NoError(t, json.Unmarshal(taskResponseJSON, &limits))
+	assert.Equal(t, map[string]interface{}{
+		"Memory": float64(memory),
+		"Pids":   float64(512),
+		"BlockIO": map[string]interface{}{
+			"Weight": float64(200),
+			"Devices": []interface{}{map[string]interface{}{
+				"Device":    "259:0",
+				"ReadBps":   float64(0),
+				"WriteBps":  float64(1048576),
+				"ReadIOPS":  float64(0),
+				"WriteIOPS": float64(0),
+			}},
+		},
+	}, limits.Limits)
+}
@@ -0,0 +1,206 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v4
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/statechange"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// TaskEventType is the type of the events about the status of the task
+	TaskEventType = "Task"
+	// ContainerEventType is the type of the events about the status, the
+	// health and the exit code of a container of the task
+	ContainerEventType = "Container"
+
+	// taskEventsBufferSize is the number of events waiting to be written to
+	// a stream. Streams whose client doesn't keep up are closed, and the
+	// client gets the current state of the task again when it reconnects.
+	taskEventsBufferSize = 100
+)
+
+// TaskEventResponse is the data of the task events of the stream
+type TaskEventResponse struct {
+	TaskARN     string `json:"TaskARN"`
+	KnownStatus string `json:"KnownStatus"`
+}
+
+// ContainerEventResponse is the data of the container events of the stream
+type ContainerEventResponse struct {
+	DockerID    string                     `json:"DockerId,omitempty"`
+	Name        string                     `json:"Name"`
+	KnownStatus string                     `json:"KnownStatus"`
+	ExitCode    *int                       `json:"ExitCode,omitempty"`
+	Health      *apicontainer.HealthStatus `json:"Health,omitempty"`
+}
+
+// taskEvent is an event of the stream of a task
+type taskEvent struct {
+	eventType string
+	data      interface{}
+}
+
+// TaskEventStreams fans the state changes of the engine out to the task event
+// streams open on the endpoint. Each stream gets the changes of its own task.
+type TaskEventStreams struct {
+	ctx     context.Context
+	streams map[*taskEventStream]struct{}
+	lock    sync.RWMutex
+}
+
+// taskEventStream is the stream of the events of a task open by a client
+type taskEventStream struct {
+	taskARN string
+	events  chan taskEvent
+	// overflowed is closed when the client doesn't keep up with the events
+	overflowed   chan struct{}
+	overflowOnce sync.Once
+}
+
+// NewTaskEventStreams creates the task event streams. Open streams are closed
+// when the context is done.
+func NewTaskEventStreams(ctx context.Context) *TaskEventStreams {
+	return &TaskEventStreams{
+		ctx:     ctx,
+		streams: make(map[*taskEventStream]struct{}),
+	}
+}
+
+// Dispatch writes the state change to the streams of its task. Changes other
+// than the ones of the status of tasks, and of the status and health of
+// containers, are ignored.
+func (streams *TaskEventStreams) Dispatch(change statechange.Event) {
+	taskARN, event, ok := newTaskEvent(change)
+	if !ok {
+		return
+	}
+	streams.lock.RLock()
+	defer streams.lock.RUnlock()
+	for stream := range streams.streams {
+		if stream.taskARN != taskARN {
+			continue
+		}
+		select {
+		case stream.events <- event:
+		default:
+			stream.overflowOnce.Do(func() {
+				seelog.Warnf("V4 task events: client of a stream of task '%s' isn't keeping up with the events, closing it",
+					taskARN)
+				close(stream.overflowed)
+			})
+		}
+	}
+}
+
+func (streams *TaskEventStreams) subscribe(taskARN string) *taskEventStream {
+	stream := &taskEventStream{
+		taskARN:    taskARN,
+		events:     make(chan taskEvent, taskEventsBufferSize),
+		overflowed: make(chan struct{}),
+	}
+	streams.lock.Lock()
+	defer streams.lock.Unlock()
+	streams.streams[stream] = struct{}{}
+	return stream
+}
+
+func (streams *TaskEventStreams) unsubscribe(stream *taskEventStream) {
+	streams.lock.Lock()
+	defer streams.lock.Unlock()
+	delete(streams.streams, stream)
+}
+
+// newTaskEvent creates the stream event of a state change, and returns the
+// task of the change
+func newTaskEvent(change statechange.Event) (string, taskEvent, bool) {
+	switch change := change.(type) {
+	case api.TaskStateChange:
+		if change.Attachment != nil {
+			return "", taskEvent{}, false
+		}
+		return change.TaskARN, taskEvent{TaskEventType, TaskEventResponse{
+			TaskARN:     change.TaskARN,
+			KnownStatus: change.Status.String(),
+		}}, true
+	case api.ContainerStateChange:
+		response := ContainerEventResponse{
+			DockerID:    change.RuntimeID,
+			Name:        change.ContainerName,
+			KnownStatus: change.Status.String(),
+			ExitCode:    change.ExitCode,
+		}
+		if change.Container != nil && change.Container.HealthStatusShouldBeReported() {
+			health := change.Container.GetHealthStatus()
+			response.Health = &health
+		}
+		return change.TaskArn, taskEvent{ContainerEventType, response}, true
+	case api.ContainerHealthChange:
+		health := change.Health
+		response := ContainerEventResponse{
+			DockerID: change.RuntimeID,
+			Name:     change.ContainerName,
+			Health:   &health,
+		}
+		if change.Container != nil {
+			response.KnownStatus = change.Container.GetKnownStatus().String()
+			response.ExitCode = change.Container.GetKnownExitCode()
+		}
+		return change.TaskArn, taskEvent{ContainerEventType, response}, true
+	default:
+		return "", taskEvent{}, false
+	}
+}
+
+// currentTaskEvents returns the events of the current status of the task and
+// of its containers, which are sent first to new streams
+func currentTaskEvents(taskARN string, state dockerstate.TaskEngineState) []taskEvent {
+	task, ok := state.TaskByArn(taskARN)
+	if !ok {
+		return nil
+	}
+	events := []taskEvent{{TaskEventType, TaskEventResponse{
+		TaskARN:     taskARN,
+		KnownStatus: task.GetKnownStatus().String(),
+	}}}
+	containers, ok := state.ContainerMapByArn(taskARN)
+	if !ok {
+		return events
+	}
+	for _, container := range task.Containers {
+		if container.IsInternal() {
+			continue
+		}
+		response := ContainerEventResponse{
+			Name:        container.Name,
+			KnownStatus: container.GetKnownStatus().String(),
+			ExitCode:    container.GetKnownExitCode(),
+		}
+		if dockerContainer, ok := containers[container.Name]; ok {
+			response.DockerID = dockerContainer.DockerID
+		}
+		if container.HealthStatusShouldBeReported() {
+			health := container.GetHealthStatus()
+			response.Health = &health
+		}
+		events = append(events, taskEvent{ContainerEventType, response})
+	}
+	return events
+}
@@ -0,0 +1,80 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v4
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTaskEventContainerHealthChange(t *testing.T) {
+	container := &apicontainer.Container{Name: "c1"}
+	container.SetKnownStatus(apicontainerstatus.ContainerRunning)
+	taskARN, event, ok := newTaskEvent(api.ContainerHealthChange{
+		TaskArn:       "t1",
+		ContainerName: "c1",
+		RuntimeID:     "id",
+		Health:        apicontainer.HealthStatus{Status: apicontainerstatus.ContainerUnhealthy},
+		Container:     container,
+	})
+	require.True(t, ok)
+	assert.Equal(t, "t1", taskARN)
+	assert.Equal(t, ContainerEventType, event.eventType)
+	response, ok := event.data.(ContainerEventResponse)
+	require.True(t, ok)
+	assert.Equal(t, "id", response.DockerID)
+	assert.Equal(t, "RUNNING", response.KnownStatus)
+	assert.Equal(t, apicontainerstatus.ContainerUnhealthy, response.Health.Status)
+}
+
+func TestNewTaskEventIgnoresDrift(t *testing.T) {
+	_, _, ok := newTaskEvent(api.ContainerDrift{TaskArn: "t1"})
+	assert.False(t, ok)
+}
+
+func TestTaskEventStreamsDispatch(t *testing.T) {
+	streams := NewTaskEventStreams(context.Background())
+	stream := streams.subscribe("t1")
+	otherStream := streams.subscribe("t2")
+
+	streams.Dispatch(api.ContainerStateChange{TaskArn: "t1", ContainerName: "c1",
+		Status: apicontainerstatus.ContainerRunning})
+	require.Len(t, stream.events, 1)
+	assert.Len(t, otherStream.events, 0)
+
+	// Streams that don't keep up are closed
+	for i := 0; i < taskEventsBufferSize; i++ {
+		streams.Dispatch(api.ContainerStateChange{TaskArn: "t1", ContainerName: "c1",
+			Status: apicontainerstatus.ContainerRunning})
+	}
+	select {
+	case <-stream.overflowed:
+	default:
+		t.Error("expected the stream to be closed once its buffer is full")
+	}
+
+	// Unsubscribed streams don't get the events anymore
+	streams.unsubscribe(otherStream)
+	streams.Dispatch(api.ContainerStateChange{TaskArn: "t2", ContainerName: "c1",
+		Status: apicontainerstatus.ContainerRunning})
+	assert.Len(t, otherStream.events, 0)
+}
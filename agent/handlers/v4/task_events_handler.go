@@ -0,0 +1,157 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v4
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	v3 "github.com/aws/amazon-ecs-agent/agent/handlers/v3"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// taskEventsKeepAliveInterval is the interval of the comments written to
+	// the streams without events, which keep the connections open and detect
+	// the clients that are gone
+	taskEventsKeepAliveInterval = 15 * time.Second
+	// taskEventsWriteTimeout is the maximum time to write an event to a
+	// stream
+	taskEventsWriteTimeout = 5 * time.Second
+	// taskEventsResponseHeader is the header of the responses of the streams.
+	// The connections are hijacked from the server, so that its write timeout
+	// doesn't apply to the streams, and the header is written as is.
+	taskEventsResponseHeader = "HTTP/1.1 200 OK\r\n" +
+		"Content-Type: text/event-stream\r\n" +
+		"Cache-Control: no-cache\r\n" +
+		"Connection: close\r\n\r\n"
+)
+
+// TaskEventsPath specifies the relative URI path for streaming the events of
+// the task as server-sent events
+var TaskEventsPath = "/v4/" + utils.ConstructMuxVar(v3.V3EndpointIDMuxName, utils.AnythingButSlashRegEx) + "/task/events"
+
+// TaskEventsHandler returns the handler method for streaming the changes of
+// the status of the task and of the status, health and exit code of its
+// containers. The current state of the task and of its containers is sent
+// first.
+func TaskEventsHandler(state dockerstate.TaskEngineState, streams *TaskEventStreams) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskARN, err := v3.GetTaskARNByRequest(r, state)
+		if err != nil {
+			responseJSON, err := json.Marshal(
+				fmt.Sprintf("V4 task events handler: unable to get task arn from request: %s", err.Error()))
+			if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+				return
+			}
+			utils.WriteJSONToResponse(w, http.StatusBadRequest, responseJSON, utils.RequestTypeTaskEvents)
+			return
+		}
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			responseJSON, _ := json.Marshal("V4 task events handler: streaming is not supported")
+			utils.WriteJSONToResponse(w, http.StatusInternalServerError, responseJSON, utils.RequestTypeTaskEvents)
+			return
+		}
+		// Subscribe before getting the current state of the task, so that no
+		// change is missed
+		stream := streams.subscribe(taskARN)
+		defer streams.unsubscribe(stream)
+
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			seelog.Errorf("V4 task events handler: unable to hijack connection for task '%s': %v", taskARN, err)
+			return
+		}
+		defer conn.Close()
+		seelog.Infof("V4 task events handler: streaming events of task '%s' to %s", taskARN, r.RemoteAddr)
+		streamTaskEvents(conn, buf.Writer, stream, streams, currentTaskEvents(taskARN, state))
+		seelog.Infof("V4 task events handler: stopped streaming events of task '%s' to %s", taskARN, r.RemoteAddr)
+	}
+}
+
+// streamTaskEvents writes the events of the stream to the connection, until
+// the client is gone or the stream is closed
+func streamTaskEvents(conn net.Conn, writer *bufio.Writer, stream *taskEventStream, streams *TaskEventStreams,
+	currentEvents []taskEvent) {
+	// The deadlines set by the server for the request don't apply to the
+	// stream. The client isn't expected to send anything, reading only
+	// detects when it's gone.
+	conn.SetReadDeadline(time.Time{})
+	gone := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, conn)
+		close(gone)
+	}()
+
+	eventID := 0
+	write := func(write func() error) error {
+		conn.SetWriteDeadline(time.Now().Add(taskEventsWriteTimeout))
+		if err := write(); err != nil {
+			return err
+		}
+		return writer.Flush()
+	}
+	writeEvent := func(event taskEvent) error {
+		data, err := json.Marshal(event.data)
+		if err != nil {
+			return err
+		}
+		eventID++
+		_, err = fmt.Fprintf(writer, "id: %d\nevent: %s\ndata: %s\n\n", eventID, event.eventType, data)
+		return err
+	}
+
+	err := write(func() error {
+		if _, err := writer.WriteString(taskEventsResponseHeader); err != nil {
+			return err
+		}
+		for _, event := range currentEvents {
+			if err := writeEvent(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	ticker := time.NewTicker(taskEventsKeepAliveInterval)
+	defer ticker.Stop()
+	for err == nil {
+		select {
+		case <-streams.ctx.Done():
+			return
+		case <-gone:
+			return
+		case <-stream.overflowed:
+			return
+		case event := <-stream.events:
+			err = write(func() error {
+				return writeEvent(event)
+			})
+		case <-ticker.C:
+			err = write(func() error {
+				_, err := writer.WriteString(": keep-alive\n\n")
+				return err
+			})
+		}
+	}
+	seelog.Debugf("V4 task events handler: unable to write events of task '%s': %v", stream.taskARN, err)
+}
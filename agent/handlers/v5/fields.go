@@ -0,0 +1,97 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v5
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fieldSelection is a set of fields of a JSON document to keep. Fields of
+// nested objects are selected with their path, such as "Containers.Name".
+// Selecting a field keeps all of its nested fields.
+type fieldSelection map[string]fieldSelection
+
+// parseFieldSelection parses the comma separated list of fields of the fields
+// query parameter. A nil selection, which keeps all the fields, is returned if
+// the list is empty.
+func parseFieldSelection(fields string) (fieldSelection, error) {
+	if strings.TrimSpace(fields) == "" {
+		return nil, nil
+	}
+	selection := make(fieldSelection)
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		current := selection
+		for _, name := range strings.Split(field, ".") {
+			if name == "" {
+				return nil, errors.Errorf("invalid field '%s'", field)
+			}
+			next, ok := current[name]
+			if !ok {
+				next = make(fieldSelection)
+				current[name] = next
+			}
+			current = next
+		}
+	}
+	return selection, nil
+}
+
+// apply marshals the value and keeps only the selected fields. The selection
+// applies to each element of lists.
+func (selection fieldSelection) apply(value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil || len(selection) == 0 {
+		return data, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers as they are, 64-bit counters don't fit in a float64
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(selection.filter(document))
+}
+
+func (selection fieldSelection) filter(document interface{}) interface{} {
+	if len(selection) == 0 {
+		return document
+	}
+	switch typed := document.(type) {
+	case map[string]interface{}:
+		filtered := make(map[string]interface{})
+		for name, nested := range selection {
+			if value, ok := typed[name]; ok {
+				filtered[name] = nested.filter(value)
+			}
+		}
+		return filtered
+	case []interface{}:
+		filtered := make([]interface{}, 0, len(typed))
+		for _, element := range typed {
+			filtered = append(filtered, selection.filter(element))
+		}
+		return filtered
+	default:
+		return document
+	}
+}
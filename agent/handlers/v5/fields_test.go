@@ -0,0 +1,105 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v5
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDocument struct {
+	KnownStatus string
+	Counter     uint64
+	Containers  []testContainer
+}
+
+type testContainer struct {
+	Name   string
+	Health *testHealth
+}
+
+type testHealth struct {
+	Status string
+	Output string
+}
+
+func TestParseFieldSelection(t *testing.T) {
+	selection, err := parseFieldSelection("KnownStatus, Containers.Name,Containers.Health.Status,")
+	require.NoError(t, err)
+	assert.Equal(t, fieldSelection{
+		"KnownStatus": {},
+		"Containers": {
+			"Name":   {},
+			"Health": {"Status": {}},
+		},
+	}, selection)
+
+	selection, err = parseFieldSelection("")
+	require.NoError(t, err)
+	assert.Nil(t, selection)
+
+	_, err = parseFieldSelection("Containers..Name")
+	assert.Error(t, err)
+}
+
+func TestFieldSelectionApply(t *testing.T) {
+	document := testDocument{
+		KnownStatus: "RUNNING",
+		Counter:     18446744073709551615,
+		Containers: []testContainer{
+			{Name: "c1", Health: &testHealth{Status: "HEALTHY", Output: "ok"}},
+			{Name: "c2"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		fields   string
+		expected string
+	}{
+		{
+			name:     "all fields",
+			fields:   "",
+			expected: `{"KnownStatus":"RUNNING","Counter":18446744073709551615,"Containers":[{"Name":"c1","Health":{"Status":"HEALTHY","Output":"ok"}},{"Name":"c2","Health":null}]}`,
+		},
+		{
+			name:     "top level fields",
+			fields:   "KnownStatus,Counter",
+			expected: `{"KnownStatus":"RUNNING","Counter":18446744073709551615}`,
+		},
+		{
+			name:     "nested fields of lists",
+			fields:   "Containers.Name,Containers.Health.Status",
+			expected: `{"Containers":[{"Name":"c1","Health":{"Status":"HEALTHY"}},{"Name":"c2","Health":null}]}`,
+		},
+		{
+			name:     "unknown fields",
+			fields:   "Unknown,Containers.Unknown",
+			expected: `{"Containers":[{},{}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selection, err := parseFieldSelection(tc.fields)
+			require.NoError(t, err)
+			data, err := selection.apply(document)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, string(data))
+		})
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v5
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	apiappmesh "github.com/aws/amazon-ecs-agent/agent/api/appmesh"
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	v4 "github.com/aws/amazon-ecs-agent/agent/handlers/v4"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
+	"github.com/cihub/seelog"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+// inspectContainersTimeout is the maximum time to inspect the containers of
+// the task. It's below the write timeout of the endpoint, containers that
+// aren't inspected in time are reported without their health check history
+// and restart count.
+const inspectContainersTimeout = 3 * time.Second
+
+// TaskResponse is the v5 task response. It augments the v4 task response with
+// the resource usage of the containers and their totals, the full health check
+// history of the containers, the ENI attachments and the App Mesh
+// configuration of the task.
+type TaskResponse struct {
+	*v4.TaskResponse
+	Containers    []ContainerResponse    `json:"Containers,omitempty"`
+	ResourceUsage *ResourceUsageResponse `json:"ResourceUsage,omitempty"`
+	Attachments   []AttachmentResponse   `json:"Attachments,omitempty"`
+	AppMesh       *apiappmesh.AppMesh    `json:"AppMesh,omitempty"`
+}
+
+// ContainerResponse is the v5 container response. It augments the v4
+// container response with the health check history, the restart count, the
+// firelens configuration, the environment files and the resource usage of the
+// container.
+type ContainerResponse struct {
+	*v4.ContainerResponse
+	Health                *HealthResponse              `json:"Health,omitempty"`
+	RestartCount          *int                         `json:"RestartCount,omitempty"`
+	FirelensConfiguration *apicontainer.FirelensConfig `json:"FirelensConfiguration,omitempty"`
+	EnvironmentFiles      []EnvironmentFileResponse    `json:"EnvironmentFiles,omitempty"`
+	ResourceUsage         *UsageResponse               `json:"ResourceUsage,omitempty"`
+}
+
+// EnvironmentFileResponse is an environment file of a container, along with
+// the names of the variables read from it once the container is created. The
+// values of the variables are never exposed.
+type EnvironmentFileResponse struct {
+	Type          string   `json:"Type"`
+	Value         string   `json:"Value"`
+	VariableNames []string `json:"VariableNames,omitempty"`
+}
+
+// HealthResponse is the health status of a container, along with the results
+// of its latest health checks as kept by docker. The output isn't truncated.
+type HealthResponse struct {
+	apicontainer.HealthStatus
+	History []HealthCheckResponse `json:"history,omitempty"`
+}
+
+// HealthCheckResponse is the result of a health check of a container
+type HealthCheckResponse struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	ExitCode int       `json:"exitCode"`
+	Output   string    `json:"output"`
+}
+
+// ResourceUsageResponse is the resource usage of the task. It augments the
+// v4 resource usage of the task cgroup with the totals of the resource usage
+// of the containers.
+type ResourceUsageResponse struct {
+	*v4.ResourceUsageResponse
+	Total UsageResponse `json:"Total"`
+}
+
+// UsageResponse is the resource usage of a container, or the total of the
+// containers of the task, since they started
+type UsageResponse struct {
+	// CPUTotalUsage is the CPU time used, in nanoseconds
+	CPUTotalUsage uint64 `json:"CPUTotalUsage"`
+	// MemoryUsage is the memory used, in bytes
+	MemoryUsage uint64 `json:"MemoryUsage"`
+	// MemoryMaxUsage is the maximum memory used, in bytes
+	MemoryMaxUsage uint64 `json:"MemoryMaxUsage"`
+	// NetworkRxBytes is the number of bytes received on the networks
+	NetworkRxBytes uint64 `json:"NetworkRxBytes"`
+	// NetworkTxBytes is the number of bytes sent on the networks
+	NetworkTxBytes uint64 `json:"NetworkTxBytes"`
+	// BlockIOReadBytes is the number of bytes read from block devices
+	BlockIOReadBytes uint64 `json:"BlockIOReadBytes"`
+	// BlockIOWriteBytes is the number of bytes written to block devices
+	BlockIOWriteBytes uint64 `json:"BlockIOWriteBytes"`
+	// OOMKills is the number of processes killed for running out of memory
+	OOMKills uint64 `json:"OOMKills"`
+}
+
+// AttachmentResponse is an ENI attached to the task
+type AttachmentResponse struct {
+	AttachmentARN                string   `json:"AttachmentARN,omitempty"`
+	Status                       string   `json:"Status,omitempty"`
+	ID                           string   `json:"ID"`
+	MACAddress                   string   `json:"MACAddress"`
+	IPv4Addresses                []string `json:"IPv4Addresses,omitempty"`
+	IPv6Addresses                []string `json:"IPv6Addresses,omitempty"`
+	PrivateDNSName               string   `json:"PrivateDNSName,omitempty"`
+	SubnetGatewayIPv4Address     string   `json:"SubnetGatewayIpv4Address,omitempty"`
+	DomainNameServers            []string `json:"DomainNameServers,omitempty"`
+	DomainNameSearchList         []string `json:"DomainNameSearchList,omitempty"`
+	InterfaceAssociationProtocol string   `json:"InterfaceAssociationProtocol,omitempty"`
+	VlanID                       string   `json:"VlanID,omitempty"`
+	TrunkInterfaceMACAddress     string   `json:"TrunkInterfaceMACAddress,omitempty"`
+}
+
+// NewTaskResponse creates a new v5 response object for the task. It augments
+// the v4 task response with the data of the stats engine, of the inspection
+// of the containers, and of the task.
+func NewTaskResponse(
+	ctx context.Context,
+	taskARN string,
+	state dockerstate.TaskEngineState,
+	ecsClient api.ECSClient,
+	statsEngine stats.Engine,
+	dockerClient dockerapi.DockerClient,
+	cluster string,
+	az string,
+	containerInstanceARN string,
+) (*TaskResponse, error) {
+	v4Resp, err := v4.NewTaskResponse(taskARN, state, ecsClient, cluster, az, containerInstanceARN, false)
+	if err != nil {
+		return nil, err
+	}
+	task, ok := state.TaskByArn(taskARN)
+	if !ok {
+		return nil, errors.Errorf("v5 task response: unable to find task '%s'", taskARN)
+	}
+	if !task.IsNetworkModeAWSVPC() {
+		// fill in non-awsvpc network details for container responses here
+		for i := range v4Resp.Containers {
+			networks, err := v4.GetContainerNetworkMetadata(v4Resp.Containers[i].ID, state)
+			if err != nil {
+				return nil, err
+			}
+			v4Resp.Containers[i].Networks = networks
+		}
+	}
+
+	resp := &TaskResponse{
+		TaskResponse: v4Resp,
+		Attachments:  newAttachmentsResponse(task, state),
+		AppMesh:      task.AppMesh,
+	}
+	if v4Resp.ResourceUsage != nil || len(v4Resp.Containers) > 0 {
+		resp.ResourceUsage = &ResourceUsageResponse{ResourceUsageResponse: v4Resp.ResourceUsage}
+	}
+
+	inspected := inspectContainers(ctx, dockerClient, v4Resp.Containers)
+	for i, v4Container := range v4Resp.Containers {
+		container := &ContainerResponse{ContainerResponse: &v4Resp.Containers[i]}
+		if taskContainer, ok := task.ContainerByName(v4Container.Name); ok {
+			container.FirelensConfiguration = taskContainer.GetFirelensConfig()
+			container.EnvironmentFiles = newEnvironmentFilesResponse(task, taskContainer.Name)
+		}
+		container.Health = newHealthResponse(v4Container.Health, inspected[v4Container.ID])
+		if dockerContainer, ok := inspected[v4Container.ID]; ok && dockerContainer.ContainerJSONBase != nil {
+			restartCount := dockerContainer.RestartCount
+			container.RestartCount = &restartCount
+		}
+		if usage, ok := newUsageResponse(taskARN, v4Container.ID, statsEngine); ok {
+			container.ResourceUsage = usage
+			resp.ResourceUsage.Total.add(usage)
+		}
+		resp.Containers = append(resp.Containers, *container)
+	}
+	return resp, nil
+}
+
+// newEnvironmentFilesResponse creates the responses of the environment files of
+// a container
+func newEnvironmentFilesResponse(task *apitask.Task, containerName string) []EnvironmentFileResponse {
+	var envfiles []EnvironmentFileResponse
+	for _, envfile := range task.GetResolvedEnvironmentFiles(containerName) {
+		envfiles = append(envfiles, EnvironmentFileResponse{
+			Type:          envfile.Type,
+			Value:         envfile.Value,
+			VariableNames: envfile.VariableNames,
+		})
+	}
+	return envfiles
+}
+
+// inspectContainers inspects the containers in parallel, and returns the ones
+// inspected in time by docker ID
+func inspectContainers(ctx context.Context, dockerClient dockerapi.DockerClient,
+	containers []v4.ContainerResponse) map[string]*types.ContainerJSON {
+	ctx, cancel := context.WithTimeout(ctx, inspectContainersTimeout)
+	defer cancel()
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	inspected := make(map[string]*types.ContainerJSON)
+	for _, container := range containers {
+		if container.ID == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(dockerID string) {
+			defer wg.Done()
+			dockerContainer, err := dockerClient.InspectContainer(ctx, dockerID, inspectContainersTimeout)
+			if err != nil {
+				seelog.Debugf("v5 task response: unable to inspect container '%s': %v", dockerID, err)
+				return
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			inspected[dockerID] = dockerContainer
+		}(container.ID)
+	}
+	wg.Wait()
+	return inspected
+}
+
+// newHealthResponse creates the health response of a container from its
+// health status and from the health check log kept by docker
+func newHealthResponse(health *apicontainer.HealthStatus, dockerContainer *types.ContainerJSON) *HealthResponse {
+	if health == nil {
+		return nil
+	}
+	resp := &HealthResponse{HealthStatus: *health}
+	if dockerContainer == nil || dockerContainer.ContainerJSONBase == nil || dockerContainer.State == nil ||
+		dockerContainer.State.Health == nil {
+		return resp
+	}
+	for _, result := range dockerContainer.State.Health.Log {
+		if result == nil {
+			continue
+		}
+		resp.History = append(resp.History, HealthCheckResponse{
+			Start:    result.Start,
+			End:      result.End,
+			ExitCode: result.ExitCode,
+			Output:   result.Output,
+		})
+	}
+	if len(resp.History) > 0 {
+		// The output of the health status is truncated by the agent
+		resp.Output = resp.History[len(resp.History)-1].Output
+	}
+	return resp
+}
+
+// newUsageResponse creates the resource usage response of a container from the
+// stats engine
+func newUsageResponse(taskARN string, dockerID string, statsEngine stats.Engine) (*UsageResponse, bool) {
+	if dockerID == "" {
+		return nil, false
+	}
+	dockerStats, err := statsEngine.ContainerDockerStats(taskARN, dockerID)
+	if err != nil || dockerStats == nil {
+		seelog.Debugf("v5 task response: unable to get stats of container '%s': %v", dockerID, err)
+		return nil, false
+	}
+	usage := &UsageResponse{
+		CPUTotalUsage:  dockerStats.CPUStats.CPUUsage.TotalUsage,
+		MemoryUsage:    dockerStats.MemoryStats.Usage,
+		MemoryMaxUsage: dockerStats.MemoryStats.MaxUsage,
+	}
+	for _, network := range dockerStats.Networks {
+		usage.NetworkRxBytes += network.RxBytes
+		usage.NetworkTxBytes += network.TxBytes
+	}
+	for _, entry := range dockerStats.BlkioStats.IoServiceBytesRecursive {
+		switch {
+		case strings.EqualFold(entry.Op, "read"):
+			usage.BlockIOReadBytes += entry.Value
+		case strings.EqualFold(entry.Op, "write"):
+			usage.BlockIOWriteBytes += entry.Value
+		}
+	}
+	if oomKills, err := statsEngine.ContainerOOMKills(taskARN, dockerID); err == nil {
+		usage.OOMKills = oomKills
+	}
+	return usage, true
+}
+
+// add adds the resource usage of a container to the totals
+func (total *UsageResponse) add(usage *UsageResponse) {
+	total.CPUTotalUsage += usage.CPUTotalUsage
+	total.MemoryUsage += usage.MemoryUsage
+	total.MemoryMaxUsage += usage.MemoryMaxUsage
+	total.NetworkRxBytes += usage.NetworkRxBytes
+	total.NetworkTxBytes += usage.NetworkTxBytes
+	total.BlockIOReadBytes += usage.BlockIOReadBytes
+	total.BlockIOWriteBytes += usage.BlockIOWriteBytes
+	total.OOMKills += usage.OOMKills
+}
+
+// newAttachmentsResponse creates the responses of the ENIs attached to the task
+func newAttachmentsResponse(task *apitask.Task, state dockerstate.TaskEngineState) []AttachmentResponse {
+	var attachments []AttachmentResponse
+	for _, eni := range task.ENIs {
+		attachment := AttachmentResponse{
+			ID:                           eni.ID,
+			MACAddress:                   eni.MacAddress,
+			IPv4Addresses:                eni.GetIPV4Addresses(),
+			IPv6Addresses:                eni.GetIPV6Addresses(),
+			PrivateDNSName:               eni.PrivateDNSName,
+			SubnetGatewayIPv4Address:     eni.SubnetGatewayIPV4Address,
+			DomainNameServers:            eni.DomainNameServers,
+			DomainNameSearchList:         eni.DomainNameSearchList,
+			InterfaceAssociationProtocol: eni.InterfaceAssociationProtocol,
+		}
+		if eni.InterfaceVlanProperties != nil {
+			attachment.VlanID = eni.InterfaceVlanProperties.VlanID
+			attachment.TrunkInterfaceMACAddress = eni.InterfaceVlanProperties.TrunkInterfaceMacAddress
+		}
+		if eniAttachment, ok := state.ENIByMac(eni.MacAddress); ok {
+			attachment.AttachmentARN = eniAttachment.AttachmentARN
+			attachment.Status = eniAttachment.Status.String()
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments
+}
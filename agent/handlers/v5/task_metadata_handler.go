@@ -0,0 +1,81 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package v5
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/dockerclient/dockerapi"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/utils"
+	v3 "github.com/aws/amazon-ecs-agent/agent/handlers/v3"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
+	"github.com/cihub/seelog"
+)
+
+// TaskMetadataPath specifies the relative URI path for serving task metadata.
+var TaskMetadataPath = "/v5/" + utils.ConstructMuxVar(v3.V3EndpointIDMuxName, utils.AnythingButSlashRegEx) + "/task"
+
+// fieldsQueryParameter is the query parameter selecting the fields of the
+// task metadata, such as "fields=KnownStatus,Containers.Name,Containers.Health"
+const fieldsQueryParameter = "fields"
+
+// TaskMetadataHandler returns the handler method for handling task metadata requests.
+func TaskMetadataHandler(state dockerstate.TaskEngineState, ecsClient api.ECSClient, dockerClient dockerapi.DockerClient,
+	statsEngine stats.Engine, cluster, az, containerInstanceArn string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var taskArn, err = v3.GetTaskARNByRequest(r, state)
+		if err != nil {
+			ResponseJSON, err := json.Marshal(fmt.Sprintf("V5 task metadata handler: unable to get task arn from request: %s", err.Error()))
+			if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+				return
+			}
+			utils.WriteJSONToResponse(w, http.StatusBadRequest, ResponseJSON, utils.RequestTypeTaskMetadata)
+			return
+		}
+
+		selection, err := parseFieldSelection(r.URL.Query().Get(fieldsQueryParameter))
+		if err != nil {
+			ResponseJSON, err := json.Marshal(fmt.Sprintf("V5 task metadata handler: %s", err.Error()))
+			if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+				return
+			}
+			utils.WriteJSONToResponse(w, http.StatusBadRequest, ResponseJSON, utils.RequestTypeTaskMetadata)
+			return
+		}
+
+		seelog.Infof("V5 taskMetadata handler: Writing response for task '%s'", taskArn)
+
+		taskResponse, err := NewTaskResponse(r.Context(), taskArn, state, ecsClient, statsEngine, dockerClient, cluster, az,
+			containerInstanceArn)
+		if err != nil {
+			seelog.Warnf("V5 taskMetadata handler: unable to generate metadata for task '%s': %v", taskArn, err)
+			errResponseJson, err := json.Marshal("Unable to generate metadata for v5 task: '" + taskArn + "'")
+			if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+				return
+			}
+			utils.WriteJSONToResponse(w, http.StatusBadRequest, errResponseJson, utils.RequestTypeTaskMetadata)
+			return
+		}
+
+		responseJSON, err := selection.apply(taskResponse)
+		if e := utils.WriteResponseIfMarshalError(w, err); e != nil {
+			return
+		}
+		utils.WriteJSONToResponse(w, http.StatusOK, responseJSON, utils.RequestTypeTaskMetadata)
+	}
+}
@@ -24,6 +24,7 @@ import (
 
 type AuditLogger interface {
 	Log(r request.LogRequest, httpResponseCode int, eventType string)
+	LogTaskAdmission(taskArn string, containerName string, rule string, decision string)
 	GetContainerInstanceArn() string
 	GetCluster() string
 }
@@ -59,6 +60,14 @@ func (a *auditLog) Log(r request.LogRequest, httpResponseCode int, eventType str
 	}
 }
 
+// LogTaskAdmission logs a decision of the task admission policy about a
+// host-level privilege requested by a container. Decisions are logged even if
+// the credentials audit log is disabled.
+func (a *auditLog) LogTaskAdmission(taskArn string, containerName string, rule string, decision string) {
+	a.logger.Info(constructTaskAdmissionAuditLogEntry(taskArn, containerName, rule, decision,
+		a.GetCluster(), a.GetContainerInstanceArn()))
+}
+
 func constructAuditLogEntry(r request.LogRequest, httpResponseCode int, eventType string,
 	cluster string, containerInstanceArn string) string {
 	commonAuditLogFields := constructCommonAuditLogEntryFields(r, httpResponseCode)
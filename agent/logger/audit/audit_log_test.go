@@ -46,6 +46,7 @@ const (
 
 	commonAuditLogEntryFieldCount = 6
 	getCredentialsEntryFieldCount = 4
+	taskAdmissionEntryFieldCount  = 9
 )
 
 func TestWritingToAuditLog(t *testing.T) {
@@ -164,6 +165,51 @@ func TestWritingToAuditLogWhenDisabled(t *testing.T) {
 	auditLogger.Log(request.LogRequest{Request: req, ARN: taskARN}, dummyResponseCode, GetCredentialsEventType(dummyRoleType))
 }
 
+func TestWritingTaskAdmissionToAuditLogWhenDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockInfoLogger := mock_infologger.NewMockInfoLogger(ctrl)
+	cfg := &config.Config{
+		Cluster:                     dummyCluster,
+		CredentialsAuditLogFile:     "foo.txt",
+		CredentialsAuditLogDisabled: true,
+	}
+	auditLogger := NewAuditLog(dummyContainerInstanceArn, cfg, mockInfoLogger)
+
+	mockInfoLogger.EXPECT().Info(gomock.Any()).Times(1)
+
+	auditLogger.LogTaskAdmission(taskARN, "container", "Privileged", "denied")
+}
+
+func TestWritingTaskAdmissionToAuditLog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockInfoLogger := mock_infologger.NewMockInfoLogger(ctrl)
+	cfg := &config.Config{
+		Cluster:                 dummyCluster,
+		CredentialsAuditLogFile: "foo.txt",
+	}
+	auditLogger := NewAuditLog(dummyContainerInstanceArn, cfg, mockInfoLogger)
+
+	mockInfoLogger.EXPECT().Info(gomock.Any()).Do(func(logLine string) {
+		tokens := strings.Split(logLine, " ")
+		assert.Equal(t, taskAdmissionEntryFieldCount, len(tokens), "Incorrect number of tokens in task admission audit log entry")
+		assert.Equal(t, "stripped", tokens[1], "decision does not match")
+		assert.Equal(t, taskARN, tokens[2], "task arn does not match")
+		assert.Equal(t, "container", tokens[3], "container name does not match")
+		assert.Equal(t, "Capabilities", tokens[4], "rule does not match")
+		assert.Equal(t, taskAdmissionEventType, tokens[5], "event type does not match")
+		auditLogVersion, _ := strconv.Atoi(tokens[6])
+		assert.Equal(t, taskAdmissionAuditLogVersion, auditLogVersion, "version does not match")
+		assert.Equal(t, dummyCluster, tokens[7], "cluster does not match")
+		assert.Equal(t, dummyContainerInstanceArn, tokens[8], "containerInstanceArn does not match")
+	})
+
+	auditLogger.LogTaskAdmission(taskARN, "container", "Capabilities", "stripped")
+}
+
 func TestConstructCommonAuditLogEntryFields(t *testing.T) {
 	req, _ := http.NewRequest("GET", "foo", nil)
 	req.RemoteAddr = dummyRemoteAddress
@@ -45,6 +45,22 @@ const (
 	// 7. event type ('GetCredentials, GetCredentialsExecutionRole')
 
 	getCredentialsAuditLogVersion = 2
+
+	taskAdmissionEventType = "TaskAdmission"
+
+	// taskAdmissionAuditLogVersion is the version of the task admission
+	// entries of the audit log
+	// Version '1', the fields are:
+	// 1. event time
+	// 2. decision ('denied' or 'stripped')
+	// 3. task arn
+	// 4. container name
+	// 5. rule of the policy
+	// 6. event type ('TaskAdmission')
+	// 7. version
+	// 8. cluster
+	// 9. container instance arn
+	taskAdmissionAuditLogVersion = 1
 )
 
 type commonAuditLogEntryFields struct {
@@ -125,6 +141,39 @@ func constructAuditLogEntryByType(eventType string, cluster string, containerIns
 	}
 }
 
+type taskAdmissionAuditLogEntryFields struct {
+	eventTime            string
+	decision             string
+	taskArn              string
+	containerName        string
+	rule                 string
+	eventType            string
+	version              int
+	cluster              string
+	containerInstanceArn string
+}
+
+func (t *taskAdmissionAuditLogEntryFields) string() string {
+	return fmt.Sprintf("%s %s %s %s %s %s %d %s %s", t.eventTime, t.decision, t.taskArn, t.containerName, t.rule,
+		t.eventType, t.version, t.cluster, t.containerInstanceArn)
+}
+
+func constructTaskAdmissionAuditLogEntry(taskArn string, containerName string, rule string, decision string,
+	cluster string, containerInstanceArn string) string {
+	fields := &taskAdmissionAuditLogEntryFields{
+		eventTime:            time.Now().UTC().Format(time.RFC3339),
+		decision:             populateField(decision),
+		taskArn:              populateField(taskArn),
+		containerName:        populateField(containerName),
+		rule:                 populateField(rule),
+		eventType:            taskAdmissionEventType,
+		version:              taskAdmissionAuditLogVersion,
+		cluster:              populateField(cluster),
+		containerInstanceArn: populateField(containerInstanceArn),
+	}
+	return fields.string()
+}
+
 func populateField(logField string) string {
 	if logField == "" {
 		logField = "-"
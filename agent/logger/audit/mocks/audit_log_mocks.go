@@ -88,6 +88,18 @@ func (mr *MockAuditLoggerMockRecorder) Log(arg0, arg1, arg2 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Log", reflect.TypeOf((*MockAuditLogger)(nil).Log), arg0, arg1, arg2)
 }
 
+// LogTaskAdmission mocks base method
+func (m *MockAuditLogger) LogTaskAdmission(arg0, arg1, arg2, arg3 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "LogTaskAdmission", arg0, arg1, arg2, arg3)
+}
+
+// LogTaskAdmission indicates an expected call of LogTaskAdmission
+func (mr *MockAuditLoggerMockRecorder) LogTaskAdmission(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogTaskAdmission", reflect.TypeOf((*MockAuditLogger)(nil).LogTaskAdmission), arg0, arg1, arg2, arg3)
+}
+
 // MockInfoLogger is a mock of InfoLogger interface
 type MockInfoLogger struct {
 	ctrl     *gomock.Controller
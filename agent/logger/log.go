@@ -46,6 +46,9 @@ type logConfig struct {
 	logfile       string
 	level         string
 	outputFormat  string
+	// consoleStderr indicates that the console logs are written to stderr
+	// rather than stdout
+	consoleStderr bool
 	lock          sync.Mutex
 }
 
@@ -77,8 +80,14 @@ func reloadConfig() {
 func seelogConfig() string {
 	c := `
 <seelog type="asyncloop" minlevel="` + Config.level + `">
-	<outputs formatid="` + Config.outputFormat + `">
+	<outputs formatid="` + Config.outputFormat + `">`
+	if Config.consoleStderr {
+		c += `
+		<custom name="stderr" />`
+	} else {
+		c += `
 		<console />`
+	}
 	c += platformLogConfig()
 	if Config.logfile != "" {
 		if Config.RolloverType == "size" {
@@ -122,6 +131,15 @@ func SetLevel(logLevel string) {
 	}
 }
 
+// SetConsoleStderr writes the console logs to stderr rather than stdout, so
+// that they don't get mixed up with the output of the agent on stdout
+func SetConsoleStderr() {
+	Config.lock.Lock()
+	defer Config.lock.Unlock()
+	Config.consoleStderr = true
+	reloadConfig()
+}
+
 // GetLevel gets the log level
 func GetLevel() string {
 	Config.lock.Lock()
@@ -171,6 +189,21 @@ func init() {
 		}
 	}
 
+	seelog.RegisterReceiver("stderr", &stderrReceiver{})
 	registerPlatformLogger()
 	reloadConfig()
 }
+
+// stderrReceiver fulfills the seelog.CustomReceiver interface, writing the
+// console logs to stderr
+type stderrReceiver struct{}
+
+// ReceiveMessage receives a formatted log line from seelog and writes it to stderr
+func (r *stderrReceiver) ReceiveMessage(message string, level seelog.LogLevel, context seelog.LogContextInterface) error {
+	_, err := fmt.Fprint(os.Stderr, message)
+	return err
+}
+
+func (r *stderrReceiver) AfterParse(initArgs seelog.CustomReceiverInitArgs) error { return nil }
+func (r *stderrReceiver) Flush()                                                  {}
+func (r *stderrReceiver) Close() error                                            { return nil }
@@ -207,6 +207,31 @@ func TestSeelogConfig_JSONOutput(t *testing.T) {
 </seelog>`, c)
 }
 
+func TestSeelogConfig_ConsoleStderr(t *testing.T) {
+	Config = &logConfig{
+		level:         DEFAULT_LOGLEVEL,
+		RolloverType:  DEFAULT_ROLLOVER_TYPE,
+		outputFormat:  DEFAULT_OUTPUT_FORMAT,
+		MaxFileSizeMB: DEFAULT_MAX_FILE_SIZE,
+		MaxRollCount:  DEFAULT_MAX_ROLL_COUNT,
+		consoleStderr: true,
+	}
+	c := seelogConfig()
+	require.Equal(t, `
+<seelog type="asyncloop" minlevel="info">
+	<outputs formatid="logfmt">
+		<custom name="stderr" />
+	</outputs>
+	<formats>
+		<format id="logfmt" format="%EcsAgentLogfmt" />
+		<format id="json" format="%EcsAgentJson" />
+		<format id="windows" format="%Msg" />
+	</formats>
+</seelog>`, c)
+	_, err := seelog.LoggerFromConfigAsString(c)
+	require.NoError(t, err)
+}
+
 type LogContextMock struct{}
 
 // Caller's function name.
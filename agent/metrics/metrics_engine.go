@@ -30,6 +30,10 @@ type MetricsEngine struct {
 	cfg            *config.Config
 	Registry       *prometheus.Registry
 	managedMetrics map[APIType]MetricsClient
+
+	imageCleanupReclaimedBytes *prometheus.CounterVec
+	imagePullQueueDepth        *prometheus.GaugeVec
+	imagePullWaitSeconds       *prometheus.SummaryVec
 }
 
 const (
@@ -91,6 +95,26 @@ func NewMetricsEngine(cfg *config.Config, registry *prometheus.Registry) *Metric
 		aClient := NewMetricsClient(managedAPI, metricsEngine.Registry)
 		metricsEngine.managedMetrics[managedAPI] = aClient
 	}
+	metricsEngine.imageCleanupReclaimedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: AgentNamespace,
+		Subsystem: ImageManagerSubsystem,
+		Name:      "reclaimed_bytes",
+		Help:      "Bytes of docker storage reclaimed by image cleanup",
+	}, []string{"Cleanup"})
+	metricsEngine.imagePullQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: AgentNamespace,
+		Subsystem: ImagePullSubsystem,
+		Name:      "queue_depth",
+		Help:      "Number of image pulls waiting for a registry",
+	}, []string{"Registry"})
+	metricsEngine.imagePullWaitSeconds = prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace: AgentNamespace,
+		Subsystem: ImagePullSubsystem,
+		Name:      "wait_seconds",
+		Help:      "Time image pulls waited for a registry",
+	}, []string{"Registry"})
+	metricsEngine.Registry.MustRegister(metricsEngine.imageCleanupReclaimedBytes,
+		metricsEngine.imagePullQueueDepth, metricsEngine.imagePullWaitSeconds)
 	return metricsEngine
 }
 
@@ -114,6 +138,43 @@ func (engine *MetricsEngine) RecordECSClientMetric(callName string) func() {
 	return engine.recordGenericMetric(ECSClient, callName)
 }
 
+// RecordImageCleanupReclaimedBytes adds the bytes of docker storage reclaimed
+// by an image cleanup of the given kind to the published metrics
+func (engine *MetricsEngine) RecordImageCleanupReclaimedBytes(cleanup string, bytes uint64) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.imageCleanupReclaimedBytes.WithLabelValues(cleanup).Add(float64(bytes))
+}
+
+// RecordImagePullQueueDepth publishes the number of image pulls waiting for
+// the given registry
+func (engine *MetricsEngine) RecordImagePullQueueDepth(registry string, depth int) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.imagePullQueueDepth.WithLabelValues(registry).Set(float64(depth))
+}
+
+// RecordImagePullWait publishes the time an image pull waited for the given
+// registry
+func (engine *MetricsEngine) RecordImagePullWait(registry string, wait time.Duration) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	engine.imagePullWaitSeconds.WithLabelValues(registry).Observe(wait.Seconds())
+}
+
+// RegisterCollector adds the metrics of the collector to the published metrics
+func (engine *MetricsEngine) RegisterCollector(collector prometheus.Collector) {
+	if engine == nil || !engine.collection {
+		return
+	}
+	if err := engine.Registry.Register(collector); err != nil {
+		seelog.Errorf("Unable to register metrics collector: %v", err)
+	}
+}
+
 // Records a call's start and returns a function to be deferred.
 // Wrapper functions will use this function for GenericMetricsClients.
 // If Metrics collection is enabled from the cfg, we record a metric with callID
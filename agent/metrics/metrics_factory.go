@@ -26,6 +26,10 @@ const (
 	TaskEngineSubsystem   = "TaskEngine"
 	StateManagerSubsystem = "StateManager"
 	ECSClientSubsystem    = "ECSClient"
+	ImageManagerSubsystem = "ImageManager"
+	ImagePullSubsystem    = "ImagePull"
+	TaskSubsystem         = "Task"
+	ContainerSubsystem    = "Container"
 )
 
 // A factory method that enables various MetricsClients to be created.
@@ -25,6 +25,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Create default config for Metrics. PrometheusMetricsEnabled is set to false
@@ -64,6 +65,28 @@ func TestDisablePrometheusMetrics(t *testing.T) {
 	assert.False(t, MetricsEngineGlobal.collection)
 }
 
+// Tests that the bytes reclaimed by image cleanups are added up per kind of
+// cleanup
+func TestRecordImageCleanupReclaimedBytes(t *testing.T) {
+	defer func() {
+		MetricsEngineGlobal = &MetricsEngine{
+			collection: false,
+		}
+	}()
+	cfg := getTestConfig()
+	registry := prometheus.NewRegistry()
+	MustInit(&cfg, registry)
+	MetricsEngineGlobal.RecordImageCleanupReclaimedBytes("DiskPressure", 1024)
+	MetricsEngineGlobal.RecordImageCleanupReclaimedBytes("DiskPressure", 2048)
+
+	metricFamilies, err := registry.Gather()
+	assert.NoError(t, err)
+	require.Len(t, metricFamilies, 1)
+	assert.Equal(t, "AgentMetrics_ImageManager_reclaimed_bytes", metricFamilies[0].GetName())
+	require.Len(t, metricFamilies[0].GetMetric(), 1)
+	assert.Equal(t, float64(3072), metricFamilies[0].GetMetric()[0].GetCounter().GetValue())
+}
+
 // Mimicks metric collection of Docker API calls through Go routines. The method
 // call to record a metric is the same used by various clients throughout Agent.
 // We sleep the go routine to simulate "work" being done.
@@ -25,6 +25,15 @@ const (
 	// AttachmentEvent is used to define the attachment state transition events
 	// emitted by ENI watcher
 	AttachmentEvent
+
+	// ContainerDriftEvent is used to define the events emitted by the engine
+	// when a running container no longer matches the configuration it was
+	// created with
+	ContainerDriftEvent
+
+	// ContainerHealthEvent is used to define the events emitted by the engine
+	// when the health status of a container changes
+	ContainerHealthEvent
 )
 
 // Event defines the type of state change event
@@ -38,3 +47,10 @@ type Event interface {
 	// identify the type of event being emitted
 	GetEventType() EventType
 }
+
+// Listener is notified of state change events as they happen, such as by the
+// event sinks and the task event streams of the metadata endpoint
+type Listener interface {
+	// Dispatch is called with each state change event. It must not block.
+	Dispatch(event Event)
+}
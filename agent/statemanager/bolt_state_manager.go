@@ -0,0 +1,420 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package statemanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// ecsDataDBFile specifies the filename of the BoltDB state store in the
+	// ECS_DATADIR
+	ecsDataDBFile = "ecs_agent_data.db"
+
+	// migratedDataFileSuffix is appended to the name of ecs_agent_data.json
+	// once its content has been migrated into the BoltDB state store
+	migratedDataFileSuffix = ".migrated"
+
+	// dbOpenTimeout specifies how long to wait for the lock on the database
+	// file when opening it
+	dbOpenTimeout = 10 * time.Second
+
+	// metadataBucket holds the version of the saved data
+	metadataBucket = "metadata"
+
+	// saveablesBucket holds every saveable that is not a RecordSaveable, keyed
+	// by the name it was added with
+	saveablesBucket = "saveables"
+
+	// versionKey is the key of the data version in the metadata bucket
+	versionKey = "version"
+
+	// bucketPathSeparator separates the names of nested buckets in a bucket
+	// path. Records of a RecordSaveable live in the "<name>/<record type>"
+	// bucket.
+	bucketPathSeparator = "/"
+)
+
+// snapshot is the content of the database, keyed by bucket path and then by
+// key within the bucket
+type snapshot map[string]map[string][]byte
+
+/*
+The BoltDB state store keeps the state in a single BoltDB file in the
+ECS_DATADIR. Every saveable is stored separately: a RecordSaveable (such as the
+task engine) is split into one key per task, container, image state and ENI
+attachment, while any other saveable is stored as one json value.
+
+On each save, the state is compared with what was committed by the previous
+save and only the keys that changed are written (or deleted) in a single
+transaction. A ChangedRecordSaveable only marshals the records that changed
+since the previous save; its other records are carried over from what was
+committed. The transaction is synced to disk before Save returns, so there is
+no window in which a state transition can be lost, and saves do not need to be
+rate limited the way the json state file is.
+
+On the first load, if the database is empty and an ecs_agent_data.json file
+from the json state store exists, its content is migrated into the database and
+the file is renamed so that the migration only happens once.
+*/
+type boltStateManager struct {
+	statePath string // The directory of the database file
+
+	db *bolt.DB
+
+	state *state // pointers to the data we should save / load into
+
+	savingLock sync.Mutex // guards save, load, committed and resync
+	committed  snapshot   // what the last save or load committed to the database
+	// resync is set until a save succeeds, so that every record is marshaled
+	// by the first save and by the save following a failed one, whose
+	// changed records were not committed
+	resync bool
+}
+
+func newBoltStateManager(statePath string, state *state) (*boltStateManager, error) {
+	db, err := bolt.Open(filepath.Join(statePath, ecsDataDBFile), 0600, &bolt.Options{Timeout: dbOpenTimeout})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open state database")
+	}
+	return &boltStateManager{
+		statePath: statePath,
+		db:        db,
+		state:     state,
+		committed: make(snapshot),
+		resync:    true,
+	}, nil
+}
+
+func (manager *boltStateManager) addSaveable(name string, saveable Saveable) {
+	manager.state.Data[name] = &saveable
+}
+
+// Save commits the changes made to the saveables since the last save to the
+// database.
+func (manager *boltStateManager) Save() error {
+	defer metrics.MetricsEngineGlobal.RecordStateManagerMetric("SAVE")()
+	return manager.ForceSave()
+}
+
+// ForceSave commits the changes made to the saveables since the last save to
+// the database.
+func (manager *boltStateManager) ForceSave() error {
+	manager.savingLock.Lock()
+	defer manager.savingLock.Unlock()
+	return manager.saveUnsafe()
+}
+
+func (manager *boltStateManager) saveUnsafe() error {
+	current, err := manager.snapshot()
+	if err != nil {
+		seelog.Errorf("Error saving state; could not marshal data: %v", err)
+		manager.resync = true
+		return err
+	}
+
+	puts, deletes := 0, 0
+	err = manager.db.Update(func(tx *bolt.Tx) error {
+		for path, values := range current {
+			committed := manager.committed[path]
+			var bucket *bolt.Bucket
+			for key, value := range values {
+				if committedValue, ok := committed[key]; ok && bytes.Equal(committedValue, value) {
+					continue
+				}
+				if bucket == nil {
+					var err error
+					if bucket, err = createBucketPath(tx, path); err != nil {
+						return err
+					}
+				}
+				if err := bucket.Put([]byte(key), value); err != nil {
+					return errors.Wrapf(err, "unable to save key %s in bucket %s", key, path)
+				}
+				puts++
+			}
+		}
+		for path, values := range manager.committed {
+			bucket := bucketPath(tx, path)
+			if bucket == nil {
+				continue
+			}
+			for key := range values {
+				if _, ok := current[path][key]; ok {
+					continue
+				}
+				if err := bucket.Delete([]byte(key)); err != nil {
+					return errors.Wrapf(err, "unable to delete key %s in bucket %s", key, path)
+				}
+				deletes++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		seelog.Errorf("Error saving state; could not commit to the state database: %v", err)
+		manager.resync = true
+		return err
+	}
+	manager.committed = current
+	manager.resync = false
+	seelog.Debugf("Saved state: %d keys written, %d keys deleted", puts, deletes)
+	return nil
+}
+
+// snapshot returns the current content of the saveables, in the layout it is
+// stored in the database
+func (manager *boltStateManager) snapshot() (snapshot, error) {
+	current := snapshot{
+		metadataBucket: map[string][]byte{
+			versionKey: []byte(strconv.Itoa(ECSDataVersion)),
+		},
+		saveablesBucket: make(map[string][]byte),
+	}
+	for name, saveable := range manager.state.Data {
+		if recordSaveable, ok := (*saveable).(RecordSaveable); ok {
+			records, err := manager.records(name, recordSaveable)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to get records of %s", name)
+			}
+			for recordType, values := range records {
+				current[name+bucketPathSeparator+recordType] = values
+			}
+			continue
+		}
+		data, err := json.Marshal(saveable)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to marshal %s", name)
+		}
+		current[saveablesBucket][name] = data
+	}
+	return current, nil
+}
+
+// records returns the current records of the saveable. If it keeps track of
+// its changes, only the records that changed are marshaled, and the others are
+// carried over from what was committed.
+func (manager *boltStateManager) records(name string, saveable RecordSaveable) (map[string]map[string][]byte, error) {
+	changedSaveable, ok := saveable.(ChangedRecordSaveable)
+	if !ok || manager.resync {
+		return saveable.Records()
+	}
+	changed, records, err := changedSaveable.ChangedRecords()
+	if err != nil {
+		return nil, err
+	}
+	for recordType, values := range changed {
+		committed := manager.committed[name+bucketPathSeparator+recordType]
+		current := make(map[string][]byte, len(committed)+len(values))
+		for key, value := range committed {
+			current[key] = value
+		}
+		for key, value := range values {
+			if value == nil {
+				delete(current, key)
+				continue
+			}
+			current[key] = value
+		}
+		records[recordType] = current
+	}
+	return records, nil
+}
+
+// Load reads the state from the database and loads it into the saveables. If
+// the database is empty, the state is migrated from the json state file
+// instead, if there is one.
+func (manager *boltStateManager) Load() error {
+	manager.savingLock.Lock()
+	defer manager.savingLock.Unlock()
+
+	seelog.Info("Loading state!")
+	loaded, err := manager.read()
+	if err != nil {
+		seelog.Errorf("Error reading the state database: %v", err)
+		return err
+	}
+	version, ok := loaded[metadataBucket][versionKey]
+	if !ok {
+		return manager.migrateFromJSONUnsafe()
+	}
+	savedVersion, err := strconv.Atoi(string(version))
+	if err != nil {
+		seelog.Criticalf("Could not parse version of the state database; corrupted data? %v", err)
+		return err
+	}
+	if savedVersion > ECSDataVersion {
+		return errors.New("Unsupported data format: Version " + string(version) + " not " + strconv.Itoa(ECSDataVersion))
+	}
+
+	records := make(map[string]map[string]map[string][]byte)
+	for path, values := range loaded {
+		parts := strings.SplitN(path, bucketPathSeparator, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if _, ok := records[parts[0]]; !ok {
+			records[parts[0]] = make(map[string]map[string][]byte)
+		}
+		records[parts[0]][parts[1]] = values
+	}
+
+	for name, saveable := range manager.state.Data {
+		if recordSaveable, ok := (*saveable).(RecordSaveable); ok {
+			saveableRecords, ok := records[name]
+			if !ok {
+				continue
+			}
+			if err := recordSaveable.LoadRecords(saveableRecords); err != nil {
+				seelog.Debugf("Could not load records of %s", name)
+				return err
+			}
+			continue
+		}
+		data, ok := loaded[saveablesBucket][name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(data, saveable); err != nil {
+			seelog.Debugf("Could not unmarshal %s", name)
+			return err
+		}
+	}
+	for name := range loaded[saveablesBucket] {
+		if _, ok := manager.state.Data[name]; !ok {
+			seelog.Error("Loading state: unknown saveable of " + name)
+		}
+	}
+
+	manager.committed = loaded
+	seelog.Debug("Loaded state!")
+	return nil
+}
+
+// read returns the whole content of the database
+func (manager *boltStateManager) read() (snapshot, error) {
+	loaded := make(snapshot)
+	err := manager.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			return readBucket(loaded, string(name), bucket)
+		})
+	})
+	return loaded, err
+}
+
+// migrateFromJSONUnsafe loads the state from the json state file into the
+// saveables, commits it to the database and then renames the state file so
+// that it isn't migrated again. The state file is left untouched if it can't
+// be loaded, for example because it was written by a newer version of the
+// agent.
+func (manager *boltStateManager) migrateFromJSONUnsafe() error {
+	jsonManager := newBasicStateManager(manager.statePath, manager.state)
+	data, err := jsonManager.readFile()
+	if err != nil {
+		seelog.Errorf("Error reading existing state file to migrate: %v", err)
+		return err
+	}
+	if data == nil {
+		// Nothing to migrate; happens every first run
+		return nil
+	}
+
+	seelog.Infof("Migrating state from %s to %s", ecsDataFile, ecsDataDBFile)
+	if err := jsonManager.Load(); err != nil {
+		return errors.Wrapf(err, "unable to migrate state from %s", ecsDataFile)
+	}
+	if err := manager.saveUnsafe(); err != nil {
+		return errors.Wrapf(err, "unable to migrate state from %s", ecsDataFile)
+	}
+
+	dataFile := filepath.Join(manager.statePath, ecsDataFile)
+	if err := os.Rename(dataFile, dataFile+migratedDataFileSuffix); err != nil && !os.IsNotExist(err) {
+		// The data is safe in the database at this point. The only
+		// consequence of failing to rename the file is that it will not be
+		// cleaned up, since the migration only happens with an empty database.
+		seelog.Warnf("Unable to rename migrated state file %s: %v", dataFile, err)
+	}
+	seelog.Infof("Migrated state from %s to %s", ecsDataFile, ecsDataDBFile)
+	return nil
+}
+
+// Close closes the database. It can be called again once the database is
+// closed, such as after the state was saved as an older data version.
+func (manager *boltStateManager) Close() error {
+	return manager.db.Close()
+}
+
+// readBucket adds the keys of the bucket, and recursively those of its nested
+// buckets, to the snapshot
+func readBucket(loaded snapshot, path string, bucket *bolt.Bucket) error {
+	return bucket.ForEach(func(key, value []byte) error {
+		if value == nil {
+			nested := bucket.Bucket(key)
+			if nested == nil {
+				return errors.Errorf("unable to read nested bucket %s in bucket %s", key, path)
+			}
+			return readBucket(loaded, path+bucketPathSeparator+string(key), nested)
+		}
+		if _, ok := loaded[path]; !ok {
+			loaded[path] = make(map[string][]byte)
+		}
+		// Values are only valid for the life of the transaction, so they
+		// must be copied
+		loaded[path][string(key)] = append([]byte(nil), value...)
+		return nil
+	})
+}
+
+// createBucketPath returns the bucket at the given path, creating it and its
+// parents as needed
+func createBucketPath(tx *bolt.Tx, path string) (*bolt.Bucket, error) {
+	names := strings.Split(path, bucketPathSeparator)
+	bucket, err := tx.CreateBucketIfNotExists([]byte(names[0]))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create bucket %s", names[0])
+	}
+	for _, name := range names[1:] {
+		bucket, err = bucket.CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to create bucket %s", path)
+		}
+	}
+	return bucket, nil
+}
+
+// bucketPath returns the bucket at the given path, or nil if it doesn't exist
+func bucketPath(tx *bolt.Tx, path string) *bolt.Bucket {
+	names := strings.Split(path, bucketPathSeparator)
+	bucket := tx.Bucket([]byte(names[0]))
+	for _, name := range names[1:] {
+		if bucket == nil {
+			return nil
+		}
+		bucket = bucket.Bucket([]byte(name))
+	}
+	return bucket
+}
@@ -0,0 +1,245 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package statemanager_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apieni "github.com/aws/amazon-ecs-agent/agent/api/eni"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTaskARN   = "arn:aws:ecs:us-west-2:1234567890:task/test-task"
+	testDockerID  = "docker-id"
+	testImageID   = "sha256:image-id"
+	testMACAddr   = "mac-address"
+	testIPAddress = "10.0.0.1"
+)
+
+type boltTestState struct {
+	state       dockerstate.TaskEngineState
+	taskEngine  engine.TaskEngine
+	cluster     string
+	instanceARN string
+}
+
+func newBoltTestStateManager(t *testing.T, dataDir string) (statemanager.StateManager, *boltTestState) {
+	cfg := &config.Config{DataDir: dataDir, StateStore: config.StateStoreBoltDB}
+	s := &boltTestState{state: dockerstate.NewTaskEngineState()}
+	s.taskEngine = engine.NewTaskEngine(&config.Config{}, nil, nil, nil, nil, s.state, nil, nil)
+	stateManager, err := statemanager.NewStateManager(cfg,
+		statemanager.AddSaveable("TaskEngine", s.taskEngine),
+		statemanager.AddSaveable("Cluster", &s.cluster),
+		statemanager.AddSaveable("ContainerInstanceArn", &s.instanceARN),
+	)
+	require.NoError(t, err)
+	return stateManager, s
+}
+
+func closeStateManager(t *testing.T, stateManager statemanager.StateManager) {
+	require.NoError(t, stateManager.Close())
+}
+
+func populateTestState(s *boltTestState) {
+	container := &apicontainer.Container{Name: "container"}
+	task := &apitask.Task{
+		Arn:        testTaskARN,
+		Family:     "family",
+		Containers: []*apicontainer.Container{container},
+	}
+	s.state.AddTask(task)
+	s.state.AddContainer(&apicontainer.DockerContainer{
+		DockerID:   testDockerID,
+		DockerName: "docker-name",
+		Container:  container,
+	}, task)
+	s.state.AddImageState(&image.ImageState{
+		Image: &image.Image{ImageID: testImageID, Names: []string{"image:latest"}},
+	})
+	s.state.AddENIAttachment(&apieni.ENIAttachment{
+		TaskARN:    testTaskARN,
+		MACAddress: testMACAddr,
+	})
+	s.state.AddTaskIPAddress(testIPAddress, testTaskARN)
+	s.cluster = "cluster"
+	s.instanceARN = "container-instance-arn"
+}
+
+func TestBoltStateManagerSaveAndLoad(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "bolt_state_manager_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	stateManager, saved := newBoltTestStateManager(t, dataDir)
+	require.NoError(t, stateManager.Load())
+	populateTestState(saved)
+	require.NoError(t, stateManager.Save())
+	closeStateManager(t, stateManager)
+
+	_, err = os.Stat(filepath.Join(dataDir, "ecs_agent_data.json"))
+	assert.True(t, os.IsNotExist(err), "json state file should not be written")
+
+	stateManager, loaded := newBoltTestStateManager(t, dataDir)
+	defer closeStateManager(t, stateManager)
+	require.NoError(t, stateManager.Load())
+
+	assert.Equal(t, "cluster", loaded.cluster)
+	assert.Equal(t, "container-instance-arn", loaded.instanceARN)
+	task, ok := loaded.state.TaskByArn(testTaskARN)
+	require.True(t, ok)
+	assert.Equal(t, "family", task.Family)
+	dockerContainer, ok := loaded.state.ContainerByID(testDockerID)
+	require.True(t, ok)
+	assert.Equal(t, "docker-name", dockerContainer.DockerName)
+	assert.Equal(t, task.Containers[0], dockerContainer.Container, "container should be resolved into its task")
+	require.Len(t, loaded.state.AllImageStates(), 1)
+	assert.Equal(t, testImageID, loaded.state.AllImageStates()[0].Image.ImageID)
+	_, ok = loaded.state.ENIByMac(testMACAddr)
+	assert.True(t, ok)
+	taskARN, ok := loaded.state.GetTaskByIPAddress(testIPAddress)
+	assert.True(t, ok)
+	assert.Equal(t, testTaskARN, taskARN)
+}
+
+func TestBoltStateManagerSaveRemovesDeletedRecords(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "bolt_state_manager_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	stateManager, saved := newBoltTestStateManager(t, dataDir)
+	require.NoError(t, stateManager.Load())
+	populateTestState(saved)
+	require.NoError(t, stateManager.Save())
+
+	task, ok := saved.state.TaskByArn(testTaskARN)
+	require.True(t, ok)
+	saved.state.RemoveTask(task)
+	saved.state.RemoveENIAttachment(testMACAddr)
+	require.NoError(t, stateManager.Save())
+	closeStateManager(t, stateManager)
+
+	stateManager, loaded := newBoltTestStateManager(t, dataDir)
+	defer closeStateManager(t, stateManager)
+	require.NoError(t, stateManager.Load())
+
+	assert.Empty(t, loaded.state.AllTasks())
+	assert.Empty(t, loaded.state.GetAllContainerIDs())
+	assert.Empty(t, loaded.state.AllENIAttachments())
+	assert.Len(t, loaded.state.AllImageStates(), 1)
+}
+
+func TestBoltStateManagerSaveOnlyMarshalsChangedTasks(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "bolt_state_manager_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	stateManager, saved := newBoltTestStateManager(t, dataDir)
+	require.NoError(t, stateManager.Load())
+	populateTestState(saved)
+	require.NoError(t, stateManager.Save())
+	task, ok := saved.state.TaskByArn(testTaskARN)
+	require.True(t, ok)
+	task.Family = "unmarked"
+	require.NoError(t, stateManager.Save())
+	closeStateManager(t, stateManager)
+
+	stateManager, loaded := newBoltTestStateManager(t, dataDir)
+	require.NoError(t, stateManager.Load())
+	task, ok = loaded.state.TaskByArn(testTaskARN)
+	require.True(t, ok)
+	assert.Equal(t, "family", task.Family, "task that wasn't marked as changed should not be saved again")
+	require.NoError(t, stateManager.Save())
+	task.Family = "marked"
+	loaded.state.MarkTaskChanged(testTaskARN)
+	require.NoError(t, stateManager.Save())
+	closeStateManager(t, stateManager)
+
+	stateManager, loaded = newBoltTestStateManager(t, dataDir)
+	defer closeStateManager(t, stateManager)
+	require.NoError(t, stateManager.Load())
+	task, ok = loaded.state.TaskByArn(testTaskARN)
+	require.True(t, ok)
+	assert.Equal(t, "marked", task.Family)
+	_, ok = loaded.state.ContainerByID(testDockerID)
+	assert.True(t, ok)
+}
+
+func TestBoltStateManagerMigratesJSONStateFile(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "bolt_state_manager_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+	data, err := ioutil.ReadFile(filepath.Join(".", "testdata", "v28", "environmentFiles", "ecs_agent_data.json"))
+	require.NoError(t, err)
+	jsonStateFile := filepath.Join(dataDir, "ecs_agent_data.json")
+	require.NoError(t, ioutil.WriteFile(jsonStateFile, data, 0600))
+	cleanup, err := setupWindowsTest(jsonStateFile)
+	require.Nil(t, err, "Failed to set up test")
+	defer cleanup()
+
+	stateManager, migrated := newBoltTestStateManager(t, dataDir)
+	require.NoError(t, stateManager.Load())
+	closeStateManager(t, stateManager)
+
+	assert.Equal(t, "state-file", migrated.cluster)
+	tasks, err := migrated.taskEngine.ListTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	_, err = os.Stat(jsonStateFile + ".migrated")
+	assert.NoError(t, err, "json state file should be kept after migration")
+
+	// The migrated state should be loaded from the database from now on
+	stateManager, loaded := newBoltTestStateManager(t, dataDir)
+	defer closeStateManager(t, stateManager)
+	require.NoError(t, stateManager.Load())
+	assert.Equal(t, "state-file", loaded.cluster)
+	tasks, err = loaded.taskEngine.ListTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "arn:aws:ecs:us-west-2:123456789011:task/70947c96-f64e-483a-a612-3fd4303546e7", tasks[0].Arn)
+	assert.Equal(t, "sleep360", tasks[0].Family)
+}
+
+func TestBoltStateManagerDoesNotMigrateNewerJSONStateFile(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "bolt_state_manager_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+	jsonStateFile := filepath.Join(dataDir, "ecs_agent_data.json")
+	newerState := []byte(`{"Data":{"Cluster":"newer"},"Version":1000}`)
+	require.NoError(t, ioutil.WriteFile(jsonStateFile, newerState, 0600))
+	cleanup, err := setupWindowsTest(jsonStateFile)
+	require.Nil(t, err, "Failed to set up test")
+	defer cleanup()
+
+	stateManager, loaded := newBoltTestStateManager(t, dataDir)
+	defer closeStateManager(t, stateManager)
+	assert.Error(t, stateManager.Load())
+	assert.Empty(t, loaded.cluster)
+	data, err := ioutil.ReadFile(jsonStateFile)
+	require.NoError(t, err)
+	assert.Equal(t, newerState, data, "json state file should be left untouched")
+}
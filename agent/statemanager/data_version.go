@@ -0,0 +1,143 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package statemanager
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// downgradedDataFileSuffix is appended to the name of the BoltDB state store
+// once its content has been written to ecs_agent_data.json in an older data
+// version
+const downgradedDataFileSuffix = ".downgraded"
+
+// versionSaver is implemented by the state managers that can save the state
+// as an older data version
+type versionSaver interface {
+	forceSaveAsVersion(version int) error
+}
+
+// DataVersion returns the version of the state saved in the data directory of
+// the given config, or 0 if there is no saved state. The state store selected
+// by the config is read first, and the json state file is used if the BoltDB
+// state store is selected but holds no state yet.
+func DataVersion(cfg *config.Config) (int, error) {
+	if cfg.StateStore == config.StateStoreBoltDB {
+		version, err := boltDataVersion(filepath.Join(cfg.DataDir, ecsDataDBFile))
+		if err != nil || version != 0 {
+			return version, err
+		}
+	}
+
+	data, err := newBasicStateManager(cfg.DataDir, nil).readFile()
+	if err != nil || data == nil {
+		return 0, err
+	}
+	var saved versionOnlyState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return 0, errors.Wrapf(err, "unable to read version of %s", ecsDataFile)
+	}
+	return saved.Version, nil
+}
+
+// boltDataVersion returns the version saved in the given database file, or 0
+// if the file doesn't exist or holds no state
+func boltDataVersion(dbFile string) (int, error) {
+	if _, err := os.Stat(dbFile); os.IsNotExist(err) {
+		return 0, nil
+	}
+	db, err := bolt.Open(dbFile, 0600, &bolt.Options{Timeout: dbOpenTimeout, ReadOnly: true})
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to open state database")
+	}
+	defer db.Close()
+
+	version := 0
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metadataBucket))
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get([]byte(versionKey))
+		if value == nil {
+			return nil
+		}
+		var err error
+		version, err = strconv.Atoi(string(value))
+		return errors.Wrap(err, "unable to parse version of the state database")
+	})
+	return version, err
+}
+
+// ForceSaveAsVersion saves the state loaded by the given state manager to
+// ecs_agent_data.json, stamped with the given data version instead of
+// ECSDataVersion, so that an agent supporting only that version is able to
+// load it. The caller is responsible for making sure that the state holds
+// nothing that such an agent can't read.
+//
+// When the state manager is backed by the BoltDB state store, the database is
+// closed and renamed, so that the json state file is migrated again if an
+// agent using the BoltDB state store is started afterwards. The state manager
+// can't be used after that.
+func ForceSaveAsVersion(manager StateManager, version int) error {
+	if version < 1 || version > ECSDataVersion {
+		return errors.Errorf("data version %d is not between 1 and %d", version, ECSDataVersion)
+	}
+	saver, ok := manager.(versionSaver)
+	if !ok {
+		return errors.New("state manager is unable to save older data versions")
+	}
+	return saver.forceSaveAsVersion(version)
+}
+
+func (manager *basicStateManager) forceSaveAsVersion(version int) error {
+	manager.savingLock.Lock()
+	defer manager.savingLock.Unlock()
+	seelog.Infof("Saving state as data version %d", version)
+
+	data, err := json.Marshal(&state{
+		Data:    manager.state.Data,
+		Version: version,
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal state")
+	}
+	return manager.writeFile(data)
+}
+
+func (manager *boltStateManager) forceSaveAsVersion(version int) error {
+	manager.savingLock.Lock()
+	defer manager.savingLock.Unlock()
+
+	if err := newBasicStateManager(manager.statePath, manager.state).forceSaveAsVersion(version); err != nil {
+		return err
+	}
+	if err := manager.db.Close(); err != nil {
+		return errors.Wrap(err, "unable to close state database")
+	}
+	dbFile := filepath.Join(manager.statePath, ecsDataDBFile)
+	if err := os.Rename(dbFile, dbFile+downgradedDataFileSuffix); err != nil {
+		return errors.Wrapf(err, "unable to rename state database %s", dbFile)
+	}
+	return nil
+}
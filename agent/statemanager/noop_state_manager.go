@@ -37,3 +37,8 @@ func (nsm *NoopStateManager) ForceSave() error {
 func (nsm *NoopStateManager) Load() error {
 	return nil
 }
+
+// Close does nothing, successfully
+func (nsm *NoopStateManager) Close() error {
+	return nil
+}
@@ -102,8 +102,13 @@ const (
 	//	 a) Add 'authorizationConfig', 'transitEncryption' and 'transitEncryptionPort' to 'taskresource.volume.EFSVolumeConfig'
 	//	 b) Add 'pauseContainerPID' field to 'taskresource.volume.VolumeResource'
 	// 28) Add 'envfile' field to 'resources'
+	// 29) Add 'PidsLimit', 'BlockIOWeight' and 'BlockIODeviceLimits' fields to 'api.task.task'
+	// 30) Add 'OOMKilled' field to 'apicontainer.Container'
+	// 31) Add 'secretFileDir' and 'secretFileOptions' fields to 'ssmsecret' and 'asmsecret' task resources
+	// 32) Add 'vaultsecret' field to 'resources'
+	// 33) Add 'variableNames' field to 'envfile' task resource
 
-	ECSDataVersion = 28
+	ECSDataVersion = 33
 
 	// ecsDataFile specifies the filename in the ECS_DATADIR
 	ecsDataFile = "ecs_agent_data.json"
@@ -153,11 +158,47 @@ type versionOnlyState struct {
 
 type platformDependencies interface{}
 
+// saveableAdder is implemented by the state managers that saveables can be
+// added to with AddSaveable
+type saveableAdder interface {
+	addSaveable(name string, saveable Saveable)
+}
+
+// RecordSaveable is a Saveable that can be split into individually keyed
+// records, grouped by record type. A state store that supports records saves
+// each record on its own, so that a save only writes the records that have
+// changed since the previous save. Other state stores save a RecordSaveable
+// as json.
+type RecordSaveable interface {
+	// Records returns the current records, grouped by record type and keyed
+	// by an identifier that is stable within the record type
+	Records() (map[string]map[string][]byte, error)
+	// LoadRecords restores the saveable from records previously returned by
+	// Records
+	LoadRecords(records map[string]map[string][]byte) error
+}
+
+// ChangedRecordSaveable is a RecordSaveable that keeps track of which of its
+// records change, so that a state store that supports records only marshals
+// those when saving, rather than every record.
+type ChangedRecordSaveable interface {
+	RecordSaveable
+	// ChangedRecords returns the records that changed since the records were
+	// last returned by Records or ChangedRecords, grouped by record type, with
+	// a nil value for the removed ones. The record types whose changes aren't
+	// tracked are returned in full as the second value, and replace the saved
+	// records of the same type.
+	ChangedRecords() (map[string]map[string][]byte, map[string]map[string][]byte, error)
+}
+
 // A StateManager can load and save state from disk.
 // Load is not expected to return an error if there is no state to load.
 type StateManager interface {
 	Saver
 	Load() error
+	// Close releases the resources held by the state manager, such as the
+	// state database. The state can't be saved or loaded after it's closed.
+	Close() error
 }
 
 type basicStateManager struct {
@@ -176,8 +217,10 @@ type basicStateManager struct {
 
 // NewStateManager constructs a new StateManager which saves data at the
 // location specified in cfg and operates under the given options.
-// The returned StateManager will not save more often than every 10 seconds and
-// will not reliably return errors with Save, but will log them appropriately.
+// The state store backing the StateManager is selected by cfg.StateStore. The
+// default json store will not save more often than every 10 seconds and will
+// not reliably return errors with Save, but will log them appropriately. The
+// boltdb store commits every save immediately.
 func NewStateManager(cfg *config.Config, options ...Option) (StateManager, error) {
 	fi, err := os.Stat(cfg.DataDir)
 	if err != nil {
@@ -191,35 +234,51 @@ func NewStateManager(cfg *config.Config, options ...Option) (StateManager, error
 		Data:    make(saveableState),
 		Version: ECSDataVersion,
 	}
-	manager := &basicStateManager{
-		statePath: cfg.DataDir,
-		state:     state,
+
+	var manager StateManager
+	if cfg.StateStore == config.StateStoreBoltDB {
+		manager, err = newBoltStateManager(cfg.DataDir, state)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		manager = newBasicStateManager(cfg.DataDir, state)
 	}
 
 	for _, option := range options {
 		option(manager)
 	}
 
-	manager.platformDependencies = newPlatformDependencies()
-
 	return manager, nil
 }
 
+func newBasicStateManager(statePath string, state *state) *basicStateManager {
+	return &basicStateManager{
+		statePath:            statePath,
+		state:                state,
+		platformDependencies: newPlatformDependencies(),
+	}
+}
+
 // AddSaveable is an option that adds a given saveable as one that should be saved
 // under the given name. The name must be the same across uses of the
 // statemanager (e.g. program invocations) for it to be serialized and
 // deserialized correctly.
 func AddSaveable(name string, saveable Saveable) Option {
 	return (Option)(func(m StateManager) {
-		manager, ok := m.(*basicStateManager)
+		manager, ok := m.(saveableAdder)
 		if !ok {
 			seelog.Critical("Unable to add to state manager; unknown instantiation")
 			return
 		}
-		manager.state.Data[name] = &saveable
+		manager.addSaveable(name, saveable)
 	})
 }
 
+func (manager *basicStateManager) addSaveable(name string, saveable Saveable) {
+	manager.state.Data[name] = &saveable
+}
+
 // Save triggers a save to file, though respects a minimum save interval to wait
 // between saves.
 func (manager *basicStateManager) Save() error {
@@ -317,6 +376,12 @@ func (manager *basicStateManager) Load() error {
 	return nil
 }
 
+// Close does nothing, since the state file is only open while it's read or
+// written
+func (manager *basicStateManager) Close() error {
+	return nil
+}
+
 func (manager *basicStateManager) dryRun(data []byte) error {
 	// Dry-run to make sure this is a version we can understand
 	tmps := versionOnlyState{}
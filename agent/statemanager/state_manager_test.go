@@ -27,6 +27,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/envFiles"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -514,3 +515,181 @@ func TestLoadsDataForEnvFiles(t *testing.T) {
 	assert.Equal(t, "arn:aws:ecs:us-west-2:123456789011:task/70947c96-f64e-483a-a612-3fd4303546e7", task.Arn)
 	assert.Equal(t, "sleep360", task.Family)
 }
+
+func TestLoadsDataForTaskPidsAndBlockIOLimits(t *testing.T) {
+	cleanup, err := setupWindowsTest(filepath.Join(".", "testdata", "v29", "taskLimits", "ecs_agent_data.json"))
+	require.Nil(t, err, "Failed to set up test")
+	defer cleanup()
+	cfg := &config.Config{DataDir: filepath.Join(".", "testdata", "v29", "taskLimits")}
+	taskEngine := engine.NewTaskEngine(&config.Config{}, nil, nil, nil, nil, dockerstate.NewTaskEngineState(), nil, nil)
+	var containerInstanceArn, cluster, savedInstanceID string
+	var sequenceNumber int64
+	stateManager, err := statemanager.NewStateManager(cfg,
+		statemanager.AddSaveable("TaskEngine", taskEngine),
+		statemanager.AddSaveable("ContainerInstanceArn", &containerInstanceArn),
+		statemanager.AddSaveable("Cluster", &cluster),
+		statemanager.AddSaveable("EC2InstanceID", &savedInstanceID),
+		statemanager.AddSaveable("SeqNum", &sequenceNumber),
+	)
+	assert.NoError(t, err)
+	err = stateManager.Load()
+	assert.NoError(t, err)
+	tasks, err := taskEngine.ListTasks()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(tasks))
+	assert.Equal(t, "state-file", cluster)
+	task := tasks[0]
+	assert.EqualValues(t, 512, task.PidsLimit)
+	assert.EqualValues(t, 500, task.BlockIOWeight)
+	assert.Equal(t, []config.BlockIODeviceLimit{
+		{Device: "259:0", ReadBps: 52428800, WriteIOPS: 1000},
+	}, task.BlockIODeviceLimits)
+}
+
+func TestLoadsDataForOOMKilledContainer(t *testing.T) {
+	cleanup, err := setupWindowsTest(filepath.Join(".", "testdata", "v30", "oomKilled", "ecs_agent_data.json"))
+	require.Nil(t, err, "Failed to set up test")
+	defer cleanup()
+	cfg := &config.Config{DataDir: filepath.Join(".", "testdata", "v30", "oomKilled")}
+	taskEngine := engine.NewTaskEngine(&config.Config{}, nil, nil, nil, nil, dockerstate.NewTaskEngineState(), nil, nil)
+	var containerInstanceArn, cluster, savedInstanceID string
+	var sequenceNumber int64
+	stateManager, err := statemanager.NewStateManager(cfg,
+		statemanager.AddSaveable("TaskEngine", taskEngine),
+		statemanager.AddSaveable("ContainerInstanceArn", &containerInstanceArn),
+		statemanager.AddSaveable("Cluster", &cluster),
+		statemanager.AddSaveable("EC2InstanceID", &savedInstanceID),
+		statemanager.AddSaveable("SeqNum", &sequenceNumber),
+	)
+	assert.NoError(t, err)
+	err = stateManager.Load()
+	assert.NoError(t, err)
+	tasks, err := taskEngine.ListTasks()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(tasks))
+	assert.Equal(t, "state-file", cluster)
+	task := tasks[0]
+	require.Equal(t, 1, len(task.Containers))
+	container := task.Containers[0]
+	assert.Equal(t, apicontainerstatus.ContainerStopped, container.GetKnownStatus())
+	require.NotNil(t, container.GetKnownExitCode())
+	assert.Equal(t, 137, *container.GetKnownExitCode())
+	assert.True(t, container.GetOOMKilled())
+}
+
+func TestLoadsDataForSecretFiles(t *testing.T) {
+	cleanup, err := setupWindowsTest(filepath.Join(".", "testdata", "v31", "secretFiles", "ecs_agent_data.json"))
+	require.Nil(t, err, "Failed to set up test")
+	defer cleanup()
+	cfg := &config.Config{DataDir: filepath.Join(".", "testdata", "v31", "secretFiles")}
+	taskEngine := engine.NewTaskEngine(&config.Config{}, nil, nil, nil, nil, dockerstate.NewTaskEngineState(), nil, nil)
+	var containerInstanceArn, cluster, savedInstanceID string
+	var sequenceNumber int64
+	stateManager, err := statemanager.NewStateManager(cfg,
+		statemanager.AddSaveable("TaskEngine", taskEngine),
+		statemanager.AddSaveable("ContainerInstanceArn", &containerInstanceArn),
+		statemanager.AddSaveable("Cluster", &cluster),
+		statemanager.AddSaveable("EC2InstanceID", &savedInstanceID),
+		statemanager.AddSaveable("SeqNum", &sequenceNumber),
+	)
+	assert.NoError(t, err)
+	err = stateManager.Load()
+	assert.NoError(t, err)
+	tasks, err := taskEngine.ListTasks()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(tasks))
+	assert.Equal(t, "state-file", cluster)
+	task := tasks[0]
+	assert.Equal(t, "arn:aws:ecs:us-west-2:123456789011:task/70947c96-f64e-483a-a612-3fd4303546e7", task.Arn)
+	require.Equal(t, 1, len(task.Containers))
+	require.Equal(t, 1, len(task.Containers[0].Secrets))
+	assert.Equal(t, "MOUNT_POINT", task.Containers[0].Secrets[0].Type)
+	assert.Equal(t, "/run/secrets/db_password", task.Containers[0].Secrets[0].ContainerPath)
+
+	resources := task.GetResources()
+	require.Equal(t, 1, len(resources))
+	assert.Equal(t, "ssmsecret", resources[0].GetName())
+	data, err := resources[0].MarshalJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"secretFileDir":"/var/lib/ecs/data/ssmsecret/70947c96-f64e-483a-a612-3fd4303546e7"`)
+	assert.Contains(t, string(data), `"secretFileOptions":{"mode":256,"uid":0,"gid":0}`)
+}
+
+func TestLoadsDataForVaultSecret(t *testing.T) {
+	cleanup, err := setupWindowsTest(filepath.Join(".", "testdata", "v32", "vaultSecret", "ecs_agent_data.json"))
+	require.Nil(t, err, "Failed to set up test")
+	defer cleanup()
+	cfg := &config.Config{DataDir: filepath.Join(".", "testdata", "v32", "vaultSecret")}
+	taskEngine := engine.NewTaskEngine(&config.Config{}, nil, nil, nil, nil, dockerstate.NewTaskEngineState(), nil, nil)
+	var containerInstanceArn, cluster, savedInstanceID string
+	var sequenceNumber int64
+	stateManager, err := statemanager.NewStateManager(cfg,
+		statemanager.AddSaveable("TaskEngine", taskEngine),
+		statemanager.AddSaveable("ContainerInstanceArn", &containerInstanceArn),
+		statemanager.AddSaveable("Cluster", &cluster),
+		statemanager.AddSaveable("EC2InstanceID", &savedInstanceID),
+		statemanager.AddSaveable("SeqNum", &sequenceNumber),
+	)
+	assert.NoError(t, err)
+	err = stateManager.Load()
+	assert.NoError(t, err)
+	tasks, err := taskEngine.ListTasks()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(tasks))
+	assert.Equal(t, "state-file", cluster)
+	assert.EqualValues(t, 0, sequenceNumber)
+	task := tasks[0]
+	assert.Equal(t, "arn:aws:ecs:us-west-2:123456789011:task/70947c96-f64e-483a-a612-3fd4303546e7", task.Arn)
+	assert.Equal(t, "sleep360", task.Family)
+	require.Equal(t, 1, len(task.Containers))
+	require.Equal(t, 1, len(task.Containers[0].Secrets))
+	assert.Equal(t, "vault", task.Containers[0].Secrets[0].Provider)
+	assert.Equal(t, "secret/data/app/db#password", task.Containers[0].Secrets[0].ValueFrom)
+	resources := task.GetResources()
+	require.Equal(t, 1, len(resources))
+	assert.Equal(t, "vaultsecret", resources[0].GetName())
+	assert.Equal(t, "CREATED", resources[0].StatusString(resources[0].GetKnownStatus()))
+}
+
+func TestLoadsDataForEnvFilesVariableNames(t *testing.T) {
+	cleanup, err := setupWindowsTest(filepath.Join(".", "testdata", "v33", "environmentFiles", "ecs_agent_data.json"))
+	require.Nil(t, err, "Failed to set up test")
+	defer cleanup()
+	cfg := &config.Config{DataDir: filepath.Join(".", "testdata", "v33", "environmentFiles")}
+	taskEngine := engine.NewTaskEngine(&config.Config{}, nil, nil, nil, nil, dockerstate.NewTaskEngineState(), nil, nil)
+	var containerInstanceArn, cluster, savedInstanceID string
+	var sequenceNumber int64
+	stateManager, err := statemanager.NewStateManager(cfg,
+		statemanager.AddSaveable("TaskEngine", taskEngine),
+		statemanager.AddSaveable("ContainerInstanceArn", &containerInstanceArn),
+		statemanager.AddSaveable("Cluster", &cluster),
+		statemanager.AddSaveable("EC2InstanceID", &savedInstanceID),
+		statemanager.AddSaveable("SeqNum", &sequenceNumber),
+	)
+	assert.NoError(t, err)
+	err = stateManager.Load()
+	assert.NoError(t, err)
+	tasks, err := taskEngine.ListTasks()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(tasks))
+	assert.Equal(t, "state-file", cluster)
+	task := tasks[0]
+	assert.Equal(t, "arn:aws:ecs:us-west-2:123456789011:task/70947c96-f64e-483a-a612-3fd4303546e7", task.Arn)
+	require.Equal(t, 1, len(task.Containers))
+	assert.Equal(t, 2, len(task.Containers[0].EnvironmentFiles))
+
+	resources := task.GetResources()
+	require.Equal(t, 1, len(resources))
+	envfileResource, ok := resources[0].(*envFiles.EnvironmentFileResource)
+	require.True(t, ok)
+	resolved := envfileResource.GetResolvedEnvironmentFiles()
+	require.Equal(t, 2, len(resolved))
+	assert.Equal(t, []string{"DB_HOST", "DB_PORT"}, resolved[0].VariableNames)
+	assert.Equal(t, []string{"LOG_LEVEL"}, resolved[1].VariableNames)
+
+	// The allowed host directories are taken from the config when the task
+	// is restored, those saved by earlier agents aren't saved again
+	data, err := envfileResource.MarshalJSON()
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "allowedHostDirs")
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
+	"github.com/aws/amazon-ecs-agent/agent/logger/audit"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
@@ -302,6 +303,12 @@ func (engine *MockTaskEngine) StateChangeEvents() chan statechange.Event {
 func (engine *MockTaskEngine) SetSaver(statemanager.Saver) {
 }
 
+func (engine *MockTaskEngine) SetAuditLogger(audit.AuditLogger) {
+}
+
+func (engine *MockTaskEngine) SetContainerHealthListener(statechange.Listener) {
+}
+
 func (engine *MockTaskEngine) AddTask(*apitask.Task) {
 }
 
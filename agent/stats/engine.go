@@ -67,6 +67,7 @@ type Engine interface {
 	GetInstanceMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric, error)
 	ContainerDockerStats(taskARN string, containerID string) (*types.StatsJSON, error)
 	GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error)
+	ContainerOOMKills(taskARN string, containerID string) (uint64, error)
 }
 
 // DockerStatsEngine is used to monitor docker container events and to report
@@ -506,6 +507,11 @@ func (engine *DockerStatsEngine) handleDockerEvents(events ...interface{}) error
 			return fmt.Errorf("Unexpected event received, expected docker container change event")
 		}
 
+		if dockerContainerChangeEvent.Type == apicontainer.ContainerOOMEvent {
+			engine.recordOOMKill(dockerContainerChangeEvent.DockerID)
+			continue
+		}
+
 		switch dockerContainerChangeEvent.Status {
 		case apicontainerstatus.ContainerRunning:
 			engine.addAndStartStatsContainer(dockerContainerChangeEvent.DockerID)
@@ -519,6 +525,21 @@ func (engine *DockerStatsEngine) handleDockerEvents(events ...interface{}) error
 	return nil
 }
 
+// recordOOMKill counts a process of the container killed for running out of
+// memory
+func (engine *DockerStatsEngine) recordOOMKill(dockerID string) {
+	engine.lock.Lock()
+	defer engine.lock.Unlock()
+
+	for _, containerMap := range engine.tasksToContainers {
+		if container, ok := containerMap[dockerID]; ok {
+			container.oomKills++
+			return
+		}
+	}
+	seelog.Debugf("Ignoring OOM kill of container not being watched, id: %s", dockerID)
+}
+
 // removeContainer deletes the container from the map of containers being watched.
 // It also stops the periodic usage data collection for the container.
 func (engine *DockerStatsEngine) removeContainer(dockerID string) {
@@ -683,3 +704,22 @@ func (engine *DockerStatsEngine) ContainerDockerStats(taskARN string, containerI
 	}
 	return container.statsQueue.GetLastStat(), nil
 }
+
+// ContainerOOMKills returns the number of processes of a container killed for
+// running out of memory since the container is watched
+func (engine *DockerStatsEngine) ContainerOOMKills(taskARN string, containerID string) (uint64, error) {
+	engine.lock.RLock()
+	defer engine.lock.RUnlock()
+
+	containerIDToStatsContainer, ok := engine.tasksToContainers[taskARN]
+	if !ok {
+		return 0, errors.Errorf("stats engine: task '%s' for container '%s' not found",
+			taskARN, containerID)
+	}
+
+	container, ok := containerIDToStatsContainer[containerID]
+	if !ok {
+		return 0, errors.Errorf("stats engine: container not found: %s", containerID)
+	}
+	return container.oomKills, nil
+}
@@ -501,3 +501,24 @@ func testNetworkModeStats(t *testing.T, netMode string, enis []*apieni.ENI, empt
 		}
 	}
 }
+
+func TestContainerOOMKills(t *testing.T) {
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestContainerOOMKills"))
+	engine.tasksToContainers["t1"] = map[string]*StatsContainer{
+		"c1": {containerMetadata: &ContainerMetadata{DockerID: "c1"}},
+	}
+
+	engine.recordOOMKill("c1")
+	engine.recordOOMKill("c1")
+	// OOM kills of containers not being watched are ignored
+	engine.recordOOMKill("c2")
+
+	oomKills, err := engine.ContainerOOMKills("t1", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), oomKills)
+
+	_, err = engine.ContainerOOMKills("t1", "c2")
+	assert.Error(t, err)
+	_, err = engine.ContainerOOMKills("t2", "c1")
+	assert.Error(t, err)
+}
@@ -64,6 +64,21 @@ func (mr *MockEngineMockRecorder) ContainerDockerStats(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerDockerStats", reflect.TypeOf((*MockEngine)(nil).ContainerDockerStats), arg0, arg1)
 }
 
+// ContainerOOMKills mocks base method
+func (m *MockEngine) ContainerOOMKills(arg0, arg1 string) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContainerOOMKills", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContainerOOMKills indicates an expected call of ContainerOOMKills
+func (mr *MockEngineMockRecorder) ContainerOOMKills(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainerOOMKills", reflect.TypeOf((*MockEngine)(nil).ContainerOOMKills), arg0, arg1)
+}
+
 // GetInstanceMetrics mocks base method
 func (m *MockEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric, error) {
 	m.ctrl.T.Helper()
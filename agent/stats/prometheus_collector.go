@@ -0,0 +1,255 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// nanoSecondsInSecond is the number of nanoseconds in a second
+	nanoSecondsInSecond = 1e9
+
+	healthStatusHealthy   = 1
+	healthStatusUnhealthy = 0
+)
+
+var (
+	taskLabels      = []string{"Cluster", "TaskARN", "Family", "Revision"}
+	containerLabels = []string{"Cluster", "TaskARN", "Family", "Revision", "ContainerName"}
+)
+
+// resourceDescs are the descriptions of the resource stats of either the
+// containers or the tasks
+type resourceDescs struct {
+	cpuUtilization    *prometheus.Desc
+	cpuUsage          *prometheus.Desc
+	memoryUsage       *prometheus.Desc
+	networkRxBytes    *prometheus.Desc
+	networkTxBytes    *prometheus.Desc
+	storageReadBytes  *prometheus.Desc
+	storageWriteBytes *prometheus.Desc
+	healthStatus      *prometheus.Desc
+}
+
+func newResourceDescs(subsystem string, labels []string) resourceDescs {
+	desc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metrics.AgentNamespace, subsystem, name), help, labels, nil)
+	}
+	return resourceDescs{
+		cpuUtilization:    desc("cpu_utilization_percent", "CPU utilization, in percent of a CPU core"),
+		cpuUsage:          desc("cpu_usage_seconds_total", "CPU time used"),
+		memoryUsage:       desc("memory_usage_bytes", "Memory used, excluding the page cache"),
+		networkRxBytes:    desc("network_rx_bytes_total", "Bytes received on the networks"),
+		networkTxBytes:    desc("network_tx_bytes_total", "Bytes sent on the networks"),
+		storageReadBytes:  desc("storage_read_bytes_total", "Bytes read from block devices"),
+		storageWriteBytes: desc("storage_write_bytes_total", "Bytes written to block devices"),
+		healthStatus:      desc("health_status", "Health status, 1 if healthy and 0 if unhealthy"),
+	}
+}
+
+func (descs resourceDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- descs.cpuUtilization
+	ch <- descs.cpuUsage
+	ch <- descs.memoryUsage
+	ch <- descs.networkRxBytes
+	ch <- descs.networkTxBytes
+	ch <- descs.storageReadBytes
+	ch <- descs.storageWriteBytes
+	ch <- descs.healthStatus
+}
+
+// resourceUsage is the resource usage of a container, or the total of the
+// containers of a task
+type resourceUsage struct {
+	cpuUtilization    float64
+	cpuUtilizationOK  bool
+	cpuUsage          float64
+	memoryUsage       float64
+	networkRxBytes    float64
+	networkTxBytes    float64
+	networkOK         bool
+	storageReadBytes  float64
+	storageWriteBytes float64
+}
+
+func (total *resourceUsage) add(usage *resourceUsage) {
+	if usage.cpuUtilizationOK {
+		total.cpuUtilization += usage.cpuUtilization
+		total.cpuUtilizationOK = true
+	}
+	total.cpuUsage += usage.cpuUsage
+	total.memoryUsage += usage.memoryUsage
+	if usage.networkOK {
+		total.networkRxBytes += usage.networkRxBytes
+		total.networkTxBytes += usage.networkTxBytes
+		total.networkOK = true
+	}
+	total.storageReadBytes += usage.storageReadBytes
+	total.storageWriteBytes += usage.storageWriteBytes
+}
+
+// PrometheusCollector exports the resource stats of the containers watched by
+// the stats engine, along with their totals per task, and the health status
+// of the containers and tasks as Prometheus gauges.
+type PrometheusCollector struct {
+	engine    *DockerStatsEngine
+	task      resourceDescs
+	container resourceDescs
+}
+
+// NewPrometheusCollector creates a new collector of the metrics of the stats
+// engine
+func NewPrometheusCollector(engine *DockerStatsEngine) *PrometheusCollector {
+	return &PrometheusCollector{
+		engine:    engine,
+		task:      newResourceDescs(metrics.TaskSubsystem, taskLabels),
+		container: newResourceDescs(metrics.ContainerSubsystem, containerLabels),
+	}
+}
+
+// Describe sends the descriptions of the metrics of the collector
+func (collector *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
+	collector.task.describe(ch)
+	collector.container.describe(ch)
+}
+
+// Collect sends the current metrics of the stats engine
+func (collector *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	cluster := collector.collectResourceUsage(ch)
+	collector.collectHealthStatus(ch, cluster)
+}
+
+// collectResourceUsage sends the resource usage of the containers and tasks,
+// from the last stats of the containers. It returns the cluster of the stats
+// engine.
+func (collector *PrometheusCollector) collectResourceUsage(ch chan<- prometheus.Metric) string {
+	engine := collector.engine
+	engine.lock.RLock()
+	defer engine.lock.RUnlock()
+
+	for taskARN, containers := range engine.tasksToContainers {
+		taskDef, ok := engine.tasksToDefinitions[taskARN]
+		if !ok {
+			continue
+		}
+		taskLabelValues := []string{engine.cluster, taskARN, taskDef.family, taskDef.version}
+		total := &resourceUsage{}
+		found := false
+		for _, container := range containers {
+			usage, ok := collector.containerResourceUsage(container)
+			if !ok {
+				continue
+			}
+			found = true
+			total.add(usage)
+			collector.container.collectResourceUsage(ch, usage,
+				append(taskLabelValues[:len(taskLabelValues):len(taskLabelValues)], container.containerMetadata.Name))
+		}
+		if found {
+			collector.task.collectResourceUsage(ch, total, taskLabelValues)
+		}
+	}
+	return engine.cluster
+}
+
+// containerResourceUsage returns the resource usage of a container from its
+// last stats. It must be called with the lock of the stats engine held.
+func (collector *PrometheusCollector) containerResourceUsage(container *StatsContainer) (*resourceUsage, bool) {
+	lastStat := container.statsQueue.GetLastStat()
+	if lastStat == nil {
+		return nil, false
+	}
+	containerStats, err := dockerStatsToContainerStats(lastStat)
+	if err != nil {
+		return nil, false
+	}
+	usage := &resourceUsage{
+		cpuUsage:          float64(lastStat.CPUStats.CPUUsage.TotalUsage) / nanoSecondsInSecond,
+		memoryUsage:       float64(containerStats.memoryUsage),
+		storageReadBytes:  float64(containerStats.storageReadBytes),
+		storageWriteBytes: float64(containerStats.storageWriteBytes),
+	}
+	if cpuUtilization, ok := container.statsQueue.GetLastCPUUsagePerc(); ok {
+		usage.cpuUtilization = float64(cpuUtilization)
+		usage.cpuUtilizationOK = true
+	}
+	// As with the metrics sent to the backend, network stats are only
+	// exported for the default/bridge/nat network modes. The containers of
+	// awsvpc tasks share the network namespace of the task.
+	if containerStats.networkStats != nil &&
+		container.containerMetadata.NetworkMode != hostNetworkMode &&
+		container.containerMetadata.NetworkMode != noneNetworkMode {
+		if task, err := collector.engine.resolver.ResolveTask(container.containerMetadata.DockerID); err == nil &&
+			!task.IsNetworkModeAWSVPC() {
+			usage.networkRxBytes = float64(containerStats.networkStats.RxBytes)
+			usage.networkTxBytes = float64(containerStats.networkStats.TxBytes)
+			usage.networkOK = true
+		}
+	}
+	return usage, true
+}
+
+func (descs resourceDescs) collectResourceUsage(ch chan<- prometheus.Metric, usage *resourceUsage, labelValues []string) {
+	if usage.cpuUtilizationOK {
+		ch <- prometheus.MustNewConstMetric(descs.cpuUtilization, prometheus.GaugeValue, usage.cpuUtilization, labelValues...)
+	}
+	ch <- prometheus.MustNewConstMetric(descs.cpuUsage, prometheus.CounterValue, usage.cpuUsage, labelValues...)
+	ch <- prometheus.MustNewConstMetric(descs.memoryUsage, prometheus.GaugeValue, usage.memoryUsage, labelValues...)
+	if usage.networkOK {
+		ch <- prometheus.MustNewConstMetric(descs.networkRxBytes, prometheus.CounterValue, usage.networkRxBytes, labelValues...)
+		ch <- prometheus.MustNewConstMetric(descs.networkTxBytes, prometheus.CounterValue, usage.networkTxBytes, labelValues...)
+	}
+	ch <- prometheus.MustNewConstMetric(descs.storageReadBytes, prometheus.CounterValue, usage.storageReadBytes, labelValues...)
+	ch <- prometheus.MustNewConstMetric(descs.storageWriteBytes, prometheus.CounterValue, usage.storageWriteBytes, labelValues...)
+}
+
+// collectHealthStatus sends the health status of the containers with health
+// checks, and of their tasks. A task is healthy if all of its containers
+// with health checks are healthy.
+func (collector *PrometheusCollector) collectHealthStatus(ch chan<- prometheus.Metric, cluster string) {
+	_, taskHealths, err := collector.engine.GetTaskHealthMetrics()
+	if err != nil {
+		return
+	}
+	for _, taskHealth := range taskHealths {
+		taskLabelValues := []string{cluster, aws.StringValue(taskHealth.TaskArn),
+			aws.StringValue(taskHealth.TaskDefinitionFamily), aws.StringValue(taskHealth.TaskDefinitionVersion)}
+		taskHealthStatus := healthStatusHealthy
+		known := false
+		for _, containerHealth := range taskHealth.Containers {
+			var healthStatus int
+			switch aws.StringValue(containerHealth.HealthStatus) {
+			case apicontainerstatus.ContainerHealthy.BackendStatus():
+				healthStatus = healthStatusHealthy
+			case apicontainerstatus.ContainerUnhealthy.BackendStatus():
+				healthStatus = healthStatusUnhealthy
+				taskHealthStatus = healthStatusUnhealthy
+			default:
+				continue
+			}
+			known = true
+			ch <- prometheus.MustNewConstMetric(collector.container.healthStatus, prometheus.GaugeValue,
+				float64(healthStatus), append(taskLabelValues[:len(taskLabelValues):len(taskLabelValues)],
+					aws.StringValue(containerHealth.ContainerName))...)
+		}
+		if known {
+			ch <- prometheus.MustNewConstMetric(collector.task.healthStatus, prometheus.GaugeValue,
+				float64(taskHealthStatus), taskLabelValues...)
+		}
+	}
+}
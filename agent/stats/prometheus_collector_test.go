@@ -0,0 +1,159 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	apitask "github.com/aws/amazon-ecs-agent/agent/api/task"
+	mock_resolver "github.com/aws/amazon-ecs-agent/agent/stats/resolver/mock"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/docker/docker/api/types"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDockerStats(read time.Time, cpuUsage uint64, memoryUsage uint64) *types.StatsJSON {
+	dockerStats := &types.StatsJSON{}
+	dockerStats.Read = read
+	dockerStats.NumProcs = 1
+	dockerStats.CPUStats.CPUUsage.TotalUsage = cpuUsage
+	dockerStats.CPUStats.CPUUsage.PercpuUsage = []uint64{cpuUsage}
+	dockerStats.MemoryStats.Usage = memoryUsage
+	dockerStats.MemoryStats.PrivateWorkingSet = memoryUsage
+	dockerStats.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{
+		{Op: "Read", Value: 10},
+		{Op: "Write", Value: 20},
+	}
+	dockerStats.Networks = map[string]types.NetworkStats{"eth0": {RxBytes: 30, TxBytes: 40}}
+	return dockerStats
+}
+
+// gatherMetrics returns the values of the gathered metrics by name and by
+// container or task ARN
+func gatherMetrics(t *testing.T, collector prometheus.Collector) map[string]map[string]float64 {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(collector))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]map[string]float64)
+	for _, family := range families {
+		// Cumulative metrics are counters, named with the _total suffix
+		if strings.HasSuffix(family.GetName(), "_total") {
+			assert.Equal(t, dto.MetricType_COUNTER, family.GetType(), family.GetName())
+		} else {
+			assert.Equal(t, dto.MetricType_GAUGE, family.GetType(), family.GetName())
+		}
+		values[family.GetName()] = make(map[string]float64)
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			assert.Equal(t, "cluster", labels["Cluster"])
+			assert.Equal(t, "f1", labels["Family"])
+			assert.Equal(t, "1", labels["Revision"])
+			key := labels["TaskARN"]
+			if name, ok := labels["ContainerName"]; ok {
+				key = name
+			}
+			values[family.GetName()][key] = metricValue(metric)
+		}
+	}
+	return values
+}
+
+func metricValue(metric *dto.Metric) float64 {
+	if metric.Counter != nil {
+		return metric.GetCounter().GetValue()
+	}
+	return metric.GetGauge().GetValue()
+}
+
+func TestPrometheusCollector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	resolver := mock_resolver.NewMockContainerMetadataResolver(ctrl)
+	task := &apitask.Task{Arn: "t1", Family: "f1", Version: "1"}
+	for _, name := range []string{"c1", "c2"} {
+		resolver.EXPECT().ResolveContainer(name).Return(&apicontainer.DockerContainer{
+			DockerID: name,
+			Container: &apicontainer.Container{
+				Name:              name,
+				NetworkModeUnsafe: "bridge",
+				KnownStatusUnsafe: apicontainerstatus.ContainerRunning,
+				HealthCheckType:   "docker",
+				Health: apicontainer.HealthStatus{
+					Status: apicontainerstatus.ContainerHealthy,
+					Since:  aws.Time(time.Now()),
+				},
+			},
+		}, nil).AnyTimes()
+		resolver.EXPECT().ResolveTask(name).Return(task, nil).AnyTimes()
+	}
+
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestPrometheusCollector"))
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	engine.ctx = ctx
+	engine.resolver = resolver
+	engine.cluster = "cluster"
+	engine.tasksToDefinitions["t1"] = &taskDefinition{family: "f1", version: "1"}
+	engine.tasksToContainers["t1"] = make(map[string]*StatsContainer)
+	engine.tasksToHealthCheckContainers["t1"] = make(map[string]*StatsContainer)
+
+	now := time.Now()
+	for _, name := range []string{"c1", "c2"} {
+		container, err := newStatsContainer(name, nil, resolver, nil)
+		require.NoError(t, err)
+		container.statsQueue = NewQueue(5)
+		require.NoError(t, container.statsQueue.Add(newTestDockerStats(now, 1e9, 100)))
+		require.NoError(t, container.statsQueue.Add(newTestDockerStats(now.Add(time.Second), 2e9, 200)))
+		engine.tasksToContainers["t1"][name] = container
+		engine.tasksToHealthCheckContainers["t1"][name] = container
+	}
+
+	values := gatherMetrics(t, NewPrometheusCollector(engine))
+
+	assert.Equal(t, map[string]float64{"c1": 2, "c2": 2}, values["AgentMetrics_Container_cpu_usage_seconds_total"])
+	assert.Equal(t, map[string]float64{"t1": 4}, values["AgentMetrics_Task_cpu_usage_seconds_total"])
+	assert.Equal(t, map[string]float64{"c1": 200, "c2": 200}, values["AgentMetrics_Container_memory_usage_bytes"])
+	assert.Equal(t, map[string]float64{"t1": 400}, values["AgentMetrics_Task_memory_usage_bytes"])
+	assert.Equal(t, map[string]float64{"t1": 60}, values["AgentMetrics_Task_network_rx_bytes_total"])
+	assert.Equal(t, map[string]float64{"t1": 40}, values["AgentMetrics_Task_storage_write_bytes_total"])
+	assert.Contains(t, values["AgentMetrics_Task_cpu_utilization_percent"], "t1")
+	assert.Equal(t, map[string]float64{"c1": 1, "c2": 1}, values["AgentMetrics_Container_health_status"])
+	assert.Equal(t, map[string]float64{"t1": 1}, values["AgentMetrics_Task_health_status"])
+}
+
+func TestPrometheusCollectorNoStats(t *testing.T) {
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestPrometheusCollectorNoStats"))
+	engine.cluster = "cluster"
+
+	values := gatherMetrics(t, NewPrometheusCollector(engine))
+	assert.Empty(t, values)
+}
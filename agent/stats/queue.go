@@ -126,6 +126,22 @@ func (queue *Queue) GetLastStat() *types.StatsJSON {
 	return queue.lastStat
 }
 
+// GetLastCPUUsagePerc returns the CPU utilization of the last stats added to
+// the queue. It's unknown until the queue has two stats.
+func (queue *Queue) GetLastCPUUsagePerc() (float32, bool) {
+	queue.lock.RLock()
+	defer queue.lock.RUnlock()
+
+	if len(queue.buffer) == 0 {
+		return 0, false
+	}
+	cpuUsagePerc := queue.buffer[len(queue.buffer)-1].CPUUsagePerc
+	if math.IsNaN(float64(cpuUsagePerc)) {
+		return 0, false
+	}
+	return cpuUsagePerc, true
+}
+
 // GetCPUStatsSet gets the stats set for CPU utilization.
 func (queue *Queue) GetCPUStatsSet() (*ecstcs.CWStatsSet, error) {
 	return queue.getCWStatsSet(getCPUUsagePerc)
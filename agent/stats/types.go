@@ -74,6 +74,10 @@ type StatsContainer struct {
 	statsQueue        *Queue
 	resolver          resolver.ContainerMetadataResolver
 	config            *config.Config
+	// oomKills is the number of processes of the container killed for
+	// running out of memory since the container is watched. It's protected
+	// by the lock of the stats engine.
+	oomKills uint64
 }
 
 // taskDefinition encapsulates family and version strings for a task definition
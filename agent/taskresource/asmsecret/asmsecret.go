@@ -30,6 +30,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/asm/factory"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfile"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
@@ -64,6 +65,11 @@ type ASMSecretResource struct {
 	requiredSecrets map[string]apicontainer.Secret
 	// map to store secret values, key is a combination of valueFrom and region
 	secretData map[string]string
+	// secretFileDir is the directory the tmpfs holding the values of the
+	// secrets vended as files is mounted on, empty if there are none
+	secretFileDir string
+	// secretFileOptions are the mode and owner of the secret files
+	secretFileOptions secretfile.Options
 
 	// ssmClientCreator is a factory interface that creates new SSM clients. This is
 	// needed mostly for testing.
@@ -83,7 +89,9 @@ func NewASMSecretResource(taskARN string,
 	asmSecrets map[string]apicontainer.Secret,
 	executionCredentialsID string,
 	credentialsManager credentials.Manager,
-	asmClientCreator factory.ClientCreator) *ASMSecretResource {
+	asmClientCreator factory.ClientCreator,
+	secretFileDir string,
+	secretFileOptions secretfile.Options) *ASMSecretResource {
 
 	s := &ASMSecretResource{
 		taskARN:                taskARN,
@@ -91,6 +99,8 @@ func NewASMSecretResource(taskARN string,
 		credentialsManager:     credentialsManager,
 		executionCredentialsID: executionCredentialsID,
 		asmClientCreator:       asmClientCreator,
+		secretFileDir:          secretFileDir,
+		secretFileOptions:      secretFileOptions,
 	}
 
 	s.initStatusToTransition()
@@ -291,6 +301,12 @@ func (secret *ASMSecretResource) Create() error {
 		secret.setTerminalReason(errorString)
 		return errors.New(errorString)
 	}
+
+	if err := secretfile.WriteSecrets(secret.secretFileDir, secret.secretFileOptions, secret.getSecrets(), secret); err != nil {
+		err = errors.Wrap(err, "ASM secret resource: unable to write secret files")
+		secret.setTerminalReason(err.Error())
+		return err
+	}
 	return nil
 }
 
@@ -386,6 +402,42 @@ func reconstructASMARN(arnARN arn.ARN) string {
 	return secretIDARN
 }
 
+// RefreshSecretFiles retrieves the values of the secrets vended as files from AWS Secrets Manager again,
+// and rewrites the files of those whose value changed. The secrets whose value changed are returned.
+func (secret *ASMSecretResource) RefreshSecretFiles() ([]apicontainer.Secret, error) {
+	changed, err := secretfile.RefreshSecrets(secret.secretFileDir, secret.secretFileOptions,
+		secret.getSecrets(), secret, secret.fetchSecretValues)
+	if err != nil {
+		return changed, errors.Wrap(err, "ASM secret resource: unable to refresh secret files")
+	}
+	return changed, nil
+}
+
+// fetchSecretValues retrieves the values of the secrets from AWS Secrets Manager, keyed by their
+// resource cache key
+func (secret *ASMSecretResource) fetchSecretValues(secrets []apicontainer.Secret) (map[string]string, error) {
+	executionCredentials, ok := secret.credentialsManager.GetTaskCredentials(secret.getExecutionCredentialsID())
+	if !ok {
+		return nil, errors.New("unable to find execution role credentials")
+	}
+	iamCredentials := executionCredentials.GetIAMRoleCredentials()
+
+	values := make(map[string]string)
+	for _, s := range secrets {
+		input, jsonKey, err := getASMParametersFromInput(s.ValueFrom)
+		if err != nil {
+			return nil, fmt.Errorf("trying to retrieve secret with value %s resulted in error: %v", s.ValueFrom, err)
+		}
+		asmClient := secret.asmClientCreator.NewASMClient(s.Region, iamCredentials)
+		secretValue, err := asm.GetSecretFromASMWithInput(input, asmClient, jsonKey)
+		if err != nil {
+			return nil, fmt.Errorf("fetching secret data from AWS Secrets Manager in region %s: %v", s.Region, err)
+		}
+		values[s.GetSecretResourceCacheKey()] = secretValue
+	}
+	return values, nil
+}
+
 // getRequiredSecrets returns the requiredSecrets field of asmsecret task resource
 func (secret *ASMSecretResource) getRequiredSecrets() map[string]apicontainer.Secret {
 	secret.lock.RLock()
@@ -394,6 +446,15 @@ func (secret *ASMSecretResource) getRequiredSecrets() map[string]apicontainer.Se
 	return secret.requiredSecrets
 }
 
+// getSecrets returns the secrets of the resource
+func (secret *ASMSecretResource) getSecrets() []apicontainer.Secret {
+	var secrets []apicontainer.Secret
+	for _, s := range secret.getRequiredSecrets() {
+		secrets = append(secrets, s)
+	}
+	return secrets
+}
+
 // getExecutionCredentialsID returns the execution role's credential ID
 func (secret *ASMSecretResource) getExecutionCredentialsID() string {
 	secret.lock.RLock()
@@ -402,9 +463,13 @@ func (secret *ASMSecretResource) getExecutionCredentialsID() string {
 	return secret.executionCredentialsID
 }
 
-// Cleanup removes the secret value created for the task
+// Cleanup removes the secret value created for the task, along with the
+// secret files
 func (secret *ASMSecretResource) Cleanup() error {
 	secret.clearASMSecretValue()
+	if err := secretfile.Remove(secret.secretFileDir); err != nil {
+		return errors.Wrapf(err, "ASM secret resource: unable to remove secret files of task [%s]", secret.taskARN)
+	}
 	return nil
 }
 
@@ -463,6 +528,8 @@ type ASMSecretResourceJSON struct {
 	KnownStatus            *ASMSecretStatus               `json:"knownStatus"`
 	RequiredSecrets        map[string]apicontainer.Secret `json:"secretResources"`
 	ExecutionCredentialsID string                         `json:"executionCredentialsID"`
+	SecretFileDir          string                         `json:"secretFileDir,omitempty"`
+	SecretFileOptions      *secretfile.Options            `json:"secretFileOptions,omitempty"`
 }
 
 // MarshalJSON serialises the ASMSecretResource struct to JSON
@@ -486,6 +553,8 @@ func (secret *ASMSecretResource) MarshalJSON() ([]byte, error) {
 		}(),
 		RequiredSecrets:        secret.getRequiredSecrets(),
 		ExecutionCredentialsID: secret.getExecutionCredentialsID(),
+		SecretFileDir:          secret.secretFileDir,
+		SecretFileOptions:      secretfile.OptionsJSON(secret.secretFileDir, secret.secretFileOptions),
 	})
 }
 
@@ -511,6 +580,10 @@ func (secret *ASMSecretResource) UnmarshalJSON(b []byte) error {
 	}
 	secret.taskARN = temp.TaskARN
 	secret.executionCredentialsID = temp.ExecutionCredentialsID
+	secret.secretFileDir = temp.SecretFileDir
+	if temp.SecretFileOptions != nil {
+		secret.secretFileOptions = *temp.SecretFileOptions
+	}
 
 	return nil
 }
@@ -19,6 +19,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -29,6 +32,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfile"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
@@ -197,10 +201,14 @@ func TestMarshalUnmarshalJSON(t *testing.T) {
 		knownStatusUnsafe:      resourcestatus.ResourceCreated,
 		desiredStatusUnsafe:    resourcestatus.ResourceCreated,
 		requiredSecrets:        requiredSecretData,
+		secretData:             map[string]string{secretKeyWest1: secretValue},
+		secretFileDir:          "/data/asmsecret/task1",
+		secretFileOptions:      secretfile.Options{Mode: 0440, UID: 1000, GID: 1000},
 	}
 
 	bytes, err := json.Marshal(asmResIn)
 	require.NoError(t, err)
+	assert.NotContains(t, string(bytes), secretValue, "secret values must not be saved in the state file")
 
 	asmResOut := &ASMSecretResource{}
 	err = json.Unmarshal(bytes, asmResOut)
@@ -212,6 +220,8 @@ func TestMarshalUnmarshalJSON(t *testing.T) {
 	assert.Equal(t, asmResIn.executionCredentialsID, asmResOut.executionCredentialsID)
 	assert.Equal(t, len(asmResIn.requiredSecrets), len(asmResOut.requiredSecrets))
 	assert.Equal(t, asmResIn.requiredSecrets[secretKeyWest1], asmResOut.requiredSecrets[secretKeyWest1])
+	assert.Equal(t, asmResIn.secretFileDir, asmResOut.secretFileDir)
+	assert.Equal(t, asmResIn.secretFileOptions, asmResOut.secretFileOptions)
 }
 
 func TestInitialize(t *testing.T) {
@@ -329,3 +339,78 @@ func sampleSecret(secretName string, valueFrom string, region string) apicontain
 		Provider:  "asm",
 	}
 }
+
+func TestRefreshSecretFiles(t *testing.T) {
+	secretFileDir, err := ioutil.TempDir("", "asmsecret")
+	require.NoError(t, err)
+	defer os.RemoveAll(secretFileDir)
+
+	fileSecret := sampleSecret(secretName1, valueFrom1, region1)
+	fileSecret.Type = apicontainer.SecretTypeMountPoint
+	fileSecret.ContainerPath = "/run/secrets/secret1"
+	require.NoError(t, os.MkdirAll(filepath.Join(secretFileDir, secretfile.MountDir("/run/secrets")), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(secretFileDir, secretfile.FilePath(fileSecret)),
+		[]byte(secretValue), 0600))
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	asmClientCreator := mock_factory.NewMockClientCreator(ctrl)
+	mockASMClient := mock_secretsmanageriface.NewMockSecretsManagerAPI(ctrl)
+
+	iamRoleCreds := credentials.IAMRoleCredentials{}
+	creds := credentials.TaskIAMRoleCredentials{
+		IAMRoleCredentials: iamRoleCreds,
+	}
+
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true)
+	asmClientCreator.EXPECT().NewASMClient(region1, iamRoleCreds).Return(mockASMClient)
+	mockASMClient.EXPECT().GetSecretValue(gomock.Any()).Return(&secretsmanager.GetSecretValueOutput{
+		SecretString: aws.String("rotated-value"),
+	}, nil)
+
+	asmRes := &ASMSecretResource{
+		executionCredentialsID: executionCredentialsID,
+		requiredSecrets:        map[string]apicontainer.Secret{secretKeyWest1: fileSecret},
+		credentialsManager:     credentialsManager,
+		asmClientCreator:       asmClientCreator,
+		secretFileDir:          secretFileDir,
+	}
+	changed, err := asmRes.RefreshSecretFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []apicontainer.Secret{fileSecret}, changed)
+	value, err := ioutil.ReadFile(filepath.Join(secretFileDir, secretfile.FilePath(fileSecret)))
+	require.NoError(t, err)
+	assert.Equal(t, "rotated-value", string(value))
+}
+
+func TestRefreshSecretFilesError(t *testing.T) {
+	fileSecret := sampleSecret(secretName1, valueFrom1, region1)
+	fileSecret.Type = apicontainer.SecretTypeMountPoint
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	asmClientCreator := mock_factory.NewMockClientCreator(ctrl)
+	mockASMClient := mock_secretsmanageriface.NewMockSecretsManagerAPI(ctrl)
+
+	iamRoleCreds := credentials.IAMRoleCredentials{}
+	creds := credentials.TaskIAMRoleCredentials{
+		IAMRoleCredentials: iamRoleCreds,
+	}
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true)
+	asmClientCreator.EXPECT().NewASMClient(region1, iamRoleCreds).Return(mockASMClient)
+	mockASMClient.EXPECT().GetSecretValue(gomock.Any()).Return(nil, errors.New("access denied"))
+
+	asmRes := &ASMSecretResource{
+		executionCredentialsID: executionCredentialsID,
+		requiredSecrets:        map[string]apicontainer.Secret{secretKeyWest1: fileSecret},
+		credentialsManager:     credentialsManager,
+		asmClientCreator:       asmClientCreator,
+	}
+	changed, err := asmRes.RefreshSecretFiles()
+	assert.Error(t, err)
+	assert.Empty(t, changed)
+}
@@ -279,6 +279,11 @@ func (cgroup *CgroupResource) setupTaskCgroup() error {
 		return errors.Wrapf(err, "cgroup resource [%s]: setup cgroup: unable to create cgroup at %s", cgroup.taskARN, cgroupRoot)
 	}
 
+	// The memory hierarchy is always enabled in the unified hierarchy of cgroup v2
+	if cgroup.control.Unified() {
+		return nil
+	}
+
 	// enabling cgroup memory hierarchy by doing 'echo 1 > memory.use_hierarchy'
 	memoryHierarchyPath := filepath.Join(cgroup.cgroupMountPath, memorySubsystem, cgroupRoot, memoryUseHierarchy)
 	err = cgroup.ioutil.WriteFile(memoryHierarchyPath, enableMemoryHierarchy, rootReadOnlyPermissions)
@@ -370,6 +375,16 @@ func (cgroup *CgroupResource) GetCgroupMountPath() string {
 	return cgroup.cgroupMountPath
 }
 
+// GetUsage returns the current resource usage of the cgroup
+func (cgroup *CgroupResource) GetUsage() (*control.Usage, error) {
+	cgroup.lock.RLock()
+	defer cgroup.lock.RUnlock()
+	if cgroup.control == nil {
+		return nil, errors.Errorf("cgroup resource [%s]: cgroup control is not initialized", cgroup.taskARN)
+	}
+	return cgroup.control.Usage(cgroup.cgroupRoot)
+}
+
 // Initialize initializes the resource fileds in cgroup
 func (cgroup *CgroupResource) Initialize(resourceFields *taskresource.ResourceFields,
 	taskKnownStatus status.TaskStatus,
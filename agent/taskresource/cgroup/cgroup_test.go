@@ -54,12 +54,31 @@ func TestCreateHappyPath(t *testing.T) {
 	gomock.InOrder(
 		mockControl.EXPECT().Exists(gomock.Any()).Return(false),
 		mockControl.EXPECT().Create(gomock.Any()).Return(nil, nil),
+		mockControl.EXPECT().Unified().Return(false),
 		mockIO.EXPECT().WriteFile(cgroupMemoryPath, gomock.Any(), gomock.Any()).Return(nil),
 	)
 	cgroupResource := NewCgroupResource("taskArn", mockControl, mockIO, cgroupRoot, cgroupMountPath, specs.LinuxResources{})
 	assert.NoError(t, cgroupResource.Create())
 }
 
+func TestCreateCgroupUnified(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockControl := mock_control.NewMockControl(ctrl)
+	mockIO := mock_ioutilwrapper.NewMockIOUtil(ctrl)
+
+	cgroupRoot := fmt.Sprintf("/ecs/%s", taskID)
+
+	// The memory hierarchy isn't set in the unified hierarchy
+	gomock.InOrder(
+		mockControl.EXPECT().Exists(gomock.Any()).Return(false),
+		mockControl.EXPECT().Create(gomock.Any()).Return(nil, nil),
+		mockControl.EXPECT().Unified().Return(true),
+	)
+	cgroupResource := NewCgroupResource("taskArn", mockControl, mockIO, cgroupRoot, cgroupMountPath, specs.LinuxResources{})
+	assert.NoError(t, cgroupResource.Create())
+}
+
 func TestCreateCgroupPathExists(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -135,6 +154,23 @@ func TestCleanupCgroupDeletedError(t *testing.T) {
 	assert.NoError(t, cgroupResource.Cleanup())
 }
 
+func TestGetUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockControl := mock_control.NewMockControl(ctrl)
+	mockIO := mock_ioutilwrapper.NewMockIOUtil(ctrl)
+
+	cgroupRoot := fmt.Sprintf("/ecs/%s", taskID)
+	usage := &cgroup.Usage{Pids: 3}
+	mockControl.EXPECT().Usage(cgroupRoot).Return(usage, nil)
+
+	cgroupResource := NewCgroupResource("taskArn", mockControl, mockIO, cgroupRoot, cgroupMountPath, specs.LinuxResources{})
+	actual, err := cgroupResource.GetUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, usage, actual)
+}
+
 func TestMarshal(t *testing.T) {
 	cgroupStr := "{\"cgroupRoot\":\"/ecs/taskid\",\"cgroupMountPath\":\"/sys/fs/cgroup\"," +
 		"\"createdAt\":\"0001-01-01T00:00:00Z\",\"desiredStatus\":\"CREATED\",\"knownStatus\":\"NONE\",\"resourceSpec\":{}}"
@@ -142,7 +178,7 @@ func TestMarshal(t *testing.T) {
 	cgroupRoot := "/ecs/taskid"
 	cgroupMountPath := "/sys/fs/cgroup"
 
-	cgroup := NewCgroupResource("", cgroup.New(), nil, cgroupRoot, cgroupMountPath, specs.LinuxResources{})
+	cgroup := NewCgroupResource("", cgroup.New(cgroupMountPath), nil, cgroupRoot, cgroupMountPath, specs.LinuxResources{})
 	cgroup.SetDesiredStatus(resourcestatus.ResourceStatus(CgroupCreated))
 	cgroup.SetKnownStatus(resourcestatus.ResourceStatus(CgroupStatusNone))
 
@@ -23,13 +23,26 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	// blkioOpRead and blkioOpWrite are the operations of the block IO stats
+	// of cgroup v1
+	blkioOpRead  = "Read"
+	blkioOpWrite = "Write"
+)
+
 // control is used to implement the cgroup Control interface
 type control struct {
 	factory.CgroupFactory
 }
 
-// New is used to obtain a new cgroup control object
-func New() Control {
+// New returns the Control of the cgroup filesystem mounted at
+// cgroupMountPath. Hosts that only mount the cgroup v2 unified hierarchy are
+// detected, and their cgroups are managed without the cgroups library.
+func New(cgroupMountPath string) Control {
+	if UnifiedHierarchy(cgroupMountPath) {
+		seelog.Infof("Using the cgroup v2 unified hierarchy mounted at %s", cgroupMountPath)
+		return newUnifiedControl(cgroupMountPath)
+	}
 	return newControl(&factory.GlobalCgroupFactory{})
 }
 
@@ -88,6 +101,51 @@ func (c *control) Exists(cgroupPath string) bool {
 	return true
 }
 
+// Usage returns the number of processes and the block IO of the cgroup
+func (c *control) Usage(cgroupPath string) (*Usage, error) {
+	controller, err := c.Load(cgroups.V1, cgroups.StaticPath(cgroupPath))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cgroup usage: unable to obtain controller")
+	}
+	stats, err := controller.Stat(cgroups.IgnoreNotExist)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cgroup usage: unable to read cgroup stats")
+	}
+
+	usage := &Usage{}
+	if stats.Pids != nil {
+		usage.Pids = stats.Pids.Current
+	}
+	if stats.Blkio != nil {
+		devices := make(deviceIOUsages)
+		// The CFQ stats of the devices, when available, are read after the
+		// throttling stats and replace them
+		for _, entry := range stats.Blkio.IoServiceBytesRecursive {
+			switch entry.Op {
+			case blkioOpRead:
+				devices.device(entry.Major, entry.Minor).ReadBytes = entry.Value
+			case blkioOpWrite:
+				devices.device(entry.Major, entry.Minor).WriteBytes = entry.Value
+			}
+		}
+		for _, entry := range stats.Blkio.IoServicedRecursive {
+			switch entry.Op {
+			case blkioOpRead:
+				devices.device(entry.Major, entry.Minor).ReadIOs = entry.Value
+			case blkioOpWrite:
+				devices.device(entry.Major, entry.Minor).WriteIOs = entry.Value
+			}
+		}
+		usage.BlockIO = devices.list()
+	}
+	return usage, nil
+}
+
+// Unified returns false, as the control manages the cgroup v1 hierarchies
+func (c *control) Unified() bool {
+	return false
+}
+
 // validateCgroupSpec checks the cgroup spec for valid path and specifications
 func validateCgroupSpec(cgroupSpec *Spec) error {
 	if cgroupSpec == nil {
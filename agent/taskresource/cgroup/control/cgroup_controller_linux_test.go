@@ -21,6 +21,7 @@ import (
 
 	mock_cgroups "github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control/factory/mock"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/cgroup/control/factory/mock_factory"
+	"github.com/containerd/cgroups"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/stretchr/testify/assert"
 
@@ -180,3 +181,51 @@ func TestExistsErrorPathWithLoadError(t *testing.T) {
 
 	assert.False(t, control.Exists(testCgroupRoot))
 }
+
+func TestUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCgroup := mock_cgroups.NewMockCgroup(ctrl)
+	mockCgroupFactory := mock_factory.NewMockCgroupFactory(ctrl)
+
+	gomock.InOrder(
+		mockCgroupFactory.EXPECT().Load(gomock.Any(), gomock.Any()).Return(mockCgroup, nil),
+		mockCgroup.EXPECT().Stat(gomock.Any()).Return(&cgroups.Stats{
+			Pids: &cgroups.PidsStat{Current: 12, Limit: 512},
+			Blkio: &cgroups.BlkioStat{
+				IoServiceBytesRecursive: []cgroups.BlkioEntry{
+					{Op: "Read", Major: 259, Minor: 0, Value: 4096},
+					{Op: "Write", Major: 259, Minor: 0, Value: 8192},
+					{Op: "Total", Major: 259, Minor: 0, Value: 12288},
+					{Op: "Write", Major: 8, Minor: 0, Value: 512},
+				},
+				IoServicedRecursive: []cgroups.BlkioEntry{
+					{Op: "Read", Major: 259, Minor: 0, Value: 1},
+					{Op: "Write", Major: 259, Minor: 0, Value: 2},
+				},
+			},
+		}, nil),
+	)
+
+	usage, err := newControl(mockCgroupFactory).Usage(testCgroupRoot)
+	assert.NoError(t, err)
+	assert.Equal(t, &Usage{
+		Pids: 12,
+		BlockIO: []DeviceIOUsage{
+			{Device: "259:0", ReadBytes: 4096, WriteBytes: 8192, ReadIOs: 1, WriteIOs: 2},
+			{Device: "8:0", WriteBytes: 512},
+		},
+	}, usage)
+}
+
+func TestUsageLoadError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCgroupFactory := mock_factory.NewMockCgroupFactory(ctrl)
+	mockCgroupFactory.EXPECT().Load(gomock.Any(), gomock.Any()).Return(nil, errors.New("cgroup error"))
+
+	_, err := newControl(mockCgroupFactory).Usage(testCgroupRoot)
+	assert.Error(t, err)
+}
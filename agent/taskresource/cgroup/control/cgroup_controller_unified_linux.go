@@ -0,0 +1,376 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package control
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cihub/seelog"
+	"github.com/containerd/cgroups"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+)
+
+const (
+	// unifiedControllersFile lists the controllers available in a cgroup of
+	// the unified hierarchy. It only exists in cgroup v2.
+	unifiedControllersFile = "cgroup.controllers"
+	// unifiedSubtreeControlFile holds the controllers enabled for the
+	// children of a cgroup of the unified hierarchy
+	unifiedSubtreeControlFile = "cgroup.subtree_control"
+
+	cpuMaxFile     = "cpu.max"
+	cpuWeightFile  = "cpu.weight"
+	memoryMaxFile  = "memory.max"
+	memoryHighFile = "memory.high"
+	ioMaxFile      = "io.max"
+	ioWeightFile   = "io.weight"
+	pidsMaxFile    = "pids.max"
+
+	pidsCurrentFile = "pids.current"
+	ioStatFile      = "io.stat"
+
+	// defaultCPUPeriod is the CPU period of cgroup v2, in microseconds
+	defaultCPUPeriod = 100000
+	unifiedFileMode  = os.FileMode(0644)
+	unifiedDirMode   = os.FileMode(0755)
+)
+
+// unifiedControllers are the controllers enabled for the task cgroups
+var unifiedControllers = []string{"cpu", "io", "memory", "pids"}
+
+// unifiedControl implements Control on the unified hierarchy of cgroup v2,
+// which isn't supported by the containerd cgroups library. The cgroups are
+// managed through the files of the cgroup filesystem mounted at mountPath.
+type unifiedControl struct {
+	mountPath string
+}
+
+func newUnifiedControl(mountPath string) Control {
+	return &unifiedControl{
+		mountPath: mountPath,
+	}
+}
+
+// UnifiedHierarchy returns true if the cgroup filesystem mounted at mountPath
+// is the unified hierarchy of cgroup v2
+func UnifiedHierarchy(mountPath string) bool {
+	_, err := os.Stat(filepath.Join(mountPath, unifiedControllersFile))
+	return err == nil
+}
+
+// Create creates the cgroup and applies the resource limits of the spec. No
+// cgroups.Cgroup is returned, as the library only supports cgroup v1.
+func (c *unifiedControl) Create(cgroupSpec *Spec) (cgroups.Cgroup, error) {
+	err := validateCgroupSpec(cgroupSpec)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cgroup create: failed to validate spec")
+	}
+
+	seelog.Infof("Creating cgroup %s in the unified hierarchy", cgroupSpec.Root)
+	if err := c.enableControllers(cgroupSpec.Root); err != nil {
+		return nil, errors.Wrapf(err, "cgroup create: unable to enable controllers")
+	}
+	cgroupPath := c.path(cgroupSpec.Root)
+	if err := os.MkdirAll(cgroupPath, unifiedDirMode); err != nil {
+		return nil, errors.Wrapf(err, "cgroup create: unable to create cgroup")
+	}
+
+	files, err := unifiedResourceFiles(cgroupSpec.Specs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cgroup create: invalid linux resource spec")
+	}
+	for _, file := range sortedKeys(files) {
+		// Files such as io.max take one entry per write
+		for _, value := range files[file] {
+			if err := ioutil.WriteFile(filepath.Join(cgroupPath, file), []byte(value), unifiedFileMode); err != nil {
+				return nil, errors.Wrapf(err, "cgroup create: unable to set %s", file)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Remove removes the cgroup. cgroups.ErrCgroupDeleted is returned if the
+// cgroup doesn't exist, as with cgroup v1.
+func (c *unifiedControl) Remove(cgroupPath string) error {
+	seelog.Debugf("Removing cgroup %s", cgroupPath)
+
+	err := os.Remove(c.path(cgroupPath))
+	if os.IsNotExist(err) {
+		return cgroups.ErrCgroupDeleted
+	}
+	if err != nil {
+		return errors.Wrapf(err, "cgroup remove: unable to delete cgroup")
+	}
+	return nil
+}
+
+// Exists returns true if the cgroup exists
+func (c *unifiedControl) Exists(cgroupPath string) bool {
+	seelog.Debugf("Checking existence of cgroup: %s", cgroupPath)
+
+	info, err := os.Stat(c.path(cgroupPath))
+	return err == nil && info.IsDir()
+}
+
+// Usage returns the number of processes and the block IO of the cgroup
+func (c *unifiedControl) Usage(cgroupPath string) (*Usage, error) {
+	if !c.Exists(cgroupPath) {
+		return nil, cgroups.ErrCgroupDeleted
+	}
+	usage := &Usage{}
+
+	pids, err := ioutil.ReadFile(filepath.Join(c.path(cgroupPath), pidsCurrentFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "cgroup usage: unable to read %s", pidsCurrentFile)
+	}
+	if err == nil {
+		usage.Pids, err = strconv.ParseUint(strings.TrimSpace(string(pids)), 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cgroup usage: invalid %s", pidsCurrentFile)
+		}
+	}
+
+	ioStat, err := ioutil.ReadFile(filepath.Join(c.path(cgroupPath), ioStatFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "cgroup usage: unable to read %s", ioStatFile)
+	}
+	if err == nil {
+		usage.BlockIO, err = parseIOStat(string(ioStat))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cgroup usage: invalid %s", ioStatFile)
+		}
+	}
+	return usage, nil
+}
+
+// Unified returns true, as the control manages the unified hierarchy
+func (c *unifiedControl) Unified() bool {
+	return true
+}
+
+func (c *unifiedControl) path(cgroupPath string) string {
+	return filepath.Join(c.mountPath, cgroupPath)
+}
+
+// enableControllers enables the controllers of the task cgroups in the
+// subtree of every ancestor of the cgroup, so that its resource files are
+// created along with it
+func (c *unifiedControl) enableControllers(cgroupPath string) error {
+	var ancestors []string
+	for dir := filepath.Dir(filepath.Clean("/" + cgroupPath)); ; dir = filepath.Dir(dir) {
+		ancestors = append([]string{dir}, ancestors...)
+		if dir == "/" {
+			break
+		}
+	}
+
+	for _, ancestor := range ancestors {
+		ancestorPath := c.path(ancestor)
+		if err := os.MkdirAll(ancestorPath, unifiedDirMode); err != nil {
+			return err
+		}
+		available, err := ioutil.ReadFile(filepath.Join(ancestorPath, unifiedControllersFile))
+		if err != nil {
+			return err
+		}
+		var enable []string
+		for _, controller := range unifiedControllers {
+			for _, availableController := range strings.Fields(string(available)) {
+				if controller == availableController {
+					enable = append(enable, "+"+controller)
+				}
+			}
+		}
+		if len(enable) == 0 {
+			continue
+		}
+		err = ioutil.WriteFile(filepath.Join(ancestorPath, unifiedSubtreeControlFile),
+			[]byte(strings.Join(enable, " ")), unifiedFileMode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unifiedResourceFiles translates the linux resource spec into the values
+// written to the resource files of a cgroup v2 cgroup
+func unifiedResourceFiles(resources *specs.LinuxResources) (map[string][]string, error) {
+	files := make(map[string][]string)
+
+	if cpu := resources.CPU; cpu != nil {
+		period := uint64(defaultCPUPeriod)
+		if cpu.Period != nil && *cpu.Period != 0 {
+			period = *cpu.Period
+		}
+		if cpu.Quota != nil && *cpu.Quota > 0 {
+			files[cpuMaxFile] = []string{fmt.Sprintf("%d %d", *cpu.Quota, period)}
+		} else if cpu.Period != nil && *cpu.Period != 0 {
+			files[cpuMaxFile] = []string{fmt.Sprintf("max %d", period)}
+		}
+		if cpu.Shares != nil && *cpu.Shares != 0 {
+			files[cpuWeightFile] = []string{strconv.FormatUint(cpuSharesToWeight(*cpu.Shares), 10)}
+		}
+	}
+
+	if memory := resources.Memory; memory != nil {
+		if memory.Limit != nil && *memory.Limit > 0 {
+			files[memoryMaxFile] = []string{strconv.FormatInt(*memory.Limit, 10)}
+		}
+		// The reservation is a soft limit in cgroup v1, above which the
+		// memory of the cgroup is reclaimed first. memory.high throttles and
+		// reclaims the memory of the cgroup above it.
+		if memory.Reservation != nil && *memory.Reservation > 0 {
+			files[memoryHighFile] = []string{strconv.FormatInt(*memory.Reservation, 10)}
+		}
+	}
+
+	if pids := resources.Pids; pids != nil && pids.Limit > 0 {
+		files[pidsMaxFile] = []string{strconv.FormatInt(pids.Limit, 10)}
+	}
+
+	if blockIO := resources.BlockIO; blockIO != nil {
+		if blockIO.Weight != nil && *blockIO.Weight != 0 {
+			files[ioWeightFile] = []string{fmt.Sprintf("default %d", blkioWeightToIOWeight(*blockIO.Weight))}
+		}
+		ioMax, err := ioMaxLines(blockIO)
+		if err != nil {
+			return nil, err
+		}
+		if len(ioMax) > 0 {
+			files[ioMaxFile] = ioMax
+		}
+	}
+	return files, nil
+}
+
+// cpuSharesToWeight converts the CPU shares of cgroup v1, in [2, 262144], to
+// the CPU weight of cgroup v2, in [1, 10000], the way runc does
+func cpuSharesToWeight(shares uint64) uint64 {
+	if shares < 2 {
+		shares = 2
+	}
+	if shares > 262144 {
+		shares = 262144
+	}
+	return 1 + ((shares-2)*9999)/262142
+}
+
+// blkioWeightToIOWeight converts the block IO weight of cgroup v1, in
+// [10, 1000], to the IO weight of cgroup v2, in [1, 10000]
+func blkioWeightToIOWeight(weight uint16) uint64 {
+	if weight < 10 {
+		weight = 10
+	}
+	if weight > 1000 {
+		weight = 1000
+	}
+	return 1 + (uint64(weight)-10)*9999/990
+}
+
+// ioMaxLines returns the io.max lines of the throttled devices, in the
+// "<major>:<minor> rbps=<n> wbps=<n> riops=<n> wiops=<n>" format
+func ioMaxLines(blockIO *specs.LinuxBlockIO) ([]string, error) {
+	limits := make(map[string][]string)
+	var devices []string
+	add := func(key string, throttles []specs.LinuxThrottleDevice) error {
+		for _, throttle := range throttles {
+			if throttle.Major < 0 || throttle.Minor < 0 {
+				return errors.Errorf("invalid device %d:%d", throttle.Major, throttle.Minor)
+			}
+			device := fmt.Sprintf("%d:%d", throttle.Major, throttle.Minor)
+			if _, ok := limits[device]; !ok {
+				devices = append(devices, device)
+			}
+			limits[device] = append(limits[device], fmt.Sprintf("%s=%d", key, throttle.Rate))
+		}
+		return nil
+	}
+	for _, throttle := range []struct {
+		key       string
+		throttles []specs.LinuxThrottleDevice
+	}{
+		{"rbps", blockIO.ThrottleReadBpsDevice},
+		{"wbps", blockIO.ThrottleWriteBpsDevice},
+		{"riops", blockIO.ThrottleReadIOPSDevice},
+		{"wiops", blockIO.ThrottleWriteIOPSDevice},
+	} {
+		if err := add(throttle.key, throttle.throttles); err != nil {
+			return nil, err
+		}
+	}
+
+	lines := make([]string, 0, len(devices))
+	for _, device := range devices {
+		lines = append(lines, device+" "+strings.Join(limits[device], " "))
+	}
+	return lines, nil
+}
+
+// parseIOStat parses the io.stat lines of the devices, in the
+// "<major>:<minor> rbytes=<n> wbytes=<n> rios=<n> wios=<n> ..." format
+func parseIOStat(ioStat string) ([]DeviceIOUsage, error) {
+	devices := make(deviceIOUsages)
+	for _, line := range strings.Split(ioStat, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var major, minor uint64
+		if _, err := fmt.Sscanf(fields[0], "%d:%d", &major, &minor); err != nil {
+			return nil, errors.Errorf("invalid device %q", fields[0])
+		}
+		device := devices.device(major, minor)
+		for _, field := range fields[1:] {
+			keyValue := strings.SplitN(field, "=", 2)
+			if len(keyValue) != 2 {
+				return nil, errors.Errorf("invalid stat %q", field)
+			}
+			value, err := strconv.ParseUint(keyValue[1], 10, 64)
+			if err != nil {
+				return nil, errors.Errorf("invalid stat %q", field)
+			}
+			switch keyValue[0] {
+			case "rbytes":
+				device.ReadBytes = value
+			case "wbytes":
+				device.WriteBytes = value
+			case "rios":
+				device.ReadIOs = value
+			case "wios":
+				device.WriteIOs = value
+			}
+		}
+	}
+	return devices.list(), nil
+}
+
+func sortedKeys(files map[string][]string) []string {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,200 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package control
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/containerd/cgroups"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupUnifiedMount creates a directory that looks like the root of the
+// unified hierarchy, along with the /ecs cgroup
+func setupUnifiedMount(t *testing.T) string {
+	mountPath, err := ioutil.TempDir("", "cgroup2")
+	require.NoError(t, err)
+	controllers := []byte("cpuset cpu io memory pids")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(mountPath, unifiedControllersFile), controllers, 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(mountPath, "ecs"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(mountPath, "ecs", unifiedControllersFile), controllers, 0644))
+	return mountPath
+}
+
+func throttleDevice(major, minor int64, rate uint64) specs.LinuxThrottleDevice {
+	device := specs.LinuxThrottleDevice{Rate: rate}
+	device.Major = major
+	device.Minor = minor
+	return device
+}
+
+func readCgroupFile(t *testing.T, path ...string) string {
+	content, err := ioutil.ReadFile(filepath.Join(path...))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestUnifiedHierarchy(t *testing.T) {
+	mountPath := setupUnifiedMount(t)
+	defer os.RemoveAll(mountPath)
+
+	assert.True(t, UnifiedHierarchy(mountPath))
+	assert.True(t, New(mountPath).Unified())
+	assert.False(t, UnifiedHierarchy(filepath.Join(mountPath, "nonexistent")))
+}
+
+func TestUnifiedCreate(t *testing.T) {
+	mountPath := setupUnifiedMount(t)
+	defer os.RemoveAll(mountPath)
+
+	control := newUnifiedControl(mountPath)
+	cgroup, err := control.Create(&Spec{
+		Root: testCgroupRoot,
+		Specs: &specs.LinuxResources{
+			CPU: &specs.LinuxCPU{
+				Quota:  aws.Int64(50000),
+				Period: aws.Uint64(100000),
+				Shares: aws.Uint64(1024),
+			},
+			Memory: &specs.LinuxMemory{
+				Limit:       aws.Int64(512 * 1024 * 1024),
+				Reservation: aws.Int64(256 * 1024 * 1024),
+			},
+			Pids: &specs.LinuxPids{Limit: 512},
+			BlockIO: &specs.LinuxBlockIO{
+				Weight:                  aws.Uint16(500),
+				ThrottleReadBpsDevice:   []specs.LinuxThrottleDevice{throttleDevice(8, 0, 1048576)},
+				ThrottleWriteIOPSDevice: []specs.LinuxThrottleDevice{throttleDevice(8, 0, 100)},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, cgroup)
+	assert.True(t, control.Exists(testCgroupRoot))
+
+	// The controllers are enabled in the subtree of every ancestor
+	assert.Equal(t, "+cpu +io +memory +pids", readCgroupFile(t, mountPath, unifiedSubtreeControlFile))
+	assert.Equal(t, "+cpu +io +memory +pids", readCgroupFile(t, mountPath, "ecs", unifiedSubtreeControlFile))
+
+	cgroupPath := filepath.Join(mountPath, testCgroupRoot)
+	assert.Equal(t, "50000 100000", readCgroupFile(t, cgroupPath, cpuMaxFile))
+	assert.Equal(t, "39", readCgroupFile(t, cgroupPath, cpuWeightFile))
+	assert.Equal(t, "536870912", readCgroupFile(t, cgroupPath, memoryMaxFile))
+	assert.Equal(t, "268435456", readCgroupFile(t, cgroupPath, memoryHighFile))
+	assert.Equal(t, "8:0 rbps=1048576 wiops=100", readCgroupFile(t, cgroupPath, ioMaxFile))
+	assert.Equal(t, "default 4950", readCgroupFile(t, cgroupPath, ioWeightFile))
+	assert.Equal(t, "512", readCgroupFile(t, cgroupPath, pidsMaxFile))
+}
+
+func TestUnifiedCreateWithoutControllers(t *testing.T) {
+	mountPath, err := ioutil.TempDir("", "cgroup2")
+	require.NoError(t, err)
+	defer os.RemoveAll(mountPath)
+
+	// The root of the unified hierarchy must list its controllers
+	_, err = newUnifiedControl(mountPath).Create(&Spec{testCgroupRoot, &specs.LinuxResources{}})
+	assert.Error(t, err)
+}
+
+func TestUnifiedCreateWithBadSpecs(t *testing.T) {
+	control := newUnifiedControl("/nonexistent")
+	for _, spec := range []*Spec{nil, {}, {Root: testCgroupRoot}} {
+		_, err := control.Create(spec)
+		assert.Error(t, err)
+	}
+}
+
+func TestUnifiedInit(t *testing.T) {
+	mountPath := setupUnifiedMount(t)
+	defer os.RemoveAll(mountPath)
+
+	control := newUnifiedControl(mountPath)
+	require.NoError(t, control.Init())
+	assert.True(t, control.Exists("/ecs"))
+	assert.Equal(t, "+cpu +io +memory +pids", readCgroupFile(t, mountPath, unifiedSubtreeControlFile))
+}
+
+func TestUnifiedRemove(t *testing.T) {
+	mountPath := setupUnifiedMount(t)
+	defer os.RemoveAll(mountPath)
+
+	control := newUnifiedControl(mountPath)
+	require.NoError(t, os.Mkdir(filepath.Join(mountPath, testCgroupRoot), 0755))
+	assert.NoError(t, control.Remove(testCgroupRoot))
+	assert.False(t, control.Exists(testCgroupRoot))
+	assert.Equal(t, cgroups.ErrCgroupDeleted, control.Remove(testCgroupRoot))
+}
+
+func TestCPUSharesToWeight(t *testing.T) {
+	assert.Equal(t, uint64(1), cpuSharesToWeight(0))
+	assert.Equal(t, uint64(1), cpuSharesToWeight(2))
+	assert.Equal(t, uint64(39), cpuSharesToWeight(1024))
+	assert.Equal(t, uint64(10000), cpuSharesToWeight(262144))
+	assert.Equal(t, uint64(10000), cpuSharesToWeight(1000000))
+}
+
+func TestUnifiedResourceFilesPeriodOnly(t *testing.T) {
+	files, err := unifiedResourceFiles(&specs.LinuxResources{
+		CPU: &specs.LinuxCPU{Period: aws.Uint64(50000)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{cpuMaxFile: {"max 50000"}}, files)
+}
+
+func TestBlkioWeightToIOWeight(t *testing.T) {
+	assert.Equal(t, uint64(1), blkioWeightToIOWeight(0))
+	assert.Equal(t, uint64(1), blkioWeightToIOWeight(10))
+	assert.Equal(t, uint64(4950), blkioWeightToIOWeight(500))
+	assert.Equal(t, uint64(10000), blkioWeightToIOWeight(1000))
+}
+
+func TestUnifiedUsage(t *testing.T) {
+	mountPath := setupUnifiedMount(t)
+	defer os.RemoveAll(mountPath)
+
+	control := newUnifiedControl(mountPath)
+	_, err := control.Usage(testCgroupRoot)
+	assert.Equal(t, cgroups.ErrCgroupDeleted, err)
+
+	cgroupPath := filepath.Join(mountPath, testCgroupRoot)
+	require.NoError(t, os.Mkdir(cgroupPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(cgroupPath, pidsCurrentFile), []byte("12\n"), 0644))
+	ioStat := "259:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n8:0 rbytes=0 wbytes=512 rios=0 wios=1 dbytes=0 dios=0\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(cgroupPath, ioStatFile), []byte(ioStat), 0644))
+
+	usage, err := control.Usage(testCgroupRoot)
+	require.NoError(t, err)
+	assert.Equal(t, &Usage{
+		Pids: 12,
+		BlockIO: []DeviceIOUsage{
+			{Device: "259:0", ReadBytes: 4096, WriteBytes: 8192, ReadIOs: 1, WriteIOs: 2},
+			{Device: "8:0", WriteBytes: 512, WriteIOs: 1},
+		},
+	}, usage)
+}
+
+func TestParseIOStatInvalid(t *testing.T) {
+	for _, ioStat := range []string{"sda rbytes=1", "8:0 rbytes", "8:0 rbytes=x"} {
+		_, err := parseIOStat(ioStat)
+		assert.Error(t, err, ioStat)
+	}
+}
@@ -34,3 +34,15 @@ func (c *control) Init() error {
 	_, err := c.Create(cgroupSpec)
 	return err
 }
+
+// Init is used to setup the cgroup root for ecs in the unified hierarchy
+func (c *unifiedControl) Init() error {
+	seelog.Infof("Creating root ecs cgroup in the unified hierarchy: %s", config.DefaultTaskCgroupPrefix)
+
+	cgroupSpec := &Spec{
+		Root:  config.DefaultTaskCgroupPrefix,
+		Specs: &specs.LinuxResources{},
+	}
+	_, err := c.Create(cgroupSpec)
+	return err
+}
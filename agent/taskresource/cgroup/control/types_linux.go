@@ -29,9 +29,16 @@ type Spec struct {
 	Specs *specs.LinuxResources
 }
 
+// Control manages the task cgroups, either in the cgroup v1 hierarchies or
+// in the cgroup v2 unified hierarchy. The cgroups.Cgroup returned by Create
+// is nil in the unified hierarchy.
 type Control interface {
 	Create(cgroupSpec *Spec) (cgroups.Cgroup, error)
 	Remove(cgroupPath string) error
 	Exists(cgroupPath string) bool
 	Init() error
+	// Usage returns the current resource usage of the cgroup
+	Usage(cgroupPath string) (*Usage, error)
+	// Unified returns true if the cgroups are managed in the unified hierarchy
+	Unified() bool
 }
@@ -0,0 +1,62 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package control
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Usage is the resource usage of a task cgroup
+type Usage struct {
+	// Pids is the number of processes in the cgroup
+	Pids uint64
+	// BlockIO is the block IO of the cgroup, per device
+	BlockIO []DeviceIOUsage
+}
+
+// DeviceIOUsage is the block IO of a cgroup on a device
+type DeviceIOUsage struct {
+	// Device is the major and minor numbers of the device, e.g. "259:0"
+	Device     string
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadIOs    uint64
+	WriteIOs   uint64
+}
+
+// deviceIOUsages collects the block IO of a cgroup per device
+type deviceIOUsages map[string]*DeviceIOUsage
+
+func (usages deviceIOUsages) device(major, minor uint64) *DeviceIOUsage {
+	device := fmt.Sprintf("%d:%d", major, minor)
+	usage, ok := usages[device]
+	if !ok {
+		usage = &DeviceIOUsage{Device: device}
+		usages[device] = usage
+	}
+	return usage
+}
+
+// list returns the block IO of the devices, sorted by device
+func (usages deviceIOUsages) list() []DeviceIOUsage {
+	var list []DeviceIOUsage
+	for _, usage := range usages {
+		list = append(list, *usage)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Device < list[j].Device
+	})
+	return list
+}
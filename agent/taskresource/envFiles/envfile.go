@@ -14,10 +14,19 @@
 package envFiles
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,10 +34,14 @@ import (
 	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
 	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
 	"github.com/aws/amazon-ecs-agent/agent/s3"
 	"github.com/aws/amazon-ecs-agent/agent/s3/factory"
+	"github.com/aws/amazon-ecs-agent/agent/ssm"
+	ssmfactory "github.com/aws/amazon-ecs-agent/agent/ssm/factory"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	"github.com/aws/amazon-ecs-agent/agent/utils/oswrapper"
+	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/cihub/seelog"
 	"github.com/pkg/errors"
 
@@ -46,11 +59,37 @@ const (
 	commentIndicator     = "#"
 	envVariableDelimiter = "="
 
-	s3DownloadTimeout = 30 * time.Second
+	s3DownloadTimeout    = 30 * time.Second
+	httpsDownloadTimeout = 30 * time.Second
+	// maxHTTPSEnvfileSize is the maximum size of an env file downloaded over
+	// HTTPS, so that a server can't fill the data directory of the agent
+	maxHTTPSEnvfileSize = 1024 * 1024
+
+	// checksumPrefix prefixes the SHA-256 checksum in the fragment of the
+	// URL of the environment files of type "https"
+	checksumPrefix = "sha256="
+)
+
+const (
+	// EnvironmentFileTypeS3 is the type of the environment files downloaded
+	// from S3, whose value is the ARN of the S3 object
+	EnvironmentFileTypeS3 = "s3"
+	// EnvironmentFileTypeFile is the type of the environment files read from
+	// the host, whose value is a path under one of the allowed directories
+	EnvironmentFileTypeFile = "file"
+	// EnvironmentFileTypeHTTPS is the type of the environment files
+	// downloaded over HTTPS, whose value is the URL of the file with its
+	// SHA-256 checksum as fragment, such as
+	// https://example.com/app.env#sha256=<hex>
+	EnvironmentFileTypeHTTPS = "https"
+	// EnvironmentFileTypeSSM is the type of the environment files stored in
+	// an SSM parameter, whose value is the name or ARN of the parameter
+	EnvironmentFileTypeSSM = "ssm"
 )
 
 // EnvironmentFileResource represents envfile as a task resource
-// these environment files are retrieved from s3
+// these environment files are retrieved from s3, from the host, over HTTPS or
+// from SSM Parameter Store
 type EnvironmentFileResource struct {
 	cluster       string
 	taskARN       string
@@ -60,13 +99,20 @@ type EnvironmentFileResource struct {
 
 	// env file related attributes
 	environmentFilesSource []apicontainer.EnvironmentFile // list of env file objects
+	allowedHostDirs        []string                       // host directories that env files can be read from
 
 	executionCredentialsID string
 	credentialsManager     credentials.Manager
 	s3ClientCreator        factory.S3ClientCreator
+	ssmClientCreator       ssmfactory.SSMClientCreator
+	httpClient             *http.Client
 	ioutil                 ioutilwrapper.IOUtil
 	bufio                  bufiowrapper.Bufio
 
+	// variableNamesUnsafe are the names of the variables read from each env
+	// file, in the order of environmentFilesSource
+	variableNamesUnsafe [][]string
+
 	// Fields for the common functionality of task resource. Access to these fields are protected by lock.
 	createdAtUnsafe      time.Time
 	desiredStatusUnsafe  resourcestatus.ResourceStatus
@@ -80,16 +126,27 @@ type EnvironmentFileResource struct {
 
 // NewEnvironmentFileResource creates a new EnvironmentFileResource object
 func NewEnvironmentFileResource(cluster, taskARN, region, dataDir, containerName string, envfiles []apicontainer.EnvironmentFile,
-	credentialsManager credentials.Manager, executionCredentialsID string) (*EnvironmentFileResource, error) {
+	allowedHostDirs []string, credentialsManager credentials.Manager, executionCredentialsID string) (*EnvironmentFileResource, error) {
+	for _, envfileSource := range envfiles {
+		switch envfileSource.Type {
+		case "", EnvironmentFileTypeS3, EnvironmentFileTypeFile, EnvironmentFileTypeHTTPS, EnvironmentFileTypeSSM:
+		default:
+			return nil, errors.Errorf("unsupported type %q of environment file %s", envfileSource.Type, envfileSource.Value)
+		}
+	}
+
 	envfileResource := &EnvironmentFileResource{
 		cluster:                cluster,
 		taskARN:                taskARN,
 		region:                 region,
 		containerName:          containerName,
 		environmentFilesSource: envfiles,
+		allowedHostDirs:        allowedHostDirs,
 		ioutil:                 ioutilwrapper.NewIOUtil(),
 		bufio:                  bufiowrapper.NewBufio(),
 		s3ClientCreator:        factory.NewS3ClientCreator(),
+		ssmClientCreator:       ssmfactory.NewSSMClientCreator(),
+		httpClient:             httpclient.New(httpsDownloadTimeout, false),
 		executionCredentialsID: executionCredentialsID,
 		credentialsManager:     credentialsManager,
 	}
@@ -112,7 +169,10 @@ func (envfile *EnvironmentFileResource) Initialize(resourceFields *taskresource.
 
 	envfile.initStatusToTransition()
 	envfile.credentialsManager = resourceFields.CredentialsManager
+	envfile.allowedHostDirs = resourceFields.EnvironmentFileAllowedDirs
 	envfile.s3ClientCreator = factory.NewS3ClientCreator()
+	envfile.ssmClientCreator = ssmfactory.NewSSMClientCreator()
+	envfile.httpClient = httpclient.New(httpsDownloadTimeout, false)
 	envfile.ioutil = ioutilwrapper.NewIOUtil()
 	envfile.bufio = bufiowrapper.NewBufio()
 
@@ -282,26 +342,37 @@ func (envfile *EnvironmentFileResource) setTerminalReason(reason string) {
 }
 
 // Create performs resource creation. This retrieves env file contents concurrently
-// from s3 and writes them to disk
+// from their source and writes them to disk
 func (envfile *EnvironmentFileResource) Create() error {
 	seelog.Debugf("Creating envfile resource.")
-	// make sure it has the task execution role
-	executionCredentials, ok := envfile.credentialsManager.GetTaskCredentials(envfile.executionCredentialsID)
-	if !ok {
-		err := errors.New("environment file resource: unable to find execution role credentials")
-		envfile.setTerminalReason(err.Error())
-		return err
+	var iamCredentials credentials.IAMRoleCredentials
+	if envfile.requiresExecutionCredentials() {
+		// make sure it has the task execution role
+		executionCredentials, ok := envfile.credentialsManager.GetTaskCredentials(envfile.executionCredentialsID)
+		if !ok {
+			err := errors.New("environment file resource: unable to find execution role credentials")
+			envfile.setTerminalReason(err.Error())
+			return err
+		}
+		iamCredentials = executionCredentials.GetIAMRoleCredentials()
 	}
 
 	var wg sync.WaitGroup
 	errorEvents := make(chan error, len(envfile.environmentFilesSource))
 
-	iamCredentials := executionCredentials.GetIAMRoleCredentials()
-	for _, envfileSource := range envfile.environmentFilesSource {
+	for index, envfileSource := range envfile.environmentFilesSource {
 		wg.Add(1)
-		// if we support types besides S3 ARN, we will need to add filtering before the below method is called
 		// call an additional go routine per env file
-		go envfile.downloadEnvfileFromS3(envfileSource.Value, iamCredentials, &wg, errorEvents)
+		switch envfileSource.Type {
+		case EnvironmentFileTypeFile:
+			go envfile.copyEnvfileFromHost(index, envfileSource.Value, &wg, errorEvents)
+		case EnvironmentFileTypeHTTPS:
+			go envfile.downloadEnvfileFromHTTPS(index, envfileSource.Value, &wg, errorEvents)
+		case EnvironmentFileTypeSSM:
+			go envfile.retrieveEnvfileFromSSM(index, envfileSource.Value, iamCredentials, &wg, errorEvents)
+		default:
+			go envfile.downloadEnvfileFromS3(envfileSource.Value, iamCredentials, &wg, errorEvents)
+		}
 	}
 
 	wg.Wait()
@@ -374,6 +445,247 @@ func (envfile *EnvironmentFileResource) downloadEnvfileFromS3(envFilePath string
 	seelog.Debugf("Downloaded envfile from s3 and saved to %s", downloadPath)
 }
 
+// requiresExecutionCredentials returns true if some of the env files are
+// retrieved with the task execution role
+func (envfile *EnvironmentFileResource) requiresExecutionCredentials() bool {
+	for _, envfileSource := range envfile.environmentFilesSource {
+		switch envfileSource.Type {
+		case EnvironmentFileTypeFile, EnvironmentFileTypeHTTPS:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// envfilePath returns the path that an env file that isn't downloaded from s3
+// is written to, based on its position in the list of env files
+func (envfile *EnvironmentFileResource) envfilePath(index int, envfileType string) string {
+	// we save these envfiles to path: /var/lib/ecs/data/envfiles/cluster_name/task_id/${type}/${index}.env
+	return filepath.Join(envfile.resourceDir, envfileType, strconv.Itoa(index)+envFileExtension)
+}
+
+// copyEnvfileFromHost copies an env file from the host, after making sure that
+// it's under one of the allowed directories once its symlinks are resolved
+func (envfile *EnvironmentFileResource) copyEnvfileFromHost(index int, hostPath string,
+	wg *sync.WaitGroup, errorEvents chan error) {
+	defer wg.Done()
+
+	resolvedPath, resolvedInfo, err := envfile.resolveHostPath(hostPath)
+	if err != nil {
+		errorEvents <- fmt.Errorf("unable to read env file %s from the host, error: %v", hostPath, err)
+		return
+	}
+
+	copyPath := envfile.envfilePath(index, EnvironmentFileTypeFile)
+	if err := mkdirAll(filepath.Dir(copyPath), os.ModePerm); err != nil {
+		errorEvents <- fmt.Errorf("unable to initialize envfile resource directory, error: %v", err)
+		return
+	}
+
+	err = envfile.writeEnvFile(func(file oswrapper.File) error {
+		hostFile, err := os.Open(resolvedPath)
+		if err != nil {
+			return err
+		}
+		defer hostFile.Close()
+		// The path could have been replaced, or one of its directories by a
+		// symlink, since it was resolved, so the opened file must be the one
+		// that was found under the allowed directory
+		info, err := hostFile.Stat()
+		if err != nil {
+			return err
+		}
+		if !os.SameFile(info, resolvedInfo) {
+			return errors.New("the path was changed while being read")
+		}
+		_, err = io.Copy(file, hostFile)
+		return err
+	}, copyPath)
+	if err != nil {
+		errorEvents <- fmt.Errorf("unable to copy env file %s from the host, error: %v", hostPath, err)
+		return
+	}
+
+	seelog.Debugf("Copied envfile from the host path %s to %s", hostPath, copyPath)
+}
+
+var evalSymlinks = filepath.EvalSymlinks
+
+// resolveHostPath resolves the symlinks of the host path of an env file, and
+// returns the resolved path along with its file info if it's a regular file
+// under an allowed directory
+func (envfile *EnvironmentFileResource) resolveHostPath(hostPath string) (string, os.FileInfo, error) {
+	if len(envfile.allowedHostDirs) == 0 {
+		return "", nil, errors.New("no host directory is allowed, ECS_ENVIRONMENT_FILE_ALLOWED_DIRS is not set")
+	}
+	if !filepath.IsAbs(hostPath) {
+		return "", nil, errors.New("the path is not absolute")
+	}
+	resolvedPath, err := evalSymlinks(hostPath)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, allowedDir := range envfile.allowedHostDirs {
+		resolvedDir, err := evalSymlinks(allowedDir)
+		if err != nil {
+			continue
+		}
+		if !strings.HasPrefix(resolvedPath, strings.TrimSuffix(resolvedDir, string(filepath.Separator))+string(filepath.Separator)) {
+			continue
+		}
+		info, err := os.Lstat(resolvedPath)
+		if err != nil {
+			return "", nil, err
+		}
+		if !info.Mode().IsRegular() {
+			return "", nil, errors.New("the path is not a regular file")
+		}
+		return resolvedPath, info, nil
+	}
+	return "", nil, errors.Errorf("the path is not under the allowed directories %s", strings.Join(envfile.allowedHostDirs, ", "))
+}
+
+// parseHTTPSEnvfileURL returns the URL that an env file of type "https" is
+// downloaded from, and the SHA-256 checksum that its content must match
+func parseHTTPSEnvfileURL(value string) (string, []byte, error) {
+	fileURL, err := url.Parse(value)
+	if err != nil {
+		return "", nil, err
+	}
+	if fileURL.Scheme != "https" || fileURL.Host == "" {
+		return "", nil, errors.New("expected an https URL")
+	}
+	if !strings.HasPrefix(fileURL.Fragment, checksumPrefix) {
+		return "", nil, errors.Errorf("expected the checksum of the file as URL fragment, such as #%s<hex>", checksumPrefix)
+	}
+	checksum, err := hex.DecodeString(strings.TrimPrefix(fileURL.Fragment, checksumPrefix))
+	if err != nil || len(checksum) != sha256.Size {
+		return "", nil, errors.New("invalid SHA-256 checksum")
+	}
+	fileURL.Fragment = ""
+	return fileURL.String(), checksum, nil
+}
+
+// downloadEnvfileFromHTTPS downloads an env file over HTTPS, and makes sure
+// that its content matches the checksum of its URL before writing it
+func (envfile *EnvironmentFileResource) downloadEnvfileFromHTTPS(index int, value string,
+	wg *sync.WaitGroup, errorEvents chan error) {
+	defer wg.Done()
+
+	fileURL, checksum, err := parseHTTPSEnvfileURL(value)
+	if err != nil {
+		errorEvents <- fmt.Errorf("unable to parse the URL specified in environmentFile %s, error: %v", redactEnvfileValue(value), err)
+		return
+	}
+
+	downloadPath := envfile.envfilePath(index, EnvironmentFileTypeHTTPS)
+	if err := mkdirAll(filepath.Dir(downloadPath), os.ModePerm); err != nil {
+		errorEvents <- fmt.Errorf("unable to initialize envfile resource directory, error: %v", err)
+		return
+	}
+
+	err = envfile.writeEnvFile(func(file oswrapper.File) error {
+		ctx, cancel := context.WithTimeout(context.Background(), httpsDownloadTimeout)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodGet, fileURL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := envfile.httpClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return errors.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		hash := sha256.New()
+		// One byte more than the maximum is read to find out whether the file
+		// is larger than allowed
+		n, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(resp.Body, maxHTTPSEnvfileSize+1))
+		if err != nil {
+			return err
+		}
+		if n > maxHTTPSEnvfileSize {
+			return errors.Errorf("the file is larger than %d bytes", maxHTTPSEnvfileSize)
+		}
+		if !bytes.Equal(hash.Sum(nil), checksum) {
+			return errors.Errorf("checksum mismatch, the SHA-256 checksum of the file is %x", hash.Sum(nil))
+		}
+		return nil
+	}, downloadPath)
+	if err != nil {
+		errorEvents <- fmt.Errorf("unable to download env file %s, error: %v", redactEnvfileValue(value), err)
+		return
+	}
+
+	seelog.Debugf("Downloaded envfile from %s and saved to %s", redactEnvfileValue(value), downloadPath)
+}
+
+// retrieveEnvfileFromSSM retrieves an env file stored in an SSM parameter. The
+// parameter is retrieved from the region of its ARN, or from the region of the
+// task if its name is given.
+func (envfile *EnvironmentFileResource) retrieveEnvfileFromSSM(index int, parameter string,
+	iamCredentials credentials.IAMRoleCredentials, wg *sync.WaitGroup, errorEvents chan error) {
+	defer wg.Done()
+
+	region := envfile.region
+	if arn.IsARN(parameter) {
+		parsedARN, err := arn.Parse(parameter)
+		if err != nil {
+			errorEvents <- fmt.Errorf("unable to parse the ARN specified in environmentFile %s, error: %v", parameter, err)
+			return
+		}
+		region = parsedARN.Region
+	}
+
+	ssmClient := envfile.ssmClientCreator.NewSSMClient(region, iamCredentials)
+	values, err := ssm.GetSecretsFromSSM([]string{parameter}, ssmClient)
+	if err != nil {
+		errorEvents <- fmt.Errorf("unable to retrieve env file %s from SSM Parameter Store in %s, error: %v", parameter, region, err)
+		return
+	}
+	if len(values) != 1 {
+		errorEvents <- fmt.Errorf("unable to retrieve env file %s from SSM Parameter Store in %s, error: parameter not found", parameter, region)
+		return
+	}
+	var content string
+	for _, value := range values {
+		content = value
+	}
+
+	parameterPath := envfile.envfilePath(index, EnvironmentFileTypeSSM)
+	if err := mkdirAll(filepath.Dir(parameterPath), os.ModePerm); err != nil {
+		errorEvents <- fmt.Errorf("unable to initialize envfile resource directory, error: %v", err)
+		return
+	}
+
+	err = envfile.writeEnvFile(func(file oswrapper.File) error {
+		_, err := file.Write([]byte(content))
+		return err
+	}, parameterPath)
+	if err != nil {
+		errorEvents <- fmt.Errorf("unable to write env file %s retrieved from SSM Parameter Store, error: %v", parameter, err)
+		return
+	}
+
+	seelog.Debugf("Retrieved envfile from SSM parameter %s and saved to %s", parameter, parameterPath)
+}
+
+// redactEnvfileValue removes the credentials and query that the value of an
+// env file of type "https" may hold, so that it can be logged or exposed
+func redactEnvfileValue(value string) string {
+	fileURL, err := url.Parse(value)
+	if err != nil || fileURL.Scheme != "https" {
+		return value
+	}
+	fileURL.User = nil
+	fileURL.RawQuery = ""
+	fileURL.Fragment = ""
+	return fileURL.String()
+}
+
 var rename = os.Rename
 
 func (envfile *EnvironmentFileResource) writeEnvFile(writeFunc func(file oswrapper.File) error, fullPathName string) error {
@@ -431,6 +743,7 @@ type environmentFileResourceJSON struct {
 	DesiredStatus          *EnvironmentFileStatus         `json:"desiredStatus"`
 	KnownStatus            *EnvironmentFileStatus         `json:"knownStatus"`
 	EnvironmentFilesSource []apicontainer.EnvironmentFile `json:"environmentFilesSource"`
+	VariableNames          [][]string                     `json:"variableNames,omitempty"`
 	ExecutionCredentialsID string                         `json:"executionCredentialsID"`
 }
 
@@ -455,6 +768,7 @@ func (envfile *EnvironmentFileResource) MarshalJSON() ([]byte, error) {
 			return &envfileStatus
 		}(),
 		EnvironmentFilesSource: envfile.environmentFilesSource,
+		VariableNames:          envfile.getVariableNames(),
 		ExecutionCredentialsID: envfile.executionCredentialsID,
 	})
 
@@ -484,6 +798,7 @@ func (envfile *EnvironmentFileResource) UnmarshalJSON(b []byte) error {
 		envfile.environmentFilesSource = envfileJson.EnvironmentFilesSource
 	}
 
+	envfile.variableNamesUnsafe = envfileJson.VariableNames
 	envfile.taskARN = envfileJson.TaskARN
 	envfile.containerName = envfileJson.ContainerName
 	envfile.executionCredentialsID = envfileJson.ExecutionCredentialsID
@@ -501,7 +816,12 @@ func (envfile *EnvironmentFileResource) GetContainerName() string {
 func (envfile *EnvironmentFileResource) convertEnvfileToPath() ([]string, error) {
 	var envfileLocations []string
 
-	for _, envfileObj := range envfile.environmentFilesSource {
+	for index, envfileObj := range envfile.environmentFilesSource {
+		switch envfileObj.Type {
+		case EnvironmentFileTypeFile, EnvironmentFileTypeHTTPS, EnvironmentFileTypeSSM:
+			envfileLocations = append(envfileLocations, envfile.envfilePath(index, envfileObj.Type))
+			continue
+		}
 		bucket, key, err := s3.ParseS3ARN(envfileObj.Value)
 		if err != nil {
 			seelog.Errorf("unable to parse bucket and key from s3 ARN specified in environmentFile %s", envfileObj.Value)
@@ -516,9 +836,11 @@ func (envfile *EnvironmentFileResource) convertEnvfileToPath() ([]string, error)
 }
 
 // ReadEnvVarsFromEnvFiles reads the environment files that have been downloaded
-// and puts them into a list of maps
+// and puts them into a list of maps. The files are parsed the same way whatever
+// their source, and the names of the variables read from each of them are kept.
 func (envfile *EnvironmentFileResource) ReadEnvVarsFromEnvfiles() ([]map[string]string, error) {
 	var envVarsPerEnvfile []map[string]string
+	var variableNames [][]string
 	envfileLocations, err := envfile.convertEnvfileToPath()
 	if err != nil {
 		return nil, err
@@ -530,11 +852,60 @@ func (envfile *EnvironmentFileResource) ReadEnvVarsFromEnvfiles() ([]map[string]
 			return nil, err
 		}
 		envVarsPerEnvfile = append(envVarsPerEnvfile, envVars)
+
+		names := make([]string, 0, len(envVars))
+		for name := range envVars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		variableNames = append(variableNames, names)
 	}
 
+	envfile.lock.Lock()
+	envfile.variableNamesUnsafe = variableNames
+	envfile.lock.Unlock()
+
 	return envVarsPerEnvfile, nil
 }
 
+func (envfile *EnvironmentFileResource) getVariableNames() [][]string {
+	envfile.lock.RLock()
+	defer envfile.lock.RUnlock()
+
+	return envfile.variableNamesUnsafe
+}
+
+// ResolvedEnvironmentFile is an environment file of the container along with
+// the names of the variables read from it. It never holds their values.
+type ResolvedEnvironmentFile struct {
+	Type          string
+	Value         string
+	VariableNames []string
+}
+
+// GetResolvedEnvironmentFiles returns the environment files of the container
+// along with the names of the variables read from them, once they have been
+// read. The credentials and query of the URLs of the files are removed.
+func (envfile *EnvironmentFileResource) GetResolvedEnvironmentFiles() []ResolvedEnvironmentFile {
+	variableNames := envfile.getVariableNames()
+	var resolved []ResolvedEnvironmentFile
+	for index, envfileSource := range envfile.environmentFilesSource {
+		envfileType := envfileSource.Type
+		if envfileType == "" {
+			envfileType = EnvironmentFileTypeS3
+		}
+		resolvedEnvfile := ResolvedEnvironmentFile{
+			Type:  envfileType,
+			Value: redactEnvfileValue(envfileSource.Value),
+		}
+		if index < len(variableNames) {
+			resolvedEnvfile.VariableNames = variableNames[index]
+		}
+		resolved = append(resolved, resolvedEnvfile)
+	}
+	return resolved
+}
+
 var open = func(name string) (oswrapper.File, error) {
 	return os.Open(name)
 }
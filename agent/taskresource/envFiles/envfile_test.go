@@ -16,9 +16,15 @@
 package envFiles
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -29,15 +35,21 @@ import (
 	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
 	mock_factory "github.com/aws/amazon-ecs-agent/agent/s3/factory/mocks"
 	mock_s3 "github.com/aws/amazon-ecs-agent/agent/s3/mocks"
+	mock_ssm_factory "github.com/aws/amazon-ecs-agent/agent/ssm/factory/mocks"
+	mock_ssm "github.com/aws/amazon-ecs-agent/agent/ssm/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/utils/bufiowrapper"
 	mock_bufio "github.com/aws/amazon-ecs-agent/agent/utils/bufiowrapper/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper"
 	mock_ioutilwrapper "github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/utils/oswrapper"
 	mock_oswrapper "github.com/aws/amazon-ecs-agent/agent/utils/oswrapper/mocks"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -100,9 +112,11 @@ func TestInitializeFileEnvResource(t *testing.T) {
 	}
 
 	envfileResource := newMockEnvfileResource(envfiles, mockCredentialsManager, nil, nil)
+	envfileResource.allowedHostDirs = []string{"/etc/ecs/envfiles", "/opt/app"}
 	envfileResource.Initialize(&taskresource.ResourceFields{
 		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
-			CredentialsManager: mockCredentialsManager,
+			CredentialsManager:         mockCredentialsManager,
+			EnvironmentFileAllowedDirs: []string{"/etc/ecs/envfiles"},
 		},
 	}, status.TaskRunning, status.TaskRunning)
 
@@ -111,6 +125,8 @@ func TestInitializeFileEnvResource(t *testing.T) {
 	assert.NotNil(t, envfileResource.credentialsManager)
 	assert.NotNil(t, envfileResource.s3ClientCreator)
 	assert.NotNil(t, envfileResource.ioutil)
+	assert.Equal(t, []string{"/etc/ecs/envfiles"}, envfileResource.allowedHostDirs,
+		"the allowed directories should be the ones of the config")
 }
 
 func TestCreateWithEnvVarFile(t *testing.T) {
@@ -439,3 +455,237 @@ func TestReadEnvVarsUnableToReadEnvfile(t *testing.T) {
 
 	assert.NotNil(t, err)
 }
+
+// newLocalEnvfileResource returns an envfile resource writing the env files to
+// a temporary directory
+func newLocalEnvfileResource(t *testing.T, envfiles []container.EnvironmentFile) (*EnvironmentFileResource, func()) {
+	dir, err := ioutil.TempDir("", "envfiles")
+	require.NoError(t, err)
+	envfileResource := &EnvironmentFileResource{
+		cluster:                cluster,
+		taskARN:                taskARN,
+		region:                 region,
+		resourceDir:            dir,
+		environmentFilesSource: envfiles,
+		ioutil:                 ioutilwrapper.NewIOUtil(),
+		bufio:                  bufiowrapper.NewBufio(),
+	}
+	return envfileResource, func() { os.RemoveAll(dir) }
+}
+
+func TestNewEnvironmentFileResourceUnsupportedType(t *testing.T) {
+	envfiles := []container.EnvironmentFile{
+		sampleEnvironmentFile("ftp://example.com/app.env", "ftp"),
+	}
+
+	_, err := NewEnvironmentFileResource(cluster, taskARN, region, resourceDir, "container", envfiles, nil, nil, executionCredentialsID)
+	assert.Error(t, err)
+}
+
+func TestCreateWithHostEnvfile(t *testing.T) {
+	hostDir, err := ioutil.TempDir("", "host")
+	require.NoError(t, err)
+	defer os.RemoveAll(hostDir)
+	hostPath := filepath.Join(hostDir, "app.env")
+	require.NoError(t, ioutil.WriteFile(hostPath, []byte("KEY2=value2\n# comment\nKEY1=value1\n"), 0600))
+
+	envfileResource, cleanup := newLocalEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile(hostPath, EnvironmentFileTypeFile),
+	})
+	defer cleanup()
+	envfileResource.allowedHostDirs = []string{hostDir}
+
+	// the execution role isn't required to read env files from the host
+	require.NoError(t, envfileResource.Create())
+
+	envVarsList, err := envfileResource.ReadEnvVarsFromEnvfiles()
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"KEY1": "value1", "KEY2": "value2"}}, envVarsList)
+	assert.Equal(t, []ResolvedEnvironmentFile{{
+		Type:          EnvironmentFileTypeFile,
+		Value:         hostPath,
+		VariableNames: []string{"KEY1", "KEY2"},
+	}}, envfileResource.GetResolvedEnvironmentFiles())
+}
+
+func TestCreateWithHostEnvfileOutsideAllowedDirs(t *testing.T) {
+	hostDir, err := ioutil.TempDir("", "host")
+	require.NoError(t, err)
+	defer os.RemoveAll(hostDir)
+	allowedDir := filepath.Join(hostDir, "allowed")
+	require.NoError(t, os.Mkdir(allowedDir, 0700))
+	outsidePath := filepath.Join(hostDir, "outside.env")
+	require.NoError(t, ioutil.WriteFile(outsidePath, []byte("KEY=value\n"), 0600))
+	linkPath := filepath.Join(allowedDir, "link.env")
+	require.NoError(t, os.Symlink(outsidePath, linkPath))
+
+	for name, testCase := range map[string]struct {
+		hostPath    string
+		allowedDirs []string
+	}{
+		"outside":          {outsidePath, []string{allowedDir}},
+		"symlink outside":  {linkPath, []string{allowedDir}},
+		"relative":         {"outside.env", []string{hostDir}},
+		"no allowed dir":   {outsidePath, nil},
+		"allowed dir only": {allowedDir, []string{hostDir}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			envfileResource, cleanup := newLocalEnvfileResource(t, []container.EnvironmentFile{
+				sampleEnvironmentFile(testCase.hostPath, EnvironmentFileTypeFile),
+			})
+			defer cleanup()
+			envfileResource.allowedHostDirs = testCase.allowedDirs
+
+			assert.Error(t, envfileResource.Create())
+			assert.Contains(t, envfileResource.GetTerminalReason(), "unable to read env file")
+		})
+	}
+}
+
+func TestCreateWithHTTPSEnvfile(t *testing.T) {
+	content := []byte("KEY=value\n")
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/app.env", r.URL.Path)
+		w.Write(content)
+	}))
+	defer server.Close()
+	checksum := sha256.Sum256(content)
+
+	envfileResource, cleanup := newLocalEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile(server.URL+"/app.env?token=secret#sha256="+hex.EncodeToString(checksum[:]), EnvironmentFileTypeHTTPS),
+	})
+	defer cleanup()
+	envfileResource.httpClient = server.Client()
+
+	require.NoError(t, envfileResource.Create())
+
+	envVarsList, err := envfileResource.ReadEnvVarsFromEnvfiles()
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"KEY": "value"}}, envVarsList)
+	assert.Equal(t, []ResolvedEnvironmentFile{{
+		Type:          EnvironmentFileTypeHTTPS,
+		Value:         server.URL + "/app.env",
+		VariableNames: []string{"KEY"},
+	}}, envfileResource.GetResolvedEnvironmentFiles())
+}
+
+func TestCreateWithHTTPSEnvfileChecksumMismatch(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("KEY=tampered\n"))
+	}))
+	defer server.Close()
+	checksum := sha256.Sum256([]byte("KEY=value\n"))
+
+	envfileResource, cleanup := newLocalEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile(server.URL+"/app.env#sha256="+hex.EncodeToString(checksum[:]), EnvironmentFileTypeHTTPS),
+	})
+	defer cleanup()
+	envfileResource.httpClient = server.Client()
+
+	assert.Error(t, envfileResource.Create())
+	assert.Contains(t, envfileResource.GetTerminalReason(), "checksum mismatch")
+}
+
+func TestCreateWithHTTPSEnvfileTooLarge(t *testing.T) {
+	content := bytes.Repeat([]byte("#"), maxHTTPSEnvfileSize+1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+	checksum := sha256.Sum256(content)
+
+	envfileResource, cleanup := newLocalEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile(server.URL+"/app.env#sha256="+hex.EncodeToString(checksum[:]), EnvironmentFileTypeHTTPS),
+	})
+	defer cleanup()
+	envfileResource.httpClient = server.Client()
+
+	assert.Error(t, envfileResource.Create())
+	assert.Contains(t, envfileResource.GetTerminalReason(), "larger than")
+}
+
+func TestParseHTTPSEnvfileURL(t *testing.T) {
+	checksum := sha256.Sum256([]byte("KEY=value\n"))
+	fileURL, parsedChecksum, err := parseHTTPSEnvfileURL("https://example.com/app.env?v=1#sha256=" + hex.EncodeToString(checksum[:]))
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/app.env?v=1", fileURL)
+	assert.Equal(t, checksum[:], parsedChecksum)
+
+	for _, value := range []string{
+		"http://example.com/app.env#sha256=" + hex.EncodeToString(checksum[:]),
+		"https://example.com/app.env",
+		"https://example.com/app.env#sha256=0123",
+		"https://example.com/app.env#md5=" + hex.EncodeToString(checksum[:]),
+	} {
+		_, _, err := parseHTTPSEnvfileURL(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestCreateWithSSMEnvfile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCredentialsManager := mock_credentials.NewMockManager(ctrl)
+	mockSSMClientCreator := mock_ssm_factory.NewMockSSMClientCreator(ctrl)
+	mockSSMClient := mock_ssm.NewMockSSMClient(ctrl)
+
+	parameterARN := "arn:aws:ssm:eu-west-1:01234567891011:parameter/app/env"
+	envfileResource, cleanup := newLocalEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile("/app/env", EnvironmentFileTypeSSM),
+		sampleEnvironmentFile(parameterARN, EnvironmentFileTypeSSM),
+	})
+	defer cleanup()
+	envfileResource.executionCredentialsID = executionCredentialsID
+	envfileResource.credentialsManager = mockCredentialsManager
+	envfileResource.ssmClientCreator = mockSSMClientCreator
+
+	creds := credentials.TaskIAMRoleCredentials{
+		ARN: iamRoleARN,
+		IAMRoleCredentials: credentials.IAMRoleCredentials{
+			AccessKeyID:     accessKeyId,
+			SecretAccessKey: secretAccessKey,
+		},
+	}
+	mockCredentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true)
+	mockSSMClientCreator.EXPECT().NewSSMClient(region, creds.IAMRoleCredentials).Return(mockSSMClient)
+	mockSSMClientCreator.EXPECT().NewSSMClient("eu-west-1", creds.IAMRoleCredentials).Return(mockSSMClient)
+	mockSSMClient.EXPECT().GetParameters(gomock.Any()).Times(2).DoAndReturn(
+		func(in *ssm.GetParametersInput) (*ssm.GetParametersOutput, error) {
+			assert.True(t, aws.BoolValue(in.WithDecryption))
+			name := aws.StringValue(in.Names[0])
+			content := "KEY=us-west-2\n"
+			if name == parameterARN {
+				content = "KEY=eu-west-1\nOTHER=value\n"
+			}
+			return &ssm.GetParametersOutput{
+				Parameters: []*ssm.Parameter{{Name: aws.String(name), Value: aws.String(content)}},
+			}, nil
+		})
+
+	require.NoError(t, envfileResource.Create())
+
+	envVarsList, err := envfileResource.ReadEnvVarsFromEnvfiles()
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"KEY": "us-west-2"}, {"KEY": "eu-west-1", "OTHER": "value"}}, envVarsList)
+	resolved := envfileResource.GetResolvedEnvironmentFiles()
+	require.Len(t, resolved, 2)
+	assert.Equal(t, []string{"KEY"}, resolved[0].VariableNames)
+	assert.Equal(t, []string{"KEY", "OTHER"}, resolved[1].VariableNames)
+}
+
+func TestMarshalUnmarshalVariableNames(t *testing.T) {
+	envfileResource, cleanup := newLocalEnvfileResource(t, []container.EnvironmentFile{
+		sampleEnvironmentFile("/etc/ecs/envfiles/app.env", EnvironmentFileTypeFile),
+	})
+	defer cleanup()
+	envfileResource.allowedHostDirs = []string{"/etc/ecs/envfiles"}
+	envfileResource.variableNamesUnsafe = [][]string{{"KEY1", "KEY2"}}
+
+	data, err := envfileResource.MarshalJSON()
+	require.NoError(t, err)
+
+	unmarshalled := &EnvironmentFileResource{}
+	require.NoError(t, unmarshalled.UnmarshalJSON(data))
+	assert.Empty(t, unmarshalled.allowedHostDirs, "the allowed directories should not be saved")
+	assert.Equal(t, envfileResource.GetResolvedEnvironmentFiles(), unmarshalled.GetResolvedEnvironmentFiles())
+}
@@ -127,6 +127,11 @@ func (firelens *FirelensResource) Create() error {
 	return errors.New("not implemented")
 }
 
+// RenderConfig returns the config that the firelens resource generates for the firelens container.
+func (firelens *FirelensResource) RenderConfig() ([]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
 // Cleanup cleans up the firelens resource.
 func (firelens *FirelensResource) Cleanup() error {
 	return errors.New("not implemented")
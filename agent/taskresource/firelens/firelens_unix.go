@@ -1,4 +1,5 @@
 // +build linux
+
 // Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License"). You may
@@ -15,7 +16,9 @@
 package firelens
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -408,8 +411,9 @@ func (firelens *FirelensResource) Create() error {
 		return err
 	}
 
+	var externalConfig []byte
 	if firelens.externalConfigType == ExternalConfigTypeS3 {
-		err = firelens.downloadConfigFromS3()
+		externalConfig, err = firelens.downloadConfigFromS3()
 		if err != nil {
 			err = errors.Wrap(err, "unable to download firelens s3 config file")
 			firelens.setTerminalReason(err.Error())
@@ -417,7 +421,23 @@ func (firelens *FirelensResource) Create() error {
 		}
 	}
 
-	err = firelens.generateConfigFile()
+	config, err := firelens.renderConfig()
+	if err != nil {
+		err = errors.Wrap(err, "unable to generate firelens config")
+		firelens.setTerminalReason(err.Error())
+		return err
+	}
+
+	// Validate the configs before the firelens container starts, so that the task fails with the reason instead of the
+	// firelens container exiting on them.
+	err = firelens.validateConfig(config, externalConfig)
+	if err != nil {
+		err = errors.Wrap(err, "invalid firelens config")
+		firelens.setTerminalReason(err.Error())
+		return err
+	}
+
+	err = firelens.generateConfigFile(config)
 	if err != nil {
 		err = errors.Wrap(err, "unable to generate firelens config file")
 		firelens.setTerminalReason(err.Error())
@@ -454,21 +474,47 @@ func (firelens *FirelensResource) createDirectories() error {
 	return nil
 }
 
-// generateConfigFile generates a firelens config file at $(RESOURCE_DIR)/config/fluent.conf.
-// This contains configs needed by the firelens container.
-func (firelens *FirelensResource) generateConfigFile() error {
+// RenderConfig returns the config that the firelens resource generates for the firelens container, after validating
+// it. The external config file isn't downloaded from S3, so it isn't validated.
+func (firelens *FirelensResource) RenderConfig() ([]byte, error) {
+	config, err := firelens.renderConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate firelens config")
+	}
+
+	err = firelens.validateConfig(config, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid firelens config")
+	}
+	return config, nil
+}
+
+// renderConfig renders the config needed by the firelens container, in the format of its type.
+func (firelens *FirelensResource) renderConfig() ([]byte, error) {
 	config, err := firelens.generateConfig()
 	if err != nil {
-		return errors.Wrap(err, "unable to generate firelens config")
+		return nil, err
 	}
 
-	confFilePath := filepath.Join(firelens.resourceDir, "config", "fluent.conf")
-	err = firelens.writeConfigFile(func(file oswrapper.File) error {
-		if firelens.firelensConfigType == FirelensConfigTypeFluentd {
-			return config.WriteFluentdConfig(file)
-		} else {
-			return config.WriteFluentBitConfig(file)
-		}
+	var buf bytes.Buffer
+	if firelens.firelensConfigType == FirelensConfigTypeFluentd {
+		err = config.WriteFluentdConfig(&buf)
+	} else {
+		err = config.WriteFluentBitConfig(&buf)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// generateConfigFile writes the rendered firelens config file at $(RESOURCE_DIR)/config/fluent.conf.
+// This contains configs needed by the firelens container.
+func (firelens *FirelensResource) generateConfigFile(config []byte) error {
+	confFilePath := filepath.Join(firelens.resourceDir, "config", generatedConfigName)
+	err := firelens.writeConfigFile(func(file oswrapper.File) error {
+		_, err := file.Write(config)
+		return err
 	}, confFilePath)
 	if err != nil {
 		return errors.Wrapf(err, "unable to generate firelens config file")
@@ -479,36 +525,44 @@ func (firelens *FirelensResource) generateConfigFile() error {
 }
 
 // downloadConfigFromS3 downloads an external config file from S3 and saves it at ${RESOURCE_DIR}/config/external.conf.
-// The generated firelens config file fluent.conf will have a reference to include this file.
-func (firelens *FirelensResource) downloadConfigFromS3() error {
+// The generated firelens config file fluent.conf will have a reference to include this file. The content of the
+// downloaded file is returned for validation.
+func (firelens *FirelensResource) downloadConfigFromS3() ([]byte, error) {
 	creds, ok := firelens.credentialsManager.GetTaskCredentials(firelens.executionCredentialsID)
 	if !ok {
-		return errors.New("unable to get execution role credentials")
+		return nil, errors.New("unable to get execution role credentials")
 	}
 
 	bucket, key, err := s3.ParseS3ARN(firelens.externalConfigValue)
 	if err != nil {
-		return errors.Wrap(err, "unable to parse bucket and key from s3 arn")
+		return nil, errors.Wrap(err, "unable to parse bucket and key from s3 arn")
 	}
 
 	s3Client, err := firelens.s3ClientCreator.NewS3ClientForBucket(bucket, firelens.region, creds.GetIAMRoleCredentials())
 	if err != nil {
-		return errors.Wrapf(err, "unable to initialize s3 client for bucket %s", bucket)
+		return nil, errors.Wrapf(err, "unable to initialize s3 client for bucket %s", bucket)
 	}
 
-	confFilePath := filepath.Join(firelens.resourceDir, "config", "external.conf")
+	confFilePath := filepath.Join(firelens.resourceDir, "config", externalConfigName)
 	err = firelens.writeConfigFile(func(file oswrapper.File) error {
 		return s3.DownloadFile(bucket, key, s3DownloadTimeout, file, s3Client)
 	}, confFilePath)
 
 	if err != nil {
-		return errors.Wrapf(err, "unable to download s3 config %s from bucket %s", key, bucket)
+		return nil, errors.Wrapf(err, "unable to download s3 config %s from bucket %s", key, bucket)
 	}
 
 	seelog.Debugf("Downloaded firelens config file from s3 and saved to: %s", confFilePath)
-	return nil
+
+	config, err := readFile(confFilePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read s3 config %s", confFilePath)
+	}
+	return config, nil
 }
 
+var readFile = ioutil.ReadFile
+
 var rename = os.Rename
 
 // writeConfigFile writes a config file at a given path.
@@ -16,6 +16,7 @@ package firelens
 
 import (
 	"io"
+	"io/ioutil"
 	"os"
 	"testing"
 
@@ -90,6 +91,16 @@ func mockRename() func() {
 	}
 }
 
+func mockReadFile(data []byte, err error) func() {
+	readFile = func(filename string) ([]byte, error) {
+		return data, err
+	}
+
+	return func() {
+		readFile = ioutil.ReadFile
+	}
+}
+
 func mockMkdirAllError() func() {
 	mkdirAll = func(path string, perm os.FileMode) error {
 		return errors.New("test error")
@@ -358,6 +369,7 @@ func TestCreateFirelensResourceWithS3Config(t *testing.T) {
 	}
 
 	defer mockRename()()
+	defer mockReadFile([]byte(testExternalFluentdConfig), nil)()
 
 	gomock.InOrder(
 		mockCredentialsManager.EXPECT().GetTaskCredentials(testExecutionCredentialsID).Return(creds, true),
@@ -442,6 +454,83 @@ func TestCreateFirelensResourceWithS3ConfigDownloadFailure(t *testing.T) {
 	assert.NotEmpty(t, firelensResource.terminalReason)
 }
 
+func TestCreateFirelensResourceWithInvalidS3Config(t *testing.T) {
+	mockFile, mockIOUtil, mockCredentialsManager, mockS3ClientCreator, mockS3Client, done := setup(t)
+	defer done()
+
+	firelensResource := newMockFirelensResource(FirelensConfigTypeFluentbit, bridgeNetworkMode, testFluentbitOptions, mockIOUtil,
+		mockCredentialsManager, mockS3ClientCreator)
+
+	err := firelensResource.parseOptions(testFirelensOptionsS3)
+	require.NoError(t, err)
+
+	creds := credentials.TaskIAMRoleCredentials{
+		ARN: "arn",
+		IAMRoleCredentials: credentials.IAMRoleCredentials{
+			AccessKeyID:     "id",
+			SecretAccessKey: "key",
+		},
+	}
+
+	defer mockRename()()
+	defer mockReadFile([]byte("[INPUT]\n    Name tcp\n    Tag firelens-healthcheck\n"), nil)()
+
+	gomock.InOrder(
+		mockCredentialsManager.EXPECT().GetTaskCredentials(testExecutionCredentialsID).Return(creds, true),
+		mockS3ClientCreator.EXPECT().NewS3ClientForBucket("bucket", testRegion, creds.IAMRoleCredentials).Return(mockS3Client, nil),
+		mockIOUtil.EXPECT().TempFile(testResourceDir, tempFile).Return(mockFile, nil),
+		mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), mockFile, gomock.Any()).Return(int64(0), nil),
+	)
+
+	assert.Error(t, firelensResource.Create())
+	assert.Contains(t, firelensResource.terminalReason,
+		"invalid firelens config: external.conf line 1: tag firelens-healthcheck is already set by the input at fluent.conf line")
+}
+
+func TestCreateFirelensResourceWithS3ConfigReadFailure(t *testing.T) {
+	mockFile, mockIOUtil, mockCredentialsManager, mockS3ClientCreator, mockS3Client, done := setup(t)
+	defer done()
+
+	firelensResource := newMockFirelensResource(FirelensConfigTypeFluentd, bridgeNetworkMode, testFluentdOptions, mockIOUtil,
+		mockCredentialsManager, mockS3ClientCreator)
+
+	err := firelensResource.parseOptions(testFirelensOptionsS3)
+	require.NoError(t, err)
+
+	creds := credentials.TaskIAMRoleCredentials{
+		ARN: "arn",
+		IAMRoleCredentials: credentials.IAMRoleCredentials{
+			AccessKeyID:     "id",
+			SecretAccessKey: "key",
+		},
+	}
+
+	defer mockRename()()
+	defer mockReadFile(nil, errors.New("test error"))()
+
+	gomock.InOrder(
+		mockCredentialsManager.EXPECT().GetTaskCredentials(testExecutionCredentialsID).Return(creds, true),
+		mockS3ClientCreator.EXPECT().NewS3ClientForBucket("bucket", testRegion, creds.IAMRoleCredentials).Return(mockS3Client, nil),
+		mockIOUtil.EXPECT().TempFile(testResourceDir, tempFile).Return(mockFile, nil),
+		mockS3Client.EXPECT().DownloadWithContext(gomock.Any(), mockFile, gomock.Any()).Return(int64(0), nil),
+	)
+
+	assert.Error(t, firelensResource.Create())
+	assert.NotEmpty(t, firelensResource.terminalReason)
+}
+
+func TestRenderConfig(t *testing.T) {
+	_, mockIOUtil, mockCredentialsManager, mockS3ClientCreator, _, done := setup(t)
+	defer done()
+
+	firelensResource := newMockFirelensResource(FirelensConfigTypeFluentbit, bridgeNetworkMode, testFluentbitOptions, mockIOUtil,
+		mockCredentialsManager, mockS3ClientCreator)
+
+	config, err := firelensResource.RenderConfig()
+	require.NoError(t, err)
+	assert.Contains(t, string(config), "[OUTPUT]\n    Name kinesis_firehose\n    Match container-firelens*\n")
+}
+
 func TestCleanupFirelensResource(t *testing.T) {
 	_, mockIOUtil, mockCredentialsManager, mockS3ClientCreator, _, done := setup(t)
 	defer done()
@@ -0,0 +1,302 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package firelens
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// generatedConfigName and externalConfigName are the names of the generated config file and of the external
+	// config file downloaded from S3, which validation errors refer to.
+	generatedConfigName = "fluent.conf"
+	externalConfigName  = "external.conf"
+
+	// inputTagOptionFluentd and inputTagOptionFluentbit are the keys of the tag that an input sets on its logs.
+	inputTagOptionFluentd   = "tag"
+	inputTagOptionFluentbit = "Tag"
+	// legacyTypeOptionFluentd is the key that names the plugin of a directive in older fluentd configs.
+	legacyTypeOptionFluentd = "type"
+)
+
+var (
+	// fluentbitSections are the sections that fluentbit configs can have.
+	fluentbitSections = map[string]bool{
+		"SERVICE":          true,
+		"INPUT":            true,
+		"FILTER":           true,
+		"OUTPUT":           true,
+		"PARSER":           true,
+		"MULTILINE_PARSER": true,
+		"PLUGINS":          true,
+		"CUSTOM":           true,
+		"UPSTREAM":         true,
+		"NODE":             true,
+	}
+	// fluentbitPluginSections are the fluentbit sections that must name their plugin.
+	fluentbitPluginSections = map[string]bool{
+		"INPUT":  true,
+		"FILTER": true,
+		"OUTPUT": true,
+		"CUSTOM": true,
+	}
+
+	// fluentdDirectives are the top level directives that fluentd configs can have.
+	fluentdDirectives = map[string]bool{
+		"source": true,
+		"match":  true,
+		"filter": true,
+		"label":  true,
+		"system": true,
+		"worker": true,
+	}
+	// fluentdPluginDirectives are the fluentd directives that must name their plugin, when they're at the top level or
+	// in a label.
+	fluentdPluginDirectives = map[string]bool{
+		"source": true,
+		"match":  true,
+		"filter": true,
+	}
+)
+
+// configError is an error found at a line of a firelens config file.
+type configError struct {
+	file string
+	line int
+	msg  string
+}
+
+func (err configError) Error() string {
+	return fmt.Sprintf("%s line %d: %s", err.file, err.line, err.msg)
+}
+
+func newConfigError(file string, line int, format string, args ...interface{}) error {
+	return configError{file: file, line: line, msg: fmt.Sprintf(format, args...)}
+}
+
+// configInput is an input of a firelens config file that sets a tag on the logs it receives.
+type configInput struct {
+	file string
+	line int
+	tag  string
+}
+
+// validateConfig validates the generated config, along with the external config downloaded from S3 if there's one.
+// The configs must be well formed, every input, filter and output must name its plugin, and no two inputs may set the
+// same tag, as their logs would be mixed up. External config files inside the firelens container can't be validated.
+func (firelens *FirelensResource) validateConfig(generated []byte, external []byte) error {
+	parse := parseFluentbitConfig
+	if firelens.firelensConfigType == FirelensConfigTypeFluentd {
+		parse = parseFluentdConfig
+	}
+
+	inputs, err := parse(generatedConfigName, generated)
+	if err != nil {
+		return err
+	}
+	if external != nil {
+		externalInputs, err := parse(externalConfigName, external)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, externalInputs...)
+	}
+
+	tags := make(map[string]configInput)
+	for _, input := range inputs {
+		if previous, ok := tags[input.tag]; ok {
+			return newConfigError(input.file, input.line, "tag %s is already set by the input at %s line %d",
+				input.tag, previous.file, previous.line)
+		}
+		tags[input.tag] = input
+	}
+	return nil
+}
+
+// parseFluentbitConfig parses a fluentbit config file, and returns the inputs that set a tag.
+func parseFluentbitConfig(file string, data []byte) ([]configInput, error) {
+	var inputs []configInput
+	var section, name, tag string
+	var sectionLine int
+	endSection := func() error {
+		if fluentbitPluginSections[section] && name == "" {
+			return newConfigError(file, sectionLine, "[%s] section has no %s", section, outputTypeLogOptionKeyFluentbit)
+		}
+		if section == "INPUT" && tag != "" {
+			inputs = append(inputs, configInput{file: file, line: sectionLine, tag: tag})
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, newConfigError(file, lineNumber, "section header %s is missing ]", line)
+			}
+			if err := endSection(); err != nil {
+				return nil, err
+			}
+			section = strings.ToUpper(strings.TrimSpace(line[1 : len(line)-1]))
+			if !fluentbitSections[section] {
+				return nil, newConfigError(file, lineNumber, "unknown section %s", line)
+			}
+			sectionLine, name, tag = lineNumber, "", ""
+		case strings.HasPrefix(line, "@"):
+			fields := strings.Fields(line)
+			switch strings.ToUpper(fields[0]) {
+			case "@INCLUDE":
+				if len(fields) < 2 {
+					return nil, newConfigError(file, lineNumber, "%s has no file", fields[0])
+				}
+			case "@SET":
+				if len(fields) < 2 || !strings.Contains(fields[1], "=") {
+					return nil, newConfigError(file, lineNumber, "%s has no key=value", fields[0])
+				}
+			default:
+				return nil, newConfigError(file, lineNumber, "unknown command %s", fields[0])
+			}
+		default:
+			if section == "" {
+				return nil, newConfigError(file, lineNumber, "entry %s is outside of a section", line)
+			}
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				return nil, newConfigError(file, lineNumber, "key %s of the [%s] section has no value", fields[0], section)
+			}
+			switch {
+			case strings.EqualFold(fields[0], outputTypeLogOptionKeyFluentbit):
+				name = fields[1]
+			case strings.EqualFold(fields[0], inputTagOptionFluentbit):
+				tag = fields[1]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read %s", file)
+	}
+	if err := endSection(); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+// fluentdDirective is a directive of a fluentd config file, such as <source>.
+type fluentdDirective struct {
+	name   string
+	line   int
+	plugin bool
+	typed  bool
+	tag    string
+}
+
+// parseFluentdConfig parses a fluentd config file, and returns the inputs that set a tag.
+func parseFluentdConfig(file string, data []byte) ([]configInput, error) {
+	var inputs []configInput
+	var directives []*fluentdDirective
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "</"):
+			if !strings.HasSuffix(line, ">") {
+				return nil, newConfigError(file, lineNumber, "closing tag %s is missing >", line)
+			}
+			name := strings.TrimSpace(line[2 : len(line)-1])
+			if len(directives) == 0 {
+				return nil, newConfigError(file, lineNumber, "</%s> closes no directive", name)
+			}
+			directive := directives[len(directives)-1]
+			if directive.name != name {
+				return nil, newConfigError(file, lineNumber, "</%s> doesn't close <%s> opened at line %d",
+					name, directive.name, directive.line)
+			}
+			directives = directives[:len(directives)-1]
+			if directive.plugin && !directive.typed {
+				return nil, newConfigError(file, directive.line, "<%s> directive has no %s",
+					directive.name, outputTypeLogOptionKeyFluentd)
+			}
+			if directive.name == "source" && directive.tag != "" {
+				inputs = append(inputs, configInput{file: file, line: directive.line, tag: directive.tag})
+			}
+		case strings.HasPrefix(line, "<"):
+			if !strings.HasSuffix(line, ">") {
+				return nil, newConfigError(file, lineNumber, "directive %s is missing >", line)
+			}
+			fields := strings.Fields(line[1 : len(line)-1])
+			if len(fields) == 0 {
+				return nil, newConfigError(file, lineNumber, "directive has no name")
+			}
+			name := fields[0]
+			topLevel := len(directives) == 0
+			if topLevel && !fluentdDirectives[name] {
+				return nil, newConfigError(file, lineNumber, "unknown directive <%s>", name)
+			}
+			if name == "label" && len(fields) < 2 {
+				return nil, newConfigError(file, lineNumber, "<label> directive has no label name")
+			}
+			directives = append(directives, &fluentdDirective{
+				name:   name,
+				line:   lineNumber,
+				plugin: fluentdPluginDirectives[name] && (topLevel || directives[len(directives)-1].name == "label"),
+			})
+		default:
+			fields := strings.Fields(line)
+			if fields[0] == "@include" {
+				if len(fields) < 2 {
+					return nil, newConfigError(file, lineNumber, "@include has no file")
+				}
+				continue
+			}
+			if len(directives) == 0 {
+				return nil, newConfigError(file, lineNumber, "entry %s is outside of a directive", line)
+			}
+			directive := directives[len(directives)-1]
+			switch fields[0] {
+			case outputTypeLogOptionKeyFluentd, legacyTypeOptionFluentd:
+				if len(fields) < 2 {
+					return nil, newConfigError(file, lineNumber, "%s of the <%s> directive has no value",
+						fields[0], directive.name)
+				}
+				directive.typed = true
+			case inputTagOptionFluentd:
+				if len(fields) > 1 {
+					directive.tag = fields[1]
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read %s", file)
+	}
+	if len(directives) > 0 {
+		directive := directives[len(directives)-1]
+		return nil, newConfigError(file, directive.line, "<%s> directive is never closed", directive.name)
+	}
+	return inputs, nil
+}
@@ -0,0 +1,207 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package firelens
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testExternalFluentdConfig = `
+<source>
+    @type tail
+    path /var/log/app.log
+    tag app
+    <parse>
+        @type json
+    </parse>
+</source>
+
+<label @APP>
+    <match app>
+        @type stdout
+    </match>
+</label>
+`
+	testExternalFluentbitConfig = `
+@SET region=us-west-2
+
+[SERVICE]
+    Flush 1
+
+[INPUT]
+    Name tail
+    Tag  app
+    Path /var/log/app.log
+
+[OUTPUT]
+    Name  cloudwatch_logs
+    Match app
+    region ${region}
+`
+)
+
+func TestValidateGeneratedConfigs(t *testing.T) {
+	testCases := []struct {
+		firelensConfigType string
+		expectedConfig     string
+	}{
+		{FirelensConfigTypeFluentd, expectedFluentdBridgeModeConfig},
+		{FirelensConfigTypeFluentd, expectedFluentdDefaultModeConfig},
+		{FirelensConfigTypeFluentd, expectedFluentdConfigWithoutECSMetadata},
+		{FirelensConfigTypeFluentbit, expectedFluentbitConfig},
+		{FirelensConfigTypeFluentbit, expectedFluentbitConfigWithoutOutputSection},
+	}
+
+	for _, tc := range testCases {
+		firelensResource := &FirelensResource{firelensConfigType: tc.firelensConfigType}
+		assert.NoError(t, firelensResource.validateConfig([]byte(tc.expectedConfig), nil))
+	}
+}
+
+func TestValidateExternalConfigs(t *testing.T) {
+	firelensResource := &FirelensResource{firelensConfigType: FirelensConfigTypeFluentd}
+	assert.NoError(t, firelensResource.validateConfig([]byte(expectedFluentdBridgeModeConfig),
+		[]byte(testExternalFluentdConfig)))
+
+	firelensResource = &FirelensResource{firelensConfigType: FirelensConfigTypeFluentbit}
+	assert.NoError(t, firelensResource.validateConfig([]byte(expectedFluentbitConfig),
+		[]byte(testExternalFluentbitConfig)))
+}
+
+func TestValidateInvalidFluentbitConfigs(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name:          "unterminated section header",
+			config:        "[INPUT\n    Name tail\n",
+			expectedError: "external.conf line 1: section header [INPUT is missing ]",
+		},
+		{
+			name:          "unknown section",
+			config:        "[INPUTS]\n    Name tail\n",
+			expectedError: "external.conf line 1: unknown section [INPUTS]",
+		},
+		{
+			name:          "entry outside of a section",
+			config:        "# comment\nName tail\n",
+			expectedError: "external.conf line 2: entry Name tail is outside of a section",
+		},
+		{
+			name:          "key without value",
+			config:        "[OUTPUT]\n    Name null\n    Match\n",
+			expectedError: "external.conf line 3: key Match of the [OUTPUT] section has no value",
+		},
+		{
+			name:          "output without plugin",
+			config:        "[INPUT]\n    Name tail\n\n[OUTPUT]\n    Match *\n",
+			expectedError: "external.conf line 4: [OUTPUT] section has no Name",
+		},
+		{
+			name:          "unknown command",
+			config:        "@INCLUDES other.conf\n",
+			expectedError: "external.conf line 1: unknown command @INCLUDES",
+		},
+		{
+			name:          "set without value",
+			config:        "@SET region\n",
+			expectedError: "external.conf line 1: @SET has no key=value",
+		},
+		{
+			name:          "duplicate tags",
+			config:        "[INPUT]\n    Name tail\n    Tag app\n\n[INPUT]\n    Name tcp\n    Tag app\n",
+			expectedError: "external.conf line 5: tag app is already set by the input at external.conf line 1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			firelensResource := &FirelensResource{firelensConfigType: FirelensConfigTypeFluentbit}
+			err := firelensResource.validateConfig([]byte(expectedFluentbitConfigWithoutOutputSection), []byte(tc.config))
+			require.Error(t, err)
+			assert.Equal(t, tc.expectedError, err.Error())
+		})
+	}
+}
+
+func TestValidateInvalidFluentdConfigs(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name:          "unknown directive",
+			config:        "<sources>\n    @type tail\n</sources>\n",
+			expectedError: "external.conf line 1: unknown directive <sources>",
+		},
+		{
+			name:          "unterminated directive",
+			config:        "<source\n    @type tail\n</source>\n",
+			expectedError: "external.conf line 1: directive <source is missing >",
+		},
+		{
+			name:          "mismatched closing tag",
+			config:        "<source>\n    @type tail\n    <parse>\n        @type json\n</source>\n",
+			expectedError: "external.conf line 5: </source> doesn't close <parse> opened at line 3",
+		},
+		{
+			name:          "closing tag without directive",
+			config:        "</match>\n",
+			expectedError: "external.conf line 1: </match> closes no directive",
+		},
+		{
+			name:          "unclosed directive",
+			config:        "<match app>\n    @type stdout\n",
+			expectedError: "external.conf line 1: <match> directive is never closed",
+		},
+		{
+			name:          "match without plugin",
+			config:        "<label @APP>\n    <match app>\n        flush_interval 1s\n    </match>\n</label>\n",
+			expectedError: "external.conf line 2: <match> directive has no @type",
+		},
+		{
+			name:          "label without name",
+			config:        "<label>\n</label>\n",
+			expectedError: "external.conf line 1: <label> directive has no label name",
+		},
+		{
+			name:          "entry outside of a directive",
+			config:        "@type stdout\n",
+			expectedError: "external.conf line 1: entry @type stdout is outside of a directive",
+		},
+		{
+			name:          "duplicate tags",
+			config:        "<source>\n    @type tail\n    tag app\n</source>\n<source>\n    @type tcp\n    tag app\n</source>\n",
+			expectedError: "external.conf line 5: tag app is already set by the input at external.conf line 1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			firelensResource := &FirelensResource{firelensConfigType: FirelensConfigTypeFluentd}
+			err := firelensResource.validateConfig([]byte(expectedFluentdBridgeModeConfig), []byte(tc.config))
+			require.Error(t, err)
+			assert.Equal(t, tc.expectedError, err.Error())
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package secretfile materialises the values of secrets as files on a tmpfs
+// mount, so that they're never written to disk
+package secretfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// tmpfsSize is the size of the tmpfs mounted to hold the secret files of
+	// a task resource
+	tmpfsSize = "1m"
+	// mountDirMode is the mode of the directories holding the secret files of
+	// a container path directory. They're bind mounted to the containers,
+	// whose users must be able to traverse them to read the files.
+	mountDirMode = 0755
+)
+
+// Options are the mode and owner of the secret files
+type Options struct {
+	Mode os.FileMode `json:"mode"`
+	UID  int         `json:"uid"`
+	GID  int         `json:"gid"`
+}
+
+// Cache holds the values of the secrets of a task resource, keyed by their
+// resource cache key
+type Cache interface {
+	GetCachedSecretValue(secretKey string) (string, bool)
+	SetCachedSecretValue(secretKey string, secretValue string)
+}
+
+// FetchFunc retrieves the current values of secrets from their provider,
+// keyed by their resource cache key
+type FetchFunc func(secrets []apicontainer.Secret) (map[string]string, error)
+
+// Dir returns the directory holding the secret files of a task resource,
+// under the data directory of the agent
+func Dir(dataDir, resourceName, taskARN string) string {
+	fields := strings.Split(taskARN, "/")
+	taskID := fields[len(fields)-1]
+	return filepath.Join(dataDir, resourceName, taskID)
+}
+
+// MountDir returns the directory, relative to the directory of a task
+// resource, holding the secret files whose container path is in the given
+// directory of the containers. It's bind mounted to that directory, rather
+// than the files individually, so that the files can be replaced atomically
+// by renaming new ones over them, which a file bind mount would not follow.
+func MountDir(containerDir string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(containerDir)))
+	return hex.EncodeToString(sum[:])
+}
+
+// FilePath returns the path of the file holding the value of a secret,
+// relative to the directory of a task resource. It's named after the
+// container path of the secret, under the directory bind mounted to the
+// directory of that path.
+func FilePath(secret apicontainer.Secret) string {
+	containerPath := filepath.Clean(secret.ContainerPath)
+	return filepath.Join(MountDir(filepath.Dir(containerPath)), filepath.Base(containerPath))
+}
+
+// Write mounts a tmpfs on the directory, unless it's mounted already, and
+// writes the secret values to it. The values are keyed by file path.
+func Write(dir string, values map[string]string, options Options) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrapf(err, "unable to create secret file directory %s", dir)
+	}
+	if err := mountTmpfs(dir); err != nil {
+		return err
+	}
+	for name, value := range values {
+		if err := writeFile(filepath.Join(dir, name), value, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes the value to a temporary file next to the path, and
+// renames it to the path, so that the file at the path always holds either
+// the previous or the new value
+func writeFile(path, value string, options Options) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, mountDirMode); err != nil {
+		return errors.Wrapf(err, "unable to create secret file directory %s", dir)
+	}
+	// The mode is set explicitly, as the one given when creating the
+	// directory is masked by the umask
+	if err := os.Chmod(dir, mountDirMode); err != nil {
+		return errors.Wrapf(err, "unable to set the mode of secret file directory %s", dir)
+	}
+
+	file, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".")
+	if err != nil {
+		return errors.Wrapf(err, "unable to create secret file %s", path)
+	}
+	tempPath := file.Name()
+	renamed := false
+	defer func() {
+		if !renamed {
+			os.Remove(tempPath)
+		}
+	}()
+	defer file.Close()
+
+	if _, err := file.WriteString(value); err != nil {
+		return errors.Wrapf(err, "unable to write secret file %s", path)
+	}
+	if err := file.Chmod(options.Mode); err != nil {
+		return errors.Wrapf(err, "unable to set the mode of secret file %s", path)
+	}
+	if err := file.Chown(options.UID, options.GID); err != nil {
+		return errors.Wrapf(err, "unable to set the owner of secret file %s", path)
+	}
+	if err := file.Close(); err != nil {
+		return errors.Wrapf(err, "unable to write secret file %s", path)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return errors.Wrapf(err, "unable to replace secret file %s", path)
+	}
+	renamed = true
+	return nil
+}
+
+// Update rewrites the files of the secret values that changed, and returns
+// the paths of those files. The values are keyed by file path, and the files
+// must have been written already. Each file is replaced atomically, the
+// containers reading either the previous or the new value.
+func Update(dir string, values map[string]string, options Options) ([]string, error) {
+	var updated []string
+	for name, value := range values {
+		path := filepath.Join(dir, name)
+		current, err := ioutil.ReadFile(path)
+		if err != nil {
+			return updated, errors.Wrapf(err, "unable to read secret file %s", path)
+		}
+		if string(current) == value {
+			continue
+		}
+		if err := writeFile(path, value, options); err != nil {
+			return updated, err
+		}
+		updated = append(updated, name)
+	}
+	return updated, nil
+}
+
+// Remove unmounts the tmpfs from the directory and removes the directory. It
+// does nothing if the directory is empty, as for resources that don't write
+// secret files.
+func Remove(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	if err := unmountTmpfs(dir); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.Wrapf(err, "unable to remove secret file directory %s", dir)
+	}
+	return nil
+}
+
+// fileSecrets returns the secrets that are vended as files
+func fileSecrets(secrets []apicontainer.Secret) []apicontainer.Secret {
+	var files []apicontainer.Secret
+	for _, secret := range secrets {
+		if secret.Type == apicontainer.SecretTypeMountPoint {
+			files = append(files, secret)
+		}
+	}
+	return files
+}
+
+// WriteSecrets writes the cached values of the secrets vended as files to the
+// directory of a task resource. Nothing is written if none of the secrets is
+// vended as a file.
+func WriteSecrets(dir string, options Options, secrets []apicontainer.Secret, cache Cache) error {
+	values := make(map[string]string)
+	for _, secret := range fileSecrets(secrets) {
+		value, _ := cache.GetCachedSecretValue(secret.GetSecretResourceCacheKey())
+		values[FilePath(secret)] = value
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	if dir == "" {
+		return errors.New("no directory to write secret files to")
+	}
+
+	seelog.Infof("Writing %d secret files to %s", len(values), dir)
+	return Write(dir, values, options)
+}
+
+// RefreshSecrets fetches the values of the secrets vended as files again, and
+// rewrites the files of those whose value changed, caching their new value.
+// The secrets whose value changed are returned, even if rewriting the files
+// of the others failed.
+func RefreshSecrets(dir string, options Options, secrets []apicontainer.Secret, cache Cache,
+	fetch FetchFunc) ([]apicontainer.Secret, error) {
+	files := fileSecrets(secrets)
+	if len(files) == 0 {
+		return nil, nil
+	}
+	values, err := fetch(files)
+	if err != nil {
+		return nil, err
+	}
+
+	fileValues := make(map[string]string)
+	for _, secret := range files {
+		if value, ok := values[secret.GetSecretResourceCacheKey()]; ok {
+			fileValues[FilePath(secret)] = value
+		}
+	}
+	updated, err := Update(dir, fileValues, options)
+
+	// Secrets sharing a file are only returned once
+	updatedNames := make(map[string]struct{})
+	for _, name := range updated {
+		updatedNames[name] = struct{}{}
+	}
+	var changed []apicontainer.Secret
+	for _, secret := range files {
+		if _, ok := updatedNames[FilePath(secret)]; !ok {
+			continue
+		}
+		delete(updatedNames, FilePath(secret))
+		cache.SetCachedSecretValue(secret.GetSecretResourceCacheKey(), values[secret.GetSecretResourceCacheKey()])
+		changed = append(changed, secret)
+	}
+	if len(changed) > 0 {
+		seelog.Infof("Rewrote %d secret files in %s", len(changed), dir)
+	}
+	return changed, err
+}
+
+// OptionsJSON returns the options of the secret files of a task resource to
+// be saved, which are only saved if the resource writes secret files
+func OptionsJSON(dir string, options Options) *Options {
+	if dir == "" {
+		return nil
+	}
+	return &options
+}
@@ -0,0 +1,141 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretfile
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// mountInfoPath lists the mounts of the mount namespace of the agent
+	mountInfoPath = "/proc/self/mountinfo"
+	// sharedPropagationTag is the optional field of the mounts with shared
+	// propagation in mountInfoPath
+	sharedPropagationTag = "shared:"
+)
+
+// mountTmpfs and unmountTmpfs are variables so that they can be stubbed in
+// tests, which can't mount filesystems
+var (
+	mountTmpfs   = mount
+	unmountTmpfs = unmount
+)
+
+// mount mounts a tmpfs on the directory, unless one is mounted already. The
+// tmpfs isn't executable and can only be accessed by root, the directories
+// of the secret files being bind mounted to the containers.
+//
+// Docker binds the directories from the host, so the tmpfs must propagate to
+// the mount namespace of the host, in case the agent runs in a container.
+// Otherwise the containers would get the empty directories under the tmpfs,
+// so an error is returned unless the tmpfs has shared propagation.
+func mount(dir string) error {
+	mounted, err := isMountPoint(dir)
+	if err != nil {
+		return err
+	}
+	if !mounted {
+		if err := unix.Mount("tmpfs", dir, "tmpfs", unix.MS_NOEXEC|unix.MS_NOSUID|unix.MS_NODEV,
+			"size="+tmpfsSize+",mode=0700"); err != nil {
+			return errors.Wrapf(err, "unable to mount tmpfs on %s", dir)
+		}
+	}
+	shared, err := isSharedMount(dir)
+	if err != nil {
+		return err
+	}
+	if !shared {
+		return errors.Errorf("tmpfs mounted on %s doesn't have shared propagation, so it isn't visible to docker: "+
+			"mount the data directory of the agent with shared propagation, such as with the rshared option", dir)
+	}
+	return nil
+}
+
+// isSharedMount returns true if the filesystem mounted on the directory has
+// shared propagation
+func isSharedMount(dir string) (bool, error) {
+	file, err := os.Open(mountInfoPath)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to open %s", mountInfoPath)
+	}
+	defer file.Close()
+	return readSharedMount(file, dir)
+}
+
+// readSharedMount returns true if the last filesystem mounted on the
+// directory in the mountinfo has shared propagation. Each line is formatted as
+// "<id> <parent id> <major:minor> <root> <mount point> <options> [<optional
+// field>...] - <type> <source> <super options>".
+func readSharedMount(mountInfo io.Reader, dir string) (bool, error) {
+	mountPoint := strings.NewReplacer(" ", "\\040", "\t", "\\011", "\n", "\\012", "\\", "\\134").
+		Replace(filepath.Clean(dir))
+	found, shared := false, false
+	scanner := bufio.NewScanner(mountInfo)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 || fields[4] != mountPoint {
+			continue
+		}
+		found, shared = true, false
+		for _, field := range fields[6:] {
+			if field == "-" {
+				break
+			}
+			if strings.HasPrefix(field, sharedPropagationTag) {
+				shared = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, errors.Wrapf(err, "unable to read %s", mountInfoPath)
+	}
+	if !found {
+		return false, errors.Errorf("no filesystem is mounted on %s", dir)
+	}
+	return shared, nil
+}
+
+// unmount unmounts the tmpfs from the directory, if one is mounted
+func unmount(dir string) error {
+	mounted, err := isMountPoint(dir)
+	if err != nil || !mounted {
+		return err
+	}
+	if err := unix.Unmount(dir, 0); err != nil {
+		return errors.Wrapf(err, "unable to unmount tmpfs from %s", dir)
+	}
+	return nil
+}
+
+// isMountPoint returns true if a filesystem is mounted on the directory,
+// which is then on a different device than its parent
+func isMountPoint(dir string) (bool, error) {
+	var stat, parentStat unix.Stat_t
+	if err := unix.Stat(dir, &stat); err != nil {
+		return false, errors.Wrapf(err, "unable to stat %s", dir)
+	}
+	if err := unix.Stat(filepath.Dir(dir), &parentStat); err != nil {
+		return false, errors.Wrapf(err, "unable to stat %s", filepath.Dir(dir))
+	}
+	return stat.Dev != parentStat.Dev, nil
+}
@@ -0,0 +1,304 @@
+// +build linux,unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubTmpfs keeps the tests from mounting filesystems, which they can't do
+// unless they're run as root
+func stubTmpfs() (*[]string, *[]string, func()) {
+	var mounted, unmounted []string
+	mountTmpfs = func(dir string) error {
+		mounted = append(mounted, dir)
+		return nil
+	}
+	unmountTmpfs = func(dir string) error {
+		unmounted = append(unmounted, dir)
+		return nil
+	}
+	return &mounted, &unmounted, func() {
+		mountTmpfs = mount
+		unmountTmpfs = unmount
+	}
+}
+
+// mapCache is a Cache of secret values held in a map
+type mapCache map[string]string
+
+func (cache mapCache) GetCachedSecretValue(secretKey string) (string, bool) {
+	value, ok := cache[secretKey]
+	return value, ok
+}
+
+func (cache mapCache) SetCachedSecretValue(secretKey string, secretValue string) {
+	cache[secretKey] = secretValue
+}
+
+func TestDir(t *testing.T) {
+	assert.Equal(t, "/data/ssmsecret/task-id",
+		Dir("/data/", "ssmsecret", "arn:aws:ecs:us-west-2:123456789012:task/cluster/task-id"))
+}
+
+func TestFilePath(t *testing.T) {
+	secret := apicontainer.Secret{Name: "secret", ValueFrom: "/db/password", ContainerPath: "/run/secrets/db"}
+	sameDirSecret := apicontainer.Secret{Name: "other", ValueFrom: "/db/user", ContainerPath: "/run/secrets/user"}
+	otherDirSecret := apicontainer.Secret{Name: "secret", ValueFrom: "/db/password", ContainerPath: "/etc/app/db"}
+
+	assert.Equal(t, filepath.Join(MountDir("/run/secrets"), "db"), FilePath(secret))
+	assert.Equal(t, filepath.Dir(FilePath(secret)), filepath.Dir(FilePath(sameDirSecret)))
+	assert.NotEqual(t, filepath.Dir(FilePath(secret)), filepath.Dir(FilePath(otherDirSecret)))
+	assert.Equal(t, MountDir("/run/secrets"), MountDir("/run/secrets/"))
+	assert.NotContains(t, MountDir("/run/secrets"), "secrets")
+}
+
+func TestWriteAndRemove(t *testing.T) {
+	mounted, unmounted, restore := stubTmpfs()
+	defer restore()
+	tmpDir, err := ioutil.TempDir("", "secretfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dir := filepath.Join(tmpDir, "ssmsecret", "task-id")
+	options := Options{Mode: 0440, UID: os.Getuid(), GID: os.Getgid()}
+	require.NoError(t, Write(dir, map[string]string{"mount/secret": "value"}, options))
+	assert.Equal(t, []string{dir}, *mounted)
+
+	value, err := ioutil.ReadFile(filepath.Join(dir, "mount", "secret"))
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(value))
+	info, err := os.Stat(filepath.Join(dir, "mount", "secret"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0440), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(dir, "mount"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(mountDirMode), info.Mode().Perm())
+	files, err := ioutil.ReadDir(filepath.Join(dir, "mount"))
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "no temporary file should be left")
+
+	require.NoError(t, Remove(dir))
+	assert.Equal(t, []string{dir}, *unmounted)
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRemoveMissingDir(t *testing.T) {
+	_, unmounted, restore := stubTmpfs()
+	defer restore()
+
+	assert.NoError(t, Remove(filepath.Join(os.TempDir(), "secretfile-missing")))
+	assert.NoError(t, Remove(""), "resources without secret files have no directory")
+	assert.Empty(t, *unmounted)
+}
+
+func TestUnmountNotMounted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secretfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	mounted, err := isMountPoint(dir)
+	require.NoError(t, err)
+	assert.False(t, mounted)
+	assert.NoError(t, unmount(dir))
+}
+
+func TestUpdate(t *testing.T) {
+	_, _, restore := stubTmpfs()
+	defer restore()
+	dir, err := ioutil.TempDir("", "secretfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	options := Options{Mode: 0600, UID: os.Getuid(), GID: os.Getgid()}
+	require.NoError(t, Write(dir, map[string]string{"mount/same": "value", "mount/shorter": "long value",
+		"mount/longer": "value"}, options))
+	sameBefore, err := os.Stat(filepath.Join(dir, "mount", "same"))
+	require.NoError(t, err)
+	before, err := os.Stat(filepath.Join(dir, "mount", "shorter"))
+	require.NoError(t, err)
+	// A reader of the previous file, such as a container, keeps reading the previous value
+	reader, err := os.Open(filepath.Join(dir, "mount", "shorter"))
+	require.NoError(t, err)
+	defer reader.Close()
+
+	updated, err := Update(dir, map[string]string{"mount/same": "value", "mount/shorter": "short",
+		"mount/longer": "longer value"}, options)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"mount/shorter", "mount/longer"}, updated)
+	for name, expected := range map[string]string{"same": "value", "shorter": "short", "longer": "longer value"} {
+		value, err := ioutil.ReadFile(filepath.Join(dir, "mount", name))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(value))
+	}
+	previous, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "long value", string(previous))
+
+	after, err := os.Stat(filepath.Join(dir, "mount", "shorter"))
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(before, after), "secret files must be replaced, not overwritten in place")
+	assert.Equal(t, os.FileMode(0600), after.Mode().Perm())
+	sameAfter, err := os.Stat(filepath.Join(dir, "mount", "same"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(sameBefore, sameAfter), "unchanged secret files must not be replaced")
+	files, err := ioutil.ReadDir(filepath.Join(dir, "mount"))
+	require.NoError(t, err)
+	assert.Len(t, files, 3, "no temporary file should be left")
+}
+
+func TestUpdateMissingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secretfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Update(dir, map[string]string{"missing": "value"}, Options{Mode: 0600})
+	assert.Error(t, err)
+}
+
+func TestWriteSecrets(t *testing.T) {
+	mounted, _, restore := stubTmpfs()
+	defer restore()
+	dir, err := ioutil.TempDir("", "secretfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fileSecret := apicontainer.Secret{Name: "secret", ValueFrom: "/db/password", Region: "us-west-2",
+		Type: apicontainer.SecretTypeMountPoint, ContainerPath: "/run/secrets/db"}
+	envSecret := apicontainer.Secret{Name: "other", ValueFrom: "/db/user", Region: "us-west-2",
+		Type: apicontainer.SecretTypeEnv}
+	cache := mapCache{fileSecret.GetSecretResourceCacheKey(): "value", envSecret.GetSecretResourceCacheKey(): "user"}
+	options := Options{Mode: 0400, UID: os.Getuid(), GID: os.Getgid()}
+
+	// Nothing is written without secrets vended as files, even without a directory
+	require.NoError(t, WriteSecrets("", options, []apicontainer.Secret{envSecret}, cache))
+	assert.Empty(t, *mounted)
+	assert.Error(t, WriteSecrets("", options, []apicontainer.Secret{fileSecret, envSecret}, cache))
+
+	require.NoError(t, WriteSecrets(dir, options, []apicontainer.Secret{fileSecret, envSecret}, cache))
+	assert.Equal(t, []string{dir}, *mounted)
+	value, err := ioutil.ReadFile(filepath.Join(dir, FilePath(fileSecret)))
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(value))
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "only the secrets vended as files are written")
+}
+
+func TestRefreshSecrets(t *testing.T) {
+	_, _, restore := stubTmpfs()
+	defer restore()
+	dir, err := ioutil.TempDir("", "secretfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	rotatedSecret := apicontainer.Secret{Name: "rotated", ValueFrom: "/db/password", Region: "us-west-2",
+		Type: apicontainer.SecretTypeMountPoint, ContainerPath: "/run/secrets/password"}
+	sameSecret := apicontainer.Secret{Name: "same", ValueFrom: "/db/user", Region: "us-west-2",
+		Type: apicontainer.SecretTypeMountPoint, ContainerPath: "/run/secrets/user"}
+	envSecret := apicontainer.Secret{Name: "env", ValueFrom: "/db/host", Region: "us-west-2",
+		Type: apicontainer.SecretTypeEnv}
+	secrets := []apicontainer.Secret{rotatedSecret, sameSecret, envSecret}
+	cache := mapCache{
+		rotatedSecret.GetSecretResourceCacheKey(): "value",
+		sameSecret.GetSecretResourceCacheKey():    "user",
+		envSecret.GetSecretResourceCacheKey():     "host",
+	}
+	options := Options{Mode: 0400, UID: os.Getuid(), GID: os.Getgid()}
+	require.NoError(t, WriteSecrets(dir, options, secrets, cache))
+
+	fetch := func(fetched []apicontainer.Secret) (map[string]string, error) {
+		assert.Equal(t, []apicontainer.Secret{rotatedSecret, sameSecret}, fetched,
+			"only the secrets vended as files are fetched")
+		return map[string]string{
+			rotatedSecret.GetSecretResourceCacheKey(): "rotated-value",
+			sameSecret.GetSecretResourceCacheKey():    "user",
+		}, nil
+	}
+	changed, err := RefreshSecrets(dir, options, secrets, cache, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, []apicontainer.Secret{rotatedSecret}, changed)
+	value, err := ioutil.ReadFile(filepath.Join(dir, FilePath(rotatedSecret)))
+	require.NoError(t, err)
+	assert.Equal(t, "rotated-value", string(value))
+	assert.Equal(t, "rotated-value", cache[rotatedSecret.GetSecretResourceCacheKey()])
+
+	changed, err = RefreshSecrets(dir, options, secrets, cache, fetch)
+	require.NoError(t, err)
+	assert.Empty(t, changed, "unchanged secret files must not be rewritten")
+
+	// The files are left as they are if the values can't be fetched
+	changed, err = RefreshSecrets(dir, options, secrets, cache, func([]apicontainer.Secret) (map[string]string, error) {
+		return nil, errors.New("access denied")
+	})
+	assert.Error(t, err)
+	assert.Empty(t, changed)
+}
+
+func TestRefreshSecretsWithoutFileSecrets(t *testing.T) {
+	envSecret := apicontainer.Secret{Name: "env", ValueFrom: "/db/host", Type: apicontainer.SecretTypeEnv}
+	changed, err := RefreshSecrets("", Options{}, []apicontainer.Secret{envSecret}, mapCache{},
+		func([]apicontainer.Secret) (map[string]string, error) {
+			t.Error("no secret should be fetched without secrets vended as files")
+			return nil, nil
+		})
+	assert.NoError(t, err)
+	assert.Empty(t, changed)
+}
+
+func TestOptionsJSON(t *testing.T) {
+	options := Options{Mode: 0400, UID: 1000, GID: 1000}
+	assert.Nil(t, OptionsJSON("", options), "options are only saved for resources writing secret files")
+	assert.Equal(t, &options, OptionsJSON("/data/ssmsecret/task-id", options))
+}
+
+func TestReadSharedMount(t *testing.T) {
+	mountInfo := `22 1 259:1 / / rw,noatime shared:1 - ext4 /dev/root rw
+130 22 259:1 /var/lib/ecs/data /data rw,noatime master:1 - ext4 /dev/root rw
+140 130 0:50 / /data/ssmsecret/task-id rw,nosuid,nodev,noexec shared:71 master:1 - tmpfs tmpfs rw,size=1024k,mode=700
+141 130 0:51 / /data/asmsecret/task-id rw,nosuid,nodev,noexec - tmpfs tmpfs rw,size=1024k,mode=700
+142 130 0:52 / /data/vaultsecret/task\040id rw,nosuid,nodev,noexec shared:72 - tmpfs tmpfs rw,size=1024k,mode=700
+`
+	for _, tc := range []struct {
+		dir    string
+		shared bool
+	}{
+		{"/data/ssmsecret/task-id", true},
+		{"/data/ssmsecret/task-id/", true},
+		{"/data/asmsecret/task-id", false},
+		{"/data/vaultsecret/task id", true},
+		{"/data", false},
+	} {
+		t.Run(tc.dir, func(t *testing.T) {
+			shared, err := readSharedMount(strings.NewReader(mountInfo), tc.dir)
+			require.NoError(t, err)
+			assert.Equal(t, tc.shared, shared)
+		})
+	}
+
+	_, err := readSharedMount(strings.NewReader(mountInfo), "/data/ssmsecret/other-task-id")
+	assert.Error(t, err, "expected an error for a directory without a mount")
+}
@@ -0,0 +1,35 @@
+// +build !linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretfile
+
+import (
+	"github.com/pkg/errors"
+)
+
+var (
+	mountTmpfs   = mount
+	unmountTmpfs = unmount
+)
+
+// mount is not supported on this platform
+func mount(dir string) error {
+	return errors.Errorf("unable to mount tmpfs on %s: unsupported platform", dir)
+}
+
+// unmount does nothing on this platform, as no tmpfs can be mounted
+func unmount(dir string) error {
+	return nil
+}
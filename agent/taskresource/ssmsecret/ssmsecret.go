@@ -29,6 +29,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/ssm"
 	"github.com/aws/amazon-ecs-agent/agent/ssm/factory"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfile"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 )
 
@@ -60,6 +61,11 @@ type SSMSecretResource struct {
 	requiredSecrets map[string][]apicontainer.Secret
 	// map to store secret values, key is a combination of valueFrom and region
 	secretData map[string]string
+	// secretFileDir is the directory the tmpfs holding the values of the
+	// secrets vended as files is mounted on, empty if there are none
+	secretFileDir string
+	// secretFileOptions are the mode and owner of the secret files
+	secretFileOptions secretfile.Options
 
 	// ssmClientCreator is a factory interface that creates new SSM clients. This is
 	// needed mostly for testing.
@@ -79,7 +85,9 @@ func NewSSMSecretResource(taskARN string,
 	ssmSecrets map[string][]apicontainer.Secret,
 	executionCredentialsID string,
 	credentialsManager credentials.Manager,
-	ssmClientCreator factory.SSMClientCreator) *SSMSecretResource {
+	ssmClientCreator factory.SSMClientCreator,
+	secretFileDir string,
+	secretFileOptions secretfile.Options) *SSMSecretResource {
 
 	s := &SSMSecretResource{
 		taskARN:                taskARN,
@@ -87,6 +95,8 @@ func NewSSMSecretResource(taskARN string,
 		credentialsManager:     credentialsManager,
 		executionCredentialsID: executionCredentialsID,
 		ssmClientCreator:       ssmClientCreator,
+		secretFileDir:          secretFileDir,
+		secretFileOptions:      secretFileOptions,
 	}
 
 	s.initStatusToTransition()
@@ -284,8 +294,63 @@ func (secret *SSMSecretResource) Create() error {
 		secret.setTerminalReason(err.Error())
 		return err
 	default:
-		return nil
 	}
+
+	if err := secretfile.WriteSecrets(secret.secretFileDir, secret.secretFileOptions, secret.getSecrets(), secret); err != nil {
+		err = errors.Wrap(err, "ssm secret resource: unable to write secret files")
+		secret.setTerminalReason(err.Error())
+		return err
+	}
+	return nil
+}
+
+// RefreshSecretFiles retrieves the values of the secrets vended as files from SSM again, and
+// rewrites the files of those whose value changed. The secrets whose value changed are returned.
+func (secret *SSMSecretResource) RefreshSecretFiles() ([]apicontainer.Secret, error) {
+	changed, err := secretfile.RefreshSecrets(secret.secretFileDir, secret.secretFileOptions,
+		secret.getSecrets(), secret, secret.fetchSecretValues)
+	if err != nil {
+		return changed, errors.Wrap(err, "ssm secret resource: unable to refresh secret files")
+	}
+	return changed, nil
+}
+
+// fetchSecretValues retrieves the values of the secrets from SSM in batches, keyed by their
+// resource cache key
+func (secret *SSMSecretResource) fetchSecretValues(secrets []apicontainer.Secret) (map[string]string, error) {
+	executionCredentials, ok := secret.credentialsManager.GetTaskCredentials(secret.getExecutionCredentialsID())
+	if !ok {
+		return nil, errors.New("unable to find execution role credentials")
+	}
+	iamCredentials := executionCredentials.GetIAMRoleCredentials()
+
+	regionNames := make(map[string][]string)
+	seen := make(map[string]struct{})
+	for _, s := range secrets {
+		if _, ok := seen[s.GetSecretResourceCacheKey()]; !ok {
+			seen[s.GetSecretResourceCacheKey()] = struct{}{}
+			regionNames[s.Region] = append(regionNames[s.Region], s.ValueFrom)
+		}
+	}
+
+	values := make(map[string]string)
+	for region, names := range regionNames {
+		ssmClient := secret.ssmClientCreator.NewSSMClient(region, iamCredentials)
+		for start := 0; start < len(names); start += MaxBatchNum {
+			end := start + MaxBatchNum
+			if end > len(names) {
+				end = len(names)
+			}
+			secValueMap, err := ssm.GetSecretsFromSSM(names[start:end], ssmClient)
+			if err != nil {
+				return nil, fmt.Errorf("fetching secret data from SSM Parameter Store in %s: %v", region, err)
+			}
+			for secretName, secretValue := range secValueMap {
+				values[secretName+"_"+region] = secretValue
+			}
+		}
+	}
+	return values, nil
 }
 
 // getGoRoutineMaxNum calculates the maximum number of goroutines that we need to spin up
@@ -360,6 +425,15 @@ func (secret *SSMSecretResource) getRequiredSecrets() map[string][]apicontainer.
 	return secret.requiredSecrets
 }
 
+// getSecrets returns the secrets of every region
+func (secret *SSMSecretResource) getSecrets() []apicontainer.Secret {
+	var secrets []apicontainer.Secret
+	for _, regionSecrets := range secret.getRequiredSecrets() {
+		secrets = append(secrets, regionSecrets...)
+	}
+	return secrets
+}
+
 // getExecutionCredentialsID returns the execution role's credential ID
 func (secret *SSMSecretResource) getExecutionCredentialsID() string {
 	secret.lock.RLock()
@@ -368,9 +442,13 @@ func (secret *SSMSecretResource) getExecutionCredentialsID() string {
 	return secret.executionCredentialsID
 }
 
-// Cleanup removes the secret value created for the task
+// Cleanup removes the secret value created for the task, along with the
+// secret files
 func (secret *SSMSecretResource) Cleanup() error {
 	secret.clearSSMSecretValue()
+	if err := secretfile.Remove(secret.secretFileDir); err != nil {
+		return errors.Wrapf(err, "ssm secret resource: unable to remove secret files of task [%s]", secret.taskARN)
+	}
 	return nil
 }
 
@@ -429,6 +507,8 @@ type SSMSecretResourceJSON struct {
 	KnownStatus            *SSMSecretStatus                 `json:"knownStatus"`
 	RequiredSecrets        map[string][]apicontainer.Secret `json:"secretResources"`
 	ExecutionCredentialsID string                           `json:"executionCredentialsID"`
+	SecretFileDir          string                           `json:"secretFileDir,omitempty"`
+	SecretFileOptions      *secretfile.Options              `json:"secretFileOptions,omitempty"`
 }
 
 // MarshalJSON serialises the SSMSecretResource struct to JSON
@@ -452,6 +532,8 @@ func (secret *SSMSecretResource) MarshalJSON() ([]byte, error) {
 		}(),
 		RequiredSecrets:        secret.getRequiredSecrets(),
 		ExecutionCredentialsID: secret.getExecutionCredentialsID(),
+		SecretFileDir:          secret.secretFileDir,
+		SecretFileOptions:      secretfile.OptionsJSON(secret.secretFileDir, secret.secretFileOptions),
 	})
 }
 
@@ -477,6 +559,10 @@ func (secret *SSMSecretResource) UnmarshalJSON(b []byte) error {
 	}
 	secret.taskARN = temp.TaskARN
 	secret.executionCredentialsID = temp.ExecutionCredentialsID
+	secret.secretFileDir = temp.SecretFileDir
+	if temp.SecretFileOptions != nil {
+		secret.secretFileOptions = *temp.SecretFileOptions
+	}
 
 	return nil
 }
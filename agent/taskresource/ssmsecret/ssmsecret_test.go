@@ -17,6 +17,9 @@ package ssmsecret
 
 import (
 	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
@@ -28,6 +31,7 @@ import (
 	mock_factory "github.com/aws/amazon-ecs-agent/agent/ssm/factory/mocks"
 	mock_ssm "github.com/aws/amazon-ecs-agent/agent/ssm/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfile"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -468,10 +472,14 @@ func TestMarshalUnmarshalJSON(t *testing.T) {
 		knownStatusUnsafe:      resourcestatus.ResourceCreated,
 		desiredStatusUnsafe:    resourcestatus.ResourceCreated,
 		requiredSecrets:        requiredSecretData,
+		secretData:             map[string]string{secretKeyWest1: secretValue},
+		secretFileDir:          "/data/ssmsecret/task1",
+		secretFileOptions:      secretfile.Options{Mode: 0440, UID: 1000, GID: 1000},
 	}
 
 	bytes, err := json.Marshal(ssmResIn)
 	require.NoError(t, err)
+	assert.NotContains(t, string(bytes), secretValue, "secret values must not be saved in the state file")
 
 	ssmResOut := &SSMSecretResource{}
 	err = json.Unmarshal(bytes, ssmResOut)
@@ -483,6 +491,8 @@ func TestMarshalUnmarshalJSON(t *testing.T) {
 	assert.Equal(t, ssmResIn.executionCredentialsID, ssmResOut.executionCredentialsID)
 	assert.Equal(t, len(ssmResIn.requiredSecrets), len(ssmResOut.requiredSecrets))
 	assert.Equal(t, ssmResIn.requiredSecrets[region1], ssmResOut.requiredSecrets[region1])
+	assert.Equal(t, ssmResIn.secretFileDir, ssmResOut.secretFileDir)
+	assert.Equal(t, ssmResIn.secretFileOptions, ssmResOut.secretFileOptions)
 }
 
 func TestInitialize(t *testing.T) {
@@ -518,3 +528,64 @@ func TestClearSSMSecretValue(t *testing.T) {
 	ssmRes.clearSSMSecretValue()
 	assert.Equal(t, 0, len(ssmRes.secretData))
 }
+
+func TestRefreshSecretFiles(t *testing.T) {
+	secretFileDir, err := ioutil.TempDir("", "ssmsecret")
+	require.NoError(t, err)
+	defer os.RemoveAll(secretFileDir)
+
+	rotatedSecret := apicontainer.Secret{Name: secretName1, ValueFrom: valueFrom1, Region: region1,
+		Provider: "ssm", Type: apicontainer.SecretTypeMountPoint, ContainerPath: "/run/secrets/secret1"}
+	sameSecret := apicontainer.Secret{Name: secretName2, ValueFrom: valueFrom2, Region: region1,
+		Provider: "ssm", Type: apicontainer.SecretTypeMountPoint, ContainerPath: "/run/secrets/secret2"}
+	envSecret := apicontainer.Secret{Name: secretName1, ValueFrom: valueFromARN, Region: region1,
+		Provider: "ssm", Type: apicontainer.SecretTypeEnv}
+	require.NoError(t, os.MkdirAll(filepath.Join(secretFileDir, secretfile.MountDir("/run/secrets")), 0700))
+	for _, s := range []apicontainer.Secret{rotatedSecret, sameSecret} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(secretFileDir, secretfile.FilePath(s)), []byte(secretValue), 0600))
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	ssmClientCreator := mock_factory.NewMockSSMClientCreator(ctrl)
+	mockSSMClient := mock_ssm.NewMockSSMClient(ctrl)
+
+	iamRoleCreds := credentials.IAMRoleCredentials{}
+	creds := credentials.TaskIAMRoleCredentials{
+		IAMRoleCredentials: iamRoleCreds,
+	}
+	ssmOutput := &ssm.GetParametersOutput{
+		InvalidParameters: []*string{},
+		Parameters: []*ssm.Parameter{
+			{Name: aws.String(valueFrom1), Value: aws.String("rotated-value")},
+			{Name: aws.String(valueFrom2), Value: aws.String(secretValue)},
+		},
+	}
+
+	credentialsManager.EXPECT().GetTaskCredentials(executionCredentialsID).Return(creds, true)
+	ssmClientCreator.EXPECT().NewSSMClient(region1, iamRoleCreds).Return(mockSSMClient)
+	mockSSMClient.EXPECT().GetParameters(gomock.Any()).Do(func(in *ssm.GetParametersInput) {
+		assert.Equal(t, []*string{aws.String(valueFrom1), aws.String(valueFrom2)}, in.Names,
+			"only the secrets vended as files are refreshed")
+	}).Return(ssmOutput, nil)
+
+	ssmRes := &SSMSecretResource{
+		executionCredentialsID: executionCredentialsID,
+		requiredSecrets:        map[string][]apicontainer.Secret{region1: {rotatedSecret, sameSecret, envSecret}},
+		credentialsManager:     credentialsManager,
+		ssmClientCreator:       ssmClientCreator,
+		secretFileDir:          secretFileDir,
+	}
+	changed, err := ssmRes.RefreshSecretFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []apicontainer.Secret{rotatedSecret}, changed)
+
+	value, err := ioutil.ReadFile(filepath.Join(secretFileDir, secretfile.FilePath(rotatedSecret)))
+	require.NoError(t, err)
+	assert.Equal(t, "rotated-value", string(value))
+	cachedValue, ok := ssmRes.GetCachedSecretValue(secretKeyWest1)
+	require.True(t, ok)
+	assert.Equal(t, "rotated-value", cachedValue)
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/envFiles"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/firelens"
 	ssmsecretres "github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
+	vaultsecretres "github.com/aws/amazon-ecs-agent/agent/taskresource/vaultsecret"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
 )
 
@@ -39,6 +40,8 @@ const (
 	SSMSecretKey = ssmsecretres.ResourceName
 	// ASMSecretKey is the string used in resources map to represent asm secret
 	ASMSecretKey = asmsecretres.ResourceName
+	// VaultSecretKey is the string used in resources map to represent vault secret
+	VaultSecretKey = vaultsecretres.ResourceName
 	// FirelensKey is the string used in resources map to represent firelens resource
 	FirelensKey = firelens.ResourceName
 	// CredentialSpecKey is the string used in resources map to represent credentialspec resource
@@ -80,6 +83,8 @@ func unmarshalResource(key string, value json.RawMessage, result map[string][]ta
 		return unmarshalSSMSecretKey(key, value, result)
 	case ASMSecretKey:
 		return unmarshalASMSecretKey(key, value, result)
+	case VaultSecretKey:
+		return unmarshalVaultSecretKey(key, value, result)
 	case FirelensKey:
 		return unmarshalFirelensKey(key, value, result)
 	case CredentialSpecKey:
@@ -179,6 +184,24 @@ func unmarshalASMSecretKey(key string, value json.RawMessage, result map[string]
 	return nil
 }
 
+func unmarshalVaultSecretKey(key string, value json.RawMessage, result map[string][]taskresource.TaskResource) error {
+	var vaultsecrets []json.RawMessage
+	err := json.Unmarshal(value, &vaultsecrets)
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range vaultsecrets {
+		res := &vaultsecretres.VaultSecretResource{}
+		err := res.UnmarshalJSON(secret)
+		if err != nil {
+			return err
+		}
+		result[key] = append(result[key], res)
+	}
+	return nil
+}
+
 func unmarshalFirelensKey(key string, value json.RawMessage, result map[string][]taskresource.TaskResource) error {
 	var firelensResources []json.RawMessage
 	err := json.Unmarshal(value, &firelensResources)
@@ -23,6 +23,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/asmsecret"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/ssmsecret"
 	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/vaultsecret"
 	"github.com/aws/amazon-ecs-agent/agent/taskresource/volume"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -106,3 +107,24 @@ func TestMarshalUnmarshalASMSecretResource(t *testing.T) {
 	assert.Equal(t, unMarshalledASMSecret[0].GetDesiredStatus(), resourcestatus.ResourceCreated)
 	assert.Equal(t, unMarshalledASMSecret[0].GetKnownStatus(), resourcestatus.ResourceStatusNone)
 }
+
+func TestMarshalUnmarshalVaultSecretResource(t *testing.T) {
+	resources := make(map[string][]taskresource.TaskResource)
+	vaultSecrets := []taskresource.TaskResource{
+		&vaultsecret.VaultSecretResource{},
+	}
+	vaultSecrets[0].SetDesiredStatus(resourcestatus.ResourceCreated)
+	vaultSecrets[0].SetKnownStatus(resourcestatus.ResourceStatusNone)
+
+	resources["vaultsecret"] = vaultSecrets
+	data, err := json.Marshal(resources)
+	require.NoError(t, err)
+
+	var unMarshalledResource ResourcesMap
+	err = json.Unmarshal(data, &unMarshalledResource)
+	assert.NoError(t, err)
+	unMarshalledVaultSecret, ok := unMarshalledResource["vaultsecret"]
+	assert.True(t, ok)
+	assert.Equal(t, unMarshalledVaultSecret[0].GetDesiredStatus(), resourcestatus.ResourceCreated)
+	assert.Equal(t, unMarshalledVaultSecret[0].GetKnownStatus(), resourcestatus.ResourceStatusNone)
+}
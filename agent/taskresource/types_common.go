@@ -18,6 +18,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	ssmfactory "github.com/aws/amazon-ecs-agent/agent/ssm/factory"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ioutilwrapper"
+	"github.com/aws/amazon-ecs-agent/agent/vault"
 )
 
 type ResourceFieldsCommon struct {
@@ -26,4 +27,11 @@ type ResourceFieldsCommon struct {
 	SSMClientCreator   ssmfactory.SSMClientCreator
 	CredentialsManager credentials.Manager
 	EC2InstanceID      string
+	// VaultClient reads the secrets of the vault provider. It's nil if no
+	// Vault server is configured.
+	VaultClient vault.Client
+	// EnvironmentFileAllowedDirs are the host directories that environment
+	// files can be read from. They're taken from the config rather than the
+	// saved state, so that narrowing them also applies to restored tasks.
+	EnvironmentFileAllowedDirs []string
 }
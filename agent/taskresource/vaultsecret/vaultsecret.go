@@ -0,0 +1,495 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package vaultsecret
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apicontainerstatus "github.com/aws/amazon-ecs-agent/agent/api/container/status"
+	"github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfile"
+	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+	"github.com/aws/amazon-ecs-agent/agent/vault"
+)
+
+const (
+	// ResourceName is the name of the vaultsecret resource
+	ResourceName = "vaultsecret"
+)
+
+// VaultSecretResource represents secrets as a task resource.
+// The secrets are stored in HashiCorp Vault.
+type VaultSecretResource struct {
+	taskARN             string
+	createdAt           time.Time
+	desiredStatusUnsafe resourcestatus.ResourceStatus
+	knownStatusUnsafe   resourcestatus.ResourceStatus
+	// appliedStatus is the status that has been "applied" (e.g., we've called some
+	// operation such as 'Create' on the resource) but we don't yet know that the
+	// application was successful, which may then change the known status. This is
+	// used while progressing resource states in progressTask() of task manager
+	appliedStatus                      resourcestatus.ResourceStatus
+	resourceStatusToTransitionFunction map[resourcestatus.ResourceStatus]func() error
+
+	// map to store all vault deduped secrets in the task, key is a combination of valueFrom and region
+	requiredSecrets map[string]apicontainer.Secret
+	// map to store secret values, key is a combination of valueFrom and region
+	secretData map[string]string
+	// secretFileDir is the directory the tmpfs holding the values of the
+	// secrets vended as files is mounted on, empty if there are none
+	secretFileDir string
+	// secretFileOptions are the mode and owner of the secret files
+	secretFileOptions secretfile.Options
+
+	// vaultClient reads the secrets from Vault. It's nil if the Agent isn't
+	// configured with a Vault server.
+	vaultClient vault.Client
+
+	// terminalReason should be set for resource creation failures. This ensures
+	// the resource object carries some context for why provisioning failed.
+	terminalReason     string
+	terminalReasonOnce sync.Once
+
+	// lock is used for fields that are accessed and updated concurrently
+	lock sync.RWMutex
+}
+
+// NewVaultSecretResource creates a new VaultSecretResource object
+func NewVaultSecretResource(taskARN string,
+	vaultSecrets map[string]apicontainer.Secret,
+	vaultClient vault.Client,
+	secretFileDir string,
+	secretFileOptions secretfile.Options) *VaultSecretResource {
+
+	s := &VaultSecretResource{
+		taskARN:           taskARN,
+		requiredSecrets:   vaultSecrets,
+		vaultClient:       vaultClient,
+		secretFileDir:     secretFileDir,
+		secretFileOptions: secretFileOptions,
+	}
+
+	s.initStatusToTransition()
+	return s
+}
+
+func (secret *VaultSecretResource) initStatusToTransition() {
+	resourceStatusToTransitionFunction := map[resourcestatus.ResourceStatus]func() error{
+		resourcestatus.ResourceStatus(VaultSecretCreated): secret.Create,
+	}
+	secret.resourceStatusToTransitionFunction = resourceStatusToTransitionFunction
+}
+
+func (secret *VaultSecretResource) setTerminalReason(reason string) {
+	secret.terminalReasonOnce.Do(func() {
+		seelog.Infof("Vault secret resource: setting terminal reason for vault secret resource in task: [%s]", secret.taskARN)
+		secret.terminalReason = reason
+	})
+}
+
+// GetTerminalReason returns an error string to propagate up through to task
+// state change messages
+func (secret *VaultSecretResource) GetTerminalReason() string {
+	return secret.terminalReason
+}
+
+// SetDesiredStatus safely sets the desired status of the resource
+func (secret *VaultSecretResource) SetDesiredStatus(status resourcestatus.ResourceStatus) {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	secret.desiredStatusUnsafe = status
+}
+
+// GetDesiredStatus safely returns the desired status of the task
+func (secret *VaultSecretResource) GetDesiredStatus() resourcestatus.ResourceStatus {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.desiredStatusUnsafe
+}
+
+// GetName safely returns the name of the resource
+func (secret *VaultSecretResource) GetName() string {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return ResourceName
+}
+
+// DesiredTerminal returns true if the secret's desired status is REMOVED
+func (secret *VaultSecretResource) DesiredTerminal() bool {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.desiredStatusUnsafe == resourcestatus.ResourceStatus(VaultSecretRemoved)
+}
+
+// KnownCreated returns true if the secret's known status is CREATED
+func (secret *VaultSecretResource) KnownCreated() bool {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.knownStatusUnsafe == resourcestatus.ResourceStatus(VaultSecretCreated)
+}
+
+// TerminalStatus returns the last transition state of vaultsecret
+func (secret *VaultSecretResource) TerminalStatus() resourcestatus.ResourceStatus {
+	return resourcestatus.ResourceStatus(VaultSecretRemoved)
+}
+
+// NextKnownState returns the state that the resource should
+// progress to based on its `KnownState`.
+func (secret *VaultSecretResource) NextKnownState() resourcestatus.ResourceStatus {
+	return secret.GetKnownStatus() + 1
+}
+
+// ApplyTransition calls the function required to move to the specified status
+func (secret *VaultSecretResource) ApplyTransition(nextState resourcestatus.ResourceStatus) error {
+	transitionFunc, ok := secret.resourceStatusToTransitionFunction[nextState]
+	if !ok {
+		return errors.Errorf("resource [%s]: transition to %s impossible", secret.GetName(),
+			secret.StatusString(nextState))
+	}
+	return transitionFunc()
+}
+
+// SteadyState returns the transition state of the resource defined as "ready"
+func (secret *VaultSecretResource) SteadyState() resourcestatus.ResourceStatus {
+	return resourcestatus.ResourceStatus(VaultSecretCreated)
+}
+
+// SetKnownStatus safely sets the currently known status of the resource
+func (secret *VaultSecretResource) SetKnownStatus(status resourcestatus.ResourceStatus) {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	secret.knownStatusUnsafe = status
+	secret.updateAppliedStatusUnsafe(status)
+}
+
+// updateAppliedStatusUnsafe updates the resource transitioning status
+func (secret *VaultSecretResource) updateAppliedStatusUnsafe(knownStatus resourcestatus.ResourceStatus) {
+	if secret.appliedStatus == resourcestatus.ResourceStatus(VaultSecretStatusNone) {
+		return
+	}
+
+	// Check if the resource transition has already finished
+	if secret.appliedStatus <= knownStatus {
+		secret.appliedStatus = resourcestatus.ResourceStatus(VaultSecretStatusNone)
+	}
+}
+
+// SetAppliedStatus sets the applied status of resource and returns whether
+// the resource is already in a transition
+func (secret *VaultSecretResource) SetAppliedStatus(status resourcestatus.ResourceStatus) bool {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	if secret.appliedStatus != resourcestatus.ResourceStatus(VaultSecretStatusNone) {
+		// return false to indicate the set operation failed
+		return false
+	}
+
+	secret.appliedStatus = status
+	return true
+}
+
+// GetKnownStatus safely returns the currently known status of the task
+func (secret *VaultSecretResource) GetKnownStatus() resourcestatus.ResourceStatus {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.knownStatusUnsafe
+}
+
+// StatusString returns the string of the vaultsecret resource status
+func (secret *VaultSecretResource) StatusString(status resourcestatus.ResourceStatus) string {
+	return VaultSecretStatus(status).String()
+}
+
+// SetCreatedAt sets the timestamp for resource's creation time
+func (secret *VaultSecretResource) SetCreatedAt(createdAt time.Time) {
+	if createdAt.IsZero() {
+		return
+	}
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	secret.createdAt = createdAt
+}
+
+// GetCreatedAt sets the timestamp for resource's creation time
+func (secret *VaultSecretResource) GetCreatedAt() time.Time {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.createdAt
+}
+
+// Create reads the secret values from Vault. It spins up multiple goroutines in order to
+// retrieve values in parallel.
+func (secret *VaultSecretResource) Create() error {
+	// To fail fast, check the Vault server is configured first
+	if secret.vaultClient == nil {
+		err := errors.New("Vault secret resource: no Vault server configured, ECS_VAULT_ADDR is not set")
+		secret.setTerminalReason(err.Error())
+		return err
+	}
+
+	var wg sync.WaitGroup
+
+	// Get the maximum number of errors to be returned, which will be one error per goroutine
+	errorEvents := make(chan error, len(secret.requiredSecrets))
+
+	seelog.Infof("Vault secret resource: retrieving secrets for containers in task: [%s]", secret.taskARN)
+	secret.secretData = make(map[string]string)
+
+	for _, vaultSecret := range secret.getRequiredSecrets() {
+		wg.Add(1)
+		// Spin up goroutine per secret to speed up processing time
+		go secret.retrieveVaultSecretValue(vaultSecret, &wg, errorEvents)
+	}
+
+	wg.Wait()
+	close(errorEvents)
+
+	if len(errorEvents) > 0 {
+		var terminalReasons []string
+		for err := range errorEvents {
+			terminalReasons = append(terminalReasons, err.Error())
+		}
+
+		errorString := strings.Join(terminalReasons, ";")
+		secret.setTerminalReason(errorString)
+		return errors.New(errorString)
+	}
+
+	if err := secretfile.WriteSecrets(secret.secretFileDir, secret.secretFileOptions, secret.getSecrets(), secret); err != nil {
+		err = errors.Wrap(err, "Vault secret resource: unable to write secret files")
+		secret.setTerminalReason(err.Error())
+		return err
+	}
+	return nil
+}
+
+// retrieveVaultSecretValue reads the value of a secret from Vault and caches it
+func (secret *VaultSecretResource) retrieveVaultSecretValue(apiSecret apicontainer.Secret, wg *sync.WaitGroup,
+	errorEvents chan error) {
+	defer wg.Done()
+
+	seelog.Infof("Vault secret resource: retrieving resource for secret %s for task: [%s]", apiSecret.ValueFrom, secret.taskARN)
+	secretValue, err := vault.GetSecretValue(apiSecret.ValueFrom, secret.vaultClient)
+	if err != nil {
+		errorEvents <- fmt.Errorf("fetching secret data from Vault: %v", err)
+		return
+	}
+
+	secret.SetCachedSecretValue(apiSecret.GetSecretResourceCacheKey(), secretValue)
+}
+
+// RefreshSecretFiles reads the values of the secrets vended as files from Vault again, and
+// rewrites the files of those whose value changed. The secrets whose value changed are returned.
+func (secret *VaultSecretResource) RefreshSecretFiles() ([]apicontainer.Secret, error) {
+	changed, err := secretfile.RefreshSecrets(secret.secretFileDir, secret.secretFileOptions,
+		secret.getSecrets(), secret, secret.fetchSecretValues)
+	if err != nil {
+		return changed, errors.Wrap(err, "Vault secret resource: unable to refresh secret files")
+	}
+	return changed, nil
+}
+
+// fetchSecretValues reads the values of the secrets from Vault, keyed by their resource cache key
+func (secret *VaultSecretResource) fetchSecretValues(secrets []apicontainer.Secret) (map[string]string, error) {
+	if secret.vaultClient == nil {
+		return nil, errors.New("no Vault server configured, ECS_VAULT_ADDR is not set")
+	}
+
+	values := make(map[string]string)
+	for _, s := range secrets {
+		secretValue, err := vault.GetSecretValue(s.ValueFrom, secret.vaultClient)
+		if err != nil {
+			return nil, fmt.Errorf("fetching secret data from Vault: %v", err)
+		}
+		values[s.GetSecretResourceCacheKey()] = secretValue
+	}
+	return values, nil
+}
+
+// getRequiredSecrets returns the requiredSecrets field of vaultsecret task resource
+func (secret *VaultSecretResource) getRequiredSecrets() map[string]apicontainer.Secret {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.requiredSecrets
+}
+
+// getSecrets returns the secrets of the resource
+func (secret *VaultSecretResource) getSecrets() []apicontainer.Secret {
+	var secrets []apicontainer.Secret
+	for _, s := range secret.getRequiredSecrets() {
+		secrets = append(secrets, s)
+	}
+	return secrets
+}
+
+// Cleanup removes the secret value created for the task, along with the
+// secret files
+func (secret *VaultSecretResource) Cleanup() error {
+	secret.clearVaultSecretValue()
+	if err := secretfile.Remove(secret.secretFileDir); err != nil {
+		return errors.Wrapf(err, "Vault secret resource: unable to remove secret files of task [%s]", secret.taskARN)
+	}
+	return nil
+}
+
+// clearVaultSecretValue cycles through the collection of secret value data and
+// removes them from the task
+func (secret *VaultSecretResource) clearVaultSecretValue() {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	for key := range secret.secretData {
+		delete(secret.secretData, key)
+	}
+}
+
+// GetCachedSecretValue retrieves the secret value from secretData field
+func (secret *VaultSecretResource) GetCachedSecretValue(secretKey string) (string, bool) {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	s, ok := secret.secretData[secretKey]
+	return s, ok
+}
+
+// SetCachedSecretValue set the secret value in the secretData field given the key and value
+func (secret *VaultSecretResource) SetCachedSecretValue(secretKey string, secretValue string) {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	if secret.secretData == nil {
+		secret.secretData = make(map[string]string)
+	}
+
+	secret.secretData[secretKey] = secretValue
+}
+
+func (secret *VaultSecretResource) Initialize(resourceFields *taskresource.ResourceFields,
+	taskKnownStatus status.TaskStatus,
+	taskDesiredStatus status.TaskStatus) {
+	secret.initStatusToTransition()
+	secret.vaultClient = resourceFields.VaultClient
+
+	// if task hasn't turn to 'created' status, and it's desire status is 'running'
+	// the resource status needs to be reset to 'NONE' status so the secret value
+	// will be retrieved again
+	if taskKnownStatus < status.TaskCreated &&
+		taskDesiredStatus <= status.TaskRunning {
+		secret.SetKnownStatus(resourcestatus.ResourceStatusNone)
+	}
+}
+
+type VaultSecretResourceJSON struct {
+	TaskARN           string                         `json:"taskARN"`
+	CreatedAt         *time.Time                     `json:"createdAt,omitempty"`
+	DesiredStatus     *VaultSecretStatus             `json:"desiredStatus"`
+	KnownStatus       *VaultSecretStatus             `json:"knownStatus"`
+	RequiredSecrets   map[string]apicontainer.Secret `json:"secretResources"`
+	SecretFileDir     string                         `json:"secretFileDir,omitempty"`
+	SecretFileOptions *secretfile.Options            `json:"secretFileOptions,omitempty"`
+}
+
+// MarshalJSON serialises the VaultSecretResource struct to JSON
+func (secret *VaultSecretResource) MarshalJSON() ([]byte, error) {
+	if secret == nil {
+		return nil, errors.New("vaultsecret resource is nil")
+	}
+	createdAt := secret.GetCreatedAt()
+	return json.Marshal(VaultSecretResourceJSON{
+		TaskARN:   secret.taskARN,
+		CreatedAt: &createdAt,
+		DesiredStatus: func() *VaultSecretStatus {
+			desiredState := secret.GetDesiredStatus()
+			s := VaultSecretStatus(desiredState)
+			return &s
+		}(),
+		KnownStatus: func() *VaultSecretStatus {
+			knownState := secret.GetKnownStatus()
+			s := VaultSecretStatus(knownState)
+			return &s
+		}(),
+		RequiredSecrets:   secret.getRequiredSecrets(),
+		SecretFileDir:     secret.secretFileDir,
+		SecretFileOptions: secretfile.OptionsJSON(secret.secretFileDir, secret.secretFileOptions),
+	})
+}
+
+// UnmarshalJSON deserialises the raw JSON to a VaultSecretResource struct
+func (secret *VaultSecretResource) UnmarshalJSON(b []byte) error {
+	temp := VaultSecretResourceJSON{}
+
+	if err := json.Unmarshal(b, &temp); err != nil {
+		return err
+	}
+
+	if temp.DesiredStatus != nil {
+		secret.SetDesiredStatus(resourcestatus.ResourceStatus(*temp.DesiredStatus))
+	}
+	if temp.KnownStatus != nil {
+		secret.SetKnownStatus(resourcestatus.ResourceStatus(*temp.KnownStatus))
+	}
+	if temp.CreatedAt != nil && !temp.CreatedAt.IsZero() {
+		secret.SetCreatedAt(*temp.CreatedAt)
+	}
+	if temp.RequiredSecrets != nil {
+		secret.requiredSecrets = temp.RequiredSecrets
+	}
+	secret.taskARN = temp.TaskARN
+	secret.secretFileDir = temp.SecretFileDir
+	if temp.SecretFileOptions != nil {
+		secret.secretFileOptions = *temp.SecretFileOptions
+	}
+
+	return nil
+}
+
+// GetAppliedStatus safely returns the currently applied status of the resource
+func (secret *VaultSecretResource) GetAppliedStatus() resourcestatus.ResourceStatus {
+	secret.lock.RLock()
+	defer secret.lock.RUnlock()
+
+	return secret.appliedStatus
+}
+
+func (secret *VaultSecretResource) DependOnTaskNetwork() bool {
+	return false
+}
+
+func (secret *VaultSecretResource) BuildContainerDependency(containerName string, satisfied apicontainerstatus.ContainerStatus,
+	dependent resourcestatus.ResourceStatus) {
+}
+
+func (secret *VaultSecretResource) GetContainerDependencies(dependent resourcestatus.ResourceStatus) []apicontainer.ContainerDependency {
+	return nil
+}
@@ -0,0 +1,191 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package vaultsecret
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apicontainer "github.com/aws/amazon-ecs-agent/agent/api/container"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource"
+	"github.com/aws/amazon-ecs-agent/agent/taskresource/secretfile"
+	resourcestatus "github.com/aws/amazon-ecs-agent/agent/taskresource/status"
+	mock_vault "github.com/aws/amazon-ecs-agent/agent/vault/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	taskARN     = "task1"
+	secretName1 = "db_password"
+	secretName2 = "api_key"
+	secretPath1 = "secret/data/db"
+	secretPath2 = "kv/api"
+	valueFrom1  = secretPath1 + "#password"
+	valueFrom2  = secretPath2 + "#key"
+	secretValue = "secret-value"
+)
+
+func sampleSecret(secretName string, valueFrom string) apicontainer.Secret {
+	return apicontainer.Secret{
+		Name:      secretName,
+		ValueFrom: valueFrom,
+		Provider:  "vault",
+	}
+}
+
+func TestCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vaultClient := mock_vault.NewMockClient(ctrl)
+	vaultClient.EXPECT().Read(secretPath1).Return(map[string]interface{}{
+		"data":     map[string]interface{}{"password": secretValue},
+		"metadata": map[string]interface{}{"version": float64(1)},
+	}, nil)
+	vaultClient.EXPECT().Read(secretPath2).Return(map[string]interface{}{"key": "api-key-value"}, nil)
+
+	secret1 := sampleSecret(secretName1, valueFrom1)
+	secret2 := sampleSecret(secretName2, valueFrom2)
+	vaultRes := NewVaultSecretResource(taskARN, map[string]apicontainer.Secret{
+		secret1.GetSecretResourceCacheKey(): secret1,
+		secret2.GetSecretResourceCacheKey(): secret2,
+	}, vaultClient, "", secretfile.Options{})
+	require.NoError(t, vaultRes.Create())
+
+	value, ok := vaultRes.GetCachedSecretValue(secret1.GetSecretResourceCacheKey())
+	require.True(t, ok)
+	assert.Equal(t, secretValue, value)
+	value, ok = vaultRes.GetCachedSecretValue(secret2.GetSecretResourceCacheKey())
+	require.True(t, ok)
+	assert.Equal(t, "api-key-value", value)
+}
+
+func TestCreateReturnError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vaultClient := mock_vault.NewMockClient(ctrl)
+	vaultClient.EXPECT().Read(secretPath1).Return(nil, errors.New("permission denied"))
+
+	secret1 := sampleSecret(secretName1, valueFrom1)
+	vaultRes := NewVaultSecretResource(taskARN, map[string]apicontainer.Secret{
+		secret1.GetSecretResourceCacheKey(): secret1,
+	}, vaultClient, "", secretfile.Options{})
+	assert.Error(t, vaultRes.Create())
+	assert.Contains(t, vaultRes.GetTerminalReason(), "permission denied")
+}
+
+func TestCreateWithoutVaultClient(t *testing.T) {
+	secret1 := sampleSecret(secretName1, valueFrom1)
+	vaultRes := NewVaultSecretResource(taskARN, map[string]apicontainer.Secret{
+		secret1.GetSecretResourceCacheKey(): secret1,
+	}, nil, "", secretfile.Options{})
+	assert.Error(t, vaultRes.Create())
+	assert.Contains(t, vaultRes.GetTerminalReason(), "ECS_VAULT_ADDR")
+}
+
+func TestMarshalUnmarshalJSON(t *testing.T) {
+	secret1 := sampleSecret(secretName1, valueFrom1)
+	secretKey := secret1.GetSecretResourceCacheKey()
+
+	vaultResIn := &VaultSecretResource{
+		taskARN:             taskARN,
+		createdAt:           time.Now(),
+		knownStatusUnsafe:   resourcestatus.ResourceCreated,
+		desiredStatusUnsafe: resourcestatus.ResourceCreated,
+		requiredSecrets:     map[string]apicontainer.Secret{secretKey: secret1},
+		secretData:          map[string]string{secretKey: secretValue},
+		secretFileDir:       "/data/vaultsecret/task1",
+		secretFileOptions:   secretfile.Options{Mode: 0440, UID: 1000, GID: 1000},
+	}
+
+	bytes, err := json.Marshal(vaultResIn)
+	require.NoError(t, err)
+	assert.NotContains(t, string(bytes), secretValue, "secret values must not be saved in the state file")
+
+	vaultResOut := &VaultSecretResource{}
+	err = json.Unmarshal(bytes, vaultResOut)
+	require.NoError(t, err)
+	assert.Equal(t, vaultResIn.taskARN, vaultResOut.taskARN)
+	assert.WithinDuration(t, vaultResIn.createdAt, vaultResOut.createdAt, time.Microsecond)
+	assert.Equal(t, vaultResIn.desiredStatusUnsafe, vaultResOut.desiredStatusUnsafe)
+	assert.Equal(t, vaultResIn.knownStatusUnsafe, vaultResOut.knownStatusUnsafe)
+	assert.Equal(t, vaultResIn.requiredSecrets, vaultResOut.requiredSecrets)
+	assert.Equal(t, vaultResIn.secretFileDir, vaultResOut.secretFileDir)
+	assert.Equal(t, vaultResIn.secretFileOptions, vaultResOut.secretFileOptions)
+}
+
+func TestInitialize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vaultClient := mock_vault.NewMockClient(ctrl)
+	vaultRes := &VaultSecretResource{
+		knownStatusUnsafe:   resourcestatus.ResourceCreated,
+		desiredStatusUnsafe: resourcestatus.ResourceCreated,
+	}
+	vaultRes.Initialize(&taskresource.ResourceFields{
+		ResourceFieldsCommon: &taskresource.ResourceFieldsCommon{
+			VaultClient: vaultClient,
+		},
+	}, apitaskstatus.TaskStatusNone, apitaskstatus.TaskRunning)
+	assert.Equal(t, resourcestatus.ResourceStatusNone, vaultRes.GetKnownStatus())
+	assert.Equal(t, resourcestatus.ResourceCreated, vaultRes.GetDesiredStatus())
+	assert.Equal(t, vaultClient, vaultRes.vaultClient)
+}
+
+func TestRefreshSecretFiles(t *testing.T) {
+	secretFileDir, err := ioutil.TempDir("", "vaultsecret")
+	require.NoError(t, err)
+	defer os.RemoveAll(secretFileDir)
+
+	fileSecret := sampleSecret(secretName1, valueFrom1)
+	fileSecret.Type = apicontainer.SecretTypeMountPoint
+	fileSecret.ContainerPath = "/run/secrets/db_password"
+	require.NoError(t, os.MkdirAll(filepath.Join(secretFileDir, secretfile.MountDir("/run/secrets")), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(secretFileDir, secretfile.FilePath(fileSecret)),
+		[]byte(secretValue), 0600))
+	envSecret := sampleSecret(secretName2, valueFrom2)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vaultClient := mock_vault.NewMockClient(ctrl)
+	vaultClient.EXPECT().Read(secretPath1).Return(map[string]interface{}{"password": "rotated-value"}, nil)
+
+	vaultRes := &VaultSecretResource{
+		requiredSecrets: map[string]apicontainer.Secret{
+			fileSecret.GetSecretResourceCacheKey(): fileSecret,
+			envSecret.GetSecretResourceCacheKey():  envSecret,
+		},
+		vaultClient:   vaultClient,
+		secretFileDir: secretFileDir,
+	}
+	changed, err := vaultRes.RefreshSecretFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []apicontainer.Secret{fileSecret}, changed)
+	value, err := ioutil.ReadFile(filepath.Join(secretFileDir, secretfile.FilePath(fileSecret)))
+	require.NoError(t, err)
+	assert.Equal(t, "rotated-value", string(value))
+}
@@ -0,0 +1,157 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package vaultsecret
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusString(t *testing.T) {
+	cases := []struct {
+		Name                 string
+		InVaultSecretStatus  VaultSecretStatus
+		OutVaultSecretStatus string
+	}{
+		{
+			Name:                 "ToStringVaultSecretStatusNone",
+			InVaultSecretStatus:  VaultSecretStatusNone,
+			OutVaultSecretStatus: "NONE",
+		},
+		{
+			Name:                 "ToStringVaultSecretCreated",
+			InVaultSecretStatus:  VaultSecretCreated,
+			OutVaultSecretStatus: "CREATED",
+		},
+		{
+			Name:                 "ToStringVaultSecretRemoved",
+			InVaultSecretStatus:  VaultSecretRemoved,
+			OutVaultSecretStatus: "REMOVED",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			assert.Equal(t, c.OutVaultSecretStatus, c.InVaultSecretStatus.String())
+		})
+	}
+}
+
+func TestMarshalNilVaultSecretStatus(t *testing.T) {
+	var status *VaultSecretStatus
+	bytes, err := status.MarshalJSON()
+
+	assert.Nil(t, bytes)
+	assert.Error(t, err)
+}
+
+func TestMarshalVaultSecretStatus(t *testing.T) {
+	cases := []struct {
+		Name                 string
+		InVaultSecretStatus  VaultSecretStatus
+		OutVaultSecretStatus string
+	}{
+		{
+			Name:                 "MarshallVaultSecretStatusNone",
+			InVaultSecretStatus:  VaultSecretStatusNone,
+			OutVaultSecretStatus: "\"NONE\"",
+		},
+		{
+			Name:                 "MarshallVaultSecretCreated",
+			InVaultSecretStatus:  VaultSecretCreated,
+			OutVaultSecretStatus: "\"CREATED\"",
+		},
+		{
+			Name:                 "MarshallVaultSecretRemoved",
+			InVaultSecretStatus:  VaultSecretRemoved,
+			OutVaultSecretStatus: "\"REMOVED\"",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			bytes, err := c.InVaultSecretStatus.MarshalJSON()
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.OutVaultSecretStatus, string(bytes[:]))
+		})
+	}
+
+}
+
+func TestUnmarshalVaultSecretStatus(t *testing.T) {
+	cases := []struct {
+		Name                 string
+		InVaultSecretStatus  string
+		OutVaultSecretStatus VaultSecretStatus
+		ShouldError          bool
+	}{
+		{
+			Name:                 "UnmarshallVaultSecretStatusNone",
+			InVaultSecretStatus:  "\"NONE\"",
+			OutVaultSecretStatus: VaultSecretStatusNone,
+			ShouldError:          false,
+		},
+		{
+			Name:                 "UnmarshallVaultSecretCreated",
+			InVaultSecretStatus:  "\"CREATED\"",
+			OutVaultSecretStatus: VaultSecretCreated,
+			ShouldError:          false,
+		},
+		{
+			Name:                 "UnmarshallVaultSecretRemoved",
+			InVaultSecretStatus:  "\"REMOVED\"",
+			OutVaultSecretStatus: VaultSecretRemoved,
+			ShouldError:          false,
+		},
+		{
+			Name:                 "UnmarshallVaultSecretStatusNull",
+			InVaultSecretStatus:  "null",
+			OutVaultSecretStatus: VaultSecretStatusNone,
+			ShouldError:          false,
+		},
+		{
+			Name:                 "UnmarshallVaultSecretStatusNonString",
+			InVaultSecretStatus:  "1",
+			OutVaultSecretStatus: VaultSecretStatusNone,
+			ShouldError:          true,
+		},
+		{
+			Name:                 "UnmarshallVaultSecretStatusUnmappedStatus",
+			InVaultSecretStatus:  "\"LOL\"",
+			OutVaultSecretStatus: VaultSecretStatusNone,
+			ShouldError:          true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+
+			var status VaultSecretStatus
+			err := json.Unmarshal([]byte(c.InVaultSecretStatus), &status)
+
+			if c.ShouldError {
+				assert.Error(t, err)
+			} else {
+
+				assert.NoError(t, err)
+				assert.Equal(t, c.OutVaultSecretStatus, status)
+			}
+		})
+	}
+}
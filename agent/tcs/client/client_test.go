@@ -60,6 +60,10 @@ func (*mockStatsEngine) ContainerDockerStats(taskARN string, id string) (*types.
 	return nil, fmt.Errorf("not implemented")
 }
 
+func (*mockStatsEngine) ContainerOOMKills(taskARN string, id string) (uint64, error) {
+	return 0, fmt.Errorf("not implemented")
+}
+
 func (*mockStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
@@ -74,6 +78,10 @@ func (*emptyStatsEngine) ContainerDockerStats(taskARN string, id string) (*types
 	return nil, fmt.Errorf("not implemented")
 }
 
+func (*emptyStatsEngine) ContainerOOMKills(taskARN string, id string) (uint64, error) {
+	return 0, fmt.Errorf("not implemented")
+}
+
 func (*emptyStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
@@ -94,6 +102,10 @@ func (*idleStatsEngine) ContainerDockerStats(taskARN string, id string) (*types.
 	return nil, fmt.Errorf("not implemented")
 }
 
+func (*idleStatsEngine) ContainerOOMKills(taskARN string, id string) (uint64, error) {
+	return 0, fmt.Errorf("not implemented")
+}
+
 func (*idleStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
@@ -122,6 +134,10 @@ func (*nonIdleStatsEngine) ContainerDockerStats(taskARN string, id string) (*typ
 	return nil, fmt.Errorf("not implemented")
 }
 
+func (*nonIdleStatsEngine) ContainerOOMKills(taskARN string, id string) (uint64, error) {
+	return 0, fmt.Errorf("not implemented")
+}
+
 func (*nonIdleStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
@@ -71,6 +71,10 @@ func (*mockStatsEngine) ContainerDockerStats(taskARN string, id string) (*types.
 	return nil, fmt.Errorf("not implemented")
 }
 
+func (*mockStatsEngine) ContainerOOMKills(taskARN string, id string) (uint64, error) {
+	return 0, fmt.Errorf("not implemented")
+}
+
 func (*mockStatsEngine) GetTaskHealthMetrics() (*ecstcs.HealthMetadata, []*ecstcs.TaskHealth, error) {
 	return nil, nil, nil
 }
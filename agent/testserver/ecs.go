@@ -0,0 +1,145 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// ecsTargetPrefix prefixes the operation in the X-Amz-Target header of
+	// the ECS API calls
+	ecsTargetPrefix = "AmazonEC2ContainerServiceV20141113."
+	// ecsContentType is the content type of the responses to ECS API calls
+	ecsContentType = "application/x-amz-json-1.1"
+	// ecsAcknowledgment acknowledges the state changes submitted
+	ecsAcknowledgment = "ACK"
+)
+
+// handleECSRequest records the ECS API call and responds to it. The calls
+// made by the agent succeed, and the other calls fail.
+func (server *Server) handleECSRequest(w http.ResponseWriter, r *http.Request) {
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), ecsTargetPrefix)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeECSError(w, "ServerException", "unable to read request")
+		return
+	}
+
+	input := newECSInput(operation)
+	if input == nil {
+		writeECSError(w, "InvalidParameterException", "operation "+operation+" is not supported by the test server")
+		return
+	}
+	if len(body) > 0 {
+		if err := jsonutil.UnmarshalJSON(input, bytes.NewReader(body)); err != nil {
+			writeECSError(w, "InvalidParameterException", "unable to parse request: "+err.Error())
+			return
+		}
+	}
+	server.record(Message{
+		Service: ServiceECS,
+		Type:    operation,
+		Body:    json.RawMessage(body),
+		Value:   input,
+	})
+	writeECSResponse(w, server.ecsOutput(input))
+}
+
+// newECSInput returns the input of the operation, or nil if the operation
+// isn't supported
+func newECSInput(operation string) interface{} {
+	switch operation {
+	case "RegisterContainerInstance":
+		return &ecs.RegisterContainerInstanceInput{}
+	case "DiscoverPollEndpoint":
+		return &ecs.DiscoverPollEndpointInput{}
+	case "SubmitTaskStateChange":
+		return &ecs.SubmitTaskStateChangeInput{}
+	case "SubmitContainerStateChange":
+		return &ecs.SubmitContainerStateChangeInput{}
+	case "SubmitAttachmentStateChanges":
+		return &ecs.SubmitAttachmentStateChangesInput{}
+	case "UpdateContainerInstancesState":
+		return &ecs.UpdateContainerInstancesStateInput{}
+	case "ListTagsForResource":
+		return &ecs.ListTagsForResourceInput{}
+	case "CreateCluster":
+		return &ecs.CreateClusterInput{}
+	}
+	return nil
+}
+
+func (server *Server) ecsOutput(input interface{}) interface{} {
+	switch input := input.(type) {
+	case *ecs.RegisterContainerInstanceInput:
+		// The agent checks that the attributes it registers are returned
+		return &ecs.RegisterContainerInstanceOutput{
+			ContainerInstance: &ecs.ContainerInstance{
+				ContainerInstanceArn: aws.String(server.ContainerInstanceARN),
+				Attributes:           input.Attributes,
+			},
+		}
+	case *ecs.DiscoverPollEndpointInput:
+		return &ecs.DiscoverPollEndpointOutput{
+			Endpoint:          aws.String(server.URL() + acsPath),
+			TelemetryEndpoint: aws.String(server.URL() + tcsPath),
+		}
+	case *ecs.SubmitTaskStateChangeInput:
+		return &ecs.SubmitTaskStateChangeOutput{Acknowledgment: aws.String(ecsAcknowledgment)}
+	case *ecs.SubmitContainerStateChangeInput:
+		return &ecs.SubmitContainerStateChangeOutput{Acknowledgment: aws.String(ecsAcknowledgment)}
+	case *ecs.SubmitAttachmentStateChangesInput:
+		return &ecs.SubmitAttachmentStateChangesOutput{Acknowledgment: aws.String(ecsAcknowledgment)}
+	case *ecs.UpdateContainerInstancesStateInput:
+		return &ecs.UpdateContainerInstancesStateOutput{}
+	case *ecs.ListTagsForResourceInput:
+		return &ecs.ListTagsForResourceOutput{}
+	case *ecs.CreateClusterInput:
+		return &ecs.CreateClusterOutput{
+			Cluster: &ecs.Cluster{ClusterName: input.ClusterName},
+		}
+	}
+	return struct{}{}
+}
+
+func writeECSResponse(w http.ResponseWriter, output interface{}) {
+	data, err := jsonutil.BuildJSON(output)
+	if err != nil {
+		seelog.Errorf("Test server: unable to marshal ECS response: %v", err)
+		writeECSError(w, "ServerException", "unable to marshal response")
+		return
+	}
+	w.Header().Set("Content-Type", ecsContentType)
+	w.Write(data)
+}
+
+func writeECSError(w http.ResponseWriter, errorType string, message string) {
+	data, _ := json.Marshal(map[string]string{
+		"__type":  errorType,
+		"message": message,
+	})
+	w.Header().Set("Content-Type", ecsContentType)
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(data)
+}
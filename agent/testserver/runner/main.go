@@ -0,0 +1,133 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// The runner runs a scenario against the test server, which stands in for
+// the ECS backend. The agent is started by the runner when its binary is
+// given, or can be pointed to the printed URL otherwise.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/testserver"
+)
+
+const (
+	// agentStopTimeout is the time the agent is given to stop before being
+	// killed
+	agentStopTimeout = 30 * time.Second
+	// defaultRegion and defaultCredentials are set in the environment of
+	// the agent when missing, as requests are signed but not checked
+	defaultRegion      = "us-west-2"
+	defaultCredentials = "testserver"
+)
+
+func main() {
+	scenarioFile := flag.String("scenario", "", "The json file of the scenario to run")
+	listenAddress := flag.String("listen", "127.0.0.1:0", "The address of the test server")
+	agentBinary := flag.String("agent", "", "The agent binary to start against the test server. The agent isn't started if empty")
+	agentArgs := flag.String("agent-args", "", "Space separated arguments added to the agent command line")
+	cluster := flag.String("cluster", "testserver", "The cluster of the agent")
+	flag.Parse()
+
+	if *scenarioFile == "" {
+		log.Fatal("-scenario is required")
+	}
+	scenario, err := testserver.LoadScenario(*scenarioFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server, err := testserver.New(*listenAddress)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.Cluster = *cluster
+	server.Start()
+	defer server.Close()
+	log.Printf("Test server listening on %s", server.URL())
+
+	var agent *exec.Cmd
+	if *agentBinary != "" {
+		agent, err = startAgent(*agentBinary, *agentArgs, server.URL(), *cluster)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		log.Printf("Start the agent with -k, ECS_BACKEND_HOST=%s and ECS_CLUSTER=%s", server.URL(), *cluster)
+	}
+
+	err = scenario.Run(context.Background(), server)
+	if agent != nil {
+		stopAgent(agent)
+	}
+	if err != nil {
+		for _, message := range server.Messages() {
+			log.Printf("Received %s", message.String())
+		}
+		log.Fatalf("Scenario failed: %v", err)
+	}
+	log.Print("Scenario succeeded")
+}
+
+// startAgent starts the agent against the test server. Nothing is saved by
+// the agent, so that it registers again on each run.
+func startAgent(binary string, args string, url string, cluster string) (*exec.Cmd, error) {
+	agent := exec.Command(binary, append([]string{"-k", "-blackhole-ec2-metadata"}, strings.Fields(args)...)...)
+	agent.Env = append(os.Environ(),
+		"ECS_BACKEND_HOST="+url,
+		"ECS_CLUSTER="+cluster,
+		"ECS_CHECKPOINT=false")
+	for key, value := range map[string]string{
+		"AWS_DEFAULT_REGION":    defaultRegion,
+		"AWS_ACCESS_KEY_ID":     defaultCredentials,
+		"AWS_SECRET_ACCESS_KEY": defaultCredentials,
+	} {
+		if os.Getenv(key) == "" {
+			agent.Env = append(agent.Env, key+"="+value)
+		}
+	}
+	agent.Stdout = os.Stdout
+	agent.Stderr = os.Stderr
+	if err := agent.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start the agent: %v", err)
+	}
+	return agent, nil
+}
+
+// stopAgent stops the agent, and kills it if it doesn't stop in time
+func stopAgent(agent *exec.Cmd) {
+	exited := make(chan error, 1)
+	go func() {
+		exited <- agent.Wait()
+	}()
+	if err := agent.Process.Signal(syscall.SIGTERM); err != nil {
+		agent.Process.Kill()
+	}
+	select {
+	case err := <-exited:
+		log.Printf("Agent exited: %v", err)
+	case <-time.After(agentStopTimeout):
+		log.Print("Agent didn't stop in time, killing it")
+		agent.Process.Kill()
+		<-exited
+	}
+}
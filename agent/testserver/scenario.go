@@ -0,0 +1,231 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"time"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+// defaultStepTimeout is the maximum time a step waits for the agent, unless
+// the step sets its own timeout
+const defaultStepTimeout = time.Minute
+
+// Scenario is a script that drives the agent through the test server. Its
+// steps are run in order: messages are sent to the agent, and messages
+// expected from it are waited for.
+type Scenario struct {
+	Steps []Step
+}
+
+// Step is a step of a scenario. Exactly one of WaitForConnection, Send,
+// Expect and Sleep must be set.
+type Step struct {
+	// Name describes the step in logs and errors
+	Name string
+	// WaitForConnection is the service, ACS or TCS, which the agent is
+	// waited to connect to
+	WaitForConnection Service
+	// Send is the message sent to the agent
+	Send *ScenarioMessage
+	// Expect is the message expected from the agent. Messages received
+	// before the step, but not matched by an earlier step, are considered
+	// too.
+	Expect *ScenarioMessage
+	// Sleep is the time to sleep, such as "10s"
+	Sleep string
+	// Timeout is the maximum time to wait for the connection or the
+	// expected message, such as "2m". It's one minute by default.
+	Timeout string
+}
+
+// ScenarioMessage is a message sent to the agent or expected from it
+type ScenarioMessage struct {
+	// Service is the service of the message: ECS, ACS or TCS. Messages can
+	// only be sent over ACS and TCS.
+	Service Service
+	// Type is the type of the message, such as "PayloadMessage", or the
+	// name of the ECS API operation expected, such as
+	// "SubmitTaskStateChange"
+	Type string
+	// Message is the message sent, or the fields expected in the message
+	// received, in the json shape of the wire protocol. Objects expected
+	// match the objects with at least their fields, and lists expected match
+	// the lists with at least their elements.
+	Message json.RawMessage
+}
+
+// LoadScenario reads a scenario from a json file
+func LoadScenario(file string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read scenario %s", file)
+	}
+	scenario := &Scenario{}
+	if err := json.Unmarshal(data, scenario); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse scenario %s", file)
+	}
+	if err := scenario.validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid scenario %s", file)
+	}
+	return scenario, nil
+}
+
+func (scenario *Scenario) validate() error {
+	for i, step := range scenario.Steps {
+		actions := 0
+		if step.WaitForConnection != "" {
+			actions++
+		}
+		if step.Send != nil {
+			actions++
+		}
+		if step.Expect != nil {
+			actions++
+		}
+		if step.Sleep != "" {
+			actions++
+			if _, err := time.ParseDuration(step.Sleep); err != nil {
+				return errors.Wrapf(err, "step %d: invalid sleep", i+1)
+			}
+		}
+		if actions != 1 {
+			return errors.Errorf("step %d: exactly one of WaitForConnection, Send, Expect and Sleep must be set", i+1)
+		}
+		if step.Timeout != "" {
+			if _, err := time.ParseDuration(step.Timeout); err != nil {
+				return errors.Wrapf(err, "step %d: invalid timeout", i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// Run runs the steps of the scenario against the server, and returns the
+// error of the first step that fails
+func (scenario *Scenario) Run(ctx context.Context, server *Server) error {
+	matched := make(map[int]bool)
+	for i, step := range scenario.Steps {
+		name := fmt.Sprintf("step %d", i+1)
+		if step.Name != "" {
+			name = fmt.Sprintf("%s (%s)", name, step.Name)
+		}
+		seelog.Infof("Test server: running %s", name)
+		if err := step.run(ctx, server, matched); err != nil {
+			return errors.Wrap(err, name)
+		}
+	}
+	return nil
+}
+
+func (step *Step) run(ctx context.Context, server *Server, matched map[int]bool) error {
+	switch {
+	case step.Sleep != "":
+		sleep, _ := time.ParseDuration(step.Sleep)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sleep):
+			return nil
+		}
+	case step.Send != nil:
+		return server.SendMessage(step.Send.Service, step.Send.Type, step.Send.Message)
+	}
+
+	timeout := defaultStepTimeout
+	if step.Timeout != "" {
+		timeout, _ = time.ParseDuration(step.Timeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if step.WaitForConnection != "" {
+		return server.WaitForConnection(ctx, step.WaitForConnection)
+	}
+	var expected interface{}
+	if len(step.Expect.Message) > 0 {
+		if err := json.Unmarshal(step.Expect.Message, &expected); err != nil {
+			return errors.Wrap(err, "unable to parse expected message")
+		}
+	}
+	index, message, err := server.WaitForMessage(ctx, func(index int, message Message) bool {
+		return !matched[index] && step.Expect.matches(message, expected)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "expected %s %s", step.Expect.Service, step.Expect.Type)
+	}
+	matched[index] = true
+	seelog.Infof("Test server: received expected %s", message.String())
+	return nil
+}
+
+func (expected *ScenarioMessage) matches(message Message, expectedBody interface{}) bool {
+	if message.Service != expected.Service || message.Type != expected.Type {
+		return false
+	}
+	if expectedBody == nil {
+		return true
+	}
+	var body interface{}
+	if err := json.Unmarshal(message.Body, &body); err != nil {
+		return false
+	}
+	return matchesJSON(expectedBody, body)
+}
+
+// matchesJSON returns true if the decoded json value has the expected
+// fields and list elements, and the expected scalar values
+func matchesJSON(expected interface{}, actual interface{}) bool {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		actualObject, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, expectedValue := range expected {
+			actualValue, ok := actualObject[key]
+			if !ok || !matchesJSON(expectedValue, actualValue) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		actualList, ok := actual.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, expectedElement := range expected {
+			found := false
+			for _, actualElement := range actualList {
+				if matchesJSON(expectedElement, actualElement) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(expected, actual)
+	}
+}
@@ -0,0 +1,120 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testserver
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchesJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+		actual   string
+		matches  bool
+	}{
+		{"equal scalars", `"a"`, `"a"`, true},
+		{"different scalars", `1`, `2`, false},
+		{"subset of fields", `{"a":1}`, `{"a":1,"b":2}`, true},
+		{"missing field", `{"c":1}`, `{"a":1,"b":2}`, false},
+		{"nested objects", `{"a":{"b":"c"}}`, `{"a":{"b":"c","d":"e"}}`, true},
+		{"list elements contained", `[{"a":1}]`, `[{"a":2},{"a":1,"b":2}]`, true},
+		{"list element missing", `[{"a":3}]`, `[{"a":2},{"a":1}]`, false},
+		{"object against list", `{"a":1}`, `[{"a":1}]`, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var expected, actual interface{}
+			require.NoError(t, json.Unmarshal([]byte(tc.expected), &expected))
+			require.NoError(t, json.Unmarshal([]byte(tc.actual), &actual))
+			assert.Equal(t, tc.matches, matchesJSON(expected, actual))
+		})
+	}
+}
+
+func writeScenario(t *testing.T, scenario string) string {
+	dir, err := ioutil.TempDir("", "testserver")
+	require.NoError(t, err)
+	file := filepath.Join(dir, "scenario.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(scenario), 0644))
+	return file
+}
+
+func TestLoadScenarioInvalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		scenario string
+	}{
+		{"no action", `{"Steps":[{"Name":"nothing"}]}`},
+		{"two actions", `{"Steps":[{"Sleep":"1s","WaitForConnection":"ACS"}]}`},
+		{"invalid sleep", `{"Steps":[{"Sleep":"soon"}]}`},
+		{"invalid timeout", `{"Steps":[{"WaitForConnection":"ACS","Timeout":"later"}]}`},
+		{"invalid json", `{"Steps":`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := writeScenario(t, tc.scenario)
+			defer os.RemoveAll(filepath.Dir(file))
+			_, err := LoadScenario(file)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestScenarioRun(t *testing.T) {
+	file := writeScenario(t, `{
+	"Steps": [
+		{"WaitForConnection": "ACS", "Timeout": "10s"},
+		{"Send": {"Service": "ACS", "Type": "PayloadMessage", "Message": {"messageId": "m1", "tasks": [{"arn": "t1"}]}}},
+		{"Expect": {"Service": "ACS", "Type": "AckRequest", "Message": {"messageId": "m1"}}, "Timeout": "10s"},
+		{"Sleep": "10ms"}
+	]
+}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	scenario, err := LoadScenario(file)
+	require.NoError(t, err)
+
+	server := startTestServer(t)
+	defer server.Close()
+	client := connectACS(t, server)
+	defer client.Close()
+
+	require.NoError(t, scenario.Run(context.Background(), server))
+}
+
+func TestScenarioRunExpectTimeout(t *testing.T) {
+	file := writeScenario(t, `{
+	"Steps": [
+		{"Expect": {"Service": "ECS", "Type": "SubmitTaskStateChange"}, "Timeout": "10ms"}
+	]
+}`)
+	defer os.RemoveAll(filepath.Dir(file))
+	scenario, err := LoadScenario(file)
+	require.NoError(t, err)
+
+	server := startTestServer(t)
+	defer server.Close()
+
+	assert.Error(t, scenario.Run(context.Background(), server))
+}
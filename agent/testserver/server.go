@@ -0,0 +1,467 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package testserver stands in for the ECS backend in end to end tests of the
+// agent. It serves the ECS API calls made by the agent, and speaks the ACS
+// and TCS websocket protocols, so that messages can be sent to a real agent
+// process and the messages received from it can be checked.
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"time"
+
+	acsclient "github.com/aws/amazon-ecs-agent/agent/acs/client"
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	tcsclient "github.com/aws/amazon-ecs-agent/agent/tcs/client"
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/aws/amazon-ecs-agent/agent/wsclient"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/cihub/seelog"
+	"github.com/gorilla/websocket"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+// Service is a backend service the agent talks to
+type Service string
+
+const (
+	// ServiceECS is the ECS API
+	ServiceECS Service = "ECS"
+	// ServiceACS is the Agent Communication Service
+	ServiceACS Service = "ACS"
+	// ServiceTCS is the Telemetry Service
+	ServiceTCS Service = "TCS"
+)
+
+const (
+	// acsPath is the path of the ACS endpoint returned by DiscoverPollEndpoint
+	acsPath = "/acs"
+	// tcsPath is the path of the TCS endpoint returned by DiscoverPollEndpoint
+	tcsPath = "/tcs"
+	// wsPath is the path the agent appends to the ACS and TCS endpoints
+	wsPath = "/ws"
+
+	defaultCluster              = "testserver"
+	defaultContainerInstanceARN = "arn:aws:ecs:us-west-2:000000000000:container-instance/testserver/0"
+)
+
+// Message is a message received from the agent
+type Message struct {
+	// Service is the service the message was sent to
+	Service Service
+	// Type is the type of the message, such as "AckRequest", or the name of
+	// the ECS API operation called, such as "SubmitTaskStateChange"
+	Type string
+	// Body is the message in the json shape of the wire protocol
+	Body json.RawMessage
+	// Value is the message decoded into its type, such as
+	// *ecsacs.AckRequest or *ecs.SubmitTaskStateChangeInput
+	Value interface{}
+}
+
+// Server stands in for the ECS API, ACS and TCS. ACS and TCS are served on
+// the endpoints returned by DiscoverPollEndpoint, and all the messages
+// received from the agent are recorded.
+type Server struct {
+	// Cluster is the cluster set in the messages sent to the agent
+	Cluster string
+	// ContainerInstanceARN is the ARN returned to the agent when it
+	// registers, and set in the messages sent to it
+	ContainerInstanceARN string
+
+	httpServer *httptest.Server
+	acsDecoder wsclient.TypeDecoder
+	tcsDecoder wsclient.TypeDecoder
+
+	lock        sync.Mutex
+	connections map[Service]*connection
+	messages    []Message
+	// updated is closed, and replaced, whenever a message is received or a
+	// connection is made, to wake up the waiters
+	updated chan struct{}
+	seqNum  int64
+}
+
+// connection is a websocket connection of the agent
+type connection struct {
+	lock sync.Mutex
+	ws   *websocket.Conn
+}
+
+func (conn *connection) write(data []byte) error {
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+	return conn.ws.WriteMessage(websocket.TextMessage, data)
+}
+
+// New creates a server listening on the address, or on a random local port
+// if the address is empty. The server must be started with Start.
+func New(listenAddress string) (*Server, error) {
+	server := &Server{
+		Cluster:              defaultCluster,
+		ContainerInstanceARN: defaultContainerInstanceARN,
+		acsDecoder:           acsclient.NewACSDecoder(),
+		tcsDecoder:           tcsclient.NewTCSDecoder(),
+		connections:          make(map[Service]*connection),
+		updated:              make(chan struct{}),
+	}
+
+	muxRouter := http.NewServeMux()
+	muxRouter.HandleFunc("/", server.handleECSRequest)
+	muxRouter.HandleFunc(acsPath+wsPath, server.websocketHandler(ServiceACS))
+	muxRouter.HandleFunc(tcsPath+wsPath, server.websocketHandler(ServiceTCS))
+	server.httpServer = httptest.NewUnstartedServer(muxRouter)
+
+	if listenAddress != "" {
+		listener, err := net.Listen("tcp", listenAddress)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to listen on %s", listenAddress)
+		}
+		server.httpServer.Listener.Close()
+		server.httpServer.Listener = listener
+	}
+	return server, nil
+}
+
+// Start starts serving over TLS, with a self-signed certificate. The agent
+// must be run with -k to accept it.
+func (server *Server) Start() {
+	server.httpServer.StartTLS()
+}
+
+// URL returns the URL of the server, which the agent is pointed to with
+// ECS_BACKEND_HOST
+func (server *Server) URL() string {
+	return server.httpServer.URL
+}
+
+// Close closes the connections of the agent and stops the server
+func (server *Server) Close() {
+	server.lock.Lock()
+	for _, conn := range server.connections {
+		conn.ws.Close()
+	}
+	server.lock.Unlock()
+	server.httpServer.Close()
+}
+
+// Messages returns the messages received from the agent so far
+func (server *Server) Messages() []Message {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	messages := make([]Message, len(server.messages))
+	copy(messages, server.messages)
+	return messages
+}
+
+// Connected returns true if the agent is connected to the service
+func (server *Server) Connected(service Service) bool {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	_, ok := server.connections[service]
+	return ok
+}
+
+// WaitForConnection waits until the agent is connected to the service
+func (server *Server) WaitForConnection(ctx context.Context, service Service) error {
+	for {
+		server.lock.Lock()
+		_, ok := server.connections[service]
+		updated := server.updated
+		server.lock.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("timed out waiting for the agent to connect to %s", service)
+		case <-updated:
+		}
+	}
+}
+
+// WaitForMessage waits for a message received from the agent that the
+// filter accepts, and returns it along with its index among the received
+// messages. Messages received before the call are considered too.
+func (server *Server) WaitForMessage(ctx context.Context, filter func(index int, message Message) bool) (int, Message, error) {
+	checked := 0
+	for {
+		server.lock.Lock()
+		messages := server.messages
+		updated := server.updated
+		server.lock.Unlock()
+
+		for ; checked < len(messages); checked++ {
+			if filter(checked, messages[checked]) {
+				return checked, messages[checked], nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return 0, Message{}, errors.New("timed out waiting for message")
+		case <-updated:
+		}
+	}
+}
+
+// SendACS sends the message, such as a *ecsacs.PayloadMessage, over the ACS
+// connection. The cluster, container instance ARN, message id, sequence
+// number and timeline of the message are set if missing.
+func (server *Server) SendACS(message interface{}) error {
+	return server.send(ServiceACS, message)
+}
+
+// SendTCS sends the message, such as a *ecstcs.HeartbeatMessage, over the
+// TCS connection
+func (server *Server) SendTCS(message interface{}) error {
+	return server.send(ServiceTCS, message)
+}
+
+// SendPayload sends the tasks to the agent in a payload message, and returns
+// the id of the message, which the agent acks
+func (server *Server) SendPayload(tasks ...*ecsacs.Task) (string, error) {
+	message := &ecsacs.PayloadMessage{Tasks: tasks}
+	if err := server.SendACS(message); err != nil {
+		return "", err
+	}
+	return aws.StringValue(message.MessageId), nil
+}
+
+// SendHeartbeat sends a heartbeat over the ACS connection
+func (server *Server) SendHeartbeat() error {
+	return server.SendACS(&ecsacs.HeartbeatMessage{Healthy: aws.Bool(true)})
+}
+
+// SendMessage decodes the message of the type, in the json shape of the wire
+// protocol, and sends it over the connection of the service
+func (server *Server) SendMessage(service Service, messageType string, body json.RawMessage) error {
+	decoder, err := server.decoder(service)
+	if err != nil {
+		return err
+	}
+	message, ok := decoder.NewOfType(messageType)
+	if !ok {
+		return errors.Errorf("unknown %s message type %s", service, messageType)
+	}
+	if len(body) > 0 {
+		if err := jsonutil.UnmarshalJSON(message, bytes.NewReader(body)); err != nil {
+			return errors.Wrapf(err, "unable to parse %s message", messageType)
+		}
+	}
+	return server.send(service, message)
+}
+
+func (server *Server) send(service Service, message interface{}) error {
+	server.setMessageDefaults(message)
+	data, err := encodeMessage(message)
+	if err != nil {
+		return err
+	}
+
+	server.lock.Lock()
+	conn, ok := server.connections[service]
+	server.lock.Unlock()
+	if !ok {
+		return errors.Errorf("the agent isn't connected to %s", service)
+	}
+	return conn.write(data)
+}
+
+// setMessageDefaults sets the fields of the messages that ACS always sets,
+// if they're missing
+func (server *Server) setMessageDefaults(message interface{}) {
+	value := reflect.ValueOf(message)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return
+	}
+	value = value.Elem()
+	setIfNil(value, "ClusterArn", func() interface{} { return aws.String(server.Cluster) })
+	setIfNil(value, "ContainerInstanceArn", func() interface{} { return aws.String(server.ContainerInstanceARN) })
+	setIfNil(value, "MessageId", func() interface{} { return aws.String(uuid.New()) })
+	setIfNil(value, "GeneratedAt", func() interface{} { return aws.Int64(time.Now().Unix()) })
+	setIfNil(value, "SeqNum", func() interface{} { return aws.Int64(server.nextSeqNum()) })
+	setIfNil(value, "Timeline", func() interface{} { return aws.Int64(server.nextSeqNum()) })
+}
+
+func setIfNil(value reflect.Value, field string, defaultValue func() interface{}) {
+	fieldValue := value.FieldByName(field)
+	if !fieldValue.IsValid() || fieldValue.Kind() != reflect.Ptr || !fieldValue.IsNil() {
+		return
+	}
+	newValue := reflect.ValueOf(defaultValue())
+	if newValue.Type().AssignableTo(fieldValue.Type()) {
+		fieldValue.Set(newValue)
+	}
+}
+
+func (server *Server) nextSeqNum() int64 {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	server.seqNum++
+	return server.seqNum
+}
+
+// encodeMessage frames the message in the {"type":...,"message":...} form of
+// the wire protocol. The type of the message is the name of its struct.
+func encodeMessage(message interface{}) ([]byte, error) {
+	messageType := reflect.TypeOf(message)
+	if messageType.Kind() != reflect.Ptr {
+		return nil, errors.Errorf("message %v is not a pointer", message)
+	}
+	body, err := jsonutil.BuildJSON(message)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to marshal %s", messageType.Elem().Name())
+	}
+	return json.Marshal(wsclient.RequestMessage{
+		Type:    messageType.Elem().Name(),
+		Message: json.RawMessage(body),
+	})
+}
+
+func (server *Server) decoder(service Service) (wsclient.TypeDecoder, error) {
+	switch service {
+	case ServiceACS:
+		return server.acsDecoder, nil
+	case ServiceTCS:
+		return server.tcsDecoder, nil
+	default:
+		return nil, errors.Errorf("messages can't be sent over %s", service)
+	}
+}
+
+// record records a message received from the agent
+func (server *Server) record(message Message) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	server.messages = append(server.messages, message)
+	server.notifyUnsafe()
+}
+
+func (server *Server) notifyUnsafe() {
+	close(server.updated)
+	server.updated = make(chan struct{})
+}
+
+// websocketHandler accepts the websocket connection of the agent to the
+// service, which replaces any previous one
+func (server *Server) websocketHandler(service Service) http.HandlerFunc {
+	upgrader := websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			seelog.Warnf("Test server: unable to accept %s connection: %v", service, err)
+			return
+		}
+		conn := &connection{ws: ws}
+		server.lock.Lock()
+		if previous, ok := server.connections[service]; ok {
+			previous.ws.Close()
+		}
+		server.connections[service] = conn
+		server.notifyUnsafe()
+		server.lock.Unlock()
+		seelog.Infof("Test server: agent connected to %s", service)
+
+		defer func() {
+			server.lock.Lock()
+			if server.connections[service] == conn {
+				delete(server.connections, service)
+				server.notifyUnsafe()
+			}
+			server.lock.Unlock()
+			ws.Close()
+		}()
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				seelog.Infof("Test server: %s connection closed: %v", service, err)
+				return
+			}
+			message, err := server.decodeMessage(service, data)
+			if err != nil {
+				seelog.Warnf("Test server: unable to decode %s message %s: %v", service, string(data), err)
+				continue
+			}
+			server.record(message)
+			if err := server.respond(conn, message); err != nil {
+				seelog.Warnf("Test server: unable to respond to %s %s: %v", service, message.Type, err)
+			}
+		}
+	}
+}
+
+func (server *Server) decodeMessage(service Service, data []byte) (Message, error) {
+	decoder, err := server.decoder(service)
+	if err != nil {
+		return Message{}, err
+	}
+	received := wsclient.ReceivedMessage{}
+	if err := json.Unmarshal(data, &received); err != nil {
+		return Message{}, err
+	}
+	value, ok := decoder.NewOfType(received.Type)
+	if !ok {
+		return Message{}, errors.Errorf("unknown message type %s", received.Type)
+	}
+	if err := jsonutil.UnmarshalJSON(value, bytes.NewReader(received.Message)); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Service: service,
+		Type:    received.Type,
+		Body:    received.Message,
+		Value:   value,
+	}, nil
+}
+
+// respond sends the responses that the backend always sends to some of the
+// messages of the agent: the stop candidates of task manifests are confirmed,
+// and telemetry is acked
+func (server *Server) respond(conn *connection, message Message) error {
+	var response interface{}
+	switch value := message.Value.(type) {
+	case *ecsacs.TaskStopVerificationMessage:
+		response = &ecsacs.TaskStopVerificationAck{
+			GeneratedAt: aws.Int64(time.Now().Unix()),
+			MessageId:   value.MessageId,
+			StopTasks:   value.StopCandidates,
+		}
+	case *ecstcs.PublishMetricsRequest:
+		response = &ecstcs.AckPublishMetric{Message: aws.String("ack")}
+	case *ecstcs.PublishHealthRequest:
+		response = &ecstcs.AckPublishHealth{Message: aws.String("ack")}
+	default:
+		return nil
+	}
+	data, err := encodeMessage(response)
+	if err != nil {
+		return err
+	}
+	return conn.write(data)
+}
+
+// String returns a short description of the message, used in logs
+func (message Message) String() string {
+	return fmt.Sprintf("%s %s: %s", message.Service, message.Type, string(message.Body))
+}
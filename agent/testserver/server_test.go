@@ -0,0 +1,165 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package testserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	acsclient "github.com/aws/amazon-ecs-agent/agent/acs/client"
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/api/ecsclient"
+	apitaskstatus "github.com/aws/amazon-ecs-agent/agent/api/task/status"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/amazon-ecs-agent/agent/wsclient"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 10 * time.Second
+
+func startTestServer(t *testing.T) *Server {
+	server, err := New("")
+	require.NoError(t, err)
+	server.Start()
+	return server
+}
+
+func testConfig(server *Server) *config.Config {
+	return &config.Config{
+		AWSRegion:          "us-west-2",
+		Cluster:            defaultCluster,
+		APIEndpoint:        server.URL(),
+		AcceptInsecureCert: true,
+	}
+}
+
+// connectACS connects an ACS client to the server, which acks the payloads
+// it receives like the agent
+func connectACS(t *testing.T, server *Server) wsclient.ClientServer {
+	client := acsclient.New(server.URL()+acsPath+wsPath, testConfig(server),
+		credentials.NewStaticCredentials("id", "secret", ""), time.Minute)
+	client.AddRequestHandler(func(message *ecsacs.PayloadMessage) {
+		client.MakeRequest(&ecsacs.AckRequest{
+			Cluster:           message.ClusterArn,
+			ContainerInstance: message.ContainerInstanceArn,
+			MessageId:         message.MessageId,
+		})
+	})
+	require.NoError(t, client.Connect())
+	go client.Serve()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	require.NoError(t, server.WaitForConnection(ctx, ServiceACS))
+	return client
+}
+
+func TestServerPayloadAck(t *testing.T) {
+	server := startTestServer(t)
+	defer server.Close()
+	client := connectACS(t, server)
+	defer client.Close()
+
+	messageID, err := server.SendPayload(&ecsacs.Task{Arn: aws.String("t1")})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	_, message, err := server.WaitForMessage(ctx, func(_ int, message Message) bool {
+		return message.Service == ServiceACS && message.Type == "AckRequest"
+	})
+	require.NoError(t, err)
+	ack, ok := message.Value.(*ecsacs.AckRequest)
+	require.True(t, ok)
+	assert.Equal(t, messageID, aws.StringValue(ack.MessageId))
+	assert.Equal(t, defaultCluster, aws.StringValue(ack.Cluster))
+	assert.Equal(t, defaultContainerInstanceARN, aws.StringValue(ack.ContainerInstance))
+}
+
+func TestServerTaskStopVerification(t *testing.T) {
+	server := startTestServer(t)
+	defer server.Close()
+	client := connectACS(t, server)
+	defer client.Close()
+
+	acks := make(chan *ecsacs.TaskStopVerificationAck, 1)
+	client.AddRequestHandler(func(message *ecsacs.TaskStopVerificationAck) {
+		acks <- message
+	})
+	stopCandidates := []*ecsacs.TaskIdentifier{{
+		TaskArn:       aws.String("t1"),
+		DesiredStatus: aws.String(apitaskstatus.TaskStoppedString),
+	}}
+	require.NoError(t, client.MakeRequest(&ecsacs.TaskStopVerificationMessage{
+		MessageId:      aws.String("mid"),
+		StopCandidates: stopCandidates,
+	}))
+
+	select {
+	case ack := <-acks:
+		assert.Equal(t, "mid", aws.StringValue(ack.MessageId))
+		assert.Equal(t, stopCandidates, ack.StopTasks)
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the task stop verification ack")
+	}
+}
+
+func TestServerSendNotConnected(t *testing.T) {
+	server := startTestServer(t)
+	defer server.Close()
+
+	assert.Error(t, server.SendHeartbeat())
+	assert.Error(t, server.SendMessage(ServiceACS, "UnknownMessage", nil))
+	assert.Error(t, server.SendMessage(ServiceECS, "PayloadMessage", nil))
+}
+
+func TestServerECSAPI(t *testing.T) {
+	server := startTestServer(t)
+	defer server.Close()
+
+	client := ecsclient.NewECSClient(credentials.NewStaticCredentials("id", "secret", ""),
+		testConfig(server), ec2.NewBlackholeEC2MetadataClient())
+
+	endpoint, err := client.DiscoverPollEndpoint(defaultContainerInstanceARN)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL()+acsPath, endpoint)
+	endpoint, err = client.DiscoverTelemetryEndpoint(defaultContainerInstanceARN)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL()+tcsPath, endpoint)
+
+	require.NoError(t, client.SubmitTaskStateChange(api.TaskStateChange{
+		TaskARN: "t1",
+		Status:  apitaskstatus.TaskRunning,
+	}))
+
+	messages := server.Messages()
+	// the telemetry endpoint is served from the cached poll endpoint response
+	require.Len(t, messages, 2)
+	assert.Equal(t, "DiscoverPollEndpoint", messages[0].Type)
+	assert.Equal(t, ServiceECS, messages[1].Service)
+	assert.Equal(t, "SubmitTaskStateChange", messages[1].Type)
+	change, ok := messages[1].Value.(*ecs.SubmitTaskStateChangeInput)
+	require.True(t, ok)
+	assert.Equal(t, "t1", aws.StringValue(change.Task))
+	assert.Equal(t, "RUNNING", aws.StringValue(change.Status))
+}
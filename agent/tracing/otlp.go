@@ -0,0 +1,141 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"encoding/hex"
+	"strconv"
+)
+
+// The types below are the JSON encoding of the OTLP trace export request, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/collector/trace/v1/trace_service.proto
+
+const (
+	// spanKindInternal is the kind of the spans of the agent
+	spanKindInternal = 1
+	// statusCodeOK is the status code of the spans that succeeded
+	statusCodeOK = 1
+	// statusCodeError is the status code of the spans that failed
+	statusCodeError = 2
+)
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanData `json:"spans"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type spanData struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+func newExportRequest(resourceAttributes []Attribute, spans []*Span) *exportRequest {
+	data := make([]spanData, 0, len(spans))
+	for _, span := range spans {
+		data = append(data, newSpanData(span))
+	}
+	return &exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{Attributes: newKeyValues(resourceAttributes)},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: scopeName},
+				Spans: data,
+			}},
+		}},
+	}
+}
+
+func newSpanData(span *Span) spanData {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+
+	data := spanData{
+		TraceID:           hex.EncodeToString(span.traceID[:]),
+		SpanID:            hex.EncodeToString(span.spanID[:]),
+		Name:              span.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+		Attributes:        newKeyValues(span.attributes),
+		Status:            status{Code: statusCodeOK},
+	}
+	if span.parentSpanID != [8]byte{} {
+		data.ParentSpanID = hex.EncodeToString(span.parentSpanID[:])
+	}
+	if span.err != nil {
+		data.Status = status{Code: statusCodeError, Message: span.err.Error()}
+	}
+	return data
+}
+
+func newKeyValues(attributes []Attribute) []keyValue {
+	keyValues := make([]keyValue, 0, len(attributes))
+	for _, attribute := range attributes {
+		keyValue := keyValue{Key: attribute.Key}
+		switch value := attribute.Value.(type) {
+		case string:
+			keyValue.Value.StringValue = &value
+		case int64:
+			intValue := strconv.FormatInt(value, 10)
+			keyValue.Value.IntValue = &intValue
+		case bool:
+			keyValue.Value.BoolValue = &value
+		default:
+			continue
+		}
+		keyValues = append(keyValues, keyValue)
+	}
+	return keyValues
+}
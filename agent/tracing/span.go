@@ -0,0 +1,98 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// TaskARNAttribute is the attribute of the spans with the ARN of their
+	// task
+	TaskARNAttribute = "aws.ecs.task.arn"
+	// ContainerNameAttribute is the attribute of the spans with the name of
+	// their container
+	ContainerNameAttribute = "container.name"
+	// StatusAttribute is the attribute of the spans with the status that a
+	// task, container or resource transitions to
+	StatusAttribute = "aws.ecs.status"
+)
+
+// Attribute is an attribute of a span. Its value is either a string, an
+// int64 or a bool.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is an operation of the lifecycle of a task. A nil span, returned when
+// tracing is disabled, can be used without recording anything.
+type Span struct {
+	tracer       *Tracer
+	traceID      [16]byte
+	spanID       [8]byte
+	parentSpanID [8]byte
+	name         string
+	start        time.Time
+
+	lock       sync.Mutex
+	attributes []Attribute
+	end        time.Time
+	err        error
+	ended      bool
+}
+
+// SetAttributes adds attributes to the span
+func (span *Span) SetAttributes(attributes ...Attribute) {
+	if span == nil {
+		return
+	}
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.attributes = append(span.attributes, attributes...)
+}
+
+// End ends the span, which failed if the error isn't nil, and queues it for
+// export. Spans are only ended once.
+func (span *Span) End(err error) {
+	if span == nil {
+		return
+	}
+	span.lock.Lock()
+	if span.ended {
+		span.lock.Unlock()
+		return
+	}
+	span.ended = true
+	span.end = time.Now()
+	span.err = err
+	span.lock.Unlock()
+	span.tracer.record(span)
+}
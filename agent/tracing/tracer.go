@@ -0,0 +1,239 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package tracing traces the lifecycle of tasks, and exports the spans to a
+// local OpenTelemetry collector over OTLP/HTTP
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/version"
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// serviceName is the name of the agent in the exported traces
+	serviceName = "amazon-ecs-agent"
+	// scopeName is the name of the instrumentation in the exported traces
+	scopeName = "github.com/aws/amazon-ecs-agent/agent"
+
+	// exportInterval is the interval between two exports of the ended spans
+	exportInterval = 5 * time.Second
+	// exportTimeout is the maximum time to export a batch of spans
+	exportTimeout = 10 * time.Second
+	// maxExportBatchSize is the number of ended spans that triggers an export
+	// before the next interval
+	maxExportBatchSize = 512
+	// maxPendingSpans is the maximum number of ended spans waiting to be
+	// exported, beyond which the oldest are dropped
+	maxPendingSpans = 2048
+)
+
+// Tracer records the spans of the lifecycle of tasks, and exports them to the
+// collector. All the spans of a task share a trace whose ID is derived from
+// the task ARN, under a root span covering the whole task lifecycle, so that
+// they're correlated without passing any context around.
+type Tracer struct {
+	enabled  bool
+	endpoint string
+	client   *http.Client
+	resource []Attribute
+
+	lock    sync.Mutex
+	pending []*Span
+	flush   chan struct{}
+}
+
+// TracerGlobal is the tracer used throughout the agent. It doesn't record any
+// span until MustInit is called with a collector endpoint.
+var TracerGlobal = &Tracer{}
+
+// MustInit initializes the global tracer and starts exporting the spans to
+// the collector, if one is configured. The remaining spans are exported when
+// the context is cancelled.
+func MustInit(ctx context.Context, cfg *config.Config) {
+	if cfg.OTLPTracesEndpoint == "" {
+		return
+	}
+	resource := []Attribute{
+		String("service.name", serviceName),
+		String("service.version", version.Version),
+	}
+	if cfg.Cluster != "" {
+		resource = append(resource, String("aws.ecs.cluster", cfg.Cluster))
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		resource = append(resource, String("host.name", hostname))
+	}
+	TracerGlobal = newTracer(cfg.OTLPTracesEndpoint, resource)
+	go TracerGlobal.run(ctx)
+	seelog.Infof("Tracing: exporting the traces of the tasks to %s", cfg.OTLPTracesEndpoint)
+}
+
+func newTracer(endpoint string, resource []Attribute) *Tracer {
+	return &Tracer{
+		enabled:  true,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: exportTimeout},
+		resource: resource,
+		flush:    make(chan struct{}, 1),
+	}
+}
+
+// StartTaskSpan starts the root span of the lifecycle of a task, which the
+// other spans of the task are children of
+func (tracer *Tracer) StartTaskSpan(taskARN string, attributes ...Attribute) *Span {
+	if tracer == nil || !tracer.enabled {
+		return nil
+	}
+	traceID, rootSpanID := taskIDs(taskARN)
+	return tracer.newSpan(traceID, rootSpanID, [8]byte{}, "task", time.Now(), taskARN, attributes)
+}
+
+// StartSpan starts a span of the lifecycle of a task
+func (tracer *Tracer) StartSpan(taskARN string, name string, attributes ...Attribute) *Span {
+	return tracer.StartSpanAt(taskARN, name, time.Now(), attributes...)
+}
+
+// StartSpanAt starts a span of the lifecycle of a task that started at the
+// given time
+func (tracer *Tracer) StartSpanAt(taskARN string, name string, start time.Time, attributes ...Attribute) *Span {
+	if tracer == nil || !tracer.enabled {
+		return nil
+	}
+	traceID, rootSpanID := taskIDs(taskARN)
+	return tracer.newSpan(traceID, randomSpanID(), rootSpanID, name, start, taskARN, attributes)
+}
+
+func (tracer *Tracer) newSpan(traceID [16]byte, spanID [8]byte, parentSpanID [8]byte, name string,
+	start time.Time, taskARN string, attributes []Attribute) *Span {
+	return &Span{
+		tracer:       tracer,
+		traceID:      traceID,
+		spanID:       spanID,
+		parentSpanID: parentSpanID,
+		name:         name,
+		start:        start,
+		attributes:   append([]Attribute{String(TaskARNAttribute, taskARN)}, attributes...),
+	}
+}
+
+// taskIDs returns the trace ID of a task, and the span ID of its root span
+func taskIDs(taskARN string) ([16]byte, [8]byte) {
+	sum := sha256.Sum256([]byte(taskARN))
+	var traceID [16]byte
+	var spanID [8]byte
+	copy(traceID[:], sum[:16])
+	copy(spanID[:], sum[16:24])
+	return traceID, spanID
+}
+
+func randomSpanID() [8]byte {
+	var spanID [8]byte
+	if _, err := io.ReadFull(rand.Reader, spanID[:]); err != nil {
+		seelog.Warnf("Tracing: unable to generate a span ID: %v", err)
+	}
+	return spanID
+}
+
+// record queues an ended span for export
+func (tracer *Tracer) record(span *Span) {
+	tracer.lock.Lock()
+	defer tracer.lock.Unlock()
+
+	if len(tracer.pending) >= maxPendingSpans {
+		seelog.Warnf("Tracing: too many spans waiting to be exported, dropping span %s", tracer.pending[0].name)
+		tracer.pending = tracer.pending[1:]
+	}
+	tracer.pending = append(tracer.pending, span)
+	if len(tracer.pending) >= maxExportBatchSize {
+		select {
+		case tracer.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run exports the ended spans periodically, or once enough of them wait to be
+// exported, until the context is cancelled
+func (tracer *Tracer) run(ctx context.Context) {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			tracer.export(context.Background())
+			return
+		case <-ticker.C:
+		case <-tracer.flush:
+		}
+		tracer.export(ctx)
+	}
+}
+
+// export sends the ended spans to the collector. Spans that can't be exported
+// are dropped.
+func (tracer *Tracer) export(ctx context.Context) {
+	tracer.lock.Lock()
+	spans := tracer.pending
+	tracer.pending = nil
+	tracer.lock.Unlock()
+
+	for len(spans) > 0 {
+		batch := spans
+		if len(batch) > maxExportBatchSize {
+			batch = spans[:maxExportBatchSize]
+		}
+		spans = spans[len(batch):]
+		if err := tracer.exportBatch(ctx, batch); err != nil {
+			seelog.Warnf("Tracing: unable to export %d spans to %s: %v", len(batch), tracer.endpoint, err)
+		}
+	}
+}
+
+func (tracer *Tracer) exportBatch(ctx context.Context, spans []*Span) error {
+	data, err := json.Marshal(newExportRequest(tracer.resource, spans))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, tracer.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := tracer.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
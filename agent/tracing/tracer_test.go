@@ -0,0 +1,154 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const taskARN = "arn:aws:ecs:us-west-2:123456789012:task/cluster/task-id"
+
+// newTestCollector returns a collector recording the export requests it
+// receives
+func newTestCollector(t *testing.T, statusCode int) (*httptest.Server, chan exportRequest) {
+	requests := make(chan exportRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		data, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var request exportRequest
+		require.NoError(t, json.Unmarshal(data, &request))
+		requests <- request
+		w.WriteHeader(statusCode)
+	}))
+	return server, requests
+}
+
+func attributeValues(keyValues []keyValue) map[string]string {
+	values := make(map[string]string)
+	for _, keyValue := range keyValues {
+		switch {
+		case keyValue.Value.StringValue != nil:
+			values[keyValue.Key] = *keyValue.Value.StringValue
+		case keyValue.Value.IntValue != nil:
+			values[keyValue.Key] = *keyValue.Value.IntValue
+		}
+	}
+	return values
+}
+
+func TestTracerExportsTaskSpans(t *testing.T) {
+	server, requests := newTestCollector(t, http.StatusOK)
+	defer server.Close()
+	tracer := newTracer(server.URL, []Attribute{String("service.name", serviceName)})
+
+	taskSpan := tracer.StartTaskSpan(taskARN)
+	start := time.Now().Add(-time.Minute)
+	span := tracer.StartSpanAt(taskARN, "container.pull", start, String(ContainerNameAttribute, "c1"))
+	span.SetAttributes(Int("attempt", 2))
+	span.End(errors.New("pull failed"))
+	span.End(nil)
+	taskSpan.End(nil)
+	tracer.StartSpan("other-task", "acs.payload").End(nil)
+
+	tracer.export(context.Background())
+	request := <-requests
+	require.Len(t, request.ResourceSpans, 1)
+	assert.Equal(t, map[string]string{"service.name": serviceName},
+		attributeValues(request.ResourceSpans[0].Resource.Attributes))
+	require.Len(t, request.ResourceSpans[0].ScopeSpans, 1)
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 3)
+
+	pull, task, other := spans[0], spans[1], spans[2]
+	assert.Equal(t, "task", task.Name)
+	assert.Empty(t, task.ParentSpanID)
+	assert.Equal(t, statusCodeOK, task.Status.Code)
+
+	assert.Equal(t, "container.pull", pull.Name)
+	assert.Equal(t, task.TraceID, pull.TraceID, "spans of a task should share a trace")
+	assert.Equal(t, task.SpanID, pull.ParentSpanID, "spans of a task should be children of the task span")
+	assert.Len(t, pull.TraceID, 32)
+	assert.Len(t, pull.SpanID, 16)
+	assert.Equal(t, status{Code: statusCodeError, Message: "pull failed"}, pull.Status)
+	assert.Equal(t, map[string]string{
+		TaskARNAttribute:       taskARN,
+		ContainerNameAttribute: "c1",
+		"attempt":              "2",
+	}, attributeValues(pull.Attributes))
+	assert.Equal(t, strconv.FormatInt(start.UnixNano(), 10), pull.StartTimeUnixNano)
+
+	assert.NotEqual(t, task.TraceID, other.TraceID)
+
+	// Spans are exported once
+	tracer.export(context.Background())
+	select {
+	case <-requests:
+		t.Fatal("unexpected export of no spans")
+	default:
+	}
+}
+
+func TestTracerSameTaskIDsAcrossTracers(t *testing.T) {
+	first := newTracer("http://127.0.0.1:4318", nil).StartTaskSpan(taskARN)
+	second := newTracer("http://127.0.0.1:4318", nil).StartTaskSpan(taskARN)
+	assert.Equal(t, first.traceID, second.traceID)
+	assert.Equal(t, first.spanID, second.spanID)
+}
+
+func TestTracerDropsOldestSpans(t *testing.T) {
+	tracer := newTracer("http://127.0.0.1:4318", nil)
+	for i := 0; i < maxPendingSpans+1; i++ {
+		tracer.StartSpan(taskARN, "span", Int("index", int64(i))).End(nil)
+	}
+	require.Len(t, tracer.pending, maxPendingSpans)
+	assert.Equal(t, Int("index", 1), tracer.pending[0].attributes[1])
+}
+
+func TestTracerExportError(t *testing.T) {
+	server, requests := newTestCollector(t, http.StatusBadRequest)
+	defer server.Close()
+	tracer := newTracer(server.URL, nil)
+
+	tracer.StartSpan(taskARN, "span").End(nil)
+	err := tracer.exportBatch(context.Background(), tracer.pending)
+	assert.Error(t, err)
+	<-requests
+}
+
+func TestTracerDisabled(t *testing.T) {
+	var nilTracer *Tracer
+	for _, tracer := range []*Tracer{{}, nilTracer} {
+		span := tracer.StartTaskSpan(taskARN)
+		assert.Nil(t, span)
+		span = tracer.StartSpan(taskARN, "span")
+		assert.Nil(t, span)
+		span.SetAttributes(String("key", "value"))
+		span.End(nil)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package diskusage reports the space and inode usage of filesystems
+package diskusage
+
+// Usage is the space and inode usage of a filesystem
+type Usage struct {
+	// TotalBytes is the size of the filesystem
+	TotalBytes uint64
+	// FreeBytes is the space available to unprivileged users
+	FreeBytes uint64
+	// TotalInodes is the number of inodes of the filesystem, or 0 if the
+	// filesystem doesn't report inodes
+	TotalInodes uint64
+	// FreeInodes is the number of free inodes
+	FreeInodes uint64
+}
+
+// UsedBytesPercent returns the percentage of the filesystem that is not
+// available to unprivileged users
+func (usage *Usage) UsedBytesPercent() float64 {
+	if usage.TotalBytes == 0 {
+		return 0
+	}
+	return float64(usage.TotalBytes-usage.FreeBytes) * 100 / float64(usage.TotalBytes)
+}
+
+// Getter returns the usage of the filesystem holding a path
+type Getter interface {
+	Usage(path string) (*Usage, error)
+}
+
+type getter struct{}
+
+// NewGetter creates a new Getter
+func NewGetter() Getter {
+	return &getter{}
+}
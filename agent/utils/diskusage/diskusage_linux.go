@@ -0,0 +1,35 @@
+// +build linux
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diskusage
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// Usage returns the usage of the filesystem holding the path
+func (*getter) Usage(path string) (*Usage, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return nil, errors.Wrapf(err, "unable to get filesystem usage of %s", path)
+	}
+	return &Usage{
+		TotalBytes:  stat.Blocks * uint64(stat.Bsize),
+		FreeBytes:   stat.Bavail * uint64(stat.Bsize),
+		TotalInodes: stat.Files,
+		FreeInodes:  stat.Ffree,
+	}, nil
+}
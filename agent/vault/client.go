@@ -0,0 +1,282 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package vault
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
+	"github.com/aws/amazon-ecs-agent/agent/utils/cipher"
+
+	"github.com/cihub/seelog"
+	"github.com/pkg/errors"
+)
+
+const (
+	roundtripTimeout = 5 * time.Second
+	// tokenHeader is the header holding the token requests are authenticated with
+	tokenHeader = "X-Vault-Token"
+	// requestHeader is the header required by Vault agents to forward requests
+	requestHeader = "X-Vault-Request"
+	// appRoleLoginPath is the path to log in to with AppRole
+	appRoleLoginPath = "auth/approle/login"
+	// tokenExpiryMargin is how long before the expiry of the token obtained
+	// with AppRole a new token is obtained
+	tokenExpiryMargin = 30 * time.Second
+)
+
+// Config is the address of the Vault server and the authentication method the
+// agent uses. Requests aren't authenticated if neither a token file nor AppRole
+// files are set, which is the case of requests to a local Vault agent using its
+// auto-auth token.
+type Config struct {
+	// Address is the URL of the Vault server or local Vault agent, such as
+	// "https://vault.example.com:8200"
+	Address string
+	// CACertFile is the PEM file of the CA certificates that the certificate
+	// of the server is verified against, instead of the ones of the host
+	CACertFile string
+	// TokenFile is the file holding the token to authenticate with. It's
+	// read before every request, so that it can be renewed by another
+	// process, such as a Vault agent sink.
+	TokenFile string
+	// RoleIDFile and SecretIDFile are the files holding the role ID and
+	// secret ID to log in with AppRole
+	RoleIDFile   string
+	SecretIDFile string
+	// AllowedPaths are the paths, without leading or trailing slashes, that
+	// secrets are read from. Secrets are only read if their path is one of
+	// these or is under one of them, such as "secret/data/app/db" under
+	// "secret/data/app".
+	AllowedPaths []string
+}
+
+// response is the body of the responses of Vault
+type response struct {
+	Data   map[string]interface{} `json:"data"`
+	Auth   *responseAuth          `json:"auth"`
+	Errors []string               `json:"errors"`
+}
+
+type responseAuth struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int64  `json:"lease_duration"`
+}
+
+type httpClient struct {
+	cfg    Config
+	client *http.Client
+
+	// token and tokenExpiry are the token obtained with AppRole and its
+	// expiry, which is zero if the token doesn't expire
+	token       string
+	tokenExpiry time.Time
+	lock        sync.Mutex
+}
+
+// NewClient returns a client reading secrets from the Vault server of the config
+func NewClient(cfg Config) (Client, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("vault: no address")
+	}
+	if len(cfg.AllowedPaths) == 0 {
+		return nil, errors.New("vault: no allowed paths")
+	}
+	for _, allowedPath := range cfg.AllowedPaths {
+		if err := ValidatePath(allowedPath); err != nil {
+			return nil, errors.Wrap(err, "vault: invalid allowed path")
+		}
+	}
+	client := httpclient.New(roundtripTimeout, false)
+	if cfg.CACertFile != "" {
+		pem, err := ioutil.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "vault: unable to read CA certificates %s", cfg.CACertFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("vault: no CA certificate found in %s", cfg.CACertFile)
+		}
+		tlsConfig := &tls.Config{RootCAs: pool}
+		cipher.WithSupportedCipherSuites(tlsConfig)
+		client.Transport.(httpclient.OverridableTransport).SetTransport(&http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			TLSClientConfig:     tlsConfig,
+			TLSHandshakeTimeout: 10 * time.Second,
+		})
+	}
+	return &httpClient{
+		cfg:    cfg,
+		client: client,
+	}, nil
+}
+
+// Read returns the data of the secret at the path, which must be allowed. A
+// new token is obtained with AppRole if the current one is denied, in case it
+// was revoked.
+func (c *httpClient) Read(path string) (map[string]interface{}, error) {
+	path = strings.Trim(path, pathSeparator)
+	if err := ValidatePath(path); err != nil {
+		return nil, errors.Wrap(err, "vault")
+	}
+	if !pathAllowed(path, c.cfg.AllowedPaths) {
+		return nil, fmt.Errorf("vault: %s is not under the allowed paths %s", path,
+			strings.Join(c.cfg.AllowedPaths, ","))
+	}
+	token, err := c.getToken(false)
+	if err != nil {
+		return nil, err
+	}
+	resp, status, err := c.do(http.MethodGet, path, token, nil)
+	if status == http.StatusForbidden && c.usesAppRole() {
+		seelog.Infof("Vault: request to %s denied, logging in with AppRole again", path)
+		if token, err = c.getToken(true); err != nil {
+			return nil, err
+		}
+		resp, _, err = c.do(http.MethodGet, path, token, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+func (c *httpClient) usesAppRole() bool {
+	return c.cfg.TokenFile == "" && c.cfg.RoleIDFile != ""
+}
+
+// getToken returns the token to authenticate requests with, which is empty if
+// requests aren't authenticated. The token obtained with AppRole is reused
+// until it's about to expire, unless a new one is forced.
+func (c *httpClient) getToken(forceLogin bool) (string, error) {
+	if c.cfg.TokenFile != "" {
+		token, err := readFile(c.cfg.TokenFile)
+		if err != nil {
+			return "", errors.Wrap(err, "vault: unable to read token")
+		}
+		return token, nil
+	}
+	if !c.usesAppRole() {
+		return "", nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !forceLogin && c.token != "" && (c.tokenExpiry.IsZero() || time.Now().Before(c.tokenExpiry)) {
+		return c.token, nil
+	}
+	token, expiry, err := c.loginWithAppRole()
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	c.tokenExpiry = expiry
+	return token, nil
+}
+
+// loginWithAppRole logs in with the role ID and secret ID, and returns the
+// token obtained along with its expiry
+func (c *httpClient) loginWithAppRole() (string, time.Time, error) {
+	roleID, err := readFile(c.cfg.RoleIDFile)
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "vault: unable to read AppRole role ID")
+	}
+	secretID, err := readFile(c.cfg.SecretIDFile)
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "vault: unable to read AppRole secret ID")
+	}
+	body, err := json.Marshal(map[string]string{
+		"role_id":   roleID,
+		"secret_id": secretID,
+	})
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "vault: unable to marshal AppRole login")
+	}
+
+	resp, _, err := c.do(http.MethodPost, appRoleLoginPath, "", bytes.NewReader(body))
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "vault: unable to log in with AppRole")
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return "", time.Time{}, errors.New("vault: unable to log in with AppRole: no token returned")
+	}
+
+	var expiry time.Time
+	if resp.Auth.LeaseDuration > 0 {
+		expiry = time.Now().Add(time.Duration(resp.Auth.LeaseDuration)*time.Second - tokenExpiryMargin)
+	}
+	seelog.Infof("Vault: logged in with AppRole, token lease duration: %ds", resp.Auth.LeaseDuration)
+	return resp.Auth.ClientToken, expiry, nil
+}
+
+// do sends a request to the path of the Vault API, and returns the decoded
+// response along with its status code
+func (c *httpClient) do(method string, path string, token string, body io.Reader) (*response, int, error) {
+	url := strings.TrimSuffix(c.cfg.Address, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "vault: unable to create request to %s", path)
+	}
+	req.Header.Set(requestHeader, "true")
+	if token != "" {
+		req.Header.Set(tokenHeader, token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "vault: request to %s failed", path)
+	}
+	defer httpResp.Body.Close()
+
+	resp := &response{}
+	decodeErr := json.NewDecoder(httpResp.Body).Decode(resp)
+	if httpResp.StatusCode == http.StatusNotFound {
+		return nil, httpResp.StatusCode, fmt.Errorf("vault: %s not found", path)
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, httpResp.StatusCode, fmt.Errorf("vault: request to %s failed with status %d: %s",
+			path, httpResp.StatusCode, strings.Join(resp.Errors, "; "))
+	}
+	if decodeErr != nil {
+		return nil, httpResp.StatusCode, errors.Wrapf(decodeErr, "vault: unable to decode response of %s", path)
+	}
+	return resp, httpResp.StatusCode, nil
+}
+
+// readFile returns the trimmed content of a file holding a credential
+func readFile(file string) (string, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(content))
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", file)
+	}
+	return value, nil
+}
@@ -0,0 +1,186 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package vault
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, dir string, name string, content string) string {
+	file := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(file, []byte(content), 0600))
+	return file
+}
+
+func TestReadWithTokenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/v1/kv/db", r.URL.Path)
+		assert.Equal(t, "true", r.Header.Get(requestHeader))
+		if r.Header.Get(tokenHeader) != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"password":"hunter2"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{
+		Address:      server.URL,
+		TokenFile:    writeTestFile(t, dir, "token", "s.token\n"),
+		AllowedPaths: []string{"kv"},
+	})
+	require.NoError(t, err)
+
+	data, err := client.Read("kv/db")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"password": "hunter2"}, data)
+
+	// The token is read again before every request
+	writeTestFile(t, dir, "token", "s.revoked")
+	_, err = client.Read("kv/db")
+	assert.Error(t, err)
+}
+
+func TestReadWithAppRole(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logins := 0
+	validToken := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			var login map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&login))
+			assert.Equal(t, map[string]string{"role_id": "role", "secret_id": "secret"}, login)
+			logins++
+			validToken = "s.token" + strconv.Itoa(logins)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"auth": map[string]interface{}{"client_token": validToken, "lease_duration": 3600},
+			})
+		case "/v1/kv/db":
+			if r.Header.Get(tokenHeader) != validToken {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"data":{"password":"hunter2"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{
+		Address:      server.URL,
+		RoleIDFile:   writeTestFile(t, dir, "role-id", "role"),
+		SecretIDFile: writeTestFile(t, dir, "secret-id", "secret"),
+		AllowedPaths: []string{"kv"},
+	})
+	require.NoError(t, err)
+
+	_, err = client.Read("kv/db")
+	require.NoError(t, err)
+	_, err = client.Read("kv/db")
+	require.NoError(t, err)
+	assert.Equal(t, 1, logins, "the token should be reused until it expires")
+
+	// A revoked token is replaced by logging in again
+	validToken = "s.other"
+	data, err := client.Read("kv/db")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"password": "hunter2"}, data)
+	assert.Equal(t, 2, logins)
+
+	_, err = client.Read("kv/missing")
+	assert.Error(t, err)
+}
+
+func TestReadWithoutToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(tokenHeader))
+		w.Write([]byte(`{"data":{"password":"hunter2"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{Address: server.URL + "/", AllowedPaths: []string{"kv/db"}})
+	require.NoError(t, err)
+
+	data, err := client.Read("/kv/db")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"password": "hunter2"}, data)
+}
+
+func TestNewClientInvalidCACert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = NewClient(Config{
+		Address:      "https://vault.example.com:8200",
+		CACertFile:   writeTestFile(t, dir, "ca.pem", "not a certificate"),
+		AllowedPaths: []string{"kv"},
+	})
+	assert.Error(t, err)
+}
+
+func TestNewClientInvalidAllowedPaths(t *testing.T) {
+	for _, allowedPaths := range [][]string{nil, {"auth"}, {"kv", "sys/raw"}, {"kv/../auth"}, {"kv//db"}} {
+		_, err := NewClient(Config{
+			Address:      "https://vault.example.com:8200",
+			AllowedPaths: allowedPaths,
+		})
+		assert.Error(t, err, allowedPaths)
+	}
+}
+
+func TestReadDisallowedPath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{Address: server.URL, AllowedPaths: []string{"secret/data/app"}})
+	require.NoError(t, err)
+
+	for _, path := range []string{
+		"auth/token/lookup-self",
+		"sys/raw/core",
+		"secret/data/app/../../data/other",
+		"secret/data/application",
+		"secret/data/app?list=true",
+		"kv/db",
+	} {
+		_, err := client.Read(path)
+		assert.Error(t, err, path)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package vault reads secrets from a HashiCorp Vault server, either through
+// its HTTP API or through a local Vault agent
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// valueFromKeyDelimiter separates the path of a secret from the key of
+	// the secret data in the valueFrom of a secret
+	valueFromKeyDelimiter = "#"
+	// pathSeparator separates the segments of the path of a secret
+	pathSeparator = "/"
+	// invalidPathChars are the characters that would change the meaning of
+	// the URL of the request reading a secret
+	invalidPathChars = "?%\\"
+)
+
+// reservedPathPrefixes are the mounts of Vault that don't hold secrets, but
+// that hold the tokens, policies and configuration of Vault. They're never
+// read, whatever the allowed paths.
+var reservedPathPrefixes = []string{"auth", "sys"}
+
+// Client reads secrets from Vault
+type Client interface {
+	// Read returns the data of the secret at the path, such as
+	// "secret/data/db" for a secret of a version 2 key/value engine
+	Read(path string) (map[string]interface{}, error)
+}
+
+// GetSecretValue reads the secret referenced by valueFrom from Vault. The
+// valueFrom is the path of the secret, optionally followed by "#" and the key
+// of the secret data whose value is returned, such as "secret/data/db#password".
+// The secret data is returned as JSON if there is no key.
+func GetSecretValue(valueFrom string, client Client) (string, error) {
+	path, key, err := ParseValueFrom(valueFrom)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := client.Read(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "vault reading secret %s", path)
+	}
+	data = secretData(data)
+
+	if key == "" {
+		value, err := json.Marshal(data)
+		if err != nil {
+			return "", errors.Wrapf(err, "vault marshalling data of secret %s", path)
+		}
+		return string(value), nil
+	}
+
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %s", path, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", errors.Wrapf(err, "vault marshalling key %s of secret %s", key, path)
+	}
+	return string(encoded), nil
+}
+
+// ParseValueFrom splits the valueFrom of a secret into the path of the secret
+// and the key of the secret data, which is empty if there is none
+func ParseValueFrom(valueFrom string) (string, string, error) {
+	path := valueFrom
+	key := ""
+	if i := strings.Index(valueFrom, valueFromKeyDelimiter); i >= 0 {
+		path = valueFrom[:i]
+		key = valueFrom[i+len(valueFromKeyDelimiter):]
+		if key == "" {
+			return "", "", fmt.Errorf("vault secret %s: empty key", valueFrom)
+		}
+	}
+	path = strings.Trim(path, pathSeparator)
+	if path == "" {
+		return "", "", fmt.Errorf("vault secret %s: empty path", valueFrom)
+	}
+	if err := ValidatePath(path); err != nil {
+		return "", "", errors.Wrapf(err, "vault secret %s", valueFrom)
+	}
+	return path, key, nil
+}
+
+// ValidatePath returns an error if the path, without leading or trailing
+// slashes, has empty, "." or ".." segments, characters that aren't part of
+// the path of the request, or is under a reserved mount
+func ValidatePath(path string) error {
+	if strings.ContainsAny(path, invalidPathChars) {
+		return fmt.Errorf("invalid path %s: contains one of %q", path, invalidPathChars)
+	}
+	segments := strings.Split(path, pathSeparator)
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid path %s: empty, . or .. segment", path)
+		}
+	}
+	for _, reserved := range reservedPathPrefixes {
+		if segments[0] == reserved {
+			return fmt.Errorf("invalid path %s: %s/ is reserved", path, reserved)
+		}
+	}
+	return nil
+}
+
+// pathAllowed returns whether the path is one of the allowed paths, or is
+// under one of them
+func pathAllowed(path string, allowedPaths []string) bool {
+	for _, allowed := range allowedPaths {
+		if path == allowed || strings.HasPrefix(path, allowed+pathSeparator) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretData returns the data of a secret of a version 2 key/value engine,
+// which is nested along with the version metadata, or the data as is
+func secretData(data map[string]interface{}) map[string]interface{} {
+	if len(data) != 2 {
+		return data
+	}
+	nested, ok := data["data"].(map[string]interface{})
+	if !ok {
+		return data
+	}
+	if _, ok := data["metadata"].(map[string]interface{}); !ok {
+		return data
+	}
+	return nested
+}
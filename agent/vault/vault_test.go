@@ -0,0 +1,119 @@
+// +build unit
+
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package vault
+
+import (
+	"errors"
+	"testing"
+
+	mock_vault "github.com/aws/amazon-ecs-agent/agent/vault/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSecretValue(t *testing.T) {
+	kvV2Data := map[string]interface{}{
+		"data": map[string]interface{}{
+			"username": "admin",
+			"password": "hunter2",
+			"port":     float64(5432),
+		},
+		"metadata": map[string]interface{}{"version": float64(3)},
+	}
+	kvV1Data := map[string]interface{}{
+		"password": "hunter2",
+	}
+
+	testCases := []struct {
+		name          string
+		valueFrom     string
+		path          string
+		data          map[string]interface{}
+		expectedValue string
+	}{
+		{
+			name:          "key of kv v2 secret",
+			valueFrom:     "secret/data/db#password",
+			path:          "secret/data/db",
+			data:          kvV2Data,
+			expectedValue: "hunter2",
+		},
+		{
+			name:          "non-string key",
+			valueFrom:     "secret/data/db#port",
+			path:          "secret/data/db",
+			data:          kvV2Data,
+			expectedValue: "5432",
+		},
+		{
+			name:          "key of kv v1 secret",
+			valueFrom:     "/kv/db#password",
+			path:          "kv/db",
+			data:          kvV1Data,
+			expectedValue: "hunter2",
+		},
+		{
+			name:          "whole secret",
+			valueFrom:     "kv/db",
+			path:          "kv/db",
+			data:          kvV1Data,
+			expectedValue: `{"password":"hunter2"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := mock_vault.NewMockClient(ctrl)
+			client.EXPECT().Read(tc.path).Return(tc.data, nil)
+
+			value, err := GetSecretValue(tc.valueFrom, client)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedValue, value)
+		})
+	}
+}
+
+func TestGetSecretValueMissingKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_vault.NewMockClient(ctrl)
+	client.EXPECT().Read("kv/db").Return(map[string]interface{}{"username": "admin"}, nil)
+
+	_, err := GetSecretValue("kv/db#password", client)
+	assert.Error(t, err)
+}
+
+func TestGetSecretValueReadError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_vault.NewMockClient(ctrl)
+	client.EXPECT().Read("kv/db").Return(nil, errors.New("permission denied"))
+
+	_, err := GetSecretValue("kv/db#password", client)
+	assert.Error(t, err)
+}
+
+func TestParseValueFromInvalid(t *testing.T) {
+	for _, valueFrom := range []string{"", "/", "kv/db#", "#password", "auth/token/lookup-self#id",
+		"sys/raw/core", "kv/../auth/token/lookup-self", "kv/./db", "kv//db", "kv/db?version=1"} {
+		_, _, err := ParseValueFrom(valueFrom)
+		assert.Error(t, err, valueFrom)
+	}
+}